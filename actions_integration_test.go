@@ -21,7 +21,7 @@ func TestExecutePostToolUseAction_WithUseStdin(t *testing.T) {
 	action := Action{
 		Type:     "command",
 		Command:  "cat " + jsonFile,
-		UseStdin: true,
+		UseStdin: boolPtr(true),
 	}
 
 	input := &PostToolUseInput{
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// hookInputEnvMaxValue is the longest value of a CCHOOK_* variable. Longer fields (such as the content of a Write)
+// are left out, as the environment is limited; they are available in the stdin JSON (use_stdin) instead.
+const hookInputEnvMaxValue = 32 * 1024
+
+// actionArgs returns the args of a command action after template expansion.
+// Each template value becomes part of a single argument as it is; no shell interprets it.
+func actionArgs(action Action, rawJSON any) []string {
+	args := make([]string, len(action.Args))
+	for i, arg := range action.Args {
+		args[i] = unifiedTemplateReplace(arg, rawJSON)
+	}
+	return args
+}
+
+// actionEnv returns the env of a command action as sorted NAME=value entries after template expansion.
+func actionEnv(action Action, rawJSON any) []string {
+	env := make([]string, 0, len(action.Env))
+	for name, value := range action.Env {
+		env = append(env, name+"="+unifiedTemplateReplace(value, rawJSON))
+	}
+	sort.Strings(env)
+	return env
+}
+
+// hookInputEnv returns the fields of the event JSON as CCHOOK_* environment variables, sorted:
+// CCHOOK_SESSION_ID, CCHOOK_TOOL_NAME, CCHOOK_TOOL_INPUT_FILE_PATH and so on.
+// Objects are flattened with "_", arrays are JSON, and null or too long values are left out.
+func hookInputEnv(rawJSON any) []string {
+	var env []string
+	var walk func(name string, v any)
+	walk = func(name string, v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			for key, child := range v {
+				walk(name+"_"+envVarName(key), child)
+			}
+		case nil:
+		default:
+			value := jqValueToString(v)
+			if len(value) <= hookInputEnvMaxValue && !strings.Contains(value, "\x00") {
+				env = append(env, name+"="+value)
+			}
+		}
+	}
+	if m, ok := rawJSON.(map[string]any); ok {
+		walk("CCHOOK", m)
+	}
+	sort.Strings(env)
+	return env
+}
+
+// envVarName turns a JSON field name into the upper-case part of an environment variable name.
+func envVarName(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, key)
+}
+
+// argvCommandLine returns a shell command line running args with env, for runners that only take command lines
+// (runner, env_from, or a CommandRunner that isn't an ArgvCommandRunner) and for logs.
+func argvCommandLine(args, env []string) string {
+	words := make([]string, 0, len(env)+len(args)+1)
+	if len(env) > 0 {
+		words = append(words, "env")
+		for _, entry := range env {
+			words = append(words, shellQuote(entry))
+		}
+	}
+	for _, arg := range args {
+		words = append(words, shellQuote(arg))
+	}
+	return strings.Join(words, " ")
+}
+
+// runArgsWithOutput runs args[0] with the arguments args[1:] without a shell, adding env to the environment,
+// and captures stdout, stderr and the exit code like runCommandWithOutput.
+func runArgsWithOutput(args, env []string, useStdin bool, data any) (stdout, stderr string, exitCode int, err error) {
+	if len(args) == 0 || args[0] == "" {
+		return "", "", 1, fmt.Errorf("empty command")
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = append(cmd.Environ(), env...)
+	return captureCommandOutput(cmd, useStdin, data)
+}
+
+// configuredCommand returns the command of a command action as written in the config
+// (templates not expanded): command, or the shell command line of args.
+func configuredCommand(action Action) string {
+	if len(action.Args) > 0 {
+		return argvCommandLine(action.Args, nil)
+	}
+	return action.Command
+}
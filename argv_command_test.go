@@ -0,0 +1,107 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHookInputEnv(t *testing.T) {
+	rawJSON := map[string]any{
+		"session_id":      "abc",
+		"hook_event_name": "PreToolUse",
+		"tool_input": map[string]any{
+			"file_path": "/repo/main.go",
+			"edits":     []any{map[string]any{"old_string": "a"}},
+			"timeout":   float64(30),
+			"missing":   nil,
+		},
+		"stop-hook.active": true,
+		"content":          strings.Repeat("x", hookInputEnvMaxValue+1),
+		"nul":              "a\x00b",
+	}
+	want := []string{
+		"CCHOOK_HOOK_EVENT_NAME=PreToolUse",
+		"CCHOOK_SESSION_ID=abc",
+		"CCHOOK_STOP_HOOK_ACTIVE=true",
+		`CCHOOK_TOOL_INPUT_EDITS=[{"old_string":"a"}]`,
+		"CCHOOK_TOOL_INPUT_FILE_PATH=/repo/main.go",
+		"CCHOOK_TOOL_INPUT_TIMEOUT=30",
+	}
+	if got := hookInputEnv(rawJSON); !reflect.DeepEqual(got, want) {
+		t.Errorf("hookInputEnv() = %q, want %q", got, want)
+	}
+	if got := hookInputEnv(nil); got != nil {
+		t.Errorf("hookInputEnv(nil) = %q, want nil", got)
+	}
+}
+
+func TestArgvCommandLine(t *testing.T) {
+	if got, want := argvCommandLine([]string{"gofmt", "-w", "my file.go"}, nil), `'gofmt' '-w' 'my file.go'`; got != want {
+		t.Errorf("argvCommandLine() = %q, want %q", got, want)
+	}
+	if got, want := argvCommandLine([]string{"notify"}, []string{"TOPIC=it's"}), `env 'TOPIC=it'\''s' 'notify'`; got != want {
+		t.Errorf("argvCommandLine() = %q, want %q", got, want)
+	}
+}
+
+func TestRunCommandAction_Args(t *testing.T) {
+	rawJSON := map[string]any{
+		"tool_name":  "Write",
+		"prompt":     `$(echo injected); "quoted"`,
+		"tool_input": map[string]any{"file_path": "/repo/a b.go"},
+	}
+	action := Action{
+		Type: "command",
+		Args: []string{"sh", "-c", `printf '%s|%s|%s|%s' "$1" "$CCHOOK_TOOL_NAME" "$CCHOOK_TOOL_INPUT_FILE_PATH" "$TOPIC"`, "sh", "{.prompt}"},
+		Env:  map[string]string{"TOPIC": "{.tool_name | lower}"},
+	}
+
+	t.Run("runs without a shell", func(t *testing.T) {
+		stdout, stderr, exitCode, err := NewActionExecutor(nil).runAction(action, rawJSON)
+		if err != nil || exitCode != 0 {
+			t.Fatalf("runAction() = %d, %v (stderr %q)", exitCode, err, stderr)
+		}
+		if want := `$(echo injected); "quoted"|Write|/repo/a b.go|write`; stdout != want {
+			t.Errorf("stdout = %q, want %q", stdout, want)
+		}
+	})
+
+	t.Run("use_stdin", func(t *testing.T) {
+		action := Action{Type: "command", Args: []string{"cat"}, UseStdin: boolPtr(true)}
+		stdout, _, _, err := NewActionExecutor(nil).runAction(action, map[string]any{"tool_name": "Write"})
+		if err != nil || stdout != `{"tool_name":"Write"}` {
+			t.Errorf("runAction() = %q, %v", stdout, err)
+		}
+	})
+
+	t.Run("exit code", func(t *testing.T) {
+		action := Action{Type: "command", Args: []string{"sh", "-c", "exit 3"}}
+		_, _, exitCode, _ := NewActionExecutor(nil).runAction(action, rawJSON)
+		if exitCode != 3 {
+			t.Errorf("exit code = %d, want 3", exitCode)
+		}
+	})
+
+	t.Run("runners without argv support get a command line", func(t *testing.T) {
+		runner := &recordingRunner{}
+		action := Action{Type: "command", Args: []string{"gofmt", "-w", "{.tool_input.file_path}"}, Env: map[string]string{"GOFLAGS": "-mod=mod"}}
+		if _, _, _, err := NewActionExecutor(runner).runAction(action, rawJSON); err != nil {
+			t.Fatal(err)
+		}
+		if want := `env 'GOFLAGS=-mod=mod' 'gofmt' '-w' '/repo/a b.go'`; runner.last != want {
+			t.Errorf("Ran %q, want %q", runner.last, want)
+		}
+	})
+
+	t.Run("runner uses a command line", func(t *testing.T) {
+		runner := &recordingRunner{}
+		action := Action{Type: "command", Args: []string{"go", "vet", "./..."}, Runner: "docker://app"}
+		if _, _, _, err := NewActionExecutor(runner).runAction(action, rawJSON); err != nil {
+			t.Fatal(err)
+		}
+		if want := `'docker' 'exec' '-i' 'app' 'sh' '-c' ` + shellQuote(`'go' 'vet' './...'`); runner.last != want {
+			t.Errorf("Ran %q", runner.last)
+		}
+	})
+}
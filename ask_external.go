@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultAskExternalTimeout is how long an ask_external action waits for a decision without `timeout`.
+// It is below the 60s Claude Code gives a hook by default.
+const defaultAskExternalTimeout = 50 * time.Second
+
+// approvalTokenEnv is the environment variable of the daemon holding the bearer token of the approval endpoint.
+const approvalTokenEnv = "CCHOOK_APPROVAL_TOKEN"
+
+// maxApprovalRequestSize is the upper bound of the body of a decision posted to the approval endpoint.
+const maxApprovalRequestSize = 64 * 1024
+
+// approvalDecision is the decision an external system posts for a pending ask_external action.
+type approvalDecision struct {
+	Decision string `json:"decision"` // allow, deny or ask
+	Reason   string `json:"reason,omitempty"`
+}
+
+// pendingApprovals are the channels of the ask_external actions waiting for a decision, by trace ID.
+// It is nil unless the daemon serves the approval endpoint (serve -approval-listen).
+var (
+	pendingApprovals      map[string]chan approvalDecision
+	pendingApprovalsMutex sync.Mutex
+)
+
+// askExternalTimeout returns how long an ask_external action waits for a decision.
+func askExternalTimeout(action Action) (time.Duration, error) {
+	if action.Timeout == "" {
+		return defaultAskExternalTimeout, nil
+	}
+	timeout, err := time.ParseDuration(action.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %q: %w", action.Timeout, err)
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("invalid timeout %q: must be positive", action.Timeout)
+	}
+	return timeout, nil
+}
+
+// registerApproval makes traceID wait for a decision and returns the channel it arrives on,
+// or nil when no approval endpoint is served.
+func registerApproval(traceID string) chan approvalDecision {
+	pendingApprovalsMutex.Lock()
+	defer pendingApprovalsMutex.Unlock()
+	if pendingApprovals == nil {
+		return nil
+	}
+	approval := make(chan approvalDecision, 1)
+	pendingApprovals[traceID] = approval
+	return approval
+}
+
+// unregisterApproval stops traceID waiting for a decision.
+func unregisterApproval(traceID string) {
+	pendingApprovalsMutex.Lock()
+	defer pendingApprovalsMutex.Unlock()
+	delete(pendingApprovals, traceID)
+}
+
+// resolveApproval passes d to the action waiting with traceID. It reports false when none is waiting.
+func resolveApproval(traceID string, d approvalDecision) bool {
+	pendingApprovalsMutex.Lock()
+	defer pendingApprovalsMutex.Unlock()
+	approval, ok := pendingApprovals[traceID]
+	if !ok {
+		return false
+	}
+	delete(pendingApprovals, traceID)
+	approval <- d
+	return true
+}
+
+// newApprovalHandler returns the endpoint external systems (a Slack button, an approval tool) post the decisions
+// of pending ask_external actions to: POST /approvals/<trace_id> with {"decision": "allow", "reason": "..."}
+// and `Authorization: Bearer <token>`. It doesn't take eventMutex, since the event waiting for the decision holds it.
+func newApprovalHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/approvals/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		var d approvalDecision
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxApprovalRequestSize)).Decode(&d); err != nil {
+			http.Error(w, fmt.Sprintf("invalid decision: %v", err), http.StatusBadRequest)
+			return
+		}
+		if d.Decision != "allow" && d.Decision != "deny" && d.Decision != "ask" {
+			http.Error(w, fmt.Sprintf("invalid decision %q (must be allow, deny or ask)", d.Decision), http.StatusBadRequest)
+			return
+		}
+		traceID := strings.TrimPrefix(r.URL.Path, "/approvals/")
+		if !resolveApproval(traceID, d) {
+			http.Error(w, fmt.Sprintf("no pending approval for trace %q (it may have timed out)", traceID), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+// startApprovalEndpoint serves the approval endpoint on addr until the returned function is called.
+// The bearer token is read from CCHOOK_APPROVAL_TOKEN, since the endpoint is usually reachable from other hosts.
+func startApprovalEndpoint(addr string) (func(), error) {
+	token := os.Getenv(approvalTokenEnv)
+	if token == "" {
+		return nil, fmt.Errorf("-approval-listen requires the token of the approval endpoint in %s", approvalTokenEnv)
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	pendingApprovalsMutex.Lock()
+	pendingApprovals = map[string]chan approvalDecision{}
+	pendingApprovalsMutex.Unlock()
+
+	server := &http.Server{Handler: newApprovalHandler(token), ReadHeaderTimeout: daemonRequestTimeout}
+	fmt.Fprintf(os.Stderr, "cchook: accepting approvals on http://%s/approvals/<trace_id>\n", listener.Addr())
+	go func() { _ = server.Serve(listener) }()
+	return func() {
+		_ = server.Close()
+		pendingApprovalsMutex.Lock()
+		pendingApprovals = nil
+		pendingApprovalsMutex.Unlock()
+	}, nil
+}
+
+// askExternalInput returns rawJSON with the trace ID of the event as trace_id, for the templates of an ask_external action.
+func askExternalInput(rawJSON any) any {
+	m, ok := rawJSON.(map[string]any)
+	if !ok {
+		return rawJSON
+	}
+	input := maps.Clone(m)
+	input["trace_id"] = eventTraceID
+	return input
+}
+
+// askExternalBody returns the body of the notification of an ask_external action: its templated body,
+// or the trace ID, the tool call (with its secrets redacted) and the message.
+func askExternalBody(action Action, input any, message string) ([]byte, error) {
+	if action.Body != nil {
+		return httpActionBody(action, input)
+	}
+	m, _ := input.(map[string]any)
+	toolInput, err := json.Marshal(m["tool_input"])
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]any{
+		"trace_id":   eventTraceID,
+		"session_id": m["session_id"],
+		"cwd":        m["cwd"],
+		"tool_name":  m["tool_name"],
+		"tool_input": redactSecretsJSON(toolInput),
+		"message":    message,
+	})
+}
+
+// notifyAskExternal posts the pending tool call of an ask_external action to its url.
+func (e *ActionExecutor) notifyAskExternal(action Action, input any, message string) error {
+	start := time.Now()
+	notification := Action{Type: "http", URL: action.URL, Method: action.Method, Headers: action.Headers}
+	exitCode := 1
+	var err error
+	defer func() { logAction(notification, input, exitCode, time.Since(start), err) }()
+
+	body, err := askExternalBody(action, input, message)
+	if err != nil {
+		return fmt.Errorf("failed to encode body: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultHTTPTimeout)
+	defer cancel()
+	req, err := newHTTPRequest(ctx, notification, input, body)
+	if err != nil {
+		return err
+	}
+	var stderr string
+	_, stderr, exitCode, err = doHTTPRequest(e.httpClient, req)
+	if err == nil && exitCode != 0 {
+		err = fmt.Errorf("%s", stderr)
+	}
+	return err
+}
+
+// runAskExternal asks an external system for the decision of a PreToolUse event: the tool call is posted to url
+// with the trace ID, and the action waits for a decision posted to the approval endpoint of the daemon.
+// Without the endpoint, or when no decision arrives within the timeout, permission_decision (ask by default) applies.
+func (e *ActionExecutor) runAskExternal(action Action, rawJSON any) *ActionOutput {
+	input := askExternalInput(rawJSON)
+	message := unifiedTemplateReplace(action.Message, input)
+	fallback := func(why string) *ActionOutput {
+		fmt.Fprintf(os.Stderr, "Warning: ask_external: %s\n", why)
+		decision := "ask"
+		if action.PermissionDecision != nil && *action.PermissionDecision != "" {
+			decision = *action.PermissionDecision
+		}
+		reason := "No external decision: " + why
+		if message != "" {
+			reason = message + "\n" + reason
+		}
+		return &ActionOutput{
+			Continue:                 true,
+			PermissionDecision:       decision,
+			HookEventName:            "PreToolUse",
+			PermissionDecisionReason: reason,
+		}
+	}
+
+	timeout, err := askExternalTimeout(action)
+	if err != nil {
+		return fallback(err.Error())
+	}
+	approval := registerApproval(eventTraceID)
+	if approval == nil {
+		return fallback("no approval endpoint (run the hook with -client and `cchook -command serve -approval-listen <addr>`)")
+	}
+	defer unregisterApproval(eventTraceID)
+
+	if err := e.notifyAskExternal(action, input, message); err != nil {
+		return fallback(fmt.Sprintf("failed to notify: %v", err))
+	}
+	hookLog.Info("waiting for approval", "timeout_ms", timeout.Milliseconds())
+	select {
+	case d := <-approval:
+		hookLog.Info("approval received", "decision", d.Decision)
+		reason := d.Reason
+		if reason == "" {
+			reason = fmt.Sprintf("External approval: %s", d.Decision)
+		}
+		return &ActionOutput{
+			Continue:                 true,
+			PermissionDecision:       d.Decision,
+			HookEventName:            "PreToolUse",
+			PermissionDecisionReason: reason,
+		}
+	case <-time.After(timeout):
+		return fallback(fmt.Sprintf("no decision within %s", timeout))
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// enableTestApprovals makes ask_external actions wait for decisions until the test ends, with the given trace ID.
+func enableTestApprovals(t *testing.T, traceID string) {
+	t.Helper()
+	origTraceID := eventTraceID
+	eventTraceID = traceID
+	pendingApprovalsMutex.Lock()
+	pendingApprovals = map[string]chan approvalDecision{}
+	pendingApprovalsMutex.Unlock()
+	t.Cleanup(func() {
+		eventTraceID = origTraceID
+		pendingApprovalsMutex.Lock()
+		pendingApprovals = nil
+		pendingApprovalsMutex.Unlock()
+	})
+}
+
+func postApproval(t *testing.T, url, token, body string) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST %s error = %v", url, err)
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestRunAskExternal_Approved(t *testing.T) {
+	enableTestApprovals(t, "trace-1")
+	approvals := httptest.NewServer(newApprovalHandler("secret"))
+	defer approvals.Close()
+
+	notified := make(chan map[string]any, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		notified <- payload
+	}))
+	defer webhook.Close()
+
+	go func() {
+		payload := <-notified
+		postApproval(t, approvals.URL+"/approvals/"+payload["trace_id"].(string), "secret", `{"decision":"allow","reason":"approved by alice"}`)
+	}()
+
+	action := Action{Type: "ask_external", URL: webhook.URL, Message: "Run {.tool_input.command}?", Timeout: "5s"}
+	rawJSON := map[string]any{"tool_name": "Bash", "tool_input": map[string]any{"command": "make deploy"}}
+	output, err := NewActionExecutor(nil).ExecutePreToolUseAction(action, &PreToolUseInput{ToolName: "Bash"}, rawJSON)
+	if err != nil {
+		t.Fatalf("ExecutePreToolUseAction() error = %v", err)
+	}
+	if output.PermissionDecision != "allow" || output.PermissionDecisionReason != "approved by alice" {
+		t.Errorf("Expected the external decision, got %+v", output)
+	}
+	if _, pending := pendingApprovals["trace-1"]; pending {
+		t.Error("Expected the approval to be unregistered")
+	}
+}
+
+func TestRunAskExternal_NotificationBody(t *testing.T) {
+	enableTestApprovals(t, "trace-2")
+	var payload map[string]any
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		resolveApproval("trace-2", approvalDecision{Decision: "deny"})
+	}))
+	defer webhook.Close()
+
+	action := Action{Type: "ask_external", URL: webhook.URL, Message: "Run {.tool_input.command}?"}
+	rawJSON := map[string]any{"session_id": "s1", "tool_name": "Bash", "tool_input": map[string]any{"command": "curl -u " + testAWSAccessKeyID + " x"}}
+	output, err := NewActionExecutor(nil).ExecutePreToolUseAction(action, &PreToolUseInput{ToolName: "Bash"}, rawJSON)
+	if err != nil {
+		t.Fatalf("ExecutePreToolUseAction() error = %v", err)
+	}
+	if output.PermissionDecision != "deny" || output.PermissionDecisionReason != "External approval: deny" {
+		t.Errorf("Expected the external decision, got %+v", output)
+	}
+	if payload["trace_id"] != "trace-2" || payload["session_id"] != "s1" || !strings.HasPrefix(payload["message"].(string), "Run curl") {
+		t.Errorf("Unexpected notification: %v", payload)
+	}
+	if command := payload["tool_input"].(map[string]any)["command"].(string); strings.Contains(command, testAWSAccessKeyID) {
+		t.Errorf("Expected the secrets of tool_input to be redacted, got %q", command)
+	}
+}
+
+func TestRunAskExternal_Fallback(t *testing.T) {
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer webhook.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+	deny := "deny"
+
+	tests := []struct {
+		name      string
+		endpoint  bool
+		action    Action
+		decision  string
+		wantInMsg string
+	}{
+		{
+			name:      "no approval endpoint",
+			action:    Action{Type: "ask_external", URL: webhook.URL},
+			decision:  "ask",
+			wantInMsg: "no approval endpoint",
+		},
+		{
+			name:      "timed out",
+			endpoint:  true,
+			action:    Action{Type: "ask_external", URL: webhook.URL, Timeout: "50ms", PermissionDecision: &deny, Message: "Deploy?"},
+			decision:  "deny",
+			wantInMsg: "Deploy?\nNo external decision: no decision within 50ms",
+		},
+		{
+			name:      "notification failed",
+			endpoint:  true,
+			action:    Action{Type: "ask_external", URL: failing.URL},
+			decision:  "ask",
+			wantInMsg: "failed to notify",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.endpoint {
+				enableTestApprovals(t, "trace-3")
+			}
+			start := time.Now()
+			output, err := NewActionExecutor(nil).ExecutePreToolUseAction(tt.action, &PreToolUseInput{ToolName: "Bash"}, map[string]any{})
+			if err != nil {
+				t.Fatalf("ExecutePreToolUseAction() error = %v", err)
+			}
+			if output.PermissionDecision != tt.decision || !strings.Contains(output.PermissionDecisionReason, tt.wantInMsg) {
+				t.Errorf("Expected %s with %q, got %+v", tt.decision, tt.wantInMsg, output)
+			}
+			if time.Since(start) > 5*time.Second {
+				t.Error("Expected the fallback not to wait for the default timeout")
+			}
+		})
+	}
+}
+
+func TestApprovalHandler(t *testing.T) {
+	enableTestApprovals(t, "")
+	server := httptest.NewServer(newApprovalHandler("secret"))
+	defer server.Close()
+
+	approval := registerApproval("pending")
+	tests := []struct {
+		name  string
+		path  string
+		token string
+		body  string
+		want  int
+	}{
+		{name: "invalid token", path: "/approvals/pending", token: "wrong", body: `{"decision":"allow"}`, want: http.StatusUnauthorized},
+		{name: "invalid decision", path: "/approvals/pending", token: "secret", body: `{"decision":"maybe"}`, want: http.StatusBadRequest},
+		{name: "invalid JSON", path: "/approvals/pending", token: "secret", body: `{`, want: http.StatusBadRequest},
+		{name: "unknown trace", path: "/approvals/unknown", token: "secret", body: `{"decision":"allow"}`, want: http.StatusNotFound},
+		{name: "accepted", path: "/approvals/pending", token: "secret", body: `{"decision":"deny","reason":"not now"}`, want: http.StatusNoContent},
+		{name: "already decided", path: "/approvals/pending", token: "secret", body: `{"decision":"allow"}`, want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := postApproval(t, server.URL+tt.path, tt.token, tt.body); got != tt.want {
+				t.Errorf("Status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+	if d := <-approval; d.Decision != "deny" || d.Reason != "not now" {
+		t.Errorf("Expected the posted decision, got %+v", d)
+	}
+
+	resp, err := http.Get(server.URL + "/approvals/pending")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %d, want 405", resp.StatusCode)
+	}
+}
+
+func TestStartApprovalEndpoint_RequiresToken(t *testing.T) {
+	t.Setenv(approvalTokenEnv, "")
+	if _, err := startApprovalEndpoint("127.0.0.1:0"); err == nil || !strings.Contains(err.Error(), approvalTokenEnv) {
+		t.Errorf("Expected the missing token to be reported, got %v", err)
+	}
+}
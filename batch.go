@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultBatchQuietPeriod is how long a `batch: true` hook waits without new events before it runs.
+const defaultBatchQuietPeriod = 2 * time.Second
+
+// Files of a batch queue directory.
+const (
+	batchHookFile    = "hook.yaml"    // バッチ対象のフック定義
+	batchEventsFile  = "events.jsonl" // 溜まっているイベント (1行1イベント)
+	batchQueueLock   = "queue.lock"   // events.jsonlの読み書きの排他
+	batchFlusherLock = "flusher.lock" // フラッシャーは1キューにつき1プロセス
+)
+
+// batchQueueHook is the content of hook.yaml: the hook a queue belongs to.
+type batchQueueHook struct {
+	Index int             `yaml:"index"` // 1始まりのフック番号 (最後にキューに積んだ時点の設定)
+	Hook  PostToolUseHook `yaml:"hook"`
+}
+
+// batchDir returns the directory holding the queues of batch hooks.
+// It is a variable so that tests can use a temporary directory.
+var batchDir = func() string {
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "cchook", "batches")
+	}
+	return filepath.Join(os.TempDir(), "cchook-batches")
+}
+
+// startBatchFlusher starts a background cchook process that flushes the queue in dir.
+// It is a variable so that tests can flush in-process.
+var startBatchFlusher = func(dir string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(exe, "-command", "batch-flush", "-batch-dir", dir)
+	detachProcess(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
+
+// batchQuietPeriod returns the quiet period of a batch hook.
+func batchQuietPeriod(hook PostToolUseHook) (time.Duration, error) {
+	if hook.BatchQuietPeriod == "" {
+		return defaultBatchQuietPeriod, nil
+	}
+	d, err := time.ParseDuration(hook.BatchQuietPeriod)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid batch_quiet_period %q (must be a positive duration such as \"2s\")", hook.BatchQuietPeriod)
+	}
+	return d, nil
+}
+
+// enqueueBatchEvent adds a PostToolUse event to the queue of a batch hook and makes sure a flusher is running.
+// Each session, working directory and hook definition has its own queue.
+// During a diagnostic run the event is neither queued nor flushed, since the flusher would run the real commands.
+func enqueueBatchEvent(index int, hook PostToolUseHook, input *PostToolUseInput, rawJSON any) error {
+	if diagnosticRun {
+		return nil
+	}
+	hookYAML, err := yaml.Marshal(hook)
+	if err != nil {
+		return fmt.Errorf("failed to encode batch hook: %w", err)
+	}
+	queueHook, err := yaml.Marshal(batchQueueHook{Index: index, Hook: hook})
+	if err != nil {
+		return fmt.Errorf("failed to encode batch hook: %w", err)
+	}
+	event, err := json.Marshal(rawJSON)
+	if err != nil {
+		return fmt.Errorf("failed to encode batch event: %w", err)
+	}
+	sum := sha256.Sum256([]byte(input.SessionID + "\x00" + input.Cwd + "\x00" + string(hookYAML)))
+	dir := filepath.Join(batchDir(), hex.EncodeToString(sum[:16]))
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create batch queue: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, batchHookFile), queueHook, 0o600); err != nil {
+		return fmt.Errorf("failed to write batch hook: %w", err)
+	}
+
+	unlock, err := lockFile(filepath.Join(dir, batchQueueLock))
+	if err != nil {
+		return fmt.Errorf("failed to lock batch queue: %w", err)
+	}
+	err = appendLine(filepath.Join(dir, batchEventsFile), event)
+	unlock()
+	if err != nil {
+		return fmt.Errorf("failed to queue batch event: %w", err)
+	}
+
+	// 動いているフラッシャーがあれば、そのフラッシャーが(終了前の再確認で)このイベントを拾う
+	release, ok, err := tryLockFile(filepath.Join(dir, batchFlusherLock))
+	if err != nil {
+		return fmt.Errorf("failed to check batch flusher: %w", err)
+	}
+	if !ok {
+		return nil
+	}
+	release()
+	if err := startBatchFlusher(dir); err != nil {
+		return fmt.Errorf("failed to start batch flusher: %w", err)
+	}
+	return nil
+}
+
+// appendLine appends data and a newline to the file at path.
+func appendLine(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// runBatchFlush is the `batch-flush` command: it waits until the queue in dir has been quiet for the hook's
+// quiet period, runs the hook once with the aggregated events, and repeats while events keep coming.
+// Only one flusher runs per queue; a second one exits immediately.
+func runBatchFlush(dir string) error {
+	data, err := os.ReadFile(filepath.Join(dir, batchHookFile))
+	if err != nil {
+		return fmt.Errorf("failed to read batch hook: %w", err)
+	}
+	var queueHook batchQueueHook
+	if err := yaml.Unmarshal(data, &queueHook); err != nil {
+		return fmt.Errorf("failed to parse batch hook: %w", err)
+	}
+	quiet, err := batchQuietPeriod(queueHook.Hook)
+	if err != nil {
+		return err
+	}
+
+	eventsPath := filepath.Join(dir, batchEventsFile)
+	for {
+		release, ok, err := tryLockFile(filepath.Join(dir, batchFlusherLock))
+		if err != nil || !ok {
+			return err
+		}
+		for {
+			events, err := takeQuietBatch(dir, quiet)
+			if err != nil {
+				release()
+				return err
+			}
+			if len(events) == 0 {
+				break
+			}
+			runBatchHook(queueHook.Index, queueHook.Hook, events)
+		}
+		release()
+
+		// ロックを手放す直前に積まれたイベントは、enqueueBatchEventがフラッシャーを起動しないので自分で拾う
+		if _, err := os.Stat(eventsPath); err != nil {
+			return nil
+		}
+	}
+}
+
+// takeQuietBatch waits until no event has been queued for quiet, then removes the queued events and returns them.
+// It returns nil if the queue is empty.
+func takeQuietBatch(dir string, quiet time.Duration) ([]map[string]any, error) {
+	eventsPath := filepath.Join(dir, batchEventsFile)
+	for {
+		info, err := os.Stat(eventsPath)
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if idle := time.Since(info.ModTime()); idle < quiet {
+			time.Sleep(quiet - idle)
+			continue
+		}
+
+		unlock, err := lockFile(filepath.Join(dir, batchQueueLock))
+		if err != nil {
+			return nil, err
+		}
+		// 待っている間に積まれていたらもう一度待つ
+		if current, err := os.Stat(eventsPath); err == nil && !current.ModTime().Equal(info.ModTime()) {
+			unlock()
+			continue
+		}
+		data, err := os.ReadFile(eventsPath)
+		if err == nil {
+			err = os.Remove(eventsPath)
+		}
+		unlock()
+		if err != nil {
+			return nil, err
+		}
+
+		var events []map[string]any
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+		for scanner.Scan() {
+			var event map[string]any
+			if json.Unmarshal(scanner.Bytes(), &event) == nil {
+				events = append(events, event)
+			}
+		}
+		return events, nil
+	}
+}
+
+// batchInput returns the input of a batch run: the last event with a `batch` object listing the events,
+// the unique file paths (tool_input.file_path, absolute) and the unique Bash commands, in order.
+func batchInput(events []map[string]any) map[string]any {
+	files := []any{}
+	commands := []any{}
+	list := make([]any, 0, len(events))
+	for _, event := range events {
+		list = append(list, event)
+		if filePath := snapshotFilePath(event); filePath != "" && !slices.Contains(files, any(filePath)) {
+			files = append(files, filePath)
+		}
+		toolInput, _ := event["tool_input"].(map[string]any)
+		if command, _ := toolInput["command"].(string); command != "" && !slices.Contains(commands, any(command)) {
+			commands = append(commands, command)
+		}
+	}
+
+	input := map[string]any{}
+	for k, v := range events[len(events)-1] {
+		input[k] = v
+	}
+	input["batch"] = map[string]any{
+		"count":    len(events),
+		"files":    files,
+		"commands": commands,
+		"events":   list,
+	}
+	return input
+}
+
+// runBatchHook runs the actions of a batch hook once for events. Nobody waits for the output of a batch run,
+// so what the actions report to Claude (a block reason or additionalContext) is kept for the session
+// and delivered with its next PostToolUse event. Files the actions changed are recorded like other hook changes.
+func runBatchHook(index int, hook PostToolUseHook, events []map[string]any) {
+	rawJSON := batchInput(events)
+	data, err := json.Marshal(rawJSON)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to encode batch input: %v\n", err)
+		return
+	}
+	input, rawAny, err := parseInputFrom[*PostToolUseInput](bytes.NewReader(data), PostToolUse)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to parse batch input: %v\n", err)
+		return
+	}
+
+	batch, _ := rawJSON["batch"].(map[string]any)
+	files, _ := batch["files"].([]any)
+	hashes := map[string][32]byte{}
+	for _, file := range files {
+		if hash, ok := fileContentHash(file.(string)); ok {
+			hashes[file.(string)] = hash
+		}
+	}
+
+	var messages []string
+	executor := NewActionExecutor(nil).withMutex(hook.Mutex).withEnvFrom(hook.EnvFrom)
+	for _, action := range hook.Actions {
+		output, err := executor.ExecutePostToolUseAction(action, input, rawAny)
+		if err != nil {
+			messages = append(messages, err.Error())
+			continue
+		}
+		if output == nil {
+			continue
+		}
+		if output.Decision == "block" && output.Reason != "" {
+			messages = append(messages, output.Reason)
+		} else if output.AdditionalContext != "" {
+			messages = append(messages, output.AdditionalContext)
+		}
+	}
+
+	for file, before := range hashes {
+		if after, exists := fileContentHash(file); !exists || after != before {
+			change := hookChange{Time: time.Now(), File: file, Event: string(PostToolUse), Hook: index, Commands: hookCommands(hook.Actions)}
+			if err := recordHookChange(input.SessionID, change); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+	}
+
+	if len(messages) > 0 && input.SessionID != "" {
+		message := fmt.Sprintf("Batched PostToolUse hook %d (%d events):\n%s", index, len(events), strings.Join(messages, "\n"))
+		data, _ := json.Marshal(message)
+		if err := os.MkdirAll(sessionStateDir(), 0o700); err == nil {
+			_ = appendLine(sessionStatePath(input.SessionID, ".batch-results.jsonl"), data)
+		}
+	}
+}
+
+// takeBatchResults returns and removes the messages of batch runs kept for the session.
+func takeBatchResults(sessionID string) []string {
+	if sessionID == "" {
+		return nil
+	}
+	path := sessionStatePath(sessionID, ".batch-results.jsonl")
+	taken := path + fmt.Sprintf(".%d", os.Getpid())
+	// renameで取り出すので、同時に動くイベントが同じ結果を二重に受け取らない
+	if err := os.Rename(path, taken); err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(taken)
+	_ = os.Remove(taken)
+	if err != nil {
+		return nil
+	}
+	var messages []string
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		var message string
+		if json.Unmarshal(line, &message) == nil && message != "" {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+import "os/exec"
+
+// detachProcess is a no-op on platforms without sessions; the process still outlives the hook.
+func detachProcess(cmd *exec.Cmd) {}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// withBatchDir makes batch queues use a temporary directory and records the flushers that would be started.
+func withBatchDir(t *testing.T) *[]string {
+	t.Helper()
+	dir := t.TempDir()
+	originalDir, originalStart := batchDir, startBatchFlusher
+	var started []string
+	batchDir = func() string { return dir }
+	startBatchFlusher = func(dir string) error {
+		started = append(started, dir)
+		return nil
+	}
+	t.Cleanup(func() { batchDir, startBatchFlusher = originalDir, originalStart })
+	return &started
+}
+
+func TestBatchInput(t *testing.T) {
+	events := []map[string]any{
+		{"cwd": "/repo", "tool_name": "Edit", "tool_input": map[string]any{"file_path": "a.go"}},
+		{"cwd": "/repo", "tool_name": "Bash", "tool_input": map[string]any{"command": "go test ./..."}},
+		{"cwd": "/repo", "tool_name": "Write", "tool_input": map[string]any{"file_path": "/repo/b.go"}},
+		{"cwd": "/repo", "tool_name": "Edit", "tool_input": map[string]any{"file_path": "/repo/a.go"}},
+	}
+	input := batchInput(events)
+
+	if input["tool_name"] != "Edit" {
+		t.Errorf("tool_name = %v, want the last event's", input["tool_name"])
+	}
+	batch := input["batch"].(map[string]any)
+	if batch["count"] != 4 {
+		t.Errorf("count = %v, want 4", batch["count"])
+	}
+	if want := []any{"/repo/a.go", "/repo/b.go"}; !reflect.DeepEqual(batch["files"], want) {
+		t.Errorf("files = %v, want %v", batch["files"], want)
+	}
+	if want := []any{"go test ./..."}; !reflect.DeepEqual(batch["commands"], want) {
+		t.Errorf("commands = %v, want %v", batch["commands"], want)
+	}
+	if len(batch["events"].([]any)) != 4 {
+		t.Errorf("events = %v, want 4 events", batch["events"])
+	}
+}
+
+func TestExecutePostToolUseHooks_Batch(t *testing.T) {
+	started := withBatchDir(t)
+	withSessionStateDir(t)
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "runs.log")
+
+	config := &Config{PostToolUse: []PostToolUseHook{{
+		Matcher:          "Write|Edit",
+		Batch:            true,
+		BatchQuietPeriod: "10ms",
+		Actions: []Action{{
+			Type:    "command",
+			Command: "echo '{.batch.count} {.batch.files | join(\" \")}' >> " + logPath,
+		}},
+	}}}
+
+	for _, file := range []string{"a.go", "b.go", "a.go"} {
+		input := &PostToolUseInput{
+			BaseInput: BaseInput{SessionID: "session-1", Cwd: dir},
+			ToolName:  "Edit",
+			ToolInput: ToolInput{FilePath: file},
+		}
+		rawJSON := map[string]any{"session_id": "session-1", "cwd": dir, "tool_name": "Edit", "tool_input": map[string]any{"file_path": file}}
+		if _, err := executePostToolUseHooksJSON(config, input, rawJSON); err != nil {
+			t.Fatalf("executePostToolUseHooksJSON() error = %v", err)
+		}
+	}
+
+	// イベント毎にはアクションを実行しない
+	if _, err := os.Stat(logPath); !os.IsNotExist(err) {
+		t.Fatalf("batch hook ran per event")
+	}
+	if len(*started) == 0 {
+		t.Fatal("no batch flusher was started")
+	}
+	queue := (*started)[0]
+	for _, dir := range *started {
+		if dir != queue {
+			t.Fatalf("events were queued to different queues: %v", *started)
+		}
+	}
+
+	if err := runBatchFlush(queue); err != nil {
+		t.Fatalf("runBatchFlush() error = %v", err)
+	}
+	got, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "3 " + filepath.Join(dir, "a.go") + " " + filepath.Join(dir, "b.go") + "\n"; string(got) != want {
+		t.Errorf("batch run = %q, want %q", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(queue, batchEventsFile)); !os.IsNotExist(err) {
+		t.Errorf("queued events were not removed after the batch run")
+	}
+}
+
+func TestExplainOutput_BatchNotQueued(t *testing.T) {
+	started := withBatchDir(t)
+	withSessionStateDir(t)
+	config := &Config{PostToolUse: []PostToolUseHook{{
+		Matcher: "Edit",
+		Batch:   true,
+		Actions: []Action{{Type: "command", Command: "touch {.tool_input.file_path}.ran"}},
+	}}}
+	data := []byte(`{"session_id":"session-1","cwd":"/repo","hook_event_name":"PostToolUse","tool_name":"Edit","tool_input":{"file_path":"a.go"}}`)
+
+	if _, err := explainOutput(data, config, PostToolUse); err != nil {
+		t.Fatalf("explainOutput() error = %v", err)
+	}
+	if len(*started) != 0 {
+		t.Errorf("explain started batch flushers: %v", *started)
+	}
+	if entries, _ := os.ReadDir(batchDir()); len(entries) != 0 {
+		t.Errorf("explain queued batch events: %v", entries)
+	}
+}
+
+func TestEnqueueBatchEvent_FlusherRunning(t *testing.T) {
+	started := withBatchDir(t)
+	hook := PostToolUseHook{Matcher: "Edit", Batch: true, Actions: []Action{{Type: "command", Command: "true"}}}
+	input := &PostToolUseInput{BaseInput: BaseInput{SessionID: "session-1", Cwd: "/repo"}}
+
+	if err := enqueueBatchEvent(1, hook, input, map[string]any{"cwd": "/repo"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(*started) != 1 {
+		t.Fatalf("started flushers = %v, want 1", *started)
+	}
+
+	// フラッシャーが動いている間は新しく起動しない
+	release, ok, err := tryLockFile(filepath.Join((*started)[0], batchFlusherLock))
+	if err != nil || !ok {
+		t.Fatalf("tryLockFile() = %v, %v", ok, err)
+	}
+	defer release()
+	if err := enqueueBatchEvent(1, hook, input, map[string]any{"cwd": "/repo"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(*started) != 1 {
+		t.Errorf("started flushers = %v, want no new flusher while one is running", *started)
+	}
+}
+
+func TestBatchResultsDeliveredWithNextEvent(t *testing.T) {
+	started := withBatchDir(t)
+	withSessionStateDir(t)
+	dir := t.TempDir()
+
+	config := &Config{PostToolUse: []PostToolUseHook{{
+		Matcher:          "Edit",
+		Batch:            true,
+		BatchQuietPeriod: "10ms",
+		Actions:          []Action{{Type: "command", Command: "echo 'lint failed' >&2; exit 1"}},
+	}}}
+	input := &PostToolUseInput{
+		BaseInput: BaseInput{SessionID: "session-1", Cwd: dir},
+		ToolName:  "Edit",
+		ToolInput: ToolInput{FilePath: "a.go"},
+	}
+	rawJSON := map[string]any{"session_id": "session-1", "cwd": dir, "tool_name": "Edit", "tool_input": map[string]any{"file_path": "a.go"}}
+
+	output, err := executePostToolUseHooksJSON(config, input, rawJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.HookSpecificOutput != nil {
+		t.Errorf("first event output = %+v, want no context", output.HookSpecificOutput)
+	}
+	if err := runBatchFlush((*started)[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	// 失敗は次のPostToolUseイベントでClaudeに届く（1回だけ）
+	output, err = executePostToolUseHooksJSON(&Config{}, input, rawJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.HookSpecificOutput == nil || !strings.Contains(output.HookSpecificOutput.AdditionalContext, "Batched PostToolUse hook 1 (1 events):") ||
+		!strings.Contains(output.HookSpecificOutput.AdditionalContext, "lint failed") {
+		t.Fatalf("next event output = %+v, want the batch result", output.HookSpecificOutput)
+	}
+	output, err = executePostToolUseHooksJSON(&Config{}, input, rawJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.HookSpecificOutput != nil {
+		t.Errorf("batch result was delivered twice: %+v", output.HookSpecificOutput)
+	}
+}
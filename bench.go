@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"time"
+)
+
+// defaultBenchIterations is the number of times `cchook -command bench` evaluates the event without -iterations.
+const defaultBenchIterations = 100
+
+// benchTimings collects the durations of one measured step over the iterations.
+type benchTimings []time.Duration
+
+// mean returns the average duration (0 if the step never ran).
+func (t benchTimings) mean() time.Duration {
+	if len(t) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, d := range t {
+		total += d
+	}
+	return total / time.Duration(len(t))
+}
+
+// percentile returns the duration below which p percent of the runs finished.
+func (t benchTimings) percentile(p int) time.Duration {
+	if len(t) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(t)
+	slices.Sort(sorted)
+	return sorted[(len(sorted)-1)*p/100]
+}
+
+// String formats the mean and p95 of the timings.
+func (t benchTimings) String() string {
+	return fmt.Sprintf("mean %s, p95 %s", formatBenchDuration(t.mean()), formatBenchDuration(t.percentile(95)))
+}
+
+// formatBenchDuration rounds d to 3 significant digits, so that the breakdown stays readable.
+func formatBenchDuration(d time.Duration) string {
+	for unit := time.Duration(1); unit < time.Second; unit *= 10 {
+		if d < 1000*unit {
+			return d.Round(unit).String()
+		}
+	}
+	return d.Round(10 * time.Millisecond).String()
+}
+
+// hookBench is the breakdown of a single hook.
+type hookBench struct {
+	trace      hookTrace      // trace of the last iteration
+	conditions []benchTimings // by condition index; only the iterations that evaluated the condition
+	templates  benchTimings   // template expansion of the actions, in the iterations the hook ran
+}
+
+// expandActionTemplates expands the templated fields of an action like the executors do before running it,
+// without running anything.
+func expandActionTemplates(action Action, rawJSON any) {
+	switch action.Type {
+	case "command":
+		actionCommand(action, rawJSON)
+	case "http":
+		unifiedTemplateReplace(action.URL, rawJSON)
+		for _, value := range action.Headers {
+			unifiedTemplateReplace(value, rawJSON)
+		}
+		_, _ = httpActionBody(action, rawJSON)
+	}
+	unifiedTemplateReplace(action.Message, rawJSON)
+	for _, field := range []*string{action.Reason, action.AdditionalContext, action.SystemMessage, action.ModelHint, action.SuggestCommand} {
+		if field != nil {
+			unifiedTemplateReplace(*field, rawJSON)
+		}
+	}
+}
+
+// hookActions returns the actions of each hook of eventType.
+func hookActions(config *Config, eventType HookEventType) [][]Action {
+	hooks, n := configHooksForEvent(config, eventType)
+	v := reflect.ValueOf(hooks)
+	actions := make([][]Action, n)
+	for i := range n {
+		actions[i] = v.Index(i).FieldByName("Actions").Interface().([]Action)
+	}
+	return actions
+}
+
+// runBench evaluates the event read from r against the hooks of eventType the given number of times and writes
+// the latency of parsing the input, of each condition and of expanding the templates of the hooks that run.
+// Actions are not run, so the numbers are cchook's own overhead.
+func runBench(w io.Writer, r io.Reader, config *Config, eventType HookEventType, iterations int) error {
+	if iterations < 1 {
+		return fmt.Errorf("invalid -iterations %d: must be at least 1", iterations)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+	var rawJSON any
+	if err := json.Unmarshal(data, &rawJSON); err != nil {
+		return fmt.Errorf("failed to parse raw JSON: %w", err)
+	}
+
+	actions := hookActions(config, eventType)
+	hooks := make([]hookBench, len(actions))
+	var parse, total benchTimings
+	for range iterations {
+		start := time.Now()
+		// フックの無い設定で評価すると入力のパースだけを測れる
+		if _, err := traceHooks(bytes.NewReader(data), &Config{}, eventType); err != nil {
+			return err
+		}
+		parse = append(parse, time.Since(start))
+
+		// 入力のパース・マッチャー・条件・テンプレート展開の合計
+		start = time.Now()
+		evaluated := make([]int, len(hooks))
+		traces, err := traceHooksWith(bytes.NewReader(data), config, eventType, func(hook int, check conditionCheck) conditionCheck {
+			return func(c Condition) (bool, error) {
+				conditionStart := time.Now()
+				matched, err := check(c)
+				index := evaluated[hook]
+				if index == len(hooks[hook].conditions) {
+					hooks[hook].conditions = append(hooks[hook].conditions, nil)
+				}
+				hooks[hook].conditions[index] = append(hooks[hook].conditions[index], time.Since(conditionStart))
+				evaluated[hook]++
+				return matched, err
+			}
+		})
+		if err != nil {
+			return err
+		}
+		for i, trace := range traces {
+			hooks[i].trace = trace
+			if !trace.runs() {
+				continue
+			}
+			templateStart := time.Now()
+			for _, action := range actions[i] {
+				expandActionTemplates(action, rawJSON)
+			}
+			hooks[i].templates = append(hooks[i].templates, time.Since(templateStart))
+		}
+		total = append(total, time.Since(start))
+	}
+
+	fmt.Fprintf(w, "=== %s Hooks (Bench, %d iterations) ===\n", eventType, iterations)
+	fmt.Fprintf(w, "Parse input: %s\n", parse)
+	if len(hooks) == 0 {
+		fmt.Fprintf(w, "No %s hooks configured\n", eventType)
+	}
+
+	var slowest string
+	var slowestMean time.Duration
+	for i, hook := range hooks {
+		fmt.Fprintf(w, "[Hook %d]", i+1)
+		if hook.trace.matcher != "" {
+			fmt.Fprintf(w, " %s", hook.trace.matcher)
+		}
+		fmt.Fprintln(w)
+		for j, condition := range hook.trace.conditions {
+			label := conditionLabel(condition.condition)
+			if j >= len(hook.conditions) {
+				fmt.Fprintf(w, "  Condition %d (%s): not evaluated\n", j+1, label)
+				continue
+			}
+			timings := hook.conditions[j]
+			fmt.Fprintf(w, "  Condition %d (%s): %s (evaluated %d/%d)\n", j+1, label, timings, len(timings), iterations)
+			if mean := timings.mean(); mean > slowestMean {
+				slowest, slowestMean = fmt.Sprintf("Hook %d condition %d (%s)", i+1, j+1, condition.condition.Type), mean
+			}
+		}
+		if len(hook.templates) > 0 {
+			fmt.Fprintf(w, "  Templates (%d action(s)): %s\n", len(actions[i]), hook.templates)
+		} else {
+			fmt.Fprintln(w, "  Templates: not expanded (the hook does not run)")
+		}
+	}
+	fmt.Fprintf(w, "Total: %s\n", total)
+	if slowest != "" {
+		fmt.Fprintf(w, "Slowest condition: %s, mean %s\n", slowest, formatBenchDuration(slowestMean))
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunBench(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "marker")
+	config := &Config{
+		PreToolUse: []PreToolUseHook{
+			{
+				Matcher: "Write",
+				Conditions: []Condition{
+					{Type: ConditionFileExtension, Value: ".go"},
+					{Type: ConditionFileExtension, Value: ".py"},
+				},
+				Actions: []Action{{Type: "command", Command: "touch " + marker}},
+			},
+			{
+				Matcher:    "Write",
+				Conditions: []Condition{{Type: ConditionFileExtension, Value: ".py"}},
+				Actions: []Action{
+					{Type: "command", Command: "touch " + marker},
+					{Type: "output", Message: "writing {.tool_input.file_path}"},
+				},
+			},
+			{
+				Matcher:    "Bash",
+				Conditions: []Condition{{Type: ConditionFileExtension, Value: ".py"}},
+				Actions:    []Action{{Type: "output", Message: "bash"}},
+			},
+		},
+	}
+	input := `{"session_id":"s","tool_name":"Write","tool_input":{"file_path":"main.py","content":""}}`
+
+	var buf bytes.Buffer
+	if err := runBench(&buf, strings.NewReader(input), config, PreToolUse, 5); err != nil {
+		t.Fatalf("runBench() error = %v", err)
+	}
+	// 時間は実行毎に変わるので伏せて比較する
+	got := regexp.MustCompile(`mean [^,]+, p95 \S+`).ReplaceAllString(buf.String(), "mean X, p95 X")
+	got = regexp.MustCompile(`(Slowest condition: .*), mean \S+`).ReplaceAllString(got, "$1, mean X")
+	want := `=== PreToolUse Hooks (Bench, 5 iterations) ===
+Parse input: mean X, p95 X
+[Hook 1] matcher "Write" matched tool_name "Write"
+  Condition 1 (file_extension ".go"): mean X, p95 X (evaluated 5/5)
+  Condition 2 (file_extension ".py"): not evaluated
+  Templates: not expanded (the hook does not run)
+[Hook 2] matcher "Write" matched tool_name "Write"
+  Condition 1 (file_extension ".py"): mean X, p95 X (evaluated 5/5)
+  Templates (2 action(s)): mean X, p95 X
+[Hook 3] matcher "Bash" did not match tool_name "Write"
+  Condition 1 (file_extension ".py"): not evaluated
+  Templates: not expanded (the hook does not run)
+Total: mean X, p95 X
+`
+	if !strings.HasPrefix(got, want) || !strings.Contains(got, "Slowest condition: Hook ") {
+		t.Errorf("runBench() output:\n%s\nwant:\n%s", got, want)
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Error("bench should not run actions")
+	}
+
+	if err := runBench(&buf, strings.NewReader(input), config, PreToolUse, 0); err == nil {
+		t.Error("Expected an error for 0 iterations")
+	}
+	if err := runBench(&buf, strings.NewReader("{"), config, PreToolUse, 1); err == nil {
+		t.Error("Expected an error for invalid JSON")
+	}
+}
+
+func TestBenchTimings(t *testing.T) {
+	var timings benchTimings
+	for i := 1; i <= 100; i++ {
+		timings = append(timings, time.Duration(i)*time.Microsecond)
+	}
+	if got := timings.String(); got != "mean 50.5µs, p95 95µs" {
+		t.Errorf("String() = %q", got)
+	}
+	for d, want := range map[time.Duration]string{
+		0:                         "0s",
+		1234 * time.Nanosecond:    "1.23µs",
+		5863417 * time.Nanosecond: "5.86ms",
+		2345 * time.Millisecond:   "2.35s",
+	} {
+		if got := formatBenchDuration(d); got != want {
+			t.Errorf("formatBenchDuration(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// breakingChangeMaxLines is the number of lines of the tool's output a breaking_change action reports to Claude.
+const breakingChangeMaxLines = 20
+
+// breakingChangeDefaultBase is the revision breaking_change actions compare against unless base is set.
+const breakingChangeDefaultBase = "HEAD"
+
+var (
+	openAPIYAMLPattern = regexp.MustCompile(`(?m)^["']?(openapi|swagger)["']?\s*:`)
+	openAPIJSONPattern = regexp.MustCompile(`"(openapi|swagger)"\s*:`)
+)
+
+// breakingChangeCheck is the comparison a breaking_change action runs in a temporary directory
+// holding the base revision and the proposed revision of the edited API definition.
+type breakingChangeCheck struct {
+	Tool    string // "buf" or "oasdiff"
+	File    string // リポジトリルートからの相対パス
+	Base    string // 比較対象のリビジョン
+	Command string // 実際に実行するシェルコマンド
+	dir     string
+}
+
+// cleanup removes the temporary directory of the check.
+func (c *breakingChangeCheck) cleanup() {
+	_ = os.RemoveAll(c.dir)
+}
+
+// breakingChangeTool returns the tool a breaking_change action compares the file at path with:
+// buf for .proto files and oasdiff for OpenAPI/Swagger definitions (YAML or JSON files named
+// openapi*/swagger* or with a top-level openapi/swagger key). It returns "" for other files.
+func breakingChangeTool(path string, content []byte) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".proto" {
+		return "buf"
+	}
+	if ext != ".yaml" && ext != ".yml" && ext != ".json" {
+		return ""
+	}
+	name := strings.ToLower(filepath.Base(path))
+	if strings.HasPrefix(name, "openapi") || strings.HasPrefix(name, "swagger") {
+		return "oasdiff"
+	}
+	pattern := openAPIYAMLPattern
+	if ext == ".json" {
+		pattern = openAPIJSONPattern
+	}
+	if pattern.Match(content) {
+		return "oasdiff"
+	}
+	return ""
+}
+
+// proposedFileContent returns what the file at path will contain after the Write, Edit or MultiEdit tool call.
+// It returns false for other tools and for edits whose old_string is not in the file (the tool call fails anyway).
+func proposedFileContent(toolName, path string, toolInput ToolInput) ([]byte, bool) {
+	if path == "" {
+		return nil, false
+	}
+	var edits []ToolInputEdit
+	switch toolName {
+	case "Write":
+		return []byte(toolInput.Content), true
+	case "Edit":
+		edits = []ToolInputEdit{{OldString: toolInput.OldString, NewString: toolInput.NewString, ReplaceAll: toolInput.ReplaceAll}}
+	case "MultiEdit":
+		edits = toolInput.Edits
+	default:
+		return nil, false
+	}
+	current, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	content := string(current)
+	for _, edit := range edits {
+		if edit.OldString == "" || !strings.Contains(content, edit.OldString) {
+			return nil, false
+		}
+		if edit.ReplaceAll {
+			content = strings.ReplaceAll(content, edit.OldString, edit.NewString)
+		} else {
+			content = strings.Replace(content, edit.OldString, edit.NewString, 1)
+		}
+	}
+	return []byte(content), true
+}
+
+// prepareBreakingChangeCheck writes the base revision and the proposed content of the API definition at path
+// to a temporary directory and returns the comparison to run there. The caller must call cleanup.
+// It returns nil for files that are not API definitions, outside a Git repository or new since base.
+func prepareBreakingChangeCheck(path string, content []byte, base string) (*breakingChangeCheck, error) {
+	tool := breakingChangeTool(path, content)
+	if tool == "" {
+		return nil, nil
+	}
+	root, ok := findUpward(filepath.Dir(path), ".git")
+	if !ok {
+		return nil, nil
+	}
+	if base == "" {
+		base = breakingChangeDefaultBase
+	}
+	if strings.HasPrefix(base, "-") {
+		return nil, fmt.Errorf("invalid base %q", base)
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "cchook-breaking-")
+	if err != nil {
+		return nil, err
+	}
+	check := &breakingChangeCheck{Tool: tool, File: filepath.ToSlash(rel), Base: base, dir: dir}
+	var prepared bool
+	if tool == "buf" {
+		prepared, err = prepareBufModules(check, root, path, content)
+	} else {
+		prepared, err = prepareOpenAPIFiles(check, root, content)
+	}
+	if err != nil || !prepared {
+		check.cleanup()
+		return nil, err
+	}
+	return check, nil
+}
+
+// prepareOpenAPIFiles writes the definition at base and the proposed one for oasdiff.
+func prepareOpenAPIFiles(check *breakingChangeCheck, root string, content []byte) (bool, error) {
+	baseContent, ok := gitShowFile(root, check.Base, check.File)
+	if !ok {
+		return false, nil
+	}
+	name := filepath.Base(check.File)
+	if err := writeFileAll(filepath.Join(check.dir, "base", name), baseContent); err != nil {
+		return false, err
+	}
+	if err := writeFileAll(filepath.Join(check.dir, "revision", name), content); err != nil {
+		return false, err
+	}
+	check.Command = "cd " + shellQuote(check.dir) + " && oasdiff breaking " +
+		shellQuote("base/"+name) + " " + shellQuote("revision/"+name) + " --fail-on ERR"
+	return true, nil
+}
+
+// prepareBufModules writes the buf module of the edited .proto file (the directory of the nearest buf.yaml,
+// or the repository) at base and with the proposed content, so that imports resolve on both sides.
+func prepareBufModules(check *breakingChangeCheck, root, path string, content []byte) (bool, error) {
+	module := root
+	if dir, ok := findUpward(filepath.Dir(path), "buf.yaml"); ok && strings.HasPrefix(dir+string(filepath.Separator), root+string(filepath.Separator)) {
+		module = dir
+	}
+	moduleRel, err := filepath.Rel(root, module)
+	if err != nil {
+		return false, err
+	}
+
+	// 比較元: baseのモジュール
+	cmd := exec.Command("git", "-C", root, "ls-tree", "-r", "-z", "--name-only", check.Base, "--", filepath.ToSlash(moduleRel))
+	out, err := cmd.Output()
+	if err != nil {
+		return false, nil
+	}
+	baseProtos := 0
+	for _, name := range strings.Split(string(out), "\x00") {
+		if !isBufModuleFile(name) {
+			continue
+		}
+		data, ok := gitShowFile(root, check.Base, name)
+		if !ok {
+			continue
+		}
+		target, err := filepath.Rel(moduleRel, filepath.FromSlash(name))
+		if err != nil {
+			continue
+		}
+		if err := writeFileAll(filepath.Join(check.dir, "base", target), data); err != nil {
+			return false, err
+		}
+		if strings.HasSuffix(name, ".proto") {
+			baseProtos++
+		}
+	}
+	if baseProtos == 0 {
+		return false, nil
+	}
+
+	// 比較先: 作業ツリーのモジュールに編集後の内容を重ねたもの
+	err = filepath.WalkDir(module, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if p != module && (strings.HasPrefix(d.Name(), ".") || d.Name() == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isBufModuleFile(d.Name()) {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(module, p)
+		if err != nil {
+			return nil
+		}
+		return writeFileAll(filepath.Join(check.dir, "revision", rel), data)
+	})
+	if err != nil {
+		return false, err
+	}
+	rel, err := filepath.Rel(module, path)
+	if err != nil {
+		return false, err
+	}
+	if err := writeFileAll(filepath.Join(check.dir, "revision", rel), content); err != nil {
+		return false, err
+	}
+
+	check.Command = "cd " + shellQuote(check.dir) + " && buf breaking revision --against base --error-format text"
+	return true, nil
+}
+
+// isBufModuleFile reports whether name is a file buf needs to build a module.
+func isBufModuleFile(name string) bool {
+	base := filepath.Base(name)
+	return strings.HasSuffix(base, ".proto") || base == "buf.yaml" || base == "buf.lock"
+}
+
+// gitShowFile returns the content of the file at rel (slash-separated, relative to root) at revision.
+// It returns false if the file doesn't exist at revision.
+func gitShowFile(root, revision, rel string) ([]byte, bool) {
+	out, err := exec.Command("git", "-C", root, "show", revision+":"+rel).Output()
+	if err != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+// writeFileAll writes data to path, creating its directory.
+func writeFileAll(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// breakingChangesFound reports whether the exit code of the check's tool means breaking changes were found:
+// 100 for buf (the code of file annotations) and 1 for oasdiff with --fail-on.
+func breakingChangesFound(tool string, exitCode int) bool {
+	if tool == "buf" {
+		return exitCode == 100
+	}
+	return exitCode == 1
+}
+
+// breakingChangeReason summarizes the breaking changes the tool found for Claude, capped at breakingChangeMaxLines lines.
+func breakingChangeReason(check *breakingChangeCheck, output string) string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(output, check.dir+string(filepath.Separator), ""), "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, strings.TrimRight(line, " \t\r"))
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Breaking API changes in %s against %s (%s breaking):", check.File, check.Base, check.Tool)
+	for i, line := range lines {
+		if i == breakingChangeMaxLines {
+			fmt.Fprintf(&b, "\n... and %d more lines", len(lines)-i)
+			break
+		}
+		b.WriteString("\n")
+		b.WriteString(line)
+	}
+	return b.String()
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testOpenAPISpec = `openapi: 3.0.0
+info:
+  title: Users
+  version: "1"
+paths:
+  /users:
+    get:
+      responses:
+        "200":
+          description: ok
+`
+
+// initBreakingChangeRepo creates a Git repository with files committed, and returns its directory.
+func initBreakingChangeRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := writeFileAll(filepath.Join(dir, name), []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := runCommand("cd "+dir+" && git init -q && git add . && git -c user.email=test@example.com -c user.name=Test commit -q -m init", false, nil); err != nil {
+		t.Skipf("git is not available: %v", err)
+	}
+	return dir
+}
+
+func TestBreakingChangeTool(t *testing.T) {
+	tests := []struct {
+		path    string
+		content string
+		want    string
+	}{
+		{path: "proto/user/v1/user.proto", want: "buf"},
+		{path: "api/openapi.yaml", want: "oasdiff"},
+		{path: "api/Swagger.json", want: "oasdiff"},
+		{path: "api/users.yml", content: testOpenAPISpec, want: "oasdiff"},
+		{path: "api/users.json", content: `{"openapi": "3.1.0", "paths": {}}`, want: "oasdiff"},
+		{path: "config/app.yaml", content: "server:\n  openapi: true\n", want: ""},
+		{path: "docs/openapi.md", want: ""},
+		{path: "main.go", want: ""},
+	}
+	for _, tt := range tests {
+		if got := breakingChangeTool(tt.path, []byte(tt.content)); got != tt.want {
+			t.Errorf("breakingChangeTool(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestProposedFileContent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api.proto")
+	if err := os.WriteFile(path, []byte("a b a"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		toolName  string
+		toolInput ToolInput
+		want      string
+		wantOK    bool
+	}{
+		{name: "Write", toolName: "Write", toolInput: ToolInput{Content: "new"}, want: "new", wantOK: true},
+		{name: "Edit first", toolName: "Edit", toolInput: ToolInput{OldString: "a", NewString: "x"}, want: "x b a", wantOK: true},
+		{name: "Edit all", toolName: "Edit", toolInput: ToolInput{OldString: "a", NewString: "x", ReplaceAll: true}, want: "x b x", wantOK: true},
+		{name: "MultiEdit", toolName: "MultiEdit", toolInput: ToolInput{Edits: []ToolInputEdit{{OldString: "b", NewString: "c"}, {OldString: "c a", NewString: "d"}}}, want: "a d", wantOK: true},
+		{name: "old_string not found", toolName: "Edit", toolInput: ToolInput{OldString: "z", NewString: "x"}},
+		{name: "other tool", toolName: "Bash", toolInput: ToolInput{Command: "ls"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := proposedFileContent(tt.toolName, path, tt.toolInput)
+			if ok != tt.wantOK || string(got) != tt.want {
+				t.Errorf("proposedFileContent() = %q, %v; want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestPrepareBreakingChangeCheck_OpenAPI(t *testing.T) {
+	repo := initBreakingChangeRepo(t, map[string]string{"api/openapi.yaml": testOpenAPISpec})
+	revised := strings.Replace(testOpenAPISpec, "/users:", "/members:", 1)
+
+	check, err := prepareBreakingChangeCheck(filepath.Join(repo, "api", "openapi.yaml"), []byte(revised), "")
+	if err != nil || check == nil {
+		t.Fatalf("prepareBreakingChangeCheck() = %v, %v", check, err)
+	}
+	defer check.cleanup()
+	if check.Tool != "oasdiff" || check.File != "api/openapi.yaml" || check.Base != "HEAD" {
+		t.Errorf("check = %+v", check)
+	}
+	if !strings.HasSuffix(check.Command, "oasdiff breaking 'base/openapi.yaml' 'revision/openapi.yaml' --fail-on ERR") {
+		t.Errorf("Command = %q", check.Command)
+	}
+	for name, want := range map[string]string{"base": testOpenAPISpec, "revision": revised} {
+		got, err := os.ReadFile(filepath.Join(check.dir, name, "openapi.yaml"))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", name, got, err, want)
+		}
+	}
+
+	check.cleanup()
+	if _, err := os.Stat(check.dir); !os.IsNotExist(err) {
+		t.Errorf("temporary directory not removed: %v", err)
+	}
+
+	// baseに無いファイルは比較しない
+	if check, err := prepareBreakingChangeCheck(filepath.Join(repo, "api", "openapi-v2.yaml"), []byte(testOpenAPISpec), ""); check != nil || err != nil {
+		t.Errorf("new file: prepareBreakingChangeCheck() = %v, %v; want nil", check, err)
+	}
+	// baseが不正なら比較しない
+	if _, err := prepareBreakingChangeCheck(filepath.Join(repo, "api", "openapi.yaml"), []byte(revised), "--output=x"); err == nil {
+		t.Error("invalid base: want error")
+	}
+}
+
+func TestPrepareBreakingChangeCheck_Buf(t *testing.T) {
+	repo := initBreakingChangeRepo(t, map[string]string{
+		"proto/buf.yaml":             "version: v2\n",
+		"proto/user/v1/user.proto":   "syntax = \"proto3\";\nmessage User { string name = 1; }\n",
+		"proto/common/v1/page.proto": "syntax = \"proto3\";\nmessage Page { int32 size = 1; }\n",
+		"README.md":                  "# repo\n",
+	})
+	revised := "syntax = \"proto3\";\nmessage User { string display_name = 1; }\n"
+
+	check, err := prepareBreakingChangeCheck(filepath.Join(repo, "proto", "user", "v1", "user.proto"), []byte(revised), "HEAD")
+	if err != nil || check == nil {
+		t.Fatalf("prepareBreakingChangeCheck() = %v, %v", check, err)
+	}
+	defer check.cleanup()
+	if check.Tool != "buf" || check.File != "proto/user/v1/user.proto" {
+		t.Errorf("check = %+v", check)
+	}
+	if !strings.HasSuffix(check.Command, "buf breaking revision --against base --error-format text") {
+		t.Errorf("Command = %q", check.Command)
+	}
+	for _, name := range []string{"buf.yaml", "common/v1/page.proto", "user/v1/user.proto"} {
+		for _, side := range []string{"base", "revision"} {
+			if _, err := os.Stat(filepath.Join(check.dir, side, name)); err != nil {
+				t.Errorf("%s/%s: %v", side, name, err)
+			}
+		}
+	}
+	if got, _ := os.ReadFile(filepath.Join(check.dir, "revision", "user", "v1", "user.proto")); string(got) != revised {
+		t.Errorf("revision user.proto = %q, want the proposed content", got)
+	}
+	if got, _ := os.ReadFile(filepath.Join(check.dir, "base", "user", "v1", "user.proto")); !strings.Contains(string(got), "string name = 1") {
+		t.Errorf("base user.proto = %q, want the committed content", got)
+	}
+	if _, err := os.Stat(filepath.Join(check.dir, "base", "README.md")); err == nil {
+		t.Error("files outside the module should not be copied")
+	}
+}
+
+func TestExecutePreToolUseAction_BreakingChange(t *testing.T) {
+	repo := initBreakingChangeRepo(t, map[string]string{"api/openapi.yaml": testOpenAPISpec})
+	oasdiffOutput := "1 breaking changes: 1 error, 0 warning, 0 info\nerror\t[api-path-removed-without-deprecation] at revision/openapi.yaml\n\tin API GET /users\n\t\tapi path removed without deprecation\n"
+	reason := "Breaking API changes in api/openapi.yaml against HEAD (oasdiff breaking):\n" + strings.TrimRight(oasdiffOutput, "\n")
+
+	tests := []struct {
+		name         string
+		action       Action
+		toolInput    ToolInput
+		runner       *stubRunnerWithOutput
+		wantDecision string
+		wantNil      bool
+	}{
+		{
+			name:         "breaking change asks",
+			action:       Action{Type: "breaking_change"},
+			toolInput:    ToolInput{FilePath: "api/openapi.yaml", OldString: "/users:", NewString: "/members:"},
+			runner:       &stubRunnerWithOutput{exitCode: 1, stdout: oasdiffOutput},
+			wantDecision: "ask",
+		},
+		{
+			name:         "permission_decision deny",
+			action:       Action{Type: "breaking_change", PermissionDecision: stringPtr("deny")},
+			toolInput:    ToolInput{FilePath: "api/openapi.yaml", OldString: "/users:", NewString: "/members:"},
+			runner:       &stubRunnerWithOutput{exitCode: 1, stdout: oasdiffOutput},
+			wantDecision: "deny",
+		},
+		{
+			name:      "no breaking change",
+			action:    Action{Type: "breaking_change"},
+			toolInput: ToolInput{FilePath: "api/openapi.yaml", OldString: "ok", NewString: "OK"},
+			runner:    &stubRunnerWithOutput{},
+			wantNil:   true,
+		},
+		{
+			name:      "oasdiff not installed",
+			action:    Action{Type: "breaking_change"},
+			toolInput: ToolInput{FilePath: "api/openapi.yaml", OldString: "/users:", NewString: "/members:"},
+			runner:    &stubRunnerWithOutput{exitCode: 127, stderr: "sh: 1: oasdiff: not found"},
+			wantNil:   true,
+		},
+		{
+			name:      "not an API definition",
+			action:    Action{Type: "breaking_change"},
+			toolInput: ToolInput{FilePath: "README.md", Content: "# API"},
+			runner:    &stubRunnerWithOutput{exitCode: 1, stdout: oasdiffOutput},
+			wantNil:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toolName := "Edit"
+			if tt.toolInput.Content != "" {
+				toolName = "Write"
+			}
+			input := &PreToolUseInput{BaseInput: BaseInput{Cwd: repo}, ToolName: toolName, ToolInput: tt.toolInput}
+			output, err := NewActionExecutor(tt.runner).ExecutePreToolUseAction(tt.action, input, map[string]any{"cwd": repo})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantNil {
+				if output != nil {
+					t.Errorf("output = %+v, want nil", output)
+				}
+				return
+			}
+			if output == nil {
+				t.Fatal("output = nil")
+			}
+			if output.PermissionDecision != tt.wantDecision || output.PermissionDecisionReason != reason {
+				t.Errorf("output = %q, %q; want %q, %q", output.PermissionDecision, output.PermissionDecisionReason, tt.wantDecision, reason)
+			}
+		})
+	}
+}
+
+func TestBreakingChangeReason_Truncates(t *testing.T) {
+	check := &breakingChangeCheck{Tool: "buf", File: "proto/a.proto", Base: "origin/main", dir: "/tmp/cchook-breaking-1"}
+	var lines []string
+	for range 25 {
+		lines = append(lines, "/tmp/cchook-breaking-1/revision/a.proto:1:1:Field \"1\" on message \"A\" changed name.")
+	}
+	reason := breakingChangeReason(check, strings.Join(lines, "\n"))
+	if !strings.HasPrefix(reason, "Breaking API changes in proto/a.proto against origin/main (buf breaking):\nrevision/a.proto:1:1:") {
+		t.Errorf("reason = %q", reason)
+	}
+	if !strings.HasSuffix(reason, "\n... and 5 more lines") {
+		t.Errorf("reason should end with the number of omitted lines: %q", reason)
+	}
+}
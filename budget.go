@@ -0,0 +1,476 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Names of the limits of the `budget:` block.
+const (
+	budgetSessionTokens = "session_tokens"
+	budgetDailyTokens   = "daily_tokens"
+	budgetSessionCost   = "session_cost"
+	budgetDailyCost     = "daily_cost"
+)
+
+// budgetLimitNames are the limits in the order they are reported.
+var budgetLimitNames = []string{budgetSessionTokens, budgetSessionCost, budgetDailyTokens, budgetDailyCost}
+
+// activeBudget is the `budget:` block of the loaded config, used by the budget conditions.
+// main sets it after loading the config; nil means no budget is configured.
+var activeBudget *BudgetConfig
+
+// builtinModelPrices are the prices of Claude models in USD per million tokens, matched by model name prefix
+// (the longest prefix wins). Cache writes and reads default to 1.25x and 0.1x the input price.
+var builtinModelPrices = map[string]ModelPrice{
+	"claude-opus-4-5":   {Input: 5, Output: 25},
+	"claude-opus-4":     {Input: 15, Output: 75},
+	"claude-sonnet-4":   {Input: 3, Output: 15},
+	"claude-haiku-4-5":  {Input: 1, Output: 5},
+	"claude-3-opus":     {Input: 15, Output: 75},
+	"claude-3-7-sonnet": {Input: 3, Output: 15},
+	"claude-3-5-sonnet": {Input: 3, Output: 15},
+	"claude-3-5-haiku":  {Input: 0.8, Output: 4},
+	"claude-3-haiku":    {Input: 0.25, Output: 1.25},
+}
+
+// validateBudgetConfig checks the limits and prices of the `budget:` block.
+func validateBudgetConfig(c *BudgetConfig) error {
+	if c == nil {
+		return nil
+	}
+	var errs []error
+	for _, limit := range []struct{ name, value string }{{budgetSessionTokens, c.SessionTokens}, {budgetDailyTokens, c.DailyTokens}} {
+		if limit.value == "" {
+			continue
+		}
+		if n, err := parseTokenCount(limit.value); err != nil || n == 0 {
+			errs = append(errs, fmt.Errorf("budget: invalid %s %q (use a positive number such as 200000, 200k or 1.5M)", limit.name, limit.value))
+		}
+	}
+	for _, limit := range []struct {
+		name  string
+		value float64
+	}{{budgetSessionCost, c.SessionCost}, {budgetDailyCost, c.DailyCost}} {
+		if limit.value < 0 {
+			errs = append(errs, fmt.Errorf("budget: invalid %s %v (must be a positive amount in USD)", limit.name, limit.value))
+		}
+	}
+	for _, model := range slices.Sorted(maps.Keys(c.Prices)) {
+		price := c.Prices[model]
+		if price.Input < 0 || price.Output < 0 || price.CacheWrite < 0 || price.CacheRead < 0 {
+			errs = append(errs, fmt.Errorf("budget: prices of %s must not be negative", model))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// limits returns the configured limits by name (token counts, or USD for costs).
+func (c *BudgetConfig) limits() map[string]float64 {
+	limits := map[string]float64{}
+	if c == nil {
+		return limits
+	}
+	if n, err := parseTokenCount(c.SessionTokens); c.SessionTokens != "" && err == nil && n > 0 {
+		limits[budgetSessionTokens] = float64(n)
+	}
+	if n, err := parseTokenCount(c.DailyTokens); c.DailyTokens != "" && err == nil && n > 0 {
+		limits[budgetDailyTokens] = float64(n)
+	}
+	if c.SessionCost > 0 {
+		limits[budgetSessionCost] = c.SessionCost
+	}
+	if c.DailyCost > 0 {
+		limits[budgetDailyCost] = c.DailyCost
+	}
+	return limits
+}
+
+// price returns the price of model: the entry of the config or the built-in table with the longest matching prefix.
+func (c *BudgetConfig) price(model string) (ModelPrice, bool) {
+	prices := builtinModelPrices
+	if c != nil && len(c.Prices) > 0 {
+		prices = make(map[string]ModelPrice, len(builtinModelPrices)+len(c.Prices))
+		for k, v := range builtinModelPrices {
+			prices[k] = v
+		}
+		for k, v := range c.Prices {
+			prices[k] = v
+		}
+	}
+	best := ""
+	for prefix := range prices {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return ModelPrice{}, false
+	}
+	return prices[best], true
+}
+
+// cost returns the cost in USD of usage billed at price p.
+func (p ModelPrice) cost(u *transcriptUsage) float64 {
+	cacheWrite, cacheRead := p.CacheWrite, p.CacheRead
+	if cacheWrite == 0 {
+		cacheWrite = p.Input * 1.25
+	}
+	if cacheRead == 0 {
+		cacheRead = p.Input * 0.1
+	}
+	return (float64(u.InputTokens)*p.Input +
+		float64(u.CacheCreationInputTokens)*cacheWrite +
+		float64(u.CacheReadInputTokens)*cacheRead +
+		float64(u.OutputTokens)*p.Output) / 1e6
+}
+
+// usageTotals is the usage of a session on one day.
+type usageTotals struct {
+	Tokens int64   `json:"tokens"`
+	Cost   float64 `json:"cost"`
+}
+
+// sessionUsage is the usage of a session accounted so far, persisted in <session>.usage.json.
+// The transcript is read incrementally from Offset.
+type sessionUsage struct {
+	SessionID      string                  `json:"session_id"`
+	TranscriptPath string                  `json:"transcript_path"`
+	Offset         int64                   `json:"offset"`
+	LastMessageID  string                  `json:"last_message_id,omitempty"`
+	Days           map[string]*usageTotals `json:"days"`                      // ローカル日付 (2006-01-02) 毎の使用量
+	UnpricedModels []string                `json:"unpriced_models,omitempty"` // 価格が分からずコストに含めていないモデル
+}
+
+// total returns the usage of the session over all days.
+func (u *sessionUsage) total() usageTotals {
+	var total usageTotals
+	for _, day := range u.Days {
+		total.Tokens += day.Tokens
+		total.Cost += day.Cost
+	}
+	return total
+}
+
+// updateSessionUsage accounts the transcript entries written since the last update and returns the usage of the session.
+// A message streamed over several transcript lines (with the same message id) is counted once.
+func updateSessionUsage(sessionID, transcriptPath string, budget *BudgetConfig) (*sessionUsage, error) {
+	path := sessionStatePath(sessionID, ".usage.json")
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create session state directory: %w", err)
+	}
+	unlock, err := lockFile(sessionStatePath(sessionID, ".usage.lock"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock session usage: %w", err)
+	}
+	defer unlock()
+
+	usage := &sessionUsage{}
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, usage)
+	}
+	if usage.TranscriptPath != transcriptPath && transcriptPath != "" {
+		// トランスクリプトが変わったら数え直す
+		usage = &sessionUsage{TranscriptPath: transcriptPath}
+	}
+	usage.SessionID = sessionID
+	if usage.Days == nil {
+		usage.Days = map[string]*usageTotals{}
+	}
+
+	if usage.TranscriptPath == "" {
+		return usage, nil
+	}
+	file, err := os.Open(usage.TranscriptPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return usage, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open transcript: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	if info, err := file.Stat(); err == nil && info.Size() < usage.Offset {
+		usage = &sessionUsage{SessionID: sessionID, TranscriptPath: usage.TranscriptPath, Days: map[string]*usageTotals{}}
+	}
+	if _, err := file.Seek(usage.Offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+	// 書きかけの最後の行は次回に回す
+	complete := bytes.LastIndexByte(data, '\n') + 1
+	if complete == 0 {
+		return usage, nil
+	}
+
+	err = forEachTranscriptEntry(bytes.NewReader(data[:complete]), func(entry transcriptEntry) {
+		message := entry.Message
+		if entry.Type != "assistant" || entry.SessionID != sessionID || message == nil || message.Usage == nil {
+			return
+		}
+		if message.ID != "" && message.ID == usage.LastMessageID {
+			return
+		}
+		usage.LastMessageID = message.ID
+
+		day := currentTime().Local().Format(time.DateOnly)
+		if t, err := time.Parse(time.RFC3339Nano, entry.Timestamp); err == nil {
+			day = t.Local().Format(time.DateOnly)
+		}
+		totals := usage.Days[day]
+		if totals == nil {
+			totals = &usageTotals{}
+			usage.Days[day] = totals
+		}
+		u := message.Usage
+		totals.Tokens += u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens + u.OutputTokens
+		if price, ok := budget.price(message.Model); ok {
+			totals.Cost += price.cost(u)
+		} else if message.Model != "" && u.OutputTokens > 0 && !slices.Contains(usage.UnpricedModels, message.Model) {
+			usage.UnpricedModels = append(usage.UnpricedModels, message.Model)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+	usage.Offset += int64(complete)
+
+	encoded, err := json.Marshal(usage)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, encoded, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to save session usage: %w", err)
+	}
+	return usage, nil
+}
+
+// loadSessionUsages returns the persisted usage of every session that used tokens on day.
+func loadSessionUsages(day string) ([]*sessionUsage, error) {
+	paths, err := filepath.Glob(filepath.Join(sessionStateDir(), "*.usage.json"))
+	if err != nil {
+		return nil, err
+	}
+	start, err := time.ParseInLocation(time.DateOnly, day, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	var usages []*sessionUsage
+	for _, path := range paths {
+		// その日より前に更新されたセッションはその日の使用量を持たない
+		if info, err := os.Stat(path); err != nil || info.ModTime().Before(start) {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var usage sessionUsage
+		if json.Unmarshal(data, &usage) == nil && usage.Days[day] != nil {
+			usages = append(usages, &usage)
+		}
+	}
+	return usages, nil
+}
+
+// budgetLimitStatus is the usage of one configured limit.
+type budgetLimitStatus struct {
+	Name  string
+	Limit float64
+	Used  float64
+}
+
+// Remaining returns the fraction of the limit still available (negative when exceeded).
+func (s budgetLimitStatus) Remaining() float64 {
+	return (s.Limit - s.Used) / s.Limit
+}
+
+// Exceeded reports whether the usage reached the limit.
+func (s budgetLimitStatus) Exceeded() bool {
+	return s.Used >= s.Limit
+}
+
+// format returns "used / limit (percent)" in tokens or USD.
+func (s budgetLimitStatus) format() string {
+	percent := s.Used / s.Limit * 100
+	if strings.HasSuffix(s.Name, "_cost") {
+		return fmt.Sprintf("$%.2f / $%.2f (%.0f%%)", s.Used, s.Limit, percent)
+	}
+	return fmt.Sprintf("%s / %s (%.0f%%)", formatTokenCount(int64(s.Used)), formatTokenCount(int64(s.Limit)), percent)
+}
+
+// formatTokenCount formats a token count like parseTokenCount accepts it (1.5M, 200k, 950).
+func formatTokenCount(n int64) string {
+	switch {
+	case n >= 1e6:
+		return strconv.FormatFloat(math.Round(float64(n)/1e4)/100, 'f', -1, 64) + "M"
+	case n >= 1e3:
+		return strconv.FormatFloat(math.Round(float64(n)/1e2)/10, 'f', -1, 64) + "k"
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+// budgetStatus accounts the session's transcript and returns the status of every configured limit.
+func budgetStatus(budget *BudgetConfig, sessionID, transcriptPath string) ([]budgetLimitStatus, error) {
+	limits := budget.limits()
+	if len(limits) == 0 || sessionID == "" {
+		return nil, nil
+	}
+	usage, err := updateSessionUsage(sessionID, transcriptPath, budget)
+	if err != nil {
+		return nil, err
+	}
+	session := usage.total()
+
+	var daily usageTotals
+	_, needsDaily := limits[budgetDailyTokens]
+	if _, ok := limits[budgetDailyCost]; ok || needsDaily {
+		day := currentTime().Local().Format(time.DateOnly)
+		usages, err := loadSessionUsages(day)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range usages {
+			daily.Tokens += u.Days[day].Tokens
+			daily.Cost += u.Days[day].Cost
+		}
+	}
+
+	used := map[string]float64{
+		budgetSessionTokens: float64(session.Tokens),
+		budgetSessionCost:   session.Cost,
+		budgetDailyTokens:   float64(daily.Tokens),
+		budgetDailyCost:     daily.Cost,
+	}
+	var statuses []budgetLimitStatus
+	for _, name := range budgetLimitNames {
+		if limit, ok := limits[name]; ok {
+			statuses = append(statuses, budgetLimitStatus{Name: name, Limit: limit, Used: used[name]})
+		}
+	}
+	return statuses, nil
+}
+
+// parseBudgetRemaining parses the value of budget_remaining_below: "20%" (any limit) or "daily_cost:20%".
+func parseBudgetRemaining(value string) (string, float64, error) {
+	limit, percent, found := strings.Cut(strings.TrimSpace(value), ":")
+	if !found {
+		limit, percent = "", limit
+	}
+	if limit != "" && !slices.Contains(budgetLimitNames, limit) {
+		return "", 0, fmt.Errorf("unknown budget limit %q (must be one of %s)", limit, strings.Join(budgetLimitNames, ", "))
+	}
+	number, ok := strings.CutSuffix(strings.TrimSpace(percent), "%")
+	n, err := strconv.ParseFloat(number, 64)
+	if !ok || err != nil || n <= 0 || n > 100 {
+		return "", 0, fmt.Errorf("invalid remaining budget %q (use a percentage such as \"20%%\" or \"daily_cost:20%%\")", value)
+	}
+	return limit, n / 100, nil
+}
+
+// checkBudgetExceeded reports whether the named limit (any limit if empty) of the active budget has been reached.
+func checkBudgetExceeded(limit string, baseInput *BaseInput) (bool, error) {
+	statuses, err := budgetStatus(activeBudget, baseInput.SessionID, baseInput.TranscriptPath)
+	if err != nil {
+		return false, err
+	}
+	return slices.ContainsFunc(statuses, func(s budgetLimitStatus) bool {
+		return (limit == "" || s.Name == limit) && s.Exceeded()
+	}), nil
+}
+
+// checkBudgetRemainingBelow reports whether less than the given fraction of the named limit (any limit if empty) remains.
+func checkBudgetRemainingBelow(value string, baseInput *BaseInput) (bool, error) {
+	limit, fraction, err := parseBudgetRemaining(value)
+	if err != nil {
+		return false, err
+	}
+	statuses, err := budgetStatus(activeBudget, baseInput.SessionID, baseInput.TranscriptPath)
+	if err != nil {
+		return false, err
+	}
+	return slices.ContainsFunc(statuses, func(s budgetLimitStatus) bool {
+		return (limit == "" || s.Name == limit) && s.Remaining() < fraction
+	}), nil
+}
+
+// runBudget is the `budget` command: it accounts the transcripts of the sessions seen today
+// and prints the daily usage against the configured limits, then each session's.
+func runBudget(w io.Writer, budget *BudgetConfig) error {
+	day := currentTime().Local().Format(time.DateOnly)
+	usages, err := loadSessionUsages(day)
+	if err != nil {
+		return err
+	}
+	var daily usageTotals
+	var unpriced []string
+	for i, u := range usages {
+		// フックが最後に動いた後の分も数える
+		if updated, err := updateSessionUsage(u.SessionID, u.TranscriptPath, budget); err == nil && updated.Days[day] != nil {
+			usages[i] = updated
+		}
+		daily.Tokens += usages[i].Days[day].Tokens
+		daily.Cost += usages[i].Days[day].Cost
+		for _, model := range usages[i].UnpricedModels {
+			if !slices.Contains(unpriced, model) {
+				unpriced = append(unpriced, model)
+			}
+		}
+	}
+	limits := budget.limits()
+
+	fmt.Fprintf(w, "Budget for %s\n", day)
+	if len(limits) == 0 {
+		fmt.Fprintln(w, "  No limits configured (add a budget: block to the config)")
+	}
+	for _, name := range []string{budgetDailyTokens, budgetDailyCost} {
+		if limit, ok := limits[name]; ok {
+			used := float64(daily.Tokens)
+			if name == budgetDailyCost {
+				used = daily.Cost
+			}
+			fmt.Fprintf(w, "  %s: %s\n", name, budgetLimitStatus{Name: name, Limit: limit, Used: used}.format())
+		}
+	}
+	fmt.Fprintf(w, "  Total: %s tokens, $%.2f\n", formatTokenCount(daily.Tokens), daily.Cost)
+
+	slices.SortFunc(usages, func(a, b *sessionUsage) int { return strings.Compare(a.SessionID, b.SessionID) })
+	fmt.Fprintf(w, "Sessions (%d):\n", len(usages))
+	for _, u := range usages {
+		total := u.total()
+		fmt.Fprintf(w, "  %s: %s tokens, $%.2f", u.SessionID, formatTokenCount(total.Tokens), total.Cost)
+		for _, name := range []string{budgetSessionTokens, budgetSessionCost} {
+			if limit, ok := limits[name]; ok {
+				used := float64(total.Tokens)
+				if name == budgetSessionCost {
+					used = total.Cost
+				}
+				status := budgetLimitStatus{Name: name, Limit: limit, Used: used}
+				fmt.Fprintf(w, " [%s %.0f%%", name, status.Used/status.Limit*100)
+				if status.Exceeded() {
+					fmt.Fprint(w, " exceeded")
+				}
+				fmt.Fprint(w, "]")
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	if len(unpriced) > 0 {
+		fmt.Fprintf(w, "Costs exclude models without a price (add them to budget.prices): %s\n", strings.Join(unpriced, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// transcriptLine returns a transcript line of an assistant message with usage.
+func transcriptLine(t *testing.T, sessionID, id, model string, at time.Time, usage transcriptUsage) string {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{
+		"type":      "assistant",
+		"sessionId": sessionID,
+		"timestamp": at.UTC().Format(time.RFC3339Nano),
+		"message": map[string]any{
+			"id":      id,
+			"model":   model,
+			"content": []any{map[string]any{"type": "text", "text": "ok"}},
+			"usage": map[string]any{
+				"input_tokens":                usage.InputTokens,
+				"cache_creation_input_tokens": usage.CacheCreationInputTokens,
+				"cache_read_input_tokens":     usage.CacheReadInputTokens,
+				"output_tokens":               usage.OutputTokens,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func appendTranscript(t *testing.T, path string, lines ...string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := f.WriteString(strings.Join(lines, "")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBudgetConditions(t *testing.T) {
+	withSessionStateDir(t)
+	now := time.Now()
+	dir := t.TempDir()
+	s1 := filepath.Join(dir, "s1.jsonl")
+	s2 := filepath.Join(dir, "s2.jsonl")
+
+	a := transcriptLine(t, "s1", "msg_a", "claude-sonnet-4-5-20250929", now, transcriptUsage{InputTokens: 1000, CacheCreationInputTokens: 10000, CacheReadInputTokens: 100000, OutputTokens: 2000})
+	appendTranscript(t, s1,
+		`{"type":"user","sessionId":"s1","message":{"role":"user","content":"hi"}}`+"\n",
+		// 前日: セッションの合計には入るが今日の合計には入らない
+		transcriptLine(t, "s1", "msg_b", "claude-opus-4-1-20250805", now.Add(-24*time.Hour), transcriptUsage{CacheReadInputTokens: 200000, OutputTokens: 4000})+"\n",
+		// 同じメッセージがcontent block毎に2行記録される
+		a+"\n", a+"\n",
+		transcriptLine(t, "s1", "msg_s", "<synthetic>", now, transcriptUsage{})+"\n",
+		transcriptLine(t, "other", "msg_o", "claude-opus-4-1", now, transcriptUsage{OutputTokens: 900000})+"\n",
+	)
+	appendTranscript(t, s2,
+		transcriptLine(t, "s2", "msg_d", "claude-haiku-4-5", now, transcriptUsage{InputTokens: 500000})+"\n",
+		transcriptLine(t, "s2", "msg_e", "mystery-model", now, transcriptUsage{OutputTokens: 1000})+"\n",
+	)
+
+	activeBudget = &BudgetConfig{SessionTokens: "400k", DailyTokens: "1M", DailyCost: 1}
+	t.Cleanup(func() { activeBudget = nil })
+
+	// s2はそのセッションのフックが動いたときに数えられる
+	usage, err := updateSessionUsage("s2", s2, activeBudget)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total := usage.total(); total.Tokens != 501000 || math.Abs(total.Cost-0.5) > 1e-9 {
+		t.Errorf("s2 usage = %+v, want 501000 tokens, $0.5", total)
+	}
+	if len(usage.UnpricedModels) != 1 || usage.UnpricedModels[0] != "mystery-model" {
+		t.Errorf("s2 unpriced models = %v, want [mystery-model]", usage.UnpricedModels)
+	}
+
+	check := func(conditionType ConditionType, value string) bool {
+		t.Helper()
+		input := &BaseInput{SessionID: "s1", TranscriptPath: s1}
+		got, err := checkCommonCondition(Condition{Type: conditionType, Value: value}, input)
+		if err != nil {
+			t.Fatalf("%s %q: %v", conditionType, value, err)
+		}
+		return got
+	}
+
+	// s1: 317000 tokens ($0.7005), 今日: 113000 + 501000 tokens ($0.1005 + $0.5)
+	tests := []struct {
+		conditionType ConditionType
+		value         string
+		want          bool
+	}{
+		{ConditionBudgetExceeded, "", false},
+		{ConditionBudgetRemainingBelow, "25%", true},                 // session_tokens: 20.75%
+		{ConditionBudgetRemainingBelow, "session_tokens:20%", false}, // 20.75%
+		{ConditionBudgetRemainingBelow, "daily_cost:40%", true},      // 39.95%
+		{ConditionBudgetRemainingBelow, "daily_cost:30%", false},
+		{ConditionBudgetRemainingBelow, "daily_tokens:40%", true},  // 38.6%
+		{ConditionBudgetRemainingBelow, "session_cost:90%", false}, // 設定されていない
+	}
+	for _, tt := range tests {
+		if got := check(tt.conditionType, tt.value); got != tt.want {
+			t.Errorf("%s %q = %v, want %v", tt.conditionType, tt.value, got, tt.want)
+		}
+	}
+
+	// 書きかけの行は数えず、書き終わってから数える
+	c := transcriptLine(t, "s1", "msg_c", "claude-sonnet-4-5", now, transcriptUsage{CacheReadInputTokens: 100000})
+	appendTranscript(t, s1, c[:20])
+	if check(ConditionBudgetExceeded, "session_tokens") {
+		t.Error("budget_exceeded session_tokens = true with a partial line")
+	}
+	appendTranscript(t, s1, c[20:]+"\n")
+	if !check(ConditionBudgetExceeded, "session_tokens") || !check(ConditionBudgetExceeded, "") {
+		t.Error("budget_exceeded = false after exceeding session_tokens")
+	}
+	if check(ConditionBudgetExceeded, "daily_cost") {
+		t.Error("budget_exceeded daily_cost = true, want false")
+	}
+
+	usage, err = updateSessionUsage("s1", s1, activeBudget)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total := usage.total(); total.Tokens != 417000 || math.Abs(total.Cost-0.7305) > 1e-9 {
+		t.Errorf("s1 usage = %+v, want 417000 tokens, $0.7305", total)
+	}
+
+	// budget:が無ければ常にfalse
+	activeBudget = nil
+	if check(ConditionBudgetExceeded, "") {
+		t.Error("budget_exceeded without budget = true, want false")
+	}
+}
+
+func TestParseBudgetRemaining(t *testing.T) {
+	tests := []struct {
+		value     string
+		wantLimit string
+		want      float64
+		wantErr   bool
+	}{
+		{"20%", "", 0.2, false},
+		{"daily_cost:12.5%", "daily_cost", 0.125, false},
+		{"20", "", 0, true},
+		{"0%", "", 0, true},
+		{"150%", "", 0, true},
+		{"weekly_cost:20%", "", 0, true},
+	}
+	for _, tt := range tests {
+		limit, fraction, err := parseBudgetRemaining(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseBudgetRemaining(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if limit != tt.wantLimit || fraction != tt.want {
+			t.Errorf("parseBudgetRemaining(%q) = %q, %v; want %q, %v", tt.value, limit, fraction, tt.wantLimit, tt.want)
+		}
+	}
+}
+
+func TestBudgetConfigPrice(t *testing.T) {
+	budget := &BudgetConfig{Prices: map[string]ModelPrice{"claude-opus-4-1": {Input: 10, Output: 50}, "local-": {Input: 0.1, Output: 0.2}}}
+	tests := []struct {
+		model  string
+		want   ModelPrice
+		wantOK bool
+	}{
+		{"claude-opus-4-5-20251101", ModelPrice{Input: 5, Output: 25}, true},
+		{"claude-opus-4-20250514", ModelPrice{Input: 15, Output: 75}, true},
+		{"claude-opus-4-1-20250805", ModelPrice{Input: 10, Output: 50}, true},
+		{"local-llama", ModelPrice{Input: 0.1, Output: 0.2}, true},
+		{"<synthetic>", ModelPrice{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := budget.price(tt.model)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("price(%q) = %+v, %v; want %+v, %v", tt.model, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestRunBudget(t *testing.T) {
+	withSessionStateDir(t)
+	now := time.Now()
+	transcript := filepath.Join(t.TempDir(), "s1.jsonl")
+	appendTranscript(t, transcript, transcriptLine(t, "s1", "msg_a", "claude-sonnet-4-5", now, transcriptUsage{InputTokens: 1500000})+"\n")
+	budget := &BudgetConfig{SessionCost: 4, DailyTokens: "2M"}
+	if _, err := updateSessionUsage("s1", transcript, budget); err != nil {
+		t.Fatal(err)
+	}
+	// フックが最後に動いた後の分もbudgetコマンドで数える
+	appendTranscript(t, transcript, transcriptLine(t, "s1", "msg_b", "claude-sonnet-4-5", now, transcriptUsage{InputTokens: 500000})+"\n")
+
+	var out bytes.Buffer
+	if err := runBudget(&out, budget); err != nil {
+		t.Fatal(err)
+	}
+	want := "Budget for " + now.Local().Format(time.DateOnly) + "\n" +
+		"  daily_tokens: 2M / 2M (100%)\n" +
+		"  Total: 2M tokens, $6.00\n" +
+		"Sessions (1):\n" +
+		"  s1: 2M tokens, $6.00 [session_cost 150% exceeded]\n"
+	if out.String() != want {
+		t.Errorf("runBudget() =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestFormatTokenCount(t *testing.T) {
+	for n, want := range map[int64]string{950: "950", 1500: "1.5k", 200000: "200k", 1234567: "1.23M", 2000000: "2M"} {
+		if got := formatTokenCount(n); got != want {
+			t.Errorf("formatTokenCount(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
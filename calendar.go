@@ -0,0 +1,495 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// calendarCacheTTL is how long a downloaded calendar (or freeze windows file) is used before it is downloaded again.
+const calendarCacheTTL = 5 * time.Minute
+
+// maxCalendarSize limits the size of a downloaded calendar.
+const maxCalendarSize = 16 << 20
+
+// maxRecurrencePeriods bounds the expansion of a recurring event (e.g. a daily meeting over 270 years).
+const maxRecurrencePeriods = 100000
+
+// calendarCacheDir returns the directory holding downloaded calendars and freeze windows files.
+// It is a variable so that tests can use a temporary directory.
+var calendarCacheDir = func() string {
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "cchook", "calendars")
+	}
+	return filepath.Join(os.TempDir(), "cchook-calendars")
+}
+
+// calendarHTTPClient downloads calendars for in_meeting and freeze windows for freeze_active.
+var calendarHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// calendarEvent is a busy VEVENT of an iCalendar file. All-day, free (TRANSP:TRANSPARENT) and cancelled events are dropped.
+type calendarEvent struct {
+	UID          string
+	Summary      string
+	Start        time.Time
+	Duration     time.Duration
+	RecurrenceID time.Time // 繰り返しの1回を上書きするイベントのみ
+	Rule         *recurrenceRule
+	ExDates      []time.Time
+}
+
+// recurrenceRule is the supported subset of an RRULE: FREQ (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL, COUNT, UNTIL and BYDAY.
+type recurrenceRule struct {
+	Freq     string
+	Interval int
+	Count    int
+	Until    time.Time
+	ByDay    []ruleWeekday
+}
+
+// ruleWeekday is a BYDAY entry; N is the ordinal of the weekday in the month (2TU, -1FR), 0 for every one.
+type ruleWeekday struct {
+	N   int
+	Day time.Weekday
+}
+
+var icsWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// inMeeting evaluates in_meeting: whether an event of the calendar at source (an iCalendar URL or file)
+// is in progress. The meeting is stored in the event JSON as meeting ({.meeting.summary}, {.meeting.end}).
+func inMeeting(source string, baseInput *BaseInput) (bool, error) {
+	data, err := loadCalendar(source)
+	if err != nil {
+		return false, err
+	}
+	events, err := parseICS(string(data))
+	if err != nil {
+		return false, err
+	}
+	event, start, ok := meetingAt(events, currentTime())
+	if !ok {
+		return false, nil
+	}
+	if m, ok := baseInput.RawJSON.(map[string]any); ok {
+		m["meeting"] = map[string]any{
+			"summary": event.Summary,
+			"start":   start.In(time.Local).Format(time.RFC3339),
+			"end":     start.Add(event.Duration).In(time.Local).Format(time.RFC3339),
+		}
+	}
+	return true, nil
+}
+
+// loadCalendar reads the calendar of in_meeting at source (see loadSharedSource).
+func loadCalendar(source string) ([]byte, error) {
+	return loadSharedSource(source, "in_meeting", "calendar", ".ics")
+}
+
+// loadSharedSource reads the file a condition shares with others (what: "calendar", ...) at source: a local file
+// ("~/" is expanded), or an http(s)/webcal URL downloaded at most every calendarCacheTTL. $VAR in source is expanded
+// so that secret addresses can live in the environment. When the download fails, the last downloaded copy is used.
+func loadSharedSource(source, condition, what, ext string) ([]byte, error) {
+	source = strings.TrimSpace(os.ExpandEnv(source))
+	if source == "" {
+		return nil, fmt.Errorf("%s URL or file is empty", what)
+	}
+	if rest, ok := strings.CutPrefix(source, "webcal://"); ok {
+		source = "https://" + rest
+	}
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		data, err := os.ReadFile(expandHomeDir(source))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", what, err)
+		}
+		return data, nil
+	}
+
+	sum := sha256.Sum256([]byte(source))
+	cachePath := filepath.Join(calendarCacheDir(), hex.EncodeToString(sum[:])+ext)
+	if info, err := os.Stat(cachePath); err == nil && time.Since(info.ModTime()) < calendarCacheTTL {
+		if data, err := os.ReadFile(cachePath); err == nil {
+			return data, nil
+		}
+	}
+	data, err := fetchSharedSource(source, what)
+	if err != nil {
+		if cached, readErr := os.ReadFile(cachePath); readErr == nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s: %v (using the cached %s)\n", condition, err, what)
+			return cached, nil
+		}
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0o700); err == nil {
+		_ = os.WriteFile(cachePath, data, 0o600)
+	}
+	return data, nil
+}
+
+// fetchSharedSource downloads a shared file. Errors don't include the URL, which is often a secret address.
+func fetchSharedSource(rawURL, what string) ([]byte, error) {
+	resp, err := calendarHTTPClient.Get(rawURL)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("failed to download %s: %w", what, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", what, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxCalendarSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", what, err)
+	}
+	return data, nil
+}
+
+// parseICS returns the busy events of an iCalendar file.
+func parseICS(data string) ([]calendarEvent, error) {
+	if !strings.Contains(data, "BEGIN:VCALENDAR") {
+		return nil, errors.New("not an iCalendar file")
+	}
+	// 折り返された行 (CRLFの後に空白) を戻す
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\n ", "")
+	data = strings.ReplaceAll(data, "\n\t", "")
+
+	var events []calendarEvent
+	var event *calendarEvent
+	var end time.Time
+	skip := false
+	depth := 0 // VEVENT内のVALARMなど
+	for _, line := range strings.Split(data, "\n") {
+		name, params, value := parseICSLine(line)
+		switch {
+		case name == "BEGIN" && value == "VEVENT" && event == nil:
+			event, end, skip, depth = &calendarEvent{}, time.Time{}, false, 0
+			continue
+		case event == nil:
+			continue
+		case name == "BEGIN":
+			depth++
+			continue
+		case name == "END" && value != "VEVENT":
+			depth--
+			continue
+		case name == "END":
+			if !end.IsZero() {
+				event.Duration = end.Sub(event.Start)
+			}
+			if !skip && !event.Start.IsZero() && event.Duration > 0 {
+				events = append(events, *event)
+			}
+			event = nil
+			continue
+		case depth > 0:
+			continue
+		}
+
+		var err error
+		switch name {
+		case "UID":
+			event.UID = value
+		case "SUMMARY":
+			event.Summary = unescapeICSText(value)
+		case "STATUS":
+			skip = skip || value == "CANCELLED"
+		case "TRANSP":
+			skip = skip || value == "TRANSPARENT"
+		case "DTSTART":
+			var allDay bool
+			event.Start, allDay, err = parseICSTime(value, params)
+			skip = skip || allDay
+		case "DTEND":
+			end, _, err = parseICSTime(value, params)
+		case "DURATION":
+			event.Duration, err = parseICSDuration(value)
+		case "RECURRENCE-ID":
+			event.RecurrenceID, _, err = parseICSTime(value, params)
+		case "RRULE":
+			event.Rule, err = parseRecurrenceRule(value, params)
+		case "EXDATE":
+			for _, v := range strings.Split(value, ",") {
+				exdate, _, exErr := parseICSTime(v, params)
+				if exErr != nil {
+					err = exErr
+					break
+				}
+				event.ExDates = append(event.ExDates, exdate)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", name, value, err)
+		}
+	}
+	return events, nil
+}
+
+// parseICSLine splits a content line "NAME;PARAM=VALUE:value" into its name, parameters and value.
+func parseICSLine(line string) (name string, params map[string]string, value string) {
+	line = strings.TrimRight(line, "\r")
+	inQuote := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			inQuote = !inQuote
+		} else if r == ':' && !inQuote {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return "", nil, ""
+	}
+	head, value := line[:colon], line[colon+1:]
+	parts := strings.Split(head, ";")
+	params = map[string]string{}
+	for _, param := range parts[1:] {
+		if k, v, ok := strings.Cut(param, "="); ok {
+			params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, value
+}
+
+// unescapeICSText decodes the escapes of a TEXT value.
+func unescapeICSText(s string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(s)
+}
+
+// parseICSTime parses a DATE-TIME (UTC, TZID or floating local time) or DATE value. allDay reports a DATE.
+// Unknown TZIDs (e.g. Windows time zone names) fall back to the local time zone.
+func parseICSTime(value string, params map[string]string) (t time.Time, allDay bool, err error) {
+	value = strings.TrimSpace(value)
+	if params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		t, err = time.ParseInLocation("20060102", value, time.Local)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err = time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+	loc := time.Local
+	if tzid := params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	t, err = time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+// parseICSDuration parses a DURATION value such as "PT1H30M", "P1D" or "P2W".
+func parseICSDuration(value string) (time.Duration, error) {
+	s, ok := strings.CutPrefix(strings.TrimPrefix(value, "+"), "P")
+	if !ok || s == "" {
+		return 0, errors.New("expected a duration such as PT1H30M")
+	}
+	var d time.Duration
+	inTime := false
+	for s != "" {
+		if s[0] == 'T' {
+			inTime, s = true, s[1:]
+			continue
+		}
+		i := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+		if i <= 0 {
+			return 0, errors.New("expected a duration such as PT1H30M")
+		}
+		n, _ := strconv.Atoi(s[:i])
+		var unit time.Duration
+		switch {
+		case !inTime && s[i] == 'W':
+			unit = 7 * 24 * time.Hour
+		case !inTime && s[i] == 'D':
+			unit = 24 * time.Hour
+		case inTime && s[i] == 'H':
+			unit = time.Hour
+		case inTime && s[i] == 'M':
+			unit = time.Minute
+		case inTime && s[i] == 'S':
+			unit = time.Second
+		default:
+			return 0, fmt.Errorf("unknown unit %q", s[i])
+		}
+		d += time.Duration(n) * unit
+		s = s[i+1:]
+	}
+	return d, nil
+}
+
+// parseRecurrenceRule parses an RRULE. Unsupported frequencies (HOURLY etc.) are an error; unsupported parts are ignored.
+func parseRecurrenceRule(value string, params map[string]string) (*recurrenceRule, error) {
+	rule := &recurrenceRule{Interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		k, v, _ := strings.Cut(part, "=")
+		var err error
+		switch strings.ToUpper(k) {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(v)
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(v)
+			if err == nil && rule.Interval < 1 {
+				err = errors.New("INTERVAL must be positive")
+			}
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(v)
+		case "UNTIL":
+			rule.Until, _, err = parseICSTime(v, params)
+		case "BYDAY":
+			for _, day := range strings.Split(v, ",") {
+				n, name := 0, day
+				if len(day) > 2 {
+					n, err = strconv.Atoi(day[:len(day)-2])
+					name = day[len(day)-2:]
+				}
+				weekday, ok := icsWeekdays[strings.ToUpper(name)]
+				if err != nil || !ok {
+					return nil, fmt.Errorf("invalid BYDAY %q", day)
+				}
+				rule.ByDay = append(rule.ByDay, ruleWeekday{N: n, Day: weekday})
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !slices.Contains([]string{"DAILY", "WEEKLY", "MONTHLY", "YEARLY"}, rule.Freq) {
+		return nil, fmt.Errorf("unsupported FREQ %q", rule.Freq)
+	}
+	// 月曜始まりの週の中で日付順に並べる
+	slices.SortFunc(rule.ByDay, func(a, b ruleWeekday) int {
+		return (int(a.Day)+6)%7 - (int(b.Day)+6)%7
+	})
+	return rule, nil
+}
+
+// meetingAt returns the event in progress at now and the start of its occurrence.
+func meetingAt(events []calendarEvent, now time.Time) (calendarEvent, time.Time, bool) {
+	// RECURRENCE-IDで上書きされた回は元の繰り返しから除く
+	overridden := map[string][]time.Time{}
+	for _, event := range events {
+		if !event.RecurrenceID.IsZero() {
+			overridden[event.UID] = append(overridden[event.UID], event.RecurrenceID)
+		}
+	}
+	for _, event := range events {
+		if event.Rule == nil || !event.RecurrenceID.IsZero() {
+			if !now.Before(event.Start) && now.Before(event.Start.Add(event.Duration)) {
+				return event, event.Start, true
+			}
+			continue
+		}
+		excluded := append(slices.Clone(event.ExDates), overridden[event.UID]...)
+		if start, ok := recurringOccurrenceAt(event, excluded, now); ok {
+			return event, start, true
+		}
+	}
+	return calendarEvent{}, time.Time{}, false
+}
+
+// recurringOccurrenceAt returns the start of the occurrence of a recurring event in progress at now.
+func recurringOccurrenceAt(event calendarEvent, excluded []time.Time, now time.Time) (time.Time, bool) {
+	rule := event.Rule
+	count := 0
+	for period := 0; period < maxRecurrencePeriods; period++ {
+		for _, start := range recurrencePeriod(event.Start, rule, period) {
+			if start.Before(event.Start) {
+				continue
+			}
+			if (!rule.Until.IsZero() && start.After(rule.Until)) || start.After(now) {
+				return time.Time{}, false
+			}
+			count++
+			if rule.Count > 0 && count > rule.Count {
+				return time.Time{}, false
+			}
+			if slices.ContainsFunc(excluded, start.Equal) {
+				continue
+			}
+			if now.Before(start.Add(event.Duration)) {
+				return start, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// recurrencePeriod returns the occurrences of the period-th period (day, week, month or year times INTERVAL)
+// of a rule starting at dtstart, in order. Dates that don't exist (Feb 30) are skipped.
+func recurrencePeriod(dtstart time.Time, rule *recurrenceRule, period int) []time.Time {
+	n := period * rule.Interval
+	y, m, d := dtstart.Date()
+	hh, mm, ss := dtstart.Clock()
+	loc := dtstart.Location()
+	at := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, hh, mm, ss, 0, loc) }
+
+	switch rule.Freq {
+	case "DAILY":
+		return []time.Time{at(y, m, d+n)}
+	case "WEEKLY":
+		day := at(y, m, d+7*n)
+		if len(rule.ByDay) == 0 {
+			return []time.Time{day}
+		}
+		monday := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		var starts []time.Time
+		for _, byDay := range rule.ByDay {
+			starts = append(starts, monday.AddDate(0, 0, (int(byDay.Day)+6)%7))
+		}
+		return starts
+	case "MONTHLY":
+		first := at(y, m+time.Month(n), 1)
+		if len(rule.ByDay) == 0 {
+			if t := at(first.Year(), first.Month(), d); t.Day() == d {
+				return []time.Time{t}
+			}
+			return nil
+		}
+		var starts []time.Time
+		for _, byDay := range rule.ByDay {
+			starts = append(starts, nthWeekdays(first, byDay)...)
+		}
+		slices.SortFunc(starts, func(a, b time.Time) int { return a.Compare(b) })
+		return starts
+	case "YEARLY":
+		if t := at(y+n, m, d); t.Day() == d {
+			return []time.Time{t}
+		}
+	}
+	return nil
+}
+
+// nthWeekdays returns the days of the month of first matching a BYDAY entry (every one when N is 0).
+func nthWeekdays(first time.Time, byDay ruleWeekday) []time.Time {
+	var days []time.Time
+	for t := first; t.Month() == first.Month(); t = t.AddDate(0, 0, 1) {
+		if t.Weekday() == byDay.Day {
+			days = append(days, t)
+		}
+	}
+	switch {
+	case byDay.N == 0:
+		return days
+	case byDay.N > 0 && byDay.N <= len(days):
+		return days[byDay.N-1 : byDay.N]
+	case byDay.N < 0 && -byDay.N <= len(days):
+		return days[len(days)+byDay.N : len(days)+byDay.N+1]
+	}
+	return nil
+}
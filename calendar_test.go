@@ -0,0 +1,212 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testCalendar = `BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VTIMEZONE
+TZID:Asia/Tokyo
+BEGIN:STANDARD
+DTSTART:19700101T000000
+TZOFFSETFROM:+0900
+TZOFFSETTO:+0900
+END:STANDARD
+END:VTIMEZONE
+BEGIN:VEVENT
+UID:standup
+SUMMARY:Daily standup
+DTSTART;TZID="Asia/Tokyo":20250106T100000
+DURATION:PT15M
+RRULE:FREQ=WEEKLY;BYDAY=MO,WE,FR
+EXDATE;TZID=Asia/Tokyo:20250108T100000
+BEGIN:VALARM
+TRIGGER:-PT10M
+DTSTART:20250101T000000Z
+END:VALARM
+END:VEVENT
+BEGIN:VEVENT
+UID:standup
+SUMMARY:Daily standup (moved)
+RECURRENCE-ID;TZID=Asia/Tokyo:20250110T100000
+DTSTART;TZID=Asia/Tokyo:20250110T160000
+DTEND;TZID=Asia/Tokyo:20250110T161500
+END:VEVENT
+BEGIN:VEVENT
+UID:review
+SUMMARY:Design review\, API
+  v2
+DTSTART:20250107T050000Z
+DTEND:20250107T060000Z
+END:VEVENT
+BEGIN:VEVENT
+UID:retro
+SUMMARY:Retro
+DTSTART;TZID=Asia/Tokyo:20250114T150000
+DTEND;TZID=Asia/Tokyo:20250114T160000
+RRULE:FREQ=MONTHLY;BYDAY=2TU;COUNT=3
+END:VEVENT
+BEGIN:VEVENT
+UID:holiday
+SUMMARY:Holiday
+DTSTART;VALUE=DATE:20250107
+DTEND;VALUE=DATE:20250108
+END:VEVENT
+BEGIN:VEVENT
+UID:focus
+SUMMARY:Focus time
+TRANSP:TRANSPARENT
+DTSTART:20250109T000000Z
+DTEND:20250109T080000Z
+END:VEVENT
+BEGIN:VEVENT
+UID:cancelled
+SUMMARY:Cancelled 1on1
+STATUS:CANCELLED
+DTSTART:20250109T010000Z
+DTEND:20250109T020000Z
+END:VEVENT
+END:VCALENDAR
+`
+
+func TestMeetingAt(t *testing.T) {
+	events, err := parseICS(strings.ReplaceAll(testCalendar, "\n", "\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip(err)
+	}
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2025, month, day, hour, minute, 0, 0, tokyo)
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want string
+	}{
+		{name: "first occurrence", now: at(1, 6, 10, 5), want: "Daily standup"},
+		{name: "end is exclusive", now: at(1, 6, 10, 15)},
+		{name: "before the first occurrence", now: at(1, 3, 10, 5)},
+		{name: "excluded occurrence", now: at(1, 8, 10, 5)},
+		{name: "overridden occurrence", now: at(1, 10, 10, 5)},
+		{name: "moved occurrence", now: at(1, 10, 16, 5), want: "Daily standup (moved)"},
+		{name: "weeks later", now: at(3, 5, 10, 0), want: "Daily standup"},
+		{name: "not a BYDAY", now: at(3, 4, 10, 0)},
+		{name: "single event in UTC", now: at(1, 7, 14, 30), want: "Design review, API v2"},
+		{name: "monthly second Tuesday", now: at(2, 11, 15, 30), want: "Retro"},
+		{name: "monthly first Tuesday", now: at(2, 4, 15, 30)},
+		{name: "after COUNT", now: at(4, 8, 15, 30)},
+		{name: "all-day event", now: at(1, 7, 12, 0)},
+		{name: "free event", now: at(1, 9, 12, 0)},
+		{name: "cancelled event", now: at(1, 9, 10, 30)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, _, ok := meetingAt(events, tt.now)
+			if got := map[bool]string{true: event.Summary}[ok]; got != tt.want {
+				t.Errorf("meetingAt(%v) = %q, want %q", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseICSErrors(t *testing.T) {
+	for _, data := range []string{
+		"not a calendar",
+		"BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:2025-01-06\nEND:VEVENT\nEND:VCALENDAR\n",
+		"BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20250106T100000Z\nRRULE:FREQ=HOURLY\nEND:VEVENT\nEND:VCALENDAR\n",
+		"BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20250106T100000Z\nDURATION:1H\nEND:VEVENT\nEND:VCALENDAR\n",
+	} {
+		if _, err := parseICS(data); err == nil {
+			t.Errorf("parseICS(%q): want error", data)
+		}
+	}
+}
+
+func TestParseICSDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"PT15M":    15 * time.Minute,
+		"PT1H30M":  90 * time.Minute,
+		"P1D":      24 * time.Hour,
+		"P1W":      7 * 24 * time.Hour,
+		"P1DT2H":   26 * time.Hour,
+		"+PT0H45S": 45 * time.Second,
+	}
+	for value, want := range tests {
+		if got, err := parseICSDuration(value); err != nil || got != want {
+			t.Errorf("parseICSDuration(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+}
+
+func TestInMeeting(t *testing.T) {
+	cacheDir := filepath.Join(t.TempDir(), "calendars")
+	origCacheDir := calendarCacheDir
+	calendarCacheDir = func() string { return cacheDir }
+	t.Cleanup(func() { calendarCacheDir = origCacheDir })
+	currentTime = func() time.Time { return time.Date(2025, 1, 7, 5, 30, 0, 0, time.UTC) }
+	t.Cleanup(func() { currentTime = time.Now })
+
+	requests := 0
+	fail := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if fail || r.URL.Path != "/secret/basic.ics" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(testCalendar))
+	}))
+	defer server.Close()
+	t.Setenv("TEST_CALENDAR_URL", server.URL+"/secret/basic.ics")
+
+	rawJSON := map[string]any{}
+	matched, err := checkCommonCondition(Condition{Type: ConditionInMeeting, Value: "$TEST_CALENDAR_URL"}, &BaseInput{RawJSON: rawJSON})
+	if err != nil || !matched {
+		t.Fatalf("in_meeting = %v, %v, want true", matched, err)
+	}
+	meeting, _ := rawJSON["meeting"].(map[string]any)
+	if meeting["summary"] != "Design review, API v2" || !strings.HasPrefix(meeting["end"].(string), time.Date(2025, 1, 7, 6, 0, 0, 0, time.UTC).In(time.Local).Format("2006-01-02T15:04")) {
+		t.Errorf("meeting = %v", meeting)
+	}
+
+	// キャッシュが新しいうちはダウンロードしない
+	if _, err := inMeeting("$TEST_CALENDAR_URL", &BaseInput{}); err != nil || requests != 1 {
+		t.Errorf("cached: requests = %d, err = %v, want 1 request", requests, err)
+	}
+
+	// 古いキャッシュはダウンロードに失敗しても使う
+	entries, _ := os.ReadDir(calendarCacheDir())
+	for _, entry := range entries {
+		old := time.Now().Add(-time.Hour)
+		_ = os.Chtimes(filepath.Join(calendarCacheDir(), entry.Name()), old, old)
+	}
+	fail = true
+	if matched, err := inMeeting("$TEST_CALENDAR_URL", &BaseInput{}); err != nil || !matched || requests != 2 {
+		t.Errorf("stale cache: %v, %v, requests = %d", matched, err, requests)
+	}
+
+	_, err = inMeeting(server.URL+"/other.ics", &BaseInput{})
+	if err == nil || strings.Contains(err.Error(), server.URL) {
+		t.Errorf("download error = %v, want an error without the URL", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "calendar.ics")
+	if err := os.WriteFile(file, []byte(testCalendar), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	currentTime = func() time.Time { return time.Date(2025, 1, 7, 7, 0, 0, 0, time.UTC) }
+	if matched, err := inMeeting(file, &BaseInput{}); err != nil || matched {
+		t.Errorf("local file: %v, %v, want false", matched, err)
+	}
+}
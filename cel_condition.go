@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"cel.dev/cel-go/cel"
+	"cel.dev/cel-go/ext"
+)
+
+// celInputTypes are the inputs of every event. Their top-level fields are the variables of cel conditions.
+var celInputTypes = []any{
+	PreToolUseInput{}, PostToolUseInput{}, PermissionRequestInput{}, NotificationInput{}, StopInput{},
+	SubagentStopInput{}, SubagentStartInput{}, PreCompactInput{}, SessionStartInput{}, SessionEndInput{},
+	UserPromptSubmitInput{},
+}
+
+// celVariable is a variable of cel conditions: a top-level field of the event input.
+type celVariable struct {
+	name string
+	typ  *cel.Type
+	zero any // value when the event has no such field
+}
+
+// celVariables returns the top-level fields of the event inputs with their types:
+// strings and booleans are typed, other fields (tool_input, tool_response, ...) are dyn.
+// A field with different types in different events is dyn too.
+var celVariables = sync.OnceValue(func() []celVariable {
+	var vars []celVariable
+	var add func(t reflect.Type)
+	add = func(t reflect.Type) {
+		for i := range t.NumField() {
+			field := t.Field(i)
+			if field.Anonymous {
+				add(field.Type)
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			v := celVariable{name: name, typ: cel.DynType, zero: map[string]any{}}
+			switch field.Type.Kind() {
+			case reflect.String:
+				v.typ, v.zero = cel.StringType, ""
+			case reflect.Bool:
+				v.typ, v.zero = cel.BoolType, false
+			case reflect.Slice:
+				v.zero = []any{}
+			}
+			if j := slices.IndexFunc(vars, func(existing celVariable) bool { return existing.name == name }); j >= 0 {
+				if vars[j].typ != v.typ {
+					vars[j].typ = cel.DynType
+				}
+				continue
+			}
+			vars = append(vars, v)
+		}
+	}
+	for _, input := range celInputTypes {
+		add(reflect.TypeOf(input))
+	}
+	return vars
+})
+
+// celEnv is the environment of cel conditions: the event fields as variables, `input` for the whole event,
+// and CEL's string extensions.
+var celEnv = sync.OnceValues(func() (*cel.Env, error) {
+	opts := []cel.EnvOption{cel.Variable("input", cel.MapType(cel.StringType, cel.DynType)), ext.Strings()}
+	for _, v := range celVariables() {
+		opts = append(opts, cel.Variable(v.name, v.typ))
+	}
+	return cel.NewEnv(opts...)
+})
+
+// cel条件の式のキャッシュ（serveでは同じ式をイベント毎にコンパイルしないため）
+var (
+	celProgramCache = make(map[string]cel.Program)
+	celProgramMutex sync.RWMutex
+)
+
+// compileCELCondition type-checks a cel condition expression, which must evaluate to a bool, and caches the program.
+func compileCELCondition(expr string) (cel.Program, error) {
+	celProgramMutex.RLock()
+	program, exists := celProgramCache[expr]
+	celProgramMutex.RUnlock()
+	if exists {
+		return program, nil
+	}
+
+	env, err := celEnv()
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("invalid expression: %s", strings.ReplaceAll(issues.Err().Error(), "\n", " "))
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("expression must evaluate to bool, got %s", ast.OutputType())
+	}
+	program, err = env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression: %w", err)
+	}
+	celProgramMutex.Lock()
+	celProgramCache[expr] = program
+	celProgramMutex.Unlock()
+	return program, nil
+}
+
+// evaluateCELCondition evaluates a cel condition against the event JSON.
+// Fields the event doesn't have are empty ("", false, {} or []).
+func evaluateCELCondition(expr string, baseInput *BaseInput) (bool, error) {
+	program, err := compileCELCondition(expr)
+	if err != nil {
+		return false, err
+	}
+
+	input, ok := baseInput.RawJSON.(map[string]any)
+	if !ok {
+		// コードで組み立てた入力は共通フィールドだけを使う
+		data, err := json.Marshal(baseInput)
+		if err != nil {
+			return false, err
+		}
+		if err := json.Unmarshal(data, &input); err != nil {
+			return false, err
+		}
+	}
+	activation := map[string]any{"input": input}
+	for _, v := range celVariables() {
+		value, ok := input[v.name]
+		if !ok || value == nil || (v.typ == cel.StringType && reflect.TypeOf(value).Kind() != reflect.String) ||
+			(v.typ == cel.BoolType && reflect.TypeOf(value).Kind() != reflect.Bool) {
+			value = v.zero
+		}
+		activation[v.name] = value
+	}
+
+	result, _, err := program.Eval(activation)
+	if err != nil {
+		return false, err
+	}
+	matched, ok := result.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression must evaluate to bool, got %v", result.Value())
+	}
+	return matched, nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+)
+
+// defaultChangelogGlobs are the files changelog_not_updated expects to be updated when the value has no "--" part.
+var defaultChangelogGlobs = []string{"**/CHANGELOG.md", "docs/adr/**"}
+
+// fileEditTools are the tools whose file_path (notebook_path for NotebookEdit) counts as a file modified in the session.
+var fileEditTools = []string{"Write", "Edit", "MultiEdit", "NotebookEdit"}
+
+// parseChangelogNotUpdatedValue splits a changelog_not_updated value "<significant globs> [-- <changelog globs>]"
+// into its globs (shell words, relative to cwd or absolute).
+func parseChangelogNotUpdatedValue(value string) (significant, changelog []string, err error) {
+	fields, err := splitCommandFields(value)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid value %q: %w", value, err)
+	}
+	if i := slices.Index(fields, "--"); i >= 0 {
+		significant, changelog = fields[:i], fields[i+1:]
+		if len(changelog) == 0 {
+			return nil, nil, fmt.Errorf("invalid value %q: expected changelog globs after --", value)
+		}
+	} else {
+		significant, changelog = fields, defaultChangelogGlobs
+	}
+	if len(significant) == 0 {
+		return nil, nil, fmt.Errorf("invalid value %q: expected the globs of significant paths", value)
+	}
+	for _, pattern := range slices.Concat(significant, changelog) {
+		if err := validateGlob(pattern); err != nil {
+			return nil, nil, err
+		}
+	}
+	return significant, changelog, nil
+}
+
+// changelogNotUpdated reports whether files matching the significant globs of value were modified in the session
+// while none matching the changelog globs were. Modified files are the Write/Edit/MultiEdit/NotebookEdit
+// tool uses of the session in the transcript.
+func changelogNotUpdated(value string, baseInput *BaseInput) (bool, error) {
+	significant, changelog, err := parseChangelogNotUpdatedValue(value)
+	if err != nil {
+		return false, err
+	}
+	files, err := cachedSessionEditedFiles(baseInput.TranscriptPath, baseInput.SessionID)
+	if err != nil {
+		return false, err
+	}
+	significantModified := false
+	for _, file := range files {
+		if matchAnyGlob(changelog, file, baseInput.Cwd) {
+			return false, nil
+		}
+		significantModified = significantModified || matchAnyGlob(significant, file, baseInput.Cwd)
+	}
+	return significantModified, nil
+}
+
+// matchAnyGlob reports whether filePath matches any of the glob patterns (see globTarget).
+// The patterns are validated beforehand, so match errors are not possible.
+func matchAnyGlob(patterns []string, filePath, cwd string) bool {
+	for _, pattern := range patterns {
+		target, ok := globTarget(pattern, filePath, cwd)
+		if !ok {
+			continue
+		}
+		if matched, _ := matchGlob(pattern, target); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// sessionEditedFiles returns the paths of the files the session's file edit tool uses in the transcript wrote, in order.
+// A transcript that does not exist yet means no files were modified.
+func sessionEditedFiles(transcriptPath, sessionID string) ([]string, error) {
+	file, err := os.Open(transcriptPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open transcript: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var files []string
+	err = forEachTranscriptEntry(file, func(entry transcriptEntry) {
+		if entry.Type != "assistant" || entry.SessionID != sessionID || entry.Message == nil {
+			return
+		}
+		var blocks []struct {
+			Type  string `json:"type"`
+			Name  string `json:"name"`
+			Input struct {
+				FilePath     string `json:"file_path"`
+				NotebookPath string `json:"notebook_path"`
+			} `json:"input"`
+		}
+		if json.Unmarshal(entry.Message.Content, &blocks) != nil {
+			return
+		}
+		for _, block := range blocks {
+			if block.Type != "tool_use" || !slices.Contains(fileEditTools, block.Name) {
+				continue
+			}
+			path := block.Input.FilePath
+			if path == "" {
+				path = block.Input.NotebookPath
+			}
+			if path != "" && !slices.Contains(files, path) {
+				files = append(files, path)
+			}
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+	return files, nil
+}
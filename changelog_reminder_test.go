@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeEditTranscript writes a transcript with one assistant tool use per file (tool name "Write" unless "Tool:path").
+func writeEditTranscript(t *testing.T, sessionID string, files ...string) string {
+	t.Helper()
+	var lines []string
+	for _, file := range files {
+		tool, path, ok := strings.Cut(file, ":")
+		if !ok {
+			tool, path = "Write", file
+		}
+		key := "file_path"
+		if tool == "NotebookEdit" {
+			key = "notebook_path"
+		}
+		lines = append(lines, `{"type":"assistant","sessionId":"`+sessionID+`","message":{"content":[{"type":"text","text":"ok"},{"type":"tool_use","name":"`+tool+`","input":{"`+key+`":"`+path+`"}}]}}`)
+	}
+	lines = append(lines, `{"type":"assistant","sessionId":"other","message":{"content":[{"type":"tool_use","name":"Write","input":{"file_path":"/repo/CHANGELOG.md"}}]}}`)
+	path := filepath.Join(t.TempDir(), "transcript.jsonl")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSessionEditedFiles(t *testing.T) {
+	transcript := writeEditTranscript(t, "s1", "/repo/a.go", "Edit:/repo/a.go", "Read:/repo/b.go", "NotebookEdit:/repo/c.ipynb", "MultiEdit:d.go")
+	got, err := sessionEditedFiles(transcript, "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"/repo/a.go", "/repo/c.ipynb", "d.go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sessionEditedFiles() = %q, want %q", got, want)
+	}
+
+	got, err = sessionEditedFiles(filepath.Join(t.TempDir(), "missing.jsonl"), "s1")
+	if err != nil || got != nil {
+		t.Errorf("missing transcript: got %q, %v, want none", got, err)
+	}
+}
+
+func TestChangelogNotUpdated(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		files []string
+		want  bool
+	}{
+		{name: "significant change without changelog", value: "src/**", files: []string{"/repo/src/api/users.go"}, want: true},
+		{name: "changelog updated", value: "src/**", files: []string{"/repo/src/api/users.go", "/repo/CHANGELOG.md"}, want: false},
+		{name: "nested changelog", value: "src/**", files: []string{"/repo/src/a.go", "/repo/packages/web/CHANGELOG.md"}, want: false},
+		{name: "ADR added", value: "src/** api/*.proto", files: []string{"/repo/api/users.proto", "Write:/repo/docs/adr/0007-users.md"}, want: false},
+		{name: "insignificant change", value: "src/**", files: []string{"/repo/README.md"}, want: false},
+		{name: "no changes", value: "src/**", want: false},
+		{name: "custom changelog globs", value: "src/** -- docs/decisions/*.md", files: []string{"/repo/src/a.go", "/repo/CHANGELOG.md"}, want: true},
+		{name: "custom changelog updated", value: "src/** -- docs/decisions/*.md", files: []string{"/repo/src/a.go", "/repo/docs/decisions/1.md"}, want: false},
+		{name: "outside cwd", value: "src/**", files: []string{"/other/src/a.go"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			baseInput := &BaseInput{SessionID: "s1", Cwd: "/repo", TranscriptPath: writeEditTranscript(t, "s1", tt.files...)}
+			got, err := checkCommonCondition(Condition{Type: ConditionChangelogNotUpdated, Value: tt.value}, baseInput)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("changelog_not_updated(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseChangelogNotUpdatedValue(t *testing.T) {
+	for _, value := range []string{"", "--", "src/** --", "-- CHANGELOG.md", "src/{a"} {
+		if _, _, err := parseChangelogNotUpdatedValue(value); err == nil {
+			t.Errorf("parseChangelogNotUpdatedValue(%q): want error", value)
+		}
+	}
+	significant, changelog, err := parseChangelogNotUpdatedValue(`"src dir/**" -- CHANGES.md`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(significant, []string{"src dir/**"}) || !reflect.DeepEqual(changelog, []string{"CHANGES.md"}) {
+		t.Errorf("got %q, %q", significant, changelog)
+	}
+}
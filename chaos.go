@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// chaosFault is a kind of failure injected into command actions by `-chaos`.
+type chaosFault string
+
+const (
+	chaosFaultFailure   chaosFault = "failure"          // command exits with non-zero status
+	chaosFaultTimeout   chaosFault = "timeout"          // command is killed before finishing
+	chaosFaultMalformed chaosFault = "malformed_output" // command exits 0 with invalid JSON on stdout
+)
+
+// chaosFaults are the faults injected by `-chaos`, in the order they are reported.
+var chaosFaults = []chaosFault{chaosFaultFailure, chaosFaultTimeout, chaosFaultMalformed}
+
+// chaosCommandRunner is a CommandRunner that never runs commands and always returns the injected fault.
+type chaosCommandRunner struct {
+	fault chaosFault
+}
+
+// RunCommand implements CommandRunner.RunCommand
+func (r *chaosCommandRunner) RunCommand(cmd string, useStdin bool, data any) error {
+	_, _, _, err := r.RunCommandWithOutput(cmd, useStdin, data)
+	return err
+}
+
+// RunCommandWithOutput implements CommandRunner.RunCommandWithOutput
+func (r *chaosCommandRunner) RunCommandWithOutput(cmd string, useStdin bool, data any) (stdout, stderr string, exitCode int, err error) {
+	switch r.fault {
+	case chaosFaultTimeout:
+		return "", "", -1, fmt.Errorf("chaos: command timed out: %s", cmd)
+	case chaosFaultMalformed:
+		return "{chaos: not json", "", 0, nil
+	default:
+		return "", "chaos: injected failure", 1, fmt.Errorf("chaos: command failed: %s", cmd)
+	}
+}
+
+// chaosHTTPClient is an HTTPClient that never sends requests and always returns the injected fault.
+type chaosHTTPClient struct {
+	fault chaosFault
+}
+
+// Do implements HTTPClient.Do
+func (c *chaosHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	switch c.fault {
+	case chaosFaultTimeout:
+		return nil, fmt.Errorf("chaos: %s %s: %w", req.Method, req.URL, context.DeadlineExceeded)
+	case chaosFaultMalformed:
+		return chaosHTTPResponse(req, http.StatusOK, "{chaos: not json"), nil
+	default:
+		return chaosHTTPResponse(req, http.StatusInternalServerError, "chaos: injected failure"), nil
+	}
+}
+
+// chaosHTTPResponse returns a JSON response with the given status and body.
+func chaosHTTPResponse(req *http.Request, status int, body string) *http.Response {
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}
+}
+
+// runChaos performs a dry-run of the event read from r and then executes the hooks once per chaosFault,
+// with every command and http action replaced by the fault, so that fail-safe decisions (deny/block) can be verified.
+// The hooks run without side effects, so a chaos run leaves no cooldown, queued event or ledger entry behind.
+func runChaos(w io.Writer, r io.Reader, config *Config, eventType HookEventType) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+
+	if err := dryRunHooksFrom(w, bytes.NewReader(data), config, eventType); err != nil {
+		return err
+	}
+
+	originalRunner, originalHTTPClient := DefaultCommandRunner, DefaultHTTPClient
+	defer func() { DefaultCommandRunner, DefaultHTTPClient = originalRunner, originalHTTPClient }()
+
+	for _, fault := range chaosFaults {
+		DefaultCommandRunner = &chaosCommandRunner{fault: fault}
+		DefaultHTTPClient = &chaosHTTPClient{fault: fault}
+
+		fmt.Fprintf(w, "\n=== Chaos: %s ===\n", fault)
+		output, err := executeHooksFrom(bytes.NewReader(data), config, eventType, false)
+		if err != nil {
+			fmt.Fprintf(w, "Error: %v\n", err)
+		}
+		jsonBytes, err := json.MarshalIndent(output, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal output: %w", err)
+		}
+		fmt.Fprintln(w, string(jsonBytes))
+		fmt.Fprintf(w, "Decision: %s\n", chaosDecision(jsonBytes))
+	}
+	return nil
+}
+
+// chaosDecision extracts the effective decision from an event's JSON output.
+func chaosDecision(jsonBytes []byte) string {
+	var output struct {
+		Decision           string `json:"decision"`
+		HookSpecificOutput struct {
+			PermissionDecision string `json:"permissionDecision"`
+			Decision           struct {
+				Behavior string `json:"behavior"`
+			} `json:"decision"`
+		} `json:"hookSpecificOutput"`
+	}
+	if err := json.Unmarshal(jsonBytes, &output); err != nil {
+		return "(unknown)"
+	}
+	switch {
+	case output.HookSpecificOutput.PermissionDecision != "":
+		return output.HookSpecificOutput.PermissionDecision
+	case output.HookSpecificOutput.Decision.Behavior != "":
+		return output.HookSpecificOutput.Decision.Behavior
+	case output.Decision != "":
+		return output.Decision
+	default:
+		return "(none)"
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRunChaos_PreToolUseFailSafe(t *testing.T) {
+	config, err := parseConfig("chaos.yaml", []byte(`PreToolUse:
+  - matcher: "Bash"
+    actions:
+      - type: command
+        command: "check-command {.tool_input.command}"
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	var buf bytes.Buffer
+	input := `{"hook_event_name":"PreToolUse","tool_name":"Bash","tool_input":{"command":"ls"}}`
+	if err := runChaos(&buf, strings.NewReader(input), config, PreToolUse); err != nil {
+		t.Fatalf("runChaos() error = %v", err)
+	}
+	output := buf.String()
+
+	if !strings.Contains(output, "[Hook 1] Would execute:") {
+		t.Errorf("Expected dry-run output, got:\n%s", output)
+	}
+	for _, fault := range chaosFaults {
+		section := "=== Chaos: " + string(fault) + " ==="
+		idx := strings.Index(output, section)
+		if idx < 0 {
+			t.Errorf("Expected section %q, got:\n%s", section, output)
+			continue
+		}
+		rest := output[idx+len(section):]
+		if next := strings.Index(rest, "=== Chaos:"); next >= 0 {
+			rest = rest[:next]
+		}
+		if !strings.Contains(rest, "Decision: deny") {
+			t.Errorf("Expected fail-safe deny for %s, got:\n%s", fault, rest)
+		}
+	}
+
+	// 実行後は元のCommandRunnerに戻す
+	if _, ok := DefaultCommandRunner.(*chaosCommandRunner); ok {
+		t.Error("Expected DefaultCommandRunner to be restored")
+	}
+}
+
+func TestRunChaos_StopFailSafe(t *testing.T) {
+	config, err := parseConfig("chaos.yaml", []byte(`Stop:
+  - actions:
+      - type: command
+        command: "make test"
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	var buf bytes.Buffer
+	input := `{"hook_event_name":"Stop","stop_hook_active":false}`
+	if err := runChaos(&buf, strings.NewReader(input), config, Stop); err != nil {
+		t.Fatalf("runChaos() error = %v", err)
+	}
+
+	if got := strings.Count(buf.String(), "Decision: block"); got != len(chaosFaults) {
+		t.Errorf("Expected %d block decisions, got %d:\n%s", len(chaosFaults), got, buf.String())
+	}
+}
+
+func TestRunChaos_HTTPAction(t *testing.T) {
+	// chaosでは実際のリクエストは送られない
+	config, err := parseConfig("chaos.yaml", []byte(`Stop:
+  - actions:
+      - type: http
+        url: "http://127.0.0.1:1/never-called"
+        body:
+          text: "{.session_id}"
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	var buf bytes.Buffer
+	input := `{"session_id":"s1","hook_event_name":"Stop","stop_hook_active":false}`
+	if err := runChaos(&buf, strings.NewReader(input), config, Stop); err != nil {
+		t.Fatalf("runChaos() error = %v", err)
+	}
+
+	output := buf.String()
+	if !strings.Contains(output, "HTTP: POST http://127.0.0.1:1/never-called") || !strings.Contains(output, `Body: {"text":"s1"}`) {
+		t.Errorf("Expected dry-run of the http action, got:\n%s", output)
+	}
+	if got := strings.Count(output, "Decision: block"); got != len(chaosFaults) {
+		t.Errorf("Expected %d block decisions, got %d:\n%s", len(chaosFaults), got, output)
+	}
+	if _, ok := DefaultHTTPClient.(*chaosHTTPClient); ok {
+		t.Error("Expected DefaultHTTPClient to be restored")
+	}
+}
+
+// stateFiles returns the contents of the files under dir by their paths.
+func stateFiles(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		files[path] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestRunChaos_LeavesStateUnchanged(t *testing.T) {
+	dir := withSessionStateDir(t)
+	started := withBatchDir(t)
+	batchDir = func() string { return filepath.Join(dir, "batch") }
+	origDigestPath := digestPath
+	digestPath = func() string { return filepath.Join(dir, "digest.jsonl") }
+	t.Cleanup(func() { digestPath = origDigestPath })
+
+	if err := queueDigest(&NotificationInput{Message: "Claude is waiting for your input"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := recordHookChange("s1", hookChange{File: "/repo/main.go", Event: "PostToolUse", Hook: 1}); err != nil {
+		t.Fatal(err)
+	}
+	before := stateFiles(t, dir)
+
+	config, err := parseConfig("chaos.yaml", []byte(`self_test: true
+SessionStart:
+  - cooldown: 1h
+    actions:
+      - type: time_tracking
+        ledger: `+filepath.Join(dir, "time.jsonl")+`
+PostToolUse:
+  - matcher: "Edit"
+    batch: true
+    actions:
+      - type: command
+        command: "gofmt -w {.tool_input.file_path}"
+  - matcher: "Edit"
+    snapshot: true
+    actions:
+      - type: command
+        command: "gofmt -w {.tool_input.file_path}"
+Notification:
+  - actions:
+      - type: digest
+Stop:
+  - actions:
+      - type: digest
+        flush: true
+      - type: hook_changes_report
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	file := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(file, []byte("package main\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	events := map[HookEventType]string{
+		SessionStart: `{"session_id":"s1","hook_event_name":"SessionStart","source":"startup"}`,
+		PostToolUse:  `{"session_id":"s1","hook_event_name":"PostToolUse","tool_name":"Edit","tool_input":{"file_path":"` + file + `"}}`,
+		Notification: `{"session_id":"s1","hook_event_name":"Notification","message":"Claude needs your permission"}`,
+		Stop:         `{"session_id":"s1","hook_event_name":"Stop"}`,
+	}
+	for eventType, input := range events {
+		var buf bytes.Buffer
+		if err := runChaos(&buf, strings.NewReader(input), config, eventType); err != nil {
+			t.Fatalf("runChaos(%s) error = %v", eventType, err)
+		}
+	}
+
+	if after := stateFiles(t, dir); !reflect.DeepEqual(after, before) {
+		t.Errorf("chaos changed the state directory:\nbefore: %v\nafter:  %v", before, after)
+	}
+	if len(*started) != 0 {
+		t.Errorf("chaos started batch flushers: %v", *started)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(file)); len(entries) != 1 {
+		t.Errorf("chaos left snapshots next to the file: %v", entries)
+	}
+	if diagnosticRun {
+		t.Error("Expected diagnosticRun to be reset")
+	}
+}
+
+func TestChaosDecision(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want string
+	}{
+		{"PreToolUse", `{"hookSpecificOutput":{"permissionDecision":"deny"}}`, "deny"},
+		{"PermissionRequest", `{"hookSpecificOutput":{"decision":{"behavior":"allow"}}}`, "allow"},
+		{"Stop", `{"decision":"block"}`, "block"},
+		{"no decision", `{"continue":true}`, "(none)"},
+		{"null output", `null`, "(none)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chaosDecision([]byte(tt.json)); got != tt.want {
+				t.Errorf("chaosDecision() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
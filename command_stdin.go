@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// stdin modes of command actions.
+const (
+	stdinNone     = "none"     // stdinに何も渡さない
+	stdinRaw      = "raw"      // イベントのJSONをそのまま渡す (デフォルト)
+	stdinEnriched = "enriched" // cchookオブジェクトを追加したJSONを渡す
+)
+
+// commandStderrMaxLength caps the stderr a command action adds to systemMessage.
+const commandStderrMaxLength = 4000
+
+// validateStdinMode checks the stdin field of an action.
+func validateStdinMode(mode string) error {
+	switch mode {
+	case "", stdinNone, stdinRaw, stdinEnriched:
+		return nil
+	}
+	return fmt.Errorf("stdin must be none, raw or enriched, got %q", mode)
+}
+
+// commandStdinMode returns the stdin mode a command action uses: stdin if set, otherwise none for
+// `use_stdin: false` (the older spelling) and raw by default.
+func commandStdinMode(action Action) string {
+	switch {
+	case action.Stdin != "":
+		return action.Stdin
+	case action.UseStdin != nil && !*action.UseStdin:
+		return stdinNone
+	}
+	return stdinRaw
+}
+
+// commandStdin returns whether a command action gets JSON on stdin and the data to send.
+func commandStdin(action Action, rawJSON any) (bool, any) {
+	switch commandStdinMode(action) {
+	case stdinNone:
+		return false, nil
+	case stdinEnriched:
+		return true, enrichedInput(action, rawJSON)
+	}
+	return rawJSON != nil, rawJSON
+}
+
+// enrichedInput returns a copy of the event JSON with a cchook object describing where
+// and why the command runs, so that scripts don't have to work it out themselves.
+func enrichedInput(action Action, rawJSON any) any {
+	m, ok := rawJSON.(map[string]any)
+	if !ok {
+		return rawJSON
+	}
+	enriched := make(map[string]any, len(m)+1)
+	for k, v := range m {
+		enriched[k] = v
+	}
+
+	cwd, _ := m["cwd"].(string)
+	metadata := map[string]any{
+		"pid":    os.Getpid(),
+		"time":   currentTime().Format(time.RFC3339),
+		"action": action.Type,
+	}
+	if cwd != "" {
+		if branch := currentGitBranch(cwd); branch != "" {
+			metadata["git_branch"] = branch
+		}
+	}
+	if filePath := snapshotFilePath(m); filePath != "" {
+		metadata["file_path"] = filePath
+	}
+	enriched["cchook"] = metadata
+	return enriched
+}
+
+// withCommandStderr adds what a successful command action wrote to stderr to the systemMessage
+// of its output, so that warnings from the command reach the user instead of being dropped.
+// output may be nil (the action had nothing to say).
+func withCommandStderr(output *ActionOutput, action Action, stderr string) *ActionOutput {
+	stderr = strings.TrimSpace(stderr)
+	if action.Type != "command" || stderr == "" {
+		return output
+	}
+	if len(stderr) > commandStderrMaxLength {
+		stderr = strings.ToValidUTF8(stderr[:commandStderrMaxLength], "") + "\n... (stderr truncated)"
+	}
+	if output == nil {
+		output = &ActionOutput{Continue: true}
+	}
+	if output.SystemMessage != "" {
+		output.SystemMessage += "\n"
+	}
+	output.SystemMessage += stderr
+	return output
+}
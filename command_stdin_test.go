@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunCommandAction_Stdin(t *testing.T) {
+	withCurrentTime(t, time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC))
+	rawJSON := map[string]any{"tool_name": "Write", "cwd": "/repo", "tool_input": map[string]any{"file_path": "a.go"}}
+
+	tests := []struct {
+		name   string
+		action Action
+		want   string
+	}{
+		{name: "raw by default", action: Action{Type: "command", Command: "cat"}, want: `{"cwd":"/repo","tool_input":{"file_path":"a.go"},"tool_name":"Write"}`},
+		{name: "use_stdin", action: Action{Type: "command", Command: "cat", UseStdin: boolPtr(true)}, want: `{"cwd":"/repo","tool_input":{"file_path":"a.go"},"tool_name":"Write"}`},
+		{name: "use_stdin false", action: Action{Type: "command", Command: "cat", UseStdin: boolPtr(false)}, want: ""},
+		{name: "stdin wins over use_stdin", action: Action{Type: "command", Command: "cat", UseStdin: boolPtr(false), Stdin: "raw"}, want: `{"cwd":"/repo","tool_input":{"file_path":"a.go"},"tool_name":"Write"}`},
+		{name: "none", action: Action{Type: "command", Command: "cat", Stdin: "none"}, want: ""},
+		{name: "args", action: Action{Type: "command", Args: []string{"cat"}, Stdin: "none"}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, _, _, err := NewActionExecutor(nil).runAction(tt.action, rawJSON)
+			if err != nil || stdout != tt.want {
+				t.Errorf("runAction() = %q, %v; want %q", stdout, err, tt.want)
+			}
+		})
+	}
+
+	t.Run("enriched", func(t *testing.T) {
+		stdout, _, _, err := NewActionExecutor(nil).runAction(Action{Type: "command", Command: "cat", Stdin: "enriched"}, rawJSON)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got map[string]any
+		if err := json.Unmarshal([]byte(stdout), &got); err != nil {
+			t.Fatalf("stdin is not JSON: %q", stdout)
+		}
+		metadata, _ := got["cchook"].(map[string]any)
+		if metadata["action"] != "command" || metadata["time"] != "2026-10-16T09:00:00Z" || metadata["file_path"] != "/repo/a.go" || metadata["pid"] != float64(os.Getpid()) {
+			t.Errorf("cchook = %v", metadata)
+		}
+		if got["tool_name"] != "Write" {
+			t.Errorf("event fields are missing: %v", got)
+		}
+		if _, ok := rawJSON["cchook"]; ok {
+			t.Error("enriched input modified the event JSON")
+		}
+	})
+}
+
+func TestWithCommandStderr(t *testing.T) {
+	command := Action{Type: "command"}
+	if got := withCommandStderr(nil, command, " \n"); got != nil {
+		t.Errorf("empty stderr: got %+v, want nil", got)
+	}
+	if got := withCommandStderr(nil, Action{Type: "http"}, "warning"); got != nil {
+		t.Errorf("http action: got %+v, want nil", got)
+	}
+	got := withCommandStderr(nil, command, "warning: unused variable\n")
+	if got == nil || !got.Continue || got.SystemMessage != "warning: unused variable" {
+		t.Errorf("nil output: got %+v", got)
+	}
+	got = withCommandStderr(&ActionOutput{Continue: true, SystemMessage: "Formatted"}, command, "warning")
+	if got.SystemMessage != "Formatted\nwarning" {
+		t.Errorf("SystemMessage = %q", got.SystemMessage)
+	}
+	got = withCommandStderr(nil, command, strings.Repeat("x", commandStderrMaxLength+1))
+	if !strings.HasSuffix(got.SystemMessage, "(stderr truncated)") || len(got.SystemMessage) > commandStderrMaxLength+30 {
+		t.Errorf("SystemMessage was not truncated: %d bytes", len(got.SystemMessage))
+	}
+}
+
+func TestExecuteHooks_CommandStderr(t *testing.T) {
+	action := Action{Type: "command", Command: "echo 'gofmt: 1 file reformatted' >&2"}
+
+	t.Run("PreToolUse", func(t *testing.T) {
+		config := &Config{PreToolUse: []PreToolUseHook{{Matcher: "Write", Actions: []Action{action}}}}
+		input := &PreToolUseInput{ToolName: "Write"}
+		output, err := executePreToolUseHooksJSON(config, input, map[string]any{"tool_name": "Write"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if output == nil || output.SystemMessage != "gofmt: 1 file reformatted" {
+			t.Errorf("output = %+v", output)
+		}
+	})
+
+	t.Run("Stop", func(t *testing.T) {
+		got, err := NewActionExecutor(nil).ExecuteStopAction(action, &StopInput{}, map[string]any{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.SystemMessage != "gofmt: 1 file reformatted" || got.Decision != "" {
+			t.Errorf("output = %+v", got)
+		}
+	})
+}
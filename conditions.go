@@ -1,14 +1,21 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/text/unicode/norm"
 )
 
 // ErrConditionNotHandled indicates that a condition type is not handled by the checking function.
@@ -17,6 +24,12 @@ var ErrConditionNotHandled = errors.New("condition not handled by this function"
 // checkPreToolUseCondition checks if a condition matches for PreToolUse events.
 // Returns ErrConditionNotHandled if the condition type is not applicable to this event.
 func checkPreToolUseCondition(condition Condition, input *PreToolUseInput) (bool, error) {
+	if matched, handled, err := checkExpandedCondition(condition, func(c Condition) (bool, error) {
+		return checkPreToolUseCondition(c, input)
+	}); handled {
+		return matched, err
+	}
+
 	// まず汎用条件をチェック
 	matched, err := checkCommonCondition(condition, &input.BaseInput)
 	if err == nil {
@@ -27,7 +40,16 @@ func checkPreToolUseCondition(condition Condition, input *PreToolUseInput) (bool
 	}
 
 	// ツール固有の条件をチェック
-	matched, err = checkToolCondition(condition, &input.ToolInput)
+	matched, err = checkToolCondition(condition, &input.ToolInput, input.Cwd)
+	if err == nil {
+		return matched, nil // 処理された
+	}
+	if !errors.Is(err, ErrConditionNotHandled) {
+		return false, err // 本当のエラー
+	}
+
+	// MCPツールの条件をチェック
+	matched, err = checkMCPCondition(condition, input.ToolName)
 	if err == nil {
 		return matched, nil // 処理された
 	}
@@ -42,6 +64,12 @@ func checkPreToolUseCondition(condition Condition, input *PreToolUseInput) (bool
 // checkPostToolUseCondition checks if a condition matches for PostToolUse events.
 // Returns ErrConditionNotHandled if the condition type is not applicable to this event.
 func checkPostToolUseCondition(condition Condition, input *PostToolUseInput) (bool, error) {
+	if matched, handled, err := checkExpandedCondition(condition, func(c Condition) (bool, error) {
+		return checkPostToolUseCondition(c, input)
+	}); handled {
+		return matched, err
+	}
+
 	// まず汎用条件をチェック
 	matched, err := checkCommonCondition(condition, &input.BaseInput)
 	if err == nil {
@@ -52,7 +80,25 @@ func checkPostToolUseCondition(condition Condition, input *PostToolUseInput) (bo
 	}
 
 	// ツール固有の条件をチェック
-	matched, err = checkToolCondition(condition, &input.ToolInput)
+	matched, err = checkToolCondition(condition, &input.ToolInput, input.Cwd)
+	if err == nil {
+		return matched, nil // 処理された
+	}
+	if !errors.Is(err, ErrConditionNotHandled) {
+		return false, err // 本当のエラー
+	}
+
+	// MCPツールの条件をチェック
+	matched, err = checkMCPCondition(condition, input.ToolName)
+	if err == nil {
+		return matched, nil // 処理された
+	}
+	if !errors.Is(err, ErrConditionNotHandled) {
+		return false, err // 本当のエラー
+	}
+
+	// tool_responseの条件をチェック
+	matched, err = checkToolResponseCondition(condition, input.ToolName, input.ToolResponse)
 	if err == nil {
 		return matched, nil // 処理された
 	}
@@ -67,6 +113,12 @@ func checkPostToolUseCondition(condition Condition, input *PostToolUseInput) (bo
 // checkUserPromptSubmitCondition checks if a condition matches for UserPromptSubmit events.
 // Supports prompt_regex and every_n_prompts conditions in addition to common conditions.
 func checkUserPromptSubmitCondition(condition Condition, input *UserPromptSubmitInput) (bool, error) {
+	if matched, handled, err := checkExpandedCondition(condition, func(c Condition) (bool, error) {
+		return checkUserPromptSubmitCondition(c, input)
+	}); handled {
+		return matched, err
+	}
+
 	// まず汎用条件をチェック
 	matched, err := checkCommonCondition(condition, &input.BaseInput)
 	if err == nil {
@@ -87,7 +139,7 @@ func checkUserPromptSubmitCondition(condition Condition, input *UserPromptSubmit
 
 	// every_n_prompts条件をチェック
 	if condition.Type == ConditionEveryNPrompts {
-		count, err := countUserPromptsFromTranscript(input.TranscriptPath, input.SessionID)
+		count, err := cachedUserPromptCount(input.TranscriptPath, input.SessionID)
 		if err != nil {
 			return false, fmt.Errorf("failed to count prompts: %w", err)
 		}
@@ -111,6 +163,12 @@ func checkUserPromptSubmitCondition(condition Condition, input *UserPromptSubmit
 // checkSessionStartCondition checks if a condition matches for SessionStart events.
 // Only supports common conditions.
 func checkSessionStartCondition(condition Condition, input *SessionStartInput) (bool, error) {
+	if matched, handled, err := checkExpandedCondition(condition, func(c Condition) (bool, error) {
+		return checkSessionStartCondition(c, input)
+	}); handled {
+		return matched, err
+	}
+
 	// SessionStartは汎用条件のみ使用
 	matched, err := checkCommonCondition(condition, &input.BaseInput)
 	if err == nil {
@@ -124,49 +182,340 @@ func checkSessionStartCondition(condition Condition, input *SessionStartInput) (
 	return false, fmt.Errorf("unknown condition type for SessionStart: %s", condition.Type)
 }
 
+// checkExpandedCondition evaluates composite conditions (any_of/all_of/not) and conditions with multiple `values:`
+// by calling check for each child condition or value.
+// Multiple values are combined according to `match:` ("any" by default, or "all").
+// handled is false when the condition has a single value and should be checked as usual.
+func checkExpandedCondition(condition Condition, check func(Condition) (bool, error)) (matched bool, handled bool, err error) {
+	if isCompositeCondition(condition.Type) {
+		matched, err := checkCompositeCondition(condition, check)
+		return matched, true, err
+	}
+	if len(condition.Conditions) > 0 {
+		return false, true, fmt.Errorf("conditions can only be used with any_of, all_of or not: %s", condition.Type)
+	}
+
+	if len(condition.Values) == 0 {
+		if condition.Match != "" {
+			return false, true, fmt.Errorf("match requires values for condition type: %s", condition.Type)
+		}
+		return false, false, nil
+	}
+	if condition.Value != "" {
+		return false, true, fmt.Errorf("value and values cannot be used together for condition type: %s", condition.Type)
+	}
+
+	matchAll := false
+	switch condition.Match {
+	case "", "any":
+	case "all":
+		matchAll = true
+	default:
+		return false, true, fmt.Errorf("invalid match value: %s (must be 'any' or 'all')", condition.Match)
+	}
+
+	for _, value := range condition.Values {
+		single := condition
+		single.Value = value
+		single.Values = nil
+		single.Match = ""
+
+		matched, err := check(single)
+		if err != nil {
+			return false, true, err
+		}
+		if matched && !matchAll {
+			return true, true, nil
+		}
+		if !matched && matchAll {
+			return false, true, nil
+		}
+	}
+	return matchAll, true, nil
+}
+
+// isCompositeCondition reports whether t groups child conditions.
+func isCompositeCondition(t ConditionType) bool {
+	return t == ConditionAnyOf || t == ConditionAllOf || t == ConditionNot
+}
+
+// checkCompositeCondition evaluates child conditions of any_of / all_of / not with check.
+// not is true when the children (AND-ed) do not all match.
+func checkCompositeCondition(condition Condition, check func(Condition) (bool, error)) (bool, error) {
+	if len(condition.Conditions) == 0 {
+		return false, fmt.Errorf("%s requires at least one condition in conditions", condition.Type)
+	}
+
+	allMatched := true
+	for _, child := range condition.Conditions {
+		matched, err := check(child)
+		if err != nil {
+			return false, err
+		}
+		if matched && condition.Type == ConditionAnyOf {
+			return true, nil
+		}
+		if !matched {
+			allMatched = false
+			if condition.Type != ConditionAnyOf {
+				break
+			}
+		}
+	}
+
+	switch condition.Type {
+	case ConditionAnyOf:
+		return false, nil
+	case ConditionNot:
+		return !allMatched, nil
+	default:
+		return allMatched, nil
+	}
+}
+
 // checkCommonCondition checks common conditions that are applicable to all event types.
 // Includes file/directory existence checks and working directory conditions.
 func checkCommonCondition(condition Condition, baseInput *BaseInput) (bool, error) {
+	// gitが無い・cwdがリポジトリ外のときはon_git_unavailableに従う
+	if reason := gitUnavailableReason(condition.Type, baseInput.Cwd); reason != "" {
+		return gitUnavailable(condition.Type, reason)
+	}
+
 	switch condition.Type {
 	case ConditionFileExists:
 		// 指定ファイルが存在する
 		return fileExists(condition.Value), nil
 	case ConditionFileExistsRecursive:
 		// ファイルが再帰的に存在するか
-		return fileExistsRecursive(condition.Value), nil
+		found, err := fileExistsRecursive(condition)
+		if err != nil {
+			return false, fmt.Errorf("file_exists_recursive: %w", err)
+		}
+		return found, nil
 	case ConditionFileNotExists:
 		// 指定ファイルが存在しない
 		return !fileExists(condition.Value), nil
 	case ConditionFileNotExistsRecursive:
 		// ファイルが再帰的に存在しない
-		return !fileExistsRecursive(condition.Value), nil
+		found, err := fileExistsRecursive(condition)
+		if err != nil {
+			return false, fmt.Errorf("file_not_exists_recursive: %w", err)
+		}
+		return !found, nil
 	case ConditionDirExists:
 		// 指定ディレクトリが存在する
 		return dirExists(condition.Value), nil
 	case ConditionDirExistsRecursive:
 		// ディレクトリが再帰的に存在するか
-		return dirExistsRecursive(condition.Value), nil
+		found, err := dirExistsRecursive(condition)
+		if err != nil {
+			return false, fmt.Errorf("dir_exists_recursive: %w", err)
+		}
+		return found, nil
 	case ConditionDirNotExists:
 		// 指定ディレクトリが存在しない
 		return !dirExists(condition.Value), nil
 	case ConditionDirNotExistsRecursive:
 		// ディレクトリが再帰的に存在しない
-		return !dirExistsRecursive(condition.Value), nil
+		found, err := dirExistsRecursive(condition)
+		if err != nil {
+			return false, fmt.Errorf("dir_not_exists_recursive: %w", err)
+		}
+		return !found, nil
 	case ConditionCwdIs:
 		// cwdが完全一致
-		return baseInput.Cwd == condition.Value, nil
+		value, cwd, err := prepareStringMatch(condition, baseInput.Cwd)
+		return err == nil && cwd == value, err
 	case ConditionCwdIsNot:
 		// cwdが完全一致しない
-		return baseInput.Cwd != condition.Value, nil
+		value, cwd, err := prepareStringMatch(condition, baseInput.Cwd)
+		return err == nil && cwd != value, err
 	case ConditionCwdContains:
 		// cwdが特定の文字列を含む
-		return strings.Contains(baseInput.Cwd, condition.Value), nil
+		value, cwd, err := prepareStringMatch(condition, baseInput.Cwd)
+		return err == nil && strings.Contains(cwd, value), err
 	case ConditionCwdNotContains:
 		// cwdが特定の文字列を含まない
-		return !strings.Contains(baseInput.Cwd, condition.Value), nil
+		value, cwd, err := prepareStringMatch(condition, baseInput.Cwd)
+		return err == nil && !strings.Contains(cwd, value), err
+	case ConditionFileOlderThan:
+		// ファイルの更新日時が指定期間より古い（ファイルが無い場合はfalse）
+		older, exists, err := fileOlderThan(condition.Value)
+		return exists && older, err
+	case ConditionFileNewerThan:
+		// ファイルの更新日時が指定期間以内（ファイルが無い場合はfalse）
+		older, exists, err := fileOlderThan(condition.Value)
+		return exists && !older, err
+	case ConditionFileSHA256Is:
+		// ファイルのSHA-256が一致する（ファイルが無い場合はfalse）
+		return fileSHA256Is(condition.Value)
+	case ConditionFileContentEqualsFile:
+		// 2つのファイルの内容が一致する（どちらかが無い場合はfalse）
+		return fileContentEqualsFile(condition.Value)
 	case ConditionPermissionModeIs:
 		// permission_modeが完全一致
 		return baseInput.PermissionMode == condition.Value, nil
+	case ConditionSessionDurationLt, ConditionSessionDurationGt:
+		// transcriptのタイムスタンプから求めたセッションの長さと比較
+		threshold, err := parseDurationWithDays(condition.Value)
+		if err != nil {
+			return false, fmt.Errorf("invalid duration for %s: %w", condition.Type, err)
+		}
+		duration, err := cachedSessionDuration(baseInput.TranscriptPath, baseInput.SessionID)
+		if err != nil {
+			return false, fmt.Errorf("failed to get session duration: %w", err)
+		}
+		if condition.Type == ConditionSessionDurationLt {
+			return duration < threshold, nil
+		}
+		return duration > threshold, nil
+	case ConditionTokensUsedLt, ConditionTokensUsedGt:
+		// transcriptのusageから求めたセッションのトークン数と比較
+		threshold, err := parseTokenCount(condition.Value)
+		if err != nil {
+			return false, fmt.Errorf("invalid token count for %s: %w", condition.Type, err)
+		}
+		stats, err := cachedTranscriptStats(baseInput.TranscriptPath, baseInput.SessionID)
+		if err != nil {
+			return false, fmt.Errorf("failed to get tokens used: %w", err)
+		}
+		if condition.Type == ConditionTokensUsedLt {
+			return stats.TokensUsed < threshold, nil
+		}
+		return stats.TokensUsed > threshold, nil
+	case ConditionLastAssistantMatches:
+		// transcriptの最後のassistantメッセージのテキストが正規表現にマッチする（メッセージが無ければfalse）
+		stats, err := cachedTranscriptStats(baseInput.TranscriptPath, baseInput.SessionID)
+		if err != nil {
+			return false, fmt.Errorf("failed to get last assistant message: %w", err)
+		}
+		if stats.LastAssistantMessage == "" {
+			return false, nil
+		}
+		return matchRegex(condition, stats.LastAssistantMessage)
+	case ConditionChangelogNotUpdated:
+		// セッションで重要なパスを編集したのにCHANGELOG/ADRを編集していない
+		matched, err := changelogNotUpdated(condition.Value, baseInput)
+		if err != nil {
+			return false, fmt.Errorf("changelog_not_updated: %w", err)
+		}
+		return matched, nil
+	case ConditionTicketReferenced:
+		// ブランチ名かセッションのプロンプトにチケットIDがある（見つけたIDは.ticketに入る）
+		matched, err := ticketReferenced(condition, baseInput)
+		if err != nil {
+			return false, fmt.Errorf("ticket_referenced: %w", err)
+		}
+		return matched, nil
+	case ConditionInMeeting:
+		// カレンダー (iCalendar URL/ファイル) の予定が進行中（予定は.meetingに入る）
+		matched, err := inMeeting(condition.Value, baseInput)
+		if err != nil {
+			return false, fmt.Errorf("in_meeting: %w", err)
+		}
+		return matched, nil
+	case ConditionFreezeActive:
+		// 凍結期間ファイル (URL/ファイル) の期間中（期間は.freezeに入る）
+		matched, err := freezeActive(condition, baseInput)
+		if err != nil {
+			return false, fmt.Errorf("freeze_active: %w", err)
+		}
+		return matched, nil
+	case ConditionDigestPending:
+		// digestアクションで溜めた通知がある
+		pending, err := digestPending()
+		if err != nil {
+			return false, fmt.Errorf("digest_pending: %w", err)
+		}
+		return pending, nil
+	case ConditionBudgetExceeded:
+		// budget:の上限 (valueで指定したもの、省略時はいずれか) に達した
+		return checkBudgetExceeded(condition.Value, baseInput)
+	case ConditionBudgetRemainingBelow:
+		// budget:の上限の残りが指定の割合を下回った
+		return checkBudgetRemainingBelow(condition.Value, baseInput)
+	case ConditionToolInputJQ:
+		// 入力JSON全体に対するjq式の結果がtruthy（false/null以外）
+		var input any = baseInput
+		if baseInput.RawJSON != nil {
+			input = baseInput.RawJSON
+		}
+		matched, err := evaluateJQCondition(condition.Value, input)
+		if err != nil {
+			return false, fmt.Errorf("tool_input_jq: %w", err)
+		}
+		return matched, nil
+	case ConditionCEL:
+		// 入力JSONのフィールドを変数とするCEL式がtrue
+		matched, err := evaluateCELCondition(condition.Value, baseInput)
+		if err != nil {
+			return false, fmt.Errorf("cel: %w", err)
+		}
+		return matched, nil
+	case ConditionGitBranchIs:
+		// cwdのリポジトリで現在のブランチが完全一致（リポジトリ外やdetached HEADではfalse）
+		branch := currentGitBranch(baseInput.Cwd)
+		if branch == "" {
+			return false, nil
+		}
+		value, branch, err := prepareStringMatch(condition, branch)
+		return err == nil && branch == value, err
+	case ConditionGitBranchMatches:
+		// 現在のブランチが正規表現にマッチする
+		branch := currentGitBranch(baseInput.Cwd)
+		if branch == "" {
+			return false, nil
+		}
+		return matchRegex(condition, branch)
+	case ConditionGitDirty, ConditionGitClean:
+		// cwdのワーキングツリーに未コミットの変更があるか（リポジトリ外ではどちらもfalse）
+		dirty, inRepo, err := cachedGitWorktreeDirty(baseInput.Cwd)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", condition.Type, err)
+		}
+		return inRepo && dirty == (condition.Type == ConditionGitDirty), nil
+	case ConditionGitSigningEnabled:
+		// cwdでのコミットがデフォルトで署名されるか（commit.gpgsignと署名鍵の設定）
+		return cachedGitSigningEnabled(baseInput.Cwd, condition.Value), nil
+	case ConditionTimeBetween:
+		// 現在時刻（tzのタイムゾーン）が範囲内
+		matched, err := timeBetween(condition.Value, condition.TZ)
+		if err != nil {
+			return false, fmt.Errorf("time_between: %w", err)
+		}
+		return matched, nil
+	case ConditionDayOfWeek:
+		// 今日の曜日（tzのタイムゾーン）が指定した曜日のいずれか
+		matched, err := isDayOfWeek(condition.Value, condition.TZ)
+		if err != nil {
+			return false, fmt.Errorf("day_of_week: %w", err)
+		}
+		return matched, nil
+	case ConditionEnvIs, ConditionEnvSet, ConditionEnvMatches:
+		// cchookプロセスの環境変数（未設定ならenv_is/env_matchesもfalse）
+		name, operand, err := parseEnvConditionValue(condition.Type, condition.Value)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", condition.Type, err)
+		}
+		envValue, set := os.LookupEnv(name)
+		if !set || condition.Type == ConditionEnvSet {
+			return set, nil
+		}
+		// 値の部分だけをignore_case/normalizeの対象にする
+		operandCondition := condition
+		operandCondition.Value = operand
+		if condition.Type == ConditionEnvMatches {
+			return matchRegex(operandCondition, envValue)
+		}
+		value, envValue, err := prepareStringMatch(operandCondition, envValue)
+		return err == nil && envValue == value, err
+	case ConditionInDevcontainer:
+		// cwdのプロジェクトがdevcontainerを定義している
+		if baseInput.Cwd == "" {
+			return false, nil
+		}
+		_, ok := findDevcontainerProject(baseInput.Cwd)
+		return ok, nil
 	default:
 		// この関数では汎用条件のみをチェック
 		// 処理できない条件タイプの場合はErrConditionNotHandledを返す
@@ -175,27 +524,106 @@ func checkCommonCondition(condition Condition, baseInput *BaseInput) (bool, erro
 }
 
 // checkToolCondition checks tool-specific conditions like file_extension, command_contains, and url_starts_with.
+// cwd is the working directory relative file_path_matches patterns and relative file paths are resolved against.
 // Returns ErrConditionNotHandled if the condition type is not a tool condition.
-func checkToolCondition(condition Condition, toolInput *ToolInput) (bool, error) {
+func checkToolCondition(condition Condition, toolInput *ToolInput, cwd string) (bool, error) {
 	switch condition.Type {
 	case ConditionFileExtension:
 		// ToolInput構造体から直接FilePath取得
 		if toolInput.FilePath != "" {
-			return strings.HasSuffix(toolInput.FilePath, condition.Value), nil
+			value, filePath, err := prepareStringMatch(condition, toolInput.FilePath)
+			return err == nil && strings.HasSuffix(filePath, value), err
+		}
+		return false, nil
+	case ConditionFilePathMatches:
+		// file_pathがglobパターンにマッチする（相対パターンはcwdからの相対パスと比較）
+		if toolInput.FilePath == "" {
+			return false, nil
+		}
+		target, ok := globTarget(condition.Value, toolInput.FilePath, cwd)
+		if !ok {
+			return false, nil
+		}
+		matched, err := matchGlob(condition.Value, target)
+		if err != nil {
+			return false, fmt.Errorf("file_path_matches: %w", err)
+		}
+		return matched, nil
+	case ConditionFileIsGitignored, ConditionFileNotGitignored:
+		// file_pathがgitで無視されているか（file_pathが無い場合はどちらもfalse）
+		if toolInput.FilePath == "" {
+			return false, nil
+		}
+		if !inGitRepository(resolveDir(cwd, toolInput.FilePath)) {
+			return gitUnavailable(condition.Type, fmt.Sprintf("%s is not in a git repository", toolInput.FilePath))
+		}
+		ignored, err := isGitIgnored(toolInput.FilePath, cwd)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", condition.Type, err)
+		}
+		return ignored == (condition.Type == ConditionFileIsGitignored), nil
+	case ConditionContentContains, ConditionContentMatches:
+		// 書き込む内容（Writeのcontent、Edit/MultiEditのnew_string）のいずれかにマッチする
+		for _, content := range toolInput.writtenContents() {
+			var matched bool
+			var err error
+			if condition.Type == ConditionContentMatches {
+				matched, err = matchRegex(condition, content)
+			} else {
+				var value string
+				value, content, err = prepareStringMatch(condition, content)
+				matched = strings.Contains(content, value)
+			}
+			if err != nil {
+				return false, err
+			}
+			if matched {
+				return true, nil
+			}
 		}
 		return false, nil
 	case ConditionCommandContains:
 		// ToolInput構造体からCommand取得
 		if toolInput.Command != "" {
-			return strings.Contains(toolInput.Command, condition.Value), nil
+			value, command, err := prepareStringMatch(condition, toolInput.Command)
+			return err == nil && strings.Contains(command, value), err
 		}
 		return false, nil
 	case ConditionCommandStartsWith:
 		// コマンドが指定文字列で始まる
 		if toolInput.Command != "" {
-			return strings.HasPrefix(toolInput.Command, condition.Value), nil
+			value, command, err := prepareStringMatch(condition, toolInput.Command)
+			return err == nil && strings.HasPrefix(command, value), err
+		}
+		return false, nil
+	case ConditionCommandRegex:
+		// コマンドが正規表現にマッチする
+		if toolInput.Command != "" {
+			return matchRegex(condition, toolInput.Command)
 		}
 		return false, nil
+	case ConditionCommandNotRegex:
+		// コマンドが正規表現にマッチしない（コマンドが無い場合はfalse）
+		if toolInput.Command != "" {
+			matched, err := matchRegex(condition, toolInput.Command)
+			return err == nil && !matched, err
+		}
+		return false, nil
+	case ConditionGitCommitMessageMatches, ConditionGitCommitMessageNotMatches:
+		// git commitのメッセージ (-m、-F、COMMIT_EDITMSG) が正規表現にマッチする/しない
+		if toolInput.Command != "" {
+			return matchGitCommitMessages(condition, toolInput.Command, cwd)
+		}
+		return false, nil
+	case ConditionPushToRemoteIs, ConditionPushIsForce, ConditionPushTargetBranchMatches:
+		// git pushのリモート・force・対象ブランチ
+		if toolInput.Command != "" {
+			return matchGitPushes(condition, toolInput.Command, cwd)
+		}
+		return false, nil
+	case ConditionFileSizeGt, ConditionFileIsBinary:
+		// Writeで作るファイル、またはgit addでステージされるファイルが大きい/バイナリ
+		return matchLargeFiles(condition, toolInput, cwd)
 	case ConditionURLStartsWith:
 		// URLが指定文字列で始まる
 		if toolInput.URL != "" {
@@ -214,6 +642,39 @@ func checkToolCondition(condition Condition, toolInput *ToolInput) (bool, error)
 	}
 }
 
+// prepareStringMatch applies the normalize / ignore_case options of condition
+// to both condition.Value and target, so that they can be compared as plain strings.
+func prepareStringMatch(condition Condition, target string) (string, string, error) {
+	value, err := normalizeUnicode(condition.Normalize, condition.Value)
+	if err != nil {
+		return "", "", err
+	}
+	target, err = normalizeUnicode(condition.Normalize, target)
+	if err != nil {
+		return "", "", err
+	}
+	if condition.IgnoreCase {
+		value = strings.ToLower(value)
+		target = strings.ToLower(target)
+	}
+	return value, target, nil
+}
+
+// normalizeUnicode normalizes s with the given form ("", "nfc" or "nfkc").
+// NFKC also folds width variants such as full-width ASCII and half-width katakana.
+func normalizeUnicode(form string, s string) (string, error) {
+	switch form {
+	case "":
+		return s, nil
+	case "nfc":
+		return norm.NFC.String(s), nil
+	case "nfkc":
+		return norm.NFKC.String(s), nil
+	default:
+		return "", fmt.Errorf("invalid normalize value: %s (must be 'nfc' or 'nfkc')", form)
+	}
+}
+
 // countUserPromptsFromTranscript counts user prompts in the transcript file for the specified session.
 // Returns the count including the current prompt.
 func countUserPromptsFromTranscript(transcriptPath, sessionID string) (int, error) {
@@ -223,31 +684,197 @@ func countUserPromptsFromTranscript(transcriptPath, sessionID string) (int, erro
 	}
 	defer func() { _ = file.Close() }()
 
-	decoder := json.NewDecoder(file)
 	count := 0
-
-	for {
-		var entry map[string]any
-		if err := decoder.Decode(&entry); err != nil {
-			if err == io.EOF {
-				break
-			}
-			// JSONのパースエラーは継続（壊れた行をスキップ）
-			continue
-		}
-
+	err = forEachTranscriptEntry(file, func(entry transcriptEntry) {
 		// type: "user" かつ同じセッションIDのメッセージをカウント
-		if entryType, ok := entry["type"].(string); ok && entryType == "user" {
-			if sid, ok := entry["sessionId"].(string); ok && sid == sessionID {
-				count++
-			}
+		if entry.Type == "user" && entry.SessionID == sessionID {
+			count++
 		}
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to read transcript: %w", err)
 	}
 
 	// 現在の発話を含める
 	return count + 1, nil
 }
 
+// sessionDurationFromTranscript returns the time between the first and the last timestamped entries
+// of the session in the transcript file. A transcript that does not exist yet means a session that just started (0).
+func sessionDurationFromTranscript(transcriptPath, sessionID string) (time.Duration, error) {
+	file, err := os.Open(transcriptPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to open transcript: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var first, last time.Time
+	err = forEachTranscriptEntry(file, func(entry transcriptEntry) {
+		if entry.SessionID != sessionID || entry.Timestamp == "" {
+			return
+		}
+		ts, err := time.Parse(time.RFC3339Nano, entry.Timestamp)
+		if err != nil {
+			return
+		}
+		if first.IsZero() || ts.Before(first) {
+			first = ts
+		}
+		if ts.After(last) {
+			last = ts
+		}
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to read transcript: %w", err)
+	}
+
+	if first.IsZero() {
+		return 0, nil
+	}
+	return last.Sub(first), nil
+}
+
+// transcriptStats is what conditions read from the assistant messages of a session in the transcript.
+type transcriptStats struct {
+	TokensUsed           int64  // 全assistantメッセージのinput/cache/outputトークンの合計
+	LastAssistantMessage string // 最後のassistantメッセージのテキスト
+}
+
+// readTranscriptStats sums the token usage of the session's assistant messages and returns the text of the last one.
+// A message streamed as several lines (one per content block, with the same message ID) is counted once.
+// A transcript that does not exist yet means a session that just started (no messages).
+func readTranscriptStats(transcriptPath, sessionID string) (transcriptStats, error) {
+	var stats transcriptStats
+	file, err := os.Open(transcriptPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return stats, nil
+	}
+	if err != nil {
+		return stats, fmt.Errorf("failed to open transcript: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	usage := map[string]int64{}
+	lines := 0
+	var lastID string
+	var lastText []string
+	err = forEachTranscriptEntry(file, func(entry transcriptEntry) {
+		if entry.Type != "assistant" || entry.SessionID != sessionID || entry.Message == nil {
+			return
+		}
+		message := entry.Message
+		lines++
+		id := message.ID
+		if id == "" {
+			// IDの無いメッセージは1行1メッセージとみなす
+			id = fmt.Sprintf("line-%d", lines)
+		}
+		if u := message.Usage; u != nil {
+			usage[id] = u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens + u.OutputTokens
+		}
+		if id != lastID {
+			lastID, lastText = id, nil
+		}
+		lastText = append(lastText, message.texts()...)
+	})
+	if err != nil {
+		return stats, fmt.Errorf("failed to read transcript: %w", err)
+	}
+
+	for _, tokens := range usage {
+		stats.TokensUsed += tokens
+	}
+	stats.LastAssistantMessage = strings.Join(lastText, "\n")
+	return stats, nil
+}
+
+// parseTokenCount parses a token count such as "200000", "200k" or "1.5M".
+func parseTokenCount(s string) (int64, error) {
+	multiplier := 1.0
+	number := strings.TrimSpace(s)
+	switch {
+	case strings.HasSuffix(number, "k") || strings.HasSuffix(number, "K"):
+		multiplier, number = 1e3, number[:len(number)-1]
+	case strings.HasSuffix(number, "m") || strings.HasSuffix(number, "M"):
+		multiplier, number = 1e6, number[:len(number)-1]
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid token count %q (use a number such as 200000, 200k or 1.5M)", s)
+	}
+	return int64(n * multiplier), nil
+}
+
+// transcriptEntry is the part of a transcript (JSONL) line used by conditions.
+type transcriptEntry struct {
+	Type      string             `json:"type"`
+	SessionID string             `json:"sessionId"`
+	Timestamp string             `json:"timestamp"`
+	Message   *transcriptMessage `json:"message"`
+}
+
+// transcriptMessage is the API message of a transcript entry.
+type transcriptMessage struct {
+	ID      string           `json:"id"`
+	Model   string           `json:"model"`
+	Content json.RawMessage  `json:"content"` // 文字列またはcontent blockの配列
+	Usage   *transcriptUsage `json:"usage"`
+}
+
+// transcriptUsage is the token usage of an assistant message.
+type transcriptUsage struct {
+	InputTokens              int64 `json:"input_tokens"`
+	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+	OutputTokens             int64 `json:"output_tokens"`
+}
+
+// texts returns the text of the message: the content string, or its text blocks (tool use and thinking are skipped).
+func (m *transcriptMessage) texts() []string {
+	var text string
+	if json.Unmarshal(m.Content, &text) == nil {
+		return []string{text}
+	}
+	var blocks []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if json.Unmarshal(m.Content, &blocks) != nil {
+		return nil
+	}
+	var texts []string
+	for _, block := range blocks {
+		if block.Type == "text" && block.Text != "" {
+			texts = append(texts, block.Text)
+		}
+	}
+	return texts
+}
+
+// forEachTranscriptEntry calls fn for every line of a transcript that parses as JSON.
+// Broken lines are skipped (a json.Decoder cannot resume after a syntax error).
+func forEachTranscriptEntry(r io.Reader, fn func(transcriptEntry)) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var entry transcriptEntry
+			if json.Unmarshal(line, &entry) == nil {
+				fn(entry)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
 // checkPromptCondition checks prompt-specific conditions like prompt_regex.
 // Returns ErrConditionNotHandled if the condition type is not a prompt condition.
 func checkPromptCondition(condition Condition, prompt string) (bool, error) {
@@ -255,11 +882,7 @@ func checkPromptCondition(condition Condition, prompt string) (bool, error) {
 	case ConditionPromptRegex:
 		// プロンプトが正規表現パターンにマッチする
 		// 例: "keyword" (部分一致), "^prefix" (前方一致), "suffix$" (後方一致), "a|b|c" (OR条件)
-		re, err := regexp.Compile(condition.Value)
-		if err != nil {
-			return false, fmt.Errorf("invalid regex pattern: %w", err)
-		}
-		return re.MatchString(prompt), nil
+		return matchRegex(condition, prompt)
 	default:
 		// この関数ではプロンプト関連条件のみをチェック
 		return false, ErrConditionNotHandled
@@ -267,9 +890,24 @@ func checkPromptCondition(condition Condition, prompt string) (bool, error) {
 }
 
 // checkNotificationCondition checks if a condition matches for Notification events.
-// Only supports common conditions.
+// Supports notification_message_contains/notification_message_regex and common conditions.
 func checkNotificationCondition(condition Condition, input *NotificationInput) (bool, error) {
-	// Notificationは汎用条件のみ使用
+	if matched, handled, err := checkExpandedCondition(condition, func(c Condition) (bool, error) {
+		return checkNotificationCondition(c, input)
+	}); handled {
+		return matched, err
+	}
+
+	switch condition.Type {
+	case ConditionNotificationMessageContains:
+		// 通知メッセージが特定の文字列を含む
+		value, message, err := prepareStringMatch(condition, input.Message)
+		return err == nil && strings.Contains(message, value), err
+	case ConditionNotificationMessageRegex:
+		// 通知メッセージが正規表現パターンにマッチする
+		return matchRegex(condition, input.Message)
+	}
+
 	matched, err := checkCommonCondition(condition, &input.BaseInput)
 	if err == nil {
 		return matched, nil // 処理された
@@ -285,6 +923,12 @@ func checkNotificationCondition(condition Condition, input *NotificationInput) (
 // checkStopCondition checks if a condition matches for Stop events.
 // Only supports common conditions.
 func checkStopCondition(condition Condition, input *StopInput) (bool, error) {
+	if matched, handled, err := checkExpandedCondition(condition, func(c Condition) (bool, error) {
+		return checkStopCondition(c, input)
+	}); handled {
+		return matched, err
+	}
+
 	// Stopは汎用条件のみ使用
 	matched, err := checkCommonCondition(condition, &input.BaseInput)
 	if err == nil {
@@ -299,9 +943,21 @@ func checkStopCondition(condition Condition, input *StopInput) (bool, error) {
 }
 
 // checkSubagentStopCondition checks if a condition matches for SubagentStop events.
-// Only supports common conditions.
+// Supports agent_type_is and common conditions.
 func checkSubagentStopCondition(condition Condition, input *SubagentStopInput) (bool, error) {
-	// SubagentStopは汎用条件のみ使用
+	if matched, handled, err := checkExpandedCondition(condition, func(c Condition) (bool, error) {
+		return checkSubagentStopCondition(c, input)
+	}); handled {
+		return matched, err
+	}
+
+	// agent_type_is condition
+	if condition.Type == ConditionAgentTypeIs {
+		value, agentType, err := prepareStringMatch(condition, input.AgentType)
+		return err == nil && agentType == value, err
+	}
+
+	// SubagentStopは汎用条件も使用
 	matched, err := checkCommonCondition(condition, &input.BaseInput)
 	if err == nil {
 		return matched, nil // 処理された
@@ -315,9 +971,21 @@ func checkSubagentStopCondition(condition Condition, input *SubagentStopInput) (
 }
 
 // checkSubagentStartCondition checks if a condition matches for SubagentStart events.
-// Only supports common conditions.
+// Supports agent_type_is and common conditions.
 func checkSubagentStartCondition(condition Condition, input *SubagentStartInput) (bool, error) {
-	// SubagentStartは汎用条件のみ使用
+	if matched, handled, err := checkExpandedCondition(condition, func(c Condition) (bool, error) {
+		return checkSubagentStartCondition(c, input)
+	}); handled {
+		return matched, err
+	}
+
+	// agent_type_is condition
+	if condition.Type == ConditionAgentTypeIs {
+		value, agentType, err := prepareStringMatch(condition, input.AgentType)
+		return err == nil && agentType == value, err
+	}
+
+	// SubagentStartは汎用条件も使用
 	matched, err := checkCommonCondition(condition, &input.BaseInput)
 	if err == nil {
 		return matched, nil // 処理された
@@ -333,6 +1001,12 @@ func checkSubagentStartCondition(condition Condition, input *SubagentStartInput)
 // checkSessionEndCondition checks if a condition matches for SessionEnd events.
 // Supports common conditions and reason_is condition.
 func checkSessionEndCondition(condition Condition, input *SessionEndInput) (bool, error) {
+	if matched, handled, err := checkExpandedCondition(condition, func(c Condition) (bool, error) {
+		return checkSessionEndCondition(c, input)
+	}); handled {
+		return matched, err
+	}
+
 	// reason_is condition
 	if condition.Type == ConditionReasonIs {
 		return input.Reason == condition.Value, nil
@@ -354,6 +1028,12 @@ func checkSessionEndCondition(condition Condition, input *SessionEndInput) (bool
 // checkPreCompactCondition checks if a condition matches for PreCompact events.
 // Only supports common conditions.
 func checkPreCompactCondition(condition Condition, input *PreCompactInput) (bool, error) {
+	if matched, handled, err := checkExpandedCondition(condition, func(c Condition) (bool, error) {
+		return checkPreCompactCondition(c, input)
+	}); handled {
+		return matched, err
+	}
+
 	// PreCompactは汎用条件のみ使用
 	matched, err := checkCommonCondition(condition, &input.BaseInput)
 	if err == nil {
@@ -370,6 +1050,12 @@ func checkPreCompactCondition(condition Condition, input *PreCompactInput) (bool
 // checkPermissionRequestCondition checks if a condition matches for PermissionRequest events.
 // Returns ErrConditionNotHandled if the condition type is not applicable to this event.
 func checkPermissionRequestCondition(condition Condition, input *PermissionRequestInput) (bool, error) {
+	if matched, handled, err := checkExpandedCondition(condition, func(c Condition) (bool, error) {
+		return checkPermissionRequestCondition(c, input)
+	}); handled {
+		return matched, err
+	}
+
 	// まず汎用条件をチェック
 	matched, err := checkCommonCondition(condition, &input.BaseInput)
 	if err == nil {
@@ -380,7 +1066,16 @@ func checkPermissionRequestCondition(condition Condition, input *PermissionReque
 	}
 
 	// ツール固有の条件をチェック
-	matched, err = checkToolCondition(condition, &input.ToolInput)
+	matched, err = checkToolCondition(condition, &input.ToolInput, input.Cwd)
+	if err == nil {
+		return matched, nil // 処理された
+	}
+	if !errors.Is(err, ErrConditionNotHandled) {
+		return false, err // 本当のエラー
+	}
+
+	// MCPツールの条件をチェック
+	matched, err = checkMCPCondition(condition, input.ToolName)
 	if err == nil {
 		return matched, nil // 処理された
 	}
@@ -391,3 +1086,49 @@ func checkPermissionRequestCondition(condition Condition, input *PermissionReque
 	// どの関数も処理しなかった場合はエラー
 	return false, fmt.Errorf("unknown condition type: %s", condition.Type)
 }
+
+// matchRegex reports whether target matches the regex in condition.Value, honoring normalize and ignore_case.
+func matchRegex(condition Condition, target string) (bool, error) {
+	// ignore_caseは(?i)で扱う（パターン自体を小文字化すると\Sなどが壊れるため）
+	pattern, err := normalizeUnicode(condition.Normalize, condition.Value)
+	if err != nil {
+		return false, err
+	}
+	target, err = normalizeUnicode(condition.Normalize, target)
+	if err != nil {
+		return false, err
+	}
+	if condition.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := compileConditionRegex(pattern)
+	if err != nil {
+		return false, fmt.Errorf("invalid regex pattern: %w", err)
+	}
+	return re.MatchString(target), nil
+}
+
+// 条件の正規表現のキャッシュ（serveでは同じパターンをイベント毎にコンパイルしないため）
+var (
+	conditionRegexCache = make(map[string]*regexp.Regexp)
+	conditionRegexMutex sync.RWMutex
+)
+
+// compileConditionRegex compiles the regex of a condition (cached).
+func compileConditionRegex(pattern string) (*regexp.Regexp, error) {
+	conditionRegexMutex.RLock()
+	re, exists := conditionRegexCache[pattern]
+	conditionRegexMutex.RUnlock()
+	if exists {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	conditionRegexMutex.Lock()
+	conditionRegexCache[pattern] = re
+	conditionRegexMutex.Unlock()
+	return re, nil
+}
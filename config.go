@@ -1,11 +1,10 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-
-	"gopkg.in/yaml.v3"
 )
 
 // loadConfig loads the configuration from the specified YAML file.
@@ -15,17 +14,100 @@ func loadConfig(configPath string) (*Config, error) {
 		configPath = getDefaultConfigPath()
 	}
 
+	// 設定ファイルのmtimeが変わっていなければ前回パースした設定を使う
+	if config, ok := loadCachedConfig(configPath); ok {
+		return config, nil
+	}
+
 	data, err := os.ReadFile(configPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file %s: %w", configPath, err)
+	// `cchook -command compile` で生成したアーティファクトがあればYAMLパースを省略
+	config, ok, err := loadCompiledConfig(configPath, data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring compiled config: %v (run 'cchook -command compile' to rebuild)\n", err)
+	}
+	if ok {
+		return config, nil
 	}
 
-	return &config, nil
+	return parseConfigCached(configPath, data)
+}
+
+// parseConfigCached parses config data read from configPath like parseConfig and caches the result
+// for loadCachedConfig.
+func parseConfigCached(configPath string, data []byte) (*Config, error) {
+	config, sources, err := parseConfigWithSources(configPath, data)
+	if err != nil {
+		return nil, err
+	}
+	storeCachedConfig(configPath, config, sources)
+	return config, nil
+}
+
+// parseConfig parses YAML config data read from configPath, merging the files it includes.
+func parseConfig(configPath string, data []byte) (*Config, error) {
+	config, _, err := parseConfigWithSources(configPath, data)
+	return config, err
+}
+
+// maxConditionNestingDepth is the maximum nesting depth of any_of / all_of / not condition groups.
+const maxConditionNestingDepth = 5
+
+// validateConfigConditions validates the structure of condition groups in all hooks.
+func validateConfigConditions(config *Config) error {
+	return errors.Join(
+		validateHooksConditions(PreToolUse, config.PreToolUse, func(h PreToolUseHook) []Condition { return h.Conditions }),
+		validateHooksConditions(PostToolUse, config.PostToolUse, func(h PostToolUseHook) []Condition { return h.Conditions }),
+		validateHooksConditions(PermissionRequest, config.PermissionRequest, func(h PermissionRequestHook) []Condition { return h.Conditions }),
+		validateHooksConditions(Notification, config.Notification, func(h NotificationHook) []Condition { return h.Conditions }),
+		validateHooksConditions(Stop, config.Stop, func(h StopHook) []Condition { return h.Conditions }),
+		validateHooksConditions(SubagentStop, config.SubagentStop, func(h SubagentStopHook) []Condition { return h.Conditions }),
+		validateHooksConditions(SubagentStart, config.SubagentStart, func(h SubagentStartHook) []Condition { return h.Conditions }),
+		validateHooksConditions(PreCompact, config.PreCompact, func(h PreCompactHook) []Condition { return h.Conditions }),
+		validateHooksConditions(SessionStart, config.SessionStart, func(h SessionStartHook) []Condition { return h.Conditions }),
+		validateHooksConditions(SessionEnd, config.SessionEnd, func(h SessionEndHook) []Condition { return h.Conditions }),
+		validateHooksConditions(UserPromptSubmit, config.UserPromptSubmit, func(h UserPromptSubmitHook) []Condition { return h.Conditions }),
+	)
+}
+
+// validateHooksConditions validates the conditions of each hook of eventType.
+func validateHooksConditions[H any](eventType HookEventType, hooks []H, conditions func(H) []Condition) error {
+	for i, hook := range hooks {
+		if err := validateConditions(conditions(hook), 1); err != nil {
+			return fmt.Errorf("%s hook %d: %w", eventType, i+1, err)
+		}
+	}
+	return nil
+}
+
+// validateConditions checks that condition groups have children, do not take value(s),
+// and are nested at most maxConditionNestingDepth levels deep.
+func validateConditions(conditions []Condition, depth int) error {
+	for _, condition := range conditions {
+		if !isCompositeCondition(condition.Type) {
+			if len(condition.Conditions) > 0 {
+				return fmt.Errorf("conditions can only be used with any_of, all_of or not: %s", condition.Type)
+			}
+			continue
+		}
+
+		if depth > maxConditionNestingDepth {
+			return fmt.Errorf("condition groups are nested deeper than %d levels", maxConditionNestingDepth)
+		}
+		if len(condition.Conditions) == 0 {
+			return fmt.Errorf("%s requires at least one condition in conditions", condition.Type)
+		}
+		if condition.Value != "" || len(condition.Values) > 0 {
+			return fmt.Errorf("%s does not take value or values", condition.Type)
+		}
+		if err := validateConditions(condition.Conditions, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // getDefaultConfigPath returns the default configuration file path.
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// configCacheEnabled reports whether parsed configs are cached between invocations. Set by -config-cache.
+var configCacheEnabled = true
+
+// configCacheDir returns the directory of the parsed config cache.
+// It is a variable so that tests can use a temporary directory.
+var configCacheDir = func() string {
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "cchook", "configs")
+	}
+	return filepath.Join(os.TempDir(), "cchook-configs")
+}
+
+// configMemoryCache keeps parsed configs in memory by absolute config path while `cchook -command serve` runs
+// (nil otherwise). Entries are checked like the files of the cache.
+var configMemoryCache map[string]*configCacheEntry
+
+// configCacheRacyWindow is how recently a config file may have been modified for its parsed config
+// not to be cached: a file written again within the file system's timestamp granularity could keep its mtime
+// and size, so the cache would not notice the change.
+const configCacheRacyWindow = 2 * time.Second
+
+// fileStamp identifies a version of a file without reading it.
+type fileStamp struct {
+	ModTime int64 // UnixNano
+	Size    int64
+}
+
+// configCacheEntry is a parsed config cached by the path and modification times of its files.
+// Unlike the artifact of `cchook -command compile` it is written automatically and checked with stat only.
+type configCacheEntry struct {
+	FormatVersion int
+	Stamps        map[string]fileStamp // config file path → stamp
+	IncludeGlobs  map[string][]string
+	Config        Config
+}
+
+// configCachePath returns the cache file of the config at configPath.
+func configCachePath(configPath string) string {
+	sum := sha256.Sum256([]byte(absConfigPath(configPath)))
+	return filepath.Join(configCacheDir(), hex.EncodeToString(sum[:16])+".gob")
+}
+
+// statFileStamp returns the stamp of the file at path.
+func statFileStamp(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{ModTime: info.ModTime().UnixNano(), Size: info.Size()}, nil
+}
+
+// loadCachedConfig returns the cached config of configPath if the config and every file it includes
+// still have the cached modification time and size. Any problem with the cache is a miss.
+func loadCachedConfig(configPath string) (*Config, bool) {
+	if !configCacheEnabled {
+		return nil, false
+	}
+	key := absConfigPath(configPath)
+	entry, ok := configMemoryCache[key]
+	if !ok {
+		raw, err := os.ReadFile(configCachePath(configPath))
+		if err != nil {
+			return nil, false
+		}
+		entry = &configCacheEntry{}
+		if err := gob.NewDecoder(bytes.NewReader(raw)).Decode(entry); err != nil || entry.FormatVersion != compiledConfigFormatVersion {
+			return nil, false
+		}
+	}
+	if !entry.fresh(configPath) {
+		delete(configMemoryCache, key)
+		return nil, false
+	}
+	if configMemoryCache != nil {
+		configMemoryCache[key] = entry
+	}
+	// フックの実行中に書き換えられてもキャッシュに残らないようコピーを返す
+	config := entry.Config
+	return &config, true
+}
+
+// fresh reports whether the files of configPath still have the stamps of the entry.
+func (entry *configCacheEntry) fresh(configPath string) bool {
+	if _, ok := entry.Stamps[configPath]; !ok {
+		return false
+	}
+	for path, want := range entry.Stamps {
+		if got, err := statFileStamp(path); err != nil || got != want {
+			return false
+		}
+	}
+	for pattern, want := range entry.IncludeGlobs {
+		if files, err := resolveInclude(pattern); err != nil || !slices.Equal(files, want) {
+			return false
+		}
+	}
+	return true
+}
+
+// storeCachedConfig caches the config parsed from sources for configPath.
+// The cache is best effort: configs modified within configCacheRacyWindow are not cached and
+// failures to write are ignored.
+func storeCachedConfig(configPath string, config *Config, sources *configSources) {
+	if !configCacheEnabled {
+		return
+	}
+	entry := configCacheEntry{
+		FormatVersion: compiledConfigFormatVersion,
+		Stamps:        map[string]fileStamp{},
+		IncludeGlobs:  sources.Globs,
+		Config:        *config,
+	}
+	racy := time.Now().Add(-configCacheRacyWindow).UnixNano()
+	for path := range sources.Files {
+		stamp, err := statFileStamp(path)
+		if err != nil || stamp.ModTime > racy {
+			return
+		}
+		entry.Stamps[path] = stamp
+	}
+
+	if configMemoryCache != nil {
+		configMemoryCache[absConfigPath(configPath)] = &entry
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&entry); err != nil {
+		return
+	}
+	// 並行して起動したcchookが書きかけのキャッシュを読まないよう、一時ファイルからrenameする
+	path := configCachePath(configPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".config-*.gob")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(buf.Bytes())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+}
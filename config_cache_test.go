@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig_Cache(t *testing.T) {
+	cacheDir := t.TempDir()
+	origCacheDir := configCacheDir
+	configCacheDir = func() string { return cacheDir }
+	t.Cleanup(func() { configCacheDir = origCacheDir })
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	includePath := filepath.Join(dir, "stop.yaml")
+	old := time.Now().Add(-time.Hour)
+	write := func(path, content string, mtime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	message := func(config *Config) string {
+		return config.Stop[0].Actions[0].Message
+	}
+	write(configPath, "include: [stop.yaml]\n", old)
+	write(includePath, "Stop:\n  - actions:\n      - type: output\n        message: one\n", old)
+
+	config, err := loadConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(configCachePath(configPath)); err != nil {
+		t.Fatalf("config was not cached: %v", err)
+	}
+
+	// mtimeとサイズが同じならファイルを読まずにキャッシュを使う
+	write(includePath, "Stop:\n  - actions:\n      - type: output\n        message: two\n", old)
+	if config, err = loadConfig(configPath); err != nil || message(config) != "one" {
+		t.Fatalf("cached config = %v, %v, want the cached message", config, err)
+	}
+
+	// includeしたファイルが更新されたらパースし直す
+	write(includePath, "Stop:\n  - actions:\n      - type: output\n        message: two\n", old.Add(time.Minute))
+	if config, err = loadConfig(configPath); err != nil || message(config) != "two" {
+		t.Fatalf("config after edit = %v, %v, want the new message", config, err)
+	}
+
+	// 更新直後のファイルはmtimeで変更を検知できないことがあるのでキャッシュしない
+	write(includePath, "Stop:\n  - actions:\n      - type: output\n        message: new\n", time.Now())
+	if config, err = loadConfig(configPath); err != nil || message(config) != "new" {
+		t.Fatalf("config after recent edit = %v, %v", config, err)
+	}
+	write(includePath, "Stop:\n  - actions:\n      - type: output\n        message: now\n", time.Now())
+	if config, err = loadConfig(configPath); err != nil || message(config) != "now" {
+		t.Errorf("recently modified config was cached: %v, %v", config, err)
+	}
+
+	configCacheEnabled = false
+	t.Cleanup(func() { configCacheEnabled = true })
+	write(includePath, "Stop:\n  - actions:\n      - type: output\n        message: off\n", old)
+	if err := os.Remove(configCachePath(configPath)); err != nil {
+		t.Fatal(err)
+	}
+	if config, err = loadConfig(configPath); err != nil || message(config) != "off" {
+		t.Fatalf("config without cache = %v, %v", config, err)
+	}
+	if _, err := os.Stat(configCachePath(configPath)); !os.IsNotExist(err) {
+		t.Errorf("config was cached with the cache disabled: %v", err)
+	}
+}
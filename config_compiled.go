@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+)
+
+// compiledConfigFormatVersion is bumped whenever the Config structure changes
+// in a way that makes previously compiled artifacts unreadable or misleading.
+const compiledConfigFormatVersion = 40
+
+func init() {
+	// updated_input holds arbitrary YAML values; gob needs the nested container types registered
+	gob.Register(map[string]any{})
+	gob.Register([]any{})
+}
+
+// compiledConfig is the on-disk representation written by `cchook -command compile`.
+// SourceHashes maps every YAML source file (the config and its includes) to its SHA-256 digest
+// and IncludeGlobs maps every include glob to the files it matched, so that a stale
+// artifact is detected without parsing YAML.
+type compiledConfig struct {
+	FormatVersion int
+	SourceHashes  map[string]string
+	IncludeGlobs  map[string][]string
+	Config        Config
+}
+
+// compiledConfigPath returns the path of the compiled artifact for the given config path.
+func compiledConfigPath(configPath string) string {
+	return configPath + ".compiled"
+}
+
+// hashConfigSource returns the hex encoded SHA-256 digest of the config source.
+func hashConfigSource(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// compileConfig parses and validates the YAML config and writes a gob encoded artifact next to it.
+// Returns the path of the written artifact.
+func compileConfig(configPath string) (string, error) {
+	if configPath == "" {
+		configPath = getDefaultConfigPath()
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	config, sources, err := parseConfigWithSources(configPath, data)
+	if err != nil {
+		return "", err
+	}
+
+	artifact := compiledConfig{
+		FormatVersion: compiledConfigFormatVersion,
+		SourceHashes:  map[string]string{},
+		IncludeGlobs:  sources.Globs,
+		Config:        *config,
+	}
+	for path, content := range sources.Files {
+		artifact.SourceHashes[path] = hashConfigSource(content)
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&artifact); err != nil {
+		return "", fmt.Errorf("failed to encode compiled config: %w", err)
+	}
+
+	outPath := compiledConfigPath(configPath)
+	if err := os.WriteFile(outPath, buf.Bytes(), 0600); err != nil {
+		return "", fmt.Errorf("failed to write compiled config: %w", err)
+	}
+	return outPath, nil
+}
+
+// loadCompiledConfig returns the compiled config for configPath if an artifact exists
+// and every recorded source hash still matches. The second return value reports
+// whether the artifact was usable; a stale or unreadable artifact is reported
+// via the error so that the caller can warn and fall back to YAML parsing.
+func loadCompiledConfig(configPath string, source []byte) (*Config, bool, error) {
+	raw, err := os.ReadFile(compiledConfigPath(configPath))
+	if err != nil {
+		// アーティファクトが無いのは通常ケース
+		return nil, false, nil
+	}
+
+	var artifact compiledConfig
+	if err := gob.NewDecoder(bytes.NewReader(raw)).Decode(&artifact); err != nil {
+		return nil, false, fmt.Errorf("failed to decode compiled config: %w", err)
+	}
+
+	if artifact.FormatVersion != compiledConfigFormatVersion {
+		return nil, false, fmt.Errorf("compiled config format version %d is not supported (expected %d)", artifact.FormatVersion, compiledConfigFormatVersion)
+	}
+
+	if _, ok := artifact.SourceHashes[configPath]; !ok {
+		return nil, false, fmt.Errorf("compiled config was built from a different config file")
+	}
+
+	for path, want := range artifact.SourceHashes {
+		var data []byte
+		if path == configPath {
+			data = source
+		} else if data, err = os.ReadFile(path); err != nil {
+			return nil, false, fmt.Errorf("compiled config source %s is unreadable: %w", path, err)
+		}
+		if hashConfigSource(data) != want {
+			return nil, false, fmt.Errorf("compiled config is stale: %s has changed", path)
+		}
+	}
+
+	// globで取り込むファイルの追加・削除も検知する
+	for pattern, want := range artifact.IncludeGlobs {
+		files, err := resolveInclude(pattern)
+		if err != nil {
+			return nil, false, fmt.Errorf("compiled config include %s cannot be resolved: %w", pattern, err)
+		}
+		if !slices.Equal(files, want) {
+			return nil, false, fmt.Errorf("compiled config is stale: files matching %s have changed", pattern)
+		}
+	}
+	return &artifact.Config, true, nil
+}
+
+// GobEncode implements gob.GobEncoder for ConditionType (opaque struct has no exported fields)
+func (c ConditionType) GobEncode() ([]byte, error) {
+	return []byte(c.v), nil
+}
+
+// GobDecode implements gob.GobDecoder for ConditionType
+func (c *ConditionType) GobDecode(data []byte) error {
+	ct, err := parseConditionType(string(data))
+	if err != nil {
+		return err
+	}
+	*c = ct
+	return nil
+}
+
+// actionGob is Action without methods, used to avoid GobEncode recursion.
+type actionGob Action
+
+// GobEncode implements gob.GobEncoder for Action.
+// gob flattens pointers and omits zero values, so `exit_status: 0` or `continue: false`
+// would otherwise decode as unset. The names of non-nil pointer fields are stored alongside.
+func (a Action) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	if err := enc.Encode(actionGob(a)); err != nil {
+		return nil, err
+	}
+	if err := enc.Encode(setPointerFields(reflect.ValueOf(a))); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GobDecode implements gob.GobDecoder for Action
+func (a *Action) GobDecode(data []byte) error {
+	dec := gob.NewDecoder(bytes.NewReader(data))
+	var decoded actionGob
+	if err := dec.Decode(&decoded); err != nil {
+		return err
+	}
+	var setFields []string
+	if err := dec.Decode(&setFields); err != nil {
+		return err
+	}
+
+	v := reflect.ValueOf(&decoded).Elem()
+	for _, name := range setFields {
+		field := v.FieldByName(name)
+		if field.IsValid() && field.Kind() == reflect.Pointer && field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+	}
+	*a = Action(decoded)
+	return nil
+}
+
+// setPointerFields returns the names of pointer fields of struct v that are non-nil.
+func setPointerFields(v reflect.Value) []string {
+	var names []string
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() == reflect.Pointer && !field.IsNil() {
+			names = append(names, v.Type().Field(i).Name)
+		}
+	}
+	return names
+}
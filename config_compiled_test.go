@@ -0,0 +1,201 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const compiledTestConfig = `
+PreToolUse:
+  - matcher: "Bash"
+    conditions:
+      - type: command_starts_with
+        value: "rm"
+      - type: not
+        conditions:
+          - type: any_of
+            conditions:
+              - type: cwd_contains
+                values: ["vendor", "node_modules"]
+    actions:
+      - type: output
+        message: "blocked"
+        exit_status: 0
+        continue: false
+        permission_decision: "deny"
+      - type: output
+        message: "rewritten"
+        permission_decision: "ask"
+        updated_input:
+          command: "trash {.tool_input.command}"
+          env: {SAFE: "1"}
+          args: ["-v", 1]
+`
+
+func TestCompileConfig_RoundTrip(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(compiledTestConfig), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	outPath, err := compileConfig(configPath)
+	if err != nil {
+		t.Fatalf("compileConfig() error = %v", err)
+	}
+	if outPath != compiledConfigPath(configPath) {
+		t.Errorf("Expected artifact at %s, got %s", compiledConfigPath(configPath), outPath)
+	}
+
+	compiled, ok, err := loadCompiledConfig(configPath, []byte(compiledTestConfig))
+	if err != nil || !ok {
+		t.Fatalf("loadCompiledConfig() = (ok=%v, err=%v), want usable artifact", ok, err)
+	}
+
+	parsed, err := parseConfig(configPath, []byte(compiledTestConfig))
+	if err != nil {
+		t.Fatalf("parseConfig() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(compiled, parsed) {
+		t.Errorf("Compiled config differs from parsed config:\ncompiled: %+v\nparsed:   %+v", compiled, parsed)
+	}
+
+	// ゼロ値ポインタ（exit_status: 0, continue: false）が保持されていること
+	action := compiled.PreToolUse[0].Actions[0]
+	if action.ExitStatus == nil || *action.ExitStatus != 0 {
+		t.Errorf("Expected exit_status 0 to survive compilation, got %v", action.ExitStatus)
+	}
+	if action.Continue == nil || *action.Continue {
+		t.Errorf("Expected continue false to survive compilation, got %v", action.Continue)
+	}
+	if action.Decision != nil {
+		t.Errorf("Expected unset decision to stay nil, got %v", *action.Decision)
+	}
+}
+
+func TestLoadConfig_UsesCompiledArtifact(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(compiledTestConfig), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if _, err := compileConfig(configPath); err != nil {
+		t.Fatalf("compileConfig() error = %v", err)
+	}
+
+	config, err := loadConfig(configPath)
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	if len(config.PreToolUse) != 1 || config.PreToolUse[0].Conditions[0].Type != ConditionCommandStartsWith {
+		t.Errorf("Unexpected config loaded from artifact: %+v", config)
+	}
+}
+
+func TestLoadConfig_StaleCompiledArtifactFallsBack(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(compiledTestConfig), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if _, err := compileConfig(configPath); err != nil {
+		t.Fatalf("compileConfig() error = %v", err)
+	}
+
+	// ソースを変更するとアーティファクトは使われない
+	updated := `
+Stop:
+  - actions:
+      - type: output
+        message: "bye"
+`
+	if err := os.WriteFile(configPath, []byte(updated), 0644); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+
+	_, ok, err := loadCompiledConfig(configPath, []byte(updated))
+	if ok || err == nil {
+		t.Errorf("Expected stale artifact to be rejected, got ok=%v err=%v", ok, err)
+	}
+
+	config, err := loadConfig(configPath)
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	if len(config.PreToolUse) != 0 || len(config.Stop) != 1 {
+		t.Errorf("Expected config parsed from updated YAML, got %+v", config)
+	}
+}
+
+func TestLoadCompiledConfig_IncludedFilesChanged(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	root := "include: [\"hooks.d/*.yaml\"]\n"
+	writeConfigFiles(t, dir, map[string]string{
+		"config.yaml":       root,
+		"hooks.d/a.yaml":    stopHook("a"),
+		"hooks.d/notes.txt": "ignored",
+	})
+	if _, err := compileConfig(configPath); err != nil {
+		t.Fatalf("compileConfig() error = %v", err)
+	}
+	if _, ok, err := loadCompiledConfig(configPath, []byte(root)); !ok || err != nil {
+		t.Fatalf("Expected fresh artifact to be used, got ok=%v err=%v", ok, err)
+	}
+
+	// globに一致するファイルが増えたらアーティファクトは使われない
+	writeConfigFiles(t, dir, map[string]string{"hooks.d/b.yaml": stopHook("b")})
+	if _, ok, err := loadCompiledConfig(configPath, []byte(root)); ok || err == nil {
+		t.Errorf("Expected artifact to be stale after adding an included file, got ok=%v err=%v", ok, err)
+	}
+	if _, err := compileConfig(configPath); err != nil {
+		t.Fatalf("compileConfig() error = %v", err)
+	}
+
+	// 取り込んだファイルの編集も検知する
+	writeConfigFiles(t, dir, map[string]string{"hooks.d/a.yaml": stopHook("a2")})
+	if _, ok, err := loadCompiledConfig(configPath, []byte(root)); ok || err == nil {
+		t.Errorf("Expected artifact to be stale after editing an included file, got ok=%v err=%v", ok, err)
+	}
+	config, err := loadConfig(configPath)
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	if len(config.Stop) != 2 || config.Stop[0].Actions[0].Message != "a2" {
+		t.Errorf("Expected config parsed from edited includes, got %+v", config.Stop)
+	}
+}
+
+func TestLoadCompiledConfig_NoArtifact(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	config, ok, err := loadCompiledConfig(configPath, []byte(compiledTestConfig))
+	if config != nil || ok || err != nil {
+		t.Errorf("Expected (nil, false, nil) without artifact, got (%v, %v, %v)", config, ok, err)
+	}
+}
+
+func TestCompileConfig_InvalidConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	invalid := `
+PreToolUse:
+  - conditions:
+      - type: no_such_condition
+        value: "x"
+`
+	if err := os.WriteFile(configPath, []byte(invalid), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	if _, err := compileConfig(configPath); err == nil {
+		t.Error("Expected error for invalid condition type, got nil")
+	}
+	if _, err := os.Stat(compiledConfigPath(configPath)); !os.IsNotExist(err) {
+		t.Errorf("Expected no artifact to be written for invalid config")
+	}
+}
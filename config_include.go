@@ -0,0 +1,173 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configSources records the files a config was built from,
+// so that a compiled artifact can detect edited, added or removed files.
+type configSources struct {
+	Files map[string][]byte   // config file path → content
+	Globs map[string][]string // absolute include glob → matched files
+}
+
+// configLoader loads a config file together with the files it includes.
+type configLoader struct {
+	sources *configSources
+	loading []string        // include chain currently being loaded (cycle detection)
+	loaded  map[string]bool // files already merged; a file included twice is merged once
+}
+
+// parseConfigWithSources parses the config at configPath (data is its content), resolves `include:`
+// directives and returns the merged config with the files it was built from.
+func parseConfigWithSources(configPath string, data []byte) (*Config, *configSources, error) {
+	l := &configLoader{
+		sources: &configSources{Files: map[string][]byte{}, Globs: map[string][]string{}},
+		loaded:  map[string]bool{},
+	}
+	merged := &Config{}
+	if err := l.load(configPath, data, merged); err != nil {
+		return nil, nil, err
+	}
+	return merged, l.sources, nil
+}
+
+// load parses a single file and merges it into merged.
+// Included files are merged first (in the listed order, globs sorted by name) and the including file last,
+// so that its hooks run after the included ones and win where later hooks take precedence.
+func (l *configLoader) load(path string, data []byte, merged *Config) error {
+	key := absConfigPath(path)
+	if slices.Contains(l.loading, key) {
+		return fmt.Errorf("include cycle: %s", strings.Join(append(l.loading, key), " -> "))
+	}
+	if l.loaded[key] {
+		return nil
+	}
+	l.sources.Files[path] = data
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	if err := errors.Join(validateConfigConditions(&config), validateMergeStrategies(config.Merge), validateLogConfig(config.Log), validateOutputFormat(config.OutputFormat), validateStrictPermissions(config.StrictPermissions), validateGitUnavailable(config.OnGitUnavailable), validateBudgetConfig(config.Budget), validateDecisionLogConfig(config.DecisionLog), validateReportConfig(config.Report), validateProjectTrustConfig(config.ProjectConfig)); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	l.loading = append(l.loading, key)
+	for _, include := range config.Include {
+		pattern := includePattern(filepath.Dir(key), include)
+		files, err := resolveInclude(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid include %q in %s: %w", include, path, err)
+		}
+		if isGlobPattern(pattern) {
+			l.sources.Globs[pattern] = files
+		}
+		for _, file := range files {
+			included, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("failed to read included config file: %w", err)
+			}
+			if err := l.load(file, included, merged); err != nil {
+				return err
+			}
+		}
+	}
+	l.loading = l.loading[:len(l.loading)-1]
+	l.loaded[key] = true
+
+	// merge・log・output_format・strict_permissions・on_git_unavailable・self_test・budget・decision_log・report・project_configは読み込みの起点となったファイルのものだけを使う
+	if len(l.loading) == 0 {
+		merged.Merge = config.Merge
+		merged.Log = config.Log
+		merged.OutputFormat = config.OutputFormat
+		merged.StrictPermissions = config.StrictPermissions
+		merged.OnGitUnavailable = config.OnGitUnavailable
+		merged.SelfTest = config.SelfTest
+		merged.Budget = config.Budget
+		merged.DecisionLog = config.DecisionLog
+		merged.Report = config.Report
+		merged.ProjectConfig = config.ProjectConfig
+	}
+	merged.Files = append(merged.Files, key)
+	config.Include = nil
+	mergeConfig(merged, &config)
+	return nil
+}
+
+// mergeConfig appends the hooks of src to dst, event by event.
+func mergeConfig(dst, src *Config) {
+	dst.PreToolUse = append(dst.PreToolUse, src.PreToolUse...)
+	dst.PostToolUse = append(dst.PostToolUse, src.PostToolUse...)
+	dst.PermissionRequest = append(dst.PermissionRequest, src.PermissionRequest...)
+	dst.Notification = append(dst.Notification, src.Notification...)
+	dst.Stop = append(dst.Stop, src.Stop...)
+	dst.SubagentStop = append(dst.SubagentStop, src.SubagentStop...)
+	dst.SubagentStart = append(dst.SubagentStart, src.SubagentStart...)
+	dst.PreCompact = append(dst.PreCompact, src.PreCompact...)
+	dst.SessionStart = append(dst.SessionStart, src.SessionStart...)
+	dst.SessionEnd = append(dst.SessionEnd, src.SessionEnd...)
+	dst.UserPromptSubmit = append(dst.UserPromptSubmit, src.UserPromptSubmit...)
+}
+
+// includePattern returns the absolute path pattern of an include entry.
+// "~/" is expanded to the home directory and relative paths are resolved against baseDir.
+func includePattern(baseDir, include string) string {
+	if rest, ok := strings.CutPrefix(include, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			include = filepath.Join(home, rest)
+		}
+	}
+	if !filepath.IsAbs(include) {
+		include = filepath.Join(baseDir, include)
+	}
+	return filepath.Clean(include)
+}
+
+// resolveInclude returns the files an include pattern refers to.
+// A glob may match no files (e.g. an empty hooks.d); a plain path must name an existing file.
+func resolveInclude(pattern string) ([]string, error) {
+	if isGlobPattern(pattern) {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		var files []string
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && info.Mode().IsRegular() {
+				files = append(files, match)
+			}
+		}
+		return files, nil
+	}
+
+	info, err := os.Stat(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a file", pattern)
+	}
+	return []string{pattern}, nil
+}
+
+// isGlobPattern reports whether pattern contains glob metacharacters.
+func isGlobPattern(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+// absConfigPath returns the absolute form of path (path itself if it cannot be resolved).
+func absConfigPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	return abs
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfigFiles writes files (relative path → content) under dir.
+func writeConfigFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// stopHook returns a Stop hook config whose only action outputs message.
+func stopHook(message string) string {
+	return "Stop:\n  - actions:\n      - type: output\n        message: \"" + message + "\"\n"
+}
+
+func TestLoadConfig_Include(t *testing.T) {
+	dir := t.TempDir()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	writeConfigFiles(t, home, map[string]string{
+		"personal.yaml": stopHook("personal"),
+	})
+	writeConfigFiles(t, dir, map[string]string{
+		"config.yaml": `include:
+  - "hooks.d/*.yaml"
+  - shared.yaml
+  - "~/personal.yaml"
+  - "empty.d/*.yaml"
+` + stopHook("root"),
+		"hooks.d/10-b.yaml": stopHook("hooks.d/10-b"),
+		"hooks.d/01-a.yaml": stopHook("hooks.d/01-a"),
+		"hooks.d/README.md": "not a config",
+		"shared.yaml": `include: ["nested/extra.yaml", "hooks.d/01-a.yaml"]
+` + stopHook("shared"),
+		"nested/extra.yaml": stopHook("nested/extra") + `PreToolUse:
+  - matcher: "Bash"
+    actions:
+      - type: output
+        message: "from nested"
+`,
+	})
+
+	config, err := loadConfig(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+
+	var got []string
+	for _, hook := range config.Stop {
+		got = append(got, hook.Actions[0].Message)
+	}
+	want := []string{"hooks.d/01-a", "hooks.d/10-b", "nested/extra", "shared", "personal", "root"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Stop hooks = %v, want %v", got, want)
+	}
+	if len(config.PreToolUse) != 1 || config.PreToolUse[0].Actions[0].Message != "from nested" {
+		t.Errorf("Expected PreToolUse hook from nested include, got %+v", config.PreToolUse)
+	}
+	if config.Include != nil {
+		t.Errorf("Expected include to be cleared after merging, got %v", config.Include)
+	}
+}
+
+func TestLoadConfig_IncludeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{
+			name:    "missing file",
+			files:   map[string]string{"config.yaml": "include: [missing.yaml]\n"},
+			wantErr: `invalid include "missing.yaml"`,
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				"config.yaml": "include: [a.yaml]\n",
+				"a.yaml":      "include: [b.yaml]\n",
+				"b.yaml":      "include: [a.yaml]\n",
+			},
+			wantErr: "include cycle",
+		},
+		{
+			name: "invalid included file",
+			files: map[string]string{
+				"config.yaml": "include: [bad.yaml]\n",
+				"bad.yaml":    "Stop:\n  - conditions:\n      - type: not\n    actions: []\n",
+			},
+			wantErr: "bad.yaml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeConfigFiles(t, dir, tt.files)
+
+			_, err := loadConfig(filepath.Join(dir, "config.yaml"))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("loadConfig() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
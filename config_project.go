@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// projectConfigFileName is the project-local config discovered from the cwd of the event input.
+const projectConfigFileName = ".cchook.yaml"
+
+// Merge strategies of the project config (`merge:` in .cchook.yaml), per event type.
+const (
+	mergeAppend  = "append"  // run the project hooks after the global ones (default)
+	mergeReplace = "replace" // run only the project hooks (an empty list disables the global hooks)
+)
+
+// validateMergeStrategies checks the `merge:` section of a config.
+func validateMergeStrategies(merge map[HookEventType]string) error {
+	for eventType, strategy := range merge {
+		if !eventType.IsValid() {
+			return fmt.Errorf("merge: unknown event type %q", eventType)
+		}
+		if strategy != mergeAppend && strategy != mergeReplace {
+			return fmt.Errorf("merge: invalid strategy %q for %s (must be append or replace)", strategy, eventType)
+		}
+	}
+	return nil
+}
+
+// findProjectConfig looks for .cchook.yaml from cwd up to the project root
+// (the first directory containing .git) or the filesystem root.
+func findProjectConfig(cwd string) (string, bool) {
+	if cwd == "" {
+		return "", false
+	}
+	dir := filepath.Clean(cwd)
+	for {
+		path := filepath.Join(dir, projectConfigFileName)
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path, true
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return "", false
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// validateProjectTrustConfig checks the `project_config:` block of a config.
+func validateProjectTrustConfig(c *ProjectTrustConfig) error {
+	if c == nil {
+		return nil
+	}
+	for _, pattern := range c.Trusted {
+		if !filepath.IsAbs(expandHomeDir(pattern)) {
+			return fmt.Errorf("project_config: trusted %q must be an absolute path or start with ~/", pattern)
+		}
+		if err := validateGlob(filepath.ToSlash(expandHomeDir(pattern))); err != nil {
+			return fmt.Errorf("project_config: %w", err)
+		}
+	}
+	return nil
+}
+
+// projectConfigTrusted reports whether the project config at path is in a directory trusted by the global config.
+func projectConfigTrusted(trust *ProjectTrustConfig, path string) bool {
+	return trust != nil && matchesPathPatterns(filepath.Dir(absConfigPath(path)), trust.Trusted)
+}
+
+// matchesPathPatterns reports whether the absolute path matches one of the globs (absolute or starting with ~/).
+func matchesPathPatterns(path string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, err := matchGlob(filepath.ToSlash(filepath.Clean(expandHomeDir(pattern))), filepath.ToSlash(path)); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// withProjectConfig merges the project config found from cwd on top of config.
+// config is returned as-is when there is no project config, when it is the global config itself (globalPath),
+// or when its directory is not trusted by the `project_config:` block of config: a .cchook.yaml runs arbitrary
+// commands, so a repository must not be able to add hooks just by being opened. `merge: replace` is only
+// honored with `allow_replace`, so that a repository can't turn off the global policies.
+func withProjectConfig(config *Config, globalPath, cwd string) (*Config, error) {
+	path, ok := findProjectConfig(cwd)
+	if !ok {
+		return config, nil
+	}
+	if globalPath == "" {
+		globalPath = getDefaultConfigPath()
+	}
+	if absConfigPath(path) == absConfigPath(globalPath) {
+		return config, nil
+	}
+	if !projectConfigTrusted(config.ProjectConfig, path) {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring untrusted project config %s (add its directory to project_config.trusted in the global config)\n", path)
+		return config, nil
+	}
+
+	project, ok := loadCachedConfig(path)
+	if !ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read project config file: %w", err)
+		}
+		if project, err = parseConfigCached(path, data); err != nil {
+			return nil, err
+		}
+	}
+	if !config.ProjectConfig.AllowReplace && slices.Contains(slices.Collect(maps.Values(project.Merge)), mergeReplace) {
+		fmt.Fprintf(os.Stderr, "Warning: %s: merge: replace is treated as append (set project_config.allow_replace in the global config to allow it)\n", path)
+		project.Merge = nil
+	}
+	return overlayConfig(config, project), nil
+}
+
+// overlayConfig returns config with the hooks of project merged in, following project.Merge.
+func overlayConfig(config, project *Config) *Config {
+	merged := *config
+	merged.Files = append(slices.Clip(config.Files), project.Files...)
+	merged.PreToolUse = overlayHooks(config.PreToolUse, project.PreToolUse, project.Merge[PreToolUse])
+	merged.PostToolUse = overlayHooks(config.PostToolUse, project.PostToolUse, project.Merge[PostToolUse])
+	merged.PermissionRequest = overlayHooks(config.PermissionRequest, project.PermissionRequest, project.Merge[PermissionRequest])
+	merged.Notification = overlayHooks(config.Notification, project.Notification, project.Merge[Notification])
+	merged.Stop = overlayHooks(config.Stop, project.Stop, project.Merge[Stop])
+	merged.SubagentStop = overlayHooks(config.SubagentStop, project.SubagentStop, project.Merge[SubagentStop])
+	merged.SubagentStart = overlayHooks(config.SubagentStart, project.SubagentStart, project.Merge[SubagentStart])
+	merged.PreCompact = overlayHooks(config.PreCompact, project.PreCompact, project.Merge[PreCompact])
+	merged.SessionStart = overlayHooks(config.SessionStart, project.SessionStart, project.Merge[SessionStart])
+	merged.SessionEnd = overlayHooks(config.SessionEnd, project.SessionEnd, project.Merge[SessionEnd])
+	merged.UserPromptSubmit = overlayHooks(config.UserPromptSubmit, project.UserPromptSubmit, project.Merge[UserPromptSubmit])
+	return &merged
+}
+
+// overlayHooks merges the project hooks of one event type into the global ones.
+func overlayHooks[H any](global, project []H, strategy string) []H {
+	if strategy == mergeReplace {
+		return project
+	}
+	return append(slices.Clip(global), project...)
+}
+
+// inputCwd returns the cwd field of the event JSON (empty if missing).
+func inputCwd(rawInput json.RawMessage) string {
+	var input struct {
+		Cwd string `json:"cwd"`
+	}
+	if err := json.Unmarshal(rawInput, &input); err != nil {
+		return ""
+	}
+	return input.Cwd
+}
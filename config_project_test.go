@@ -0,0 +1,192 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindProjectConfig(t *testing.T) {
+	root := t.TempDir()
+	writeConfigFiles(t, root, map[string]string{
+		"outer/.cchook.yaml":                   stopHook("outer"),
+		"outer/repo/.git/HEAD":                 "ref: refs/heads/main\n",
+		"outer/repo/src/pkg/main.go":           "package main\n",
+		"outer/project/.cchook.yaml":           stopHook("project"),
+		"outer/project/src/main.go":            "package main\n",
+		"outer/dir-config/.cchook.yaml/README": "not a config",
+	})
+
+	tests := []struct {
+		name string
+		cwd  string
+		want string
+	}{
+		{"config in cwd", "outer/project", "outer/project/.cchook.yaml"},
+		{"config in parent", "outer/project/src", "outer/project/.cchook.yaml"},
+		{"stops at git root", "outer/repo/src/pkg", ""},
+		{"directory named .cchook.yaml is skipped", "outer/dir-config", "outer/.cchook.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := findProjectConfig(filepath.Join(root, tt.cwd))
+			if tt.want == "" {
+				if ok {
+					t.Errorf("findProjectConfig() = %q, want none", got)
+				}
+				return
+			}
+			if !ok || got != filepath.Join(root, tt.want) {
+				t.Errorf("findProjectConfig() = %q, %v, want %q", got, ok, tt.want)
+			}
+		})
+	}
+
+	if _, ok := findProjectConfig(""); ok {
+		t.Error("Expected no project config for empty cwd")
+	}
+}
+
+func TestWithProjectConfig(t *testing.T) {
+	dir := t.TempDir()
+	globalPath := filepath.Join(dir, "global.yaml")
+	writeConfigFiles(t, dir, map[string]string{
+		"global.yaml": "project_config:\n  trusted: [" + dir + "/*]\n  allow_replace: true\n" + stopHook("global") + `PreToolUse:
+  - matcher: "Bash"
+    actions:
+      - type: output
+        message: "global pre"
+Notification:
+  - actions:
+      - type: output
+        message: "global notification"
+`,
+		"project/.git/HEAD": "ref: refs/heads/main\n",
+		"project/.cchook.yaml": `merge:
+  PreToolUse: replace
+  Notification: replace
+include: [hooks/shared.yaml]
+` + stopHook("project") + `PreToolUse:
+  - matcher: "Bash"
+    actions:
+      - type: output
+        message: "project pre"
+`,
+		"project/hooks/shared.yaml": stopHook("shared"),
+	})
+
+	global, err := loadConfig(globalPath)
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+
+	config, err := withProjectConfig(global, globalPath, filepath.Join(dir, "project"))
+	if err != nil {
+		t.Fatalf("withProjectConfig() error = %v", err)
+	}
+
+	var stop []string
+	for _, hook := range config.Stop {
+		stop = append(stop, hook.Actions[0].Message)
+	}
+	if got, want := strings.Join(stop, ","), "global,shared,project"; got != want {
+		t.Errorf("Stop hooks = %s, want %s (append)", got, want)
+	}
+	if len(config.PreToolUse) != 1 || config.PreToolUse[0].Actions[0].Message != "project pre" {
+		t.Errorf("Expected PreToolUse to be replaced, got %+v", config.PreToolUse)
+	}
+	if len(config.Notification) != 0 {
+		t.Errorf("Expected replace with no hooks to disable global Notification hooks, got %+v", config.Notification)
+	}
+	if len(global.Stop) != 1 || len(global.PreToolUse) != 1 {
+		t.Error("Expected the global config to be left untouched")
+	}
+
+	// プロジェクトの設定が無い場合・グローバル設定そのものの場合はそのまま
+	if got, err := withProjectConfig(global, globalPath, filepath.Join(dir, "project", "hooks")); err != nil || len(got.Stop) != 3 {
+		t.Errorf("Expected project config found from subdirectory, got %v, %v", got, err)
+	}
+	if got, err := withProjectConfig(global, globalPath, dir); err != nil || got != global {
+		t.Errorf("Expected global config without project config, got %v, %v", got, err)
+	}
+	projectPath := filepath.Join(dir, "project", ".cchook.yaml")
+	if got, err := withProjectConfig(global, projectPath, filepath.Join(dir, "project")); err != nil || got != global {
+		t.Errorf("Expected no overlay when the project config is the global config, got %v, %v", got, err)
+	}
+}
+
+func TestWithProjectConfig_InvalidMerge(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFiles(t, dir, map[string]string{
+		".git/HEAD":    "ref: refs/heads/main\n",
+		".cchook.yaml": "merge:\n  Stop: override\n",
+	})
+
+	config := &Config{ProjectConfig: &ProjectTrustConfig{Trusted: []string{dir}}}
+	_, err := withProjectConfig(config, filepath.Join(dir, "global.yaml"), dir)
+	if err == nil || !strings.Contains(err.Error(), `invalid strategy "override"`) {
+		t.Errorf("withProjectConfig() error = %v, want invalid strategy", err)
+	}
+}
+
+func TestWithProjectConfig_Trust(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFiles(t, dir, map[string]string{
+		".git/HEAD": "ref: refs/heads/main\n",
+		".cchook.yaml": `merge:
+  PreToolUse: replace
+PreToolUse:
+  - matcher: "Bash"
+    actions:
+      - type: output
+        message: "pwned-by-repo"
+`,
+	})
+	global := &Config{PreToolUse: []PreToolUseHook{{Matcher: "Bash", Actions: []Action{{Type: "output", Message: "no rm"}}}}}
+	globalPath := filepath.Join(t.TempDir(), "global.yaml")
+
+	// 許可リストに無いリポジトリの.cchook.yamlは読み込まない
+	for _, trust := range []*ProjectTrustConfig{nil, {Trusted: []string{filepath.Join(dir, "other")}}} {
+		global.ProjectConfig = trust
+		if got, err := withProjectConfig(global, globalPath, dir); err != nil || got != global {
+			t.Errorf("withProjectConfig() with %+v = %v, %v, want the global config", trust, got, err)
+		}
+	}
+
+	// allow_replaceが無ければグローバルのフックは置き換えられない
+	global.ProjectConfig = &ProjectTrustConfig{Trusted: []string{filepath.Dir(dir) + "/*"}}
+	got, err := withProjectConfig(global, globalPath, dir)
+	if err != nil {
+		t.Fatalf("withProjectConfig() error = %v", err)
+	}
+	if len(got.PreToolUse) != 2 || got.PreToolUse[0].Actions[0].Message != "no rm" {
+		t.Errorf("Expected merge: replace to be treated as append, got %+v", got.PreToolUse)
+	}
+}
+
+func TestParseInput_Prefetched(t *testing.T) {
+	t.Cleanup(func() { prefetchedInput = nil })
+	withStdin(t, `{"session_id":"s1","hook_event_name":"Stop","cwd":"/work/project"}`)
+
+	raw, err := prefetchInput()
+	if err != nil {
+		t.Fatalf("prefetchInput() error = %v", err)
+	}
+	if got := inputCwd(raw); got != "/work/project" {
+		t.Errorf("inputCwd() = %q, want /work/project", got)
+	}
+
+	input, _, err := parseInput[*StopInput](Stop)
+	if err != nil {
+		t.Fatalf("parseInput() error = %v", err)
+	}
+	if input.SessionID != "s1" {
+		t.Errorf("Expected the prefetched input to be parsed, got session_id %q", input.SessionID)
+	}
+
+	prefetchedInput = &prefetchedStdin{err: os.ErrClosed}
+	if _, _, err := parseInput[*StopInput](Stop); err != os.ErrClosed {
+		t.Errorf("Expected the prefetch error to be returned, got %v", err)
+	}
+}
@@ -102,6 +102,84 @@ PostToolUse:
 	}
 }
 
+func TestParseConfig_InvalidConditionGroups(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{
+			name: "empty group",
+			yaml: `PreToolUse:
+  - matcher: "Bash"
+    conditions:
+      - type: any_of
+    actions:
+      - type: output
+        message: "x"
+`,
+			wantErr: "any_of requires at least one condition",
+		},
+		{
+			name: "group with value",
+			yaml: `Stop:
+  - conditions:
+      - type: not
+        value: "x"
+        conditions:
+          - type: cwd_contains
+            value: "vendor"
+    actions:
+      - type: output
+        message: "x"
+`,
+			wantErr: "not does not take value or values",
+		},
+		{
+			name: "conditions on leaf condition",
+			yaml: `Stop:
+  - conditions:
+      - type: cwd_contains
+        value: "vendor"
+        conditions:
+          - type: cwd_contains
+            value: "x"
+    actions:
+      - type: output
+        message: "x"
+`,
+			wantErr: "Stop hook 1: conditions can only be used with any_of, all_of or not",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseConfig("test.yaml", []byte(tt.yaml))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseConfig() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateConditions_NestingDepth(t *testing.T) {
+	leaf := Condition{Type: ConditionFileExtension, Value: ".go"}
+	nest := func(depth int) []Condition {
+		conditions := []Condition{leaf}
+		for i := 0; i < depth; i++ {
+			conditions = []Condition{{Type: ConditionNot, Conditions: conditions}}
+		}
+		return conditions
+	}
+
+	if err := validateConditions(nest(maxConditionNestingDepth), 1); err != nil {
+		t.Errorf("validateConditions() at max depth error = %v, want nil", err)
+	}
+	if err := validateConditions(nest(maxConditionNestingDepth+1), 1); err == nil {
+		t.Error("validateConditions() beyond max depth error = nil, want error")
+	}
+}
+
 func TestLoadConfig_EmptyFile(t *testing.T) {
 	tmpDir := t.TempDir()
 	configPath := filepath.Join(tmpDir, "empty.yaml")
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scopes of a hook's cooldown.
+const (
+	cooldownScopeSession = "session"
+	cooldownScopeGlobal  = "global"
+)
+
+// validateCooldown checks the cooldown and cooldown_scope of a hook.
+func validateCooldown(cooldown, scope string) error {
+	if cooldown != "" {
+		if d, err := parseDurationWithDays(cooldown); err != nil || d == 0 {
+			return fmt.Errorf("invalid cooldown %q (use a positive duration such as 30s, 10m, 1h or 1d)", cooldown)
+		}
+	}
+	if scope != "" && scope != cooldownScopeSession && scope != cooldownScopeGlobal {
+		return fmt.Errorf("invalid cooldown_scope %q (must be session or global)", scope)
+	}
+	return nil
+}
+
+// cooldownStorePath returns the file holding the cooldowns of the scope: one per session, or one shared by all sessions.
+func cooldownStorePath(scope, sessionID string) string {
+	if scope == cooldownScopeGlobal {
+		return filepath.Join(sessionStateDir(), "cooldowns.json")
+	}
+	return sessionStatePath(sessionID, ".cooldowns.json")
+}
+
+// cooldownKey identifies a hook in the cooldown store by its event and definition,
+// so that editing a hook (or moving it in the config) doesn't keep it cooling down.
+func cooldownKey(eventType HookEventType, hook any) string {
+	data, _ := yaml.Marshal(hook)
+	sum := sha256.Sum256(append([]byte(string(eventType)+"\x00"), data...))
+	return hex.EncodeToString(sum[:16])
+}
+
+// claimCooldown reports whether a matched hook with a cooldown may run its actions now, and if so
+// records that it ran. Checking and recording happen under a file lock, so of several concurrent
+// cchook processes only one runs the hook per cooldown. Hooks without a cooldown always run,
+// and so does a hook whose cooldown store can't be used (with a warning on stderr).
+// During a diagnostic run the cooldown is only checked, so explaining an event doesn't make the next real run skip the hook.
+func claimCooldown(eventType HookEventType, id string, hook any, cooldown, scope, sessionID string) bool {
+	if cooldown == "" {
+		return true
+	}
+	d, err := parseDurationWithDays(cooldown)
+	if err != nil || d == 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %s: invalid cooldown %q, ignoring it\n", hookRef(eventType, id), cooldown)
+		return true
+	}
+	path, key := cooldownStorePath(scope, sessionID), cooldownKey(eventType, hook)
+	if diagnosticRun {
+		until, running := runningCooldown(path, key)
+		if running {
+			hookLog.Info("hook skipped by cooldown", "hook_id", id, "until", until.Format(time.RFC3339))
+		}
+		return !running
+	}
+	until, ok, err := takeCooldown(path, key, d)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %s: cooldown not applied: %v\n", hookRef(eventType, id), err)
+		return true
+	}
+	if !ok {
+		hookLog.Info("hook skipped by cooldown", "hook_id", id, "until", until.Format(time.RFC3339))
+	}
+	return ok
+}
+
+// runningCooldown reports whether the cooldown of key in the store at path is still running, and when it ends.
+func runningCooldown(path, key string) (time.Time, bool) {
+	store := map[string]time.Time{}
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &store)
+	}
+	until, ok := store[key]
+	return until, ok && currentTime().Before(until)
+}
+
+// takeCooldown starts the cooldown of key in the store at path unless it is still running,
+// in which case it returns false and the time the running cooldown ends.
+// Entries of cooldowns that have ended are dropped from the store.
+func takeCooldown(path, key string, d time.Duration) (time.Time, bool, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to create session state directory: %w", err)
+	}
+	unlock, err := lockFile(path + ".lock")
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to lock cooldowns: %w", err)
+	}
+	defer unlock()
+
+	store := map[string]time.Time{}
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &store)
+	}
+	now := currentTime()
+	if until, ok := store[key]; ok && now.Before(until) {
+		return until, false, nil
+	}
+	for k, until := range store {
+		if !now.Before(until) {
+			delete(store, k)
+		}
+	}
+	store[key] = now.Add(d)
+
+	data, err := json.Marshal(store)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to save cooldowns: %w", err)
+	}
+	return store[key], true, nil
+}
+
+// cooldownScopeName returns the scope of a cooldown, defaulting to session.
+func cooldownScopeName(scope string) string {
+	if scope == "" {
+		return cooldownScopeSession
+	}
+	return scope
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestValidateCooldown(t *testing.T) {
+	tests := []struct {
+		cooldown string
+		scope    string
+		wantErr  bool
+	}{
+		{cooldown: "10m"},
+		{cooldown: "1d", scope: "global"},
+		{cooldown: "30s", scope: "session"},
+		{cooldown: "0s", wantErr: true},
+		{cooldown: "-5m", wantErr: true},
+		{cooldown: "ten minutes", wantErr: true},
+		{cooldown: "10m", scope: "project", wantErr: true},
+	}
+	for _, tt := range tests {
+		err := validateCooldown(tt.cooldown, tt.scope)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("validateCooldown(%q, %q) error = %v, wantErr %v", tt.cooldown, tt.scope, err, tt.wantErr)
+		}
+	}
+}
+
+func TestClaimCooldown(t *testing.T) {
+	withSessionStateDir(t)
+	now := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	withCurrentTime(t, now)
+	hook := StopHook{Actions: []Action{{Type: "output", Message: "take a break"}}, Cooldown: "10m"}
+
+	if !claimCooldown(Stop, "hook0", hook, hook.Cooldown, "", "s1") {
+		t.Fatal("first claim should run the hook")
+	}
+	if claimCooldown(Stop, "hook0", hook, hook.Cooldown, "", "s1") {
+		t.Error("claim within the cooldown should skip the hook")
+	}
+	if !claimCooldown(Stop, "hook0", hook, hook.Cooldown, "", "s2") {
+		t.Error("session cooldown should not apply to another session")
+	}
+	other := StopHook{Actions: []Action{{Type: "output", Message: "drink water"}}, Cooldown: "10m"}
+	if !claimCooldown(Stop, "hook1", other, other.Cooldown, "", "s1") {
+		t.Error("cooldown should not apply to another hook")
+	}
+	if !claimCooldown(SubagentStop, "hook0", hook, hook.Cooldown, "", "s1") {
+		t.Error("cooldown should not apply to the same hook of another event")
+	}
+
+	withCurrentTime(t, now.Add(9*time.Minute))
+	if claimCooldown(Stop, "hook0", hook, hook.Cooldown, "", "s1") {
+		t.Error("claim 9 minutes later should still skip the hook")
+	}
+	withCurrentTime(t, now.Add(10*time.Minute))
+	if !claimCooldown(Stop, "hook0", hook, hook.Cooldown, "", "s1") {
+		t.Error("claim after the cooldown should run the hook")
+	}
+
+	if !claimCooldown(Stop, "hook0", hook, "", "", "s1") {
+		t.Error("hooks without cooldown should always run")
+	}
+}
+
+func TestClaimCooldown_GlobalScope(t *testing.T) {
+	withSessionStateDir(t)
+	withCurrentTime(t, time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC))
+	hook := NotificationHook{Actions: []Action{{Type: "command", Command: "notify-send done"}}, Cooldown: "1h", CooldownScope: "global"}
+
+	if !claimCooldown(Notification, "hook0", hook, hook.Cooldown, hook.CooldownScope, "s1") {
+		t.Fatal("first claim should run the hook")
+	}
+	if claimCooldown(Notification, "hook0", hook, hook.Cooldown, hook.CooldownScope, "s2") {
+		t.Error("global cooldown should apply to other sessions")
+	}
+}
+
+func TestClaimCooldown_Concurrent(t *testing.T) {
+	withSessionStateDir(t)
+	hook := StopHook{Actions: []Action{{Type: "output", Message: "reminder"}}, Cooldown: "10m", CooldownScope: "global"}
+
+	var fired atomic.Int32
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if claimCooldown(Stop, "hook0", hook, hook.Cooldown, hook.CooldownScope, "session-"+string(rune('a'+i))) {
+				fired.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := fired.Load(); got != 1 {
+		t.Errorf("hook fired %d times, want 1", got)
+	}
+}
+
+func TestExecuteUserPromptSubmitHooks_Cooldown(t *testing.T) {
+	withSessionStateDir(t)
+	config := &Config{
+		UserPromptSubmit: []UserPromptSubmitHook{
+			{Actions: []Action{{Type: "output", Message: "Remember to run the tests"}}, Cooldown: "10m"},
+			{Actions: []Action{{Type: "output", Message: "Always shown"}}},
+		},
+	}
+	input := &UserPromptSubmitInput{BaseInput: BaseInput{SessionID: "s1", HookEventName: UserPromptSubmit}, Prompt: "hi"}
+
+	for i, want := range []string{"Remember to run the tests\nAlways shown", "Always shown"} {
+		output, err := executeUserPromptSubmitHooks(config, input, map[string]any{})
+		if err != nil {
+			t.Fatalf("run %d: unexpected error: %v", i+1, err)
+		}
+		if output.HookSpecificOutput == nil || output.HookSpecificOutput.AdditionalContext != want {
+			t.Errorf("run %d: additionalContext = %+v, want %q", i+1, output.HookSpecificOutput, want)
+		}
+	}
+}
+
+func TestExplainOutput_CooldownNotClaimed(t *testing.T) {
+	withSessionStateDir(t)
+	config := &Config{
+		UserPromptSubmit: []UserPromptSubmitHook{
+			{Actions: []Action{{Type: "output", Message: "Remember to run the tests"}}, Cooldown: "10m"},
+		},
+	}
+	data := []byte(`{"session_id":"s1","hook_event_name":"UserPromptSubmit","prompt":"hi"}`)
+	input := &UserPromptSubmitInput{BaseInput: BaseInput{SessionID: "s1", HookEventName: UserPromptSubmit}, Prompt: "hi"}
+
+	// explainは何度実行してもクールダウンを開始しない
+	for i := 0; i < 2; i++ {
+		output, err := explainOutput(data, config, UserPromptSubmit)
+		if err != nil {
+			t.Fatalf("explain %d: unexpected error: %v", i+1, err)
+		}
+		if got := output.(*UserPromptSubmitOutput).HookSpecificOutput; got == nil || got.AdditionalContext != "Remember to run the tests" {
+			t.Errorf("explain %d: additionalContext = %+v, want the hook to run", i+1, got)
+		}
+	}
+
+	output, err := executeUserPromptSubmitHooks(config, input, map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.HookSpecificOutput == nil || output.HookSpecificOutput.AdditionalContext != "Remember to run the tests" {
+		t.Errorf("real run after explain: additionalContext = %+v, want the hook to run", output.HookSpecificOutput)
+	}
+
+	// 実際の実行で始まったクールダウンはexplainにも反映される
+	output2, err := explainOutput(data, config, UserPromptSubmit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := output2.(*UserPromptSubmitOutput).HookSpecificOutput; got != nil && got.AdditionalContext != "" {
+		t.Errorf("explain within the cooldown: additionalContext = %q, want the hook skipped", got.AdditionalContext)
+	}
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Formats of the `decision_log:` block.
+const (
+	decisionLogCloudEvents = "cloudevents"
+	decisionLogOCSF        = "ocsf"
+)
+
+// defaultDecisionLogTimeout is the timeout of the HTTP sink without `timeout`.
+const defaultDecisionLogTimeout = 5 * time.Second
+
+// Identifiers of the exported records.
+const (
+	decisionEventSource = "cchook"
+	decisionEventType   = "io.github.syou6162.cchook.decision"
+	ocsfSchemaVersion   = "1.3.0"
+)
+
+// Decisions of an event, as exported.
+const (
+	decisionNone  = "none"  // the hooks didn't decide anything
+	decisionAllow = "allow" // PreToolUse / PermissionRequest allowed
+	decisionDeny  = "deny"  // PreToolUse / PermissionRequest denied
+	decisionAsk   = "ask"   // PreToolUse left to the user
+	decisionBlock = "block" // PostToolUse, Stop, SubagentStop or UserPromptSubmit blocked
+	decisionStop  = "stop"  // continue: false
+)
+
+// activeDecisionLog is the `decision_log:` block of the loaded config (nil to export nothing).
+var activeDecisionLog *DecisionLogConfig
+
+// validateDecisionLogConfig checks the `decision_log:` block of a config.
+func validateDecisionLogConfig(c *DecisionLogConfig) error {
+	if c == nil {
+		return nil
+	}
+	if c.Format != "" && c.Format != decisionLogCloudEvents && c.Format != decisionLogOCSF {
+		return fmt.Errorf("decision_log: invalid format %q (must be cloudevents or ocsf)", c.Format)
+	}
+	if c.Path == "" && c.URL == "" {
+		return errors.New("decision_log: path or url is required")
+	}
+	if c.URL != "" {
+		if err := validateSinkURL(c.URL); err != nil {
+			return fmt.Errorf("decision_log: %w", err)
+		}
+	}
+	if _, err := parseSinkTimeout(c.Timeout, defaultDecisionLogTimeout); err != nil {
+		return fmt.Errorf("decision_log: %w", err)
+	}
+	return nil
+}
+
+// validateSinkURL checks the URL of an HTTP sink. URLs with $VAR are expanded at run time and not checked.
+func validateSinkURL(rawURL string) error {
+	if strings.Contains(rawURL, "$") {
+		return nil
+	}
+	if u, err := url.Parse(rawURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid url %q (must be an http or https URL)", rawURL)
+	}
+	return nil
+}
+
+// parseSinkTimeout parses the timeout of an HTTP sink (def when empty).
+func parseSinkTimeout(value string, def time.Duration) (time.Duration, error) {
+	if value == "" {
+		return def, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %q: %w", value, err)
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("invalid timeout %q: must be positive", value)
+	}
+	return timeout, nil
+}
+
+// decision is the outcome of an event, derived from its final JSON output.
+type decision struct {
+	Event     string          `json:"event"`
+	SessionID string          `json:"session_id,omitempty"`
+	Cwd       string          `json:"cwd,omitempty"`
+	ToolName  string          `json:"tool_name,omitempty"`
+	ToolInput json.RawMessage `json:"tool_input,omitempty"`
+	Decision  string          `json:"decision"`
+	Reason    string          `json:"reason,omitempty"`
+	Hooks     []string        `json:"hooks,omitempty"`    // マッチしたフックのID
+	TraceID   string          `json:"trace_id,omitempty"` // ログのtrace_id (explain-decisionで使う)
+	Output    json.RawMessage `json:"output"`
+}
+
+// eventDecision returns the decision and its reason expressed by the JSON output of eventType.
+func eventDecision(eventType HookEventType, jsonBytes []byte) (string, string) {
+	var output struct {
+		legacyOutput
+		Continue   *bool  `json:"continue"`
+		StopReason string `json:"stopReason"`
+	}
+	if err := json.Unmarshal(jsonBytes, &output); err != nil {
+		return decisionNone, ""
+	}
+	if output.Continue != nil && !*output.Continue {
+		reason := output.StopReason
+		if reason == "" {
+			reason = output.SystemMessage
+		}
+		return decisionStop, reason
+	}
+	specific := output.HookSpecificOutput
+
+	switch eventType {
+	case PreToolUse:
+		if specific.PermissionDecision != "" {
+			return specific.PermissionDecision, specific.PermissionDecisionReason
+		}
+	case PermissionRequest:
+		if specific.Decision != nil && specific.Decision.Behavior != "" {
+			return specific.Decision.Behavior, specific.Decision.Message
+		}
+	case PostToolUse, Stop, SubagentStop, UserPromptSubmit:
+		if output.Decision == "block" {
+			reason := output.Reason
+			if reason == "" {
+				reason = output.SystemMessage
+			}
+			return decisionBlock, reason
+		}
+	}
+	return decisionNone, ""
+}
+
+// newDecision builds the decision of eventType from the event JSON and the final output.
+// The prompt and other free text of the event are left out; the tool input is kept to audit what was decided on.
+func newDecision(eventType HookEventType, rawInput json.RawMessage, jsonBytes []byte) decision {
+	d := decision{Event: string(eventType), Output: json.RawMessage(compactJSON(jsonBytes))}
+	if !json.Valid(d.Output) {
+		d.Output, _ = json.Marshal(string(jsonBytes))
+	}
+	d.Decision, d.Reason = eventDecision(eventType, jsonBytes)
+
+	var input struct {
+		SessionID string          `json:"session_id"`
+		Cwd       string          `json:"cwd"`
+		ToolName  string          `json:"tool_name"`
+		ToolInput json.RawMessage `json:"tool_input"`
+	}
+	if err := json.Unmarshal(rawInput, &input); err == nil {
+		d.SessionID, d.Cwd, d.ToolName, d.ToolInput = input.SessionID, input.Cwd, input.ToolName, input.ToolInput
+	}
+	return d
+}
+
+// newDecisionID returns a random ID of an exported record.
+func newDecisionID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// cloudEventRecord formats d as a CloudEvents 1.0 event in structured JSON mode.
+func cloudEventRecord(d decision, id string, now time.Time) map[string]any {
+	subject := d.Event
+	if d.ToolName != "" {
+		subject += "/" + d.ToolName
+	}
+	return map[string]any{
+		"specversion":     "1.0",
+		"id":              id,
+		"source":          decisionEventSource,
+		"type":            decisionEventType,
+		"subject":         subject,
+		"time":            now.UTC().Format(time.RFC3339Nano),
+		"datacontenttype": "application/json",
+		"data":            d,
+	}
+}
+
+// ocsfRecord formats d as an OCSF API Activity event: the hook event is the operation of the session's agent,
+// and the decision is the action (allowed / denied) taken on it.
+func ocsfRecord(d decision, id string, now time.Time) map[string]any {
+	const (
+		classUID    = 6003 // API Activity
+		categoryUID = 6    // Application Activity
+		activityID  = 99   // Other
+	)
+	actionID, action, severityID := 0, "Unknown", 1 // Informational
+	switch d.Decision {
+	case decisionAllow:
+		actionID, action = 1, "Allowed"
+	case decisionDeny, decisionBlock, decisionStop:
+		actionID, action, severityID = 2, "Denied", 3 // Medium
+	case decisionAsk:
+		actionID, action = 99, "Other"
+	}
+	operation := d.Event
+	if d.ToolName != "" {
+		operation = d.ToolName
+	}
+	hostname, _ := os.Hostname()
+
+	return map[string]any{
+		"class_uid":     classUID,
+		"class_name":    "API Activity",
+		"category_uid":  categoryUID,
+		"category_name": "Application Activity",
+		"activity_id":   activityID,
+		"activity_name": d.Event,
+		"type_uid":      classUID*100 + activityID,
+		"time":          now.UnixMilli(),
+		"severity_id":   severityID,
+		"action_id":     actionID,
+		"action":        action,
+		"status_id":     1, // Success
+		"message":       d.Reason,
+		"metadata": map[string]any{
+			"uid":      id,
+			"version":  ocsfSchemaVersion,
+			"log_name": "decision_log",
+			"product":  map[string]any{"name": decisionEventSource, "vendor_name": decisionEventSource},
+		},
+		"actor":        map[string]any{"app_name": "Claude Code", "session": map[string]any{"uid": d.SessionID}},
+		"api":          map[string]any{"operation": operation},
+		"src_endpoint": map[string]any{"hostname": hostname},
+		"unmapped":     d,
+	}
+}
+
+// decisionRecord returns the record of d in the format of the `decision_log:` block.
+func decisionRecord(c *DecisionLogConfig, d decision, now time.Time) ([]byte, error) {
+	id := newDecisionID()
+	if c.Format == decisionLogOCSF {
+		return json.Marshal(ocsfRecord(d, id, now))
+	}
+	return json.Marshal(cloudEventRecord(d, id, now))
+}
+
+// exportDecision writes the decision of eventType, derived from the final JSON output, to the sinks of
+// activeDecisionLog. Failures are reported as warnings: the hook output is never affected by the exporter.
+func exportDecision(eventType HookEventType, jsonBytes []byte) {
+	if activeDecisionLog == nil {
+		return
+	}
+	// 入力はrunHookEventで先読みしている
+	rawInput, _ := prefetchInput()
+	d := newDecision(eventType, rawInput, jsonBytes)
+	d.Hooks = matchedHooks()
+	// tool_inputにはsecret_scanで拒否した内容も含まれるので、明示したときだけマスクして出力する
+	if activeDecisionLog.IncludeToolInput {
+		d.ToolInput = redactSecretsJSON(d.ToolInput)
+	} else {
+		d.ToolInput = nil
+	}
+	d.TraceID = eventTraceID
+	if err := writeDecisionRecord(activeDecisionLog, d, time.Now()); err != nil {
+		hookLog.Warn("decision export failed", "error", err.Error())
+		fmt.Fprintf(os.Stderr, "Warning: failed to export decision: %v\n", err)
+	}
+}
+
+// writeDecisionRecord appends the record of d to the file and POSTs it to the URL of the `decision_log:` block.
+func writeDecisionRecord(c *DecisionLogConfig, d decision, now time.Time) error {
+	record, err := decisionRecord(c, d, now)
+	if err != nil {
+		return fmt.Errorf("failed to encode decision: %w", err)
+	}
+	if c.Path != "" {
+		if err := appendDecisionRecord(expandHomeDir(c.Path), record); err != nil {
+			return err
+		}
+	}
+	if c.URL != "" {
+		return postDecisionRecord(DefaultHTTPClient, c, record)
+	}
+	return nil
+}
+
+// expandHomeDir expands a leading "~/" of path to the home directory.
+func expandHomeDir(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
+
+// appendDecisionRecord appends record as a line of the JSON Lines file at path.
+func appendDecisionRecord(path string, record []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create decision log directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open decision log: %w", err)
+	}
+	// 1行を1回で書き込み、並行して動くフックの記録が混ざらないようにする
+	if _, err := f.Write(append(record, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write decision log: %w", err)
+	}
+	return f.Close()
+}
+
+// postDecisionRecord sends record to the URL of the `decision_log:` block. CloudEvents are sent in structured mode.
+func postDecisionRecord(client HTTPClient, c *DecisionLogConfig, record []byte) error {
+	timeout, err := parseSinkTimeout(c.Timeout, defaultDecisionLogTimeout)
+	if err != nil {
+		return err
+	}
+	contentType := "application/cloudevents+json"
+	if c.Format == decisionLogOCSF {
+		contentType = "application/json"
+	}
+	if err := postToSink(client, c.URL, c.Headers, timeout, contentType, record); err != nil {
+		return fmt.Errorf("failed to send decision: %w", err)
+	}
+	return nil
+}
+
+// postToSink POSTs body to the HTTP sink at rawURL ($VAR and ${VAR} are expanded in the URL and headers).
+// A response other than 2xx is an error. Errors don't contain the URL, which often contains a token.
+func postToSink(client HTTPClient, rawURL string, headers map[string]string, timeout time.Duration, contentType string, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, os.ExpandEnv(rawURL), bytes.NewReader(body))
+	if err != nil {
+		return errors.New("invalid url")
+	}
+	req.Header.Set("Content-Type", contentType)
+	for name, value := range headers {
+		req.Header.Set(name, os.ExpandEnv(value))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.New(resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventDecision(t *testing.T) {
+	tests := []struct {
+		name         string
+		eventType    HookEventType
+		output       string
+		wantDecision string
+		wantReason   string
+	}{
+		{"PreToolUse deny", PreToolUse, `{"continue":true,"hookSpecificOutput":{"permissionDecision":"deny","permissionDecisionReason":"no rm"}}`, decisionDeny, "no rm"},
+		{"PreToolUse ask", PreToolUse, `{"continue":true,"hookSpecificOutput":{"permissionDecision":"ask"}}`, decisionAsk, ""},
+		{"PreToolUse without decision", PreToolUse, `{"continue":true}`, decisionNone, ""},
+		{"PermissionRequest allow", PermissionRequest, `{"continue":true,"hookSpecificOutput":{"decision":{"behavior":"allow"}}}`, decisionAllow, ""},
+		{"Stop block", Stop, `{"continue":true,"decision":"block","reason":"tests fail"}`, decisionBlock, "tests fail"},
+		{"UserPromptSubmit block", UserPromptSubmit, `{"continue":true,"decision":"block","systemMessage":"secret"}`, decisionBlock, "secret"},
+		{"continue false", SessionStart, `{"continue":false,"stopReason":"broken"}`, decisionStop, "broken"},
+		{"invalid output", Stop, `not json`, decisionNone, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, reason := eventDecision(tt.eventType, []byte(tt.output))
+			if decision != tt.wantDecision || reason != tt.wantReason {
+				t.Errorf("eventDecision() = %q, %q, want %q, %q", decision, reason, tt.wantDecision, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestWriteDecisionRecord(t *testing.T) {
+	rawInput := json.RawMessage(`{"session_id":"s1","cwd":"/repo","tool_name":"Bash","tool_input":{"command":"rm -rf /"},"prompt":"ignored"}`)
+	output := []byte("{\n  \"continue\": true,\n  \"hookSpecificOutput\": {\"permissionDecision\": \"deny\", \"permissionDecisionReason\": \"no rm\"}\n}")
+	d := newDecision(PreToolUse, rawInput, output)
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("cloudevents file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "audit", "decisions.jsonl")
+		c := &DecisionLogConfig{Path: path}
+		for range 2 {
+			if err := writeDecisionRecord(c, d, now); err != nil {
+				t.Fatalf("writeDecisionRecord() error = %v", err)
+			}
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("Expected 2 records, got %q", data)
+		}
+		var first, second struct {
+			SpecVersion string         `json:"specversion"`
+			ID          string         `json:"id"`
+			Type        string         `json:"type"`
+			Subject     string         `json:"subject"`
+			Time        string         `json:"time"`
+			Data        map[string]any `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal([]byte(lines[1]), &second); err != nil {
+			t.Fatal(err)
+		}
+		if first.SpecVersion != "1.0" || first.Type != decisionEventType || first.Subject != "PreToolUse/Bash" || first.Time != "2026-01-02T03:04:05Z" {
+			t.Errorf("Unexpected CloudEvent: %s", lines[0])
+		}
+		if first.ID == "" || first.ID == second.ID {
+			t.Errorf("Expected unique ids, got %q and %q", first.ID, second.ID)
+		}
+		if first.Data["decision"] != "deny" || first.Data["reason"] != "no rm" || first.Data["session_id"] != "s1" ||
+			first.Data["tool_input"].(map[string]any)["command"] != "rm -rf /" {
+			t.Errorf("Unexpected data: %v", first.Data)
+		}
+		if _, ok := first.Data["prompt"]; ok {
+			t.Error("The prompt should not be exported")
+		}
+	})
+
+	t.Run("ocsf http", func(t *testing.T) {
+		var body []byte
+		var contentType, auth string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ = io.ReadAll(r.Body)
+			contentType, auth = r.Header.Get("Content-Type"), r.Header.Get("Authorization")
+		}))
+		defer server.Close()
+		t.Setenv("SIEM_TOKEN", "secret")
+
+		c := &DecisionLogConfig{Format: decisionLogOCSF, URL: server.URL, Headers: map[string]string{"Authorization": "Bearer $SIEM_TOKEN"}}
+		if err := writeDecisionRecord(c, d, now); err != nil {
+			t.Fatalf("writeDecisionRecord() error = %v", err)
+		}
+		if contentType != "application/json" || auth != "Bearer secret" {
+			t.Errorf("Content-Type = %q, Authorization = %q", contentType, auth)
+		}
+		var record map[string]any
+		if err := json.Unmarshal(body, &record); err != nil {
+			t.Fatalf("Invalid record %q: %v", body, err)
+		}
+		if record["class_uid"] != float64(6003) || record["type_uid"] != float64(600399) || record["action_id"] != float64(2) ||
+			record["time"] != float64(now.UnixMilli()) || record["message"] != "no rm" ||
+			record["api"].(map[string]any)["operation"] != "Bash" {
+			t.Errorf("Unexpected OCSF record: %s", body)
+		}
+	})
+
+	t.Run("http error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer server.Close()
+
+		err := writeDecisionRecord(&DecisionLogConfig{URL: server.URL + "/token"}, d, now)
+		if err == nil || !strings.Contains(err.Error(), "401") || strings.Contains(err.Error(), "token") {
+			t.Errorf("writeDecisionRecord() error = %v, want the status without the URL", err)
+		}
+	})
+}
+
+func TestWriteHookOutput_ExportsDecision(t *testing.T) {
+	t.Cleanup(func() {
+		activeDecisionLog = nil
+		prefetchedInput = nil
+		eventTraceID = ""
+	})
+	withStdin(t, `{"session_id":"s1","tool_name":"Write","tool_input":{"file_path":"main.go"}}`)
+	if _, err := prefetchInput(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "decisions.jsonl")
+	activeDecisionLog = &DecisionLogConfig{Path: path}
+	eventTraceID = "abcd123456789012"
+
+	var buf bytes.Buffer
+	if err := writeHookOutput(&buf, PostToolUse, []byte(`{"continue":true,"decision":"block","reason":"lint"}`)); err != nil {
+		t.Fatalf("writeHookOutput() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"decision":"block"`) || !strings.Contains(string(data), `"tool_name":"Write"`) ||
+		!strings.Contains(string(data), `"trace_id":"abcd123456789012"`) {
+		t.Errorf("Unexpected record: %s", data)
+	}
+	if strings.Contains(string(data), `"tool_input"`) {
+		t.Errorf("tool_input should only be exported with include_tool_input: %s", data)
+	}
+}
+
+func TestExportDecision_IncludeToolInput(t *testing.T) {
+	t.Cleanup(func() {
+		activeDecisionLog = nil
+		prefetchedInput = nil
+	})
+	// secret_scanで拒否した内容もシンクには送らない
+	withStdin(t, `{"session_id":"s1","tool_name":"Write","tool_input":{"file_path":"main.go","content":"key = `+testAWSAccessKeyID+`"}}`)
+	if _, err := prefetchInput(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "decisions.jsonl")
+	activeDecisionLog = &DecisionLogConfig{Path: path, IncludeToolInput: true}
+
+	exportDecision(PreToolUse, []byte(`{"continue":true}`))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"content":"key = AKIA****************"`) || strings.Contains(string(data), testAWSAccessKeyID) {
+		t.Errorf("Expected the redacted tool_input, got: %s", data)
+	}
+}
+
+func TestValidateDecisionLogConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *DecisionLogConfig
+		wantErr string
+	}{
+		{"nil", nil, ""},
+		{"file", &DecisionLogConfig{Path: "~/audit.jsonl"}, ""},
+		{"env url", &DecisionLogConfig{Format: decisionLogOCSF, URL: "$SIEM_URL", Timeout: "2s"}, ""},
+		{"no sink", &DecisionLogConfig{}, "path or url is required"},
+		{"format", &DecisionLogConfig{Path: "a", Format: "cef"}, `invalid format "cef"`},
+		{"url", &DecisionLogConfig{URL: "siem.example.com"}, "invalid url"},
+		{"timeout", &DecisionLogConfig{URL: "https://siem.example.com", Timeout: "-1s"}, "must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDecisionLogConfig(tt.config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateDecisionLogConfig() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateDecisionLogConfig() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// runnerDevcontainer is the `runner` value that runs command actions in the project's dev container.
+const runnerDevcontainer = "devcontainer"
+
+// devcontainerLocalFolderLabel is the label the Dev Containers CLI and VS Code put on a dev container,
+// holding the host path of the project it was started for.
+const devcontainerLocalFolderLabel = "devcontainer.local_folder"
+
+// findDevcontainerProject returns the nearest directory from dir upwards that defines a dev container:
+// .devcontainer/devcontainer.json, .devcontainer.json or .devcontainer/<name>/devcontainer.json.
+func findDevcontainerProject(dir string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	for {
+		if definesDevcontainer(dir) {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// definesDevcontainer reports whether dir contains a dev container configuration.
+func definesDevcontainer(dir string) bool {
+	for _, name := range []string{filepath.Join(".devcontainer", "devcontainer.json"), ".devcontainer.json"} {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil && !info.IsDir() {
+			return true
+		}
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, ".devcontainer", "*", "devcontainer.json"))
+	return len(matches) > 0
+}
+
+// devcontainerCommand returns the command line that runs cmd in the running dev container of the project at root,
+// in the directory corresponding to cwd. The container is looked up when the command runs, and the command
+// fails if it is not running. The workspace is where root is mounted (/workspaces/<name> by default).
+func devcontainerCommand(root, cwd, cmd string) string {
+	workdir := ""
+	if absCwd, err := filepath.Abs(cwd); err == nil {
+		if rel, err := filepath.Rel(root, absCwd); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			workdir = "/" + filepath.ToSlash(rel)
+		}
+	}
+	mountFormat := "{{range .Mounts}}{{if eq .Source " + strconv.Quote(root) + "}}{{.Destination}}{{end}}{{end}}"
+
+	lines := []string{
+		"id=$(docker ps -q --filter " + shellQuote("label="+devcontainerLocalFolderLabel+"="+root) + " | head -n 1)",
+		`if [ -z "$id" ]; then echo ` + shellQuote(fmt.Sprintf("cchook: no running dev container for %s", root)) + ` >&2; exit 1; fi`,
+		"ws=$(docker inspect -f " + shellQuote(mountFormat) + ` "$id")`,
+		`[ -n "$ws" ] || ws=` + shellQuote("/workspaces/"+filepath.Base(root)),
+		`exec docker exec -i -w "$ws"` + shellQuote(workdir) + ` "$id" sh -c ` + shellQuote(cmd),
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindDevcontainerProject(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{
+		"a/.devcontainer/devcontainer.json",
+		"b/.devcontainer.json",
+		"c/.devcontainer/go/devcontainer.json",
+	}
+	for _, name := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "a", "src", "pkg"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "d", ".devcontainer"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		dir      string
+		wantRoot string
+		wantOK   bool
+	}{
+		{filepath.Join(dir, "a"), filepath.Join(dir, "a"), true},
+		{filepath.Join(dir, "a", "src", "pkg"), filepath.Join(dir, "a"), true},
+		{filepath.Join(dir, "b"), filepath.Join(dir, "b"), true},
+		{filepath.Join(dir, "c"), filepath.Join(dir, "c"), true},
+		{filepath.Join(dir, "d"), "", false},
+	}
+	for _, tt := range tests {
+		root, ok := findDevcontainerProject(tt.dir)
+		if root != tt.wantRoot || ok != tt.wantOK {
+			t.Errorf("findDevcontainerProject(%q) = %q, %v, want %q, %v", tt.dir, root, ok, tt.wantRoot, tt.wantOK)
+		}
+	}
+}
+
+func TestDevcontainerCommand(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, ".devcontainer"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".devcontainer", "devcontainer.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cwd := filepath.Join(root, "src")
+
+	// docker を偽のスクリプトに置き換えて、生成したコマンドを実際のシェルで実行する
+	bin := t.TempDir()
+	fakeDocker := `#!/bin/sh
+case "$1" in
+ps) [ "$4" = "label=devcontainer.local_folder=` + root + `" ] && echo c0ffee ;;
+inspect) echo /workspaces/app ;;
+exec) shift; printf '%s\n' "$@" ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(bin, "docker"), []byte(fakeDocker), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cmd, err := remoteCommand(runnerDevcontainer, cwd, "gofmt -l 'a b.go'")
+	if err != nil {
+		t.Fatalf("remoteCommand() error = %v", err)
+	}
+	out, err := exec.Command("sh", "-c", cmd).CombinedOutput()
+	if err != nil {
+		t.Fatalf("Running %q failed: %v: %s", cmd, err, out)
+	}
+	want := "-i\n-w\n/workspaces/app/src\nc0ffee\nsh\n-c\ngofmt -l 'a b.go'\n"
+	if string(out) != want {
+		t.Errorf("docker exec arguments = %q, want %q", out, want)
+	}
+
+	// コンテナが起動していなければ失敗する
+	other := t.TempDir()
+	if err := os.WriteFile(filepath.Join(other, ".devcontainer.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd, err = remoteCommand(runnerDevcontainer, other, "true")
+	if err != nil {
+		t.Fatalf("remoteCommand() error = %v", err)
+	}
+	out, err = exec.Command("sh", "-c", cmd).CombinedOutput()
+	if err == nil || !strings.Contains(string(out), "no running dev container") {
+		t.Errorf("Expected a missing container to fail, got %v: %s", err, out)
+	}
+
+	// devcontainerの無いプロジェクトではローカルで実行する
+	if cmd, err := remoteCommand(runnerDevcontainer, t.TempDir(), "true"); err != nil || cmd != "true" {
+		t.Errorf("remoteCommand() without a devcontainer = %q, %v, want the command as-is", cmd, err)
+	}
+}
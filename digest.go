@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// digestPath returns the queue of notifications held back by digest actions (shared by all sessions,
+// since a meeting spans them). It is a variable so that tests can use a temporary file.
+var digestPath = func() string {
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "cchook", "digest.jsonl")
+	}
+	return filepath.Join(os.TempDir(), "cchook-digest.jsonl")
+}
+
+// digestItem is a notification queued by a digest action, one JSON line per notification.
+type digestItem struct {
+	Time             time.Time `json:"time"`
+	SessionID        string    `json:"session_id,omitempty"`
+	Cwd              string    `json:"cwd,omitempty"`
+	NotificationType string    `json:"notification_type,omitempty"`
+	Message          string    `json:"message"`
+}
+
+// queueDigest appends the notification to the digest queue.
+func queueDigest(input *NotificationInput) error {
+	data, err := json.Marshal(digestItem{
+		Time:             time.Now(),
+		SessionID:        input.SessionID,
+		Cwd:              input.Cwd,
+		NotificationType: input.NotificationType,
+		Message:          input.Message,
+	})
+	if err != nil {
+		return err
+	}
+	path := digestPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create digest directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open digest: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write digest: %w", err)
+	}
+	return nil
+}
+
+// readDigest returns the notifications queued in the file at path (none when it doesn't exist).
+func readDigest(path string) ([]digestItem, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read digest: %w", err)
+	}
+	var items []digestItem
+	for _, line := range strings.Split(string(data), "\n") {
+		var item digestItem
+		if json.Unmarshal([]byte(line), &item) == nil {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// digestPending evaluates digest_pending: whether notifications are queued.
+func digestPending() (bool, error) {
+	items, err := readDigest(digestPath())
+	return len(items) > 0, err
+}
+
+// flushDigest empties the digest queue and stores its notifications in the event JSON as digest,
+// so that the following actions can use {.digest.count} and {.digest.text} (one "- 15:04 message (project)" line each;
+// empty when nothing was queued). With keep the notifications are only read and stay queued.
+// Returns the number of notifications.
+func flushDigest(rawJSON any, keep bool) (int, error) {
+	path := digestPath()
+	var items []digestItem
+	if keep {
+		var err error
+		if items, err = readDigest(path); err != nil {
+			return 0, err
+		}
+	} else {
+		// 取り出してから読むので、同時に実行されたflushが同じ通知を二重に出さない
+		flushing := path + ".flushing-" + strconv.Itoa(os.Getpid())
+		if err := os.Rename(path, flushing); err == nil {
+			items, err = readDigest(flushing)
+			_ = os.Remove(flushing)
+			if err != nil {
+				return 0, err
+			}
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return 0, fmt.Errorf("failed to flush digest: %w", err)
+		}
+	}
+
+	lines := make([]string, len(items))
+	for i, item := range items {
+		lines[i] = "- " + item.Time.In(time.Local).Format("15:04") + " " + item.Message
+		if item.Cwd != "" {
+			lines[i] += " (" + filepath.Base(item.Cwd) + ")"
+		}
+	}
+	if m, ok := rawJSON.(map[string]any); ok {
+		m["digest"] = map[string]any{"count": len(items), "text": strings.Join(lines, "\n")}
+	}
+	return len(items), nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExecuteNotificationHooksJSON_Digest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "digest.jsonl")
+	origPath := digestPath
+	digestPath = func() string { return path }
+	t.Cleanup(func() { digestPath = origPath })
+
+	queue := &Config{Notification: []NotificationHook{
+		{Actions: []Action{{Type: "digest"}}},
+	}}
+	for _, message := range []string{"Claude needs your permission to use Bash", "Claude is waiting for your input"} {
+		input := &NotificationInput{BaseInput: BaseInput{SessionID: "s1", Cwd: "/home/user/webapp"}, Message: message}
+		output, err := executeNotificationHooksJSON(queue, input, map[string]any{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if output.HookSpecificOutput != nil && output.HookSpecificOutput.AdditionalContext != "" {
+			t.Errorf("queued notification produced output: %+v", output.HookSpecificOutput)
+		}
+	}
+	if pending, err := digestPending(); err != nil || !pending {
+		t.Fatalf("digest_pending = %v, %v, want true", pending, err)
+	}
+
+	flush := &Config{Notification: []NotificationHook{
+		{
+			Conditions: []Condition{{Type: ConditionDigestPending}},
+			Actions: []Action{
+				{Type: "digest", Flush: true},
+				{Type: "output", Message: "{.digest.count} while you were away:\n{.digest.text}"},
+			},
+		},
+	}}
+	output, err := executeNotificationHooksJSON(flush, &NotificationInput{Message: "done"}, map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := output.HookSpecificOutput.AdditionalContext
+	if !strings.HasPrefix(got, "2 while you were away:\n- ") || !strings.Contains(got, " Claude is waiting for your input (webapp)") {
+		t.Errorf("additionalContext = %q", got)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("digest queue still exists: %v", err)
+	}
+	if pending, err := digestPending(); err != nil || pending {
+		t.Errorf("digest_pending after flush = %v, %v, want false", pending, err)
+	}
+
+	// 溜まっていなくても.digestは設定される
+	rawJSON := map[string]any{}
+	if n, err := flushDigest(rawJSON, false); err != nil || n != 0 {
+		t.Fatalf("empty flush = %d, %v", n, err)
+	}
+	if digest, _ := rawJSON["digest"].(map[string]any); digest["count"] != 0 || digest["text"] != "" {
+		t.Errorf("digest = %v", rawJSON["digest"])
+	}
+}
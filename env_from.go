@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Values of `env_from`.
+const (
+	envFromDirenv     = "direnv"
+	envFromNixDevelop = "nix develop"
+)
+
+// envFromSources maps each `env_from` value to the files whose changes invalidate the cached environment.
+var envFromSources = map[string][]string{
+	envFromDirenv:     {".envrc", ".env"},
+	envFromNixDevelop: {"flake.nix", "flake.lock", "shell.nix", "default.nix"},
+}
+
+// envFromCacheDir returns the directory caching captured environments.
+// It is a variable so that tests can use a temporary directory.
+var envFromCacheDir = func() string {
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "cchook", "env")
+	}
+	return filepath.Join(os.TempDir(), "cchook-env")
+}
+
+// envVarNamePattern matches names that can be exported by a POSIX shell.
+var envVarNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// envFromIgnoredVars are shell bookkeeping variables that differ in every shell and are not exported.
+var envFromIgnoredVars = []string{"_", "PWD", "OLDPWD", "SHLVL"}
+
+// validateEnvFrom checks an `env_from` value.
+func validateEnvFrom(envFrom string) error {
+	if _, ok := envFromSources[envFrom]; !ok {
+		return fmt.Errorf("invalid env_from %q (must be %q or %q)", envFrom, envFromDirenv, envFromNixDevelop)
+	}
+	return nil
+}
+
+// envFromCache is the cached environment of an `env_from` source in a directory.
+type envFromCache struct {
+	Fingerprint string             `json:"fingerprint"`
+	Env         map[string]*string `json:"env"` // nilの値はunsetする変数
+}
+
+// envFromPrefix returns shell lines that apply the environment of envFrom in dir, to be put before a command.
+// The environment is captured once and cached per directory until one of the source's files changes.
+func envFromPrefix(envFrom, dir string) (string, error) {
+	env, err := loadEnvFrom(envFrom, dir)
+	if err != nil {
+		return "", err
+	}
+	names := make([]string, 0, len(env))
+	for name := range env {
+		if envVarNamePattern.MatchString(name) && !slices.Contains(envFromIgnoredVars, name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		if value := env[name]; value != nil {
+			fmt.Fprintf(&b, "export %s=%s\n", name, shellQuote(*value))
+		} else {
+			fmt.Fprintf(&b, "unset %s\n", name)
+		}
+	}
+	return b.String(), nil
+}
+
+// loadEnvFrom returns the environment changes of envFrom in dir, from the cache if it is up to date.
+func loadEnvFrom(envFrom, dir string) (map[string]*string, error) {
+	if err := validateEnvFrom(envFrom); err != nil {
+		return nil, err
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256([]byte(envFrom + "\x00" + dir))
+	cachePath := filepath.Join(envFromCacheDir(), hex.EncodeToString(sum[:])+".json")
+	fingerprint := envFromFingerprint(envFromSources[envFrom], dir)
+
+	var cache envFromCache
+	if data, err := os.ReadFile(cachePath); err == nil && json.Unmarshal(data, &cache) == nil && cache.Fingerprint == fingerprint {
+		return cache.Env, nil
+	}
+
+	var env map[string]*string
+	switch envFrom {
+	case envFromDirenv:
+		env, err = captureDirenvEnv(dir)
+	case envFromNixDevelop:
+		env, err = captureNixDevelopEnv(dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("env_from %s: %w", envFrom, err)
+	}
+
+	// キャッシュの書き込みに失敗しても環境は使える
+	if data, err := json.Marshal(envFromCache{Fingerprint: fingerprint, Env: env}); err == nil {
+		if err := os.MkdirAll(filepath.Dir(cachePath), 0o700); err == nil {
+			_ = os.WriteFile(cachePath, data, 0o600)
+		}
+	}
+	return env, nil
+}
+
+// envFromFingerprint describes the files named names in dir and its parents (path, modification time and size).
+func envFromFingerprint(names []string, dir string) string {
+	var b strings.Builder
+	for {
+		for _, name := range names {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil {
+				fmt.Fprintf(&b, "%s:%d:%d\n", path, info.ModTime().UnixNano(), info.Size())
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return b.String()
+		}
+		dir = parent
+	}
+}
+
+// captureDirenvEnv returns the environment changes `direnv export json` makes in dir.
+// The .envrc must have been allowed with `direnv allow`.
+func captureDirenvEnv(dir string) (map[string]*string, error) {
+	out, err := runEnvCapture(dir, "direnv", "export", "json")
+	if err != nil {
+		return nil, err
+	}
+	env := map[string]*string{}
+	if len(bytes.TrimSpace(out)) == 0 {
+		// 読み込む.envrcが無い
+		return env, nil
+	}
+	if err := json.Unmarshal(out, &env); err != nil {
+		return nil, fmt.Errorf("failed to parse direnv output: %w", err)
+	}
+	return env, nil
+}
+
+// captureNixDevelopEnv returns the variables the development shell of `nix develop` in dir sets or changes.
+func captureNixDevelopEnv(dir string) (map[string]*string, error) {
+	out, err := runEnvCapture(dir, "nix", "develop", "--command", "env", "-0")
+	if err != nil {
+		return nil, err
+	}
+	env := map[string]*string{}
+	for _, entry := range strings.Split(string(out), "\x00") {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		if current, set := os.LookupEnv(name); !set || current != value {
+			env[name] = &value
+		}
+	}
+	return env, nil
+}
+
+// runEnvCapture runs a command capturing an environment in dir and returns its stdout.
+func runEnvCapture(dir, name string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w: %s", strings.Join(cmd.Args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
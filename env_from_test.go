@@ -0,0 +1,142 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withFakeEnvTools puts fake direnv and nix commands on PATH that log their invocations to a file,
+// and caches environments in a temporary directory.
+func withFakeEnvTools(t *testing.T) (calls func() string) {
+	t.Helper()
+	bin := t.TempDir()
+	log := filepath.Join(bin, "calls.log")
+	scripts := map[string]string{
+		"direnv": `#!/bin/sh
+echo "direnv $*" >> ` + log + `
+[ -f .envrc ] || exit 0
+printf '{"PATH":"/nix/store/go/bin:/usr/bin","GOFLAGS":"-mod=mod","it_s":"it'"'"'s","OLD_VAR":null,"BAD-NAME":"x"}'
+`,
+		"nix": `#!/bin/sh
+echo "nix $*" >> ` + log + `
+printf 'HOME=%s\0SHLVL=9\0GOTOOLCHAIN=local\0MULTI=a\nb\0' "$HOME"
+`,
+	}
+	for name, script := range scripts {
+		if err := os.WriteFile(filepath.Join(bin, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cacheDir := t.TempDir()
+	original := envFromCacheDir
+	envFromCacheDir = func() string { return cacheDir }
+	t.Cleanup(func() { envFromCacheDir = original })
+
+	return func() string {
+		data, _ := os.ReadFile(log)
+		return string(data)
+	}
+}
+
+func TestEnvFromPrefix_Direnv(t *testing.T) {
+	calls := withFakeEnvTools(t)
+	dir := t.TempDir()
+	envrc := filepath.Join(dir, ".envrc")
+	if err := os.WriteFile(envrc, []byte("use flake"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	prefix, err := envFromPrefix(envFromDirenv, dir)
+	if err != nil {
+		t.Fatalf("envFromPrefix() error = %v", err)
+	}
+	want := "export GOFLAGS='-mod=mod'\nunset OLD_VAR\nexport PATH='/nix/store/go/bin:/usr/bin'\nexport it_s='it'\\''s'\n"
+	if prefix != want {
+		t.Errorf("envFromPrefix() = %q, want %q", prefix, want)
+	}
+
+	// 2回目はキャッシュを使う
+	if _, err := envFromPrefix(envFromDirenv, dir); err != nil {
+		t.Fatalf("envFromPrefix() error = %v", err)
+	}
+	if got := strings.Count(calls(), "direnv export json"); got != 1 {
+		t.Errorf("direnv ran %d times, want 1 (cached)", got)
+	}
+
+	// .envrcが変わったら取り直す
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(envrc, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := envFromPrefix(envFromDirenv, dir); err != nil {
+		t.Fatalf("envFromPrefix() error = %v", err)
+	}
+	if got := strings.Count(calls(), "direnv export json"); got != 2 {
+		t.Errorf("direnv ran %d times, want 2 after .envrc changed", got)
+	}
+
+	// .envrcが無ければ何もしない
+	if prefix, err := envFromPrefix(envFromDirenv, t.TempDir()); err != nil || prefix != "" {
+		t.Errorf("envFromPrefix() without .envrc = %q, %v, want empty", prefix, err)
+	}
+}
+
+func TestEnvFromPrefix_NixDevelop(t *testing.T) {
+	calls := withFakeEnvTools(t)
+
+	prefix, err := envFromPrefix(envFromNixDevelop, t.TempDir())
+	if err != nil {
+		t.Fatalf("envFromPrefix() error = %v", err)
+	}
+	// 変化していないHOMEとシェルの変数は含めない
+	want := "export GOTOOLCHAIN='local'\nexport MULTI='a\nb'\n"
+	if prefix != want {
+		t.Errorf("envFromPrefix() = %q, want %q", prefix, want)
+	}
+	if !strings.Contains(calls(), "nix develop --command env -0") {
+		t.Errorf("nix calls = %q", calls())
+	}
+
+	if _, err := envFromPrefix("asdf", t.TempDir()); err == nil {
+		t.Error("Expected an error for an unknown env_from")
+	}
+}
+
+func TestActionExecutor_EnvFrom(t *testing.T) {
+	withFakeEnvTools(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".envrc"), []byte("use flake"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rawJSON := map[string]any{"cwd": dir}
+
+	runner := &recordingRunner{}
+	executor := NewActionExecutor(runner).withEnvFrom(envFromNixDevelop)
+
+	// アクションのenv_fromがフックのものより優先される
+	if _, err := executor.ExecuteStopAction(Action{Type: "command", Command: "go test ./...", EnvFrom: envFromDirenv}, &StopInput{}, rawJSON); err != nil {
+		t.Fatalf("ExecuteStopAction() error = %v", err)
+	}
+	if !strings.HasPrefix(runner.last, "export GOFLAGS='-mod=mod'\n") || !strings.HasSuffix(runner.last, "\ngo test ./...") {
+		t.Errorf("Ran %q, want the direnv environment before the command", runner.last)
+	}
+
+	if _, err := executor.ExecuteStopAction(Action{Type: "command", Command: "go test ./..."}, &StopInput{}, rawJSON); err != nil {
+		t.Fatalf("ExecuteStopAction() error = %v", err)
+	}
+	if !strings.HasPrefix(runner.last, "export GOTOOLCHAIN='local'\n") {
+		t.Errorf("Ran %q, want the hook's nix develop environment", runner.last)
+	}
+
+	if _, err := NewActionExecutor(runner).ExecuteStopAction(Action{Type: "command", Command: "go test ./..."}, &StopInput{}, rawJSON); err != nil {
+		t.Fatalf("ExecuteStopAction() error = %v", err)
+	}
+	if runner.last != "go test ./..." {
+		t.Errorf("Ran %q without env_from, want the command as-is", runner.last)
+	}
+}
@@ -6,6 +6,13 @@ import "errors"
 // in a command. This syntax cannot be expanded by shell.Fields due to mvdan.cc/sh/v3 limitations.
 var ErrProcessSubstitutionDetected = errors.New("process substitution (<() or >()) detected: please rewrite command without process substitution")
 
+// ErrBraceExpansionTooLarge is returned when brace expansion in a command (e.g. {1..100000000})
+// would produce too many fields to tokenize safely.
+var ErrBraceExpansionTooLarge = errors.New("brace expansion produces too many fields: please rewrite command without large brace expansion")
+
+// ErrInputTooLarge is returned when the event JSON exceeds the maximum input size (-max-input-size).
+var ErrInputTooLarge = errors.New("input too large")
+
 // ExitError は特定の終了ステータスでプログラムを終了したいことを示すエラー型
 type ExitError struct {
 	Code    int
@@ -26,3 +33,10 @@ func NewExitError(code int, message string, stderr bool) *ExitError {
 		Stderr:  stderr,
 	}
 }
+
+// ErrCommandTimedOut is returned by TimeoutCommandRunner when a command is killed after its timeout.
+var ErrCommandTimedOut = errors.New("timed out")
+
+// ErrGitUnavailable is returned by git-backed conditions when git can't be used (the binary is not installed
+// or the directory is not in a repository) and on_git_unavailable is deny.
+var ErrGitUnavailable = errors.New("git is not available")
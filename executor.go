@@ -5,38 +5,213 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 )
 
 // ActionExecutor executes actions with a specified CommandRunner.
 // This struct-based approach makes dependencies explicit and enables
 // safe dependency injection in tests without global state.
 type ActionExecutor struct {
-	runner CommandRunner
+	runner        CommandRunner
+	httpClient    HTTPClient
+	mutex         string // 空でなければ、アクションの実行中はこの名前のmutexを保持する
+	envFrom       string // env_fromを指定していないコマンドアクションが読み込む環境（フックのenv_from）
+	snapshot      bool   // コマンドアクションをfile_pathのスナップショットに対して実行する（PostToolUseのsnapshot）
+	noSideEffects bool   // 状態を書き込まない（explainとchaosの実行。diagnosticRunを参照）
 }
 
 // NewActionExecutor creates a new ActionExecutor with the given CommandRunner.
-// If runner is nil, DefaultCommandRunner is used.
+// If runner is nil, DefaultCommandRunner is used. http actions use DefaultHTTPClient.
+// During a diagnostic run (see diagnosticRun) the executor doesn't write state.
 func NewActionExecutor(runner CommandRunner) *ActionExecutor {
 	if runner == nil {
 		runner = DefaultCommandRunner
 	}
-	return &ActionExecutor{runner: runner}
+	return &ActionExecutor{runner: runner, httpClient: DefaultHTTPClient, noSideEffects: diagnosticRun}
+}
+
+// withMutex returns an executor that holds the named hook mutex while running each action.
+// An empty name returns e itself.
+func (e *ActionExecutor) withMutex(name string) *ActionExecutor {
+	if name == "" {
+		return e
+	}
+	locked := *e
+	locked.mutex = name
+	return &locked
+}
+
+// withEnvFrom returns an executor whose command actions load the environment of envFrom unless they set their own env_from.
+// An empty envFrom returns e itself.
+func (e *ActionExecutor) withEnvFrom(envFrom string) *ActionExecutor {
+	if envFrom == "" {
+		return e
+	}
+	c := *e
+	c.envFrom = envFrom
+	return &c
+}
+
+// withSnapshot returns an executor whose command actions work on a snapshot of tool_input.file_path
+// (see fileSnapshot). false returns e itself.
+func (e *ActionExecutor) withSnapshot(snapshot bool) *ActionExecutor {
+	if !snapshot {
+		return e
+	}
+	c := *e
+	c.snapshot = true
+	return &c
+}
+
+// runAction runs a command, http or opa action and returns its stdout, stderr and exit code.
+func (e *ActionExecutor) runAction(action Action, rawJSON any) (stdout, stderr string, exitCode int, err error) {
+	start := time.Now()
+	defer func() {
+		logAction(action, rawJSON, exitCode, time.Since(start), err)
+		// アクションがファイルやリポジトリを変更した可能性がある
+		invalidateWorkspaceCache()
+	}()
+
+	if e.mutex != "" {
+		unlock, err := lockHookMutex(e.mutex)
+		if err != nil {
+			return "", "", 1, err
+		}
+		defer unlock()
+	}
+
+	switch action.Type {
+	case "http":
+		return runHTTPAction(e.httpClient, action, rawJSON)
+	case "opa":
+		return runOPAAction(action, rawJSON)
+	}
+	if e.snapshot && !e.noSideEffects && action.Type == "command" {
+		if m, ok := rawJSON.(map[string]any); ok {
+			if filePath := snapshotFilePath(m); filePath != "" {
+				snapshot, err := takeFileSnapshot(filePath)
+				if err != nil {
+					return "", "", 1, err
+				}
+				if snapshot != nil {
+					return e.runSnapshotAction(action, snapshot, m)
+				}
+			}
+		}
+	}
+	return e.runCommandAction(action, rawJSON)
+}
+
+// runSnapshotAction runs a command action on the snapshot and applies the result
+// unless the file changed while the command was running.
+func (e *ActionExecutor) runSnapshotAction(action Action, snapshot *fileSnapshot, rawJSON map[string]any) (stdout, stderr string, exitCode int, err error) {
+	stdout, stderr, exitCode, err = e.runCommandAction(action, snapshot.withCopy(rawJSON))
+	if exitCode != 0 || err != nil {
+		snapshot.discard()
+		return stdout, stderr, exitCode, err
+	}
+	applied, err := snapshot.apply()
+	if err != nil {
+		return stdout, stderr, 1, err
+	}
+	if !applied {
+		fmt.Fprintf(os.Stderr, "Warning: %s changed while the hook was running; discarded the result of %q\n", snapshot.path, configuredCommand(action))
+	}
+	return stdout, stderr, 0, nil
+}
+
+// runCommandAction runs a command action with its runner and env_from.
+// It also runs the command cchook built for a typecheck, format_file or lint_feedback action,
+// and the test command of a run_tests action with its timeout.
+func (e *ActionExecutor) runCommandAction(action Action, rawJSON any) (stdout, stderr string, exitCode int, err error) {
+	cwd := rawJSONCwd(rawJSON)
+	envFrom := action.EnvFrom
+	if envFrom == "" {
+		envFrom = e.envFrom
+	}
+	useStdin, data := commandStdin(action, rawJSON)
+	// argsはローカルではシェルを介さずに実行する
+	if len(action.Args) > 0 && action.Runner == "" && envFrom == "" {
+		if runner, ok := e.runner.(ArgvCommandRunner); ok {
+			env := append(hookInputEnv(rawJSON), actionEnv(action, rawJSON)...)
+			return runner.RunArgsWithOutput(actionArgs(action, rawJSON), env, useStdin, data)
+		}
+	}
+	cmd, err := remoteCommand(action.Runner, cwd, actionCommand(action, rawJSON))
+	if err != nil {
+		return "", "", 1, err
+	}
+	if envFrom != "" {
+		// 環境はローカルのシェルで設定する（runnerのコマンドを起動するsshやdockerにも適用される）
+		prefix, err := envFromPrefix(envFrom, cwd)
+		if err != nil {
+			return "", "", 1, err
+		}
+		cmd = prefix + cmd
+	}
+	if action.Type == "run_tests" {
+		if runner, ok := e.runner.(TimeoutCommandRunner); ok {
+			timeout, err := runTestsTimeout(action)
+			if err != nil {
+				return "", "", 1, err
+			}
+			return runner.RunCommandWithTimeout(cmd, timeout, useStdin, data)
+		}
+	}
+	return e.runner.RunCommandWithOutput(cmd, useStdin, data)
+}
+
+// actionCommand returns the command line of a command action after template expansion,
+// with the values shell-escaped (see shellTemplateReplace), or the command line running its args.
+// The commands of typecheck, breaking_change, format_file, lint_feedback and git_checkpoint actions are built by cchook
+// from quoted values and are not expanded.
+func actionCommand(action Action, rawJSON any) string {
+	if action.Type == "typecheck" || action.Type == "breaking_change" || action.Type == "format_file" || action.Type == "lint_feedback" || action.Type == "git_checkpoint" {
+		return action.Command
+	}
+	if len(action.Args) > 0 {
+		return argvCommandLine(actionArgs(action, rawJSON), actionEnv(action, rawJSON))
+	}
+	return shellTemplateReplace(action.Command, rawJSON)
+}
+
+// rawJSONCwd returns the cwd field of the decoded event JSON (empty if missing).
+func rawJSONCwd(rawJSON any) string {
+	if m, ok := rawJSON.(map[string]any); ok {
+		if cwd, ok := m["cwd"].(string); ok {
+			return cwd
+		}
+	}
+	return ""
+}
+
+// appendModelHint appends the templated action.ModelHint to additionalContext.
+// Callers only use it when the action denies/blocks, so that the hint is shown to Claude
+// as a corrective instruction separate from the human-facing reason.
+func appendModelHint(additionalContext string, action Action, rawJSON any) string {
+	if action.ModelHint == nil {
+		return additionalContext
+	}
+	hint := strings.TrimSpace(unifiedTemplateReplace(*action.ModelHint, rawJSON))
+	if hint == "" {
+		return additionalContext
+	}
+	if additionalContext == "" {
+		return hint
+	}
+	return additionalContext + "\n" + hint
 }
 
 // ExecuteNotificationAction executes an action for the Notification event and returns JSON output.
 // Similar to SessionStart, Notification uses hookSpecificOutput with additionalContext.
 func (e *ActionExecutor) ExecuteNotificationAction(action Action, input *NotificationInput, rawJSON any) (*ActionOutput, error) {
 	switch action.Type {
-	case "command":
-		cmd := unifiedTemplateReplace(action.Command, rawJSON)
-		stdout, stderr, exitCode, err := e.runner.RunCommandWithOutput(cmd, action.UseStdin, rawJSON)
+	case "command", "http", "opa":
+		stdout, stderr, exitCode, err := e.runAction(action, rawJSON)
 
 		// Command failed with non-zero exit code
 		if exitCode != 0 {
-			errMsg := fmt.Sprintf("Command failed with exit code %d: %s", exitCode, stderr)
-			if strings.TrimSpace(stderr) == "" && err != nil {
-				errMsg = fmt.Sprintf("Command failed with exit code %d: %v", exitCode, err)
-			}
+			errMsg := actionFailureMessage(action, exitCode, stderr, err)
 			fmt.Fprintf(os.Stderr, "Warning: %s\n", errMsg)
 			return &ActionOutput{
 				Continue:      false,
@@ -46,10 +221,10 @@ func (e *ActionExecutor) ExecuteNotificationAction(action Action, input *Notific
 
 		// Empty stdout - Allow for validation-type CLI tools
 		if strings.TrimSpace(stdout) == "" {
-			return &ActionOutput{
+			return withCommandStderr(&ActionOutput{
 				Continue:      true,
 				HookEventName: "Notification",
-			}, nil
+			}, action, stderr), nil
 		}
 
 		// Parse JSON output
@@ -112,7 +287,7 @@ func (e *ActionExecutor) ExecuteNotificationAction(action Action, input *Notific
 		// Set AdditionalContext
 		result.AdditionalContext = cmdOutput.HookSpecificOutput.AdditionalContext
 
-		return result, err
+		return withCommandStderr(result, action, stderr), err
 
 	case "output":
 		processedMessage := unifiedTemplateReplace(action.Message, rawJSON)
@@ -138,6 +313,19 @@ func (e *ActionExecutor) ExecuteNotificationAction(action Action, input *Notific
 			HookEventName:     "Notification",
 			AdditionalContext: processedMessage,
 		}, nil
+
+	case "digest":
+		// 通知を溜める (flush: trueなら溜めた通知を.digestに取り出す)
+		var err error
+		if action.Flush {
+			_, err = flushDigest(rawJSON, e.noSideEffects)
+		} else if !e.noSideEffects {
+			err = queueDigest(input)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: digest: %v\n", err)
+		}
+		return nil, nil
 	}
 
 	return nil, nil
@@ -147,16 +335,12 @@ func (e *ActionExecutor) ExecuteNotificationAction(action Action, input *Notific
 // Similar to Notification, SubagentStart uses hookSpecificOutput with additionalContext.
 func (e *ActionExecutor) ExecuteSubagentStartAction(action Action, input *SubagentStartInput, rawJSON any) (*ActionOutput, error) {
 	switch action.Type {
-	case "command":
-		cmd := unifiedTemplateReplace(action.Command, rawJSON)
-		stdout, stderr, exitCode, err := e.runner.RunCommandWithOutput(cmd, action.UseStdin, rawJSON)
+	case "command", "http", "opa":
+		stdout, stderr, exitCode, err := e.runAction(action, rawJSON)
 
 		// Command failed with non-zero exit code
 		if exitCode != 0 {
-			errMsg := fmt.Sprintf("Command failed with exit code %d: %s", exitCode, stderr)
-			if strings.TrimSpace(stderr) == "" && err != nil {
-				errMsg = fmt.Sprintf("Command failed with exit code %d: %v", exitCode, err)
-			}
+			errMsg := actionFailureMessage(action, exitCode, stderr, err)
 			fmt.Fprintf(os.Stderr, "Warning: %s\n", errMsg)
 			return &ActionOutput{
 				Continue:      false,
@@ -166,10 +350,10 @@ func (e *ActionExecutor) ExecuteSubagentStartAction(action Action, input *Subage
 
 		// Empty stdout - Allow for validation-type CLI tools
 		if strings.TrimSpace(stdout) == "" {
-			return &ActionOutput{
+			return withCommandStderr(&ActionOutput{
 				Continue:      true,
 				HookEventName: "SubagentStart",
-			}, nil
+			}, action, stderr), nil
 		}
 
 		// Parse JSON output
@@ -232,7 +416,7 @@ func (e *ActionExecutor) ExecuteSubagentStartAction(action Action, input *Subage
 		// Set AdditionalContext
 		result.AdditionalContext = cmdOutput.HookSpecificOutput.AdditionalContext
 
-		return result, err
+		return withCommandStderr(result, action, stderr), err
 
 	case "output":
 		processedMessage := unifiedTemplateReplace(action.Message, rawJSON)
@@ -268,16 +452,12 @@ func (e *ActionExecutor) ExecuteSubagentStartAction(action Action, input *Subage
 // Stop hooks use top-level decision pattern (no hookSpecificOutput).
 func (e *ActionExecutor) ExecuteStopAction(action Action, input *StopInput, rawJSON any) (*ActionOutput, error) {
 	switch action.Type {
-	case "command":
-		cmd := unifiedTemplateReplace(action.Command, rawJSON)
-		stdout, stderr, exitCode, err := e.runner.RunCommandWithOutput(cmd, action.UseStdin, rawJSON)
+	case "command", "http", "opa":
+		stdout, stderr, exitCode, err := e.runAction(action, rawJSON)
 
 		// Command failed with non-zero exit code
 		if exitCode != 0 {
-			errMsg := fmt.Sprintf("Command failed with exit code %d: %s", exitCode, stderr)
-			if strings.TrimSpace(stderr) == "" && err != nil {
-				errMsg = fmt.Sprintf("Command failed with exit code %d: %v", exitCode, err)
-			}
+			errMsg := actionFailureMessage(action, exitCode, stderr, err)
 			fmt.Fprintf(os.Stderr, "Warning: %s\n", errMsg)
 			return &ActionOutput{
 				Continue:      true,
@@ -289,10 +469,10 @@ func (e *ActionExecutor) ExecuteStopAction(action Action, input *StopInput, rawJ
 
 		// Empty stdout - Allow stop (validation-type CLI tools: silence = OK)
 		if strings.TrimSpace(stdout) == "" {
-			return &ActionOutput{
+			return withCommandStderr(&ActionOutput{
 				Continue: true,
 				Decision: "", // Allow stop
-			}, nil
+			}, action, stderr), nil
 		}
 
 		// Parse JSON output (StopOutput has no hookSpecificOutput)
@@ -337,20 +517,25 @@ func (e *ActionExecutor) ExecuteStopAction(action Action, input *StopInput, rawJ
 		checkUnsupportedFieldsStop(stdout)
 
 		// Build ActionOutput from parsed JSON
-		return &ActionOutput{
+		return withCommandStderr(&ActionOutput{
 			Continue:       true,
 			Decision:       decision,
 			Reason:         cmdOutput.Reason,
 			StopReason:     cmdOutput.StopReason,
 			SuppressOutput: cmdOutput.SuppressOutput,
 			SystemMessage:  cmdOutput.SystemMessage,
-		}, nil
+		}, action, stderr), nil
 
 	case "output":
 		processedMessage := unifiedTemplateReplace(action.Message, rawJSON)
+		processedReason := ""
+		if action.Reason != nil {
+			processedReason = unifiedTemplateReplace(*action.Reason, rawJSON)
+		}
 
 		// Empty message check → fail-safe (decision: block)
-		if strings.TrimSpace(processedMessage) == "" {
+		// reasonだけが指定されている場合はClaude向けの理由のみを返す
+		if strings.TrimSpace(processedMessage) == "" && strings.TrimSpace(processedReason) == "" {
 			errMsg := "Empty message in Stop action"
 			fmt.Fprintf(os.Stderr, "Warning: %s\n", errMsg)
 			return &ActionOutput{
@@ -376,42 +561,15 @@ func (e *ActionExecutor) ExecuteStopAction(action Action, input *StopInput, rawJ
 				return &ActionOutput{
 					Continue:      true,
 					Decision:      "block",
-					Reason:        processedMessage,
+					Reason:        decisionReason(processedReason, processedMessage, "block"),
 					SystemMessage: errMsg,
 				}, nil
 			}
 			decision = *action.Decision
 		}
 
-		// Determine reason with template expansion
-		reason := processedMessage
-		if action.Reason != nil {
-			// Apply template expansion to reason
-			reasonValue := unifiedTemplateReplace(*action.Reason, rawJSON)
-			// Empty/whitespace reason with "block" decision should fallback to processedMessage
-			if strings.TrimSpace(reasonValue) == "" && decision == "block" {
-				reason = processedMessage
-			} else {
-				reason = reasonValue
-			}
-		}
-
-		// For allow (decision=""), clear reason (not applicable)
-		if decision == "" {
-			reason = ""
-		}
-
-		// Final validation: decision "block" requires non-empty reason
-		if decision == "block" && strings.TrimSpace(reason) == "" {
-			errMsg := "Empty reason when decision is 'block' (reason is required for block)"
-			fmt.Fprintf(os.Stderr, "Warning: %s\n", errMsg)
-			return &ActionOutput{
-				Continue:      true,
-				Decision:      "block",
-				Reason:        errMsg,
-				SystemMessage: errMsg,
-			}, nil
-		}
+		// reason (Claude向け) はmessage (ユーザー向け) とは独立にテンプレート展開される
+		reason := decisionReason(processedReason, processedMessage, decision)
 
 		return &ActionOutput{
 			Continue:      true,
@@ -419,25 +577,67 @@ func (e *ActionExecutor) ExecuteStopAction(action Action, input *StopInput, rawJ
 			Reason:        reason,
 			SystemMessage: processedMessage,
 		}, nil
+
+	case "hook_changes_report":
+		// 変更が無ければ何も出力しない（停止も妨げない）
+		report, err := hookChangesReport(input.SessionID, !e.noSideEffects)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			return nil, nil
+		}
+		if report == "" {
+			return nil, nil
+		}
+		return &ActionOutput{
+			Continue:      true,
+			SystemMessage: report,
+		}, nil
+
+	case "run_tests":
+		return e.runTests(action, input, rawJSON), nil
+
+	case "git_checkpoint":
+		e.gitCheckpoint(action, input.Cwd, rawJSON)
+		return nil, nil
+
+	case "digest":
+		// Stopでは溜めた通知を.digestに取り出すだけ (flush: true)
+		if !action.Flush {
+			fmt.Fprintf(os.Stderr, "Warning: digest: Stop hooks can only flush the digest (set flush: true)\n")
+			return nil, nil
+		}
+		if _, err := flushDigest(rawJSON, e.noSideEffects); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: digest: %v\n", err)
+		}
+		return nil, nil
 	}
 
 	return nil, nil
 }
 
+// decisionReason returns the reason of an output action for decision events (Stop/SubagentStop/PostToolUse).
+// The templated reason (for Claude) takes precedence over message (for the user) and falls back to it when empty.
+// A reason only applies to "block" and is empty otherwise.
+func decisionReason(reason, message, decision string) string {
+	if decision != "block" {
+		return ""
+	}
+	if strings.TrimSpace(reason) != "" {
+		return reason
+	}
+	return message
+}
+
 // ExecuteSubagentStopAction executes an action for the SubagentStop event.
 // Command failures result in exit status 2 to block the subagent stop operation.
 func (e *ActionExecutor) ExecuteSubagentStopAction(action Action, input *SubagentStopInput, rawJSON any) (*ActionOutput, error) {
 	switch action.Type {
-	case "command":
-		cmd := unifiedTemplateReplace(action.Command, rawJSON)
-		stdout, stderr, exitCode, err := e.runner.RunCommandWithOutput(cmd, action.UseStdin, rawJSON)
+	case "command", "http", "opa":
+		stdout, stderr, exitCode, err := e.runAction(action, rawJSON)
 
 		// Command failed with non-zero exit code
 		if exitCode != 0 {
-			errMsg := fmt.Sprintf("Command failed with exit code %d: %s", exitCode, stderr)
-			if strings.TrimSpace(stderr) == "" && err != nil {
-				errMsg = fmt.Sprintf("Command failed with exit code %d: %v", exitCode, err)
-			}
+			errMsg := actionFailureMessage(action, exitCode, stderr, err)
 			fmt.Fprintf(os.Stderr, "Warning: %s\n", errMsg)
 			return &ActionOutput{
 				Continue:      true,
@@ -449,10 +649,10 @@ func (e *ActionExecutor) ExecuteSubagentStopAction(action Action, input *Subagen
 
 		// Empty stdout - Allow subagent stop (validation-type CLI tools: silence = OK)
 		if strings.TrimSpace(stdout) == "" {
-			return &ActionOutput{
+			return withCommandStderr(&ActionOutput{
 				Continue: true,
 				Decision: "", // Allow subagent stop
-			}, nil
+			}, action, stderr), nil
 		}
 
 		// Parse JSON output (SubagentStopOutput has no hookSpecificOutput, same schema as Stop)
@@ -497,20 +697,25 @@ func (e *ActionExecutor) ExecuteSubagentStopAction(action Action, input *Subagen
 		checkUnsupportedFieldsSubagentStop(stdout)
 
 		// Build ActionOutput from parsed JSON
-		return &ActionOutput{
+		return withCommandStderr(&ActionOutput{
 			Continue:       true,
 			Decision:       decision,
 			Reason:         cmdOutput.Reason,
 			StopReason:     cmdOutput.StopReason,
 			SuppressOutput: cmdOutput.SuppressOutput,
 			SystemMessage:  cmdOutput.SystemMessage,
-		}, nil
+		}, action, stderr), nil
 
 	case "output":
 		processedMessage := unifiedTemplateReplace(action.Message, rawJSON)
+		processedReason := ""
+		if action.Reason != nil {
+			processedReason = unifiedTemplateReplace(*action.Reason, rawJSON)
+		}
 
 		// Empty message check → fail-safe (decision: block)
-		if strings.TrimSpace(processedMessage) == "" {
+		// reasonだけが指定されている場合はClaude向けの理由のみを返す
+		if strings.TrimSpace(processedMessage) == "" && strings.TrimSpace(processedReason) == "" {
 			errMsg := "Empty message in SubagentStop action"
 			fmt.Fprintf(os.Stderr, "Warning: %s\n", errMsg)
 			return &ActionOutput{
@@ -536,42 +741,15 @@ func (e *ActionExecutor) ExecuteSubagentStopAction(action Action, input *Subagen
 				return &ActionOutput{
 					Continue:      true,
 					Decision:      "block",
-					Reason:        processedMessage,
+					Reason:        decisionReason(processedReason, processedMessage, "block"),
 					SystemMessage: errMsg,
 				}, nil
 			}
 			decision = *action.Decision
 		}
 
-		// Determine reason with template expansion
-		reason := processedMessage
-		if action.Reason != nil {
-			// Apply template expansion to reason
-			reasonValue := unifiedTemplateReplace(*action.Reason, rawJSON)
-			// Empty/whitespace reason with "block" decision should fallback to processedMessage
-			if strings.TrimSpace(reasonValue) == "" && decision == "block" {
-				reason = processedMessage
-			} else {
-				reason = reasonValue
-			}
-		}
-
-		// For allow (decision=""), clear reason (not applicable)
-		if decision == "" {
-			reason = ""
-		}
-
-		// Final validation: decision "block" requires non-empty reason
-		if decision == "block" && strings.TrimSpace(reason) == "" {
-			errMsg := "Empty reason when decision is 'block' (reason is required for block)"
-			fmt.Fprintf(os.Stderr, "Warning: %s\n", errMsg)
-			return &ActionOutput{
-				Continue:      true,
-				Decision:      "block",
-				Reason:        errMsg,
-				SystemMessage: errMsg,
-			}, nil
-		}
+		// reason (Claude向け) はmessage (ユーザー向け) とは独立にテンプレート展開される
+		reason := decisionReason(processedReason, processedMessage, decision)
 
 		return &ActionOutput{
 			Continue:      true,
@@ -591,16 +769,12 @@ func (e *ActionExecutor) ExecuteSubagentStopAction(action Action, input *Subagen
 // Errors are reported via systemMessage field, not by blocking execution.
 func (e *ActionExecutor) ExecutePreCompactAction(action Action, input *PreCompactInput, rawJSON any) (*ActionOutput, error) {
 	switch action.Type {
-	case "command":
-		cmd := unifiedTemplateReplace(action.Command, rawJSON)
-		stdout, stderr, exitCode, err := e.runner.RunCommandWithOutput(cmd, action.UseStdin, rawJSON)
+	case "command", "http", "opa":
+		stdout, stderr, exitCode, err := e.runAction(action, rawJSON)
 
 		// Command failed with non-zero exit code
 		if exitCode != 0 {
-			errMsg := fmt.Sprintf("Command failed with exit code %d: %s", exitCode, stderr)
-			if strings.TrimSpace(stderr) == "" && err != nil {
-				errMsg = fmt.Sprintf("Command failed with exit code %d: %v", exitCode, err)
-			}
+			errMsg := actionFailureMessage(action, exitCode, stderr, err)
 			fmt.Fprintf(os.Stderr, "Warning: %s\n", errMsg)
 			return &ActionOutput{
 				Continue:      true,
@@ -610,9 +784,9 @@ func (e *ActionExecutor) ExecutePreCompactAction(action Action, input *PreCompac
 
 		// Empty stdout - Allow compaction (validation-type CLI tools: silence = OK)
 		if strings.TrimSpace(stdout) == "" {
-			return &ActionOutput{
+			return withCommandStderr(&ActionOutput{
 				Continue: true,
-			}, nil
+			}, action, stderr), nil
 		}
 
 		// Parse JSON output (PreCompactOutput has Common JSON Fields only)
@@ -630,12 +804,12 @@ func (e *ActionExecutor) ExecutePreCompactAction(action Action, input *PreCompac
 		checkUnsupportedFieldsPreCompact(stdout)
 
 		// Build ActionOutput from parsed JSON
-		return &ActionOutput{
+		return withCommandStderr(&ActionOutput{
 			Continue:       true,
 			StopReason:     cmdOutput.StopReason,
 			SuppressOutput: cmdOutput.SuppressOutput,
 			SystemMessage:  cmdOutput.SystemMessage,
-		}, nil
+		}, action, stderr), nil
 
 	case "output":
 		processedMessage := unifiedTemplateReplace(action.Message, rawJSON)
@@ -670,16 +844,12 @@ func (e *ActionExecutor) ExecutePreCompactAction(action Action, input *PreCompac
 // Returns ActionOutput for JSON serialization.
 func (e *ActionExecutor) ExecuteSessionStartAction(action Action, input *SessionStartInput, rawJSON any) (*ActionOutput, error) {
 	switch action.Type {
-	case "command":
-		cmd := unifiedTemplateReplace(action.Command, rawJSON)
-		stdout, stderr, exitCode, err := e.runner.RunCommandWithOutput(cmd, action.UseStdin, rawJSON)
+	case "command", "http", "opa":
+		stdout, stderr, exitCode, err := e.runAction(action, rawJSON)
 
 		// Command failed with non-zero exit code
 		if exitCode != 0 {
-			errMsg := fmt.Sprintf("Command failed with exit code %d: %s", exitCode, stderr)
-			if strings.TrimSpace(stderr) == "" && err != nil {
-				errMsg = fmt.Sprintf("Command failed with exit code %d: %v", exitCode, err)
-			}
+			errMsg := actionFailureMessage(action, exitCode, stderr, err)
 			fmt.Fprintf(os.Stderr, "Warning: %s\n", errMsg)
 			return &ActionOutput{
 				Continue:      false,
@@ -692,10 +862,10 @@ func (e *ActionExecutor) ExecuteSessionStartAction(action Action, input *Session
 		// In this case, we return continue: true to allow the session to proceed.
 		// Note: additionalContext will be empty, so no information is provided to Claude.
 		if strings.TrimSpace(stdout) == "" {
-			return &ActionOutput{
+			return withCommandStderr(&ActionOutput{
 				Continue:      true,
 				HookEventName: "SessionStart",
-			}, nil
+			}, action, stderr), nil
 		}
 
 		// Parse JSON output
@@ -747,7 +917,7 @@ func (e *ActionExecutor) ExecuteSessionStartAction(action Action, input *Session
 		// Set AdditionalContext
 		result.AdditionalContext = cmdOutput.HookSpecificOutput.AdditionalContext
 
-		return result, err
+		return withCommandStderr(result, action, stderr), err
 
 	case "output":
 		processedMessage := unifiedTemplateReplace(action.Message, rawJSON)
@@ -773,6 +943,13 @@ func (e *ActionExecutor) ExecuteSessionStartAction(action Action, input *Session
 			HookEventName:     "SessionStart",
 			AdditionalContext: processedMessage,
 		}, nil
+
+	case "time_tracking":
+		// 計測の失敗でセッションを止めない
+		if _, err := trackTime(action, SessionStart, &input.BaseInput, rawJSON, !e.noSideEffects); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: time_tracking: %v\n", err)
+		}
+		return nil, nil
 	}
 
 	return nil, nil
@@ -782,16 +959,12 @@ func (e *ActionExecutor) ExecuteSessionStartAction(action Action, input *Session
 // This method implements Phase 2 JSON output functionality for UserPromptSubmit hooks.
 func (e *ActionExecutor) ExecuteUserPromptSubmitAction(action Action, input *UserPromptSubmitInput, rawJSON any) (*ActionOutput, error) {
 	switch action.Type {
-	case "command":
-		cmd := unifiedTemplateReplace(action.Command, rawJSON)
-		stdout, stderr, exitCode, err := e.runner.RunCommandWithOutput(cmd, action.UseStdin, rawJSON)
+	case "command", "http", "opa":
+		stdout, stderr, exitCode, err := e.runAction(action, rawJSON)
 
 		// Command failed with non-zero exit code
 		if exitCode != 0 {
-			errMsg := fmt.Sprintf("Command failed with exit code %d: %s", exitCode, stderr)
-			if strings.TrimSpace(stderr) == "" && err != nil {
-				errMsg = fmt.Sprintf("Command failed with exit code %d: %v", exitCode, err)
-			}
+			errMsg := actionFailureMessage(action, exitCode, stderr, err)
 			fmt.Fprintf(os.Stderr, "Warning: %s\n", errMsg)
 			return &ActionOutput{
 				Continue:      true,
@@ -805,11 +978,11 @@ func (e *ActionExecutor) ExecuteUserPromptSubmitAction(action Action, input *Use
 		// Tools like linters exit 0 with no output when everything is OK.
 		// In this case, we return continue: true with decision omitted (empty string) to proceed.
 		if strings.TrimSpace(stdout) == "" {
-			return &ActionOutput{
+			return withCommandStderr(&ActionOutput{
 				Continue:      true,
 				Decision:      "", // Empty string will be omitted from JSON (omitempty), allowing prompt
 				HookEventName: "UserPromptSubmit",
-			}, nil
+			}, action, stderr), nil
 		}
 
 		// Parse JSON output
@@ -893,7 +1066,7 @@ func (e *ActionExecutor) ExecuteUserPromptSubmitAction(action Action, input *Use
 		// Set AdditionalContext
 		result.AdditionalContext = cmdOutput.HookSpecificOutput.AdditionalContext
 
-		return result, err
+		return withCommandStderr(result, action, stderr), err
 
 	case "output":
 		processedMessage := unifiedTemplateReplace(action.Message, rawJSON)
@@ -926,11 +1099,15 @@ func (e *ActionExecutor) ExecuteUserPromptSubmitAction(action Action, input *Use
 			decision = *action.Decision
 		}
 
+		additionalContext := processedMessage
+		if decision == "block" {
+			additionalContext = appendModelHint(additionalContext, action, rawJSON)
+		}
 		return &ActionOutput{
 			Continue:          true,
 			Decision:          decision,
 			HookEventName:     "UserPromptSubmit",
-			AdditionalContext: processedMessage,
+			AdditionalContext: additionalContext,
 		}, nil
 	}
 
@@ -942,16 +1119,12 @@ func (e *ActionExecutor) ExecuteUserPromptSubmitAction(action Action, input *Use
 // Errors are reported via systemMessage field, not by blocking execution.
 func (e *ActionExecutor) ExecuteSessionEndAction(action Action, input *SessionEndInput, rawJSON any) (*ActionOutput, error) {
 	switch action.Type {
-	case "command":
-		cmd := unifiedTemplateReplace(action.Command, rawJSON)
-		stdout, stderr, exitCode, err := e.runner.RunCommandWithOutput(cmd, action.UseStdin, rawJSON)
+	case "command", "http", "opa":
+		stdout, stderr, exitCode, err := e.runAction(action, rawJSON)
 
 		// Command failed with non-zero exit code
 		if exitCode != 0 {
-			errMsg := fmt.Sprintf("Command failed with exit code %d: %s", exitCode, stderr)
-			if strings.TrimSpace(stderr) == "" && err != nil {
-				errMsg = fmt.Sprintf("Command failed with exit code %d: %v", exitCode, err)
-			}
+			errMsg := actionFailureMessage(action, exitCode, stderr, err)
 			fmt.Fprintf(os.Stderr, "Warning: %s\n", errMsg)
 			return &ActionOutput{
 				Continue:      true,
@@ -961,9 +1134,9 @@ func (e *ActionExecutor) ExecuteSessionEndAction(action Action, input *SessionEn
 
 		// Empty stdout - Allow session end (validation-type CLI tools: silence = OK)
 		if strings.TrimSpace(stdout) == "" {
-			return &ActionOutput{
+			return withCommandStderr(&ActionOutput{
 				Continue: true,
-			}, nil
+			}, action, stderr), nil
 		}
 
 		// Parse JSON output (SessionEndOutput has Common JSON Fields only)
@@ -981,12 +1154,12 @@ func (e *ActionExecutor) ExecuteSessionEndAction(action Action, input *SessionEn
 		checkUnsupportedFieldsSessionEnd(stdout)
 
 		// Build ActionOutput from parsed JSON
-		return &ActionOutput{
+		return withCommandStderr(&ActionOutput{
 			Continue:       true,
 			StopReason:     cmdOutput.StopReason,
 			SuppressOutput: cmdOutput.SuppressOutput,
 			SystemMessage:  cmdOutput.SystemMessage,
-		}, nil
+		}, action, stderr), nil
 
 	case "output":
 		processedMessage := unifiedTemplateReplace(action.Message, rawJSON)
@@ -1011,6 +1184,11 @@ func (e *ActionExecutor) ExecuteSessionEndAction(action Action, input *SessionEn
 			Continue:      true,
 			SystemMessage: processedMessage,
 		}, nil
+
+	case "time_tracking":
+		if _, err := trackTime(action, SessionEnd, &input.BaseInput, rawJSON, !e.noSideEffects); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: time_tracking: %v\n", err)
+		}
 	}
 
 	return &ActionOutput{
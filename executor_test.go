@@ -415,6 +415,21 @@ func TestExecuteUserPromptSubmitAction_TypeOutput(t *testing.T) {
 			wantSystemMessage: "",
 			wantErr:           false,
 		},
+		{
+			name: "Message with decision: block and model_hint",
+			action: Action{
+				Type:      "output",
+				Message:   "Blocked message",
+				Decision:  stringPtr("block"),
+				ModelHint: stringPtr("Do not paste credentials into prompts"),
+			},
+			wantContinue:      true,
+			wantDecision:      "block",
+			wantHookEventName: "UserPromptSubmit",
+			wantAdditionalCtx: "Blocked message\nDo not paste credentials into prompts",
+			wantSystemMessage: "",
+			wantErr:           false,
+		},
 		{
 			name: "Message with invalid decision value",
 			action: Action{
@@ -1269,6 +1284,43 @@ func TestExecuteStopAndSubagentStopAction_TypeOutput(t *testing.T) {
 			wantSystemMessage: "Empty message in Stop action",
 			wantErr:           false,
 		},
+		{
+			name:      "Stop: reason and message are templated independently",
+			eventType: "Stop",
+			action: Action{
+				Type:     "output",
+				Message:  "Session {.session_id}: tests are failing",
+				Decision: stringPtr("block"),
+				Reason:   stringPtr("Run `make test` and fix failures before stopping (stop_hook_active={.stop_hook_active})"),
+			},
+			wantDecision:      "block",
+			wantReason:        "Run `make test` and fix failures before stopping (stop_hook_active=false)",
+			wantSystemMessage: "Session test-session-123: tests are failing",
+		},
+		{
+			name:      "Stop: reason without message -> reason only, no systemMessage",
+			eventType: "Stop",
+			action: Action{
+				Type:     "output",
+				Decision: stringPtr("block"),
+				Reason:   stringPtr("Update CHANGELOG.md before stopping"),
+			},
+			wantDecision:      "block",
+			wantReason:        "Update CHANGELOG.md before stopping",
+			wantSystemMessage: "",
+		},
+		{
+			name:      "SubagentStop: reason without message -> reason only",
+			eventType: "SubagentStop",
+			action: Action{
+				Type:     "output",
+				Decision: stringPtr("block"),
+				Reason:   stringPtr("Summarize findings first"),
+			},
+			wantDecision:      "block",
+			wantReason:        "Summarize findings first",
+			wantSystemMessage: "",
+		},
 		{
 			name:      "Stop: decision: block + empty reason -> fallback to processedMessage",
 			eventType: "Stop",
@@ -11,16 +11,12 @@ import (
 // This method implements Phase 3 JSON output functionality for PreToolUse hooks.
 func (e *ActionExecutor) ExecutePreToolUseAction(action Action, input *PreToolUseInput, rawJSON any) (*ActionOutput, error) {
 	switch action.Type {
-	case "command":
-		cmd := unifiedTemplateReplace(action.Command, rawJSON)
-		stdout, stderr, exitCode, err := e.runner.RunCommandWithOutput(cmd, action.UseStdin, rawJSON)
+	case "command", "http", "opa":
+		stdout, stderr, exitCode, err := e.runAction(action, rawJSON)
 
 		// Command failed with non-zero exit code
 		if exitCode != 0 {
-			errMsg := fmt.Sprintf("Command failed with exit code %d: %s", exitCode, stderr)
-			if strings.TrimSpace(stderr) == "" && err != nil {
-				errMsg = fmt.Sprintf("Command failed with exit code %d: %v", exitCode, err)
-			}
+			errMsg := actionFailureMessage(action, exitCode, stderr, err)
 			fmt.Fprintf(os.Stderr, "Warning: %s\n", errMsg)
 			return &ActionOutput{
 				Continue:           true,
@@ -34,7 +30,7 @@ func (e *ActionExecutor) ExecutePreToolUseAction(action Action, input *PreToolUs
 		// Tools like linters exit 0 with no output when everything is OK.
 		// In this case, we return nil to delegate to Claude Code's permission flow.
 		if strings.TrimSpace(stdout) == "" {
-			return nil, nil
+			return withCommandStderr(nil, action, stderr), nil
 		}
 
 		// Parse JSON output
@@ -133,7 +129,7 @@ func (e *ActionExecutor) ExecutePreToolUseAction(action Action, input *PreToolUs
 			SystemMessage:            cmdOutput.SystemMessage,
 		}
 
-		return result, err
+		return withCommandStderr(result, action, stderr), err
 
 	case "output":
 		processedMessage := unifiedTemplateReplace(action.Message, rawJSON)
@@ -172,19 +168,247 @@ func (e *ActionExecutor) ExecutePreToolUseAction(action Action, input *PreToolUs
 		if action.AdditionalContext != nil {
 			additionalContext = unifiedTemplateReplace(*action.AdditionalContext, rawJSON)
 		}
+		if permissionDecision == "deny" {
+			additionalContext = appendModelHint(additionalContext, action, rawJSON)
+		}
 
-		return &ActionOutput{
+		// Process suggest_command: shown in the reason on deny/ask,
+		// and offered as a rewritten tool_input on ask (the user confirms the rewrite)
+		var updatedInput map[string]any
+		if action.SuggestCommand != nil && permissionDecision != "allow" {
+			suggested := strings.TrimSpace(unifiedTemplateReplace(*action.SuggestCommand, rawJSON))
+			if suggested != "" {
+				processedMessage += "\nSuggested command: " + suggested
+				if permissionDecision == "ask" {
+					updatedInput = suggestedToolInput(rawJSON, suggested)
+				}
+			}
+		}
+
+		// Process updated_input (公式仕様: deny時はupdatedInput不可)
+		if action.UpdatedInput != nil {
+			if permissionDecision == "deny" {
+				fmt.Fprintf(os.Stderr, "Warning: updated_input is set but permission_decision is 'deny'. updated_input will be ignored\n")
+			} else {
+				updatedInput = applyUpdatedInput(updatedInput, action.UpdatedInput, rawJSON)
+			}
+		}
+
+		output := &ActionOutput{
 			Continue:                 true,
 			PermissionDecision:       permissionDecision,
 			HookEventName:            "PreToolUse",
 			PermissionDecisionReason: processedMessage,
 			AdditionalContext:        additionalContext,
+			UpdatedInput:             updatedInput,
+		}
+		applyOutputMessageFields(output, action, rawJSON)
+		return output, nil
+
+	case "secret_scan":
+		// 何も見つからなければClaude Codeの権限判定に任せる
+		findings := secretScanToolInput(input.ToolInput)
+		if len(findings) == 0 {
+			return nil, nil
+		}
+		return &ActionOutput{
+			Continue:                 true,
+			PermissionDecision:       "deny",
+			HookEventName:            "PreToolUse",
+			PermissionDecisionReason: secretScanReason(findings),
+		}, nil
+
+	case "rewrite_command":
+		// コマンドの無いツールはClaude Codeの権限判定に任せる
+		command := input.ToolInput.Command
+		if command == "" {
+			return nil, nil
+		}
+		rewritten, matched, err := rewriteCommand(command, action.Rules)
+		if err != nil {
+			errMsg := fmt.Sprintf("rewrite_command: %v", err)
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", errMsg)
+			return &ActionOutput{
+				Continue:           true,
+				PermissionDecision: "deny",
+				HookEventName:      "PreToolUse",
+				SystemMessage:      errMsg,
+			}, nil
+		}
+		if !matched {
+			if !action.DenyIfNoMatch {
+				return nil, nil
+			}
+			reason := unifiedTemplateReplace(action.Message, rawJSON)
+			if reason == "" {
+				reason = "The command does not match any rewrite rule: " + command
+			}
+			return &ActionOutput{
+				Continue:                 true,
+				PermissionDecision:       "deny",
+				HookEventName:            "PreToolUse",
+				PermissionDecisionReason: reason,
+			}, nil
+		}
+		if rewritten == command {
+			return nil, nil
+		}
+		decision := "ask"
+		if action.PermissionDecision != nil && *action.PermissionDecision != "" {
+			decision = *action.PermissionDecision
+		}
+		reason := unifiedTemplateReplace(action.Message, rawJSON)
+		if reason == "" {
+			reason = "Rewrote the command to: " + rewritten
+		}
+		return &ActionOutput{
+			Continue:                 true,
+			PermissionDecision:       decision,
+			HookEventName:            "PreToolUse",
+			PermissionDecisionReason: reason,
+			AdditionalContext:        "The command was rewritten by a hook and runs as: " + rewritten,
+			UpdatedInput:             suggestedToolInput(rawJSON, rewritten),
+		}, nil
+
+	case "breaking_change":
+		// API定義以外のファイルや、比較できない場合はClaude Codeの権限判定に任せる
+		filePath := resolveToolFilePath(input.ToolInput.FilePath, input.Cwd)
+		content, ok := proposedFileContent(input.ToolName, filePath, input.ToolInput)
+		if !ok {
+			return nil, nil
+		}
+		check, err := prepareBreakingChangeCheck(filePath, content, action.Base)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: breaking_change could not prepare %s: %v\n", input.ToolInput.FilePath, err)
+			return nil, nil
+		}
+		if check == nil {
+			return nil, nil
+		}
+		defer check.cleanup()
+		stdout, stderr, exitCode, err := e.runAction(Action{Type: "breaking_change", Command: check.Command}, rawJSON)
+		if exitCode == 0 {
+			return nil, nil
+		}
+		if !breakingChangesFound(check.Tool, exitCode) {
+			// buf/oasdiffが無い、定義が読めないなどで比較できなかっただけなのでClaude Codeに任せる
+			message := strings.TrimSpace(stderr + "\n" + stdout)
+			if message == "" && err != nil {
+				message = err.Error()
+			}
+			fmt.Fprintf(os.Stderr, "Warning: breaking_change could not run %s breaking for %s: %s\n", check.Tool, check.File, message)
+			return nil, nil
+		}
+		decision := "ask"
+		if action.PermissionDecision != nil && *action.PermissionDecision != "" {
+			decision = *action.PermissionDecision
+		}
+		return &ActionOutput{
+			Continue:                 true,
+			PermissionDecision:       decision,
+			HookEventName:            "PreToolUse",
+			PermissionDecisionReason: breakingChangeReason(check, stdout+"\n"+stderr),
+		}, nil
+
+	case "ask_external":
+		return e.runAskExternal(action, rawJSON), nil
+
+	case "terminology":
+		// 既定ではClaudeへ伝えるだけで、permission_decisionを指定すると書き込みを止める
+		findings := terminologyToolInput(input.ToolInput, action.Terms)
+		if len(findings) == 0 {
+			return nil, nil
+		}
+		report := terminologyReport(input.ToolInput.FilePath, findings)
+		if action.PermissionDecision != nil && *action.PermissionDecision != "" {
+			return &ActionOutput{
+				Continue:                 true,
+				PermissionDecision:       *action.PermissionDecision,
+				HookEventName:            "PreToolUse",
+				PermissionDecisionReason: report,
+			}, nil
+		}
+		return &ActionOutput{
+			Continue:          true,
+			HookEventName:     "PreToolUse",
+			AdditionalContext: report,
 		}, nil
 	}
 
 	return nil, nil
 }
 
+// suggestedToolInput returns a copy of tool_input in rawJSON with command replaced by suggested.
+// Other fields (description, timeout, ...) are preserved.
+func suggestedToolInput(rawJSON any, suggested string) map[string]any {
+	updated := copyToolInput(rawJSON)
+	updated["command"] = suggested
+	return updated
+}
+
+// copyToolInput returns a shallow copy of tool_input in rawJSON (empty if absent),
+// with file_path as Claude sent it (see withNormalizedFilePath).
+func copyToolInput(rawJSON any) map[string]any {
+	updated := map[string]any{}
+	if data, ok := rawJSON.(map[string]any); ok {
+		if toolInput, ok := data["tool_input"].(map[string]any); ok {
+			for k, v := range toolInput {
+				updated[k] = v
+			}
+		}
+	}
+	if raw, ok := updated["file_path_raw"]; ok {
+		updated["file_path"] = raw
+		delete(updated, "file_path_raw")
+	}
+	return updated
+}
+
+// applyUpdatedInput overrides fields of base (a copy of tool_input when nil) with the updated_input of an action.
+// String values are expanded as templates, including those nested in maps and lists.
+// Fields not listed in updated_input are kept, so a rule only needs to set what it rewrites.
+func applyUpdatedInput(base map[string]any, overrides map[string]any, rawJSON any) map[string]any {
+	if base == nil {
+		base = copyToolInput(rawJSON)
+	}
+	for k, v := range overrides {
+		base[k] = templateValue(v, rawJSON)
+	}
+	return base
+}
+
+// templateValue expands templates in every string of a YAML value.
+func templateValue(value any, rawJSON any) any {
+	switch v := value.(type) {
+	case string:
+		return unifiedTemplateReplace(v, rawJSON)
+	case map[string]any:
+		expanded := make(map[string]any, len(v))
+		for k, item := range v {
+			expanded[k] = templateValue(item, rawJSON)
+		}
+		return expanded
+	case []any:
+		expanded := make([]any, len(v))
+		for i, item := range v {
+			expanded[i] = templateValue(item, rawJSON)
+		}
+		return expanded
+	default:
+		return v
+	}
+}
+
+// applyOutputMessageFields sets system_message and suppress_output of an output action on output.
+func applyOutputMessageFields(output *ActionOutput, action Action, rawJSON any) {
+	if action.SystemMessage != nil {
+		output.SystemMessage = unifiedTemplateReplace(*action.SystemMessage, rawJSON)
+	}
+	if action.SuppressOutput != nil {
+		output.SuppressOutput = *action.SuppressOutput
+	}
+}
+
 // checkUnsupportedFieldsPreToolUse checks for unsupported fields in PreToolUse JSON output
 // and logs warnings to stderr for any fields that are not in the supported list.
 func checkUnsupportedFieldsPreToolUse(stdout string) {
@@ -215,16 +439,12 @@ func checkUnsupportedFieldsPreToolUse(stdout string) {
 // Returns (*ActionOutput, error) following the new JSON output pattern.
 func (e *ActionExecutor) ExecutePostToolUseAction(action Action, input *PostToolUseInput, rawJSON any) (*ActionOutput, error) {
 	switch action.Type {
-	case "command":
-		cmd := unifiedTemplateReplace(action.Command, rawJSON)
-		stdout, stderr, exitCode, err := e.runner.RunCommandWithOutput(cmd, action.UseStdin, rawJSON)
+	case "command", "http", "opa":
+		stdout, stderr, exitCode, err := e.runAction(action, rawJSON)
 
 		// Command failed with non-zero exit code
 		if exitCode != 0 {
-			errMsg := fmt.Sprintf("Command failed with exit code %d: %s", exitCode, stderr)
-			if strings.TrimSpace(stderr) == "" && err != nil {
-				errMsg = fmt.Sprintf("Command failed with exit code %d: %v", exitCode, err)
-			}
+			errMsg := actionFailureMessage(action, exitCode, stderr, err)
 			fmt.Fprintf(os.Stderr, "Warning: %s\n", errMsg)
 			return &ActionOutput{
 				Continue:      true,
@@ -237,11 +457,11 @@ func (e *ActionExecutor) ExecutePostToolUseAction(action Action, input *PostTool
 
 		// Empty stdout - Allow tool result (validation-type CLI tools: silence = OK)
 		if strings.TrimSpace(stdout) == "" {
-			return &ActionOutput{
+			return withCommandStderr(&ActionOutput{
 				Continue:      true,
 				Decision:      "", // Allow tool result
 				HookEventName: "PostToolUse",
-			}, nil
+			}, action, stderr), nil
 		}
 
 		// Parse JSON output (PostToolUseOutput with hookSpecificOutput)
@@ -307,7 +527,7 @@ func (e *ActionExecutor) ExecutePostToolUseAction(action Action, input *PostTool
 		checkUnsupportedFieldsPostToolUse(stdout)
 
 		// Build ActionOutput from parsed JSON
-		return &ActionOutput{
+		return withCommandStderr(&ActionOutput{
 			Continue:             true,
 			Decision:             decision,
 			Reason:               cmdOutput.Reason,
@@ -317,13 +537,18 @@ func (e *ActionExecutor) ExecutePostToolUseAction(action Action, input *PostTool
 			HookEventName:        hookEventName,
 			AdditionalContext:    additionalContext,
 			UpdatedMCPToolOutput: cmdOutput.UpdatedMCPToolOutput,
-		}, nil
+		}, action, stderr), nil
 
 	case "output":
 		processedMessage := unifiedTemplateReplace(action.Message, rawJSON)
+		processedReason := ""
+		if action.Reason != nil {
+			processedReason = unifiedTemplateReplace(*action.Reason, rawJSON)
+		}
 
 		// Empty message check → fail-safe (decision: block)
-		if strings.TrimSpace(processedMessage) == "" {
+		// reasonだけが指定されている場合はClaude向けの理由のみを返す
+		if strings.TrimSpace(processedMessage) == "" && strings.TrimSpace(processedReason) == "" {
 			errMsg := "Empty message in PostToolUse action"
 			fmt.Fprintf(os.Stderr, "Warning: %s\n", errMsg)
 			return &ActionOutput{
@@ -350,7 +575,7 @@ func (e *ActionExecutor) ExecutePostToolUseAction(action Action, input *PostTool
 				return &ActionOutput{
 					Continue:      true,
 					Decision:      "block",
-					Reason:        processedMessage,
+					Reason:        decisionReason(processedReason, processedMessage, "block"),
 					SystemMessage: errMsg,
 					HookEventName: "PostToolUse",
 				}, nil
@@ -358,45 +583,170 @@ func (e *ActionExecutor) ExecutePostToolUseAction(action Action, input *PostTool
 			decision = *action.Decision
 		}
 
-		// Determine reason with template expansion
-		reason := processedMessage
-		if action.Reason != nil {
-			// Apply template expansion to reason
-			reasonValue := unifiedTemplateReplace(*action.Reason, rawJSON)
-			// Empty/whitespace reason with "block" decision should fallback to processedMessage
-			if strings.TrimSpace(reasonValue) == "" && decision == "block" {
-				reason = processedMessage
-			} else {
-				reason = reasonValue
+		// reason (Claude向け) はmessage (ユーザー向け) とは独立にテンプレート展開される
+		reason := decisionReason(processedReason, processedMessage, decision)
+
+		// PostToolUse: message maps to AdditionalContext only (not SystemMessage)
+		// SystemMessage is only for errors (as per design pattern L21-25 in dev diary)
+		additionalContext := processedMessage
+		if decision == "block" {
+			additionalContext = appendModelHint(additionalContext, action, rawJSON)
+		}
+		output := &ActionOutput{
+			Continue:          true,
+			Decision:          decision,
+			Reason:            reason,
+			HookEventName:     "PostToolUse",
+			AdditionalContext: additionalContext,
+		}
+		applyOutputMessageFields(output, action, rawJSON)
+		return output, nil
+
+	case "secret_scan":
+		// ツールは実行済みなので、blockでClaudeに秘密情報を取り除かせる
+		findings := secretScanToolInput(input.ToolInput)
+		if action.ScanFile {
+			if filePath := resolveToolFilePath(input.ToolInput.FilePath, input.Cwd); filePath != "" {
+				findings = append(findings, secretScanFile(filePath)...)
 			}
 		}
+		if len(findings) == 0 {
+			return nil, nil
+		}
+		reason := secretScanReason(findings)
+		return &ActionOutput{
+			Continue:      true,
+			Decision:      "block",
+			Reason:        reason,
+			HookEventName: "PostToolUse",
+		}, nil
 
-		// For allow (decision=""), clear reason (not applicable)
-		if decision == "" {
-			reason = ""
+	case "syntax_check":
+		// 対応していない拡張子や読めないファイルは何もしない
+		filePath := resolveToolFilePath(input.ToolInput.FilePath, input.Cwd)
+		if filePath == "" {
+			return nil, nil
 		}
+		checked, err := checkFileSyntax(filePath)
+		if !checked || err == nil {
+			return nil, nil
+		}
+		reason := syntaxCheckReason(input.ToolInput.FilePath, err)
+		// decision: "" を明示した場合はブロックせずにClaudeへ伝えるだけ
+		if action.Decision != nil && *action.Decision == "" {
+			return &ActionOutput{
+				Continue:          true,
+				HookEventName:     "PostToolUse",
+				AdditionalContext: reason,
+			}, nil
+		}
+		return &ActionOutput{
+			Continue:      true,
+			Decision:      "block",
+			Reason:        reason,
+			HookEventName: "PostToolUse",
+		}, nil
 
-		// Final validation: decision "block" requires non-empty reason
-		if decision == "block" && strings.TrimSpace(reason) == "" {
-			errMsg := "Empty reason when decision is 'block' (reason is required for block)"
-			fmt.Fprintf(os.Stderr, "Warning: %s\n", errMsg)
+	case "markdown_check":
+		// Markdown以外のファイルや読めないファイルは何もしない
+		filePath := resolveToolFilePath(input.ToolInput.FilePath, input.Cwd)
+		if !isMarkdownFile(filePath) {
+			return nil, nil
+		}
+		schemaPath := ""
+		if action.FrontmatterSchema != "" {
+			schemaPath = resolveToolFilePath(action.FrontmatterSchema, input.Cwd)
+		}
+		findings, err := checkMarkdownFile(filePath, schemaPath)
+		if err != nil || len(findings) == 0 {
+			return nil, nil
+		}
+		report := markdownCheckReport(input.ToolInput.FilePath, findings)
+		// 既定ではClaudeへ伝えるだけで、decision: blockでブロックする
+		if action.Decision != nil && *action.Decision == "block" {
 			return &ActionOutput{
 				Continue:      true,
 				Decision:      "block",
-				Reason:        errMsg,
-				SystemMessage: errMsg,
+				Reason:        report,
 				HookEventName: "PostToolUse",
 			}, nil
 		}
+		return &ActionOutput{
+			Continue:          true,
+			HookEventName:     "PostToolUse",
+			AdditionalContext: report,
+		}, nil
 
-		// PostToolUse: message maps to AdditionalContext only (not SystemMessage)
-		// SystemMessage is only for errors (as per design pattern L21-25 in dev diary)
+	case "terminology":
+		// 既定ではClaudeへ伝えるだけで、decision: blockでブロックする
+		findings := terminologyToolInput(input.ToolInput, action.Terms)
+		if len(findings) == 0 {
+			return nil, nil
+		}
+		report := terminologyReport(input.ToolInput.FilePath, findings)
+		if action.Decision != nil && *action.Decision == "block" {
+			return &ActionOutput{
+				Continue:      true,
+				Decision:      "block",
+				Reason:        report,
+				HookEventName: "PostToolUse",
+			}, nil
+		}
 		return &ActionOutput{
 			Continue:          true,
-			Decision:          decision,
-			Reason:            reason,
 			HookEventName:     "PostToolUse",
-			AdditionalContext: processedMessage,
+			AdditionalContext: report,
+		}, nil
+
+	case "format_file":
+		// フォーマッタの無い拡張子や読めないファイルは何もしない
+		filePath := resolveToolFilePath(input.ToolInput.FilePath, input.Cwd)
+		return e.formatFile(action, filePath, input.ToolInput.FilePath, rawJSON), nil
+
+	case "git_checkpoint":
+		e.gitCheckpoint(action, gitCheckpointDir(input.ToolInput.FilePath, input.Cwd), rawJSON)
+		return nil, nil
+
+	case "lint_feedback":
+		// リンタの無い拡張子のファイルは何もしない
+		filePath := resolveToolFilePath(input.ToolInput.FilePath, input.Cwd)
+		return e.lintFeedback(action, filePath, input.ToolInput.FilePath, rawJSON), nil
+
+	case "typecheck":
+		// Go/TypeScript以外のファイルやモジュール・プロジェクトの外のファイルは何もしない
+		filePath := resolveToolFilePath(input.ToolInput.FilePath, input.Cwd)
+		target, ok := typecheckTargetFor(filePath)
+		if !ok {
+			return nil, nil
+		}
+		check := Action{Type: "typecheck", Command: target.Command, EnvFrom: action.EnvFrom}
+		stdout, stderr, exitCode, err := e.runAction(check, rawJSON)
+		if exitCode == 0 {
+			return nil, nil
+		}
+		if exitCode == 127 || (err != nil && stdout == "" && stderr == "") {
+			// go/tscが無い環境ではチェックできないだけなのでブロックしない
+			message := strings.TrimSpace(stderr)
+			if message == "" && err != nil {
+				message = err.Error()
+			}
+			fmt.Fprintf(os.Stderr, "Warning: typecheck could not run %s: %s\n", target.Display, message)
+			return nil, nil
+		}
+		reason := typecheckReason(target, filePath, input.Cwd, stdout+"\n"+stderr)
+		// decision: "" を明示した場合はブロックせずにClaudeへ伝えるだけ
+		if action.Decision != nil && *action.Decision == "" {
+			return &ActionOutput{
+				Continue:          true,
+				HookEventName:     "PostToolUse",
+				AdditionalContext: reason,
+			}, nil
+		}
+		return &ActionOutput{
+			Continue:      true,
+			Decision:      "block",
+			Reason:        reason,
+			HookEventName: "PostToolUse",
 		}, nil
 	}
 
@@ -428,6 +778,12 @@ func checkUnsupportedFieldsPostToolUse(stdout string) {
 	}
 }
 
+// isPermissionRequestBehavior reports whether behavior is a valid PermissionRequest decision.
+// "ask" is not part of Claude Code's protocol: cchook leaves the request to the user (see finalizePermissionRequestAsk).
+func isPermissionRequestBehavior(behavior string) bool {
+	return behavior == "allow" || behavior == "deny" || behavior == "ask"
+}
+
 // createPermissionRequestDenyOutput creates a deny ActionOutput with the given error message
 func createPermissionRequestDenyOutput(errMsg string) *ActionOutput {
 	fmt.Fprintf(os.Stderr, "Warning: %s\n", errMsg)
@@ -443,17 +799,13 @@ func createPermissionRequestDenyOutput(errMsg string) *ActionOutput {
 // ExecutePermissionRequestAction executes a PermissionRequest action
 func (e *ActionExecutor) ExecutePermissionRequestAction(action Action, input *PermissionRequestInput, rawJSON any) (*ActionOutput, error) {
 	switch action.Type {
-	case "command":
-		cmd := unifiedTemplateReplace(action.Command, rawJSON)
-		stdout, stderr, exitCode, err := e.runner.RunCommandWithOutput(cmd, action.UseStdin, rawJSON)
+	case "command", "http", "opa":
+		stdout, stderr, exitCode, err := e.runAction(action, rawJSON)
 
 		// Command failed with non-zero exit code
 		if exitCode != 0 {
 			// Include err in message when stderr is empty (e.g., stdin JSON marshal failure)
-			errMsg := fmt.Sprintf("Command failed with exit code %d: %s", exitCode, stderr)
-			if stderr == "" && err != nil {
-				errMsg = fmt.Sprintf("Command failed with exit code %d: %v", exitCode, err)
-			}
+			errMsg := actionFailureMessage(action, exitCode, stderr, err)
 			return createPermissionRequestDenyOutput(errMsg), nil
 		}
 
@@ -485,9 +837,9 @@ func (e *ActionExecutor) ExecutePermissionRequestAction(action Action, input *Pe
 			return createPermissionRequestDenyOutput("Command output is missing required field: hookSpecificOutput.decision.behavior"), nil
 		}
 
-		// Validate behavior value (only "allow" or "deny")
-		if cmdOutput.HookSpecificOutput.Decision.Behavior != "allow" && cmdOutput.HookSpecificOutput.Decision.Behavior != "deny" {
-			errMsg := fmt.Sprintf("Invalid behavior value: must be 'allow' or 'deny', got '%s'", cmdOutput.HookSpecificOutput.Decision.Behavior)
+		// Validate behavior value ("allow", "deny" or "ask")
+		if !isPermissionRequestBehavior(cmdOutput.HookSpecificOutput.Decision.Behavior) {
+			errMsg := fmt.Sprintf("Invalid behavior value: must be 'allow', 'deny' or 'ask', got '%s'", cmdOutput.HookSpecificOutput.Decision.Behavior)
 			return createPermissionRequestDenyOutput(errMsg), nil
 		}
 
@@ -512,7 +864,7 @@ func (e *ActionExecutor) ExecutePermissionRequestAction(action Action, input *Pe
 		}
 
 		// Return output with all fields
-		return &ActionOutput{
+		return withCommandStderr(&ActionOutput{
 			Continue:       continueValue,
 			Behavior:       cmdOutput.HookSpecificOutput.Decision.Behavior,
 			Message:        cmdOutput.HookSpecificOutput.Decision.Message,
@@ -522,7 +874,7 @@ func (e *ActionExecutor) ExecutePermissionRequestAction(action Action, input *Pe
 			SystemMessage:  cmdOutput.SystemMessage,
 			StopReason:     cmdOutput.StopReason,
 			SuppressOutput: cmdOutput.SuppressOutput,
-		}, nil
+		}, action, stderr), nil
 
 	case "output":
 		message := unifiedTemplateReplace(action.Message, rawJSON)
@@ -532,8 +884,8 @@ func (e *ActionExecutor) ExecutePermissionRequestAction(action Action, input *Pe
 		if action.Behavior != nil {
 			behavior = *action.Behavior
 			// Validate behavior value
-			if behavior != "allow" && behavior != "deny" {
-				errMsg := "Invalid behavior value in action config: must be 'allow' or 'deny'"
+			if !isPermissionRequestBehavior(behavior) {
+				errMsg := "Invalid behavior value in action config: must be 'allow', 'deny' or 'ask'"
 				return &ActionOutput{
 					Continue:      true,
 					Behavior:      "deny",
@@ -559,13 +911,20 @@ func (e *ActionExecutor) ExecutePermissionRequestAction(action Action, input *Pe
 		// Set fields based on behavior (公式仕様に準拠)
 		var resultMessage string
 		var resultInterrupt bool
-		if behavior == "deny" {
+		switch behavior {
+		case "deny":
 			// deny時: message/interruptを設定
 			resultMessage = message
 			if action.Interrupt != nil {
 				resultInterrupt = *action.Interrupt
 			}
-		} else {
+		case "ask":
+			// ask時: messageはユーザーへの提案としてsystemMessageに出す
+			resultMessage = message
+			if action.Interrupt != nil && *action.Interrupt {
+				fmt.Fprintf(os.Stderr, "Warning: interrupt is set but behavior is 'ask'. interrupt will be ignored\n")
+			}
+		default:
 			// allow時: message/interruptが設定されていたら警告
 			if message != "" {
 				fmt.Fprintf(os.Stderr, "Warning: message is set but behavior is 'allow'. message will be ignored (公式仕様: allow時はmessage不可)\n")
@@ -576,13 +935,26 @@ func (e *ActionExecutor) ExecutePermissionRequestAction(action Action, input *Pe
 		}
 		// allow時: message=""、interrupt=false（デフォルト値のまま）
 
-		return &ActionOutput{
+		// updated_inputはallow時のみ (公式仕様: deny時はupdatedInput不可)
+		var updatedInput map[string]any
+		if action.UpdatedInput != nil {
+			if behavior == "allow" {
+				updatedInput = applyUpdatedInput(nil, action.UpdatedInput, rawJSON)
+			} else {
+				fmt.Fprintf(os.Stderr, "Warning: updated_input is set but behavior is '%s'. updated_input will be ignored\n", behavior)
+			}
+		}
+
+		output := &ActionOutput{
 			Continue:      true,
 			Behavior:      behavior,
 			Message:       resultMessage,
 			Interrupt:     resultInterrupt,
+			UpdatedInput:  updatedInput,
 			HookEventName: "PermissionRequest",
-		}, nil
+		}
+		applyOutputMessageFields(output, action, rawJSON)
+		return output, nil
 
 	default:
 		return nil, fmt.Errorf("unsupported action type: %s", action.Type)
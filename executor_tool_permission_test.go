@@ -138,6 +138,39 @@ func TestExecutePreToolUseAction_TypeOutput(t *testing.T) {
 			wantAdditionalContext:        "",
 			wantHookEventName:            "PreToolUse",
 		},
+		{
+			name: "model_hint with deny -> appended to AdditionalContext",
+			action: Action{
+				Type:               "output",
+				Message:            "mv is not allowed for tracked files",
+				PermissionDecision: stringPtr("deny"),
+				AdditionalContext:  stringPtr("Repository policy"),
+				ModelHint:          stringPtr("Use git mv instead of mv for {.tool_name}"),
+			},
+			input: &PreToolUseInput{
+				ToolName: "Bash",
+			},
+			wantPermissionDecision:       "deny",
+			wantPermissionDecisionReason: "mv is not allowed for tracked files",
+			wantAdditionalContext:        "Repository policy\nUse git mv instead of mv for Bash",
+			wantHookEventName:            "PreToolUse",
+		},
+		{
+			name: "model_hint with allow -> ignored",
+			action: Action{
+				Type:               "output",
+				Message:            "Operation allowed",
+				PermissionDecision: stringPtr("allow"),
+				ModelHint:          stringPtr("Use git mv instead of mv"),
+			},
+			input: &PreToolUseInput{
+				ToolName: "Bash",
+			},
+			wantPermissionDecision:       "allow",
+			wantPermissionDecisionReason: "Operation allowed",
+			wantAdditionalContext:        "",
+			wantHookEventName:            "PreToolUse",
+		},
 	}
 
 	for _, tt := range tests {
@@ -186,6 +219,221 @@ func TestExecutePreToolUseAction_TypeOutput(t *testing.T) {
 	}
 }
 
+func TestExecutePreToolUseAction_SuggestCommand(t *testing.T) {
+	rawJSON := map[string]any{
+		"tool_name": "Bash",
+		"tool_input": map[string]any{
+			"command":     "rm main.go",
+			"description": "Remove file",
+		},
+	}
+
+	tests := []struct {
+		name             string
+		action           Action
+		wantReason       string
+		wantUpdatedInput map[string]any
+	}{
+		{
+			name: "deny -> suggestion in reason only",
+			action: Action{
+				Type:               "output",
+				Message:            "main.go is tracked by Git",
+				PermissionDecision: stringPtr("deny"),
+				SuggestCommand:     stringPtr("git {.tool_input.command}"),
+			},
+			wantReason: "main.go is tracked by Git\nSuggested command: git rm main.go",
+		},
+		{
+			name: "ask -> suggestion in reason and updatedInput",
+			action: Action{
+				Type:               "output",
+				Message:            "main.go is tracked by Git",
+				PermissionDecision: stringPtr("ask"),
+				SuggestCommand:     stringPtr("git {.tool_input.command}"),
+			},
+			wantReason: "main.go is tracked by Git\nSuggested command: git rm main.go",
+			wantUpdatedInput: map[string]any{
+				"command":     "git rm main.go",
+				"description": "Remove file",
+			},
+		},
+		{
+			name: "allow -> suggestion ignored",
+			action: Action{
+				Type:               "output",
+				Message:            "ok",
+				PermissionDecision: stringPtr("allow"),
+				SuggestCommand:     stringPtr("git {.tool_input.command}"),
+			},
+			wantReason: "ok",
+		},
+		{
+			name: "empty suggestion -> ignored",
+			action: Action{
+				Type:               "output",
+				Message:            "blocked",
+				PermissionDecision: stringPtr("ask"),
+				SuggestCommand:     stringPtr("  "),
+			},
+			wantReason: "blocked",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := NewActionExecutor(nil)
+			output, err := executor.ExecutePreToolUseAction(tt.action, &PreToolUseInput{ToolName: "Bash"}, rawJSON)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if output.PermissionDecisionReason != tt.wantReason {
+				t.Errorf("PermissionDecisionReason = %q, want %q", output.PermissionDecisionReason, tt.wantReason)
+			}
+			if !reflect.DeepEqual(output.UpdatedInput, tt.wantUpdatedInput) {
+				t.Errorf("UpdatedInput = %v, want %v", output.UpdatedInput, tt.wantUpdatedInput)
+			}
+		})
+	}
+
+	// 元のtool_inputは書き換えない
+	if got := rawJSON["tool_input"].(map[string]any)["command"]; got != "rm main.go" {
+		t.Errorf("rawJSON tool_input was modified: %v", got)
+	}
+}
+
+func TestExecuteToolActions_UpdatedInputAndMessageFields(t *testing.T) {
+	rawJSON := map[string]any{
+		"tool_name": "Bash",
+		"tool_input": map[string]any{
+			"command":     "rm -rf build",
+			"description": "Clean",
+		},
+	}
+	updatedInput := map[string]any{
+		"command": "trash {.tool_input.command}",
+		"env":     map[string]any{"REASON": "{.tool_name}"},
+		"args":    []any{"{.tool_input.description}", 1},
+	}
+	want := map[string]any{
+		"command":     "trash rm -rf build",
+		"description": "Clean",
+		"env":         map[string]any{"REASON": "Bash"},
+		"args":        []any{"Clean", 1},
+	}
+	executor := NewActionExecutor(nil)
+
+	t.Run("PreToolUse allow", func(t *testing.T) {
+		output, err := executor.ExecutePreToolUseAction(Action{
+			Type:               "output",
+			Message:            "rewritten",
+			PermissionDecision: stringPtr("allow"),
+			AdditionalContext:  stringPtr("used trash instead of rm"),
+			UpdatedInput:       updatedInput,
+			SystemMessage:      stringPtr("rm was rewritten for {.tool_name}"),
+			SuppressOutput:     boolPtr(true),
+		}, &PreToolUseInput{ToolName: "Bash"}, rawJSON)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if !reflect.DeepEqual(output.UpdatedInput, want) {
+			t.Errorf("UpdatedInput = %v, want %v", output.UpdatedInput, want)
+		}
+		if output.AdditionalContext != "used trash instead of rm" {
+			t.Errorf("AdditionalContext = %q", output.AdditionalContext)
+		}
+		if output.SystemMessage != "rm was rewritten for Bash" {
+			t.Errorf("SystemMessage = %q", output.SystemMessage)
+		}
+		if !output.SuppressOutput {
+			t.Error("Expected SuppressOutput to be true")
+		}
+	})
+
+	t.Run("PreToolUse ask overrides suggest_command", func(t *testing.T) {
+		output, err := executor.ExecutePreToolUseAction(Action{
+			Type:               "output",
+			Message:            "confirm",
+			PermissionDecision: stringPtr("ask"),
+			SuggestCommand:     stringPtr("git clean -fd"),
+			UpdatedInput:       map[string]any{"description": "Clean (rewritten)"},
+		}, &PreToolUseInput{ToolName: "Bash"}, rawJSON)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		wantAsk := map[string]any{"command": "git clean -fd", "description": "Clean (rewritten)"}
+		if !reflect.DeepEqual(output.UpdatedInput, wantAsk) {
+			t.Errorf("UpdatedInput = %v, want %v", output.UpdatedInput, wantAsk)
+		}
+	})
+
+	t.Run("PreToolUse deny ignores updated_input", func(t *testing.T) {
+		output, err := executor.ExecutePreToolUseAction(Action{
+			Type:         "output",
+			Message:      "blocked",
+			UpdatedInput: updatedInput,
+		}, &PreToolUseInput{ToolName: "Bash"}, rawJSON)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if output.PermissionDecision != "deny" || output.UpdatedInput != nil {
+			t.Errorf("Expected deny without updatedInput, got %q / %v", output.PermissionDecision, output.UpdatedInput)
+		}
+	})
+
+	t.Run("PermissionRequest allow", func(t *testing.T) {
+		output, err := executor.ExecutePermissionRequestAction(Action{
+			Type:          "output",
+			Behavior:      stringPtr("allow"),
+			UpdatedInput:  updatedInput,
+			SystemMessage: stringPtr("auto-approved"),
+		}, &PermissionRequestInput{ToolName: "Bash"}, rawJSON)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if !reflect.DeepEqual(output.UpdatedInput, want) {
+			t.Errorf("UpdatedInput = %v, want %v", output.UpdatedInput, want)
+		}
+		if output.SystemMessage != "auto-approved" {
+			t.Errorf("SystemMessage = %q", output.SystemMessage)
+		}
+	})
+
+	t.Run("PermissionRequest deny ignores updated_input", func(t *testing.T) {
+		output, err := executor.ExecutePermissionRequestAction(Action{
+			Type:         "output",
+			Message:      "no",
+			UpdatedInput: updatedInput,
+		}, &PermissionRequestInput{ToolName: "Bash"}, rawJSON)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if output.Behavior != "deny" || output.UpdatedInput != nil {
+			t.Errorf("Expected deny without updatedInput, got %q / %v", output.Behavior, output.UpdatedInput)
+		}
+	})
+
+	t.Run("PostToolUse system_message", func(t *testing.T) {
+		output, err := executor.ExecutePostToolUseAction(Action{
+			Type:           "output",
+			Message:        "formatted",
+			SystemMessage:  stringPtr("gofmt applied"),
+			SuppressOutput: boolPtr(true),
+		}, &PostToolUseInput{ToolName: "Bash"}, rawJSON)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if output.SystemMessage != "gofmt applied" || !output.SuppressOutput {
+			t.Errorf("Expected system message and suppressOutput, got %q / %v", output.SystemMessage, output.SuppressOutput)
+		}
+	})
+
+	// 元のtool_inputは書き換えない
+	if got := rawJSON["tool_input"].(map[string]any)["command"]; got != "rm -rf build" {
+		t.Errorf("rawJSON tool_input was modified: %v", got)
+	}
+}
+
 // TestExecutePreToolUseAction_TypeCommand tests ExecutePreToolUseAction with type: command (Phase 3)
 
 func TestExecutePreToolUseAction_TypeCommand(t *testing.T) {
@@ -690,7 +938,7 @@ func TestExecutePermissionRequestAction_TypeOutput(t *testing.T) {
 			wantHookEventName: "PermissionRequest",
 		},
 		{
-			name: "behavior: ask -> error (ask is invalid for PermissionRequest)",
+			name: "behavior: ask -> ask with message",
 			action: Action{
 				Type:     "output",
 				Message:  "Please confirm",
@@ -699,9 +947,23 @@ func TestExecutePermissionRequestAction_TypeOutput(t *testing.T) {
 			input: &PermissionRequestInput{
 				ToolName: "Write",
 			},
+			wantBehavior:      "ask",
+			wantMessage:       "Please confirm",
+			wantHookEventName: "PermissionRequest",
+		},
+		{
+			name: "invalid behavior -> error",
+			action: Action{
+				Type:     "output",
+				Message:  "Please confirm",
+				Behavior: stringPtr("maybe"),
+			},
+			input: &PermissionRequestInput{
+				ToolName: "Write",
+			},
 			wantBehavior:      "deny",
-			wantMessage:       "Invalid behavior value in action config: must be 'allow' or 'deny'",
-			wantSystemMessage: "Invalid behavior value in action config: must be 'allow' or 'deny'",
+			wantMessage:       "Invalid behavior value in action config: must be 'allow', 'deny' or 'ask'",
+			wantSystemMessage: "Invalid behavior value in action config: must be 'allow', 'deny' or 'ask'",
 			wantHookEventName: "PermissionRequest",
 		},
 		{
@@ -850,6 +1112,37 @@ func TestExecutePermissionRequestAction_TypeCommand(t *testing.T) {
 			wantSystemMessage: "Command output is missing required field: hookSpecificOutput.decision.behavior",
 			wantHookEventName: "PermissionRequest",
 		},
+		{
+			name: "ask -> ask (ユーザーに確認)",
+			action: Action{
+				Type:    "command",
+				Command: "echo",
+			},
+			input: &PermissionRequestInput{
+				ToolName: "Bash",
+			},
+			stubStdout:        `{"hookSpecificOutput":{"hookEventName":"PermissionRequest","decision":{"behavior":"ask","message":"This deletes files outside the project"}}}`,
+			stubExitCode:      0,
+			wantBehavior:      "ask",
+			wantMessage:       "This deletes files outside the project",
+			wantHookEventName: "PermissionRequest",
+		},
+		{
+			name: "ask時にupdatedInputがある -> deny (semantic validation)",
+			action: Action{
+				Type:    "command",
+				Command: "echo",
+			},
+			input: &PermissionRequestInput{
+				ToolName: "Bash",
+			},
+			stubStdout:        `{"hookSpecificOutput":{"hookEventName":"PermissionRequest","decision":{"behavior":"ask","updatedInput":{"command":"ls"}}}}`,
+			stubExitCode:      0,
+			wantBehavior:      "deny",
+			wantMessage:       "Command output validation failed: semantic validation failed: 'updatedInput' should not exist when behavior is 'ask'",
+			wantSystemMessage: "Command output validation failed: semantic validation failed: 'updatedInput' should not exist when behavior is 'ask'",
+			wantHookEventName: "PermissionRequest",
+		},
 		{
 			name: "allow時にinterruptが立っている -> deny (semantic validation)",
 			action: Action{
@@ -1011,6 +1304,47 @@ func TestExecutePostToolUseAction_TypeOutput(t *testing.T) {
 			wantSystemMessage:     "",
 			wantErr:               false,
 		},
+		{
+			name: "decision: block + reason without message -> reason only",
+			action: Action{
+				Type:     "output",
+				Decision: stringPtr("block"),
+				Reason:   stringPtr("{.tool_name} result needs review"),
+			},
+			wantDecision:          "block",
+			wantReason:            "Write result needs review",
+			wantAdditionalContext: "",
+			wantSystemMessage:     "",
+			wantErr:               false,
+		},
+		{
+			name: "decision: block + model_hint -> hint appended to additionalContext",
+			action: Action{
+				Type:      "output",
+				Message:   "Tests failed",
+				Decision:  stringPtr("block"),
+				Reason:    stringPtr("go test failed"),
+				ModelHint: stringPtr("Fix the failing tests before editing other files"),
+			},
+			wantDecision:          "block",
+			wantReason:            "go test failed",
+			wantAdditionalContext: "Tests failed\nFix the failing tests before editing other files",
+			wantSystemMessage:     "",
+			wantErr:               false,
+		},
+		{
+			name: "model_hint without block -> ignored",
+			action: Action{
+				Type:      "output",
+				Message:   "Tool executed successfully",
+				ModelHint: stringPtr("Fix the failing tests"),
+			},
+			wantDecision:          "",
+			wantReason:            "",
+			wantAdditionalContext: "Tool executed successfully",
+			wantSystemMessage:     "",
+			wantErr:               false,
+		},
 		{
 			name: "decision: block + reason unspecified -> decision=block, reason=processedMessage",
 			action: Action{
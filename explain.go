@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+)
+
+// hookTrace explains whether a single hook runs for an event.
+type hookTrace struct {
+	matcher    string // result of the matcher check ("" if the hook has no matcher)
+	matched    bool   // false if the matcher rejected the event
+	conditions []conditionTrace
+	actions    int
+}
+
+// conditionTrace is the result of a single condition of a hook.
+type conditionTrace struct {
+	condition Condition
+	evaluated bool // false for conditions after the first one that failed (they are short-circuited)
+	matched   bool
+	err       error
+}
+
+// runs reports whether the hook would run its actions.
+func (h hookTrace) runs() bool {
+	if !h.matched {
+		return false
+	}
+	for _, c := range h.conditions {
+		if !c.matched || c.err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// newHookTrace evaluates the conditions of a hook whose matcher check resulted in matched,
+// in the same order and with the same short-circuiting as the actual execution.
+func newHookTrace(matcher string, matched bool, conditions []Condition, actions int, check func(Condition) (bool, error)) hookTrace {
+	trace := hookTrace{matcher: matcher, matched: matched, actions: actions}
+	failed := !matched
+	for _, condition := range conditions {
+		if failed {
+			trace.conditions = append(trace.conditions, conditionTrace{condition: condition})
+			continue
+		}
+		ok, err := check(condition)
+		trace.conditions = append(trace.conditions, conditionTrace{condition: condition, evaluated: true, matched: ok, err: err})
+		failed = !ok || err != nil
+	}
+	return trace
+}
+
+// describeMatcher describes the result of comparing matcher with the input field.
+func describeMatcher(matcher, field, value string, matched bool) string {
+	if matcher == "" {
+		return ""
+	}
+	if matched {
+		return fmt.Sprintf("matcher %q matched %s %q", matcher, field, value)
+	}
+	return fmt.Sprintf("matcher %q did not match %s %q", matcher, field, value)
+}
+
+// toolHookTrace explains a PreToolUse/PostToolUse/PermissionRequest hook.
+func toolHookTrace(matcher string, excludeTools []string, mcpServer string, toolName string, conditions []Condition, actions int, check func(Condition) (bool, error)) hookTrace {
+	if slices.Contains(excludeTools, toolName) {
+		return newHookTrace(fmt.Sprintf("tool_name %q is in exclude_tools", toolName), false, conditions, actions, check)
+	}
+	if !checkMCPServerMatcher(mcpServer, toolName) {
+		return newHookTrace(fmt.Sprintf("tool_name %q is not a tool of mcp_server %q", toolName, mcpServer), false, conditions, actions, check)
+	}
+	matched := checkMatcher(matcher, toolName)
+	return newHookTrace(describeMatcher(matcher, "tool_name", toolName, matched), matched, conditions, actions, check)
+}
+
+// explainHooksFrom parses the event read from r and writes why each hook of eventType runs or not,
+// followed by the JSON output the event would produce.
+func explainHooksFrom(w io.Writer, r io.Reader, config *Config, eventType HookEventType) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+	traces, err := traceHooks(bytes.NewReader(data), config, eventType)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "=== %s Hooks (Explain) ===\n", eventType)
+	if len(traces) == 0 {
+		fmt.Fprintf(w, "No %s hooks configured\n", eventType)
+	}
+	ids := hookIDs(config, eventType)
+	for i, trace := range traces {
+		writeHookTrace(w, i, ids[i], trace)
+	}
+
+	output, err := explainOutput(data, config, eventType)
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+	}
+	jsonBytes, err := marshalOutput(config.OutputFormat, output)
+	if err != nil {
+		return fmt.Errorf("failed to marshal output: %w", err)
+	}
+	fmt.Fprintln(w, "\n=== Output (command and http actions assumed to succeed without output) ===")
+	fmt.Fprintln(w, string(jsonBytes))
+	return nil
+}
+
+// writeHookTrace writes the trace of the hook at index i (shown 1-based, like dry-run) with the given ID.
+func writeHookTrace(w io.Writer, i int, id string, trace hookTrace) {
+	fmt.Fprintf(w, "[Hook %d id=%s]", i+1, id)
+	if trace.matcher != "" {
+		fmt.Fprintf(w, " %s", trace.matcher)
+	}
+	fmt.Fprintln(w)
+
+	for _, c := range trace.conditions {
+		label := conditionLabel(c.condition)
+		switch {
+		case !c.evaluated:
+			fmt.Fprintf(w, "  condition %s: not evaluated\n", label)
+		case c.err != nil:
+			fmt.Fprintf(w, "  condition %s: error: %v\n", label, c.err)
+		case c.matched:
+			fmt.Fprintf(w, "  condition %s: matched\n", label)
+		default:
+			fmt.Fprintf(w, "  condition %s: not met\n", label)
+		}
+	}
+
+	if trace.runs() {
+		fmt.Fprintf(w, "  -> would run %d action(s)\n", trace.actions)
+	} else {
+		fmt.Fprintln(w, "  -> skipped")
+	}
+}
+
+// conditionLabel describes a condition by its type and value, e.g. `file_extension ".go"`.
+func conditionLabel(condition Condition) string {
+	label := condition.Type.String()
+	switch {
+	case condition.Value != "":
+		label += fmt.Sprintf(" %q", condition.Value)
+	case len(condition.Values) > 0:
+		label += fmt.Sprintf(" %q", condition.Values)
+	case len(condition.Conditions) > 0:
+		label += fmt.Sprintf(" (%d conditions)", len(condition.Conditions))
+	}
+	return label
+}
+
+// traceHooks parses the event read from r and explains every hook of eventType.
+func traceHooks(r io.Reader, config *Config, eventType HookEventType) ([]hookTrace, error) {
+	return traceHooksWith(r, config, eventType, func(_ int, check conditionCheck) conditionCheck { return check })
+}
+
+// conditionCheck evaluates a condition of a hook against the event.
+type conditionCheck func(Condition) (bool, error)
+
+// traceHooksWith is traceHooks with the condition check of the hook at each index wrapped by observe
+// (e.g. to time the conditions).
+func traceHooksWith(r io.Reader, config *Config, eventType HookEventType, observe func(hook int, check conditionCheck) conditionCheck) ([]hookTrace, error) {
+	var traces []hookTrace
+	switch eventType {
+	case PreToolUse:
+		input, _, err := parseInputFrom[*PreToolUseInput](r, eventType)
+		if err != nil {
+			return nil, err
+		}
+		for i, hook := range config.PreToolUse {
+			traces = append(traces, toolHookTrace(hook.Matcher, hook.ExcludeTools, hook.MCPServer, input.ToolName, hook.Conditions, len(hook.Actions),
+				observe(i, func(c Condition) (bool, error) { return checkPreToolUseCondition(c, input) })))
+		}
+	case PostToolUse:
+		input, _, err := parseInputFrom[*PostToolUseInput](r, eventType)
+		if err != nil {
+			return nil, err
+		}
+		for i, hook := range config.PostToolUse {
+			traces = append(traces, toolHookTrace(hook.Matcher, hook.ExcludeTools, hook.MCPServer, input.ToolName, hook.Conditions, len(hook.Actions),
+				observe(i, func(c Condition) (bool, error) { return checkPostToolUseCondition(c, input) })))
+		}
+	case PermissionRequest:
+		input, _, err := parseInputFrom[*PermissionRequestInput](r, eventType)
+		if err != nil {
+			return nil, err
+		}
+		for i, hook := range config.PermissionRequest {
+			traces = append(traces, toolHookTrace(hook.Matcher, hook.ExcludeTools, hook.MCPServer, input.ToolName, hook.Conditions, len(hook.Actions),
+				observe(i, func(c Condition) (bool, error) { return checkPermissionRequestCondition(c, input) })))
+		}
+	case Notification:
+		input, _, err := parseInputFrom[*NotificationInput](r, eventType)
+		if err != nil {
+			return nil, err
+		}
+		for i, hook := range config.Notification {
+			matched := checkNotificationMatcher(hook.Matcher, input.NotificationType)
+			traces = append(traces, newHookTrace(describeMatcher(hook.Matcher, "notification_type", input.NotificationType, matched), matched, hook.Conditions, len(hook.Actions),
+				observe(i, func(c Condition) (bool, error) { return checkNotificationCondition(c, input) })))
+		}
+	case Stop:
+		input, _, err := parseInputFrom[*StopInput](r, eventType)
+		if err != nil {
+			return nil, err
+		}
+		for i, hook := range config.Stop {
+			traces = append(traces, newHookTrace("", true, hook.Conditions, len(hook.Actions),
+				observe(i, func(c Condition) (bool, error) { return checkStopCondition(c, input) })))
+		}
+	case SubagentStop:
+		input, _, err := parseInputFrom[*SubagentStopInput](r, eventType)
+		if err != nil {
+			return nil, err
+		}
+		for i, hook := range config.SubagentStop {
+			matched := checkMatcher(hook.Matcher, input.AgentType)
+			traces = append(traces, newHookTrace(describeMatcher(hook.Matcher, "agent_type", input.AgentType, matched), matched, hook.Conditions, len(hook.Actions),
+				observe(i, func(c Condition) (bool, error) { return checkSubagentStopCondition(c, input) })))
+		}
+	case SubagentStart:
+		input, _, err := parseInputFrom[*SubagentStartInput](r, eventType)
+		if err != nil {
+			return nil, err
+		}
+		for i, hook := range config.SubagentStart {
+			matched := checkMatcher(hook.Matcher, input.AgentType)
+			traces = append(traces, newHookTrace(describeMatcher(hook.Matcher, "agent_type", input.AgentType, matched), matched, hook.Conditions, len(hook.Actions),
+				observe(i, func(c Condition) (bool, error) { return checkSubagentStartCondition(c, input) })))
+		}
+	case PreCompact:
+		input, _, err := parseInputFrom[*PreCompactInput](r, eventType)
+		if err != nil {
+			return nil, err
+		}
+		for i, hook := range config.PreCompact {
+			matched := hook.Matcher == "" || hook.Matcher == input.Trigger
+			traces = append(traces, newHookTrace(describeMatcher(hook.Matcher, "trigger", input.Trigger, matched), matched, hook.Conditions, len(hook.Actions),
+				observe(i, func(c Condition) (bool, error) { return checkPreCompactCondition(c, input) })))
+		}
+	case SessionStart:
+		input, _, err := parseInputFrom[*SessionStartInput](r, eventType)
+		if err != nil {
+			return nil, err
+		}
+		for i, hook := range config.SessionStart {
+			matched := hook.Matcher == "" || hook.Matcher == input.Source
+			traces = append(traces, newHookTrace(describeMatcher(hook.Matcher, "source", input.Source, matched), matched, hook.Conditions, len(hook.Actions),
+				observe(i, func(c Condition) (bool, error) { return checkSessionStartCondition(c, input) })))
+		}
+	case SessionEnd:
+		input, _, err := parseInputFrom[*SessionEndInput](r, eventType)
+		if err != nil {
+			return nil, err
+		}
+		for i, hook := range config.SessionEnd {
+			traces = append(traces, newHookTrace("", true, hook.Conditions, len(hook.Actions),
+				observe(i, func(c Condition) (bool, error) { return checkSessionEndCondition(c, input) })))
+		}
+	case UserPromptSubmit:
+		input, _, err := parseInputFrom[*UserPromptSubmitInput](r, eventType)
+		if err != nil {
+			return nil, err
+		}
+		for i, hook := range config.UserPromptSubmit {
+			traces = append(traces, newHookTrace("", true, hook.Conditions, len(hook.Actions),
+				observe(i, func(c Condition) (bool, error) { return checkUserPromptSubmitCondition(c, input) })))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported event type: %s", eventType)
+	}
+	return traces, nil
+}
+
+// explainCommandRunner is a CommandRunner that never runs commands and reports success without output.
+type explainCommandRunner struct{}
+
+// RunCommand implements CommandRunner.RunCommand
+func (explainCommandRunner) RunCommand(cmd string, useStdin bool, data any) error {
+	return nil
+}
+
+// RunCommandWithOutput implements CommandRunner.RunCommandWithOutput
+func (explainCommandRunner) RunCommandWithOutput(cmd string, useStdin bool, data any) (stdout, stderr string, exitCode int, err error) {
+	return "", "", 0, nil
+}
+
+// explainHTTPClient is an HTTPClient that never sends requests and answers 204 No Content.
+type explainHTTPClient struct{}
+
+// Do implements HTTPClient.Do
+func (explainHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		Status:     "204 No Content",
+		StatusCode: http.StatusNoContent,
+		Header:     http.Header{},
+		Body:       http.NoBody,
+		Request:    req,
+	}, nil
+}
+
+// explainOutput executes the hooks with command and http actions stubbed out and without side effects,
+// and returns the event's JSON output. Warnings printed by the executors go to stderr as usual.
+func explainOutput(data []byte, config *Config, eventType HookEventType) (any, error) {
+	originalRunner, originalHTTPClient := DefaultCommandRunner, DefaultHTTPClient
+	defer func() { DefaultCommandRunner, DefaultHTTPClient = originalRunner, originalHTTPClient }()
+	DefaultCommandRunner, DefaultHTTPClient = explainCommandRunner{}, explainHTTPClient{}
+
+	return executeHooksFrom(bytes.NewReader(data), config, eventType, false)
+}
+
+// explainHooks reads the event from stdin and writes the explanation to stdout.
+func explainHooks(config *Config, eventType HookEventType) error {
+	r, err := stdinReader()
+	if err != nil {
+		return err
+	}
+	return explainHooksFrom(os.Stdout, r, config, eventType)
+}
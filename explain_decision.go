@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// traceRecord is a record of the `log:` file (json format), as written by hookLog.
+type traceRecord struct {
+	Time       time.Time       `json:"time"`
+	Level      string          `json:"level"`
+	Msg        string          `json:"msg"`
+	Event      string          `json:"event"`
+	SessionID  string          `json:"session_id"`
+	Pid        int             `json:"pid"`
+	TraceID    string          `json:"trace_id"`
+	Hook       int             `json:"hook"`
+	HookID     string          `json:"hook_id"`
+	Matcher    string          `json:"matcher"`
+	Type       string          `json:"type"`
+	Value      string          `json:"value"`
+	Matched    bool            `json:"matched"`
+	Command    string          `json:"command"`
+	Method     string          `json:"method"`
+	URL        string          `json:"url"`
+	Policy     string          `json:"policy"`
+	Query      string          `json:"query"`
+	ExitCode   int             `json:"exit_code"`
+	DurationMS int64           `json:"duration_ms"`
+	Error      string          `json:"error"`
+	Input      json.RawMessage `json:"input"`
+	Output     json.RawMessage `json:"output"`
+}
+
+// traceHook is a hook of a traced event with what was recorded about it.
+type traceHook struct {
+	Index   int
+	ID      string
+	Matched bool
+	Lines   []string
+}
+
+// loadTrace returns the records of the event whose trace ID starts with prefix, from the log file at path.
+func loadTrace(path, prefix string) ([]traceRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log: %w", err)
+	}
+	defer f.Close()
+
+	var records []traceRecord
+	var ids []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if !bytes.Contains(line, []byte(`"trace_id":"`+prefix)) {
+			continue
+		}
+		var record traceRecord
+		// textフォーマットの行や書き込み途中の行は読み飛ばす
+		if json.Unmarshal(line, &record) != nil || !strings.HasPrefix(record.TraceID, prefix) {
+			continue
+		}
+		if !slices.Contains(ids, record.TraceID) {
+			ids = append(ids, record.TraceID)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read log: %w", err)
+	}
+	switch {
+	case len(ids) == 0:
+		return nil, fmt.Errorf("trace %q not found in %s (only events run with `log:` in json format are recorded)", prefix, path)
+	case len(ids) > 1:
+		return nil, fmt.Errorf("trace ID prefix %q is ambiguous (%s)", prefix, strings.Join(ids, ", "))
+	}
+	return records, nil
+}
+
+// traceActionLine describes an action run recorded in the log.
+func traceActionLine(r traceRecord) string {
+	var target string
+	switch r.Type {
+	case "http":
+		target = r.Method + " " + r.URL
+	case "opa":
+		target = fmt.Sprintf("%s (query %s)", r.Policy, r.Query)
+	default:
+		target = r.Command
+	}
+	line := fmt.Sprintf("action %s: %s -> exit %d (%dms)", r.Type, target, r.ExitCode, r.DurationMS)
+	if r.Error != "" {
+		line += ": " + r.Error
+	}
+	return line
+}
+
+// writeIndentedJSON writes data indented by 2 spaces under a heading line.
+func writeIndentedJSON(w io.Writer, data json.RawMessage) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "  ", "  "); err != nil {
+		buf.Reset()
+		buf.Write(data)
+	}
+	fmt.Fprintf(w, "  %s\n", buf.String())
+}
+
+// runExplainDecision reconstructs the event with the given trace ID (or a unique prefix of it) from the log
+// of the `log:` block: the input, the hooks evaluated with their conditions and actions, and the final output.
+// Conditions, skipped hooks and the input are only recorded with log level debug.
+func runExplainDecision(w io.Writer, config *Config, args []string) error {
+	if len(args) != 1 || args[0] == "" {
+		return errors.New("usage: cchook -command explain-decision <trace-id>")
+	}
+	logConfig := config.Log
+	if logConfig == nil {
+		logConfig = &LogConfig{}
+	}
+	records, err := loadTrace(hookLogPath(logConfig), args[0])
+	if err != nil {
+		return err
+	}
+
+	first := records[0]
+	var input, output json.RawMessage
+	var duration int64
+	var hooks []*traceHook
+	var current *traceHook
+	var pending, other []string
+	debug := false
+	for _, r := range records {
+		switch r.Msg {
+		case "event input":
+			input, debug = r.Input, true
+		case "event finished":
+			output, duration = r.Output, r.DurationMS
+		case "matcher did not match":
+			pending, debug = append(pending, fmt.Sprintf("matcher %q did not match %q", r.Matcher, r.Value)), true
+		case "condition evaluated":
+			pending, debug = append(pending, fmt.Sprintf("condition %s %q: %v", r.Type, r.Value, r.Matched)), true
+		case "condition error":
+			pending = append(pending, fmt.Sprintf("condition %s %q: error: %s", r.Type, r.Value, r.Error))
+		case "hook matched", "hook skipped":
+			current = &traceHook{Index: r.Hook, ID: r.HookID, Matched: r.Msg == "hook matched", Lines: pending}
+			hooks, pending = append(hooks, current), nil
+			if !current.Matched {
+				current, debug = nil, true
+			}
+		case "hook disabled":
+			other = append(other, fmt.Sprintf("hook id=%s disabled", r.HookID))
+		case "action finished", "action failed":
+			if current != nil {
+				current.Lines = append(current.Lines, traceActionLine(r))
+			} else {
+				other = append(other, traceActionLine(r))
+			}
+		case "event started":
+		default:
+			if r.Error != "" {
+				other = append(other, fmt.Sprintf("%s: %s", r.Msg, r.Error))
+			} else {
+				other = append(other, r.Msg)
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "Trace:    %s\n", first.TraceID)
+	fmt.Fprintf(w, "Event:    %s (session %s, pid %d)\n", first.Event, first.SessionID, first.Pid)
+	fmt.Fprintf(w, "Time:     %s\n", first.Time.Format(time.RFC3339))
+	if output != nil {
+		decision, reason := eventDecision(HookEventType(first.Event), output)
+		if reason != "" {
+			decision += ": " + reason
+		}
+		fmt.Fprintf(w, "Duration: %dms\n", duration)
+		fmt.Fprintf(w, "Decision: %s\n", decision)
+	}
+
+	fmt.Fprintln(w, "\nInput:")
+	if input != nil {
+		writeIndentedJSON(w, input)
+	} else {
+		fmt.Fprintln(w, "  (not recorded)")
+	}
+
+	fmt.Fprintln(w, "\nHooks:")
+	if len(hooks) == 0 {
+		fmt.Fprintln(w, "  (no hooks matched)")
+	}
+	for _, hook := range hooks {
+		state := "skipped"
+		if hook.Matched {
+			state = "matched"
+		}
+		fmt.Fprintf(w, "  [Hook %d id=%s] %s\n", hook.Index+1, hook.ID, state)
+		for _, line := range hook.Lines {
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+	}
+	if other = append(pending, other...); len(other) > 0 {
+		fmt.Fprintln(w, "\nOther records:")
+		for _, line := range other {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
+
+	fmt.Fprintln(w, "\nOutput:")
+	if output != nil {
+		writeIndentedJSON(w, output)
+	} else {
+		fmt.Fprintln(w, "  (not recorded: the event did not finish)")
+	}
+	if !debug {
+		fmt.Fprintln(w, "\nNote: the input, conditions and skipped hooks are only recorded with log level debug")
+	}
+	return nil
+}
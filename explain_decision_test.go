@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunExplainDecision(t *testing.T) {
+	t.Cleanup(func() {
+		resetHookLog()
+		prefetchedInput = nil
+		eventTraceID = ""
+	})
+	withStdin(t, `{"session_id":"s1","hook_event_name":"Stop"}`)
+	if _, err := prefetchInput(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "cchook.log")
+	logConfig := &LogConfig{Path: path, Level: "debug"}
+	// 別のイベントの記録は無視する
+	if err := os.WriteFile(path, []byte(`{"msg":"event started","trace_id":"ffff000000000000"}`+"\nnot json\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	eventTraceID = "abcd123456789012"
+	if err := setupHookLog(logConfig, Stop); err != nil {
+		t.Fatalf("setupHookLog() error = %v", err)
+	}
+	config := &Config{Log: logConfig, Stop: []StopHook{
+		{
+			Conditions: []Condition{{Type: ConditionFileExists, Value: "does-not-exist"}},
+			Actions:    []Action{{Type: "command", Command: "echo never"}},
+		},
+		{Actions: []Action{{Type: "command", Command: "echo oops >&2; exit 3"}}},
+	}}
+	if _, err := executeStopHooks(config, &StopInput{}, map[string]any{}); err != nil {
+		t.Fatalf("executeStopHooks() error = %v", err)
+	}
+	logOutput([]byte(`{"decision":"block","reason":"tests failed"}`))
+
+	var out bytes.Buffer
+	if err := runExplainDecision(&out, config, []string{"abcd"}); err != nil {
+		t.Fatalf("runExplainDecision() error = %v", err)
+	}
+	for _, want := range []string{
+		"Trace:    abcd123456789012",
+		"Event:    Stop (session s1, pid ",
+		"Decision: block: tests failed",
+		`"session_id": "s1"`,
+		"] skipped\n    condition file_exists \"does-not-exist\": false\n",
+		"[Hook 2 id=",
+		`action command: echo oops >&2; exit 3 -> exit 3 (`,
+		"Output:\n  {\n    \"decision\": \"block\",",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Output should contain %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "log level debug") {
+		t.Errorf("Unexpected note about the log level:\n%s", out.String())
+	}
+
+	if err := runExplainDecision(&out, config, []string{"0123"}); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("runExplainDecision() error = %v, want not found", err)
+	}
+	if err := runExplainDecision(&out, config, nil); err == nil || !strings.Contains(err.Error(), "usage") {
+		t.Errorf("runExplainDecision() error = %v, want usage", err)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExplainHooksFrom_PreToolUse(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "marker")
+	config := &Config{
+		PreToolUse: []PreToolUseHook{
+			{
+				Matcher: "Bash",
+				Actions: []Action{{Type: "output", Message: "bash", PermissionDecision: stringPtr("deny")}},
+			},
+			{
+				Matcher: "Write|Edit",
+				Conditions: []Condition{
+					{Type: ConditionFileExtension, Value: ".py"},
+					{Type: ConditionFileExtension, Value: ".go"},
+				},
+				Actions: []Action{{Type: "output", Message: "python", PermissionDecision: stringPtr("deny")}},
+			},
+			{
+				Matcher:      "Write",
+				ExcludeTools: []string{"Write"},
+				Actions:      []Action{{Type: "output", Message: "excluded", PermissionDecision: stringPtr("deny")}},
+			},
+			{
+				Matcher:    "Write",
+				Conditions: []Condition{{Type: ConditionFileExtension, Value: ".go"}},
+				Actions: []Action{
+					{Type: "command", Command: "touch " + marker},
+					{Type: "output", Message: "writing {.tool_input.file_path}", PermissionDecision: stringPtr("ask")},
+				},
+			},
+		},
+	}
+	input := `{"session_id":"s1","hook_event_name":"PreToolUse","tool_name":"Write","tool_input":{"file_path":"main.go"}}`
+
+	var out bytes.Buffer
+	if err := explainHooksFrom(&out, strings.NewReader(input), config, PreToolUse); err != nil {
+		t.Fatalf("explainHooksFrom() error = %v", err)
+	}
+
+	ids := hookIDs(config, PreToolUse)
+	want := `=== PreToolUse Hooks (Explain) ===
+[Hook 1 id=` + ids[0] + `] matcher "Bash" did not match tool_name "Write"
+  -> skipped
+[Hook 2 id=` + ids[1] + `] matcher "Write|Edit" matched tool_name "Write"
+  condition file_extension ".py": not met
+  condition file_extension ".go": not evaluated
+  -> skipped
+[Hook 3 id=` + ids[2] + `] tool_name "Write" is in exclude_tools
+  -> skipped
+[Hook 4 id=` + ids[3] + `] matcher "Write" matched tool_name "Write"
+  condition file_extension ".go": matched
+  -> would run 2 action(s)
+
+=== Output (command and http actions assumed to succeed without output) ===
+{
+  "continue": true,
+  "hookSpecificOutput": {
+    "hookEventName": "PreToolUse",
+    "permissionDecision": "ask",
+    "permissionDecisionReason": "writing main.go"
+  }
+}
+`
+	if got := out.String(); got != want {
+		t.Errorf("explainHooksFrom() output:\n%s\nwant:\n%s", got, want)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("Expected command actions not to be run")
+	}
+}
+
+func TestExplainHooksFrom_ConditionError(t *testing.T) {
+	config := &Config{
+		Stop: []StopHook{{
+			Conditions: []Condition{{Type: ConditionSessionDurationGt, Value: "soon"}},
+			Actions:    []Action{{Type: "output", Message: "x", Decision: stringPtr("block")}},
+		}},
+	}
+
+	var out bytes.Buffer
+	if err := explainHooksFrom(&out, strings.NewReader(`{"session_id":"s1","hook_event_name":"Stop"}`), config, Stop); err != nil {
+		t.Fatalf("explainHooksFrom() error = %v", err)
+	}
+	id := hookIDs(config, Stop)[0]
+	for _, want := range []string{
+		"[Hook 1 id=" + id + "]\n",
+		`condition session_duration_gt "soon": error: `,
+		"  -> skipped\n",
+		"Error: hook[Stop][id=" + id + "]: invalid duration",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Output should contain %q, got:\n%s", want, out.String())
+		}
+	}
+
+	if err := explainHooksFrom(&out, strings.NewReader(`{`), config, Stop); err == nil {
+		t.Error("Expected an error for invalid input")
+	}
+}
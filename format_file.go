@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// formatFileMaxOutput is the number of lines of a failed formatter's output reported to Claude.
+const formatFileMaxOutput = 20
+
+// defaultFormatters are the formatter commands of format_file actions by file extension, in order of
+// preference: the first one installed is used. The file path is appended to the command.
+var defaultFormatters = map[string][]string{
+	".go":  {"goimports -w", "gofmt -w"},
+	".py":  {"black -q"},
+	".pyi": {"black -q"},
+	".rs":  {"rustfmt"},
+}
+
+// prettierExtensions are the extensions formatted with prettier (the project's node_modules/.bin/prettier if installed).
+var prettierExtensions = []string{
+	".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx", ".mts", ".cts", ".json", ".css", ".scss", ".less",
+	".html", ".vue", ".md", ".mdx", ".yaml", ".yml", ".graphql",
+}
+
+func init() {
+	for _, ext := range prettierExtensions {
+		defaultFormatters[ext] = []string{"prettier --write --log-level warn"}
+	}
+}
+
+// fileToolTarget is the formatter or linter a format_file or lint_feedback action runs for a file.
+type fileToolTarget struct {
+	Name    string // フォーマッタ・リンタの名前 (goimports, prettier, ruff, ...)
+	Display string // Claudeとdry-runに見せるコマンド
+	Command string // 実際に実行するシェルコマンド
+}
+
+// formatFileTargetFor returns the formatter for the file at path: the formatters entry of its extension
+// (an empty command disables formatting), otherwise the first installed default formatter.
+// missing lists the formatters that were not installed when none could be used;
+// both are empty for files without a formatter.
+func formatFileTargetFor(path string, formatters map[string]string) (target *fileToolTarget, missing []string) {
+	return fileToolTargetFor(path, defaultFormatters, formatters)
+}
+
+// fileToolTargetFor returns the command for the file at path: the overrides entry of its extension
+// (an empty command disables it), otherwise the first installed command of defaults, with the quoted path appended.
+// missing lists the programs that were not installed when none could be used.
+func fileToolTargetFor(path string, defaults map[string][]string, overrides map[string]string) (target *fileToolTarget, missing []string) {
+	if path == "" {
+		return nil, nil
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	candidates := defaults[ext]
+	for key, command := range overrides {
+		if strings.EqualFold(normalizeToolExtension(key), ext) {
+			candidates = []string{command}
+			break
+		}
+	}
+
+	for _, command := range candidates {
+		fields := strings.Fields(command)
+		if len(fields) == 0 {
+			return nil, nil
+		}
+		name := filepath.Base(fields[0])
+		program, ok := findFileTool(fields[0], filepath.Dir(path))
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		parts := append([]string{shellQuote(program)}, fields[1:]...)
+		return &fileToolTarget{
+			Name:    name,
+			Display: command + " " + path,
+			Command: strings.Join(append(parts, shellQuote(path)), " "),
+		}, nil
+	}
+	return nil, missing
+}
+
+// normalizeToolExtension adds the leading dot to an extension of the formatters or linters field.
+func normalizeToolExtension(ext string) string {
+	if strings.HasPrefix(ext, ".") {
+		return ext
+	}
+	return "." + ext
+}
+
+// nodeFileTools are the formatters and linters usually installed in the project's node_modules.
+var nodeFileTools = []string{"prettier", "eslint"}
+
+// findFileTool returns the path of a formatter or linter program: the project's node_modules/.bin
+// (searched upward from dir) for prettier and eslint, otherwise PATH.
+func findFileTool(program, dir string) (string, bool) {
+	if slices.Contains(nodeFileTools, program) {
+		bin := filepath.Join("node_modules", ".bin", program)
+		if root, ok := findUpward(dir, bin); ok {
+			return filepath.Join(root, bin), true
+		}
+	}
+	path, err := exec.LookPath(program)
+	return path, err == nil
+}
+
+// formatFile runs the formatter of the file at filePath (shown to the user as displayPath) for a format_file action.
+// The user is told in systemMessage when the file was reformatted or its formatter is not installed.
+// When the formatter fails, the end of its output (usually a syntax error) is reported to Claude
+// as additionalContext, or blocks with decision: block. Files without a formatter are ignored.
+func (e *ActionExecutor) formatFile(action Action, filePath, displayPath string, rawJSON any) *ActionOutput {
+	target, missing := formatFileTargetFor(filePath, action.Formatters)
+	if target == nil {
+		if len(missing) == 0 {
+			return nil
+		}
+		return &ActionOutput{
+			Continue:      true,
+			HookEventName: "PostToolUse",
+			SystemMessage: fmt.Sprintf("format_file: %s was not formatted: %s not installed", displayPath, formatterList(missing)),
+		}
+	}
+
+	before, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil
+	}
+	stdout, stderr, exitCode, err := e.runAction(Action{Type: "format_file", Command: target.Command}, rawJSON)
+	if exitCode == 127 || (err != nil && stdout == "" && stderr == "") {
+		return &ActionOutput{
+			Continue:      true,
+			HookEventName: "PostToolUse",
+			SystemMessage: fmt.Sprintf("format_file: %s was not formatted: %s could not run", displayPath, target.Name),
+		}
+	}
+	if exitCode == 0 {
+		after, err := os.ReadFile(filePath)
+		if err != nil || bytes.Equal(before, after) {
+			return nil
+		}
+		return &ActionOutput{
+			Continue:          true,
+			HookEventName:     "PostToolUse",
+			SystemMessage:     fmt.Sprintf("format_file: formatted %s with %s", displayPath, target.Name),
+			AdditionalContext: fmt.Sprintf("%s was reformatted by %s; read it again before editing it.", displayPath, target.Name),
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout+"\n"+stderr), "\n")
+	if len(lines) > formatFileMaxOutput {
+		lines = lines[len(lines)-formatFileMaxOutput:]
+	}
+	failure := fmt.Sprintf("%s failed on %s (exit code %d):\n%s", target.Name, displayPath, exitCode, strings.Join(lines, "\n"))
+	output := &ActionOutput{
+		Continue:      true,
+		HookEventName: "PostToolUse",
+		SystemMessage: fmt.Sprintf("format_file: %s failed on %s", target.Name, displayPath),
+	}
+	if action.Decision != nil && *action.Decision == "block" {
+		output.Decision = "block"
+		output.Reason = failure
+	} else {
+		output.AdditionalContext = failure
+	}
+	return output
+}
+
+// formatterList joins the names of formatters for a message ("goimports and gofmt are", "black is").
+func formatterList(names []string) string {
+	if len(names) == 1 {
+		return names[0] + " is"
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1] + " are"
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormatFileTargetFor(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PATH", dir)
+	for _, name := range []string{"gofmt", "black"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	project := filepath.Join(dir, "web")
+	if err := os.MkdirAll(filepath.Join(project, "node_modules", ".bin"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(project, "node_modules", ".bin", "prettier"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		formatters  map[string]string
+		wantCommand string
+		wantMissing []string
+	}{
+		{"falls back to gofmt", "/src/main.go", nil, shellQuote(filepath.Join(dir, "gofmt")) + " -w '/src/main.go'", nil},
+		{"black", "/src/app.py", nil, shellQuote(filepath.Join(dir, "black")) + " -q '/src/app.py'", nil},
+		{"project prettier", filepath.Join(project, "src", "app.tsx"), nil, shellQuote(filepath.Join(project, "node_modules", ".bin", "prettier")) + " --write --log-level warn " + shellQuote(filepath.Join(project, "src", "app.tsx")), nil},
+		{"prettier not installed", "/src/app.ts", nil, "", []string{"prettier"}},
+		{"rustfmt not installed", "/src/main.rs", nil, "", []string{"rustfmt"}},
+		{"no formatter", "/src/notes.txt", nil, "", nil},
+		{"override", "/src/main.go", map[string]string{"go": "black --fast"}, shellQuote(filepath.Join(dir, "black")) + " --fast '/src/main.go'", nil},
+		{"disabled", "/src/app.py", map[string]string{".py": ""}, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, missing := formatFileTargetFor(tt.path, tt.formatters)
+			command := ""
+			if target != nil {
+				command = target.Command
+			}
+			if command != tt.wantCommand || strings.Join(missing, ",") != strings.Join(tt.wantMissing, ",") {
+				t.Errorf("formatFileTargetFor() = %q, %v; want %q, %v", command, missing, tt.wantCommand, tt.wantMissing)
+			}
+		})
+	}
+}
+
+func TestExecutePostToolUseAction_FormatFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+	formatter := filepath.Join(dir, "fmt.sh")
+	// 中身にsyntaxがあれば失敗し、それ以外はformattedを書き込む
+	script := "#!/bin/sh\nif grep -q syntax \"$1\"; then echo \"$1:1:1: syntax error\" >&2; exit 2; fi\necho formatted > \"$1\"\n"
+	if err := os.WriteFile(formatter, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	executor := NewActionExecutor(nil)
+	input := &PostToolUseInput{BaseInput: BaseInput{Cwd: dir}, ToolName: "Write", ToolInput: ToolInput{FilePath: "main.go"}}
+	action := Action{Type: "format_file", Formatters: map[string]string{".go": formatter}}
+
+	run := func(content string, action Action) *ActionOutput {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		output, err := executor.ExecutePostToolUseAction(action, input, map[string]any{"cwd": dir})
+		if err != nil {
+			t.Fatalf("ExecutePostToolUseAction() error = %v", err)
+		}
+		return output
+	}
+
+	if output := run("package main\n", action); output == nil || output.SystemMessage != "format_file: formatted main.go with fmt.sh" || !strings.Contains(output.AdditionalContext, "read it again") {
+		t.Errorf("Unexpected output for a reformatted file: %+v", output)
+	}
+	if output := run("formatted\n", action); output != nil {
+		t.Errorf("Expected no output for an already formatted file, got %+v", output)
+	}
+	if output := run("syntax", action); output == nil || output.Decision != "" || !strings.Contains(output.AdditionalContext, "fmt.sh failed on main.go (exit code 2):\n") || !strings.Contains(output.AdditionalContext, "syntax error") {
+		t.Errorf("Unexpected output for a failed formatter: %+v", output)
+	}
+	block := action
+	block.Decision = stringPtr("block")
+	if output := run("syntax", block); output == nil || output.Decision != "block" || !strings.Contains(output.Reason, "syntax error") {
+		t.Errorf("Expected decision: block to block on failure, got %+v", output)
+	}
+
+	missing := Action{Type: "format_file", Formatters: map[string]string{".go": "no-such-formatter -w"}}
+	if output := run("package main\n", missing); output == nil || output.SystemMessage != "format_file: main.go was not formatted: no-such-formatter is not installed" {
+		t.Errorf("Unexpected output for a missing formatter: %+v", output)
+	}
+}
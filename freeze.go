@@ -0,0 +1,177 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// freezeWindow is an entry of a freeze windows file: a date range (start/end), recurring days
+// (weekdays, day_of_week syntax) and hours (time, time_between syntax), each optional but not all.
+type freezeWindow struct {
+	Name     string `yaml:"name"`
+	Reason   string `yaml:"reason"`
+	Start    string `yaml:"start"`    // 日付 (その日の0時から) かRFC 3339の時刻
+	End      string `yaml:"end"`      // 日付 (その日を含む) かRFC 3339の時刻 (含まない)
+	Weekdays string `yaml:"weekdays"` // Fri, Sat-Sun, ...
+	Time     string `yaml:"time"`     // HH:MM-HH:MM
+}
+
+// parsedFreezeWindow is a freezeWindow with its fields parsed in the condition's time zone.
+type parsedFreezeWindow struct {
+	freezeWindow
+	start, end           time.Time // zero when open
+	days                 *[7]bool  // nil for every day
+	fromMinute, toMinute int
+	hasTime              bool
+}
+
+// parseFreezeWindows parses a freeze windows file: a YAML list of freeze windows, whose dates are in loc.
+func parseFreezeWindows(data []byte, loc *time.Location) ([]parsedFreezeWindow, error) {
+	var entries []freezeWindow
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid freeze windows: %w", err)
+	}
+	windows := make([]parsedFreezeWindow, 0, len(entries))
+	for i, entry := range entries {
+		window, err := parseFreezeWindow(entry, loc)
+		if err != nil {
+			label := fmt.Sprintf("window %d", i+1)
+			if entry.Name != "" {
+				label += fmt.Sprintf(" (%s)", entry.Name)
+			}
+			return nil, fmt.Errorf("%s: %w", label, err)
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+// parseFreezeWindow parses the fields of a single freeze window.
+func parseFreezeWindow(entry freezeWindow, loc *time.Location) (parsedFreezeWindow, error) {
+	window := parsedFreezeWindow{freezeWindow: entry}
+	if entry.Start == "" && entry.End == "" && entry.Weekdays == "" {
+		return window, errors.New("requires start, end or weekdays")
+	}
+	var err error
+	if entry.Start != "" {
+		if window.start, err = parseFreezeTime(entry.Start, loc, false); err != nil {
+			return window, err
+		}
+	}
+	if entry.End != "" {
+		if window.end, err = parseFreezeTime(entry.End, loc, true); err != nil {
+			return window, err
+		}
+		if !window.start.IsZero() && !window.end.After(window.start) {
+			return window, fmt.Errorf("end %s is before start %s", entry.End, entry.Start)
+		}
+	}
+	if entry.Weekdays != "" {
+		days, err := parseDaysOfWeek(entry.Weekdays)
+		if err != nil {
+			return window, err
+		}
+		window.days = &days
+	}
+	if entry.Time != "" {
+		if window.fromMinute, window.toMinute, err = parseTimeRange(entry.Time); err != nil {
+			return window, err
+		}
+		window.hasTime = true
+	}
+	return window, nil
+}
+
+// parseFreezeTime parses the start or end of a freeze window: a date (an end date includes the whole day)
+// or an RFC 3339 time.
+func parseFreezeTime(value string, loc *time.Location, isEnd bool) (time.Time, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, value, loc); err == nil {
+		if isEnd {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q (use YYYY-MM-DD or RFC 3339)", value)
+	}
+	return t, nil
+}
+
+// activeAt reports whether the window is in effect at now (in the time zone of the dates).
+func (w parsedFreezeWindow) activeAt(now time.Time) bool {
+	if (!w.start.IsZero() && now.Before(w.start)) || (!w.end.IsZero() && !now.Before(w.end)) {
+		return false
+	}
+	if w.days != nil && !w.days[now.Weekday()] {
+		return false
+	}
+	if w.hasTime {
+		minute := now.Hour()*60 + now.Minute()
+		if w.fromMinute < w.toMinute {
+			return w.fromMinute <= minute && minute < w.toMinute
+		}
+		return minute >= w.fromMinute || minute < w.toMinute
+	}
+	return true
+}
+
+// freezeActive evaluates freeze_active: whether a window of the freeze windows file at the condition value
+// (a file or URL, shared like in_meeting calendars) is in effect. The window is stored in the event JSON
+// as freeze ({.freeze.name}, {.freeze.reason}, {.freeze.end}).
+func freezeActive(condition Condition, baseInput *BaseInput) (bool, error) {
+	loc, err := conditionLocation(condition.TZ)
+	if err != nil {
+		return false, err
+	}
+	data, err := loadSharedSource(condition.Value, "freeze_active", "freeze windows", ".yaml")
+	if err != nil {
+		return false, err
+	}
+	windows, err := parseFreezeWindows(data, loc)
+	if err != nil {
+		return false, err
+	}
+
+	now := currentTime().In(loc)
+	for _, window := range windows {
+		if !window.activeAt(now) {
+			continue
+		}
+		if m, ok := baseInput.RawJSON.(map[string]any); ok {
+			end := ""
+			if !window.end.IsZero() {
+				end = window.end.In(loc).Format(time.RFC3339)
+			}
+			m["freeze"] = map[string]any{"name": window.Name, "reason": window.Reason, "end": end}
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// validateFreezeSource checks the value of a freeze_active condition. A local file is parsed
+// when it exists; URLs and values with $VAR are only checked at run time.
+func validateFreezeSource(value, tz string) error {
+	source := strings.TrimSpace(value)
+	if source == "" {
+		return errors.New("requires a freeze windows file or URL")
+	}
+	if strings.Contains(source, "$") || strings.Contains(source, "://") {
+		return nil
+	}
+	data, err := loadSharedSource(source, "freeze_active", "freeze windows", ".yaml")
+	if err != nil {
+		return nil // 実行時に作られるファイルもある
+	}
+	loc, err := conditionLocation(tz)
+	if err != nil {
+		return err
+	}
+	_, err = parseFreezeWindows(data, loc)
+	return err
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testFreezeWindows = `
+- name: Year-end freeze
+  start: 2025-12-20
+  end: 2026-01-04
+  reason: Holiday coverage is limited
+- name: No Friday afternoon deploys
+  weekdays: Fri
+  time: "15:00-24:00"
+- name: Launch
+  start: 2026-02-01T09:00:00+09:00
+  end: 2026-02-01T18:00:00+09:00
+`
+
+func TestFreezeActive(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "freeze.yaml")
+	if err := os.WriteFile(file, []byte(testFreezeWindows), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { currentTime = time.Now })
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		wantName string
+		wantEnd  string
+	}{
+		{"first day", time.Date(2025, 12, 20, 0, 0, 0, 0, time.UTC), "Year-end freeze", "2026-01-05T00:00:00Z"},
+		{"end date is inclusive", time.Date(2026, 1, 4, 23, 59, 0, 0, time.UTC), "Year-end freeze", "2026-01-05T00:00:00Z"},
+		{"after the freeze", time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC), "", ""},
+		{"Friday afternoon", time.Date(2026, 1, 9, 16, 0, 0, 0, time.UTC), "No Friday afternoon deploys", ""},
+		{"Friday morning", time.Date(2026, 1, 9, 10, 0, 0, 0, time.UTC), "", ""},
+		{"Thursday afternoon", time.Date(2026, 1, 8, 16, 0, 0, 0, time.UTC), "", ""},
+		{"RFC 3339 range", time.Date(2026, 2, 1, 1, 0, 0, 0, time.UTC), "Launch", "2026-02-01T09:00:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			currentTime = func() time.Time { return tt.now }
+			rawJSON := map[string]any{}
+			matched, err := checkCommonCondition(Condition{Type: ConditionFreezeActive, Value: file, TZ: "UTC"}, &BaseInput{RawJSON: rawJSON})
+			if err != nil {
+				t.Fatalf("freeze_active error = %v", err)
+			}
+			if matched != (tt.wantName != "") {
+				t.Fatalf("freeze_active = %v, want %v", matched, tt.wantName != "")
+			}
+			if !matched {
+				return
+			}
+			freeze := rawJSON["freeze"].(map[string]any)
+			if freeze["name"] != tt.wantName || freeze["end"] != tt.wantEnd {
+				t.Errorf("freeze = %v, want name %q and end %q", freeze, tt.wantName, tt.wantEnd)
+			}
+		})
+	}
+
+	// 日付はtzのタイムゾーンで解釈する
+	currentTime = func() time.Time { return time.Date(2025, 12, 19, 16, 0, 0, 0, time.UTC) }
+	if matched, err := freezeActive(Condition{Value: file, TZ: "Asia/Tokyo"}, &BaseInput{}); err != nil || !matched {
+		t.Errorf("freeze_active in Asia/Tokyo = %v, %v, want true", matched, err)
+	}
+}
+
+func TestFreezeActive_URL(t *testing.T) {
+	cacheDir := filepath.Join(t.TempDir(), "calendars")
+	origCacheDir := calendarCacheDir
+	calendarCacheDir = func() string { return cacheDir }
+	t.Cleanup(func() { calendarCacheDir = origCacheDir })
+	currentTime = func() time.Time { return time.Date(2025, 12, 24, 12, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { currentTime = time.Now })
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(testFreezeWindows))
+	}))
+	defer server.Close()
+
+	for range 2 {
+		if matched, err := freezeActive(Condition{Value: server.URL + "/freeze.yaml"}, &BaseInput{}); err != nil || !matched {
+			t.Fatalf("freeze_active = %v, %v, want true", matched, err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("requests = %d, want the downloaded file to be cached", requests)
+	}
+}
+
+func TestParseFreezeWindows_Errors(t *testing.T) {
+	tests := []struct {
+		data    string
+		wantErr string
+	}{
+		{"name: not a list", "invalid freeze windows"},
+		{"- name: Empty", "window 1 (Empty): requires start, end or weekdays"},
+		{"- start: 2026-01-10\n  end: 2026-01-01", "window 1: end 2026-01-01 is before start 2026-01-10"},
+		{"- start: tomorrow", `invalid time "tomorrow"`},
+		{"- weekdays: Funday", "invalid day of week"},
+		{"- weekdays: Fri\n  time: 15:00", "expected \"HH:MM-HH:MM\""},
+	}
+	for _, tt := range tests {
+		_, err := parseFreezeWindows([]byte(tt.data), time.UTC)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("parseFreezeWindows(%q) error = %v, want %q", tt.data, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// 現在のブランチのキャッシュ（1回の起動中に同じcwdでgitを何度も読まないため）
+var (
+	gitBranchCache      = make(map[string]gitBranchEntry)
+	gitBranchCacheMutex sync.RWMutex
+)
+
+// gitBranchEntry is a cached branch with the HEAD file it was read from, so that
+// `cchook -command serve` can keep it until the branch is switched (see revalidateGitBranchCache).
+type gitBranchEntry struct {
+	branch string
+	head   string // "" outside a repository
+	stamp  fileStamp
+}
+
+// currentGitBranch returns the branch checked out in the repository containing dir.
+// It returns "" outside a repository and on a detached HEAD. Results are cached per directory.
+func currentGitBranch(dir string) string {
+	gitBranchCacheMutex.RLock()
+	entry, ok := gitBranchCache[dir]
+	gitBranchCacheMutex.RUnlock()
+	if ok {
+		return entry.branch
+	}
+
+	entry = gitBranchEntry{head: gitHeadFile(dir)}
+	if entry.head != "" {
+		entry.stamp, _ = statFileStamp(entry.head)
+	}
+	entry.branch = readGitBranch(dir)
+	gitBranchCacheMutex.Lock()
+	gitBranchCache[dir] = entry
+	gitBranchCacheMutex.Unlock()
+	return entry.branch
+}
+
+// revalidateGitBranchCache drops the cached branches whose HEAD file has changed since they were read,
+// and those of directories outside a repository (one may be created there).
+func revalidateGitBranchCache() {
+	gitBranchCacheMutex.Lock()
+	defer gitBranchCacheMutex.Unlock()
+	for dir, entry := range gitBranchCache {
+		if entry.head == "" {
+			delete(gitBranchCache, dir)
+			continue
+		}
+		if stamp, err := statFileStamp(entry.head); err != nil || stamp != entry.stamp {
+			delete(gitBranchCache, dir)
+		}
+	}
+}
+
+// gitHeadFile returns the HEAD file of the repository containing dir ("" outside a repository).
+func gitHeadFile(dir string) string {
+	root, err := filepath.Abs(dir)
+	if dir == "" || err != nil {
+		return ""
+	}
+	for ; ; root = filepath.Dir(root) {
+		if _, err := os.Stat(filepath.Join(root, ".git")); err == nil {
+			gitDir, _ := gitDirs(root)
+			return filepath.Join(gitDir, "HEAD")
+		}
+		if filepath.Dir(root) == root {
+			return ""
+		}
+	}
+}
+
+// readGitBranch reads HEAD of the repository containing dir without caching.
+func readGitBranch(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	repo, err := openGitRepository(dir)
+	if err != nil {
+		return ""
+	}
+	// repo.Head()はコミットの無いブランチで失敗するため、シンボリック参照のまま読む
+	head, err := repo.Reference(plumbing.HEAD, false)
+	if err != nil || head.Type() != plumbing.SymbolicReference || !head.Target().IsBranch() {
+		return ""
+	}
+	return head.Target().Short()
+}
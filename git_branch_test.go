@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCurrentGitBranch(t *testing.T) {
+	dir := t.TempDir()
+	// コミットの無いブランチでも読めること
+	if err := runCommand("cd "+dir+" && git init -q && git symbolic-ref HEAD refs/heads/feature/login", false, nil); err != nil {
+		t.Fatalf("Failed to init git repo: %v", err)
+	}
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readGitBranch(sub); got != "feature/login" {
+		t.Errorf("readGitBranch(subdirectory) = %q, want %q", got, "feature/login")
+	}
+	if got := readGitBranch(t.TempDir()); got != "" {
+		t.Errorf("readGitBranch(outside a repository) = %q, want empty", got)
+	}
+	if got := readGitBranch(""); got != "" {
+		t.Errorf("readGitBranch(\"\") = %q, want empty", got)
+	}
+
+	// detached HEAD
+	if err := runCommand("cd "+dir+" && git -c user.email=test@example.com -c user.name=Test commit -q --allow-empty -m init && git checkout -q --detach", false, nil); err != nil {
+		t.Fatalf("Failed to detach HEAD: %v", err)
+	}
+	if got := readGitBranch(dir); got != "" {
+		t.Errorf("readGitBranch(detached HEAD) = %q, want empty", got)
+	}
+
+	// 同じディレクトリは1回だけ読む
+	cached := t.TempDir()
+	if got := currentGitBranch(cached); got != "" {
+		t.Fatalf("currentGitBranch() = %q, want empty", got)
+	}
+	if err := runCommand("cd "+cached+" && git init -q && git symbolic-ref HEAD refs/heads/main", false, nil); err != nil {
+		t.Fatalf("Failed to init git repo: %v", err)
+	}
+	if got := currentGitBranch(cached); got != "" {
+		t.Errorf("currentGitBranch() = %q, want the cached empty branch", got)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// defaultGitCheckpointMessage is the message of a git_checkpoint action without `message`.
+const defaultGitCheckpointMessage = "cchook checkpoint: {.session_id}"
+
+// Modes of a git_checkpoint action.
+const (
+	gitCheckpointCommit = "commit" // commits every change to the current branch
+	gitCheckpointStash  = "stash"  // records the tracked changes in the stash list, leaving the working tree and index alone
+)
+
+// gitCheckpointMode returns the mode of a git_checkpoint action.
+func gitCheckpointMode(action Action) string {
+	if action.Mode == "" {
+		return gitCheckpointCommit
+	}
+	return action.Mode
+}
+
+// gitCheckpointMessage returns the templated message of a git_checkpoint action.
+func gitCheckpointMessage(action Action, rawJSON any) string {
+	message := action.Message
+	if message == "" {
+		message = defaultGitCheckpointMessage
+	}
+	return strings.TrimSpace(unifiedTemplateReplace(message, rawJSON))
+}
+
+// validateGitCheckpointRepo checks an entry of the repos allowlist of a git_checkpoint action.
+func validateGitCheckpointRepo(pattern string) error {
+	if !filepath.IsAbs(expandHomeDir(pattern)) {
+		return fmt.Errorf("repo %q must be an absolute path or start with ~/", pattern)
+	}
+	return validateGlob(filepath.ToSlash(expandHomeDir(pattern)))
+}
+
+// gitCheckpointAllowed reports whether the repository rooted at root matches the repos allowlist.
+func gitCheckpointAllowed(root string, repos []string) bool {
+	return matchesPathPatterns(root, repos)
+}
+
+// gitTopLevel returns the root of the worktree containing dir ("" outside a git repository).
+func gitTopLevel(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return ""
+	}
+	return filepath.Clean(strings.TrimSpace(string(out)))
+}
+
+// gitCheckpointDir returns the directory whose repository a PostToolUse git_checkpoint action records:
+// the directory of the edited file, or cwd for tools without a file_path.
+func gitCheckpointDir(filePath, cwd string) string {
+	if filePath == "" {
+		return cwd
+	}
+	return filepath.Dir(resolveToolFilePath(filePath, cwd))
+}
+
+// gitCheckpointCommand returns the shell command recording a checkpoint of the repository rooted at root.
+// Commits skip the repository's commit hooks, which are meant for the user's own commits.
+func gitCheckpointCommand(mode, root, message string) string {
+	git := "git -C " + shellQuote(root)
+	if mode == gitCheckpointStash {
+		// stash createは変更が無ければ何も出力しない（未追跡のファイルだけの場合など）
+		return fmt.Sprintf(`sha=$(%s stash create %s) && { [ -z "$sha" ] || %s stash store -m %s "$sha"; }`, git, shellQuote(message), git, shellQuote(message))
+	}
+	return fmt.Sprintf("%s add -A && %s commit -q --no-verify -m %s", git, git, shellQuote(message))
+}
+
+// gitCheckpoint records a checkpoint of the repository containing dir for a git_checkpoint action: a commit
+// of every change, or a stash entry. Repositories outside the repos allowlist and clean working trees are
+// skipped, and failures only print a warning, so that a checkpoint never blocks Claude.
+func (e *ActionExecutor) gitCheckpoint(action Action, dir string, rawJSON any) {
+	root := gitTopLevel(dir)
+	if root == "" || !gitCheckpointAllowed(root, action.Repos) {
+		return
+	}
+	dirty, _, err := gitWorktreeDirty(root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: git_checkpoint: %v\n", err)
+		return
+	}
+	if !dirty {
+		return
+	}
+
+	checkpoint := Action{Type: "git_checkpoint", Command: gitCheckpointCommand(gitCheckpointMode(action), root, gitCheckpointMessage(action, rawJSON))}
+	_, stderr, exitCode, err := e.runAction(checkpoint, rawJSON)
+	if exitCode != 0 {
+		message := strings.TrimSpace(stderr)
+		if message == "" && err != nil {
+			message = err.Error()
+		}
+		fmt.Fprintf(os.Stderr, "Warning: git_checkpoint failed in %s: %s\n", root, message)
+	}
+}
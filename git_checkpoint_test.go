@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGitCheckpointAllowed(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip(err)
+	}
+	tests := []struct {
+		root  string
+		repos []string
+		want  bool
+	}{
+		{"/src/app", []string{"/src/app"}, true},
+		{"/src/app", []string{"/src/app/"}, true},
+		{"/src/app", []string{"/src/*"}, true},
+		{"/src/team/app", []string{"/src/**"}, true},
+		{"/src/app-other", []string{"/src/app"}, false},
+		{filepath.Join(home, "work", "app"), []string{"~/work/*"}, true},
+		{"/src/app", nil, false},
+	}
+	for _, tt := range tests {
+		if got := gitCheckpointAllowed(tt.root, tt.repos); got != tt.want {
+			t.Errorf("gitCheckpointAllowed(%q, %q) = %v, want %v", tt.root, tt.repos, got, tt.want)
+		}
+	}
+}
+
+func TestGitCheckpoint(t *testing.T) {
+	repo := t.TempDir()
+	if err := runCommand("cd "+repo+" && git init -q && git config user.email a@b && git config user.name a", false, nil); err != nil {
+		t.Skipf("git is not available: %v", err)
+	}
+	// macOSの一時ディレクトリはシンボリックリンクを経由する
+	repo, err := filepath.EvalSymlinks(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(repo, "main.go")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git := func(args ...string) string {
+		t.Helper()
+		out, err := exec.Command("git", append([]string{"-C", repo}, args...)...).Output()
+		if err != nil {
+			t.Fatalf("git %v: %v", args, err)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	write("package main\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "init")
+	rawJSON := map[string]any{"session_id": "s1", "cwd": repo}
+
+	// 許可リストに無いリポジトリは記録しない
+	write("package main\n\nfunc main() {}\n")
+	stop := &StopInput{BaseInput: BaseInput{SessionID: "s1", Cwd: repo}}
+	if _, err := NewActionExecutor(nil).ExecuteStopAction(Action{Type: "git_checkpoint", Repos: []string{"/elsewhere"}}, stop, rawJSON); err != nil {
+		t.Fatal(err)
+	}
+	if got := git("rev-list", "--count", "HEAD"); got != "1" {
+		t.Fatalf("commits outside the allowlist = %s, want 1", got)
+	}
+
+	output, err := NewActionExecutor(nil).ExecuteStopAction(Action{Type: "git_checkpoint", Repos: []string{filepath.Dir(repo) + "/*"}}, stop, rawJSON)
+	if err != nil || output != nil {
+		t.Fatalf("ExecuteStopAction() = %+v, %v, want no output", output, err)
+	}
+	if got := git("log", "-1", "--format=%s"); got != "cchook checkpoint: s1" {
+		t.Errorf("checkpoint commit message = %q", got)
+	}
+	if got := git("status", "--porcelain"); got != "" {
+		t.Errorf("status after the checkpoint commit = %q, want clean", got)
+	}
+	// 変更が無ければ何もしない
+	if _, err := NewActionExecutor(nil).ExecuteStopAction(Action{Type: "git_checkpoint", Repos: []string{repo}}, stop, rawJSON); err != nil {
+		t.Fatal(err)
+	}
+	if got := git("rev-list", "--count", "HEAD"); got != "2" {
+		t.Errorf("commits after a checkpoint of a clean tree = %s, want 2", got)
+	}
+
+	// stashは作業ツリーをそのままにしてstashに記録する
+	write("package main\n\nfunc main() { println() }\n")
+	post := &PostToolUseInput{BaseInput: BaseInput{SessionID: "s1", Cwd: t.TempDir()}, ToolInput: ToolInput{FilePath: file}}
+	action := Action{Type: "git_checkpoint", Mode: "stash", Message: "edit {.tool_input.file_path}", Repos: []string{repo}}
+	if _, err := NewActionExecutor(nil).ExecutePostToolUseAction(action, post, map[string]any{"tool_input": map[string]any{"file_path": file}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := git("stash", "list", "--format=%gs"); got != "edit "+file {
+		t.Errorf("stash list = %q, want the checkpoint", got)
+	}
+	if got := git("status", "--porcelain"); got != "M main.go" {
+		t.Errorf("status after the stash checkpoint = %q, want the change kept", got)
+	}
+}
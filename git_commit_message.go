@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// conventionalCommitPattern matches the first line of a Conventional Commits message ("feat(api)!: add users").
+// It is used for git_commit_message_matches/not_matches conditions with the value "conventional".
+const conventionalCommitPattern = `^(feat|fix|docs|style|refactor|perf|test|build|ci|chore|revert)(\([^()\n]+\))?!?: \S`
+
+// gitGlobalOptionsWithValue are the options of git itself (before the subcommand) that take a separate value.
+var gitGlobalOptionsWithValue = map[string]bool{
+	"-C": true, "-c": true, "--git-dir": true, "--work-tree": true, "--namespace": true,
+}
+
+// gitCommitMessagePattern returns the regex of a git_commit_message_matches/not_matches value.
+func gitCommitMessagePattern(value string) string {
+	if value == "conventional" {
+		return conventionalCommitPattern
+	}
+	return value
+}
+
+// matchGitCommitMessages evaluates git_commit_message_matches (any message matches) or
+// git_commit_message_not_matches (any message doesn't match) for the git commit commands of command.
+// Both are false for commands that don't commit or whose message can't be determined.
+func matchGitCommitMessages(condition Condition, command, cwd string) (bool, error) {
+	messages := gitCommitMessages(command, cwd)
+	condition.Value = gitCommitMessagePattern(condition.Value)
+	for _, message := range messages {
+		matched, err := matchRegex(condition, message)
+		if err != nil {
+			return false, err
+		}
+		if matched == (condition.Type == ConditionGitCommitMessageMatches) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// gitCommitMessages returns the messages of the `git commit` commands in the shell command:
+// the -m/--message values (joined by blank lines, as git does), the file of -F/--file, or
+// when neither is given, the message prepared in .git/COMMIT_EDITMSG (without comment lines).
+// Messages given as `"$(cat <<'EOF' ... EOF)"` are read from the here-document.
+func gitCommitMessages(command, cwd string) []string {
+	var messages []string
+	for _, args := range shellCallArgs(command) {
+		if message, ok := parseGitCommitArgs(args, cwd); ok {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+// shellCallArgs returns the arguments of each simple command in the shell command, with quotes removed
+// and variables expanded from the environment. Commands with other expansions are left out.
+func shellCallArgs(command string) [][]string {
+	file, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+	if err != nil {
+		return nil
+	}
+	cfg := &expand.Config{Env: expand.FuncEnviron(os.Getenv), CmdSubst: heredocCmdSubst}
+
+	var calls [][]string
+	syntax.Walk(file, func(node syntax.Node) bool {
+		call, ok := node.(*syntax.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+		var args []string
+		for _, word := range call.Args {
+			arg, err := expand.Literal(cfg, word)
+			if err != nil {
+				return true
+			}
+			args = append(args, arg)
+		}
+		calls = append(calls, args)
+		return true
+	})
+	return calls
+}
+
+// gitSubcommand splits a `git [options] <subcommand> ...` command line into the subcommand and its arguments.
+// dir is where git runs: cwd, or the directory of -C.
+func gitSubcommand(args []string, cwd string) (subcommand string, subArgs []string, dir string, ok bool) {
+	if len(args) < 2 || filepath.Base(args[0]) != "git" {
+		return "", nil, "", false
+	}
+	dir = cwd
+	i := 1
+	for ; i < len(args) && strings.HasPrefix(args[i], "-"); i++ {
+		if gitGlobalOptionsWithValue[args[i]] && i+1 < len(args) {
+			if args[i] == "-C" {
+				dir = resolveDir(dir, args[i+1])
+			}
+			i++
+		}
+	}
+	if i >= len(args) {
+		return "", nil, "", false
+	}
+	return args[i], args[i+1:], dir, true
+}
+
+// heredocCmdSubst expands `$(cat <<EOF ... EOF)`, the usual way to pass a multi-line commit message,
+// to the here-document. Other command substitutions are not run.
+func heredocCmdSubst(w io.Writer, cs *syntax.CmdSubst) error {
+	if len(cs.Stmts) != 1 {
+		return errors.New("unsupported command substitution")
+	}
+	stmt := cs.Stmts[0]
+	call, ok := stmt.Cmd.(*syntax.CallExpr)
+	if !ok || len(call.Args) != 1 || call.Args[0].Lit() != "cat" || len(stmt.Redirs) != 1 {
+		return errors.New("unsupported command substitution")
+	}
+	redirect := stmt.Redirs[0]
+	if (redirect.Op != syntax.Hdoc && redirect.Op != syntax.DashHdoc) || redirect.Hdoc == nil {
+		return errors.New("unsupported command substitution")
+	}
+	body, err := expand.Document(&expand.Config{Env: expand.FuncEnviron(os.Getenv)}, redirect.Hdoc)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, body)
+	return err
+}
+
+// parseGitCommitArgs returns the commit message of a `git [options] commit ...` command line.
+// It returns false for other commands and when the message can't be determined.
+func parseGitCommitArgs(args []string, cwd string) (string, bool) {
+	subcommand, commitArgs, dir, ok := gitSubcommand(args, cwd)
+	if !ok || subcommand != "commit" {
+		return "", false
+	}
+
+	var messages []string
+	messageFile := ""
+	reused := false // -C/-c/--fixup/--squashなど、メッセージを既存のコミットから作る
+	for j := 0; j < len(commitArgs); j++ {
+		arg := commitArgs[j]
+		// 値を取るオプションの値 (--opt=value、--opt value、-xvalue、-x value)
+		value := func(attached string) (string, bool) {
+			if attached != "" {
+				return attached, true
+			}
+			if j+1 < len(commitArgs) {
+				j++
+				return commitArgs[j], true
+			}
+			return "", false
+		}
+		name, attached, hasValue := strings.Cut(arg, "=")
+		switch {
+		case arg == "--":
+			j = len(commitArgs)
+		case name == "--message":
+			if !hasValue {
+				attached = ""
+			}
+			if v, ok := value(attached); ok {
+				messages = append(messages, v)
+			}
+		case name == "--file":
+			if !hasValue {
+				attached = ""
+			}
+			if v, ok := value(attached); ok {
+				messageFile = v
+			}
+		case name == "--reuse-message" || name == "--reedit-message" || name == "--fixup" || name == "--squash":
+			reused = true
+			if !hasValue {
+				value("")
+			}
+		case name == "--template":
+			if !hasValue {
+				value("")
+			}
+		case strings.HasPrefix(arg, "--"):
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			// -am "msg" のようにまとめられた短いオプション
+			for k := 1; k < len(arg); k++ {
+				c := arg[k]
+				if c != 'm' && c != 'F' && c != 'C' && c != 'c' && c != 't' {
+					continue
+				}
+				v, ok := value(arg[k+1:])
+				switch {
+				case !ok:
+				case c == 'm':
+					messages = append(messages, v)
+				case c == 'F':
+					messageFile = v
+				case c == 'C' || c == 'c':
+					reused = true
+				}
+				break
+			}
+		}
+	}
+
+	switch {
+	case len(messages) > 0:
+		return strings.Join(messages, "\n\n"), true
+	case messageFile == "-" || (reused && messageFile == ""):
+		return "", false
+	case messageFile != "":
+		data, err := os.ReadFile(resolveDir(dir, messageFile))
+		if err != nil {
+			return "", false
+		}
+		return string(data), true
+	}
+	return preparedCommitMessage(dir)
+}
+
+// preparedCommitMessage returns the message in COMMIT_EDITMSG of the repository containing dir, without comment lines.
+func preparedCommitMessage(dir string) (string, bool) {
+	root, ok := findUpward(dir, ".git")
+	if !ok {
+		return "", false
+	}
+	gitDir, _ := gitDirs(root)
+	data, err := os.ReadFile(filepath.Join(gitDir, "COMMIT_EDITMSG"))
+	if err != nil {
+		return "", false
+	}
+	var lines [][]byte
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if !bytes.HasPrefix(line, []byte("#")) {
+			lines = append(lines, line)
+		}
+	}
+	message := strings.TrimSpace(string(bytes.Join(lines, []byte("\n"))))
+	if message == "" {
+		return "", false
+	}
+	return message, true
+}
+
+// validateGitCommitMessagePattern checks the value of a git_commit_message condition.
+func validateGitCommitMessagePattern(value string, ignoreCase bool) error {
+	if value == "" {
+		return fmt.Errorf("requires a regex or \"conventional\"")
+	}
+	return checkRegexValue(gitCommitMessagePattern(value), ignoreCase)
+}
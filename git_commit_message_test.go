@@ -0,0 +1,123 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGitCommitMessages(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "msg.txt"), []byte("fix: from file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		command string
+		want    []string
+	}{
+		{name: "-m", command: `git commit -m "feat: add users"`, want: []string{"feat: add users"}},
+		{name: "multiple -m", command: `git commit -m "feat: add users" -m "Refs PROJ-1"`, want: []string{"feat: add users\n\nRefs PROJ-1"}},
+		{name: "clustered -am", command: `git commit -am 'fix: typo'`, want: []string{"fix: typo"}},
+		{name: "attached -m", command: `git commit -mwip`, want: []string{"wip"}},
+		{name: "--message=", command: `git commit --message="docs: readme"`, want: []string{"docs: readme"}},
+		{name: "--message value", command: `git commit --amend --message "chore: bump"`, want: []string{"chore: bump"}},
+		{name: "-F file", command: `git commit -F msg.txt`, want: []string{"fix: from file\n"}},
+		{name: "--file=", command: `git commit --file=msg.txt`, want: []string{"fix: from file\n"}},
+		{
+			name:    "heredoc",
+			command: "git commit -m \"$(cat <<'EOF'\nfeat: heredoc\n\nBody line\nEOF\n)\"",
+			want:    []string{"feat: heredoc\n\nBody line"},
+		},
+		{name: "command chain", command: `git add . && git commit -m "wip" && git push`, want: []string{"wip"}},
+		{name: "global options", command: `git -c core.editor=true --no-pager commit -m "fix: x"`, want: []string{"fix: x"}},
+		{name: "-C dir for -F", command: `git -C sub commit -F ../msg.txt`, want: []string{"fix: from file\n"}},
+		{name: "-- ends options", command: `git commit -m "fix: x" -- -m`, want: []string{"fix: x"}},
+		{name: "not a commit", command: `git log -m "x"`},
+		{name: "not git", command: `echo git commit -m "x"`},
+		{name: "reuse message", command: `git commit -C HEAD`},
+		{name: "fixup", command: `git commit --fixup HEAD~1`},
+		{name: "message from stdin", command: `echo x | git commit -F -`},
+		{name: "missing file", command: `git commit -F nope.txt`},
+		{name: "other substitution", command: `git commit -m "$(date)"`},
+		{name: "parse error", command: `git commit -m "unterminated`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := gitCommitMessages(tt.command, dir); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("gitCommitMessages(%q) = %q, want %q", tt.command, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitCommitMessages_PreparedMessage(t *testing.T) {
+	repo := t.TempDir()
+	if err := runCommand("cd "+repo+" && git init -q", false, nil); err != nil {
+		t.Skipf("git is not available: %v", err)
+	}
+	sub := filepath.Join(repo, "pkg")
+	if err := os.MkdirAll(sub, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	// COMMIT_EDITMSGが無ければメッセージは不明
+	if got := gitCommitMessages("git commit", sub); got != nil {
+		t.Errorf("without COMMIT_EDITMSG: got %q, want nil", got)
+	}
+
+	editMsg := "feat: prepared\n\nBody\n# Please enter the commit message for your changes.\n#\n"
+	if err := os.WriteFile(filepath.Join(repo, ".git", "COMMIT_EDITMSG"), []byte(editMsg), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := gitCommitMessages("git commit --no-verify", sub), []string{"feat: prepared\n\nBody"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("gitCommitMessages() = %q, want %q", got, want)
+	}
+
+	// worktreeの.gitファイルが指すgitdirから読む
+	worktree := t.TempDir()
+	if err := os.WriteFile(filepath.Join(worktree, ".git"), []byte("gitdir: "+filepath.Join(repo, ".git")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := gitCommitMessages("git commit", worktree), []string{"feat: prepared\n\nBody"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("worktree: gitCommitMessages() = %q, want %q", got, want)
+	}
+}
+
+func TestCheckCondition_GitCommitMessage(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition Condition
+		command   string
+		want      bool
+	}{
+		{name: "conventional matches", condition: Condition{Type: ConditionGitCommitMessageMatches, Value: "conventional"}, command: `git commit -m "feat(api)!: drop v1"`, want: true},
+		{name: "conventional not matches ok", condition: Condition{Type: ConditionGitCommitMessageNotMatches, Value: "conventional"}, command: `git commit -m "fix: typo"`, want: false},
+		{name: "conventional not matches bad", condition: Condition{Type: ConditionGitCommitMessageNotMatches, Value: "conventional"}, command: `git commit -m "Fixed stuff"`, want: true},
+		{name: "conventional needs description", condition: Condition{Type: ConditionGitCommitMessageNotMatches, Value: "conventional"}, command: `git commit -m "feat: "`, want: true},
+		{name: "ticket id", condition: Condition{Type: ConditionGitCommitMessageNotMatches, Value: `\b[A-Z]+-[0-9]+\b`}, command: `git commit -m "fix: login" -m "Refs PROJ-42"`, want: false},
+		{name: "ticket id missing", condition: Condition{Type: ConditionGitCommitMessageNotMatches, Value: `\b[A-Z]+-[0-9]+\b`}, command: `git commit -m "fix: login"`, want: true},
+		{name: "ignore_case", condition: Condition{Type: ConditionGitCommitMessageMatches, Value: "^wip", IgnoreCase: true}, command: `git commit -m "WIP"`, want: true},
+		{name: "any commit of a chain", condition: Condition{Type: ConditionGitCommitMessageNotMatches, Value: "conventional"}, command: `git commit -m "feat: a" && git commit -m "b"`, want: true},
+		{name: "not a commit", condition: Condition{Type: ConditionGitCommitMessageNotMatches, Value: "conventional"}, command: `git status`, want: false},
+		{name: "unknown message", condition: Condition{Type: ConditionGitCommitMessageNotMatches, Value: "conventional"}, command: `git commit --fixup HEAD`, want: false},
+		{name: "no command", condition: Condition{Type: ConditionGitCommitMessageNotMatches, Value: "conventional"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := checkToolCondition(tt.condition, &ToolInput{Command: tt.command}, t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("checkToolCondition(%q) = %v, want %v", tt.command, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := checkToolCondition(Condition{Type: ConditionGitCommitMessageMatches, Value: "("}, &ToolInput{Command: `git commit -m x`}, ""); err == nil {
+		t.Error("invalid regex: want error")
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// openGitRepository opens the repository containing dir, searching parent directories like git does.
+// In a linked worktree the .git file points to .git/worktrees/<name> of the main repository, which has
+// only HEAD and the index; the config, refs and objects are read from the common dir, as git does.
+// Submodules (whose .git file points to .git/modules/<name> of the superproject) are opened as their own repository.
+func openGitRepository(dir string) (*git.Repository, error) {
+	return git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true, EnableDotGitCommonDir: true})
+}
+
+// gitDirs returns the git dir of the worktree rooted at root (the directory containing .git)
+// and its common dir, which holds the config, refs and info/exclude shared by all worktrees.
+// Both are root/.git in a plain repository.
+func gitDirs(root string) (gitDir, commonDir string) {
+	gitDir = filepath.Join(root, ".git")
+	// worktreeやsubmoduleでは.gitが"gitdir: <path>"を書いたファイルになる
+	if data, err := os.ReadFile(gitDir); err == nil {
+		if path, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: "); ok {
+			gitDir = resolveDir(root, path)
+		}
+	}
+	commonDir = gitDir
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		commonDir = resolveDir(gitDir, strings.TrimSpace(string(data)))
+	}
+	return gitDir, commonDir
+}
+
+// resolveDir resolves path against dir unless it is absolute.
+func resolveDir(dir, path string) string {
+	if filepath.IsAbs(path) || dir == "" {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
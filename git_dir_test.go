@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// initGitWorktreeAndSubmodule creates a repository on main with a linked worktree on feature
+// and a submodule at libs/sub on branch vendor, and returns their directories.
+func initGitWorktreeAndSubmodule(t *testing.T) (main, worktree, submodule string) {
+	t.Helper()
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(t.TempDir(), "gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	base := t.TempDir()
+	main = filepath.Join(base, "main")
+	worktree = filepath.Join(base, "worktree")
+	upstream := filepath.Join(base, "upstream")
+	git := "git -c user.email=test@example.com -c user.name=Test -c protocol.file.allow=always"
+	script := "git init -q -b vendor " + upstream + " && cd " + upstream + " && echo lib > lib.go && git add . && " + git + " commit -qm lib" +
+		" && git init -q -b main " + main + " && cd " + main + " && echo 'build/' > .gitignore && echo x > a.go && git add . && " + git + " commit -qm init" +
+		" && " + git + " submodule add -q " + upstream + " libs/sub && " + git + " commit -qm sub" +
+		" && git worktree add -q -b feature " + worktree
+	if err := runCommand(script, false, nil); err != nil {
+		t.Skipf("git is not available: %v", err)
+	}
+	return main, worktree, filepath.Join(main, "libs", "sub")
+}
+
+func TestGitDirs(t *testing.T) {
+	main, worktree, submodule := initGitWorktreeAndSubmodule(t)
+	mainGitDir := filepath.Join(main, ".git")
+
+	tests := []struct {
+		name          string
+		root          string
+		wantGitDir    string
+		wantCommonDir string
+	}{
+		{name: "repository", root: main, wantGitDir: mainGitDir, wantCommonDir: mainGitDir},
+		{name: "linked worktree", root: worktree, wantGitDir: filepath.Join(mainGitDir, "worktrees", "worktree"), wantCommonDir: mainGitDir},
+		{name: "submodule", root: submodule, wantGitDir: filepath.Join(mainGitDir, "modules", "libs", "sub"), wantCommonDir: filepath.Join(mainGitDir, "modules", "libs", "sub")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gitDir, commonDir := gitDirs(tt.root)
+			if filepath.Clean(gitDir) != tt.wantGitDir || filepath.Clean(commonDir) != tt.wantCommonDir {
+				t.Errorf("gitDirs() = %q, %q; want %q, %q", gitDir, commonDir, tt.wantGitDir, tt.wantCommonDir)
+			}
+		})
+	}
+}
+
+func TestGitConditions_WorktreeAndSubmodule(t *testing.T) {
+	main, worktree, submodule := initGitWorktreeAndSubmodule(t)
+	// 設定とinfo/excludeはメインのリポジトリにだけある
+	if err := runCommand("cd "+main+" && git config remote.pushDefault fork && echo '*.local' >> .git/info/exclude", false, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := runCommand("echo '*.tmp' >> "+filepath.Join(main, ".git", "modules", "libs", "sub", "info", "exclude"), false, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("branch", func(t *testing.T) {
+		for dir, want := range map[string]string{main: "main", worktree: "feature", submodule: "vendor"} {
+			if got := readGitBranch(dir); got != want {
+				t.Errorf("readGitBranch(%s) = %q, want %q", dir, got, want)
+			}
+		}
+	})
+
+	t.Run("tracked", func(t *testing.T) {
+		for _, path := range []string{filepath.Join(worktree, "a.go"), filepath.Join(submodule, "lib.go"), filepath.Join(worktree, "libs", "sub")} {
+			if tracked, err := isGitTracked(path); err != nil || !tracked {
+				t.Errorf("isGitTracked(%s) = %v, %v; want true", path, tracked, err)
+			}
+		}
+		if tracked, _ := isGitTracked(filepath.Join(worktree, "new.go")); tracked {
+			t.Error("isGitTracked(untracked file in worktree) = true")
+		}
+	})
+
+	t.Run("ignored", func(t *testing.T) {
+		tests := []struct {
+			path string
+			want bool
+		}{
+			{path: filepath.Join(worktree, "build", "out"), want: true},
+			{path: filepath.Join(worktree, "settings.local"), want: true},
+			{path: filepath.Join(worktree, "a.go"), want: false},
+			{path: filepath.Join(submodule, "scratch.tmp"), want: true},
+			{path: filepath.Join(submodule, "settings.local"), want: false},
+		}
+		for _, tt := range tests {
+			if got, err := isGitIgnored(tt.path, ""); err != nil || got != tt.want {
+				t.Errorf("isGitIgnored(%s) = %v, %v; want %v", tt.path, got, err, tt.want)
+			}
+		}
+	})
+
+	t.Run("dirty", func(t *testing.T) {
+		if dirty, inRepo, err := gitWorktreeDirty(worktree); err != nil || !inRepo || dirty {
+			t.Errorf("gitWorktreeDirty(clean worktree) = %v, %v, %v", dirty, inRepo, err)
+		}
+		if err := os.WriteFile(filepath.Join(worktree, "a.go"), []byte("changed\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if dirty, _, err := gitWorktreeDirty(worktree); err != nil || !dirty {
+			t.Errorf("gitWorktreeDirty(modified worktree) = %v, %v", dirty, err)
+		}
+		// worktreeの変更はメインのworking treeには影響しない
+		if dirty, _, err := gitWorktreeDirty(main); err != nil || dirty {
+			t.Errorf("gitWorktreeDirty(main) = %v, %v", dirty, err)
+		}
+	})
+
+	t.Run("push remote", func(t *testing.T) {
+		if got := gitPushRemote(worktree, "feature"); got != "fork" {
+			t.Errorf("gitPushRemote(worktree) = %q, want %q (remote.pushDefault of the main repository)", got, "fork")
+		}
+		if got := gitPushRemote(submodule, "vendor"); got != "origin" {
+			t.Errorf("gitPushRemote(submodule) = %q, want %q (the submodule has its own config)", got, "origin")
+		}
+	})
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"strings"
+)
+
+// gitPushOptionsWithValue are the options of `git push` that take a separate value.
+var gitPushOptionsWithValue = map[string]bool{
+	"-o": true, "--push-option": true, "--repo": true, "--receive-pack": true, "--exec": true,
+}
+
+// gitPush is a `git push` parsed from a Bash command, as used by the push_* conditions and exposed
+// to templates as .git_push.
+type gitPush struct {
+	Remote   string   // リモート名またはURL
+	Branches []string // 更新・削除するブランチ (refs/heads/を除いた名前)
+	All      bool     // --all/--mirrorで全ブランチをpushする
+	Force    bool     // -f、--force、--force-with-lease、+refspec
+	Delete   bool     // --deleteまたは:branchでブランチを削除する
+}
+
+// gitPushes returns the `git push` commands of the shell command. The remote and branch git would use
+// are filled in when they are omitted: the push remote of the current branch (or origin) and the current branch.
+func gitPushes(command, cwd string) []gitPush {
+	var pushes []gitPush
+	for _, args := range shellCallArgs(command) {
+		subcommand, pushArgs, dir, ok := gitSubcommand(args, cwd)
+		if !ok || subcommand != "push" {
+			continue
+		}
+		pushes = append(pushes, parseGitPushArgs(pushArgs, dir))
+	}
+	return pushes
+}
+
+// parseGitPushArgs parses the arguments of `git push` run in dir.
+func parseGitPushArgs(args []string, dir string) gitPush {
+	var push gitPush
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := strings.Cut(arg, "=")
+		switch {
+		case arg == "--":
+			positional = append(positional, args[i+1:]...)
+			i = len(args)
+		case name == "--force" || name == "--force-with-lease":
+			push.Force = true
+		case arg == "--delete":
+			push.Delete = true
+		case arg == "--all" || arg == "--branches" || arg == "--mirror":
+			push.All = true
+		case name == "--repo":
+			if hasValue {
+				push.Remote = value
+			} else if i+1 < len(args) {
+				i++
+				push.Remote = args[i]
+			}
+		case gitPushOptionsWithValue[arg]:
+			i++
+		case strings.HasPrefix(arg, "--"):
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			// -fu のようにまとめられた短いオプション (-o は値を取る)
+			for k := 1; k < len(arg); k++ {
+				switch arg[k] {
+				case 'f':
+					push.Force = true
+				case 'd':
+					push.Delete = true
+				case 'o':
+					if k == len(arg)-1 {
+						i++
+					}
+					k = len(arg)
+				}
+			}
+		default:
+			positional = append(positional, arg)
+		}
+	}
+
+	if len(positional) > 0 {
+		push.Remote = positional[0]
+		positional = positional[1:]
+	}
+	current := currentGitBranch(dir)
+	if push.Remote == "" {
+		push.Remote = gitPushRemote(dir, current)
+	}
+	if len(positional) == 0 && !push.All && current != "" {
+		push.Branches = []string{current}
+	}
+	for _, refspec := range positional {
+		if strings.HasPrefix(refspec, "+") {
+			push.Force = true
+			refspec = refspec[1:]
+		}
+		src, dst, hasDst := strings.Cut(refspec, ":")
+		if hasDst && src == "" {
+			push.Delete = true
+		}
+		if !hasDst {
+			dst = src
+		}
+		if dst == "HEAD" || dst == "@" {
+			dst = current
+		}
+		if branch, ok := gitPushBranchName(dst); ok {
+			push.Branches = append(push.Branches, branch)
+		}
+	}
+	return push
+}
+
+// gitPushBranchName returns the branch a refspec destination names. Tags and other refs are not branches.
+func gitPushBranchName(dst string) (string, bool) {
+	if branch, ok := strings.CutPrefix(dst, "refs/heads/"); ok {
+		return branch, branch != ""
+	}
+	if dst == "" || strings.HasPrefix(dst, "refs/") {
+		return "", false
+	}
+	return dst, true
+}
+
+// gitPushRemote returns the remote `git push` uses without arguments on branch in the repository of dir:
+// branch.<name>.pushRemote, remote.pushDefault or branch.<name>.remote, and origin otherwise.
+func gitPushRemote(dir, branch string) string {
+	if dir == "" {
+		return "origin"
+	}
+	repo, err := openGitRepository(dir)
+	if err != nil {
+		return "origin"
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		return "origin"
+	}
+	if branch != "" {
+		if remote := cfg.Raw.Section("branch").Subsection(branch).Option("pushRemote"); remote != "" {
+			return remote
+		}
+	}
+	if remote := cfg.Raw.Section("remote").Option("pushDefault"); remote != "" {
+		return remote
+	}
+	if branch != "" {
+		if remote := cfg.Raw.Section("branch").Subsection(branch).Option("remote"); remote != "" {
+			return remote
+		}
+	}
+	return "origin"
+}
+
+// matchGitPushes evaluates a push_* condition: true when any `git push` of command matches.
+func matchGitPushes(condition Condition, command, cwd string) (bool, error) {
+	for _, push := range gitPushes(command, cwd) {
+		matched, err := matchGitPush(condition, push)
+		if err != nil || matched {
+			return matched, err
+		}
+	}
+	return false, nil
+}
+
+// matchGitPush evaluates a push_* condition for one push.
+func matchGitPush(condition Condition, push gitPush) (bool, error) {
+	switch condition.Type {
+	case ConditionPushToRemoteIs:
+		value, remote, err := prepareStringMatch(condition, push.Remote)
+		return err == nil && remote == value, err
+	case ConditionPushIsForce:
+		return push.Force, nil
+	case ConditionPushTargetBranchMatches:
+		// --all/--mirrorはどのブランチにもpushし得る
+		if push.All {
+			return true, nil
+		}
+		for _, branch := range push.Branches {
+			matched, err := matchRegex(condition, branch)
+			if err != nil || matched {
+				return matched, err
+			}
+		}
+	}
+	return false, nil
+}
+
+// withGitPush adds the first `git push` of a Bash tool call to the event JSON as git_push,
+// so that templates can use {.git_push.remote} and {.git_push.branches}.
+func withGitPush(rawJSON any) {
+	m, ok := rawJSON.(map[string]any)
+	if !ok || m["tool_name"] != "Bash" {
+		return
+	}
+	toolInput, _ := m["tool_input"].(map[string]any)
+	command, _ := toolInput["command"].(string)
+	if !strings.Contains(command, "push") {
+		return
+	}
+	cwd, _ := m["cwd"].(string)
+	pushes := gitPushes(command, cwd)
+	if len(pushes) == 0 {
+		return
+	}
+	push := pushes[0]
+	branches := make([]any, len(push.Branches))
+	for i, branch := range push.Branches {
+		branches[i] = branch
+	}
+	m["git_push"] = map[string]any{
+		"remote":   push.Remote,
+		"branches": branches,
+		"all":      push.All,
+		"force":    push.Force,
+		"delete":   push.Delete,
+	}
+}
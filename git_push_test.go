@@ -0,0 +1,177 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// initGitPushRepo creates a Git repository on branch feature with the given extra config, and returns its directory.
+func initGitPushRepo(t *testing.T, config string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := runCommand("cd "+dir+" && git init -q -b feature"+config, false, nil); err != nil {
+		t.Skipf("git is not available: %v", err)
+	}
+	return dir
+}
+
+func TestGitPushes(t *testing.T) {
+	repo := initGitPushRepo(t, "")
+
+	tests := []struct {
+		name    string
+		command string
+		want    []gitPush
+	}{
+		{name: "defaults", command: "git push", want: []gitPush{{Remote: "origin", Branches: []string{"feature"}}}},
+		{name: "remote only", command: "git push fork", want: []gitPush{{Remote: "fork", Branches: []string{"feature"}}}},
+		{name: "refspec", command: "git push origin main", want: []gitPush{{Remote: "origin", Branches: []string{"main"}}}},
+		{name: "src:dst", command: "git push origin HEAD~1:refs/heads/release", want: []gitPush{{Remote: "origin", Branches: []string{"release"}}}},
+		{name: "HEAD", command: "git push -u origin HEAD", want: []gitPush{{Remote: "origin", Branches: []string{"feature"}}}},
+		{name: "-f", command: "git push -f origin main", want: []gitPush{{Remote: "origin", Branches: []string{"main"}, Force: true}}},
+		{name: "clustered -uf", command: "git push -uf origin main", want: []gitPush{{Remote: "origin", Branches: []string{"main"}, Force: true}}},
+		{name: "--force-with-lease", command: "git push --force-with-lease=main:abc origin main", want: []gitPush{{Remote: "origin", Branches: []string{"main"}, Force: true}}},
+		{name: "+refspec", command: "git push origin +main +dev", want: []gitPush{{Remote: "origin", Branches: []string{"main", "dev"}, Force: true}}},
+		{name: "delete refspec", command: "git push origin :old", want: []gitPush{{Remote: "origin", Branches: []string{"old"}, Delete: true}}},
+		{name: "--delete", command: "git push --delete origin old", want: []gitPush{{Remote: "origin", Branches: []string{"old"}, Delete: true}}},
+		{name: "--all", command: "git push --all fork", want: []gitPush{{Remote: "fork", All: true}}},
+		{name: "tags are not branches", command: "git push origin v1.0 refs/tags/v1.1", want: []gitPush{{Remote: "origin", Branches: []string{"v1.0"}}}},
+		{name: "push option value", command: "git push -o ci.skip origin main", want: []gitPush{{Remote: "origin", Branches: []string{"main"}}}},
+		{name: "--repo", command: "git push --repo=fork", want: []gitPush{{Remote: "fork", Branches: []string{"feature"}}}},
+		{name: "quoted", command: `git push "origin" 'main'`, want: []gitPush{{Remote: "origin", Branches: []string{"main"}}}},
+		{name: "chain", command: "git commit -m x && git push fork main && git push origin", want: []gitPush{
+			{Remote: "fork", Branches: []string{"main"}},
+			{Remote: "origin", Branches: []string{"feature"}},
+		}},
+		{name: "not a push", command: "git pull origin main"},
+		{name: "push in an argument", command: "echo git push -f"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := gitPushes(tt.command, repo); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("gitPushes(%q) = %+v, want %+v", tt.command, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitPushRemote(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{name: "origin by default", want: "origin"},
+		{name: "branch remote", config: " && git config branch.feature.remote upstream", want: "upstream"},
+		{name: "pushDefault", config: " && git config branch.feature.remote upstream && git config remote.pushDefault fork", want: "fork"},
+		{name: "pushRemote", config: " && git config remote.pushDefault fork && git config branch.feature.pushRemote mine", want: "mine"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := initGitPushRepo(t, tt.config)
+			if got := gitPushes("git push", repo); len(got) != 1 || got[0].Remote != tt.want {
+				t.Errorf("gitPushes() = %+v, want remote %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckCondition_GitPush(t *testing.T) {
+	repo := initGitPushRepo(t, "")
+
+	tests := []struct {
+		name      string
+		condition Condition
+		command   string
+		want      bool
+	}{
+		{name: "remote is", condition: Condition{Type: ConditionPushToRemoteIs, Value: "origin"}, command: "git push origin main", want: true},
+		{name: "remote is not", condition: Condition{Type: ConditionPushToRemoteIs, Value: "origin"}, command: "git push fork main", want: false},
+		{name: "default remote", condition: Condition{Type: ConditionPushToRemoteIs, Value: "origin"}, command: "git push", want: true},
+		{name: "force", condition: Condition{Type: ConditionPushIsForce}, command: "git push --force fork main", want: true},
+		{name: "not force", condition: Condition{Type: ConditionPushIsForce}, command: "git push fork main", want: false},
+		{name: "target branch", condition: Condition{Type: ConditionPushTargetBranchMatches, Value: "^(main|master)$"}, command: "git push origin HEAD:main", want: true},
+		{name: "current branch", condition: Condition{Type: ConditionPushTargetBranchMatches, Value: "^main$"}, command: "git push", want: false},
+		{name: "--all targets every branch", condition: Condition{Type: ConditionPushTargetBranchMatches, Value: "^main$"}, command: "git push --all origin", want: true},
+		{name: "not a push", condition: Condition{Type: ConditionPushIsForce}, command: "git reset --hard", want: false},
+		{name: "no command", condition: Condition{Type: ConditionPushIsForce}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := checkToolCondition(tt.condition, &ToolInput{Command: tt.command}, repo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("checkToolCondition(%q) = %v, want %v", tt.command, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecutePreToolUseHooks_ForcePushProtection(t *testing.T) {
+	repo := initGitPushRepo(t, "")
+	config := &Config{PreToolUse: []PreToolUseHook{{
+		Matcher: "Bash",
+		Conditions: []Condition{
+			{Type: ConditionPushIsForce},
+			{Type: ConditionPushToRemoteIs, Value: "origin"},
+			{Type: ConditionPushTargetBranchMatches, Value: "^main$"},
+		},
+		Actions: []Action{{
+			Type:               "output",
+			Message:            "Force-pushing to {.git_push.remote}/{.git_push.branches | join(\",\")} is not allowed",
+			PermissionDecision: stringPtr("deny"),
+		}},
+	}}}
+
+	tests := []struct {
+		command      string
+		wantDecision string
+		wantReason   string
+	}{
+		{command: "git push -f origin main", wantDecision: "deny", wantReason: "Force-pushing to origin/main is not allowed"},
+		{command: "git push -f fork main"},
+		{command: "git push origin main"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			rawJSON := map[string]any{"cwd": repo, "tool_name": "Bash", "tool_input": map[string]any{"command": tt.command}}
+			withGitPush(rawJSON)
+			input := &PreToolUseInput{BaseInput: BaseInput{Cwd: repo}, ToolName: "Bash", ToolInput: ToolInput{Command: tt.command}}
+			output, err := executePreToolUseHooksJSON(config, input, rawJSON)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if output.HookSpecificOutput == nil {
+				if tt.wantDecision != "" {
+					t.Fatalf("no decision, want %q", tt.wantDecision)
+				}
+				return
+			}
+			if output.HookSpecificOutput.PermissionDecision != tt.wantDecision || output.HookSpecificOutput.PermissionDecisionReason != tt.wantReason {
+				t.Errorf("decision = %q, %q; want %q, %q", output.HookSpecificOutput.PermissionDecision, output.HookSpecificOutput.PermissionDecisionReason, tt.wantDecision, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestWithGitPush(t *testing.T) {
+	repo := initGitPushRepo(t, "")
+	rawJSON := map[string]any{"cwd": repo, "tool_name": "Bash", "tool_input": map[string]any{"command": "git push -f fork main"}}
+	withGitPush(rawJSON)
+	want := map[string]any{"remote": "fork", "branches": []any{"main"}, "all": false, "force": true, "delete": false}
+	if !reflect.DeepEqual(rawJSON["git_push"], want) {
+		t.Errorf("git_push = %v, want %v", rawJSON["git_push"], want)
+	}
+
+	for _, rawJSON := range []map[string]any{
+		{"tool_name": "Bash", "tool_input": map[string]any{"command": "git status"}},
+		{"tool_name": "Write", "tool_input": map[string]any{"content": "git push"}},
+	} {
+		withGitPush(rawJSON)
+		if _, ok := rawJSON["git_push"]; ok {
+			t.Errorf("git_push added to %v", rawJSON)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// gitSigningFormats are the values of gpg.format, which git_signing_enabled can require.
+var gitSigningFormats = []string{"openpgp", "ssh", "x509"}
+
+// gitSigningEnabled reports whether commits made in dir are signed by default: commit.gpgsign is true
+// in the effective git config (repository, global and system, with includes), and with gpg.format=ssh,
+// user.signingkey is set (git can't pick an SSH key by itself). When format is not empty, gpg.format
+// (default openpgp) must also be format. It is false when git is not installed or dir doesn't exist.
+func gitSigningEnabled(dir, format string) bool {
+	args := []string{"config", "--get-regexp", `^(commit\.gpgsign|gpg\.format|user\.signingkey)$`}
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		// 設定が1つも無いときもexit 1になる
+		return false
+	}
+
+	// 同じキーが複数のスコープにあるときは後のもの (より狭いスコープ) が優先される
+	values := map[string]string{}
+	for _, line := range strings.Split(strings.TrimRight(string(out), "\n"), "\n") {
+		key, value, hasValue := strings.Cut(line, " ")
+		if !hasValue {
+			value = "true" // `[commit] gpgsign` のように値の無いキーはtrue
+		}
+		values[strings.ToLower(key)] = value
+	}
+
+	if !gitConfigBool(values["commit.gpgsign"]) {
+		return false
+	}
+	actual := strings.ToLower(values["gpg.format"])
+	if actual == "" {
+		actual = "openpgp"
+	}
+	if actual == "ssh" && values["user.signingkey"] == "" {
+		return false
+	}
+	return format == "" || actual == format
+}
+
+// gitConfigBool parses a boolean git config value.
+func gitConfigBool(value string) bool {
+	switch strings.ToLower(value) {
+	case "true", "yes", "on", "1":
+		return true
+	}
+	return false
+}
+
+// validateGitSigningFormat checks the value of a git_signing_enabled condition.
+func validateGitSigningFormat(value string) error {
+	if value == "" {
+		return nil
+	}
+	for _, format := range gitSigningFormats {
+		if value == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown signing format %q (must be %s, or empty for any)", value, strings.Join(gitSigningFormats, ", "))
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGitSigningEnabled(t *testing.T) {
+	// ユーザーの設定に影響されないようにする
+	global := filepath.Join(t.TempDir(), "gitconfig")
+	t.Setenv("GIT_CONFIG_GLOBAL", global)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	tests := []struct {
+		name   string
+		global string
+		local  string
+		format string
+		want   bool
+	}{
+		{name: "not configured", want: false},
+		{name: "gpgsign in repo", local: "[commit]\n\tgpgsign = true\n", want: true},
+		{name: "gpgsign globally", global: "[commit]\n\tgpgsign = yes\n", want: true},
+		{name: "key without value", local: "[commit]\n\tgpgsign\n", want: true},
+		{name: "repo overrides global", global: "[commit]\n\tgpgsign = true\n", local: "[commit]\n\tgpgsign = false\n", want: false},
+		{name: "ssh without key", local: "[commit]\n\tgpgsign = true\n[gpg]\n\tformat = ssh\n", want: false},
+		{name: "ssh with key", global: "[user]\n\tsigningkey = ~/.ssh/id_ed25519.pub\n", local: "[commit]\n\tgpgsign = true\n[gpg]\n\tformat = ssh\n", want: true},
+		{name: "format required", local: "[commit]\n\tgpgsign = true\n", format: "ssh", want: false},
+		{name: "default format is openpgp", local: "[commit]\n\tgpgsign = true\n", format: "openpgp", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := t.TempDir()
+			if err := runCommand("cd "+repo+" && git init -q", false, nil); err != nil {
+				t.Skipf("git is not available: %v", err)
+			}
+			if err := os.WriteFile(global, []byte(tt.global), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if tt.local != "" {
+				f, err := os.OpenFile(filepath.Join(repo, ".git", "config"), os.O_APPEND|os.O_WRONLY, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, err = f.WriteString(tt.local)
+				f.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := gitSigningEnabled(repo, tt.format); got != tt.want {
+				t.Errorf("gitSigningEnabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if gitSigningEnabled(filepath.Join(t.TempDir(), "missing"), "") {
+		t.Error("missing directory: want false")
+	}
+}
+
+func TestCheckCondition_GitSigningEnabled(t *testing.T) {
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(t.TempDir(), "gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	repo := t.TempDir()
+	if err := runCommand("cd "+repo+" && git init -q", false, nil); err != nil {
+		t.Skipf("git is not available: %v", err)
+	}
+
+	// 署名が設定されていなければgit commitをブロックする
+	conditions := []Condition{
+		{Type: ConditionCommandRegex, Value: `\bgit\s+commit\b`},
+		{Type: ConditionNot, Conditions: []Condition{{Type: ConditionGitSigningEnabled}}},
+	}
+	input := &PreToolUseInput{BaseInput: BaseInput{Cwd: repo}, ToolName: "Bash", ToolInput: ToolInput{Command: "git commit -m x"}}
+	check := func() bool {
+		t.Helper()
+		// 設定の変更は別のイベントから見る
+		resetInvocationCache()
+		for _, condition := range conditions {
+			matched, err := checkPreToolUseCondition(condition, input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !matched {
+				return false
+			}
+		}
+		return true
+	}
+
+	if !check() {
+		t.Error("unsigned repository: want the hook to match")
+	}
+	if err := runCommand("git -C "+repo+" config commit.gpgsign true", false, nil); err != nil {
+		t.Fatal(err)
+	}
+	if check() {
+		t.Error("signed repository: want the hook not to match")
+	}
+}
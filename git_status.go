@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// gitWorktreeDirty reports whether the working tree of the repository containing dir has uncommitted changes:
+// staged or unstaged modifications, deletions or untracked (not ignored) files.
+// inRepo is false outside a git repository.
+// `git status` is used instead of go-git, which hashes every file and is slow on large repositories.
+func gitWorktreeDirty(dir string) (dirty, inRepo bool, err error) {
+	if dir == "" {
+		return false, false, nil
+	}
+	if _, err := openGitRepository(dir); err != nil {
+		return false, false, nil
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", "-C", dir, "status", "--porcelain", "--ignore-submodules=dirty")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return false, true, fmt.Errorf("git status failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return len(bytes.TrimSpace(out)) > 0, true, nil
+}
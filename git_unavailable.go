@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Values of on_git_unavailable.
+const (
+	gitUnavailableSkip = "skip" // 条件を満たさないとする (省略時)
+	gitUnavailableWarn = "warn" // 条件を満たさないとし、systemMessageで知らせる
+	gitUnavailableDeny = "deny" // 条件のエラーとして扱う (PreToolUseはdenyになる)
+)
+
+// activeGitUnavailable is the on_git_unavailable setting of the main config for the current event.
+var activeGitUnavailable string
+
+// gitUnavailableWarnings are the systemMessage lines of the conditions skipped with on_git_unavailable: warn.
+var gitUnavailableWarnings []string
+
+// lookPathGit reports whether the git binary can be found (a variable so that tests can simulate a missing git).
+var lookPathGit = func() error {
+	_, err := exec.LookPath("git")
+	return err
+}
+
+// validateGitUnavailable checks the `on_git_unavailable` setting of a config.
+func validateGitUnavailable(policy string) error {
+	switch policy {
+	case "", gitUnavailableSkip, gitUnavailableWarn, gitUnavailableDeny:
+		return nil
+	default:
+		return fmt.Errorf("on_git_unavailable: invalid value %q (must be skip, warn or deny)", policy)
+	}
+}
+
+// gitUnavailableReason returns why a condition of conditionType can't use git in the cwd dir,
+// or "" when it can or doesn't use git. git_dirty, git_clean and git_signing_enabled run the git binary;
+// the git_branch_* conditions read the repository directly.
+func gitUnavailableReason(conditionType ConditionType, dir string) string {
+	needsBinary := false
+	needsRepository := false
+	switch conditionType {
+	case ConditionGitSigningEnabled:
+		needsBinary = true
+	case ConditionGitDirty, ConditionGitClean:
+		needsBinary, needsRepository = true, true
+	case ConditionGitBranchIs, ConditionGitBranchMatches:
+		needsRepository = true
+	default:
+		return ""
+	}
+
+	if needsBinary && lookPathGit() != nil {
+		return "git is not installed"
+	}
+	if needsRepository && !inGitRepository(dir) {
+		if dir == "" {
+			return "cwd is not set"
+		}
+		return fmt.Sprintf("%s is not in a git repository", dir)
+	}
+	return ""
+}
+
+// inGitRepository reports whether path (a directory or a file, which may not exist yet) is in a git repository.
+func inGitRepository(path string) bool {
+	if path == "" {
+		return false
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	inRepo, _ := cachedLookup(invocationCacheKey("git_repository", absPath), true, func() (bool, error) {
+		if info, err := os.Stat(absPath); err == nil && info.IsDir() {
+			_, err := openGitRepository(absPath)
+			return err == nil, nil
+		}
+		// まだ無いファイルは存在する親ディレクトリから探す
+		_, err := findGitRepository(absPath)
+		return err == nil, nil
+	})
+	return inRepo
+}
+
+// gitUnavailable applies on_git_unavailable to a condition that can't use git for reason:
+// skip and warn make the condition false (warn also reports it in systemMessage), deny fails the condition.
+func gitUnavailable(conditionType ConditionType, reason string) (bool, error) {
+	switch activeGitUnavailable {
+	case gitUnavailableDeny:
+		return false, fmt.Errorf("%s: %w: %s", conditionType, ErrGitUnavailable, reason)
+	case gitUnavailableWarn:
+		message := fmt.Sprintf("cchook: %s was skipped: %s", conditionType, reason)
+		if !slices.Contains(gitUnavailableWarnings, message) {
+			gitUnavailableWarnings = append(gitUnavailableWarnings, message)
+		}
+	}
+	hookLog.Info("git condition skipped", "type", conditionType.String(), "reason", reason)
+	return false, nil
+}
+
+// withGitUnavailableWarnings appends the warnings of on_git_unavailable: warn to the systemMessage of
+// the JSON output. The output is returned unchanged when there are none or it can't be parsed.
+func withGitUnavailableWarnings(jsonBytes []byte) []byte {
+	if len(gitUnavailableWarnings) == 0 {
+		return jsonBytes
+	}
+	var output map[string]json.RawMessage
+	if err := json.Unmarshal(jsonBytes, &output); err != nil {
+		return jsonBytes
+	}
+	var message string
+	if raw, ok := output["systemMessage"]; ok {
+		if err := json.Unmarshal(raw, &message); err != nil {
+			return jsonBytes
+		}
+	}
+	lines := slices.DeleteFunc(append([]string{message}, gitUnavailableWarnings...), func(s string) bool { return s == "" })
+	raw, err := json.Marshal(strings.Join(lines, "\n"))
+	if err != nil {
+		return jsonBytes
+	}
+	output["systemMessage"] = raw
+
+	var updated []byte
+	if strings.Contains(string(jsonBytes), "\n") {
+		updated, err = json.MarshalIndent(output, "", "  ")
+	} else {
+		updated, err = json.Marshal(output)
+	}
+	if err != nil {
+		return jsonBytes
+	}
+	return updated
+}
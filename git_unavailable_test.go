@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGitUnavailable(t *testing.T) {
+	t.Cleanup(func() {
+		activeGitUnavailable = ""
+		gitUnavailableWarnings = nil
+		resetInvocationCache()
+	})
+	notRepo := t.TempDir()
+	base := &BaseInput{Cwd: notRepo}
+
+	tests := []struct {
+		policy       string
+		wantErr      bool
+		wantWarnings int
+	}{
+		{"", false, 0},
+		{gitUnavailableSkip, false, 0},
+		{gitUnavailableWarn, false, 1},
+		{gitUnavailableDeny, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run("policy "+tt.policy, func(t *testing.T) {
+			activeGitUnavailable = tt.policy
+			gitUnavailableWarnings = nil
+			for _, condition := range []Condition{{Type: ConditionGitDirty}, {Type: ConditionGitDirty}} {
+				matched, err := checkCommonCondition(condition, base)
+				if matched || (err != nil) != tt.wantErr {
+					t.Fatalf("git_dirty outside a repository = %v, %v, want false (error %v)", matched, err, tt.wantErr)
+				}
+				if err != nil && (!errors.Is(err, ErrGitUnavailable) || !strings.Contains(err.Error(), notRepo+" is not in a git repository")) {
+					t.Errorf("Unexpected error: %v", err)
+				}
+			}
+			// 同じ理由の警告は1回だけ
+			if len(gitUnavailableWarnings) != tt.wantWarnings {
+				t.Errorf("warnings = %q, want %d", gitUnavailableWarnings, tt.wantWarnings)
+			}
+		})
+	}
+
+	t.Run("git is not installed", func(t *testing.T) {
+		original := lookPathGit
+		lookPathGit = func() error { return errors.New("not found") }
+		t.Cleanup(func() { lookPathGit = original })
+
+		// 以前はgit statusの実行エラーでフックが失敗していた
+		activeGitUnavailable = ""
+		if matched, err := checkCommonCondition(Condition{Type: ConditionGitClean}, &BaseInput{Cwd: "."}); matched || err != nil {
+			t.Errorf("git_clean without git = %v, %v, want false without an error", matched, err)
+		}
+
+		activeGitUnavailable = gitUnavailableDeny
+
+		// git_signing_enabledはリポジトリ外でも使える
+		_, err := checkCommonCondition(Condition{Type: ConditionGitSigningEnabled}, &BaseInput{})
+		if err == nil || !strings.Contains(err.Error(), "git_signing_enabled: git is not available: git is not installed") {
+			t.Errorf("git_signing_enabled error = %v", err)
+		}
+		// git_branch_*はgitのバイナリを使わない
+		if reason := gitUnavailableReason(ConditionGitBranchIs, "."); reason != "" {
+			t.Errorf("git_branch_is reason = %q, want none in this repository", reason)
+		}
+	})
+
+	t.Run("file_is_gitignored outside a repository", func(t *testing.T) {
+		activeGitUnavailable = gitUnavailableDeny
+		toolInput := &ToolInput{FilePath: filepath.Join(notRepo, "new", "file.txt")}
+		if _, err := checkToolCondition(Condition{Type: ConditionFileNotGitignored}, toolInput, notRepo); !errors.Is(err, ErrGitUnavailable) {
+			t.Errorf("file_not_gitignored error = %v, want ErrGitUnavailable", err)
+		}
+	})
+}
+
+func TestWithGitUnavailableWarnings(t *testing.T) {
+	t.Cleanup(func() { gitUnavailableWarnings = nil })
+
+	gitUnavailableWarnings = nil
+	output := []byte(`{"continue":true}`)
+	if got := withGitUnavailableWarnings(output); string(got) != string(output) {
+		t.Errorf("Output without warnings should be unchanged, got %s", got)
+	}
+
+	gitUnavailableWarnings = []string{"cchook: git_dirty was skipped: git is not installed"}
+	for _, output := range []string{`{"continue":true}`, "{\n  \"continue\": true,\n  \"systemMessage\": \"done\"\n}"} {
+		got := withGitUnavailableWarnings([]byte(output))
+		var parsed struct {
+			Continue      bool   `json:"continue"`
+			SystemMessage string `json:"systemMessage"`
+		}
+		if err := json.Unmarshal(got, &parsed); err != nil {
+			t.Fatal(err)
+		}
+		want := gitUnavailableWarnings[0]
+		if strings.Contains(output, "done") {
+			want = "done\n" + want
+		}
+		if !parsed.Continue || parsed.SystemMessage != want || strings.Contains(output, "\n") != strings.Contains(string(got), "\n") {
+			t.Errorf("withGitUnavailableWarnings(%s) = %s", output, got)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// isGitIgnored reports whether filePath is ignored by git, like `git check-ignore`:
+// files outside a repository and tracked files are never ignored.
+// Relative paths are resolved against cwd.
+func isGitIgnored(filePath, cwd string) (bool, error) {
+	absPath := filePath
+	if !filepath.IsAbs(absPath) {
+		absPath = filepath.Join(cwd, absPath)
+	}
+	absPath, err := filepath.Abs(absPath)
+	if err != nil {
+		return false, err
+	}
+
+	repo, err := findGitRepository(absPath)
+	if err != nil {
+		// Gitリポジトリではない
+		return false, nil
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return false, err
+	}
+	root := wt.Filesystem.Root()
+	relPath, err := filepath.Rel(root, absPath)
+	if err != nil || relPath == "." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return false, nil
+	}
+
+	// トラックされているファイルは.gitignoreにマッチしても無視されない
+	if tracked, err := isFileTrackedInRepo(repo, absPath); err != nil || tracked {
+		return false, err
+	}
+
+	segments := strings.Split(filepath.ToSlash(relPath), "/")
+	info, err := os.Stat(absPath)
+	isDir := err == nil && info.IsDir()
+	return gitignore.NewMatcher(gitignorePatterns(root, segments)).Match(segments, isDir), nil
+}
+
+// gitignorePatterns collects the patterns that apply to the path segments under root, lowest priority first:
+// the system and global excludes files, info/exclude of the git dir and the .gitignore of each directory down to the file.
+// Unlike gitignore.ReadPatterns it doesn't walk the whole worktree.
+func gitignorePatterns(root string, segments []string) []gitignore.Pattern {
+	rootFS := osfs.New("/")
+	ps, _ := gitignore.LoadSystemPatterns(rootFS)
+	global, _ := gitignore.LoadGlobalPatterns(rootFS)
+	if global == nil {
+		// core.excludesfileが未設定の場合のgitのデフォルト
+		if configDir, err := os.UserConfigDir(); err == nil {
+			global = readGitignoreFile(filepath.Join(configDir, "git", "ignore"), nil)
+		}
+	}
+	ps = append(ps, global...)
+	// worktreeではinfo/excludeはメインのリポジトリ (common dir) にある
+	_, commonDir := gitDirs(root)
+	ps = append(ps, readGitignoreFile(filepath.Join(commonDir, "info", "exclude"), nil)...)
+
+	for i := range segments {
+		domain := segments[:i]
+		ps = append(ps, readGitignoreFile(filepath.Join(root, filepath.Join(domain...), ".gitignore"), domain)...)
+	}
+	return ps
+}
+
+// readGitignoreFile parses a gitignore file whose patterns are relative to domain (nil if it doesn't exist).
+func readGitignoreFile(path string, domain []string) []gitignore.Pattern {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var ps []gitignore.Pattern
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+			continue
+		}
+		ps = append(ps, gitignore.ParsePattern(line, domain))
+	}
+	return ps
+}
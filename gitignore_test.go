@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newGitignoreTestRepo creates a git repository with .gitignore files, info/exclude and a tracked file matching an ignore pattern.
+// HOME and XDG_CONFIG_HOME are isolated so that the user's global excludes file doesn't apply.
+func newGitignoreTestRepo(t *testing.T) string {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	dir := t.TempDir()
+	files := map[string]string{
+		".gitignore":         "vendor/\n*.gen.go\n# comment\n!keep.gen.go\n/build\n",
+		"web/.gitignore":     "dist\n",
+		"web/src/app.ts":     "",
+		"vendor/lib/lib.go":  "",
+		"tracked.gen.go":     "",
+		"api/types.gen.go":   "",
+		"api/keep.gen.go":    "",
+		"sub/build/out.txt":  "",
+		"local/scratch.txt":  "",
+		"web/dist/bundle.js": "",
+	}
+	if err := runCommand("cd "+dir+" && git init -q", false, nil); err != nil {
+		t.Fatalf("Failed to init git repo: %v", err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".git", "info", "exclude"), []byte("local/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runCommand("cd "+dir+" && git add -f tracked.gen.go", false, nil); err != nil {
+		t.Fatalf("Failed to add file to git: %v", err)
+	}
+	return dir
+}
+
+func TestIsGitIgnored(t *testing.T) {
+	dir := newGitignoreTestRepo(t)
+
+	tests := []struct {
+		name     string
+		filePath string
+		cwd      string
+		want     bool
+	}{
+		{"ignored directory", filepath.Join(dir, "vendor/lib/lib.go"), "", true},
+		{"ignored pattern in subdirectory", filepath.Join(dir, "api/types.gen.go"), "", true},
+		{"negated pattern", filepath.Join(dir, "api/keep.gen.go"), "", false},
+		{"tracked file is not ignored", filepath.Join(dir, "tracked.gen.go"), "", false},
+		{"anchored pattern only matches at the root", filepath.Join(dir, "sub/build/out.txt"), "", false},
+		{"nested .gitignore", filepath.Join(dir, "web/dist/bundle.js"), "", true},
+		{"nested .gitignore does not apply elsewhere", filepath.Join(dir, "dist"), "", false},
+		{"info/exclude", filepath.Join(dir, "local/scratch.txt"), "", true},
+		{"not ignored", filepath.Join(dir, "web/src/app.ts"), "", false},
+		{"not yet created file", filepath.Join(dir, "vendor/new.go"), "", true},
+		{"relative to cwd", "lib/lib.go", filepath.Join(dir, "vendor"), true},
+		{"outside the repository", filepath.Join(t.TempDir(), "vendor/lib.go"), "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := isGitIgnored(tt.filePath, tt.cwd)
+			if err != nil {
+				t.Fatalf("isGitIgnored() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("isGitIgnored(%q) = %v, want %v", tt.filePath, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// matchGlob reports whether name (a slash-separated path) matches a doublestar glob pattern.
+// Besides the path.Match syntax (*, ?, [...]) within a path segment, "**" matches zero or more
+// whole segments and {a,b} matches any of the comma-separated alternatives.
+func matchGlob(pattern, name string) (bool, error) {
+	alternatives, err := expandBraces(pattern)
+	if err != nil {
+		return false, err
+	}
+	names := strings.Split(name, "/")
+	for _, alternative := range alternatives {
+		segments := strings.Split(alternative, "/")
+		if err := validateGlobSegments(segments); err != nil {
+			return false, err
+		}
+		if matchGlobSegments(segments, names) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// validateGlob checks the syntax of a doublestar glob pattern.
+func validateGlob(pattern string) error {
+	alternatives, err := expandBraces(pattern)
+	if err != nil {
+		return err
+	}
+	for _, alternative := range alternatives {
+		if err := validateGlobSegments(strings.Split(alternative, "/")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateGlobSegments checks that every segment is a valid path.Match pattern.
+func validateGlobSegments(segments []string) error {
+	for _, segment := range segments {
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("invalid glob pattern segment %q: %w", segment, err)
+		}
+	}
+	return nil
+}
+
+// matchGlobSegments matches path segments against pattern segments ("**" matches any number of segments).
+func matchGlobSegments(patterns, names []string) bool {
+	if len(patterns) == 0 {
+		return len(names) == 0
+	}
+	if patterns[0] == "**" {
+		for i := 0; i <= len(names); i++ {
+			if matchGlobSegments(patterns[1:], names[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(names) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(patterns[0], names[0]); !ok {
+		return false
+	}
+	return matchGlobSegments(patterns[1:], names[1:])
+}
+
+// expandBraces expands the first {a,b,...} group of pattern (recursively, for nested and later groups).
+func expandBraces(pattern string) ([]string, error) {
+	start := strings.IndexByte(pattern, '{')
+	if start < 0 {
+		if strings.IndexByte(pattern, '}') >= 0 {
+			return nil, fmt.Errorf("invalid glob pattern %q: unmatched }", pattern)
+		}
+		return []string{pattern}, nil
+	}
+
+	depth := 0
+	var options []string
+	last := start + 1
+	for i := start; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '{':
+			depth++
+		case ',':
+			if depth == 1 {
+				options = append(options, pattern[last:i])
+				last = i + 1
+			}
+		case '}':
+			depth--
+			if depth > 0 {
+				continue
+			}
+			options = append(options, pattern[last:i])
+			var expanded []string
+			for _, option := range options {
+				rest, err := expandBraces(pattern[:start] + option + pattern[i+1:])
+				if err != nil {
+					return nil, err
+				}
+				expanded = append(expanded, rest...)
+			}
+			return expanded, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid glob pattern %q: unmatched {", pattern)
+}
+
+// globTarget returns the path a file_path_matches pattern is matched against:
+// the absolute file path for absolute patterns, and the path relative to cwd for relative patterns.
+// ok is false when a relative pattern can't apply (the file is outside cwd).
+func globTarget(pattern, filePath, cwd string) (string, bool) {
+	if !filepath.IsAbs(filePath) && cwd != "" {
+		filePath = filepath.Join(cwd, filePath)
+	}
+	filePath = filepath.Clean(filePath)
+	if path.IsAbs(pattern) || filepath.IsAbs(pattern) || cwd == "" || !filepath.IsAbs(filePath) {
+		return filepath.ToSlash(filePath), true
+	}
+
+	rel, err := filepath.Rel(cwd, filePath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"src/**/*.test.ts", "src/a.test.ts", true},
+		{"src/**/*.test.ts", "src/components/ui/button.test.ts", true},
+		{"src/**/*.test.ts", "src/button.ts", false},
+		{"src/**/*.test.ts", "lib/src/a.test.ts", false},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "cmd/tool/main.go", true},
+		{"*.go", "cmd/main.go", false},
+		{"docs/**", "docs/guide/index.md", true},
+		{"docs/**", "docs", true},
+		{"**/testdata/**", "pkg/testdata/golden/out.json", true},
+		{"src/*.{ts,tsx}", "src/app.tsx", true},
+		{"src/*.{ts,tsx}", "src/app.js", false},
+		{"{cmd,internal}/**/*_test.go", "internal/x/y_test.go", true},
+		{"/etc/**", "/etc/hosts", true},
+		{"file?.[ch]", "file1.c", true},
+	}
+	for _, tt := range tests {
+		got, err := matchGlob(tt.pattern, tt.name)
+		if err != nil {
+			t.Errorf("matchGlob(%q, %q) error = %v", tt.pattern, tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+
+	for _, pattern := range []string{"src/[a-", "src/{a,b", "src/a}"} {
+		if _, err := matchGlob(pattern, "src/a"); err == nil {
+			t.Errorf("matchGlob(%q) expected an error", pattern)
+		}
+		if err := validateGlob(pattern); err == nil {
+			t.Errorf("validateGlob(%q) expected an error", pattern)
+		}
+	}
+}
+
+func TestGlobTarget(t *testing.T) {
+	tests := []struct {
+		pattern, filePath, cwd string
+		want                   string
+		wantOK                 bool
+	}{
+		{"src/**", "/repo/src/a.go", "/repo", "src/a.go", true},
+		{"src/**", "src/./a.go", "/repo", "src/a.go", true},
+		{"src/**", "/other/src/a.go", "/repo", "", false},
+		{"/repo/**", "src/a.go", "/repo", "/repo/src/a.go", true},
+		{"src/**", "src/a.go", "", "src/a.go", true},
+	}
+	for _, tt := range tests {
+		got, ok := globTarget(tt.pattern, tt.filePath, tt.cwd)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("globTarget(%q, %q, %q) = %q, %v, want %q, %v", tt.pattern, tt.filePath, tt.cwd, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// updateGolden regenerates testdata/golden/*: go test -run TestGoldenOutput -update ./...
+var updateGolden = flag.Bool("update", false, "update golden files in testdata/golden")
+
+// goldenCase is a canonical config+input fixture whose stdout JSON is compared byte-for-byte
+// against testdata/golden/<event>/<name>.json.
+type goldenCase struct {
+	event  HookEventType
+	name   string
+	config string
+	input  string
+}
+
+var goldenCases = []goldenCase{
+	// PreToolUse
+	{
+		event:  PreToolUse,
+		name:   "no_match",
+		config: "PreToolUse: []\n",
+		input:  `{"session_id":"s1","transcript_path":"/tmp/t","cwd":"/tmp","hook_event_name":"PreToolUse","tool_name":"Bash","tool_input":{"command":"ls"}}`,
+	},
+	{
+		event: PreToolUse,
+		name:  "deny",
+		config: `PreToolUse:
+  - matcher: "Bash"
+    conditions:
+      - type: command_starts_with
+        value: "rm"
+    actions:
+      - type: output
+        message: "rm is not allowed: {.tool_input.command}"
+        permission_decision: "deny"
+`,
+		input: `{"session_id":"s1","transcript_path":"/tmp/t","cwd":"/tmp","hook_event_name":"PreToolUse","tool_name":"Bash","tool_input":{"command":"rm -rf build"}}`,
+	},
+	{
+		event: PreToolUse,
+		name:  "allow_with_additional_context",
+		config: `PreToolUse:
+  - matcher: "Write"
+    actions:
+      - type: output
+        message: "writing {.tool_input.file_path}"
+        permission_decision: "allow"
+        additional_context: "Remember to run gofmt"
+`,
+		input: `{"session_id":"s1","transcript_path":"/tmp/t","cwd":"/tmp","hook_event_name":"PreToolUse","tool_name":"Write","tool_input":{"file_path":"main.go","content":"package main"}}`,
+	},
+	// PostToolUse
+	{
+		event:  PostToolUse,
+		name:   "no_match",
+		config: "PostToolUse: []\n",
+		input:  `{"session_id":"s1","transcript_path":"/tmp/t","cwd":"/tmp","hook_event_name":"PostToolUse","tool_name":"Write","tool_input":{"file_path":"main.go"},"tool_response":{"success":true}}`,
+	},
+	{
+		event: PostToolUse,
+		name:  "block",
+		config: `PostToolUse:
+  - matcher: "Write"
+    conditions:
+      - type: file_extension
+        value: ".go"
+    actions:
+      - type: output
+        message: "run tests for {.tool_input.file_path}"
+        decision: "block"
+        reason: "tests must pass"
+`,
+		input: `{"session_id":"s1","transcript_path":"/tmp/t","cwd":"/tmp","hook_event_name":"PostToolUse","tool_name":"Write","tool_input":{"file_path":"main.go"},"tool_response":{"success":true}}`,
+	},
+	// PermissionRequest
+	{
+		event:  PermissionRequest,
+		name:   "no_match",
+		config: "PermissionRequest: []\n",
+		input:  `{"session_id":"s1","transcript_path":"/tmp/t","cwd":"/tmp","hook_event_name":"PermissionRequest","tool_name":"Bash","tool_input":{"command":"ls"}}`,
+	},
+	{
+		event: PermissionRequest,
+		name:  "deny",
+		config: `PermissionRequest:
+  - matcher: "Bash"
+    actions:
+      - type: output
+        message: "denied: {.tool_input.command}"
+        behavior: "deny"
+        interrupt: true
+`,
+		input: `{"session_id":"s1","transcript_path":"/tmp/t","cwd":"/tmp","hook_event_name":"PermissionRequest","tool_name":"Bash","tool_input":{"command":"curl example.com"}}`,
+	},
+	// Notification
+	{
+		event:  Notification,
+		name:   "no_match",
+		config: "Notification: []\n",
+		input:  `{"session_id":"s1","transcript_path":"/tmp/t","cwd":"/tmp","hook_event_name":"Notification","message":"waiting","notification_type":"idle_prompt"}`,
+	},
+	{
+		event: Notification,
+		name:  "output",
+		config: `Notification:
+  - actions:
+      - type: output
+        message: "notified: {.message}"
+`,
+		input: `{"session_id":"s1","transcript_path":"/tmp/t","cwd":"/tmp","hook_event_name":"Notification","message":"waiting","notification_type":"idle_prompt"}`,
+	},
+	// Stop
+	{
+		event:  Stop,
+		name:   "no_match",
+		config: "Stop: []\n",
+		input:  `{"session_id":"s1","transcript_path":"/tmp/t","cwd":"/tmp","hook_event_name":"Stop","stop_hook_active":false}`,
+	},
+	{
+		event: Stop,
+		name:  "block",
+		config: `Stop:
+  - actions:
+      - type: output
+        message: "not yet"
+        decision: "block"
+        reason: "tests are failing"
+`,
+		input: `{"session_id":"s1","transcript_path":"/tmp/t","cwd":"/tmp","hook_event_name":"Stop","stop_hook_active":false}`,
+	},
+	// SubagentStop
+	{
+		event:  SubagentStop,
+		name:   "no_match",
+		config: "SubagentStop: []\n",
+		input:  `{"session_id":"s1","transcript_path":"/tmp/t","cwd":"/tmp","hook_event_name":"SubagentStop","stop_hook_active":false}`,
+	},
+	{
+		event: SubagentStop,
+		name:  "block",
+		config: `SubagentStop:
+  - actions:
+      - type: output
+        message: "keep going"
+        decision: "block"
+        reason: "subagent has unfinished work"
+`,
+		input: `{"session_id":"s1","transcript_path":"/tmp/t","cwd":"/tmp","hook_event_name":"SubagentStop","stop_hook_active":false}`,
+	},
+	// SubagentStart
+	{
+		event:  SubagentStart,
+		name:   "no_match",
+		config: "SubagentStart: []\n",
+		input:  `{"session_id":"s1","transcript_path":"/tmp/t","cwd":"/tmp","hook_event_name":"SubagentStart","agent_id":"a1","agent_type":"Explore"}`,
+	},
+	{
+		event: SubagentStart,
+		name:  "output",
+		config: `SubagentStart:
+  - actions:
+      - type: output
+        message: "agent {.agent_type} started"
+`,
+		input: `{"session_id":"s1","transcript_path":"/tmp/t","cwd":"/tmp","hook_event_name":"SubagentStart","agent_id":"a1","agent_type":"Explore"}`,
+	},
+	// PreCompact
+	{
+		event:  PreCompact,
+		name:   "no_match",
+		config: "PreCompact: []\n",
+		input:  `{"session_id":"s1","transcript_path":"/tmp/t","cwd":"/tmp","hook_event_name":"PreCompact","trigger":"manual","custom_instructions":""}`,
+	},
+	{
+		event: PreCompact,
+		name:  "output",
+		config: `PreCompact:
+  - matcher: "manual"
+    actions:
+      - type: output
+        message: "compacting ({.trigger})"
+`,
+		input: `{"session_id":"s1","transcript_path":"/tmp/t","cwd":"/tmp","hook_event_name":"PreCompact","trigger":"manual","custom_instructions":""}`,
+	},
+	// SessionStart
+	{
+		event:  SessionStart,
+		name:   "no_match",
+		config: "SessionStart: []\n",
+		input:  `{"session_id":"s1","transcript_path":"/tmp/t","cwd":"/tmp","hook_event_name":"SessionStart","source":"startup"}`,
+	},
+	{
+		event: SessionStart,
+		name:  "output",
+		config: `SessionStart:
+  - matcher: "startup"
+    actions:
+      - type: output
+        message: "welcome ({.source})"
+`,
+		input: `{"session_id":"s1","transcript_path":"/tmp/t","cwd":"/tmp","hook_event_name":"SessionStart","source":"startup"}`,
+	},
+	// SessionEnd
+	{
+		event:  SessionEnd,
+		name:   "no_match",
+		config: "SessionEnd: []\n",
+		input:  `{"session_id":"s1","transcript_path":"/tmp/t","cwd":"/tmp","hook_event_name":"SessionEnd","reason":"clear"}`,
+	},
+	{
+		event: SessionEnd,
+		name:  "output",
+		config: `SessionEnd:
+  - actions:
+      - type: output
+        message: "bye ({.reason})"
+`,
+		input: `{"session_id":"s1","transcript_path":"/tmp/t","cwd":"/tmp","hook_event_name":"SessionEnd","reason":"clear"}`,
+	},
+	// UserPromptSubmit
+	{
+		event:  UserPromptSubmit,
+		name:   "no_match",
+		config: "UserPromptSubmit: []\n",
+		input:  `{"session_id":"s1","transcript_path":"/tmp/t","cwd":"/tmp","hook_event_name":"UserPromptSubmit","prompt":"hello"}`,
+	},
+	{
+		event: UserPromptSubmit,
+		name:  "block",
+		config: `UserPromptSubmit:
+  - conditions:
+      - type: prompt_regex
+        value: "password"
+    actions:
+      - type: output
+        message: "prompt contains a secret"
+        decision: "block"
+`,
+		input: `{"session_id":"s1","transcript_path":"/tmp/t","cwd":"/tmp","hook_event_name":"UserPromptSubmit","prompt":"my password is hunter2"}`,
+	},
+}
+
+// TestGoldenOutput verifies the exact JSON envelope (field order, omitempty behavior) written to stdout for each event.
+func TestGoldenOutput(t *testing.T) {
+	for _, tc := range goldenCases {
+		t.Run(string(tc.event)+"/"+tc.name, func(t *testing.T) {
+			config, err := parseConfig("golden.yaml", []byte(tc.config))
+			if err != nil {
+				t.Fatalf("Failed to parse config: %v", err)
+			}
+
+			got := renderGoldenOutput(t, tc.event, config, tc.input)
+
+			goldenPath := filepath.Join("testdata", "golden", string(tc.event), tc.name+".json")
+			if *updateGolden {
+				if err := os.MkdirAll(filepath.Dir(goldenPath), 0755); err != nil {
+					t.Fatalf("Failed to create golden dir: %v", err)
+				}
+				if err := os.WriteFile(goldenPath, got, 0644); err != nil {
+					t.Fatalf("Failed to write golden file: %v", err)
+				}
+			}
+
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("Failed to read golden file (run with -update to create): %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("Output mismatch for %s\n--- got ---\n%s\n--- want ---\n%s", goldenPath, got, want)
+			}
+		})
+	}
+}
+
+// renderGoldenOutput feeds input via stdin and returns the bytes main() would print to stdout.
+func renderGoldenOutput(t *testing.T, event HookEventType, config *Config, input string) []byte {
+	t.Helper()
+
+	withStdin(t, input)
+
+	var output any
+	var err error
+	switch event {
+	case PreToolUse:
+		output, err = RunPreToolUseHooks(config)
+	case PostToolUse:
+		output, err = RunPostToolUseHooks(config)
+	case Notification:
+		output, err = RunNotificationHooks(config)
+	case Stop:
+		output, err = RunStopHooks(config)
+	case SubagentStop:
+		output, err = RunSubagentStopHooks(config)
+	case SubagentStart:
+		output, err = RunSubagentStartHooks(config)
+	case PreCompact:
+		output, err = RunPreCompactHooks(config)
+	case SessionStart:
+		output, err = RunSessionStartHooks(config)
+	case SessionEnd:
+		output, err = RunSessionEndHooks(config)
+	case UserPromptSubmit:
+		output, err = RunUserPromptSubmitHooks(config)
+	case PermissionRequest:
+		// PermissionRequestは自身でstdoutに出力するためキャプチャする
+		var err error
+		stdout := captureStdout(t, func() {
+			err = RunPermissionRequestHooks(config)
+		})
+		if err != nil {
+			t.Fatalf("RunPermissionRequestHooks() error = %v", err)
+		}
+		return []byte(stdout)
+	default:
+		t.Fatalf("unsupported event type: %s", event)
+	}
+	if err != nil {
+		t.Fatalf("Run%sHooks() error = %v", event, err)
+	}
+
+	jsonBytes, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		t.Fatalf("Failed to marshal output: %v", err)
+	}
+	// main()はfmt.Printlnで出力する
+	return append(jsonBytes, '\n')
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// hookIDLength is the number of hex digits of a hook ID.
+const hookIDLength = 7
+
+// disabledHooksPath returns the file listing the IDs of the hooks disabled with `cchook -command hooks disable`.
+// It is a variable so that tests can use a temporary file.
+var disabledHooksPath = func() string {
+	return filepath.Join(filepath.Dir(getDefaultConfigPath()), "disabled-hooks.json")
+}
+
+// hookIDs returns the stable IDs of the hooks of eventType: a hash of the event and the hook's name,
+// or of its definition for hooks without a name (numbered when several hooks are identical),
+// so that the IDs don't change when the hooks are reordered.
+func hookIDs(config *Config, eventType HookEventType) []string {
+	hooks, n := configHooksForEvent(config, eventType)
+	v := reflect.ValueOf(hooks)
+	ids := make([]string, n)
+	seen := map[string]int{}
+	for i := range n {
+		hook := v.Index(i)
+		key := "name\x00" + hook.FieldByName("Name").String()
+		if key == "name\x00" {
+			data, _ := yaml.Marshal(hook.Interface())
+			key = "hook\x00" + string(data)
+		}
+		// 同じ内容のフックは出現順で区別する
+		seen[key]++
+		if seen[key] > 1 {
+			key += fmt.Sprintf("\x00%d", seen[key])
+		}
+		sum := sha256.Sum256([]byte(string(eventType) + "\x00" + key))
+		ids[i] = hex.EncodeToString(sum[:])[:hookIDLength]
+	}
+	return ids
+}
+
+// hookRef identifies the hook with the given ID in error messages and warnings, e.g. hook[PreToolUse][id=ab12f3c].
+func hookRef(eventType HookEventType, id string) string {
+	return fmt.Sprintf("hook[%s][id=%s]", eventType, id)
+}
+
+// loadDisabledHooks returns the IDs of the disabled hooks (none when the file doesn't exist).
+func loadDisabledHooks() ([]string, error) {
+	data, err := os.ReadFile(disabledHooksPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read disabled hooks: %w", err)
+	}
+	var ids []string
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return nil, fmt.Errorf("invalid disabled hooks file %s: %w", disabledHooksPath(), err)
+	}
+	return ids, nil
+}
+
+// saveDisabledHooks writes the IDs of the disabled hooks, sorted.
+func saveDisabledHooks(ids []string) error {
+	slices.Sort(ids)
+	data, err := json.MarshalIndent(slices.Compact(ids), "", "  ")
+	if err != nil {
+		return err
+	}
+	path := disabledHooksPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// withoutDisabledHooks returns config without the disabled hooks of eventType, keeping the order of the others.
+// config itself is not modified (serve caches it across events).
+func withoutDisabledHooks(config *Config, eventType HookEventType) (*Config, error) {
+	disabled, err := loadDisabledHooks()
+	if err != nil || len(disabled) == 0 {
+		return config, err
+	}
+	ids := hookIDs(config, eventType)
+	copied := *config
+	// Configのフィールド名はイベント名と同じ
+	field := reflect.ValueOf(&copied).Elem().FieldByName(string(eventType))
+	if !field.IsValid() {
+		return config, nil
+	}
+	kept := reflect.MakeSlice(field.Type(), 0, field.Len())
+	for i, id := range ids {
+		if slices.Contains(disabled, id) {
+			hookLog.Info("hook disabled", "hook_id", id)
+			continue
+		}
+		kept = reflect.Append(kept, field.Index(i))
+	}
+	field.Set(kept)
+	return &copied, nil
+}
+
+// hookSummary describes a hook in the hooks list: its name, or its matcher and action types.
+func hookSummary(hook reflect.Value) string {
+	if name := hook.FieldByName("Name").String(); name != "" {
+		return name
+	}
+	var parts []string
+	if matcher := hook.FieldByName("Matcher"); matcher.IsValid() && matcher.String() != "" {
+		parts = append(parts, fmt.Sprintf("matcher %q", matcher.String()))
+	}
+	var types []string
+	for _, action := range hook.FieldByName("Actions").Interface().([]Action) {
+		types = append(types, action.Type)
+	}
+	return strings.Join(append(parts, strings.Join(types, ", ")), ": ")
+}
+
+// runHooksCommand lists the hooks of the config with their IDs (in a fixed order: by event, then as configured),
+// or disables or enables hooks by ID.
+func runHooksCommand(w io.Writer, config *Config, args []string) error {
+	if len(args) == 0 || !slices.Contains([]string{"list", "disable", "enable"}, args[0]) || (args[0] == "list") != (len(args) == 1) {
+		return errors.New("usage: cchook -command hooks list | disable <id> ... | enable <id> ...")
+	}
+	disabled, err := loadDisabledHooks()
+	if err != nil {
+		return err
+	}
+
+	known := map[string]HookEventType{}
+	for _, eventType := range policyUIEventOrder {
+		hooks, _ := configHooksForEvent(config, eventType)
+		v := reflect.ValueOf(hooks)
+		for i, id := range hookIDs(config, eventType) {
+			known[id] = eventType
+			if args[0] == "list" {
+				state := ""
+				if slices.Contains(disabled, id) {
+					state = " (disabled)"
+				}
+				fmt.Fprintf(w, "%s  %-17s %d  %s%s\n", id, eventType, i+1, hookSummary(v.Index(i)), state)
+			}
+		}
+	}
+	if args[0] == "list" {
+		return nil
+	}
+
+	for _, id := range args[1:] {
+		eventType, ok := known[id]
+		switch {
+		case args[0] == "disable" && !ok:
+			return fmt.Errorf("unknown hook ID %q (see cchook -command hooks list)", id)
+		case args[0] == "disable":
+			disabled = append(disabled, id)
+			fmt.Fprintf(w, "Disabled %s\n", hookRef(eventType, id))
+		case ok:
+			disabled = slices.DeleteFunc(disabled, func(d string) bool { return d == id })
+			fmt.Fprintf(w, "Enabled %s\n", hookRef(eventType, id))
+		default:
+			// 設定から消えたフックのIDも有効に戻せる
+			disabled = slices.DeleteFunc(disabled, func(d string) bool { return d == id })
+			fmt.Fprintf(w, "Enabled hook id=%s\n", id)
+		}
+	}
+	return saveDisabledHooks(disabled)
+}
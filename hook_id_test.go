@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withDisabledHooksFile makes the disabled hooks use a temporary file.
+func withDisabledHooksFile(t *testing.T) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "disabled-hooks.json")
+	original := disabledHooksPath
+	disabledHooksPath = func() string { return path }
+	t.Cleanup(func() { disabledHooksPath = original })
+}
+
+func TestHookIDs(t *testing.T) {
+	lint := StopHook{Actions: []Action{{Type: "command", Command: "make lint"}}}
+	test := StopHook{Actions: []Action{{Type: "command", Command: "make test"}}}
+	named := StopHook{Name: "notify", Actions: []Action{{Type: "output", Message: "done"}}}
+
+	ids := hookIDs(&Config{Stop: []StopHook{lint, test, named}}, Stop)
+	if len(ids) != 3 || len(ids[0]) != hookIDLength || ids[0] == ids[1] {
+		t.Fatalf("hookIDs() = %v, want 3 distinct IDs", ids)
+	}
+
+	// 並べ替えても同じID
+	reordered := hookIDs(&Config{Stop: []StopHook{named, test, lint}}, Stop)
+	if reordered[0] != ids[2] || reordered[1] != ids[1] || reordered[2] != ids[0] {
+		t.Errorf("hookIDs() after reordering = %v, want %v reversed", reordered, ids)
+	}
+	// 名前のあるフックは中身を変えても同じID
+	named.Actions[0].Message = "finished"
+	if got := hookIDs(&Config{Stop: []StopHook{named}}, Stop)[0]; got != ids[2] {
+		t.Errorf("hookIDs() of the edited named hook = %s, want %s", got, ids[2])
+	}
+	// 同じ内容のフックも区別し、イベントが違えば別のID
+	if dup := hookIDs(&Config{Stop: []StopHook{lint, lint}}, Stop); dup[0] != ids[0] || dup[1] == dup[0] {
+		t.Errorf("hookIDs() of identical hooks = %v", dup)
+	}
+	if other := hookIDs(&Config{SubagentStop: []SubagentStopHook{{Actions: lint.Actions}}}, SubagentStop); other[0] == ids[0] {
+		t.Errorf("hookIDs() of the same hook in another event = %v, want a different ID", other)
+	}
+}
+
+func TestRunHooksCommand(t *testing.T) {
+	withDisabledHooksFile(t)
+	config := &Config{
+		PreToolUse: []PreToolUseHook{{Matcher: "Bash", Actions: []Action{{Type: "output", Message: "no"}}}},
+		Stop: []StopHook{
+			{Name: "tests", Actions: []Action{{Type: "command", Command: "make test"}}},
+			{Actions: []Action{{Type: "output", Message: "bye"}}},
+		},
+	}
+	stopIDs := hookIDs(config, Stop)
+
+	run := func(args ...string) string {
+		t.Helper()
+		var out bytes.Buffer
+		if err := runHooksCommand(&out, config, args); err != nil {
+			t.Fatalf("runHooksCommand(%v) error = %v", args, err)
+		}
+		return out.String()
+	}
+
+	want := hookIDs(config, PreToolUse)[0] + `  PreToolUse        1  matcher "Bash": output` + "\n" +
+		stopIDs[0] + "  Stop              1  tests\n" +
+		stopIDs[1] + "  Stop              2  output\n"
+	if got := run("list"); got != want {
+		t.Errorf("hooks list =\n%s\nwant:\n%s", got, want)
+	}
+
+	if got := run("disable", stopIDs[0]); got != "Disabled hook[Stop][id="+stopIDs[0]+"]\n" {
+		t.Errorf("hooks disable = %q", got)
+	}
+	if got := run("list"); !strings.Contains(got, "tests (disabled)") {
+		t.Errorf("hooks list after disable = %q", got)
+	}
+	filtered, err := withoutDisabledHooks(config, Stop)
+	if err != nil || len(filtered.Stop) != 1 || filtered.Stop[0].Name != "" || len(config.Stop) != 2 {
+		t.Errorf("withoutDisabledHooks() = %+v, %v, want only the second hook (config unchanged)", filtered.Stop, err)
+	}
+
+	run("enable", stopIDs[0])
+	if filtered, err := withoutDisabledHooks(config, Stop); err != nil || len(filtered.Stop) != 2 {
+		t.Errorf("withoutDisabledHooks() after enable = %+v, %v", filtered.Stop, err)
+	}
+
+	for _, args := range [][]string{nil, {"list", "x"}, {"disable"}, {"remove", "x"}, {"disable", "0000000"}} {
+		if err := runHooksCommand(&bytes.Buffer{}, config, args); err == nil {
+			t.Errorf("runHooksCommand(%v) should fail", args)
+		}
+	}
+}
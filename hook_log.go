@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Formats of the `log:` block.
+const (
+	logFormatJSON = "json"
+	logFormatText = "text"
+)
+
+// hookLogLevels maps the `level` of the `log:` block to slog levels.
+var hookLogLevels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+}
+
+// hookLog records hook evaluations of the current event. Records are discarded unless `log:` is configured.
+var hookLog = slog.New(slog.DiscardHandler)
+
+// eventTraceID identifies the current event in the log and the decision log (see `cchook -command explain-decision`).
+var eventTraceID string
+
+// newTraceID returns a random trace ID of an event.
+func newTraceID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// hookLogStart is the time the event started being processed, for the duration of the final output.
+var hookLogStart = time.Now()
+
+// validateLogConfig checks the `log:` block of a config.
+func validateLogConfig(c *LogConfig) error {
+	if c == nil {
+		return nil
+	}
+	if _, ok := hookLogLevels[c.Level]; c.Level != "" && !ok {
+		return fmt.Errorf("log: invalid level %q (must be debug, info, warn or error)", c.Level)
+	}
+	if c.Format != "" && c.Format != logFormatJSON && c.Format != logFormatText {
+		return fmt.Errorf("log: invalid format %q (must be json or text)", c.Format)
+	}
+	return nil
+}
+
+// hookLogPath returns the log file of the `log:` block ("~/" is expanded to the home directory).
+func hookLogPath(c *LogConfig) string {
+	if c.Path == "" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			dir = os.TempDir()
+		}
+		return filepath.Join(dir, "cchook", "cchook.log")
+	}
+	return expandHomeDir(c.Path)
+}
+
+// hookLogFile is the log file opened by setupHookLog (nil when there is none).
+var hookLogFile *os.File
+
+// resetHookLog closes the log file and discards records until setupHookLog is called again,
+// so that `cchook -command serve` starts every event like a new process.
+func resetHookLog() {
+	if hookLogFile != nil {
+		_ = hookLogFile.Close()
+		hookLogFile = nil
+	}
+	hookLog = slog.New(slog.DiscardHandler)
+	hookLogStart = time.Now()
+}
+
+// setupHookLog opens the log file of the `log:` block and makes hookLog write the records of eventType to it.
+// The file is opened in append mode and left open until the process exits (or resetHookLog).
+func setupHookLog(c *LogConfig, eventType HookEventType) error {
+	if err := validateLogConfig(c); err != nil {
+		return err
+	}
+	path := hookLogPath(c)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	sessionID := ""
+	rawInput, inputErr := prefetchInput()
+	if inputErr == nil {
+		sessionID = inputSessionID(rawInput)
+	}
+	hookLogFile = f
+	hookLog = newHookLogger(f, c).With("event", string(eventType), "session_id", sessionID, "pid", os.Getpid(), "trace_id", eventTraceID)
+	hookLog.Info("event started")
+	if inputErr == nil && json.Valid(rawInput) {
+		hookLog.Debug("event input", "input", json.RawMessage(compactJSON(rawInput)))
+	}
+	return nil
+}
+
+// newHookLogger returns a logger writing the records of the `log:` block to w.
+func newHookLogger(w io.Writer, c *LogConfig) *slog.Logger {
+	level := slog.LevelInfo
+	if l, ok := hookLogLevels[c.Level]; ok {
+		level = l
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	if c.Format == logFormatText {
+		return slog.New(slog.NewTextHandler(w, opts))
+	}
+	return slog.New(slog.NewJSONHandler(w, opts))
+}
+
+// logMatcherMismatch records a hook skipped because its matcher didn't match value.
+func logMatcherMismatch(matcher, value string) {
+	hookLog.Debug("matcher did not match", "matcher", matcher, "value", value)
+}
+
+// logCondition records the result of a single condition of a hook.
+func logCondition(condition Condition, matched bool, err error) {
+	if err != nil {
+		hookLog.Warn("condition error", "type", condition.Type.String(), "value", condition.Value, "error", err.Error())
+		return
+	}
+	hookLog.Debug("condition evaluated", "type", condition.Type.String(), "value", condition.Value, "matched", matched)
+}
+
+// matchedHookIDs are the IDs of the hooks matched by the current event, exported with its decision.
+// Hooks of an event may run concurrently, so the slice is guarded by matchedHookIDsMutex.
+var (
+	matchedHookIDs      []string
+	matchedHookIDsMutex sync.Mutex
+)
+
+// resetMatchedHookIDs forgets the hooks matched by the previous event.
+func resetMatchedHookIDs() {
+	matchedHookIDsMutex.Lock()
+	matchedHookIDs = nil
+	matchedHookIDsMutex.Unlock()
+}
+
+// matchedHooks returns a copy of the IDs of the hooks matched by the current event.
+func matchedHooks() []string {
+	matchedHookIDsMutex.Lock()
+	defer matchedHookIDsMutex.Unlock()
+	return slices.Clone(matchedHookIDs)
+}
+
+// logHook records whether the hook with the given ID at index (0-based) is executed.
+func logHook(id string, index int, executed bool) {
+	if executed {
+		matchedHookIDsMutex.Lock()
+		matchedHookIDs = append(matchedHookIDs, id)
+		matchedHookIDsMutex.Unlock()
+		hookLog.Info("hook matched", "hook", index, "hook_id", id)
+		return
+	}
+	hookLog.Debug("hook skipped", "hook", index, "hook_id", id)
+}
+
+// logAction records a command, http or opa action run, with its exit code and duration.
+// For http actions only the host of the URL is recorded, since webhook URLs often contain secrets.
+func logAction(action Action, rawJSON any, exitCode int, duration time.Duration, err error) {
+	attrs := []any{"type", action.Type}
+	if action.Type == "http" {
+		target := ""
+		if u, parseErr := url.Parse(unifiedTemplateReplace(os.ExpandEnv(action.URL), rawJSON)); parseErr == nil {
+			target = u.Scheme + "://" + u.Host
+		}
+		attrs = append(attrs, "method", httpActionMethod(action), "url", target)
+	} else if action.Type == "opa" {
+		attrs = append(attrs, "policy", action.Policy, "query", opaActionQuery(action))
+	} else {
+		attrs = append(attrs, "command", actionCommand(action, rawJSON))
+	}
+	attrs = append(attrs, "exit_code", exitCode, "duration_ms", duration.Milliseconds())
+	if err != nil {
+		attrs = append(attrs, "error", err.Error())
+	}
+	if exitCode != 0 {
+		hookLog.Warn("action failed", attrs...)
+		return
+	}
+	hookLog.Info("action finished", attrs...)
+}
+
+// logOutput records the final JSON output of the event and the total duration.
+func logOutput(output []byte) {
+	hookLog.Info("event finished", "output", json.RawMessage(compactJSON(output)), "duration_ms", time.Since(hookLogStart).Milliseconds())
+}
+
+// compactJSON returns data without insignificant whitespace (data as-is if it isn't valid JSON).
+func compactJSON(data []byte) []byte {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return data
+	}
+	return buf.Bytes()
+}
+
+// inputSessionID returns the session_id field of the event JSON (empty if missing).
+func inputSessionID(rawInput json.RawMessage) string {
+	var input struct {
+		SessionID string `json:"session_id"`
+	}
+	if err := json.Unmarshal(rawInput, &input); err != nil {
+		return ""
+	}
+	return input.SessionID
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withHookLog makes hookLog write JSON records at the given level to the returned buffer.
+func withHookLog(t *testing.T, level string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	original := hookLog
+	hookLog = newHookLogger(&buf, &LogConfig{Level: level})
+	t.Cleanup(func() { hookLog = original })
+	return &buf
+}
+
+// hookLogRecords decodes the JSON records written to buf.
+func hookLogRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Invalid log record %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestHookLog_PostToolUse(t *testing.T) {
+	buf := withHookLog(t, "debug")
+
+	config := &Config{
+		PostToolUse: []PostToolUseHook{
+			{
+				Matcher: "Bash",
+				Actions: []Action{{Type: "command", Command: "echo never"}},
+			},
+			{
+				Matcher:    "Write",
+				Conditions: []Condition{{Type: ConditionFileExtension, Value: ".go"}},
+				Actions:    []Action{{Type: "command", Command: "echo {.tool_input.file_path}"}},
+			},
+			{
+				Matcher:    "Write",
+				Conditions: []Condition{{Type: ConditionFileExtension, Value: ".py"}},
+				Actions:    []Action{{Type: "command", Command: "echo never"}},
+			},
+		},
+	}
+	input := &PostToolUseInput{ToolName: "Write", ToolInput: ToolInput{FilePath: "main.go"}}
+	rawJSON := map[string]any{"tool_name": "Write", "tool_input": map[string]any{"file_path": "main.go"}}
+
+	if _, err := executePostToolUseHooksJSON(config, input, rawJSON); err != nil {
+		t.Fatalf("executePostToolUseHooksJSON() error = %v", err)
+	}
+
+	var got []string
+	for _, record := range hookLogRecords(t, buf) {
+		entry := record["msg"].(string)
+		switch entry {
+		case "matcher did not match":
+			entry += " " + record["matcher"].(string)
+		case "condition evaluated":
+			entry += " " + record["value"].(string)
+			if record["matched"] == true {
+				entry += " (matched)"
+			}
+		case "hook matched", "hook skipped":
+			entry += fmt.Sprintf(" %v", record["hook"])
+		case "action finished":
+			if record["command"] != "echo 'main.go'" || record["exit_code"] != float64(0) {
+				t.Errorf("Unexpected action record: %v", record)
+			}
+			if _, ok := record["duration_ms"]; !ok {
+				t.Errorf("Expected duration_ms in action record: %v", record)
+			}
+		}
+		got = append(got, entry)
+	}
+
+	want := []string{
+		"matcher did not match Bash",
+		"hook skipped 0",
+		"condition evaluated .go (matched)",
+		"hook matched 1",
+		"action finished",
+		"condition evaluated .py",
+		"hook skipped 2",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Log records:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestHookLog_Level(t *testing.T) {
+	buf := withHookLog(t, "")
+
+	config := &Config{Stop: []StopHook{{
+		Conditions: []Condition{{Type: ConditionFileExists, Value: "does-not-exist"}},
+		Actions:    []Action{{Type: "command", Command: "exit 0"}},
+	}}}
+	if _, err := executeStopHooks(config, &StopInput{}, map[string]any{}); err != nil {
+		t.Fatalf("executeStopHooks() error = %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected debug records to be dropped at the default level, got %s", buf.String())
+	}
+
+	config.Stop[0].Conditions = nil
+	config.Stop[0].Actions[0].Command = "echo oops >&2; exit 3"
+	if _, err := executeStopHooks(config, &StopInput{}, map[string]any{}); err != nil {
+		t.Fatalf("executeStopHooks() error = %v", err)
+	}
+	records := hookLogRecords(t, buf)
+	if len(records) != 2 || records[0]["msg"] != "hook matched" || records[1]["msg"] != "action failed" ||
+		records[1]["level"] != "WARN" || records[1]["exit_code"] != float64(3) {
+		t.Errorf("Unexpected records: %v", records)
+	}
+}
+
+func TestSetupHookLog(t *testing.T) {
+	t.Cleanup(func() {
+		resetHookLog()
+		prefetchedInput = nil
+	})
+	withStdin(t, `{"session_id":"s1","hook_event_name":"Stop"}`)
+	// プロジェクト設定のために先読みした入力をもう一度読まない
+	if _, err := prefetchInput(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "logs", "cchook.log")
+	if err := setupHookLog(&LogConfig{Path: path, Format: logFormatText}, Stop); err != nil {
+		t.Fatalf("setupHookLog() error = %v", err)
+	}
+	logOutput([]byte("{\n  \"continue\": true\n}"))
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 records, got %q", data)
+	}
+	for _, want := range []string{`msg="event started"`, "event=Stop", "session_id=s1"} {
+		if !strings.Contains(lines[0], want) {
+			t.Errorf("Record %q should contain %s", lines[0], want)
+		}
+	}
+	if !strings.Contains(lines[1], `output="{\"continue\":true}"`) {
+		t.Errorf("Record %q should contain the compacted output", lines[1])
+	}
+
+	// parseInputは先読みした入力を使う
+	if input, _, err := parseInput[*StopInput](Stop); err != nil || input.SessionID != "s1" {
+		t.Errorf("parseInput() = %v, %v, want the prefetched input", input, err)
+	}
+
+	if err := setupHookLog(&LogConfig{Path: path, Level: "trace"}, Stop); err == nil {
+		t.Error("Expected an error for an invalid level")
+	}
+}
+
+func TestLoadConfig_LogFromMainConfigOnly(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFiles(t, dir, map[string]string{
+		"config.yaml":  "include: [shared.yaml]\nlog:\n  level: debug\n",
+		"shared.yaml":  "log:\n  path: /tmp/shared.log\n" + stopHook("shared"),
+		"invalid.yaml": "log:\n  format: xml\n",
+	})
+
+	config, err := loadConfig(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	if config.Log == nil || config.Log.Level != "debug" || config.Log.Path != "" {
+		t.Errorf("Expected the log block of the main config, got %+v", config.Log)
+	}
+
+	if _, err := loadConfig(filepath.Join(dir, "invalid.yaml")); err == nil || !strings.Contains(err.Error(), `invalid format "xml"`) {
+		t.Errorf("loadConfig() error = %v, want invalid format", err)
+	}
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"os"
 )
 
 // runHooks parses input and executes hooks for the specified event type.
@@ -97,78 +99,137 @@ func RunPreToolUseHooks(config *Config) (*PreToolUseOutput, error) {
 
 // dryRunHooks parses input and performs a dry-run of hooks for the specified event type.
 func dryRunHooks(config *Config, eventType HookEventType) error {
+	r, err := stdinReader()
+	if err != nil {
+		return err
+	}
+	return dryRunHooksFrom(os.Stdout, r, config, eventType)
+}
+
+// dryRunHooksFrom parses input from r and writes the dry-run of hooks for the specified event type to w.
+func dryRunHooksFrom(w io.Writer, r io.Reader, config *Config, eventType HookEventType) error {
 	switch eventType {
 	case PreToolUse:
-		input, rawJSON, err := parseInput[*PreToolUseInput](eventType)
+		input, rawJSON, err := parseInputFrom[*PreToolUseInput](r, eventType)
 		if err != nil {
 			return err
 		}
-		return dryRunPreToolUseHooks(config, input, rawJSON)
+		return dryRunPreToolUseHooks(w, config, input, rawJSON)
 	case PermissionRequest:
-		input, rawJSON, err := parseInput[*PermissionRequestInput](eventType)
+		input, rawJSON, err := parseInputFrom[*PermissionRequestInput](r, eventType)
 		if err != nil {
 			return err
 		}
-		return dryRunPermissionRequestHooks(config, input, rawJSON)
+		return dryRunPermissionRequestHooks(w, config, input, rawJSON)
 	case PostToolUse:
-		input, rawJSON, err := parseInput[*PostToolUseInput](eventType)
+		input, rawJSON, err := parseInputFrom[*PostToolUseInput](r, eventType)
 		if err != nil {
 			return err
 		}
-		return dryRunPostToolUseHooks(config, input, rawJSON)
+		return dryRunPostToolUseHooks(w, config, input, rawJSON)
 	case Notification:
-		input, rawJSON, err := parseInput[*NotificationInput](eventType)
+		input, rawJSON, err := parseInputFrom[*NotificationInput](r, eventType)
 		if err != nil {
 			return err
 		}
-		return dryRunNotificationHooks(config, input, rawJSON)
+		return dryRunNotificationHooks(w, config, input, rawJSON)
 	case Stop:
-		input, rawJSON, err := parseInput[*StopInput](eventType)
+		input, rawJSON, err := parseInputFrom[*StopInput](r, eventType)
 		if err != nil {
 			return err
 		}
-		return dryRunStopHooks(config, input, rawJSON)
+		return dryRunStopHooks(w, config, input, rawJSON)
 	case SubagentStop:
-		input, rawJSON, err := parseInput[*SubagentStopInput](eventType)
+		input, rawJSON, err := parseInputFrom[*SubagentStopInput](r, eventType)
 		if err != nil {
 			return err
 		}
-		return dryRunSubagentStopHooks(config, input, rawJSON)
+		return dryRunSubagentStopHooks(w, config, input, rawJSON)
 	case SubagentStart:
-		input, rawJSON, err := parseInput[*SubagentStartInput](eventType)
+		input, rawJSON, err := parseInputFrom[*SubagentStartInput](r, eventType)
 		if err != nil {
 			return err
 		}
-		return dryRunSubagentStartHooks(config, input, rawJSON)
+		return dryRunSubagentStartHooks(w, config, input, rawJSON)
 	case PreCompact:
-		input, rawJSON, err := parseInput[*PreCompactInput](eventType)
+		input, rawJSON, err := parseInputFrom[*PreCompactInput](r, eventType)
 		if err != nil {
 			return err
 		}
-		return dryRunPreCompactHooks(config, input, rawJSON)
+		return dryRunPreCompactHooks(w, config, input, rawJSON)
 	case SessionStart:
-		input, rawJSON, err := parseInput[*SessionStartInput](eventType)
+		input, rawJSON, err := parseInputFrom[*SessionStartInput](r, eventType)
 		if err != nil {
 			return err
 		}
-		return dryRunSessionStartHooks(config, input, rawJSON)
+		return dryRunSessionStartHooks(w, config, input, rawJSON)
 	case UserPromptSubmit:
-		input, rawJSON, err := parseInput[*UserPromptSubmitInput](eventType)
+		input, rawJSON, err := parseInputFrom[*UserPromptSubmitInput](r, eventType)
 		if err != nil {
 			return err
 		}
-		return dryRunUserPromptSubmitHooks(config, input, rawJSON)
+		return dryRunUserPromptSubmitHooks(w, config, input, rawJSON)
 	case SessionEnd:
-		input, rawJSON, err := parseInput[*SessionEndInput](eventType)
+		input, rawJSON, err := parseInputFrom[*SessionEndInput](r, eventType)
 		if err != nil {
 			return err
 		}
-		return dryRunSessionEndHooks(config, input, rawJSON)
+		return dryRunSessionEndHooks(w, config, input, rawJSON)
 	default:
 		return fmt.Errorf("unsupported event type: %s", eventType)
 	}
 }
 
+// diagnosticRun is set while explain and chaos execute the hooks of an event. The actions run with stubbed
+// commands and requests, and nothing that outlives the run is written: cooldowns, batch queues, the time ledger,
+// the digest, the changes of the session and snapshots. It is a variable so that tests can set it.
+var diagnosticRun bool
+
+// executeHooksFrom parses input from r and executes hooks for the specified event type,
+// returning the event's JSON output struct (not yet marshaled).
+// Without sideEffects the hooks run as a diagnostic run (see diagnosticRun).
+func executeHooksFrom(r io.Reader, config *Config, eventType HookEventType, sideEffects bool) (any, error) {
+	original := diagnosticRun
+	diagnosticRun = !sideEffects
+	defer func() { diagnosticRun = original }()
+
+	switch eventType {
+	case PreToolUse:
+		return executeHooksWith(r, config, eventType, executePreToolUseHooksJSON)
+	case PostToolUse:
+		return executeHooksWith(r, config, eventType, executePostToolUseHooksJSON)
+	case PermissionRequest:
+		return executeHooksWith(r, config, eventType, executePermissionRequestHooksJSON)
+	case Notification:
+		return executeHooksWith(r, config, eventType, executeNotificationHooksJSON)
+	case Stop:
+		return executeHooksWith(r, config, eventType, executeStopHooks)
+	case SubagentStop:
+		return executeHooksWith(r, config, eventType, executeSubagentStopHooks)
+	case SubagentStart:
+		return executeHooksWith(r, config, eventType, executeSubagentStartHooksJSON)
+	case PreCompact:
+		return executeHooksWith(r, config, eventType, executePreCompactHooksJSON)
+	case SessionStart:
+		return executeHooksWith(r, config, eventType, executeSessionStartHooks)
+	case SessionEnd:
+		return executeHooksWith(r, config, eventType, executeSessionEndHooksJSON)
+	case UserPromptSubmit:
+		return executeHooksWith(r, config, eventType, executeUserPromptSubmitHooks)
+	default:
+		return nil, fmt.Errorf("unsupported event type: %s", eventType)
+	}
+}
+
+// executeHooksWith parses input of type T from r and calls execute.
+func executeHooksWith[T HookInput, O any](r io.Reader, config *Config, eventType HookEventType, execute func(*Config, T, any) (O, error)) (any, error) {
+	input, rawJSON, err := parseInputFrom[T](r, eventType)
+	if err != nil {
+		return nil, err
+	}
+	return execute(config, input, rawJSON)
+}
+
 // RunNotificationHooks is the wrapper function called from main.go.
 // It delegates to executeNotificationHooksJSON.
 func RunNotificationHooks(config *Config) (*NotificationOutput, error) {
@@ -1,21 +1,12 @@
 package main
 
 import (
-	"os"
 	"strings"
 	"testing"
 )
 
 // TestRunNotificationHooks tests the basic notification hook execution path
 func TestRunNotificationHooks(t *testing.T) {
-	// Backup and restore stdin
-	oldStdin := os.Stdin
-	defer func() { os.Stdin = oldStdin }()
-
-	// Setup stdin with valid JSON
-	r, w, _ := os.Pipe()
-	os.Stdin = r
-
 	jsonInput := `{
 		"session_id": "test-session",
 		"transcript_path": "/tmp/test",
@@ -24,10 +15,7 @@ func TestRunNotificationHooks(t *testing.T) {
 		"notification_type": "idle_prompt"
 	}`
 
-	go func() {
-		defer func() { _ = w.Close() }()
-		_, _ = w.Write([]byte(jsonInput))
-	}()
+	withStdin(t, jsonInput)
 
 	config := &Config{
 		Notification: []NotificationHook{
@@ -69,14 +57,6 @@ func TestRunNotificationHooks(t *testing.T) {
 
 // TestRunStopHooks tests the basic stop hook execution path
 func TestRunStopHooks(t *testing.T) {
-	// Backup and restore stdin
-	oldStdin := os.Stdin
-	defer func() { os.Stdin = oldStdin }()
-
-	// Setup stdin with valid JSON
-	r, w, _ := os.Pipe()
-	os.Stdin = r
-
 	jsonInput := `{
 		"session_id": "test-session",
 		"transcript_path": "/tmp/test",
@@ -84,10 +64,7 @@ func TestRunStopHooks(t *testing.T) {
 		"reason": "user_requested"
 	}`
 
-	go func() {
-		defer func() { _ = w.Close() }()
-		_, _ = w.Write([]byte(jsonInput))
-	}()
+	withStdin(t, jsonInput)
 
 	config := &Config{
 		Stop: []StopHook{
@@ -121,14 +98,6 @@ func TestRunStopHooks(t *testing.T) {
 
 // TestRunSubagentStopHooks tests the basic subagent stop hook execution path
 func TestRunSubagentStopHooks(t *testing.T) {
-	// Backup and restore stdin
-	oldStdin := os.Stdin
-	defer func() { os.Stdin = oldStdin }()
-
-	// Setup stdin with valid JSON
-	r, w, _ := os.Pipe()
-	os.Stdin = r
-
 	jsonInput := `{
 		"session_id": "test-session",
 		"transcript_path": "/tmp/test",
@@ -138,10 +107,7 @@ func TestRunSubagentStopHooks(t *testing.T) {
 		"reason": "task_complete"
 	}`
 
-	go func() {
-		defer func() { _ = w.Close() }()
-		_, _ = w.Write([]byte(jsonInput))
-	}()
+	withStdin(t, jsonInput)
 
 	config := &Config{
 		SubagentStop: []SubagentStopHook{
@@ -175,14 +141,6 @@ func TestRunSubagentStopHooks(t *testing.T) {
 
 // TestRunPreCompactHooks tests the basic precompact hook execution path
 func TestRunPreCompactHooks(t *testing.T) {
-	// Backup and restore stdin
-	oldStdin := os.Stdin
-	defer func() { os.Stdin = oldStdin }()
-
-	// Setup stdin with valid JSON
-	r, w, _ := os.Pipe()
-	os.Stdin = r
-
 	jsonInput := `{
 		"session_id": "test-session",
 		"transcript_path": "/tmp/test",
@@ -190,10 +148,7 @@ func TestRunPreCompactHooks(t *testing.T) {
 		"trigger": "manual"
 	}`
 
-	go func() {
-		defer func() { _ = w.Close() }()
-		_, _ = w.Write([]byte(jsonInput))
-	}()
+	withStdin(t, jsonInput)
 
 	config := &Config{
 		PreCompact: []PreCompactHook{
@@ -228,14 +183,6 @@ func TestRunPreCompactHooks(t *testing.T) {
 
 // TestRunSessionEndHooks tests the basic session end hook execution path
 func TestRunSessionEndHooks(t *testing.T) {
-	// Backup and restore stdin
-	oldStdin := os.Stdin
-	defer func() { os.Stdin = oldStdin }()
-
-	// Setup stdin with valid JSON
-	r, w, _ := os.Pipe()
-	os.Stdin = r
-
 	jsonInput := `{
 		"session_id": "test-session",
 		"transcript_path": "/tmp/test",
@@ -243,10 +190,7 @@ func TestRunSessionEndHooks(t *testing.T) {
 		"reason": "clear"
 	}`
 
-	go func() {
-		defer func() { _ = w.Close() }()
-		_, _ = w.Write([]byte(jsonInput))
-	}()
+	withStdin(t, jsonInput)
 
 	config := &Config{
 		SessionEnd: []SessionEndHook{
@@ -283,14 +227,6 @@ func TestRunSessionEndHooks(t *testing.T) {
 
 // TestRunNotificationHooks_EmptyConfig tests no hooks match case
 func TestRunNotificationHooks_EmptyConfig(t *testing.T) {
-	// Backup and restore stdin
-	oldStdin := os.Stdin
-	defer func() { os.Stdin = oldStdin }()
-
-	// Setup stdin with valid JSON
-	r, w, _ := os.Pipe()
-	os.Stdin = r
-
 	jsonInput := `{
 		"session_id": "test-session",
 		"transcript_path": "/tmp/test",
@@ -299,10 +235,7 @@ func TestRunNotificationHooks_EmptyConfig(t *testing.T) {
 		"notification_type": "idle_prompt"
 	}`
 
-	go func() {
-		defer func() { _ = w.Close() }()
-		_, _ = w.Write([]byte(jsonInput))
-	}()
+	withStdin(t, jsonInput)
 
 	config := &Config{
 		Notification: []NotificationHook{},
@@ -325,14 +258,6 @@ func TestRunNotificationHooks_EmptyConfig(t *testing.T) {
 
 // TestRunStopHooks_BlockingDecision tests blocking decision in Stop hook
 func TestRunStopHooks_BlockingDecision(t *testing.T) {
-	// Backup and restore stdin
-	oldStdin := os.Stdin
-	defer func() { os.Stdin = oldStdin }()
-
-	// Setup stdin with valid JSON
-	r, w, _ := os.Pipe()
-	os.Stdin = r
-
 	jsonInput := `{
 		"session_id": "test-session",
 		"transcript_path": "/tmp/test",
@@ -340,10 +265,7 @@ func TestRunStopHooks_BlockingDecision(t *testing.T) {
 		"reason": "user_requested"
 	}`
 
-	go func() {
-		defer func() { _ = w.Close() }()
-		_, _ = w.Write([]byte(jsonInput))
-	}()
+	withStdin(t, jsonInput)
 
 	blockDecision := "block"
 	blockReason := "Important work in progress"
@@ -385,14 +307,6 @@ func TestRunStopHooks_BlockingDecision(t *testing.T) {
 
 // TestRunSessionEndHooks_NoConditionMatch tests no conditions match case
 func TestRunSessionEndHooks_NoConditionMatch(t *testing.T) {
-	// Backup and restore stdin
-	oldStdin := os.Stdin
-	defer func() { os.Stdin = oldStdin }()
-
-	// Setup stdin with valid JSON
-	r, w, _ := os.Pipe()
-	os.Stdin = r
-
 	jsonInput := `{
 		"session_id": "test-session",
 		"transcript_path": "/tmp/test",
@@ -400,10 +314,7 @@ func TestRunSessionEndHooks_NoConditionMatch(t *testing.T) {
 		"reason": "logout"
 	}`
 
-	go func() {
-		defer func() { _ = w.Close() }()
-		_, _ = w.Write([]byte(jsonInput))
-	}()
+	withStdin(t, jsonInput)
 
 	config := &Config{
 		SessionEnd: []SessionEndHook{
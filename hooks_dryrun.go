@@ -1,92 +1,217 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 )
 
 // dryRunPreToolUseHooks performs a dry-run of PreToolUse hooks, showing what would be executed without actually running.
-func dryRunPreToolUseHooks(config *Config, input *PreToolUseInput, rawJSON any) error {
-	fmt.Println("=== PreToolUse Hooks (Dry Run) ===")
+func dryRunPreToolUseHooks(w io.Writer, config *Config, input *PreToolUseInput, rawJSON any) error {
+	fmt.Fprintln(w, "=== PreToolUse Hooks (Dry Run) ===")
 	executed := false
 	for i, hook := range config.PreToolUse {
 		shouldExecute, err := shouldExecutePreToolUseHook(hook, input)
 		if err != nil {
 			if errors.Is(err, ErrProcessSubstitutionDetected) {
-				fmt.Printf("[Hook %d] Process substitution detected - would deny\n", i+1)
+				fmt.Fprintf(w, "[Hook %d] Process substitution detected - would deny\n", i+1)
 				continue
 			}
-			fmt.Printf("[Hook %d] Condition check error: %v\n", i+1, err)
+			fmt.Fprintf(w, "[Hook %d] Condition check error: %v\n", i+1, err)
 			continue
 		}
 		if shouldExecute {
 			executed = true
-			fmt.Printf("[Hook %d] Would execute:\n", i+1)
+			if dryRunWithinMaxSideEffects(w, i, hook.Actions) {
+				continue
+			}
+			fmt.Fprintf(w, "[Hook %d] Would execute:\n", i+1)
+			dryRunSideEffects(w, hook.Actions)
+			dryRunMCPTool(w, input.ToolName)
+			if hook.Mutex != "" {
+				fmt.Fprintf(w, "  Mutex: %s\n", hook.Mutex)
+			}
+			if hook.EnvFrom != "" {
+				fmt.Fprintf(w, "  Env from: %s\n", hook.EnvFrom)
+			}
+			if hook.Cooldown != "" {
+				fmt.Fprintf(w, "  Cooldown: %s (%s)\n", hook.Cooldown, cooldownScopeName(hook.CooldownScope))
+			}
 			for _, action := range hook.Actions {
 				switch action.Type {
 				case "command":
-					cmd := unifiedTemplateReplace(action.Command, rawJSON)
-					fmt.Printf("  Command: %s\n", cmd)
-					if action.UseStdin {
-						fmt.Printf("  UseStdin: true\n")
+					cmd := actionCommand(action, rawJSON)
+					fmt.Fprintf(w, "  Command: %s\n", cmd)
+					if action.Runner != "" {
+						fmt.Fprintf(w, "  Runner: %s\n", action.Runner)
+					}
+					if action.EnvFrom != "" {
+						fmt.Fprintf(w, "  Env from: %s\n", action.EnvFrom)
 					}
+					fmt.Fprintf(w, "  Stdin: %s\n", commandStdinMode(action))
+				case "http":
+					dryRunHTTPAction(w, action, rawJSON)
+				case "opa":
+					fmt.Fprintf(w, "  Policy: %s (query %s)\n", action.Policy, opaActionQuery(action))
 				case "output":
-					fmt.Printf("  Message: %s\n", action.Message)
+					fmt.Fprintf(w, "  Message: %s\n", action.Message)
+					// permission_decisionのデフォルトはdenyで、deny時のupdated_inputは無視される
+					if action.PermissionDecision != nil && *action.PermissionDecision != "deny" {
+						dryRunUpdatedInput(w, action, rawJSON)
+					}
+				case "secret_scan":
+					fmt.Fprintln(w, "  Secret scan: tool_input")
+				case "terminology":
+					fmt.Fprintf(w, "  Terminology: %d terms\n", len(action.Terms))
+				case "breaking_change":
+					base := action.Base
+					if base == "" {
+						base = breakingChangeDefaultBase
+					}
+					fmt.Fprintf(w, "  Breaking change check: %s against %s\n", input.ToolInput.FilePath, base)
+				case "ask_external":
+					timeout, _ := askExternalTimeout(action)
+					fmt.Fprintf(w, "  Ask external: %s %s, wait up to %s for a decision\n", httpActionMethod(action), unifiedTemplateReplace(action.URL, rawJSON), timeout)
+				case "rewrite_command":
+					if rewritten, matched, err := rewriteCommand(input.ToolInput.Command, action.Rules); err != nil {
+						fmt.Fprintf(w, "  Rewrite command: (error: %v)\n", err)
+					} else if matched {
+						fmt.Fprintf(w, "  Rewrite command: %s\n", rewritten)
+					} else {
+						fmt.Fprintln(w, "  Rewrite command: no rule matches")
+					}
 				}
 			}
 		}
 	}
 	if !executed {
-		fmt.Println("No hooks would be executed")
+		fmt.Fprintln(w, "No hooks would be executed")
 	}
 	return nil
 }
 
 // dryRunPostToolUseHooks performs a dry-run of PostToolUse hooks, showing what would be executed without actually running.
-func dryRunPostToolUseHooks(config *Config, input *PostToolUseInput, rawJSON any) error {
-	fmt.Println("=== PostToolUse Hooks (Dry Run) ===")
+func dryRunPostToolUseHooks(w io.Writer, config *Config, input *PostToolUseInput, rawJSON any) error {
+	fmt.Fprintln(w, "=== PostToolUse Hooks (Dry Run) ===")
 	executed := false
 	for i, hook := range config.PostToolUse {
 		shouldExecute, err := shouldExecutePostToolUseHook(hook, input)
 		if err != nil {
 			if errors.Is(err, ErrProcessSubstitutionDetected) {
-				fmt.Printf("[Hook %d] Process substitution detected - would warn\n", i+1)
+				fmt.Fprintf(w, "[Hook %d] Process substitution detected - would warn\n", i+1)
 				continue
 			}
-			fmt.Printf("[Hook %d] Condition check error: %v\n", i+1, err)
+			fmt.Fprintf(w, "[Hook %d] Condition check error: %v\n", i+1, err)
 			continue
 		}
 		if shouldExecute {
 			executed = true
-			fmt.Printf("[Hook %d] Would execute:\n", i+1)
+			if dryRunWithinMaxSideEffects(w, i, hook.Actions) {
+				continue
+			}
+			fmt.Fprintf(w, "[Hook %d] Would execute:\n", i+1)
+			dryRunSideEffects(w, hook.Actions)
+			dryRunMCPTool(w, input.ToolName)
+			if hook.Mutex != "" {
+				fmt.Fprintf(w, "  Mutex: %s\n", hook.Mutex)
+			}
+			if hook.EnvFrom != "" {
+				fmt.Fprintf(w, "  Env from: %s\n", hook.EnvFrom)
+			}
+			if hook.Cooldown != "" {
+				fmt.Fprintf(w, "  Cooldown: %s (%s)\n", hook.Cooldown, cooldownScopeName(hook.CooldownScope))
+			}
+			if hook.Snapshot {
+				fmt.Fprintf(w, "  Snapshot: %s\n", input.ToolInput.FilePath)
+			}
+			if hook.NotifyModelOnFileChange {
+				fmt.Fprintln(w, "  Notify model on file change: true")
+			}
+			if hook.Batch {
+				quiet, _ := batchQuietPeriod(hook)
+				fmt.Fprintf(w, "  Batch: actions run once after %s without new events\n", quiet)
+			}
 			for _, action := range hook.Actions {
 				switch action.Type {
 				case "command":
-					cmd := unifiedTemplateReplace(action.Command, rawJSON)
-					fmt.Printf("  Command: %s\n", cmd)
-					if action.UseStdin {
-						fmt.Printf("  UseStdin: true\n")
+					cmd := actionCommand(action, rawJSON)
+					fmt.Fprintf(w, "  Command: %s\n", cmd)
+					if action.Runner != "" {
+						fmt.Fprintf(w, "  Runner: %s\n", action.Runner)
 					}
+					if action.EnvFrom != "" {
+						fmt.Fprintf(w, "  Env from: %s\n", action.EnvFrom)
+					}
+					fmt.Fprintf(w, "  Stdin: %s\n", commandStdinMode(action))
+				case "http":
+					dryRunHTTPAction(w, action, rawJSON)
+				case "opa":
+					fmt.Fprintf(w, "  Policy: %s (query %s)\n", action.Policy, opaActionQuery(action))
 				case "output":
-					fmt.Printf("  Message: %s\n", action.Message)
+					fmt.Fprintf(w, "  Message: %s\n", action.Message)
+				case "secret_scan":
+					if action.ScanFile {
+						fmt.Fprintln(w, "  Secret scan: tool_input, file")
+					} else {
+						fmt.Fprintln(w, "  Secret scan: tool_input")
+					}
+				case "terminology":
+					fmt.Fprintf(w, "  Terminology: %d terms\n", len(action.Terms))
+				case "syntax_check":
+					fmt.Fprintf(w, "  Syntax check: %s\n", input.ToolInput.FilePath)
+				case "markdown_check":
+					if action.FrontmatterSchema != "" {
+						fmt.Fprintf(w, "  Markdown check: %s (frontmatter schema: %s)\n", input.ToolInput.FilePath, action.FrontmatterSchema)
+					} else {
+						fmt.Fprintf(w, "  Markdown check: %s\n", input.ToolInput.FilePath)
+					}
+				case "typecheck":
+					if target, ok := typecheckTargetFor(resolveToolFilePath(input.ToolInput.FilePath, input.Cwd)); ok {
+						fmt.Fprintf(w, "  Typecheck: %s\n", target.Display)
+					} else {
+						fmt.Fprintf(w, "  Typecheck: skipped (%s is not in a Go module or TypeScript project)\n", input.ToolInput.FilePath)
+					}
+				case "format_file":
+					target, missing := formatFileTargetFor(resolveToolFilePath(input.ToolInput.FilePath, input.Cwd), action.Formatters)
+					switch {
+					case target != nil:
+						fmt.Fprintf(w, "  Format: %s\n", target.Display)
+					case len(missing) > 0:
+						fmt.Fprintf(w, "  Format: skipped (%s not installed)\n", formatterList(missing))
+					default:
+						fmt.Fprintf(w, "  Format: skipped (no formatter for %s)\n", input.ToolInput.FilePath)
+					}
+				case "lint_feedback":
+					target, missing := lintFeedbackTargetFor(resolveToolFilePath(input.ToolInput.FilePath, input.Cwd), action.Linters)
+					switch {
+					case target != nil:
+						fmt.Fprintf(w, "  Lint: %s\n", target.Display)
+					case len(missing) > 0:
+						fmt.Fprintf(w, "  Lint: skipped (%s not installed)\n", formatterList(missing))
+					default:
+						fmt.Fprintf(w, "  Lint: skipped (no linter for %s)\n", input.ToolInput.FilePath)
+					}
+				case "git_checkpoint":
+					dryRunGitCheckpointAction(w, action, gitCheckpointDir(input.ToolInput.FilePath, input.Cwd), rawJSON)
 				}
 			}
 		}
 	}
 	if !executed {
-		fmt.Println("No hooks would be executed")
+		fmt.Fprintln(w, "No hooks would be executed")
 	}
 	return nil
 }
 
 // dryRunNotificationHooks performs a dry-run of Notification hooks, showing what would be executed without actually running.
-func dryRunNotificationHooks(config *Config, input *NotificationInput, rawJSON any) error {
-	fmt.Println("=== Notification Hooks (Dry Run) ===")
+func dryRunNotificationHooks(w io.Writer, config *Config, input *NotificationInput, rawJSON any) error {
+	fmt.Fprintln(w, "=== Notification Hooks (Dry Run) ===")
 
 	if len(config.Notification) == 0 {
-		fmt.Println("No Notification hooks configured")
+		fmt.Fprintln(w, "No Notification hooks configured")
 		return nil
 	}
 
@@ -119,7 +244,7 @@ func dryRunNotificationHooks(config *Config, input *NotificationInput, rawJSON a
 		for _, condition := range hook.Conditions {
 			matched, err := checkNotificationCondition(condition, input)
 			if err != nil {
-				fmt.Printf("[Hook %d] Condition check error: %v\n", i+1, err)
+				fmt.Fprintf(w, "[Hook %d] Condition check error: %v\n", i+1, err)
 				shouldExecute = false
 				break
 			}
@@ -133,37 +258,60 @@ func dryRunNotificationHooks(config *Config, input *NotificationInput, rawJSON a
 		}
 
 		executed = true
-		fmt.Printf("[Hook %d] Would execute:\n", i+1)
+		if dryRunWithinMaxSideEffects(w, i, hook.Actions) {
+			continue
+		}
+		fmt.Fprintf(w, "[Hook %d] Would execute:\n", i+1)
+		dryRunSideEffects(w, hook.Actions)
 		if hook.Matcher != "" {
-			fmt.Printf("  Matcher: %s\n", hook.Matcher)
+			fmt.Fprintf(w, "  Matcher: %s\n", hook.Matcher)
+		}
+		if hook.Mutex != "" {
+			fmt.Fprintf(w, "  Mutex: %s\n", hook.Mutex)
+		}
+		if hook.EnvFrom != "" {
+			fmt.Fprintf(w, "  Env from: %s\n", hook.EnvFrom)
+		}
+		if hook.Cooldown != "" {
+			fmt.Fprintf(w, "  Cooldown: %s (%s)\n", hook.Cooldown, cooldownScopeName(hook.CooldownScope))
 		}
 		for _, action := range hook.Actions {
 			switch action.Type {
 			case "command":
-				cmd := unifiedTemplateReplace(action.Command, rawJSON)
-				fmt.Printf("  Command: %s\n", cmd)
-				if action.UseStdin {
-					fmt.Printf("  UseStdin: true\n")
+				cmd := actionCommand(action, rawJSON)
+				fmt.Fprintf(w, "  Command: %s\n", cmd)
+				if action.Runner != "" {
+					fmt.Fprintf(w, "  Runner: %s\n", action.Runner)
 				}
+				if action.EnvFrom != "" {
+					fmt.Fprintf(w, "  Env from: %s\n", action.EnvFrom)
+				}
+				fmt.Fprintf(w, "  Stdin: %s\n", commandStdinMode(action))
+			case "http":
+				dryRunHTTPAction(w, action, rawJSON)
+			case "opa":
+				fmt.Fprintf(w, "  Policy: %s (query %s)\n", action.Policy, opaActionQuery(action))
 			case "output":
-				fmt.Printf("  Message: %s\n", action.Message)
+				fmt.Fprintf(w, "  Message: %s\n", action.Message)
+			case "digest":
+				dryRunDigestAction(w, action)
 			}
 		}
 	}
 
 	if !executed {
-		fmt.Println("No hooks would be executed")
+		fmt.Fprintln(w, "No hooks would be executed")
 	}
 	return nil
 }
 
 // dryRunSubagentStartHooks performs a dry-run of SubagentStart hooks, showing what would be executed without actually running.
 // Includes matcher check on agent_type.
-func dryRunSubagentStartHooks(config *Config, input *SubagentStartInput, rawJSON any) error {
-	fmt.Println("=== SubagentStart Hooks (Dry Run) ===")
+func dryRunSubagentStartHooks(w io.Writer, config *Config, input *SubagentStartInput, rawJSON any) error {
+	fmt.Fprintln(w, "=== SubagentStart Hooks (Dry Run) ===")
 
 	if len(config.SubagentStart) == 0 {
-		fmt.Println("No SubagentStart hooks configured")
+		fmt.Fprintln(w, "No SubagentStart hooks configured")
 		return nil
 	}
 
@@ -179,7 +327,7 @@ func dryRunSubagentStartHooks(config *Config, input *SubagentStartInput, rawJSON
 		for _, condition := range hook.Conditions {
 			matched, err := checkSubagentStartCondition(condition, input)
 			if err != nil {
-				fmt.Printf("[Hook %d] Condition check error: %v\n", i+1, err)
+				fmt.Fprintf(w, "[Hook %d] Condition check error: %v\n", i+1, err)
 				shouldExecute = false
 				break
 			}
@@ -193,34 +341,55 @@ func dryRunSubagentStartHooks(config *Config, input *SubagentStartInput, rawJSON
 		}
 
 		executed = true
-		fmt.Printf("[Hook %d] Would execute:\n", i+1)
-		fmt.Printf("  Matcher: %s\n", hook.Matcher)
+		if dryRunWithinMaxSideEffects(w, i, hook.Actions) {
+			continue
+		}
+		fmt.Fprintf(w, "[Hook %d] Would execute:\n", i+1)
+		dryRunSideEffects(w, hook.Actions)
+		fmt.Fprintf(w, "  Matcher: %s\n", hook.Matcher)
+		if hook.Mutex != "" {
+			fmt.Fprintf(w, "  Mutex: %s\n", hook.Mutex)
+		}
+		if hook.EnvFrom != "" {
+			fmt.Fprintf(w, "  Env from: %s\n", hook.EnvFrom)
+		}
+		if hook.Cooldown != "" {
+			fmt.Fprintf(w, "  Cooldown: %s (%s)\n", hook.Cooldown, cooldownScopeName(hook.CooldownScope))
+		}
 		for _, action := range hook.Actions {
 			switch action.Type {
 			case "command":
-				cmd := unifiedTemplateReplace(action.Command, rawJSON)
-				fmt.Printf("  Command: %s\n", cmd)
-				if action.UseStdin {
-					fmt.Printf("  UseStdin: true\n")
+				cmd := actionCommand(action, rawJSON)
+				fmt.Fprintf(w, "  Command: %s\n", cmd)
+				if action.Runner != "" {
+					fmt.Fprintf(w, "  Runner: %s\n", action.Runner)
+				}
+				if action.EnvFrom != "" {
+					fmt.Fprintf(w, "  Env from: %s\n", action.EnvFrom)
 				}
+				fmt.Fprintf(w, "  Stdin: %s\n", commandStdinMode(action))
+			case "http":
+				dryRunHTTPAction(w, action, rawJSON)
+			case "opa":
+				fmt.Fprintf(w, "  Policy: %s (query %s)\n", action.Policy, opaActionQuery(action))
 			case "output":
-				fmt.Printf("  Message: %s\n", action.Message)
+				fmt.Fprintf(w, "  Message: %s\n", action.Message)
 			}
 		}
 	}
 
 	if !executed {
-		fmt.Println("No hooks would be executed")
+		fmt.Fprintln(w, "No hooks would be executed")
 	}
 	return nil
 }
 
 // dryRunStopHooks performs a dry-run of Stop hooks, showing what would be executed without actually running.
-func dryRunStopHooks(config *Config, input *StopInput, rawJSON any) error {
-	fmt.Println("=== Stop Hooks (Dry Run) ===")
+func dryRunStopHooks(w io.Writer, config *Config, input *StopInput, rawJSON any) error {
+	fmt.Fprintln(w, "=== Stop Hooks (Dry Run) ===")
 
 	if len(config.Stop) == 0 {
-		fmt.Println("No Stop hooks configured")
+		fmt.Fprintln(w, "No Stop hooks configured")
 		return nil
 	}
 
@@ -231,7 +400,7 @@ func dryRunStopHooks(config *Config, input *StopInput, rawJSON any) error {
 		for _, condition := range hook.Conditions {
 			matched, err := checkStopCondition(condition, input)
 			if err != nil {
-				fmt.Printf("[Hook %d] Condition check error: %v\n", i+1, err)
+				fmt.Fprintf(w, "[Hook %d] Condition check error: %v\n", i+1, err)
 				shouldExecute = false
 				break
 			}
@@ -245,44 +414,79 @@ func dryRunStopHooks(config *Config, input *StopInput, rawJSON any) error {
 		}
 
 		executed = true
-		fmt.Printf("[Hook %d] Would execute:\n", i+1)
+		if dryRunWithinMaxSideEffects(w, i, hook.Actions) {
+			continue
+		}
+		fmt.Fprintf(w, "[Hook %d] Would execute:\n", i+1)
+		dryRunSideEffects(w, hook.Actions)
+		if hook.Mutex != "" {
+			fmt.Fprintf(w, "  Mutex: %s\n", hook.Mutex)
+		}
+		if hook.EnvFrom != "" {
+			fmt.Fprintf(w, "  Env from: %s\n", hook.EnvFrom)
+		}
+		if hook.Cooldown != "" {
+			fmt.Fprintf(w, "  Cooldown: %s (%s)\n", hook.Cooldown, cooldownScopeName(hook.CooldownScope))
+		}
 		for _, action := range hook.Actions {
 			switch action.Type {
 			case "command":
-				cmd := unifiedTemplateReplace(action.Command, rawJSON)
-				fmt.Printf("  Command: %s\n", cmd)
-				if action.UseStdin {
-					fmt.Printf("  UseStdin: true\n")
+				cmd := actionCommand(action, rawJSON)
+				fmt.Fprintf(w, "  Command: %s\n", cmd)
+				if action.Runner != "" {
+					fmt.Fprintf(w, "  Runner: %s\n", action.Runner)
+				}
+				if action.EnvFrom != "" {
+					fmt.Fprintf(w, "  Env from: %s\n", action.EnvFrom)
 				}
+				fmt.Fprintf(w, "  Stdin: %s\n", commandStdinMode(action))
+			case "http":
+				dryRunHTTPAction(w, action, rawJSON)
+			case "opa":
+				fmt.Fprintf(w, "  Policy: %s (query %s)\n", action.Policy, opaActionQuery(action))
 			case "output":
-				fmt.Printf("  Message: %s\n", action.Message)
+				fmt.Fprintf(w, "  Message: %s\n", action.Message)
+			case "hook_changes_report":
+				fmt.Fprintf(w, "  Report: files changed by hooks in this session\n")
+			case "git_checkpoint":
+				dryRunGitCheckpointAction(w, action, input.Cwd, rawJSON)
+			case "run_tests":
+				timeout, _ := runTestsTimeout(action)
+				fmt.Fprintf(w, "  Run tests: %s (timeout %s, skipped if the working tree is unchanged since the last run)\n", actionCommand(action, rawJSON), timeout)
+			case "digest":
+				dryRunDigestAction(w, action)
 			}
 		}
 	}
 
 	if !executed {
-		fmt.Println("No hooks would be executed")
+		fmt.Fprintln(w, "No hooks would be executed")
 	}
 	return nil
 }
 
 // dryRunSubagentStopHooks performs a dry-run of SubagentStop hooks, showing what would be executed without actually running.
-func dryRunSubagentStopHooks(config *Config, input *SubagentStopInput, rawJSON any) error {
-	fmt.Println("=== SubagentStop Hooks (Dry Run) ===")
+func dryRunSubagentStopHooks(w io.Writer, config *Config, input *SubagentStopInput, rawJSON any) error {
+	fmt.Fprintln(w, "=== SubagentStop Hooks (Dry Run) ===")
 
 	if len(config.SubagentStop) == 0 {
-		fmt.Println("No SubagentStop hooks configured")
+		fmt.Fprintln(w, "No SubagentStop hooks configured")
 		return nil
 	}
 
 	executed := false
 	for i, hook := range config.SubagentStop {
+		// Matcher check (agent type filter)
+		if !checkMatcher(hook.Matcher, input.AgentType) {
+			continue
+		}
+
 		// 条件チェック
 		shouldExecute := true
 		for _, condition := range hook.Conditions {
 			matched, err := checkSubagentStopCondition(condition, input)
 			if err != nil {
-				fmt.Printf("[Hook %d] Condition check error: %v\n", i+1, err)
+				fmt.Fprintf(w, "[Hook %d] Condition check error: %v\n", i+1, err)
 				shouldExecute = false
 				break
 			}
@@ -296,33 +500,57 @@ func dryRunSubagentStopHooks(config *Config, input *SubagentStopInput, rawJSON a
 		}
 
 		executed = true
-		fmt.Printf("[Hook %d] Would execute:\n", i+1)
+		if dryRunWithinMaxSideEffects(w, i, hook.Actions) {
+			continue
+		}
+		fmt.Fprintf(w, "[Hook %d] Would execute:\n", i+1)
+		dryRunSideEffects(w, hook.Actions)
+		if hook.Matcher != "" {
+			fmt.Fprintf(w, "  Matcher: %s\n", hook.Matcher)
+		}
+		if hook.Mutex != "" {
+			fmt.Fprintf(w, "  Mutex: %s\n", hook.Mutex)
+		}
+		if hook.EnvFrom != "" {
+			fmt.Fprintf(w, "  Env from: %s\n", hook.EnvFrom)
+		}
+		if hook.Cooldown != "" {
+			fmt.Fprintf(w, "  Cooldown: %s (%s)\n", hook.Cooldown, cooldownScopeName(hook.CooldownScope))
+		}
 		for _, action := range hook.Actions {
 			switch action.Type {
 			case "command":
-				cmd := unifiedTemplateReplace(action.Command, rawJSON)
-				fmt.Printf("  Command: %s\n", cmd)
-				if action.UseStdin {
-					fmt.Printf("  UseStdin: true\n")
+				cmd := actionCommand(action, rawJSON)
+				fmt.Fprintf(w, "  Command: %s\n", cmd)
+				if action.Runner != "" {
+					fmt.Fprintf(w, "  Runner: %s\n", action.Runner)
 				}
+				if action.EnvFrom != "" {
+					fmt.Fprintf(w, "  Env from: %s\n", action.EnvFrom)
+				}
+				fmt.Fprintf(w, "  Stdin: %s\n", commandStdinMode(action))
+			case "http":
+				dryRunHTTPAction(w, action, rawJSON)
+			case "opa":
+				fmt.Fprintf(w, "  Policy: %s (query %s)\n", action.Policy, opaActionQuery(action))
 			case "output":
-				fmt.Printf("  Message: %s\n", action.Message)
+				fmt.Fprintf(w, "  Message: %s\n", action.Message)
 			}
 		}
 	}
 
 	if !executed {
-		fmt.Println("No hooks would be executed")
+		fmt.Fprintln(w, "No hooks would be executed")
 	}
 	return nil
 }
 
 // dryRunPreCompactHooks performs a dry-run of PreCompact hooks, showing what would be executed without actually running.
-func dryRunPreCompactHooks(config *Config, input *PreCompactInput, rawJSON any) error {
-	fmt.Println("=== PreCompact Hooks (Dry Run) ===")
+func dryRunPreCompactHooks(w io.Writer, config *Config, input *PreCompactInput, rawJSON any) error {
+	fmt.Fprintln(w, "=== PreCompact Hooks (Dry Run) ===")
 
 	if len(config.PreCompact) == 0 {
-		fmt.Println("No PreCompact hooks configured")
+		fmt.Fprintln(w, "No PreCompact hooks configured")
 		return nil
 	}
 
@@ -343,7 +571,7 @@ func dryRunPreCompactHooks(config *Config, input *PreCompactInput, rawJSON any)
 		for _, condition := range hook.Conditions {
 			matched, err := checkPreCompactCondition(condition, input)
 			if err != nil {
-				fmt.Printf("[Hook %d] Condition check error: %v\n", i+1, err)
+				fmt.Fprintf(w, "[Hook %d] Condition check error: %v\n", i+1, err)
 				shouldExecute = false
 				break
 			}
@@ -357,33 +585,54 @@ func dryRunPreCompactHooks(config *Config, input *PreCompactInput, rawJSON any)
 		}
 
 		executed = true
-		fmt.Printf("[Hook %d] Would execute:\n", i+1)
+		if dryRunWithinMaxSideEffects(w, i, hook.Actions) {
+			continue
+		}
+		fmt.Fprintf(w, "[Hook %d] Would execute:\n", i+1)
+		dryRunSideEffects(w, hook.Actions)
+		if hook.Mutex != "" {
+			fmt.Fprintf(w, "  Mutex: %s\n", hook.Mutex)
+		}
+		if hook.EnvFrom != "" {
+			fmt.Fprintf(w, "  Env from: %s\n", hook.EnvFrom)
+		}
+		if hook.Cooldown != "" {
+			fmt.Fprintf(w, "  Cooldown: %s (%s)\n", hook.Cooldown, cooldownScopeName(hook.CooldownScope))
+		}
 		for _, action := range hook.Actions {
 			switch action.Type {
 			case "command":
-				cmd := unifiedTemplateReplace(action.Command, rawJSON)
-				fmt.Printf("  Command: %s\n", cmd)
-				if action.UseStdin {
-					fmt.Printf("  UseStdin: true\n")
+				cmd := actionCommand(action, rawJSON)
+				fmt.Fprintf(w, "  Command: %s\n", cmd)
+				if action.Runner != "" {
+					fmt.Fprintf(w, "  Runner: %s\n", action.Runner)
+				}
+				if action.EnvFrom != "" {
+					fmt.Fprintf(w, "  Env from: %s\n", action.EnvFrom)
 				}
+				fmt.Fprintf(w, "  Stdin: %s\n", commandStdinMode(action))
+			case "http":
+				dryRunHTTPAction(w, action, rawJSON)
+			case "opa":
+				fmt.Fprintf(w, "  Policy: %s (query %s)\n", action.Policy, opaActionQuery(action))
 			case "output":
-				fmt.Printf("  Message: %s\n", action.Message)
+				fmt.Fprintf(w, "  Message: %s\n", action.Message)
 			}
 		}
 	}
 
 	if !executed {
-		fmt.Println("No hooks would be executed")
+		fmt.Fprintln(w, "No hooks would be executed")
 	}
 	return nil
 }
 
 // dryRunSessionStartHooks performs a dry-run of SessionStart hooks, showing what would be executed without actually running.
-func dryRunSessionStartHooks(config *Config, input *SessionStartInput, rawJSON any) error {
-	fmt.Println("=== SessionStart Hooks (Dry Run) ===")
+func dryRunSessionStartHooks(w io.Writer, config *Config, input *SessionStartInput, rawJSON any) error {
+	fmt.Fprintln(w, "=== SessionStart Hooks (Dry Run) ===")
 
 	if len(config.SessionStart) == 0 {
-		fmt.Println("No SessionStart hooks configured")
+		fmt.Fprintln(w, "No SessionStart hooks configured")
 		return nil
 	}
 
@@ -399,7 +648,7 @@ func dryRunSessionStartHooks(config *Config, input *SessionStartInput, rawJSON a
 		for _, condition := range hook.Conditions {
 			matched, err := checkSessionStartCondition(condition, input)
 			if err != nil {
-				fmt.Printf("[Hook %d] Condition check error: %v\n", i+1, err)
+				fmt.Fprintf(w, "[Hook %d] Condition check error: %v\n", i+1, err)
 				shouldExecute = false
 				break
 			}
@@ -413,33 +662,56 @@ func dryRunSessionStartHooks(config *Config, input *SessionStartInput, rawJSON a
 		}
 
 		executed = true
-		fmt.Printf("[Hook %d] Matcher: %s, Source: %s\n", i+1, hook.Matcher, input.Source)
+		if dryRunWithinMaxSideEffects(w, i, hook.Actions) {
+			continue
+		}
+		fmt.Fprintf(w, "[Hook %d] Matcher: %s, Source: %s\n", i+1, hook.Matcher, input.Source)
+		dryRunSideEffects(w, hook.Actions)
+		if hook.Mutex != "" {
+			fmt.Fprintf(w, "  Mutex: %s\n", hook.Mutex)
+		}
+		if hook.EnvFrom != "" {
+			fmt.Fprintf(w, "  Env from: %s\n", hook.EnvFrom)
+		}
+		if hook.Cooldown != "" {
+			fmt.Fprintf(w, "  Cooldown: %s (%s)\n", hook.Cooldown, cooldownScopeName(hook.CooldownScope))
+		}
 		for _, action := range hook.Actions {
 			switch action.Type {
 			case "command":
-				cmd := unifiedTemplateReplace(action.Command, rawJSON)
-				fmt.Printf("  Command: %s\n", cmd)
-				if action.UseStdin {
-					fmt.Printf("  UseStdin: true\n")
+				cmd := actionCommand(action, rawJSON)
+				fmt.Fprintf(w, "  Command: %s\n", cmd)
+				if action.Runner != "" {
+					fmt.Fprintf(w, "  Runner: %s\n", action.Runner)
+				}
+				if action.EnvFrom != "" {
+					fmt.Fprintf(w, "  Env from: %s\n", action.EnvFrom)
 				}
+				fmt.Fprintf(w, "  Stdin: %s\n", commandStdinMode(action))
+			case "http":
+				dryRunHTTPAction(w, action, rawJSON)
+			case "opa":
+				fmt.Fprintf(w, "  Policy: %s (query %s)\n", action.Policy, opaActionQuery(action))
 			case "output":
-				fmt.Printf("  Message: %s\n", action.Message)
+				fmt.Fprintf(w, "  Message: %s\n", action.Message)
+			case "time_tracking":
+				fmt.Fprintf(w, "  Time tracking: start a timer in %s\n", timeLedgerPath(action))
 			}
 		}
 	}
 
 	if !executed {
-		fmt.Println("No matching SessionStart hooks found")
+		fmt.Fprintln(w, "No matching SessionStart hooks found")
 	}
 	return nil
 }
 
 // dryRunUserPromptSubmitHooks performs a dry-run of UserPromptSubmit hooks, showing what would be executed without actually running.
-func dryRunUserPromptSubmitHooks(config *Config, input *UserPromptSubmitInput, rawJSON any) error {
-	fmt.Println("=== UserPromptSubmit Hooks (Dry Run) ===")
+func dryRunUserPromptSubmitHooks(w io.Writer, config *Config, input *UserPromptSubmitInput, rawJSON any) error {
+	fmt.Fprintln(w, "=== UserPromptSubmit Hooks (Dry Run) ===")
 
 	if len(config.UserPromptSubmit) == 0 {
-		fmt.Println("No UserPromptSubmit hooks configured")
+		fmt.Fprintln(w, "No UserPromptSubmit hooks configured")
 		return nil
 	}
 
@@ -450,7 +722,7 @@ func dryRunUserPromptSubmitHooks(config *Config, input *UserPromptSubmitInput, r
 		for _, condition := range hook.Conditions {
 			matched, err := checkUserPromptSubmitCondition(condition, input)
 			if err != nil {
-				fmt.Printf("[Hook %d] Condition check error: %v\n", i+1, err)
+				fmt.Fprintf(w, "[Hook %d] Condition check error: %v\n", i+1, err)
 				shouldExecute = false
 				break
 			}
@@ -464,33 +736,54 @@ func dryRunUserPromptSubmitHooks(config *Config, input *UserPromptSubmitInput, r
 		}
 
 		executed = true
-		fmt.Printf("[Hook %d] Prompt: %s\n", i+1, input.Prompt)
+		if dryRunWithinMaxSideEffects(w, i, hook.Actions) {
+			continue
+		}
+		fmt.Fprintf(w, "[Hook %d] Prompt: %s\n", i+1, input.Prompt)
+		dryRunSideEffects(w, hook.Actions)
+		if hook.Mutex != "" {
+			fmt.Fprintf(w, "  Mutex: %s\n", hook.Mutex)
+		}
+		if hook.EnvFrom != "" {
+			fmt.Fprintf(w, "  Env from: %s\n", hook.EnvFrom)
+		}
+		if hook.Cooldown != "" {
+			fmt.Fprintf(w, "  Cooldown: %s (%s)\n", hook.Cooldown, cooldownScopeName(hook.CooldownScope))
+		}
 		for _, action := range hook.Actions {
 			switch action.Type {
 			case "command":
-				cmd := unifiedTemplateReplace(action.Command, rawJSON)
-				fmt.Printf("  Command: %s\n", cmd)
-				if action.UseStdin {
-					fmt.Printf("  UseStdin: true\n")
+				cmd := actionCommand(action, rawJSON)
+				fmt.Fprintf(w, "  Command: %s\n", cmd)
+				if action.Runner != "" {
+					fmt.Fprintf(w, "  Runner: %s\n", action.Runner)
+				}
+				if action.EnvFrom != "" {
+					fmt.Fprintf(w, "  Env from: %s\n", action.EnvFrom)
 				}
+				fmt.Fprintf(w, "  Stdin: %s\n", commandStdinMode(action))
+			case "http":
+				dryRunHTTPAction(w, action, rawJSON)
+			case "opa":
+				fmt.Fprintf(w, "  Policy: %s (query %s)\n", action.Policy, opaActionQuery(action))
 			case "output":
-				fmt.Printf("  Message: %s\n", action.Message)
+				fmt.Fprintf(w, "  Message: %s\n", action.Message)
 			}
 		}
 	}
 
 	if !executed {
-		fmt.Println("No matching UserPromptSubmit hooks found")
+		fmt.Fprintln(w, "No matching UserPromptSubmit hooks found")
 	}
 	return nil
 }
 
 // dryRunSessionEndHooks performs a dry-run of SessionEnd hooks, showing what would be executed without actually running.
-func dryRunSessionEndHooks(config *Config, input *SessionEndInput, rawJSON any) error {
-	fmt.Println("=== SessionEnd Hooks (Dry Run) ===")
+func dryRunSessionEndHooks(w io.Writer, config *Config, input *SessionEndInput, rawJSON any) error {
+	fmt.Fprintln(w, "=== SessionEnd Hooks (Dry Run) ===")
 
 	if len(config.SessionEnd) == 0 {
-		fmt.Println("No SessionEnd hooks configured")
+		fmt.Fprintln(w, "No SessionEnd hooks configured")
 		return nil
 	}
 
@@ -501,7 +794,7 @@ func dryRunSessionEndHooks(config *Config, input *SessionEndInput, rawJSON any)
 		for _, condition := range hook.Conditions {
 			matched, err := checkSessionEndCondition(condition, input)
 			if err != nil {
-				fmt.Printf("[Hook %d] Condition check error: %v\n", i+1, err)
+				fmt.Fprintf(w, "[Hook %d] Condition check error: %v\n", i+1, err)
 				shouldExecute = false
 				break
 			}
@@ -515,33 +808,56 @@ func dryRunSessionEndHooks(config *Config, input *SessionEndInput, rawJSON any)
 		}
 
 		executed = true
-		fmt.Printf("[Hook %d] Reason: %s\n", i+1, input.Reason)
+		if dryRunWithinMaxSideEffects(w, i, hook.Actions) {
+			continue
+		}
+		fmt.Fprintf(w, "[Hook %d] Reason: %s\n", i+1, input.Reason)
+		dryRunSideEffects(w, hook.Actions)
+		if hook.Mutex != "" {
+			fmt.Fprintf(w, "  Mutex: %s\n", hook.Mutex)
+		}
+		if hook.EnvFrom != "" {
+			fmt.Fprintf(w, "  Env from: %s\n", hook.EnvFrom)
+		}
+		if hook.Cooldown != "" {
+			fmt.Fprintf(w, "  Cooldown: %s (%s)\n", hook.Cooldown, cooldownScopeName(hook.CooldownScope))
+		}
 		for _, action := range hook.Actions {
 			switch action.Type {
 			case "command":
-				cmd := unifiedTemplateReplace(action.Command, rawJSON)
-				fmt.Printf("  Command: %s\n", cmd)
-				if action.UseStdin {
-					fmt.Printf("  UseStdin: true\n")
+				cmd := actionCommand(action, rawJSON)
+				fmt.Fprintf(w, "  Command: %s\n", cmd)
+				if action.Runner != "" {
+					fmt.Fprintf(w, "  Runner: %s\n", action.Runner)
+				}
+				if action.EnvFrom != "" {
+					fmt.Fprintf(w, "  Env from: %s\n", action.EnvFrom)
 				}
+				fmt.Fprintf(w, "  Stdin: %s\n", commandStdinMode(action))
+			case "http":
+				dryRunHTTPAction(w, action, rawJSON)
+			case "opa":
+				fmt.Fprintf(w, "  Policy: %s (query %s)\n", action.Policy, opaActionQuery(action))
 			case "output":
 				msg := unifiedTemplateReplace(action.Message, rawJSON)
-				fmt.Printf("  Message: %s\n", msg)
+				fmt.Fprintf(w, "  Message: %s\n", msg)
+			case "time_tracking":
+				fmt.Fprintf(w, "  Time tracking: stop the timer in %s\n", timeLedgerPath(action))
 			}
 		}
 	}
 
 	if !executed {
-		fmt.Println("No matching SessionEnd hooks found")
+		fmt.Fprintln(w, "No matching SessionEnd hooks found")
 	}
 	return nil
 }
 
 // dryRunPermissionRequestHooks prints what would be executed for PermissionRequest hooks
-func dryRunPermissionRequestHooks(config *Config, input *PermissionRequestInput, rawJSON any) error {
-	fmt.Println("\n=== PermissionRequest Hooks ===")
+func dryRunPermissionRequestHooks(w io.Writer, config *Config, input *PermissionRequestInput, rawJSON any) error {
+	fmt.Fprintln(w, "\n=== PermissionRequest Hooks ===")
 	if len(config.PermissionRequest) == 0 {
-		fmt.Println("No PermissionRequest hooks configured")
+		fmt.Fprintln(w, "No PermissionRequest hooks configured")
 		return nil
 	}
 
@@ -549,7 +865,7 @@ func dryRunPermissionRequestHooks(config *Config, input *PermissionRequestInput,
 	for i, hook := range config.PermissionRequest {
 		shouldExecute, err := shouldExecutePermissionRequestHook(hook, input)
 		if err != nil {
-			fmt.Printf("[Hook %d] Error checking conditions: %v\n", i+1, err)
+			fmt.Fprintf(w, "[Hook %d] Error checking conditions: %v\n", i+1, err)
 			continue
 		}
 		if !shouldExecute {
@@ -557,30 +873,108 @@ func dryRunPermissionRequestHooks(config *Config, input *PermissionRequestInput,
 		}
 
 		executed = true
-		fmt.Printf("[Hook %d] Tool: %s\n", i+1, input.ToolName)
+		if dryRunWithinMaxSideEffects(w, i, hook.Actions) {
+			continue
+		}
+		fmt.Fprintf(w, "[Hook %d] Tool: %s\n", i+1, input.ToolName)
+		dryRunSideEffects(w, hook.Actions)
+		dryRunMCPTool(w, input.ToolName)
+		if hook.Mutex != "" {
+			fmt.Fprintf(w, "  Mutex: %s\n", hook.Mutex)
+		}
+		if hook.EnvFrom != "" {
+			fmt.Fprintf(w, "  Env from: %s\n", hook.EnvFrom)
+		}
+		if hook.Cooldown != "" {
+			fmt.Fprintf(w, "  Cooldown: %s (%s)\n", hook.Cooldown, cooldownScopeName(hook.CooldownScope))
+		}
 		for _, action := range hook.Actions {
 			switch action.Type {
 			case "command":
-				cmd := unifiedTemplateReplace(action.Command, rawJSON)
-				fmt.Printf("  Command: %s\n", cmd)
-				if action.UseStdin {
-					fmt.Printf("  UseStdin: true\n")
+				cmd := actionCommand(action, rawJSON)
+				fmt.Fprintf(w, "  Command: %s\n", cmd)
+				if action.Runner != "" {
+					fmt.Fprintf(w, "  Runner: %s\n", action.Runner)
 				}
+				if action.EnvFrom != "" {
+					fmt.Fprintf(w, "  Env from: %s\n", action.EnvFrom)
+				}
+				fmt.Fprintf(w, "  Stdin: %s\n", commandStdinMode(action))
+			case "http":
+				dryRunHTTPAction(w, action, rawJSON)
+			case "opa":
+				fmt.Fprintf(w, "  Policy: %s (query %s)\n", action.Policy, opaActionQuery(action))
 			case "output":
 				msg := unifiedTemplateReplace(action.Message, rawJSON)
-				fmt.Printf("  Message: %s\n", msg)
+				fmt.Fprintf(w, "  Message: %s\n", msg)
 				if action.Behavior != nil {
-					fmt.Printf("  Behavior: %s\n", *action.Behavior)
+					fmt.Fprintf(w, "  Behavior: %s\n", *action.Behavior)
 				}
 				if action.Interrupt != nil && *action.Interrupt {
-					fmt.Printf("  Interrupt: true\n")
+					fmt.Fprintf(w, "  Interrupt: true\n")
+				}
+				if action.Behavior != nil && *action.Behavior == "allow" {
+					dryRunUpdatedInput(w, action, rawJSON)
 				}
 			}
 		}
 	}
 
 	if !executed {
-		fmt.Println("No matching PermissionRequest hooks found")
+		fmt.Fprintln(w, "No matching PermissionRequest hooks found")
 	}
 	return nil
 }
+
+// dryRunMCPTool prints the server and tool of an MCP tool name.
+func dryRunMCPTool(w io.Writer, toolName string) {
+	if server, tool, ok := parseMCPToolName(toolName); ok {
+		fmt.Fprintf(w, "  MCP server: %s, tool: %s\n", server, tool)
+	}
+}
+
+// dryRunUpdatedInput prints the tool_input the updated_input of an output action would produce.
+func dryRunUpdatedInput(w io.Writer, action Action, rawJSON any) {
+	if action.UpdatedInput == nil {
+		return
+	}
+	updated, err := json.Marshal(applyUpdatedInput(nil, action.UpdatedInput, rawJSON))
+	if err != nil {
+		fmt.Fprintf(w, "  Updated input: (error: %v)\n", err)
+		return
+	}
+	fmt.Fprintf(w, "  Updated input: %s\n", updated)
+}
+
+// dryRunHTTPAction prints the request an http action would send.
+// Environment variables are left unexpanded so that tokens are not printed.
+func dryRunHTTPAction(w io.Writer, action Action, rawJSON any) {
+	fmt.Fprintf(w, "  HTTP: %s %s\n", httpActionMethod(action), unifiedTemplateReplace(action.URL, rawJSON))
+	if body, err := httpActionBody(action, rawJSON); err != nil {
+		fmt.Fprintf(w, "  Body: (error: %v)\n", err)
+	} else if body != nil {
+		fmt.Fprintf(w, "  Body: %s\n", body)
+	}
+}
+
+// dryRunDigestAction prints what a digest action would do with the queued notifications.
+func dryRunDigestAction(w io.Writer, action Action) {
+	if action.Flush {
+		fmt.Fprintf(w, "  Digest: flush the queued notifications into {.digest}\n")
+		return
+	}
+	fmt.Fprintf(w, "  Digest: queue the notification in %s\n", digestPath())
+}
+
+// dryRunGitCheckpointAction prints the checkpoint a git_checkpoint action would record for the repository containing dir.
+func dryRunGitCheckpointAction(w io.Writer, action Action, dir string, rawJSON any) {
+	root := gitTopLevel(dir)
+	switch {
+	case root == "":
+		fmt.Fprintf(w, "  Checkpoint: skipped (%s is not in a git repository)\n", dir)
+	case !gitCheckpointAllowed(root, action.Repos):
+		fmt.Fprintf(w, "  Checkpoint: skipped (%s is not in repos)\n", root)
+	default:
+		fmt.Fprintf(w, "  Checkpoint: %s %s if it has changes (%q)\n", gitCheckpointMode(action), root, gitCheckpointMessage(action, rawJSON))
+	}
+}
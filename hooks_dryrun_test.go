@@ -1,19 +1,12 @@
 package main
 
 import (
-	"bytes"
-	"io"
 	"os"
 	"strings"
 	"testing"
 )
 
 func TestDryRunPreToolUseHooks_NoMatch(t *testing.T) {
-	// 標準出力をキャプチャ
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
 	config := &Config{
 		PreToolUse: []PreToolUseHook{
 			{Matcher: "Edit", Actions: []Action{{Type: "output", Message: "test", ExitStatus: &[]int{0}[0]}}},
@@ -22,19 +15,13 @@ func TestDryRunPreToolUseHooks_NoMatch(t *testing.T) {
 
 	input := &PreToolUseInput{ToolName: "Write"} // マッチしない
 
-	err := dryRunPreToolUseHooks(config, input, nil)
-
-	// 標準出力を復元
-	_ = w.Close()
-	os.Stdout = oldStdout
-
-	// 出力を読み取り
-	var buf bytes.Buffer
-	_, _ = buf.ReadFrom(r)
-	output := buf.String()
+	var err error
+	output := captureStdout(t, func() {
+		err = dryRunPreToolUseHooks(os.Stdout, config, input, nil)
+	})
 
 	if err != nil {
-		t.Errorf("dryRunPreToolUseHooks() error = %v", err)
+		t.Errorf("dryRunPreToolUseHooks(os.Stdout, ) error = %v", err)
 	}
 
 	if !strings.Contains(output, "No hooks would be executed") {
@@ -43,11 +30,6 @@ func TestDryRunPreToolUseHooks_NoMatch(t *testing.T) {
 }
 
 func TestDryRunPreToolUseHooks_WithMatch(t *testing.T) {
-	// 標準出力をキャプチャ
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
 	config := &Config{
 		PreToolUse: []PreToolUseHook{
 			{
@@ -73,25 +55,19 @@ func TestDryRunPreToolUseHooks_WithMatch(t *testing.T) {
 		},
 	}
 
-	err := dryRunPreToolUseHooks(config, input, rawJSON)
-
-	// 標準出力を復元
-	_ = w.Close()
-	os.Stdout = oldStdout
-
-	// 出力を読み取り
-	var buf bytes.Buffer
-	_, _ = buf.ReadFrom(r)
-	output := buf.String()
+	var err error
+	output := captureStdout(t, func() {
+		err = dryRunPreToolUseHooks(os.Stdout, config, input, rawJSON)
+	})
 
 	if err != nil {
-		t.Errorf("dryRunPreToolUseHooks() error = %v", err)
+		t.Errorf("dryRunPreToolUseHooks(os.Stdout, ) error = %v", err)
 	}
 
 	expectedStrings := []string{
 		"=== PreToolUse Hooks (Dry Run) ===",
 		"[Hook 1] Would execute:",
-		"Command: echo test.go",
+		"Command: echo 'test.go'",
 		"Message: Processing...",
 	}
 
@@ -102,12 +78,83 @@ func TestDryRunPreToolUseHooks_WithMatch(t *testing.T) {
 	}
 }
 
-func TestDryRunPreToolUseHooks_ProcessSubstitution(t *testing.T) {
-	// 標準出力をキャプチャ
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
+func TestDryRunPreToolUseHooks_Stdin(t *testing.T) {
+	config := &Config{
+		PreToolUse: []PreToolUseHook{
+			{
+				Matcher: "Write",
+				Actions: []Action{
+					{Type: "command", Command: "check-default"},
+					{Type: "command", Command: "check-use-stdin-false", UseStdin: boolPtr(false)},
+					{Type: "command", Command: "check-enriched", Stdin: "enriched"},
+				},
+			},
+		},
+	}
+
+	var err error
+	output := captureStdout(t, func() {
+		err = dryRunPreToolUseHooks(os.Stdout, config, &PreToolUseInput{ToolName: "Write"}, map[string]any{"tool_name": "Write"})
+	})
+	if err != nil {
+		t.Fatalf("dryRunPreToolUseHooks() error = %v", err)
+	}
+
+	// 実際に使われるstdinのモードを表示する
+	want := "Command: check-default\n  Stdin: raw\n  Command: check-use-stdin-false\n  Stdin: none\n  Command: check-enriched\n  Stdin: enriched\n"
+	if !strings.Contains(output, want) {
+		t.Errorf("Expected output to contain %q, got: %q", want, output)
+	}
+}
 
+func TestDryRunPreToolUseHooks_UpdatedInput(t *testing.T) {
+	config := &Config{
+		PreToolUse: []PreToolUseHook{
+			{
+				Matcher: "Bash",
+				Actions: []Action{
+					{
+						Type:               "output",
+						Message:            "rm is replaced by trash",
+						PermissionDecision: stringPtr("allow"),
+						UpdatedInput:       map[string]any{"command": `trash {.tool_input.command | ltrimstr("rm ")}`},
+					},
+					{
+						Type:         "output",
+						Message:      "denied",
+						UpdatedInput: map[string]any{"command": "ignored"},
+					},
+				},
+			},
+		},
+	}
+	input := &PreToolUseInput{ToolName: "Bash", ToolInput: ToolInput{Command: "rm a.txt"}}
+	rawJSON := map[string]any{
+		"tool_name":  "Bash",
+		"tool_input": map[string]any{"command": "rm a.txt", "description": "Remove a.txt"},
+	}
+
+	var err error
+	output := captureStdout(t, func() {
+		err = dryRunPreToolUseHooks(os.Stdout, config, input, rawJSON)
+	})
+	if err != nil {
+		t.Fatalf("dryRunPreToolUseHooks() error = %v", err)
+	}
+
+	want := `Updated input: {"command":"trash a.txt","description":"Remove a.txt"}`
+	if !strings.Contains(output, want) {
+		t.Errorf("Expected output to contain %q, got: %q", want, output)
+	}
+	if strings.Count(output, "Updated input:") != 1 {
+		t.Errorf("updated_input of a deny action should not be shown, got: %q", output)
+	}
+	if rawJSON["tool_input"].(map[string]any)["command"] != "rm a.txt" {
+		t.Errorf("dry run modified the input: %v", rawJSON)
+	}
+}
+
+func TestDryRunPreToolUseHooks_ProcessSubstitution(t *testing.T) {
 	config := &Config{
 		PreToolUse: []PreToolUseHook{
 			{
@@ -145,20 +192,14 @@ func TestDryRunPreToolUseHooks_ProcessSubstitution(t *testing.T) {
 		},
 	}
 
-	err := dryRunPreToolUseHooks(config, input, rawJSON)
-
-	// 標準出力を元に戻す
-	_ = w.Close()
-	os.Stdout = oldStdout
-
-	// 出力を読み取る
-	var buf bytes.Buffer
-	_, _ = io.Copy(&buf, r)
-	stdoutOutput := buf.String()
+	var err error
+	stdoutOutput := captureStdout(t, func() {
+		err = dryRunPreToolUseHooks(os.Stdout, config, input, rawJSON)
+	})
 
 	// エラーがないこと
 	if err != nil {
-		t.Errorf("dryRunPreToolUseHooks() error = %v, want nil", err)
+		t.Errorf("dryRunPreToolUseHooks(os.Stdout, ) error = %v, want nil", err)
 	}
 
 	// 標準出力に"would deny"が含まれることを確認
@@ -168,11 +209,6 @@ func TestDryRunPreToolUseHooks_ProcessSubstitution(t *testing.T) {
 }
 
 func TestDryRunPostToolUseHooks_ProcessSubstitution(t *testing.T) {
-	// 標準出力をキャプチャ
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
 	config := &Config{
 		PostToolUse: []PostToolUseHook{
 			{
@@ -210,20 +246,14 @@ func TestDryRunPostToolUseHooks_ProcessSubstitution(t *testing.T) {
 		},
 	}
 
-	err := dryRunPostToolUseHooks(config, input, rawJSON)
-
-	// 標準出力を元に戻す
-	_ = w.Close()
-	os.Stdout = oldStdout
-
-	// 出力を読み取る
-	var buf bytes.Buffer
-	_, _ = io.Copy(&buf, r)
-	stdoutOutput := buf.String()
+	var err error
+	stdoutOutput := captureStdout(t, func() {
+		err = dryRunPostToolUseHooks(os.Stdout, config, input, rawJSON)
+	})
 
 	// エラーがないこと
 	if err != nil {
-		t.Errorf("dryRunPostToolUseHooks() error = %v, want nil", err)
+		t.Errorf("dryRunPostToolUseHooks(os.Stdout, ) error = %v, want nil", err)
 	}
 
 	// 標準出力に"would warn"が含まれることを確認
@@ -233,11 +263,6 @@ func TestDryRunPostToolUseHooks_ProcessSubstitution(t *testing.T) {
 }
 
 func TestDryRunNotificationHooks_NoMatch(t *testing.T) {
-	// 標準出力をキャプチャ
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
 	config := &Config{
 		Notification: []NotificationHook{
 			{Matcher: "permission_prompt", Actions: []Action{{Type: "output", Message: "test"}}},
@@ -249,19 +274,13 @@ func TestDryRunNotificationHooks_NoMatch(t *testing.T) {
 		NotificationType: "idle_prompt", // マッチしない
 	}
 
-	err := dryRunNotificationHooks(config, input, nil)
-
-	// 標準出力を復元
-	_ = w.Close()
-	os.Stdout = oldStdout
-
-	// 出力を読み取り
-	var buf bytes.Buffer
-	_, _ = buf.ReadFrom(r)
-	output := buf.String()
+	var err error
+	output := captureStdout(t, func() {
+		err = dryRunNotificationHooks(os.Stdout, config, input, nil)
+	})
 
 	if err != nil {
-		t.Errorf("dryRunNotificationHooks() error = %v", err)
+		t.Errorf("dryRunNotificationHooks(os.Stdout, ) error = %v", err)
 	}
 
 	if !strings.Contains(output, "No hooks would be executed") {
@@ -270,11 +289,6 @@ func TestDryRunNotificationHooks_NoMatch(t *testing.T) {
 }
 
 func TestDryRunNotificationHooks_WithMatch(t *testing.T) {
-	// 標準出力をキャプチャ
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
 	config := &Config{
 		Notification: []NotificationHook{
 			{
@@ -299,25 +313,19 @@ func TestDryRunNotificationHooks_WithMatch(t *testing.T) {
 		"title":             input.Title,
 	}
 
-	err := dryRunNotificationHooks(config, input, rawJSON)
-
-	// 標準出力を復元
-	_ = w.Close()
-	os.Stdout = oldStdout
-
-	// 出力を読み取り
-	var buf bytes.Buffer
-	_, _ = buf.ReadFrom(r)
-	output := buf.String()
+	var err error
+	output := captureStdout(t, func() {
+		err = dryRunNotificationHooks(os.Stdout, config, input, rawJSON)
+	})
 
 	if err != nil {
-		t.Errorf("dryRunNotificationHooks() error = %v", err)
+		t.Errorf("dryRunNotificationHooks(os.Stdout, ) error = %v", err)
 	}
 
 	expectedStrings := []string{
 		"=== Notification Hooks (Dry Run) ===",
 		"[Hook 1] Would execute:",
-		"Command: echo permission_prompt",
+		"Command: echo 'permission_prompt'",
 		"Message: Notification received",
 	}
 
@@ -329,11 +337,6 @@ func TestDryRunNotificationHooks_WithMatch(t *testing.T) {
 }
 
 func TestDryRunSubagentStartHooks_NoMatch(t *testing.T) {
-	// 標準出力をキャプチャ
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
 	config := &Config{
 		SubagentStart: []SubagentStartHook{
 			{Matcher: "Explore", Actions: []Action{{Type: "output", Message: "test"}}},
@@ -345,19 +348,13 @@ func TestDryRunSubagentStartHooks_NoMatch(t *testing.T) {
 		AgentType: "Bash", // マッチしない
 	}
 
-	err := dryRunSubagentStartHooks(config, input, nil)
-
-	// 標準出力を復元
-	_ = w.Close()
-	os.Stdout = oldStdout
-
-	// 出力を読み取り
-	var buf bytes.Buffer
-	_, _ = buf.ReadFrom(r)
-	output := buf.String()
+	var err error
+	output := captureStdout(t, func() {
+		err = dryRunSubagentStartHooks(os.Stdout, config, input, nil)
+	})
 
 	if err != nil {
-		t.Errorf("dryRunSubagentStartHooks() error = %v", err)
+		t.Errorf("dryRunSubagentStartHooks(os.Stdout, ) error = %v", err)
 	}
 
 	if !strings.Contains(output, "No hooks would be executed") {
@@ -366,11 +363,6 @@ func TestDryRunSubagentStartHooks_NoMatch(t *testing.T) {
 }
 
 func TestDryRunSubagentStartHooks_WithMatch(t *testing.T) {
-	// 標準出力をキャプチャ
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
 	config := &Config{
 		SubagentStart: []SubagentStartHook{
 			{
@@ -395,25 +387,19 @@ func TestDryRunSubagentStartHooks_WithMatch(t *testing.T) {
 		"agent_type":      input.AgentType,
 	}
 
-	err := dryRunSubagentStartHooks(config, input, rawJSON)
-
-	// 標準出力を復元
-	_ = w.Close()
-	os.Stdout = oldStdout
-
-	// 出力を読み取り
-	var buf bytes.Buffer
-	_, _ = buf.ReadFrom(r)
-	output := buf.String()
+	var err error
+	output := captureStdout(t, func() {
+		err = dryRunSubagentStartHooks(os.Stdout, config, input, rawJSON)
+	})
 
 	if err != nil {
-		t.Errorf("dryRunSubagentStartHooks() error = %v", err)
+		t.Errorf("dryRunSubagentStartHooks(os.Stdout, ) error = %v", err)
 	}
 
 	expectedStrings := []string{
 		"=== SubagentStart Hooks (Dry Run) ===",
 		"[Hook 1] Would execute:",
-		"Command: echo Explore",
+		"Command: echo 'Explore'",
 		"Message: Subagent started",
 	}
 
@@ -425,11 +411,6 @@ func TestDryRunSubagentStartHooks_WithMatch(t *testing.T) {
 }
 
 func TestDryRunStopHooks_NoMatch(t *testing.T) {
-	// 標準出力をキャプチャ
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
 	config := &Config{
 		Stop: []StopHook{
 			{
@@ -446,19 +427,13 @@ func TestDryRunStopHooks_NoMatch(t *testing.T) {
 		},
 	}
 
-	err := dryRunStopHooks(config, input, nil)
-
-	// 標準出力を復元
-	_ = w.Close()
-	os.Stdout = oldStdout
-
-	// 出力を読み取り
-	var buf bytes.Buffer
-	_, _ = buf.ReadFrom(r)
-	output := buf.String()
+	var err error
+	output := captureStdout(t, func() {
+		err = dryRunStopHooks(os.Stdout, config, input, nil)
+	})
 
 	if err != nil {
-		t.Errorf("dryRunStopHooks() error = %v", err)
+		t.Errorf("dryRunStopHooks(os.Stdout, ) error = %v", err)
 	}
 
 	if !strings.Contains(output, "No hooks would be executed") {
@@ -467,11 +442,6 @@ func TestDryRunStopHooks_NoMatch(t *testing.T) {
 }
 
 func TestDryRunStopHooks_WithMatch(t *testing.T) {
-	// 標準出力をキャプチャ
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
 	config := &Config{
 		Stop: []StopHook{
 			{
@@ -498,25 +468,19 @@ func TestDryRunStopHooks_WithMatch(t *testing.T) {
 		"stop_hook_active": input.StopHookActive,
 	}
 
-	err := dryRunStopHooks(config, input, rawJSON)
-
-	// 標準出力を復元
-	_ = w.Close()
-	os.Stdout = oldStdout
-
-	// 出力を読み取り
-	var buf bytes.Buffer
-	_, _ = buf.ReadFrom(r)
-	output := buf.String()
+	var err error
+	output := captureStdout(t, func() {
+		err = dryRunStopHooks(os.Stdout, config, input, rawJSON)
+	})
 
 	if err != nil {
-		t.Errorf("dryRunStopHooks() error = %v", err)
+		t.Errorf("dryRunStopHooks(os.Stdout, ) error = %v", err)
 	}
 
 	expectedStrings := []string{
 		"=== Stop Hooks (Dry Run) ===",
 		"[Hook 1] Would execute:",
-		"Command: echo /important/project",
+		"Command: echo '/important/project'",
 		"Message: Stop requested",
 	}
 
@@ -528,11 +492,6 @@ func TestDryRunStopHooks_WithMatch(t *testing.T) {
 }
 
 func TestDryRunSubagentStopHooks_NoMatch(t *testing.T) {
-	// 標準出力をキャプチャ
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
 	config := &Config{
 		SubagentStop: []SubagentStopHook{
 			{
@@ -549,19 +508,13 @@ func TestDryRunSubagentStopHooks_NoMatch(t *testing.T) {
 		},
 	}
 
-	err := dryRunSubagentStopHooks(config, input, nil)
-
-	// 標準出力を復元
-	_ = w.Close()
-	os.Stdout = oldStdout
-
-	// 出力を読み取り
-	var buf bytes.Buffer
-	_, _ = buf.ReadFrom(r)
-	output := buf.String()
+	var err error
+	output := captureStdout(t, func() {
+		err = dryRunSubagentStopHooks(os.Stdout, config, input, nil)
+	})
 
 	if err != nil {
-		t.Errorf("dryRunSubagentStopHooks() error = %v", err)
+		t.Errorf("dryRunSubagentStopHooks(os.Stdout, ) error = %v", err)
 	}
 
 	if !strings.Contains(output, "No hooks would be executed") {
@@ -570,11 +523,6 @@ func TestDryRunSubagentStopHooks_NoMatch(t *testing.T) {
 }
 
 func TestDryRunSubagentStopHooks_WithMatch(t *testing.T) {
-	// 標準出力をキャプチャ
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
 	config := &Config{
 		SubagentStop: []SubagentStopHook{
 			{
@@ -601,25 +549,19 @@ func TestDryRunSubagentStopHooks_WithMatch(t *testing.T) {
 		"stop_hook_active": input.StopHookActive,
 	}
 
-	err := dryRunSubagentStopHooks(config, input, rawJSON)
-
-	// 標準出力を復元
-	_ = w.Close()
-	os.Stdout = oldStdout
-
-	// 出力を読み取り
-	var buf bytes.Buffer
-	_, _ = buf.ReadFrom(r)
-	output := buf.String()
+	var err error
+	output := captureStdout(t, func() {
+		err = dryRunSubagentStopHooks(os.Stdout, config, input, rawJSON)
+	})
 
 	if err != nil {
-		t.Errorf("dryRunSubagentStopHooks() error = %v", err)
+		t.Errorf("dryRunSubagentStopHooks(os.Stdout, ) error = %v", err)
 	}
 
 	expectedStrings := []string{
 		"=== SubagentStop Hooks (Dry Run) ===",
 		"[Hook 1] Would execute:",
-		"Command: echo /important/project",
+		"Command: echo '/important/project'",
 		"Message: SubagentStop requested",
 	}
 
@@ -631,11 +573,6 @@ func TestDryRunSubagentStopHooks_WithMatch(t *testing.T) {
 }
 
 func TestDryRunPreCompactHooks_NoMatch(t *testing.T) {
-	// 標準出力をキャプチャ
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
 	config := &Config{
 		PreCompact: []PreCompactHook{
 			{Matcher: "manual", Actions: []Action{{Type: "output", Message: "test"}}},
@@ -647,19 +584,13 @@ func TestDryRunPreCompactHooks_NoMatch(t *testing.T) {
 		Trigger:   "auto", // マッチしない
 	}
 
-	err := dryRunPreCompactHooks(config, input, nil)
-
-	// 標準出力を復元
-	_ = w.Close()
-	os.Stdout = oldStdout
-
-	// 出力を読み取り
-	var buf bytes.Buffer
-	_, _ = buf.ReadFrom(r)
-	output := buf.String()
+	var err error
+	output := captureStdout(t, func() {
+		err = dryRunPreCompactHooks(os.Stdout, config, input, nil)
+	})
 
 	if err != nil {
-		t.Errorf("dryRunPreCompactHooks() error = %v", err)
+		t.Errorf("dryRunPreCompactHooks(os.Stdout, ) error = %v", err)
 	}
 
 	if !strings.Contains(output, "No hooks would be executed") {
@@ -668,11 +599,6 @@ func TestDryRunPreCompactHooks_NoMatch(t *testing.T) {
 }
 
 func TestDryRunPreCompactHooks_WithMatch(t *testing.T) {
-	// 標準出力をキャプチャ
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
 	config := &Config{
 		PreCompact: []PreCompactHook{
 			{
@@ -697,25 +623,19 @@ func TestDryRunPreCompactHooks_WithMatch(t *testing.T) {
 		"custom_instructions": input.CustomInstructions,
 	}
 
-	err := dryRunPreCompactHooks(config, input, rawJSON)
-
-	// 標準出力を復元
-	_ = w.Close()
-	os.Stdout = oldStdout
-
-	// 出力を読み取り
-	var buf bytes.Buffer
-	_, _ = buf.ReadFrom(r)
-	output := buf.String()
+	var err error
+	output := captureStdout(t, func() {
+		err = dryRunPreCompactHooks(os.Stdout, config, input, rawJSON)
+	})
 
 	if err != nil {
-		t.Errorf("dryRunPreCompactHooks() error = %v", err)
+		t.Errorf("dryRunPreCompactHooks(os.Stdout, ) error = %v", err)
 	}
 
 	expectedStrings := []string{
 		"=== PreCompact Hooks (Dry Run) ===",
 		"[Hook 1] Would execute:",
-		"Command: echo manual",
+		"Command: echo 'manual'",
 		"Message: Pre-compaction processing",
 	}
 
@@ -727,11 +647,6 @@ func TestDryRunPreCompactHooks_WithMatch(t *testing.T) {
 }
 
 func TestDryRunSessionStartHooks_NoMatch(t *testing.T) {
-	// 標準出力をキャプチャ
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
 	config := &Config{
 		SessionStart: []SessionStartHook{
 			{Matcher: "startup", Actions: []Action{{Type: "output", Message: "test"}}},
@@ -743,19 +658,13 @@ func TestDryRunSessionStartHooks_NoMatch(t *testing.T) {
 		Source:    "resume", // マッチしない
 	}
 
-	err := dryRunSessionStartHooks(config, input, nil)
-
-	// 標準出力を復元
-	_ = w.Close()
-	os.Stdout = oldStdout
-
-	// 出力を読み取り
-	var buf bytes.Buffer
-	_, _ = buf.ReadFrom(r)
-	output := buf.String()
+	var err error
+	output := captureStdout(t, func() {
+		err = dryRunSessionStartHooks(os.Stdout, config, input, nil)
+	})
 
 	if err != nil {
-		t.Errorf("dryRunSessionStartHooks() error = %v", err)
+		t.Errorf("dryRunSessionStartHooks(os.Stdout, ) error = %v", err)
 	}
 
 	if !strings.Contains(output, "No matching SessionStart hooks found") {
@@ -764,11 +673,6 @@ func TestDryRunSessionStartHooks_NoMatch(t *testing.T) {
 }
 
 func TestDryRunSessionStartHooks_WithMatch(t *testing.T) {
-	// 標準出力をキャプチャ
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
 	config := &Config{
 		SessionStart: []SessionStartHook{
 			{
@@ -795,25 +699,19 @@ func TestDryRunSessionStartHooks_WithMatch(t *testing.T) {
 		"model":           input.Model,
 	}
 
-	err := dryRunSessionStartHooks(config, input, rawJSON)
-
-	// 標準出力を復元
-	_ = w.Close()
-	os.Stdout = oldStdout
-
-	// 出力を読み取り
-	var buf bytes.Buffer
-	_, _ = buf.ReadFrom(r)
-	output := buf.String()
+	var err error
+	output := captureStdout(t, func() {
+		err = dryRunSessionStartHooks(os.Stdout, config, input, rawJSON)
+	})
 
 	if err != nil {
-		t.Errorf("dryRunSessionStartHooks() error = %v", err)
+		t.Errorf("dryRunSessionStartHooks(os.Stdout, ) error = %v", err)
 	}
 
 	expectedStrings := []string{
 		"=== SessionStart Hooks (Dry Run) ===",
 		"[Hook 1] Matcher: startup, Source: startup",
-		"Command: echo startup",
+		"Command: echo 'startup'",
 		"Message: Session started",
 	}
 
@@ -825,11 +723,6 @@ func TestDryRunSessionStartHooks_WithMatch(t *testing.T) {
 }
 
 func TestDryRunUserPromptSubmitHooks_NoMatch(t *testing.T) {
-	// 標準出力をキャプチャ
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
 	config := &Config{
 		UserPromptSubmit: []UserPromptSubmitHook{
 			{
@@ -844,19 +737,13 @@ func TestDryRunUserPromptSubmitHooks_NoMatch(t *testing.T) {
 		Prompt:    "hello world", // マッチしない
 	}
 
-	err := dryRunUserPromptSubmitHooks(config, input, nil)
-
-	// 標準出力を復元
-	_ = w.Close()
-	os.Stdout = oldStdout
-
-	// 出力を読み取り
-	var buf bytes.Buffer
-	_, _ = buf.ReadFrom(r)
-	output := buf.String()
+	var err error
+	output := captureStdout(t, func() {
+		err = dryRunUserPromptSubmitHooks(os.Stdout, config, input, nil)
+	})
 
 	if err != nil {
-		t.Errorf("dryRunUserPromptSubmitHooks() error = %v", err)
+		t.Errorf("dryRunUserPromptSubmitHooks(os.Stdout, ) error = %v", err)
 	}
 
 	if !strings.Contains(output, "No matching UserPromptSubmit hooks found") {
@@ -865,11 +752,6 @@ func TestDryRunUserPromptSubmitHooks_NoMatch(t *testing.T) {
 }
 
 func TestDryRunUserPromptSubmitHooks_WithMatch(t *testing.T) {
-	// 標準出力をキャプチャ
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
 	config := &Config{
 		UserPromptSubmit: []UserPromptSubmitHook{
 			{
@@ -892,25 +774,19 @@ func TestDryRunUserPromptSubmitHooks_WithMatch(t *testing.T) {
 		"prompt":          input.Prompt,
 	}
 
-	err := dryRunUserPromptSubmitHooks(config, input, rawJSON)
-
-	// 標準出力を復元
-	_ = w.Close()
-	os.Stdout = oldStdout
-
-	// 出力を読み取り
-	var buf bytes.Buffer
-	_, _ = buf.ReadFrom(r)
-	output := buf.String()
+	var err error
+	output := captureStdout(t, func() {
+		err = dryRunUserPromptSubmitHooks(os.Stdout, config, input, rawJSON)
+	})
 
 	if err != nil {
-		t.Errorf("dryRunUserPromptSubmitHooks() error = %v", err)
+		t.Errorf("dryRunUserPromptSubmitHooks(os.Stdout, ) error = %v", err)
 	}
 
 	expectedStrings := []string{
 		"=== UserPromptSubmit Hooks (Dry Run) ===",
 		"[Hook 1] Prompt: delete all files",
-		"Command: echo delete all files",
+		"Command: echo 'delete all files'",
 		"Message: Dangerous command detected",
 	}
 
@@ -922,11 +798,6 @@ func TestDryRunUserPromptSubmitHooks_WithMatch(t *testing.T) {
 }
 
 func TestDryRunSessionEndHooks_NoMatch(t *testing.T) {
-	// 標準出力をキャプチャ
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
 	config := &Config{
 		SessionEnd: []SessionEndHook{
 			{
@@ -941,19 +812,13 @@ func TestDryRunSessionEndHooks_NoMatch(t *testing.T) {
 		Reason:    "logout", // マッチしない
 	}
 
-	err := dryRunSessionEndHooks(config, input, nil)
-
-	// 標準出力を復元
-	_ = w.Close()
-	os.Stdout = oldStdout
-
-	// 出力を読み取り
-	var buf bytes.Buffer
-	_, _ = buf.ReadFrom(r)
-	output := buf.String()
+	var err error
+	output := captureStdout(t, func() {
+		err = dryRunSessionEndHooks(os.Stdout, config, input, nil)
+	})
 
 	if err != nil {
-		t.Errorf("dryRunSessionEndHooks() error = %v", err)
+		t.Errorf("dryRunSessionEndHooks(os.Stdout, ) error = %v", err)
 	}
 
 	if !strings.Contains(output, "No matching SessionEnd hooks found") {
@@ -962,11 +827,6 @@ func TestDryRunSessionEndHooks_NoMatch(t *testing.T) {
 }
 
 func TestDryRunSessionEndHooks_WithMatch(t *testing.T) {
-	// 標準出力をキャプチャ
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
 	config := &Config{
 		SessionEnd: []SessionEndHook{
 			{
@@ -989,25 +849,19 @@ func TestDryRunSessionEndHooks_WithMatch(t *testing.T) {
 		"reason":          input.Reason,
 	}
 
-	err := dryRunSessionEndHooks(config, input, rawJSON)
-
-	// 標準出力を復元
-	_ = w.Close()
-	os.Stdout = oldStdout
-
-	// 出力を読み取り
-	var buf bytes.Buffer
-	_, _ = buf.ReadFrom(r)
-	output := buf.String()
+	var err error
+	output := captureStdout(t, func() {
+		err = dryRunSessionEndHooks(os.Stdout, config, input, rawJSON)
+	})
 
 	if err != nil {
-		t.Errorf("dryRunSessionEndHooks() error = %v", err)
+		t.Errorf("dryRunSessionEndHooks(os.Stdout, ) error = %v", err)
 	}
 
 	expectedStrings := []string{
 		"=== SessionEnd Hooks (Dry Run) ===",
 		"[Hook 1] Reason: clear",
-		"Command: echo clear",
+		"Command: echo 'clear'",
 		"Message: Session cleanup complete",
 	}
 
@@ -1019,11 +873,6 @@ func TestDryRunSessionEndHooks_WithMatch(t *testing.T) {
 }
 
 func TestDryRunPermissionRequestHooks_NoMatch(t *testing.T) {
-	// 標準出力をキャプチャ
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
 	config := &Config{
 		PermissionRequest: []PermissionRequestHook{
 			{Matcher: "Write", Actions: []Action{{Type: "output", Message: "test"}}},
@@ -1035,19 +884,13 @@ func TestDryRunPermissionRequestHooks_NoMatch(t *testing.T) {
 		ToolName:  "Read", // マッチしない
 	}
 
-	err := dryRunPermissionRequestHooks(config, input, nil)
-
-	// 標準出力を復元
-	_ = w.Close()
-	os.Stdout = oldStdout
-
-	// 出力を読み取り
-	var buf bytes.Buffer
-	_, _ = buf.ReadFrom(r)
-	output := buf.String()
+	var err error
+	output := captureStdout(t, func() {
+		err = dryRunPermissionRequestHooks(os.Stdout, config, input, nil)
+	})
 
 	if err != nil {
-		t.Errorf("dryRunPermissionRequestHooks() error = %v", err)
+		t.Errorf("dryRunPermissionRequestHooks(os.Stdout, ) error = %v", err)
 	}
 
 	if !strings.Contains(output, "No matching PermissionRequest hooks found") {
@@ -1056,11 +899,6 @@ func TestDryRunPermissionRequestHooks_NoMatch(t *testing.T) {
 }
 
 func TestDryRunPermissionRequestHooks_WithMatch(t *testing.T) {
-	// 標準出力をキャプチャ
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
 	config := &Config{
 		PermissionRequest: []PermissionRequestHook{
 			{
@@ -1087,25 +925,19 @@ func TestDryRunPermissionRequestHooks_WithMatch(t *testing.T) {
 		},
 	}
 
-	err := dryRunPermissionRequestHooks(config, input, rawJSON)
-
-	// 標準出力を復元
-	_ = w.Close()
-	os.Stdout = oldStdout
-
-	// 出力を読み取り
-	var buf bytes.Buffer
-	_, _ = buf.ReadFrom(r)
-	output := buf.String()
+	var err error
+	output := captureStdout(t, func() {
+		err = dryRunPermissionRequestHooks(os.Stdout, config, input, rawJSON)
+	})
 
 	if err != nil {
-		t.Errorf("dryRunPermissionRequestHooks() error = %v", err)
+		t.Errorf("dryRunPermissionRequestHooks(os.Stdout, ) error = %v", err)
 	}
 
 	expectedStrings := []string{
 		"=== PermissionRequest Hooks ===",
 		"[Hook 1] Tool: Write",
-		"Command: echo test.txt",
+		"Command: echo 'test.txt'",
 		"Message: Permission granted",
 		"Behavior: allow",
 	}
@@ -1116,3 +948,70 @@ func TestDryRunPermissionRequestHooks_WithMatch(t *testing.T) {
 		}
 	}
 }
+
+func TestDryRunHooks_SideEffects(t *testing.T) {
+	config := &Config{
+		Stop: []StopHook{
+			{Actions: []Action{{Type: "command", Command: "gofmt -w .", SideEffects: []string{"writes_files"}}}},
+			{Actions: []Action{
+				{Type: "http", URL: "https://chat.example.com/hook", SideEffects: []string{"notifications", "network"}},
+				{Type: "output", Message: "done"},
+			}},
+			{Actions: []Action{{Type: "output", Message: "no side effects"}}},
+		},
+	}
+	original := dryRunMaxSideEffects
+	t.Cleanup(func() { dryRunMaxSideEffects = original })
+
+	tests := []struct {
+		name           string
+		maxSideEffects string
+		want           []string
+		wantNot        []string
+	}{
+		{
+			name: "no limit",
+			want: []string{
+				"[Hook 1] Would execute:\n  Side effects: writes_files\n",
+				"[Hook 2] Would execute:\n  Side effects: network, notifications\n",
+				"[Hook 3] Would execute:\n  Message: no side effects\n",
+			},
+		},
+		{
+			name:           "limit",
+			maxSideEffects: "writes_files",
+			want: []string{
+				"[Hook 1] Would execute (side effects within -max-side-effects: writes_files)\n",
+				"[Hook 2] Would execute:\n  Side effects: network, notifications (beyond -max-side-effects)\n",
+				"[Hook 3] Would execute (side effects within -max-side-effects: none)\n",
+			},
+			wantNot: []string{"Command: gofmt", "no side effects"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if dryRunMaxSideEffects, err = parseMaxSideEffects(tt.maxSideEffects); err != nil {
+				t.Fatal(err)
+			}
+			var out strings.Builder
+			if err := dryRunStopHooks(&out, config, &StopInput{}, map[string]any{}); err != nil {
+				t.Fatalf("dryRunStopHooks() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("Expected output to contain %q, got: %q", want, out.String())
+				}
+			}
+			for _, notWant := range tt.wantNot {
+				if strings.Contains(out.String(), notWant) {
+					t.Errorf("Expected output not to contain %q, got: %q", notWant, out.String())
+				}
+			}
+		})
+	}
+
+	if _, err := parseMaxSideEffects("writes_files,email"); err == nil || !strings.Contains(err.Error(), `invalid side effect "email"`) {
+		t.Errorf("parseMaxSideEffects() error = %v, want an invalid side effect", err)
+	}
+}
@@ -27,6 +27,7 @@ func executeNotificationHooksJSON(config *Config, input *NotificationInput, rawJ
 	var systemMessageBuilder strings.Builder
 	hookEventName := ""
 
+	ids := hookIDs(config, Notification)
 	for i, hook := range config.Notification {
 		// Matcher check: filter by notification_type
 		if hook.Matcher != "" {
@@ -46,6 +47,8 @@ func executeNotificationHooksJSON(config *Config, input *NotificationInput, rawJ
 
 			// Filter hooks by matcher
 			if !checkNotificationMatcher(hook.Matcher, input.NotificationType) {
+				logMatcherMismatch(hook.Matcher, input.NotificationType)
+				logHook(ids[i], i, false)
 				continue
 			}
 		}
@@ -54,9 +57,10 @@ func executeNotificationHooksJSON(config *Config, input *NotificationInput, rawJ
 		shouldExecute := true
 		for _, condition := range hook.Conditions {
 			matched, err := checkNotificationCondition(condition, input)
+			logCondition(condition, matched, err)
 			if err != nil {
 				conditionErrors = append(conditionErrors,
-					fmt.Errorf("hook[Notification][%d]: %w", i, err))
+					fmt.Errorf("%s: %w", hookRef(Notification, ids[i]), err))
 				shouldExecute = false
 				break
 			}
@@ -65,12 +69,17 @@ func executeNotificationHooksJSON(config *Config, input *NotificationInput, rawJ
 				break
 			}
 		}
+		logHook(ids[i], i, shouldExecute)
 		if !shouldExecute {
 			continue
 		}
+		if !claimCooldown(Notification, ids[i], hook, hook.Cooldown, hook.CooldownScope, input.SessionID) {
+			continue
+		}
 
+		hookExecutor := executor.withMutex(hook.Mutex).withEnvFrom(hook.EnvFrom)
 		for _, action := range hook.Actions {
-			actionOutput, err := executor.ExecuteNotificationAction(action, input, rawJSON)
+			actionOutput, err := hookExecutor.ExecuteNotificationAction(action, input, rawJSON)
 			if err != nil {
 				actionErrors = append(actionErrors, fmt.Errorf("notification hook %d action failed: %w", i, err))
 				continue
@@ -168,9 +177,12 @@ func executeSubagentStartHooksJSON(config *Config, input *SubagentStartInput, ra
 	var systemMessageBuilder strings.Builder
 	hookEventName := ""
 
+	ids := hookIDs(config, SubagentStart)
 	for i, hook := range config.SubagentStart {
 		// Matcher check (agent type filter)
 		if !checkMatcher(hook.Matcher, input.AgentType) {
+			logMatcherMismatch(hook.Matcher, input.AgentType)
+			logHook(ids[i], i, false)
 			continue
 		}
 
@@ -178,9 +190,10 @@ func executeSubagentStartHooksJSON(config *Config, input *SubagentStartInput, ra
 		shouldExecute := true
 		for _, condition := range hook.Conditions {
 			matched, err := checkSubagentStartCondition(condition, input)
+			logCondition(condition, matched, err)
 			if err != nil {
 				conditionErrors = append(conditionErrors,
-					fmt.Errorf("hook[SubagentStart][%d]: %w", i, err))
+					fmt.Errorf("%s: %w", hookRef(SubagentStart, ids[i]), err))
 				shouldExecute = false
 				break
 			}
@@ -189,12 +202,17 @@ func executeSubagentStartHooksJSON(config *Config, input *SubagentStartInput, ra
 				break
 			}
 		}
+		logHook(ids[i], i, shouldExecute)
 		if !shouldExecute {
 			continue
 		}
+		if !claimCooldown(SubagentStart, ids[i], hook, hook.Cooldown, hook.CooldownScope, input.SessionID) {
+			continue
+		}
 
+		hookExecutor := executor.withMutex(hook.Mutex).withEnvFrom(hook.EnvFrom)
 		for _, action := range hook.Actions {
-			actionOutput, err := executor.ExecuteSubagentStartAction(action, input, rawJSON)
+			actionOutput, err := hookExecutor.ExecuteSubagentStartAction(action, input, rawJSON)
 			if err != nil {
 				actionErrors = append(actionErrors, fmt.Errorf("SubagentStart hook %d action failed: %w", i, err))
 				continue
@@ -291,14 +309,16 @@ func executeStopHooks(config *Config, input *StopInput, rawJSON any) (*StopOutpu
 
 	var systemMessageBuilder strings.Builder
 
+	ids := hookIDs(config, Stop)
 	for i, hook := range config.Stop {
 		// 条件チェック
 		shouldExecute := true
 		for _, condition := range hook.Conditions {
 			matched, err := checkStopCondition(condition, input)
+			logCondition(condition, matched, err)
 			if err != nil {
 				conditionErrors = append(conditionErrors,
-					fmt.Errorf("hook[Stop][%d]: %w", i, err))
+					fmt.Errorf("%s: %w", hookRef(Stop, ids[i]), err))
 				shouldExecute = false
 				break
 			}
@@ -307,12 +327,17 @@ func executeStopHooks(config *Config, input *StopInput, rawJSON any) (*StopOutpu
 				break
 			}
 		}
+		logHook(ids[i], i, shouldExecute)
 		if !shouldExecute {
 			continue
 		}
+		if !claimCooldown(Stop, ids[i], hook, hook.Cooldown, hook.CooldownScope, input.SessionID) {
+			continue
+		}
 
+		hookExecutor := executor.withMutex(hook.Mutex).withEnvFrom(hook.EnvFrom)
 		for _, action := range hook.Actions {
-			actionOutput, err := executor.ExecuteStopAction(action, input, rawJSON)
+			actionOutput, err := hookExecutor.ExecuteStopAction(action, input, rawJSON)
 			if err != nil {
 				actionErrors = append(actionErrors, fmt.Errorf("stop hook %d action failed: %w", i, err))
 				continue
@@ -397,6 +422,7 @@ func executeStopHooks(config *Config, input *StopInput, rawJSON any) (*StopOutpu
 
 // executeSubagentStopHooks executes all matching SubagentStop hooks based on condition checks.
 // Returns an error to block the subagent stop operation if any hook fails.
+// Includes matcher check on agent_type.
 func executeSubagentStopHooks(config *Config, input *SubagentStopInput, rawJSON any) (*SubagentStopOutput, error) {
 	executor := NewActionExecutor(nil)
 	var conditionErrors []error
@@ -410,14 +436,23 @@ func executeSubagentStopHooks(config *Config, input *SubagentStopInput, rawJSON
 
 	var systemMessageBuilder strings.Builder
 
+	ids := hookIDs(config, SubagentStop)
 	for i, hook := range config.SubagentStop {
+		// Matcher check (agent type filter)
+		if !checkMatcher(hook.Matcher, input.AgentType) {
+			logMatcherMismatch(hook.Matcher, input.AgentType)
+			logHook(ids[i], i, false)
+			continue
+		}
+
 		// 条件チェック
 		shouldExecute := true
 		for _, condition := range hook.Conditions {
 			matched, err := checkSubagentStopCondition(condition, input)
+			logCondition(condition, matched, err)
 			if err != nil {
 				conditionErrors = append(conditionErrors,
-					fmt.Errorf("hook[SubagentStop][%d]: %w", i, err))
+					fmt.Errorf("%s: %w", hookRef(SubagentStop, ids[i]), err))
 				shouldExecute = false
 				break
 			}
@@ -426,12 +461,17 @@ func executeSubagentStopHooks(config *Config, input *SubagentStopInput, rawJSON
 				break
 			}
 		}
+		logHook(ids[i], i, shouldExecute)
 		if !shouldExecute {
 			continue
 		}
+		if !claimCooldown(SubagentStop, ids[i], hook, hook.Cooldown, hook.CooldownScope, input.SessionID) {
+			continue
+		}
 
+		hookExecutor := executor.withMutex(hook.Mutex).withEnvFrom(hook.EnvFrom)
 		for _, action := range hook.Actions {
-			actionOutput, err := executor.ExecuteSubagentStopAction(action, input, rawJSON)
+			actionOutput, err := hookExecutor.ExecuteSubagentStopAction(action, input, rawJSON)
 			if err != nil {
 				actionErrors = append(actionErrors, fmt.Errorf("subagent stop hook %d action failed: %w", i, err))
 				continue
@@ -527,6 +567,7 @@ func executePreCompactHooksJSON(config *Config, input *PreCompactInput, rawJSON
 
 	var systemMessageBuilder strings.Builder
 
+	ids := hookIDs(config, PreCompact)
 	for i, hook := range config.PreCompact {
 		// Warn about invalid matcher values (early detection of configuration mistakes)
 		if hook.Matcher != "" && hook.Matcher != "manual" && hook.Matcher != "auto" {
@@ -535,6 +576,8 @@ func executePreCompactHooksJSON(config *Config, input *PreCompactInput, rawJSON
 
 		// マッチャーチェック (manual/auto)
 		if hook.Matcher != "" && hook.Matcher != input.Trigger {
+			logMatcherMismatch(hook.Matcher, input.Trigger)
+			logHook(ids[i], i, false)
 			continue
 		}
 
@@ -542,9 +585,10 @@ func executePreCompactHooksJSON(config *Config, input *PreCompactInput, rawJSON
 		shouldExecute := true
 		for _, condition := range hook.Conditions {
 			matched, err := checkPreCompactCondition(condition, input)
+			logCondition(condition, matched, err)
 			if err != nil {
 				conditionErrors = append(conditionErrors,
-					fmt.Errorf("hook[PreCompact][%d]: %w", i, err))
+					fmt.Errorf("%s: %w", hookRef(PreCompact, ids[i]), err))
 				shouldExecute = false
 				break
 			}
@@ -553,12 +597,17 @@ func executePreCompactHooksJSON(config *Config, input *PreCompactInput, rawJSON
 				break
 			}
 		}
+		logHook(ids[i], i, shouldExecute)
 		if !shouldExecute {
 			continue
 		}
+		if !claimCooldown(PreCompact, ids[i], hook, hook.Cooldown, hook.CooldownScope, input.SessionID) {
+			continue
+		}
 
+		hookExecutor := executor.withMutex(hook.Mutex).withEnvFrom(hook.EnvFrom)
 		for _, action := range hook.Actions {
-			actionOutput, err := executor.ExecutePreCompactAction(action, input, rawJSON)
+			actionOutput, err := hookExecutor.ExecutePreCompactAction(action, input, rawJSON)
 			if err != nil {
 				actionErrors = append(actionErrors, fmt.Errorf("pre compact hook %d action failed: %w", i, err))
 				continue
@@ -627,9 +676,12 @@ func executeSessionStartHooks(config *Config, input *SessionStartInput, rawJSON
 	var systemMessageBuilder strings.Builder
 	hookEventName := ""
 
+	ids := hookIDs(config, SessionStart)
 	for i, hook := range config.SessionStart {
 		// マッチャーチェック (startup, resume, clear)
 		if hook.Matcher != "" && hook.Matcher != input.Source {
+			logMatcherMismatch(hook.Matcher, input.Source)
+			logHook(ids[i], i, false)
 			continue
 		}
 
@@ -637,9 +689,10 @@ func executeSessionStartHooks(config *Config, input *SessionStartInput, rawJSON
 		shouldExecute := true
 		for _, condition := range hook.Conditions {
 			matched, err := checkSessionStartCondition(condition, input)
+			logCondition(condition, matched, err)
 			if err != nil {
 				conditionErrors = append(conditionErrors,
-					fmt.Errorf("hook[SessionStart][%d]: %w", i, err))
+					fmt.Errorf("%s: %w", hookRef(SessionStart, ids[i]), err))
 				shouldExecute = false
 				break
 			}
@@ -648,12 +701,17 @@ func executeSessionStartHooks(config *Config, input *SessionStartInput, rawJSON
 				break
 			}
 		}
+		logHook(ids[i], i, shouldExecute)
 		if !shouldExecute {
 			continue
 		}
+		if !claimCooldown(SessionStart, ids[i], hook, hook.Cooldown, hook.CooldownScope, input.SessionID) {
+			continue
+		}
 
+		hookExecutor := executor.withMutex(hook.Mutex).withEnvFrom(hook.EnvFrom)
 		for _, action := range hook.Actions {
-			actionOutput, err := executor.ExecuteSessionStartAction(action, input, rawJSON)
+			actionOutput, err := hookExecutor.ExecuteSessionStartAction(action, input, rawJSON)
 			if err != nil {
 				actionErrors = append(actionErrors, fmt.Errorf("SessionStart hook %d action failed: %w", i, err))
 				continue
@@ -713,6 +771,16 @@ func executeSessionStartHooks(config *Config, input *SessionStartInput, rawJSON
 		AdditionalContext: additionalContextBuilder.String(),
 	}
 
+	// self_test: 見つからないコマンドはセッションの最初に1回だけまとめて知らせる
+	if config.SelfTest {
+		if warning := runSelfTest(config, input.SessionID); warning != "" {
+			if systemMessageBuilder.Len() > 0 {
+				systemMessageBuilder.WriteString("\n")
+			}
+			systemMessageBuilder.WriteString(warning)
+		}
+	}
+
 	finalOutput.SystemMessage = systemMessageBuilder.String()
 
 	// Collect all errors
@@ -756,14 +824,16 @@ func executeUserPromptSubmitHooks(config *Config, input *UserPromptSubmitInput,
 	var systemMessageBuilder strings.Builder
 	hookEventName := ""
 
+	ids := hookIDs(config, UserPromptSubmit)
 	for i, hook := range config.UserPromptSubmit {
 		// 条件チェック
 		shouldExecute := true
 		for _, condition := range hook.Conditions {
 			matched, err := checkUserPromptSubmitCondition(condition, input)
+			logCondition(condition, matched, err)
 			if err != nil {
 				conditionErrors = append(conditionErrors,
-					fmt.Errorf("hook[UserPromptSubmit][%d]: %w", i, err))
+					fmt.Errorf("%s: %w", hookRef(UserPromptSubmit, ids[i]), err))
 				shouldExecute = false
 				break
 			}
@@ -772,12 +842,17 @@ func executeUserPromptSubmitHooks(config *Config, input *UserPromptSubmitInput,
 				break
 			}
 		}
+		logHook(ids[i], i, shouldExecute)
 		if !shouldExecute {
 			continue
 		}
+		if !claimCooldown(UserPromptSubmit, ids[i], hook, hook.Cooldown, hook.CooldownScope, input.SessionID) {
+			continue
+		}
 
+		hookExecutor := executor.withMutex(hook.Mutex).withEnvFrom(hook.EnvFrom)
 		for _, action := range hook.Actions {
-			actionOutput, err := executor.ExecuteUserPromptSubmitAction(action, input, rawJSON)
+			actionOutput, err := hookExecutor.ExecuteUserPromptSubmitAction(action, input, rawJSON)
 			if err != nil {
 				actionErrors = append(actionErrors, fmt.Errorf("UserPromptSubmit hook %d action failed: %w", i, err))
 				continue
@@ -891,14 +966,16 @@ func executeSessionEndHooksJSON(config *Config, input *SessionEndInput, rawJSON
 
 	var systemMessageBuilder strings.Builder
 
+	ids := hookIDs(config, SessionEnd)
 	for i, hook := range config.SessionEnd {
 		// 条件チェック
 		shouldExecute := true
 		for _, condition := range hook.Conditions {
 			matched, err := checkSessionEndCondition(condition, input)
+			logCondition(condition, matched, err)
 			if err != nil {
 				conditionErrors = append(conditionErrors,
-					fmt.Errorf("hook[SessionEnd][%d]: %w", i, err))
+					fmt.Errorf("%s: %w", hookRef(SessionEnd, ids[i]), err))
 				shouldExecute = false
 				break
 			}
@@ -907,12 +984,17 @@ func executeSessionEndHooksJSON(config *Config, input *SessionEndInput, rawJSON
 				break
 			}
 		}
+		logHook(ids[i], i, shouldExecute)
 		if !shouldExecute {
 			continue
 		}
+		if !claimCooldown(SessionEnd, ids[i], hook, hook.Cooldown, hook.CooldownScope, input.SessionID) {
+			continue
+		}
 
+		hookExecutor := executor.withMutex(hook.Mutex).withEnvFrom(hook.EnvFrom)
 		for _, action := range hook.Actions {
-			actionOutput, err := executor.ExecuteSessionEndAction(action, input, rawJSON)
+			actionOutput, err := hookExecutor.ExecuteSessionEndAction(action, input, rawJSON)
 			if err != nil {
 				actionErrors = append(actionErrors, fmt.Errorf("session end hook %d action failed: %w", i, err))
 				continue
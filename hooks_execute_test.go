@@ -466,9 +466,9 @@ func TestExecuteUserPromptSubmitHooks(t *testing.T) {
 	}
 }
 
-// TestExecuteStopAndSubagentStopHook_FailingCommandReturnsExit2 tests that failing commands
+// TestExecuteStopAndSubagentStopHook_FailingCommandReturnsBlock tests that failing commands
 // result in decision="block" (fail-safe) from executeStopHooks and executeSubagentStopHooks.
-func TestExecuteStopAndSubagentStopHook_FailingCommandReturnsExit2(t *testing.T) {
+func TestExecuteStopAndSubagentStopHook_FailingCommandReturnsBlock(t *testing.T) {
 	tests := []struct {
 		name      string
 		eventType string // "Stop" or "SubagentStop"
@@ -560,78 +560,9 @@ func TestExecuteStopAndSubagentStopHook_FailingCommandReturnsExit2(t *testing.T)
 // TestExecuteNotification removed - old implementation used executeNotification,
 // new implementation uses executeNotificationHooksJSON. See TestExecuteNotificationHooksJSON in hooks_execute_test.go.
 
-func TestExecuteStopAndSubagentStopHooks(t *testing.T) {
-	tests := []struct {
-		name      string
-		eventType string // "Stop" or "SubagentStop"
-	}{
-		{
-			name:      "Stop: empty config returns allow",
-			eventType: "Stop",
-		},
-		{
-			name:      "SubagentStop: empty config returns allow",
-			eventType: "SubagentStop",
-		},
-	}
+// TestExecuteStopAndSubagentStopHooks removed - merged into TestExecuteStopAndSubagentStopHooksJSON ("No hooks configured" cases)
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			config := &Config{}
-			var decision string
-			var continueValue bool
-
-			if tt.eventType == "Stop" {
-				input := &StopInput{}
-				output, err := executeStopHooks(config, input, nil)
-				if err != nil {
-					t.Errorf("%s error = %v, expected nil", tt.eventType, err)
-				}
-				if output == nil {
-					t.Fatal("Expected output, got nil")
-				}
-				decision = output.Decision
-				continueValue = output.Continue
-			} else {
-				input := &SubagentStopInput{}
-				output, err := executeSubagentStopHooks(config, input, nil)
-				if err != nil {
-					t.Errorf("%s error = %v, expected nil", tt.eventType, err)
-				}
-				if output == nil {
-					t.Fatal("Expected output, got nil")
-				}
-				decision = output.Decision
-				continueValue = output.Continue
-			}
-
-			// JSON対応後は、空のconfigでもOutputを返す（decision=""でallow）
-			if decision != "" {
-				t.Errorf("Expected decision empty (allow), got %q", decision)
-			}
-
-			if continueValue != true {
-				t.Errorf("Expected continue true, got %v", continueValue)
-			}
-		})
-	}
-}
-
-func TestExecutePreCompactHooks(t *testing.T) {
-	config := &Config{}
-	input := &PreCompactInput{}
-
-	output, err := executePreCompactHooksJSON(config, input, nil)
-	if err != nil {
-		t.Errorf("executePreCompactHooksJSON() error = %v, expected nil", err)
-	}
-	if output == nil {
-		t.Fatal("executePreCompactHooksJSON() returned nil output")
-	}
-	if !output.Continue {
-		t.Error("executePreCompactHooksJSON() Continue = false, expected true")
-	}
-}
+// TestExecutePreCompactHooks removed - merged into TestExecutePreCompactHooksJSON ("No hooks configured" case)
 
 func TestExecutePreCompactHooksJSON(t *testing.T) {
 	tests := []struct {
@@ -1508,7 +1439,7 @@ func TestExecuteUserPromptSubmitHooks_MultipleConditionErrors(t *testing.T) {
 	}
 
 	// Should contain second condition error (transcript file error)
-	if !strings.Contains(errMsg, "transcript") || !strings.Contains(errMsg, "hook[UserPromptSubmit][1]") {
+	if !strings.Contains(errMsg, "transcript") || !strings.Contains(errMsg, hookRef(UserPromptSubmit, hookIDs(config, UserPromptSubmit)[1])) {
 		t.Errorf("Expected second condition error about transcript in error message, got: %s", errMsg)
 	}
 
@@ -2570,3 +2501,22 @@ func TestExecuteSubagentStartHooksJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestExecuteSubagentStopHooks_Matcher(t *testing.T) {
+	config := &Config{
+		SubagentStop: []SubagentStopHook{
+			{Matcher: "Plan", Actions: []Action{{Type: "output", Message: "plan finished"}}},
+			{Matcher: "Explore|general-purpose", Actions: []Action{{Type: "output", Message: "explore finished"}}},
+			{Actions: []Action{{Type: "output", Message: "any agent finished"}}},
+		},
+	}
+	input := &SubagentStopInput{BaseInput: BaseInput{SessionID: "test", HookEventName: SubagentStop}, AgentType: "Explore"}
+
+	output, err := executeSubagentStopHooks(config, input, map[string]any{})
+	if err != nil {
+		t.Fatalf("executeSubagentStopHooks() error = %v", err)
+	}
+	if output.SystemMessage != "explore finished\nany agent finished" {
+		t.Errorf("SystemMessage = %q, want the Explore and unfiltered hooks only", output.SystemMessage)
+	}
+}
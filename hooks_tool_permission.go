@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 )
 
 // RunPermissionRequestHooks runs PermissionRequest hooks and outputs JSON
@@ -27,9 +28,8 @@ func RunPermissionRequestHooks(config *Config) error {
 			},
 			SystemMessage: errMsg,
 		}
-		jsonOutput, _ := json.Marshal(output)
-		fmt.Println(string(jsonOutput))
-		return nil // Always exit 0
+		jsonOutput, _ := marshalOutput(config.OutputFormat, output)
+		return writeHookOutput(os.Stdout, PermissionRequest, jsonOutput) // Always exit 0 with JSON output
 	}
 
 	// Execute hooks
@@ -40,7 +40,7 @@ func RunPermissionRequestHooks(config *Config) error {
 	}
 
 	// Output JSON
-	jsonOutput, err := json.Marshal(output)
+	jsonOutput, err := marshalOutput(config.OutputFormat, output)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: failed to marshal output: %v\n", err)
 		// Fail-safe: output deny decision
@@ -56,11 +56,10 @@ func RunPermissionRequestHooks(config *Config) error {
 			},
 			SystemMessage: errMsg,
 		}
-		jsonOutput, _ = json.Marshal(fallbackOutput)
+		jsonOutput, _ = marshalOutput(config.OutputFormat, fallbackOutput)
 	}
 
-	fmt.Println(string(jsonOutput))
-	return nil // Always exit 0
+	return writeHookOutput(os.Stdout, PermissionRequest, jsonOutput) // Always exit 0 with JSON output
 }
 
 // executePreToolUseHooksJSON executes all matching PreToolUse hooks and returns JSON output.
@@ -84,6 +83,7 @@ func executePreToolUseHooksJSON(config *Config, input *PreToolUseInput, rawJSON
 	stopReason := ""
 	suppressOutput := false
 
+	ids := hookIDs(config, PreToolUse)
 	for i, hook := range config.PreToolUse {
 		// Matcher and condition checks
 		shouldExecute, err := shouldExecutePreToolUseHook(hook, input)
@@ -101,13 +101,17 @@ func executePreToolUseHooksJSON(config *Config, input *PreToolUseInput, rawJSON
 				break // denyを確定させるため、以降のフックを処理しない
 			}
 			conditionErrors = append(conditionErrors,
-				fmt.Errorf("hook[PreToolUse][%d]: %w", i, err))
+				fmt.Errorf("%s: %w", hookRef(PreToolUse, ids[i]), err))
 			continue // Skip this hook but continue checking others
 		}
 
+		logHook(ids[i], i, shouldExecute)
 		if !shouldExecute {
 			continue
 		}
+		if !claimCooldown(PreToolUse, ids[i], hook, hook.Cooldown, hook.CooldownScope, input.SessionID) {
+			continue
+		}
 
 		// Execute hook actions
 		actionOutput, err := executePreToolUseHook(executor, hook, input, rawJSON)
@@ -244,13 +248,15 @@ func executePreToolUseHooksJSON(config *Config, input *PreToolUseInput, rawJSON
 // shouldExecutePreToolUseHook checks if a PreToolUse hook should be executed based on matcher and conditions.
 func shouldExecutePreToolUseHook(hook PreToolUseHook, input *PreToolUseInput) (bool, error) {
 	// マッチャーチェック
-	if !checkMatcher(hook.Matcher, input.ToolName) {
+	if !checkToolMatcher(hook.Matcher, hook.ExcludeTools, hook.MCPServer, input.ToolName) {
+		logMatcherMismatch(hook.Matcher, input.ToolName)
 		return false, nil
 	}
 
 	// 条件チェック
 	for _, condition := range hook.Conditions {
 		matched, err := checkPreToolUseCondition(condition, input)
+		logCondition(condition, matched, err)
 		if err != nil {
 			// プロセス置換検出の場合は条件マッチとして扱う
 			if errors.Is(err, ErrProcessSubstitutionDetected) {
@@ -269,6 +275,7 @@ func shouldExecutePreToolUseHook(hook PreToolUseHook, input *PreToolUseInput) (b
 // executePreToolUseHook executes all actions for a single PreToolUse hook and returns JSON output.
 // This function implements Phase 3 JSON output functionality for PreToolUse hooks.
 func executePreToolUseHook(executor *ActionExecutor, hook PreToolUseHook, input *PreToolUseInput, rawJSON any) (*ActionOutput, error) {
+	executor = executor.withMutex(hook.Mutex).withEnvFrom(hook.EnvFrom)
 	// Initialize output with Continue: true (always true for PreToolUse)
 	// permissionDecision starts empty and will be set by actions or remain empty to delegate
 	output := &ActionOutput{
@@ -385,10 +392,14 @@ func executePostToolUseHooksJSON(config *Config, input *PostToolUseInput, rawJSO
 	var systemMessageBuilder strings.Builder
 	var additionalContextBuilder strings.Builder
 	var hookEventName string
+	fileChangeNotified := false // notify_model_on_file_changeの通知は1イベントにつき1回
 
+	ids := hookIDs(config, PostToolUse)
 	for i, hook := range config.PostToolUse {
 		// マッチャーチェック
-		if !checkMatcher(hook.Matcher, input.ToolName) {
+		if !checkToolMatcher(hook.Matcher, hook.ExcludeTools, hook.MCPServer, input.ToolName) {
+			logMatcherMismatch(hook.Matcher, input.ToolName)
+			logHook(ids[i], i, false)
 			continue
 		}
 
@@ -396,6 +407,7 @@ func executePostToolUseHooksJSON(config *Config, input *PostToolUseInput, rawJSO
 		shouldExecute := true
 		for _, condition := range hook.Conditions {
 			matched, err := checkPostToolUseCondition(condition, input)
+			logCondition(condition, matched, err)
 			if err != nil {
 				// プロセス置換検出の場合は警告をstderrに出力してフック継続
 				if errors.Is(err, ErrProcessSubstitutionDetected) {
@@ -405,7 +417,7 @@ func executePostToolUseHooksJSON(config *Config, input *PostToolUseInput, rawJSO
 					break
 				}
 				conditionErrors = append(conditionErrors,
-					fmt.Errorf("hook[PostToolUse][%d]: %w", i, err))
+					fmt.Errorf("%s: %w", hookRef(PostToolUse, ids[i]), err))
 				shouldExecute = false
 				break
 			}
@@ -414,12 +426,34 @@ func executePostToolUseHooksJSON(config *Config, input *PostToolUseInput, rawJSO
 				break
 			}
 		}
+		logHook(ids[i], i, shouldExecute)
 		if !shouldExecute {
 			continue
 		}
+		if !claimCooldown(PostToolUse, ids[i], hook, hook.Cooldown, hook.CooldownScope, input.SessionID) {
+			continue
+		}
 
+		// batchフックはイベントを溜めるだけで、アクションはバックグラウンドのフラッシャーがまとめて実行する
+		if hook.Batch {
+			if err := enqueueBatchEvent(i+1, hook, input, rawJSON); err != nil {
+				actionErrors = append(actionErrors, fmt.Errorf("PostToolUse hook %d batch failed: %w", i, err))
+			}
+			continue
+		}
+
+		// アクションの前後でfile_pathの内容を比べ、書き換えられていたらセッションに記録し、
+		// notify_model_on_file_changeならClaudeに読み直すよう伝える
+		filePath := resolveToolFilePath(input.ToolInput.FilePath, input.Cwd)
+		var hashBefore [32]byte
+		var existedBefore bool
+		if filePath != "" {
+			hashBefore, existedBefore = fileContentHash(filePath)
+		}
+
+		hookExecutor := executor.withMutex(hook.Mutex).withEnvFrom(hook.EnvFrom).withSnapshot(hook.Snapshot)
 		for _, action := range hook.Actions {
-			actionOutput, err := executor.ExecutePostToolUseAction(action, input, rawJSON)
+			actionOutput, err := hookExecutor.ExecutePostToolUseAction(action, input, rawJSON)
 			if err != nil {
 				actionErrors = append(actionErrors, fmt.Errorf("PostToolUse hook %d action failed: %w", i, err))
 				continue
@@ -480,6 +514,35 @@ func executePostToolUseHooksJSON(config *Config, input *PostToolUseInput, rawJSO
 
 		}
 
+		if existedBefore {
+			if hashAfter, exists := fileContentHash(filePath); !exists || hashAfter != hashBefore {
+				change := hookChange{Time: time.Now(), File: filePath, Event: string(PostToolUse), Hook: i + 1, Commands: hookCommands(hook.Actions)}
+				if err := recordHookChange(input.SessionID, change); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				}
+				if hook.NotifyModelOnFileChange && !fileChangeNotified {
+					fileChangeNotified = true
+					if additionalContextBuilder.Len() > 0 {
+						additionalContextBuilder.WriteString("\n")
+					}
+					additionalContextBuilder.WriteString(fileChangedNotice(input.ToolInput.FilePath, exists))
+					if hookEventName == "" {
+						hookEventName = string(PostToolUse)
+					}
+				}
+			}
+		}
+	}
+
+	// 前回までのイベントで溜めたbatchフックの結果を届ける
+	for _, message := range takeBatchResults(input.SessionID) {
+		if additionalContextBuilder.Len() > 0 {
+			additionalContextBuilder.WriteString("\n")
+		}
+		additionalContextBuilder.WriteString(message)
+		if hookEventName == "" {
+			hookEventName = string(PostToolUse)
+		}
 	}
 
 	finalOutput.SystemMessage = systemMessageBuilder.String()
@@ -530,16 +593,27 @@ func executePostToolUseHooksJSON(config *Config, input *PostToolUseInput, rawJSO
 	return finalOutput, nil
 }
 
+// fileChangedNotice is the additionalContext telling Claude that a hook rewrote (or removed) the file it just edited,
+// so that it reads the file again instead of editing from a stale view.
+func fileChangedNotice(filePath string, exists bool) string {
+	if !exists {
+		return fmt.Sprintf("%s was removed by a PostToolUse hook.", filePath)
+	}
+	return fmt.Sprintf("%s was modified on disk by a PostToolUse hook (e.g. a formatter). Read it again before editing it further.", filePath)
+}
+
 // shouldExecutePostToolUseHook checks if a PostToolUse hook should be executed based on matcher and conditions.
 func shouldExecutePostToolUseHook(hook PostToolUseHook, input *PostToolUseInput) (bool, error) {
 	// マッチャーチェック
-	if !checkMatcher(hook.Matcher, input.ToolName) {
+	if !checkToolMatcher(hook.Matcher, hook.ExcludeTools, hook.MCPServer, input.ToolName) {
+		logMatcherMismatch(hook.Matcher, input.ToolName)
 		return false, nil
 	}
 
 	// 条件チェック
 	for _, condition := range hook.Conditions {
 		matched, err := checkPostToolUseCondition(condition, input)
+		logCondition(condition, matched, err)
 		if err != nil {
 			// プロセス置換検出の場合は条件マッチとして扱う
 			if errors.Is(err, ErrProcessSubstitutionDetected) {
@@ -576,18 +650,23 @@ func executePermissionRequestHooksJSON(config *Config, input *PermissionRequestI
 	suppressOutput := false
 	matchedAny := false // Track if any hook matched
 
+	ids := hookIDs(config, PermissionRequest)
 	for i, hook := range config.PermissionRequest {
 		// Matcher and condition checks
 		shouldExecute, err := shouldExecutePermissionRequestHook(hook, input)
 		if err != nil {
 			conditionErrors = append(conditionErrors,
-				fmt.Errorf("hook[PermissionRequest][%d]: %w", i, err))
+				fmt.Errorf("%s: %w", hookRef(PermissionRequest, ids[i]), err))
 			continue // Skip this hook but continue checking others
 		}
 
+		logHook(ids[i], i, shouldExecute)
 		if !shouldExecute {
 			continue
 		}
+		if !claimCooldown(PermissionRequest, ids[i], hook, hook.Cooldown, hook.CooldownScope, input.SessionID) {
+			continue
+		}
 
 		matchedAny = true // Mark that at least one hook matched
 
@@ -639,6 +718,10 @@ func executePermissionRequestHooksJSON(config *Config, input *PermissionRequestI
 			case "deny":
 				// deny時: updatedInputをクリア (公式仕様: deny時はupdatedInput不可)
 				updatedInput = nil
+			case "ask":
+				// ask時: ユーザーが判断するのでupdatedInput/interruptは使わない
+				updatedInput = nil
+				interrupt = false
 			}
 		}
 
@@ -713,9 +796,13 @@ func executePermissionRequestHooksJSON(config *Config, input *PermissionRequestI
 		finalOutput.Continue = true
 	}
 
-	// Validate final output against JSON schema
+	if behavior == "ask" {
+		finalizePermissionRequestAsk(finalOutput)
+	}
+
+	// Validate final output against JSON schema (ask has no decision to validate)
 	finalOutputJSON, err := json.Marshal(finalOutput)
-	if err == nil {
+	if err == nil && finalOutput.HookSpecificOutput != nil {
 		if validationErr := validatePermissionRequestOutput(finalOutputJSON); validationErr != nil {
 			fmt.Fprintf(os.Stderr, "Warning: Final output validation failed: %v\n", validationErr)
 		}
@@ -732,8 +819,24 @@ func executePermissionRequestHooksJSON(config *Config, input *PermissionRequestI
 	return finalOutput, nil
 }
 
+// finalizePermissionRequestAsk turns an "ask" decision into what Claude Code understands.
+// PermissionRequest has no ask: without a decision Claude Code shows its usual permission dialog,
+// so the decision is left out and its message is shown to the user in systemMessage instead.
+func finalizePermissionRequestAsk(output *PermissionRequestOutput) {
+	message := output.HookSpecificOutput.Decision.Message
+	output.HookSpecificOutput = nil
+	if message == "" {
+		return
+	}
+	if output.SystemMessage != "" {
+		message += "\n" + output.SystemMessage
+	}
+	output.SystemMessage = message
+}
+
 // executePermissionRequestHook executes all actions in a single hook and merges their outputs
 func executePermissionRequestHook(executor *ActionExecutor, hook PermissionRequestHook, input *PermissionRequestInput, rawJSON any) (*ActionOutput, error) {
+	executor = executor.withMutex(hook.Mutex).withEnvFrom(hook.EnvFrom)
 	var mergedOutput *ActionOutput
 
 	for _, action := range hook.Actions {
@@ -789,6 +892,10 @@ func executePermissionRequestHook(executor *ActionExecutor, hook PermissionReque
 				// Note: systemMessageはトップレベルのフィールドでdecisionとは独立なので残す
 				mergedOutput.Message = ""
 				mergedOutput.Interrupt = false
+			case "ask":
+				// ask時: ユーザーが判断するのでupdatedInput/interruptは使わない
+				mergedOutput.UpdatedInput = nil
+				mergedOutput.Interrupt = false
 			}
 		}
 
@@ -826,23 +933,15 @@ func executePermissionRequestHook(executor *ActionExecutor, hook PermissionReque
 // shouldExecutePermissionRequestHook checks if a hook should be executed based on matcher and conditions
 func shouldExecutePermissionRequestHook(hook PermissionRequestHook, input *PermissionRequestInput) (bool, error) {
 	// Check matcher (tool name partial match)
-	if hook.Matcher != "" {
-		matchers := strings.Split(hook.Matcher, "|")
-		matched := false
-		for _, m := range matchers {
-			if strings.Contains(input.ToolName, strings.TrimSpace(m)) {
-				matched = true
-				break
-			}
-		}
-		if !matched {
-			return false, nil
-		}
+	if !checkToolMatcher(hook.Matcher, hook.ExcludeTools, hook.MCPServer, input.ToolName) {
+		logMatcherMismatch(hook.Matcher, input.ToolName)
+		return false, nil
 	}
 
 	// Check conditions
 	for _, condition := range hook.Conditions {
 		matched, err := checkPermissionRequestCondition(condition, input)
+		logCondition(condition, matched, err)
 		if err != nil {
 			return false, fmt.Errorf("condition check failed: %w", err)
 		}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -32,6 +33,20 @@ func TestShouldExecutePreToolUseHook(t *testing.T) {
 			false,
 			false,
 		},
+		{
+			"Wildcard matcher",
+			PreToolUseHook{Matcher: "*", ExcludeTools: []string{"Read", "Glob", "Grep"}},
+			&PreToolUseInput{ToolName: "Bash"},
+			true,
+			false,
+		},
+		{
+			"Wildcard matcher with excluded tool",
+			PreToolUseHook{Matcher: "*", ExcludeTools: []string{"Read", "Glob", "Grep"}},
+			&PreToolUseInput{ToolName: "Glob"},
+			false,
+			false,
+		},
 		{
 			"Match with satisfied condition",
 			PreToolUseHook{
@@ -268,11 +283,12 @@ func TestExecutePostToolUseHooks_ConditionErrorAggregation(t *testing.T) {
 
 	// 複数のエラーが集約されていることを確認
 	errMsg := err.Error()
-	if !strings.Contains(errMsg, "hook[PostToolUse][0]") {
+	ids := hookIDs(config, PostToolUse)
+	if !strings.Contains(errMsg, "hook[PostToolUse][id="+ids[0]+"]") {
 		t.Errorf("Expected error message to contain first hook error, got: %q", errMsg)
 	}
 	// 2件目のフックのエラーも含まれることを確認
-	if !strings.Contains(errMsg, "hook[PostToolUse][1]") {
+	if !strings.Contains(errMsg, "hook[PostToolUse][id="+ids[1]+"]") {
 		t.Errorf("Expected error message to contain second hook error, got: %q", errMsg)
 	}
 	if !strings.Contains(errMsg, "unknown condition type") {
@@ -1493,3 +1509,126 @@ func TestExecutePermissionRequestHooks_DenyEarlyReturn(t *testing.T) {
 		t.Errorf("Message should NOT contain second action message (early return), got %q", output.Message)
 	}
 }
+
+func TestExecutePermissionRequestHooks_Ask(t *testing.T) {
+	input := &PermissionRequestInput{ToolName: "Bash", ToolInput: ToolInput{Command: "rm -rf build"}}
+	rawJSON := map[string]any{"tool_name": "Bash", "tool_input": map[string]any{"command": "rm -rf build"}}
+
+	t.Run("ask leaves the decision to the user", func(t *testing.T) {
+		config := &Config{PermissionRequest: []PermissionRequestHook{
+			{Matcher: "Bash", Actions: []Action{{Type: "output", Behavior: stringPtr("allow"), UpdatedInput: map[string]any{"command": "ls"}}}},
+			{Matcher: "Bash", Actions: []Action{{Type: "output", Behavior: stringPtr("ask"), Message: "{.tool_input.command} deletes files", SystemMessage: stringPtr("Checked by cchook")}}},
+		}}
+		output, err := executePermissionRequestHooksJSON(config, input, rawJSON)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if output.HookSpecificOutput != nil {
+			t.Errorf("HookSpecificOutput = %+v, want none", output.HookSpecificOutput)
+		}
+		if want := "rm -rf build deletes files\nChecked by cchook"; output.SystemMessage != want {
+			t.Errorf("SystemMessage = %q, want %q", output.SystemMessage, want)
+		}
+		jsonOutput, err := json.Marshal(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := `{"continue":true,"systemMessage":"rm -rf build deletes files\nChecked by cchook"}`; string(jsonOutput) != want {
+			t.Errorf("output = %s, want %s", jsonOutput, want)
+		}
+	})
+
+	t.Run("a later deny wins over ask", func(t *testing.T) {
+		config := &Config{PermissionRequest: []PermissionRequestHook{
+			{Matcher: "Bash", Actions: []Action{{Type: "output", Behavior: stringPtr("ask"), Message: "Confirm"}}},
+			{Matcher: "Bash", Actions: []Action{{Type: "output", Behavior: stringPtr("deny"), Message: "Blocked"}}},
+		}}
+		output, err := executePermissionRequestHooksJSON(config, input, rawJSON)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if output.HookSpecificOutput == nil || output.HookSpecificOutput.Decision.Behavior != "deny" {
+			t.Fatalf("output = %+v, want deny", output)
+		}
+	})
+}
+
+func TestExecutePostToolUseHooks_NotifyModelOnFileChange(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+
+	tests := []struct {
+		name        string
+		hooks       []PostToolUseHook
+		wantContext string
+	}{
+		{
+			name: "formatter changed the file",
+			hooks: []PostToolUseHook{{
+				Matcher:                 "Write",
+				NotifyModelOnFileChange: true,
+				Actions:                 []Action{{Type: "command", Command: "printf 'formatted\\n' > {.tool_input.file_path}"}},
+			}},
+			wantContext: "main.go was modified on disk by a PostToolUse hook (e.g. a formatter). Read it again before editing it further.",
+		},
+		{
+			name: "file unchanged",
+			hooks: []PostToolUseHook{{
+				Matcher:                 "Write",
+				NotifyModelOnFileChange: true,
+				Actions:                 []Action{{Type: "command", Command: "cat {.tool_input.file_path} > /dev/null"}},
+			}},
+		},
+		{
+			name: "disabled",
+			hooks: []PostToolUseHook{{
+				Matcher: "Write",
+				Actions: []Action{{Type: "command", Command: "printf 'formatted\\n' > {.tool_input.file_path}"}},
+			}},
+		},
+		{
+			name: "notified once per event",
+			hooks: []PostToolUseHook{
+				{
+					Matcher:                 "Write",
+					NotifyModelOnFileChange: true,
+					Actions:                 []Action{{Type: "command", Command: "printf 'formatted\\n' > {.tool_input.file_path}"}},
+				},
+				{
+					Matcher:                 "Write",
+					NotifyModelOnFileChange: true,
+					Actions:                 []Action{{Type: "command", Command: "rm {.tool_input.file_path}"}},
+				},
+			},
+			wantContext: "main.go was modified on disk by a PostToolUse hook (e.g. a formatter). Read it again before editing it further.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(path, []byte("original\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			input := &PostToolUseInput{
+				BaseInput: BaseInput{Cwd: dir},
+				ToolName:  "Write",
+				ToolInput: ToolInput{FilePath: "main.go"},
+			}
+			rawJSON := map[string]any{"cwd": dir, "tool_name": "Write", "tool_input": map[string]any{"file_path": path}}
+
+			output, err := executePostToolUseHooksJSON(&Config{PostToolUse: tt.hooks}, input, rawJSON)
+			if err != nil {
+				t.Fatalf("executePostToolUseHooksJSON() error = %v", err)
+			}
+			var gotContext string
+			if output.HookSpecificOutput != nil {
+				gotContext = output.HookSpecificOutput.AdditionalContext
+				if output.HookSpecificOutput.HookEventName != "PostToolUse" {
+					t.Errorf("hookEventName = %q, want PostToolUse", output.HookSpecificOutput.HookEventName)
+				}
+			}
+			if gotContext != tt.wantContext {
+				t.Errorf("additionalContext = %q, want %q", gotContext, tt.wantContext)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultHTTPTimeout is the timeout of an http action without `timeout`.
+const defaultHTTPTimeout = 10 * time.Second
+
+// maxHTTPResponseSize is the upper bound of the response body read from an http action.
+const maxHTTPResponseSize = 1024 * 1024
+
+// httpActionMethods are the methods accepted by http actions.
+var httpActionMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// DefaultHTTPClient is the client used by http actions in production.
+var DefaultHTTPClient HTTPClient = http.DefaultClient
+
+// httpActionMethod returns the method of an http action (POST when omitted).
+func httpActionMethod(action Action) string {
+	if action.Method == "" {
+		return http.MethodPost
+	}
+	return strings.ToUpper(action.Method)
+}
+
+// httpActionTimeout returns the timeout of an http action.
+func httpActionTimeout(action Action) (time.Duration, error) {
+	if action.Timeout == "" {
+		return defaultHTTPTimeout, nil
+	}
+	timeout, err := time.ParseDuration(action.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %q: %w", action.Timeout, err)
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("invalid timeout %q: must be positive", action.Timeout)
+	}
+	return timeout, nil
+}
+
+// httpActionBody returns the templated request body of an http action.
+// A string body is sent as-is after template expansion; any other YAML value
+// (mapping, list, ...) is encoded as JSON, so that substituted values are escaped properly.
+func httpActionBody(action Action, rawJSON any) ([]byte, error) {
+	switch body := action.Body.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []byte(unifiedTemplateReplace(body, rawJSON)), nil
+	default:
+		return json.Marshal(templateValue(body, rawJSON))
+	}
+}
+
+// newHTTPActionRequest builds the request of an http action.
+// Environment variables ($VAR / ${VAR}) in url and headers are expanded before templates,
+// so that tokens can be kept out of the config file without exposing the environment to event data.
+func newHTTPActionRequest(ctx context.Context, action Action, rawJSON any) (*http.Request, error) {
+	body, err := httpActionBody(action, rawJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode body: %w", err)
+	}
+	return newHTTPRequest(ctx, action, rawJSON, body)
+}
+
+// newHTTPRequest builds a request to the url of action with its method and headers, and body as a JSON body.
+func newHTTPRequest(ctx context.Context, action Action, rawJSON any, body []byte) (*http.Request, error) {
+	rawURL := unifiedTemplateReplace(os.ExpandEnv(action.URL), rawJSON)
+	method := httpActionMethod(action)
+	if !slices.Contains(httpActionMethods, method) {
+		return nil, fmt.Errorf("invalid method %q", action.Method)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range action.Headers {
+		req.Header.Set(name, unifiedTemplateReplace(os.ExpandEnv(value), rawJSON))
+	}
+	return req, nil
+}
+
+// runHTTPAction sends the request of an http action and reports the result like a command:
+// a JSON response body is returned as stdout (hook output), other bodies are ignored (e.g. Slack's "ok"),
+// and non-2xx responses or request errors are failures, so that the event's fail-safe decision applies.
+func runHTTPAction(client HTTPClient, action Action, rawJSON any) (stdout, stderr string, exitCode int, err error) {
+	timeout, err := httpActionTimeout(action)
+	if err != nil {
+		return "", "", 1, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := newHTTPActionRequest(ctx, action, rawJSON)
+	if err != nil {
+		return "", "", 1, err
+	}
+	return doHTTPRequest(client, req)
+}
+
+// doHTTPRequest sends req and reports the result like runHTTPAction.
+func doHTTPRequest(client HTTPClient, req *http.Request) (stdout, stderr string, exitCode int, err error) {
+	// webhookのURLはパスやクエリに秘密を含むことが多いので、エラーにはホストまでしか出さない
+	target := req.URL.Scheme + "://" + req.URL.Host
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return "", "", 1, fmt.Errorf("%s %s: %w", req.Method, target, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPResponseSize))
+	if err != nil {
+		return "", "", 1, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		stderr = fmt.Sprintf("%s %s returned %s", req.Method, target, resp.Status)
+		if text := strings.TrimSpace(string(body)); text != "" {
+			stderr += ": " + text
+		}
+		return "", stderr, 1, nil
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil &&
+		(mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")) {
+		return string(body), "", 0, nil
+	}
+	return "", "", 0, nil
+}
+
+// actionFailureMessage describes a failed command, http or opa action.
+func actionFailureMessage(action Action, exitCode int, stderr string, err error) string {
+	if action.Type == "opa" {
+		// print()の出力はエラーの原因を調べる手がかりになる
+		if printed := strings.TrimSpace(stderr); printed != "" {
+			return fmt.Sprintf("Policy evaluation failed: %v\n%s", err, printed)
+		}
+		return fmt.Sprintf("Policy evaluation failed: %v", err)
+	}
+	if action.Type == "http" {
+		if strings.TrimSpace(stderr) == "" && err != nil {
+			return fmt.Sprintf("HTTP request failed: %v", err)
+		}
+		return fmt.Sprintf("HTTP request failed: %s", stderr)
+	}
+	if strings.TrimSpace(stderr) == "" && err != nil {
+		return fmt.Sprintf("Command failed with exit code %d: %v", exitCode, err)
+	}
+	return fmt.Sprintf("Command failed with exit code %d: %s", exitCode, stderr)
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunHTTPAction_Request(t *testing.T) {
+	t.Setenv("CCHOOK_TEST_TOKEN", "secret-token")
+
+	var gotMethod, gotPath, gotAuth, gotContentType string
+	var gotBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod, gotPath = r.Method, r.URL.Path
+		gotAuth, gotContentType = r.Header.Get("Authorization"), r.Header.Get("Content-Type")
+		data, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(data, &gotBody)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	action := Action{
+		Type:    "http",
+		URL:     server.URL + "/hooks/{.session_id}",
+		Headers: map[string]string{"Authorization": "Bearer ${CCHOOK_TEST_TOKEN}"},
+		Body: map[string]any{
+			"text":   `Session {.session_id} stopped: "{.stop_reason}"`,
+			"fields": []any{"{.cwd}", 1},
+		},
+	}
+	rawJSON := map[string]any{"session_id": "abc", "cwd": "/work", "stop_reason": `said "done"`}
+
+	stdout, stderr, exitCode, err := runHTTPAction(http.DefaultClient, action, rawJSON)
+	if err != nil || exitCode != 0 || stdout != "" || stderr != "" {
+		t.Fatalf("runHTTPAction() = %q, %q, %d, %v; want success without output", stdout, stderr, exitCode, err)
+	}
+	if gotMethod != http.MethodPost || gotPath != "/hooks/abc" {
+		t.Errorf("Request = %s %s, want POST /hooks/abc", gotMethod, gotPath)
+	}
+	if gotAuth != "Bearer secret-token" {
+		t.Errorf("Authorization = %q, want env expanded", gotAuth)
+	}
+	if gotContentType != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", gotContentType)
+	}
+	if gotBody["text"] != `Session abc stopped: "said "done""` {
+		t.Errorf("Body text = %v", gotBody["text"])
+	}
+	if fields, ok := gotBody["fields"].([]any); !ok || len(fields) != 2 || fields[0] != "/work" || fields[1] != float64(1) {
+		t.Errorf("Body fields = %v", gotBody["fields"])
+	}
+}
+
+func TestRunHTTPAction_Response(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		timeout     string
+		delay       time.Duration
+		wantStdout  string
+		wantFailure string // substring of the failure message, "" for success
+	}{
+		{
+			name:        "JSON response is hook output",
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body:        `{"continue":true,"decision":"block","reason":"from webhook"}`,
+			wantStdout:  `{"continue":true,"decision":"block","reason":"from webhook"}`,
+		},
+		{
+			name:        "non-JSON response is ignored",
+			status:      http.StatusOK,
+			contentType: "text/html",
+			body:        "ok",
+		},
+		{
+			name:   "no content",
+			status: http.StatusNoContent,
+		},
+		{
+			name:        "non-2xx response fails",
+			status:      http.StatusServiceUnavailable,
+			contentType: "text/plain",
+			body:        "maintenance",
+			wantFailure: "HTTP request failed: POST http://127.0.0.1",
+		},
+		{
+			name:        "timeout fails",
+			status:      http.StatusOK,
+			timeout:     "50ms",
+			delay:       time.Second,
+			wantFailure: "context deadline exceeded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-time.After(tt.delay):
+				case <-r.Context().Done():
+					return
+				}
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			action := Action{Type: "http", URL: server.URL + "/secret-path", Timeout: tt.timeout}
+			stdout, stderr, exitCode, err := runHTTPAction(http.DefaultClient, action, map[string]any{})
+
+			if tt.wantFailure == "" {
+				if exitCode != 0 || err != nil {
+					t.Fatalf("runHTTPAction() failed: %q, %d, %v", stderr, exitCode, err)
+				}
+				if stdout != tt.wantStdout {
+					t.Errorf("stdout = %q, want %q", stdout, tt.wantStdout)
+				}
+				return
+			}
+			if exitCode == 0 {
+				t.Fatalf("Expected failure, got stdout %q", stdout)
+			}
+			msg := actionFailureMessage(action, exitCode, stderr, err)
+			if !strings.Contains(msg, tt.wantFailure) {
+				t.Errorf("Failure message = %q, want it to contain %q", msg, tt.wantFailure)
+			}
+			if strings.Contains(msg, "secret-path") {
+				t.Errorf("Failure message should not contain the URL path: %q", msg)
+			}
+		})
+	}
+}
+
+func TestExecuteAction_HTTPFailSafe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	action := Action{Type: "http", URL: server.URL}
+	executor := NewActionExecutor(nil)
+
+	stopOutput, err := executor.ExecuteStopAction(action, &StopInput{}, map[string]any{})
+	if err != nil {
+		t.Fatalf("ExecuteStopAction() error = %v", err)
+	}
+	if stopOutput.Decision != "block" || !strings.Contains(stopOutput.Reason, "returned 500") {
+		t.Errorf("Expected Stop to be blocked on HTTP 500, got %+v", stopOutput)
+	}
+
+	preOutput, err := executor.ExecutePreToolUseAction(action, &PreToolUseInput{ToolName: "Bash"}, map[string]any{})
+	if err != nil {
+		t.Fatalf("ExecutePreToolUseAction() error = %v", err)
+	}
+	if preOutput == nil || preOutput.PermissionDecision != "deny" {
+		t.Errorf("Expected PreToolUse to be denied on HTTP 500, got %+v", preOutput)
+	}
+
+	notifyOutput, err := executor.ExecuteNotificationAction(Action{Type: "http", URL: server.URL, Timeout: "soon"}, &NotificationInput{}, map[string]any{})
+	if err != nil {
+		t.Fatalf("ExecuteNotificationAction() error = %v", err)
+	}
+	if notifyOutput.Continue || !strings.Contains(notifyOutput.SystemMessage, `invalid timeout "soon"`) {
+		t.Errorf("Expected invalid timeout to fail, got %+v", notifyOutput)
+	}
+}
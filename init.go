@@ -0,0 +1,296 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultInitTemplate is the starter template written by `cchook -command init` without -template.
+const defaultInitTemplate = "basic"
+
+// initHeader is prepended to every starter config; %s is the template name.
+const initHeader = `# cchook config generated by ` + "`cchook -command init -template %s`" + `.
+#
+# - Register cchook in .claude/settings.json for each event below, e.g.
+#   {"type": "command", "command": "cchook -event PreToolUse"} (see the Quick Start of the README)
+# - Check the config with ` + "`cchook -command validate`" + ` and try a hook with
+#   echo '{...event JSON...}' | cchook -command dry-run -event PreToolUse
+# - Reference: https://github.com/syou6162/cchook#configuration-reference
+
+`
+
+// initProtectTrackedFiles is the PreToolUse hook shared by the starter templates.
+const initProtectTrackedFiles = `  # rm/mv of files tracked by git loses them from the index: ask Claude to use git rm / git mv
+  - matcher: "Bash"
+    conditions:
+      - type: git_tracked_file_operation
+        value: "rm|mv"
+    actions:
+      - type: output
+        permission_decision: deny
+        message: "Use 'git rm' / 'git mv' for files tracked by git: {.tool_input.command}"
+`
+
+// initDirtyTreeContext is the SessionStart hook shared by the starter templates.
+const initDirtyTreeContext = `  # Tell Claude about uncommitted changes it didn't make
+  - matcher: "startup"
+    conditions:
+      - type: git_dirty
+    actions:
+      - type: output
+        message: "The working tree has uncommitted changes from before this session. Check git status before editing those files."
+`
+
+// initTemplates are the starter configs of `cchook -command init`, by -template name.
+var initTemplates = map[string]string{
+	"basic": `PreToolUse:
+` + initProtectTrackedFiles + `
+SessionStart:
+` + initDirtyTreeContext,
+
+	"go": `PreToolUse:
+` + initProtectTrackedFiles + `
+PostToolUse:
+  # Format Go files Claude writes (generated and vendored files ignored by git are skipped)
+  - matcher: "Write|Edit|MultiEdit"
+    conditions:
+      - type: file_extension
+        value: ".go"
+      - type: file_not_gitignored
+    actions:
+      - type: command
+        command: "gofmt -w {.tool_input.file_path}"
+
+SessionStart:
+  - matcher: "startup"
+    conditions:
+      - type: file_exists
+        value: "go.mod"
+    actions:
+      - type: output
+        message: "This is a Go module. Run go vet ./... and go test ./... before finishing a change."
+` + initDirtyTreeContext,
+
+	"node": `PreToolUse:
+` + initProtectTrackedFiles + `
+  # Keep the lock file of the project's package manager
+  - matcher: "Bash"
+    conditions:
+      - type: file_exists
+        value: "pnpm-lock.yaml"
+      - type: command_regex
+        value: '^\s*(npm|yarn)\s+(install|add|i)\b'
+    actions:
+      - type: output
+        permission_decision: deny
+        message: "This project uses pnpm"
+        model_hint: "Use pnpm instead of npm or yarn"
+
+PostToolUse:
+  # Format the files Claude writes with the project's prettier (does nothing when it isn't installed)
+  - matcher: "Write|Edit|MultiEdit"
+    conditions:
+      - type: file_path_matches
+        value: "**/*.{js,jsx,mjs,cjs,ts,tsx,json,css,md}"
+      - type: file_not_gitignored
+      - type: dir_exists
+        value: "node_modules/prettier"
+    actions:
+      - type: command
+        command: "npx --no-install prettier --write {.tool_input.file_path}"
+
+SessionStart:
+  - matcher: "startup"
+    conditions:
+      - type: file_exists
+        value: "package.json"
+    actions:
+      - type: output
+        message: "This is a Node.js project. Use the scripts of package.json (lint, test) to check a change."
+` + initDirtyTreeContext,
+
+	"python": `PreToolUse:
+` + initProtectTrackedFiles + `
+  # Manage dependencies with uv instead of installing into whatever environment pip finds
+  - matcher: "Bash"
+    conditions:
+      - type: command_regex
+        value: '\bpip3?\s+install\b'
+    actions:
+      - type: output
+        permission_decision: ask
+        message: "pip install outside of uv: {.tool_input.command}"
+        model_hint: "Use uv add (or uv pip install in the project's virtualenv) instead of pip install"
+
+PostToolUse:
+  # Format and lint the Python files Claude writes
+  - matcher: "Write|Edit|MultiEdit"
+    conditions:
+      - type: file_extension
+        value: ".py"
+      - type: file_not_gitignored
+    actions:
+      - type: command
+        command: "ruff format {.tool_input.file_path} && ruff check --fix {.tool_input.file_path}"
+
+SessionStart:
+  - matcher: "startup"
+    conditions:
+      - type: file_exists
+        value: "pyproject.toml"
+    actions:
+      - type: output
+        message: "This is a Python project managed with uv. Run commands with uv run (e.g. uv run pytest)."
+` + initDirtyTreeContext,
+
+	"strict-security": `PreToolUse:
+` + initProtectTrackedFiles + `
+  # Recursive deletes (rm -r, rm -rf, rm --recursive)
+  - matcher: "Bash"
+    conditions:
+      - type: command_regex
+        value: '(^|[;&|(]\s*|\bsudo\s+)rm\s+([^-;&|\s][^;&|\s]*\s+|-\w+\s+)*(-\w*[rR]\w*|--recursive)\b'
+    actions:
+      - type: output
+        permission_decision: deny
+        message: "Recursive rm is not allowed: {.tool_input.command}"
+
+  # Piping downloads into a shell
+  - matcher: "Bash"
+    conditions:
+      - type: command_regex
+        value: '\b(curl|wget)\b[^|]*\|\s*(sudo\s+)?(ba|z)?sh\b'
+    actions:
+      - type: output
+        permission_decision: deny
+        message: "Running a downloaded script is not allowed: {.tool_input.command}"
+
+  # Force pushes rewrite shared history
+  - matcher: "Bash"
+    conditions:
+      - type: push_is_force
+    actions:
+      - type: output
+        permission_decision: deny
+        message: "Force pushes are not allowed"
+
+  # Secrets in .env files stay out of the conversation
+  - matcher: "Read|Write|Edit|MultiEdit"
+    conditions:
+      - type: file_path_matches
+        value: "**/.env*"
+      - type: not
+        conditions:
+          - type: file_path_matches
+            value: "**/.env.example"
+    actions:
+      - type: output
+        permission_decision: deny
+        message: "Access to {.tool_input.file_path} is not allowed"
+
+  # Content that looks like a credential
+  - matcher: "Write|Edit|MultiEdit"
+    conditions:
+      - type: content_matches
+        values:
+          - 'AKIA[0-9A-Z]{16}'
+          - '-----BEGIN ([A-Z]+ )?PRIVATE KEY-----'
+          - '\bgh[pousr]_[A-Za-z0-9]{36}\b'
+    actions:
+      - type: output
+        permission_decision: deny
+        message: "The content looks like it contains a secret"
+
+  # Fetching arbitrary URLs needs confirmation
+  - matcher: "WebFetch"
+    actions:
+      - type: output
+        permission_decision: ask
+        message: "Fetch {.tool_input.url}?"
+
+SessionStart:
+  - matcher: "startup"
+    actions:
+      - type: output
+        message: "Security policy: recursive rm, force pushes, piping downloads into a shell and reading .env files are blocked. Ask the user when you need one of them."
+` + initDirtyTreeContext,
+}
+
+// initTemplateNames returns the names of the starter templates, sorted.
+func initTemplateNames() []string {
+	names := make([]string, 0, len(initTemplates))
+	for name := range initTemplates {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// initConfig returns the starter config of the template, with a header comment.
+func initConfig(template string) (string, error) {
+	body, ok := initTemplates[template]
+	if !ok {
+		return "", fmt.Errorf("unknown template '%s'. Valid templates: %s", template, strings.Join(initTemplateNames(), ", "))
+	}
+	return fmt.Sprintf(initHeader, template) + body, nil
+}
+
+// runInit writes the starter config of the template to configPath (the default config path when empty).
+// An existing file is only overwritten with force.
+func runInit(w io.Writer, configPath, template string, force bool) error {
+	content, err := initConfig(template)
+	if err != nil {
+		return err
+	}
+	if configPath == "" {
+		configPath = getDefaultConfigPath()
+	}
+	if err := os.MkdirAll(filepath.Dir(configPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(configPath, flags, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("%s already exists (use -force to overwrite it)", configPath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create config file: %w", err)
+	}
+	if _, err := f.WriteString(content); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	fmt.Fprintf(w, "Wrote the %s starter config to %s\n", template, configPath)
+	fmt.Fprintf(w, "Register cchook in .claude/settings.json for these events: %s\n", strings.Join(initEvents(content), ", "))
+	return nil
+}
+
+// initEvents returns the events configured by a starter config, in file order.
+func initEvents(content string) []string {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil || len(doc.Content) == 0 {
+		return nil
+	}
+	var events []string
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		events = append(events, root.Content[i].Value)
+	}
+	return events
+}
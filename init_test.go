@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInitTemplates(t *testing.T) {
+	for _, name := range initTemplateNames() {
+		t.Run(name, func(t *testing.T) {
+			content, err := initConfig(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if issues := validateConfigData("config.yaml", []byte(content)); len(issues) > 0 {
+				t.Errorf("template has issues: %v", issues)
+			}
+			if !strings.HasPrefix(content, "# cchook config generated by `cchook -command init -template "+name+"`") {
+				t.Errorf("missing header:\n%s", content)
+			}
+		})
+	}
+}
+
+func TestRunInit(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path := getDefaultConfigPath()
+
+	var out bytes.Buffer
+	if err := runInit(&out, "", "go", false); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), path) || !strings.Contains(out.String(), "these events: PreToolUse, PostToolUse, SessionStart") {
+		t.Errorf("output = %q", out.String())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "gofmt -w {.tool_input.file_path}") {
+		t.Errorf("config = %s", data)
+	}
+
+	// 既存の設定は-forceが無ければ上書きしない
+	err = runInit(&out, "", "strict-security", false)
+	if err == nil || !strings.Contains(err.Error(), "-force") {
+		t.Errorf("existing config: err = %v, want an error suggesting -force", err)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(after, data) {
+		t.Error("existing config was modified")
+	}
+	if err := runInit(&out, "", "strict-security", true); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := os.ReadFile(path); !strings.Contains(string(after), "push_is_force") {
+		t.Errorf("-force did not overwrite the config: %s", after)
+	}
+
+	custom := filepath.Join(t.TempDir(), "nested", "cchook.yaml")
+	if err := runInit(&out, custom, defaultInitTemplate, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := runInit(&out, custom+".2", "rust", false); err == nil || !strings.Contains(err.Error(), "basic, go, node, python, strict-security") {
+		t.Errorf("unknown template: err = %v", err)
+	}
+	if _, err := os.Stat(custom + ".2"); !os.IsNotExist(err) {
+		t.Errorf("unknown template created a file: %v", err)
+	}
+}
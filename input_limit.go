@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf8"
+)
+
+// defaultMaxInputSize is the default upper bound of the event JSON read from stdin.
+const defaultMaxInputSize = 32 * 1024 * 1024
+
+// maxTruncatedStringSize is the upper bound of a single string value kept when the input is truncated.
+const maxTruncatedStringSize = 1024 * 1024
+
+// Input overflow strategies (-input-overflow).
+const (
+	inputOverflowTruncate = "truncate" // truncate large string values (e.g. tool_response of a huge Read)
+	inputOverflowReject   = "reject"   // fail with ErrInputTooLarge (fail-safe decisions apply)
+)
+
+// maxInputSize is the upper bound of the event JSON in bytes (0 means unlimited). Set by -max-input-size.
+var maxInputSize int64 = defaultMaxInputSize
+
+// inputOverflowStrategy is how input larger than maxInputSize is handled. Set by -input-overflow.
+var inputOverflowStrategy = inputOverflowTruncate
+
+// readInput reads a single JSON value from r, honoring maxInputSize and inputOverflowStrategy.
+// Input within the limit is decoded as-is. Larger input is never buffered as a whole:
+// with the truncate strategy it is streamed and string values longer than
+// maxTruncatedStringSize are cut (a warning is written to stderr), so that the event is still processed.
+func readInput(r io.Reader) (json.RawMessage, error) {
+	var rawInput json.RawMessage
+	if maxInputSize <= 0 {
+		if err := json.NewDecoder(r).Decode(&rawInput); err != nil {
+			return nil, fmt.Errorf("failed to decode JSON input: %w", err)
+		}
+		return rawInput, nil
+	}
+
+	head, err := io.ReadAll(io.LimitReader(r, maxInputSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+	if int64(len(head)) <= maxInputSize {
+		if err := json.NewDecoder(bytes.NewReader(head)).Decode(&rawInput); err != nil {
+			return nil, fmt.Errorf("failed to decode JSON input: %w", err)
+		}
+		return rawInput, nil
+	}
+
+	if inputOverflowStrategy == inputOverflowReject {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrInputTooLarge, maxInputSize)
+	}
+
+	maxString := min(int64(maxTruncatedStringSize), maxInputSize/4)
+	t := &jsonTruncator{
+		r:         bufio.NewReader(io.MultiReader(bytes.NewReader(head), r)),
+		maxString: int(maxString),
+		maxOutput: int(maxInputSize),
+	}
+	if err := t.value(); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON input: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Warning: input exceeded %d bytes; truncated %d string value(s) to %d bytes\n", maxInputSize, t.truncated, maxString)
+	return json.RawMessage(t.out.Bytes()), nil
+}
+
+// jsonTruncator copies a single JSON value from r to out while cutting long string values.
+type jsonTruncator struct {
+	r         *bufio.Reader
+	out       bytes.Buffer
+	maxString int
+	maxOutput int
+	truncated int
+}
+
+// value copies one JSON value (object, array, string or literal).
+func (t *jsonTruncator) value() error {
+	c, err := t.peek()
+	if err != nil {
+		return err
+	}
+	switch c {
+	case '{':
+		err = t.container('{', '}', true)
+	case '[':
+		err = t.container('[', ']', false)
+	case '"':
+		err = t.str()
+	default:
+		err = t.literal()
+	}
+	if err != nil {
+		return err
+	}
+	if t.out.Len() > t.maxOutput {
+		return fmt.Errorf("%w: more than %d bytes even after truncation", ErrInputTooLarge, t.maxOutput)
+	}
+	return nil
+}
+
+// container copies an object (keyed=true) or an array.
+func (t *jsonTruncator) container(open, close byte, keyed bool) error {
+	if _, err := t.r.ReadByte(); err != nil {
+		return err
+	}
+	t.out.WriteByte(open)
+
+	c, err := t.peek()
+	if err != nil {
+		return err
+	}
+	if c == close {
+		_, _ = t.r.ReadByte()
+		t.out.WriteByte(close)
+		return nil
+	}
+
+	for {
+		if keyed {
+			if c, err := t.peek(); err != nil {
+				return err
+			} else if c != '"' {
+				return fmt.Errorf("invalid character %q looking for object key", c)
+			}
+			if err := t.str(); err != nil {
+				return err
+			}
+			if err := t.expect(':'); err != nil {
+				return err
+			}
+			t.out.WriteByte(':')
+		}
+		if err := t.value(); err != nil {
+			return err
+		}
+
+		c, err := t.peek()
+		if err != nil {
+			return err
+		}
+		_, _ = t.r.ReadByte()
+		switch c {
+		case ',':
+			t.out.WriteByte(',')
+		case close:
+			t.out.WriteByte(close)
+			return nil
+		default:
+			return fmt.Errorf("invalid character %q after element", c)
+		}
+	}
+}
+
+// str copies a string, keeping at most maxString bytes of its content.
+func (t *jsonTruncator) str() error {
+	if _, err := t.r.ReadByte(); err != nil {
+		return err
+	}
+	t.out.WriteByte('"')
+	start := t.out.Len()
+	kept, dropped := 0, 0
+
+	write := func(b []byte) {
+		if dropped == 0 && kept+len(b) <= t.maxString {
+			t.out.Write(b)
+			kept += len(b)
+			return
+		}
+		dropped += len(b)
+	}
+
+	for {
+		b, err := t.r.ReadByte()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		switch {
+		case b == '"':
+			if dropped > 0 {
+				// 途中で切れたマルチバイト文字を取り除く
+				content := t.out.Bytes()[start:]
+				cut := len(content)
+				i := cut - 1
+				for i >= 0 && cut-i < utf8.UTFMax && !utf8.RuneStart(content[i]) {
+					i--
+				}
+				if i >= 0 && !utf8.FullRune(content[i:]) {
+					cut = i
+				}
+				dropped += len(content) - cut
+				t.out.Truncate(start + cut)
+				fmt.Fprintf(&t.out, "...[truncated %d bytes]", dropped)
+				t.truncated++
+			}
+			t.out.WriteByte('"')
+			return nil
+		case b == '\\':
+			esc, err := t.r.ReadByte()
+			if err != nil {
+				return unexpectedEOF(err)
+			}
+			seq := []byte{b, esc}
+			if esc == 'u' {
+				hex := make([]byte, 4)
+				if _, err := io.ReadFull(t.r, hex); err != nil {
+					return unexpectedEOF(err)
+				}
+				seq = append(seq, hex...)
+			}
+			write(seq)
+		case b < 0x20:
+			return fmt.Errorf("invalid control character %q in string literal", b)
+		default:
+			write([]byte{b})
+		}
+	}
+}
+
+// literal copies a number, true, false or null.
+func (t *jsonTruncator) literal() error {
+	n := 0
+	for {
+		b, err := t.r.ReadByte()
+		if err == io.EOF && n > 0 {
+			return nil
+		}
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		if !isLiteralByte(b) {
+			if n == 0 {
+				return fmt.Errorf("invalid character %q looking for beginning of value", b)
+			}
+			return t.r.UnreadByte()
+		}
+		t.out.WriteByte(b)
+		n++
+	}
+}
+
+// expect skips whitespace and consumes the byte c.
+func (t *jsonTruncator) expect(c byte) error {
+	got, err := t.peek()
+	if err != nil {
+		return err
+	}
+	if got != c {
+		return fmt.Errorf("invalid character %q, expected %q", got, c)
+	}
+	_, _ = t.r.ReadByte()
+	return nil
+}
+
+// peek skips whitespace and returns the next byte without consuming it.
+func (t *jsonTruncator) peek() (byte, error) {
+	for {
+		b, err := t.r.ReadByte()
+		if err != nil {
+			return 0, unexpectedEOF(err)
+		}
+		switch b {
+		case ' ', '\t', '\n', '\r':
+			continue
+		}
+		return b, t.r.UnreadByte()
+	}
+}
+
+// isLiteralByte reports whether b may appear in a JSON number or true/false/null.
+func isLiteralByte(b byte) bool {
+	return (b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || b == '-' || b == '+' || b == '.' || b == 'E'
+}
+
+// unexpectedEOF converts io.EOF in the middle of a value into io.ErrUnexpectedEOF.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
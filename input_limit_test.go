@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// setInputLimit overrides maxInputSize / inputOverflowStrategy for the duration of a test.
+func setInputLimit(t *testing.T, size int64, strategy string) {
+	t.Helper()
+	origSize, origStrategy := maxInputSize, inputOverflowStrategy
+	maxInputSize, inputOverflowStrategy = size, strategy
+	t.Cleanup(func() {
+		maxInputSize, inputOverflowStrategy = origSize, origStrategy
+	})
+}
+
+func TestReadInput_WithinLimit(t *testing.T) {
+	setInputLimit(t, 1024, inputOverflowReject)
+
+	input := `{"tool_name":"Read","tool_response":{"content":"small"}}`
+	got, err := readInput(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("readInput() error = %v", err)
+	}
+	if string(got) != input {
+		t.Errorf("readInput() = %s, want %s", got, input)
+	}
+}
+
+func TestReadInput_Reject(t *testing.T) {
+	setInputLimit(t, 64, inputOverflowReject)
+
+	input := `{"tool_name":"Read","tool_response":{"content":"` + strings.Repeat("x", 1000) + `"}}`
+	_, err := readInput(strings.NewReader(input))
+	if !errors.Is(err, ErrInputTooLarge) {
+		t.Errorf("readInput() error = %v, want ErrInputTooLarge", err)
+	}
+}
+
+func TestReadInput_Truncate(t *testing.T) {
+	setInputLimit(t, 400, inputOverflowTruncate)
+
+	huge := strings.Repeat("あ", 1000) // マルチバイト文字の途中で切れないこと
+	input := `{"session_id":"s1","tool_name":"Read","tool_input":{"file_path":"big.txt","limit":-1.5e3,"ok":true,"none":null},` +
+		`"tool_response":{"content":"` + huge + `","lines":["a","b\"c"]}}`
+
+	got, err := readInput(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("readInput() error = %v", err)
+	}
+
+	var decoded struct {
+		SessionID string         `json:"session_id"`
+		ToolInput map[string]any `json:"tool_input"`
+		Response  struct {
+			Content string   `json:"content"`
+			Lines   []string `json:"lines"`
+		} `json:"tool_response"`
+	}
+	if err := json.Unmarshal(got, &decoded); err != nil {
+		t.Fatalf("Truncated input is not valid JSON: %v\n%s", err, got)
+	}
+	if decoded.SessionID != "s1" || decoded.ToolInput["file_path"] != "big.txt" || decoded.ToolInput["limit"] != -1500.0 {
+		t.Errorf("Small fields should be preserved, got %+v", decoded)
+	}
+	if !strings.HasPrefix(decoded.Response.Content, "あ") || !strings.Contains(decoded.Response.Content, "...[truncated ") {
+		t.Errorf("Expected truncated content, got %q", decoded.Response.Content)
+	}
+	if !utf8.ValidString(decoded.Response.Content) {
+		t.Errorf("Truncated content must be valid UTF-8")
+	}
+	if len(decoded.Response.Lines) != 2 || decoded.Response.Lines[1] != `b"c` {
+		t.Errorf("Lines = %v, want [a b\"c]", decoded.Response.Lines)
+	}
+}
+
+func TestReadInput_TruncateInvalidJSON(t *testing.T) {
+	setInputLimit(t, 16, inputOverflowTruncate)
+
+	for _, input := range []string{
+		`{"a":"` + strings.Repeat("x", 100),
+		`{"a":` + strings.Repeat("1", 100) + `,}`,
+		`[` + strings.Repeat(`"x",`, 100) + `?]`,
+	} {
+		if _, err := readInput(strings.NewReader(input)); err == nil {
+			t.Errorf("readInput(%.20q...) expected error", input)
+		}
+	}
+}
+
+func TestParseInputFrom_HugeToolResponse(t *testing.T) {
+	setInputLimit(t, 1024, inputOverflowTruncate)
+
+	input := `{"hook_event_name":"PostToolUse","tool_name":"Read","tool_input":{"file_path":"big.log"},` +
+		`"tool_response":{"content":"` + strings.Repeat("log line\\n", 10000) + `"}}`
+
+	got, rawJSON, err := parseInputFrom[*PostToolUseInput](strings.NewReader(input), PostToolUse)
+	if err != nil {
+		t.Fatalf("parseInputFrom() error = %v", err)
+	}
+	if got.ToolInput.FilePath != "big.log" {
+		t.Errorf("FilePath = %q, want big.log", got.ToolInput.FilePath)
+	}
+	if rawJSON == nil {
+		t.Error("Expected rawJSON to be set")
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// invocationCacheEntry is the memoized result of a lookup.
+type invocationCacheEntry struct {
+	value     any
+	err       error
+	workspace bool // depends on the working tree, the index or the git config
+}
+
+// invocationCache memoizes the expensive lookups of conditions (transcript scans, git commands and index reads)
+// for the current event, so that hooks sharing a check don't repeat it. Lookups of the workspace are dropped
+// whenever an action runs, since the action may change the files or the repository; transcript lookups are kept,
+// since only Claude Code appends to the transcript.
+var (
+	invocationCache      = map[string]invocationCacheEntry{}
+	invocationCacheMutex sync.Mutex
+)
+
+// cachedLookup returns the memoized result of the lookup identified by key, calling compute on a miss.
+// Errors are memoized too: the lookup would fail the same way for the rest of the event.
+func cachedLookup[T any](key string, workspace bool, compute func() (T, error)) (T, error) {
+	invocationCacheMutex.Lock()
+	entry, ok := invocationCache[key]
+	invocationCacheMutex.Unlock()
+	if ok {
+		return entry.value.(T), entry.err
+	}
+
+	value, err := compute()
+	invocationCacheMutex.Lock()
+	invocationCache[key] = invocationCacheEntry{value: value, err: err, workspace: workspace}
+	invocationCacheMutex.Unlock()
+	return value, err
+}
+
+// invocationCacheKey joins the kind of a lookup and its arguments into a cache key.
+func invocationCacheKey(kind string, args ...string) string {
+	return kind + "\x00" + strings.Join(args, "\x00")
+}
+
+// resetInvocationCache drops every memoized lookup.
+func resetInvocationCache() {
+	invocationCacheMutex.Lock()
+	defer invocationCacheMutex.Unlock()
+	clear(invocationCache)
+}
+
+// resetEventCaches makes the long-running commands (serve, simulate and ui) start every event like a new process:
+// the lookups memoized by the previous event are dropped and the cached git branches revalidated.
+func resetEventCaches() {
+	resetInvocationCache()
+	revalidateGitBranchCache()
+}
+
+// invalidateWorkspaceCache drops the memoized lookups of the workspace after an action ran.
+func invalidateWorkspaceCache() {
+	invocationCacheMutex.Lock()
+	defer invocationCacheMutex.Unlock()
+	for key, entry := range invocationCache {
+		if entry.workspace {
+			delete(invocationCache, key)
+		}
+	}
+}
+
+// cachedUserPromptCount is countUserPromptsFromTranscript memoized for the event.
+func cachedUserPromptCount(transcriptPath, sessionID string) (int, error) {
+	return cachedLookup(invocationCacheKey("user_prompts", transcriptPath, sessionID), false, func() (int, error) {
+		return countUserPromptsFromTranscript(transcriptPath, sessionID)
+	})
+}
+
+// cachedSessionDuration is sessionDurationFromTranscript memoized for the event.
+func cachedSessionDuration(transcriptPath, sessionID string) (time.Duration, error) {
+	return cachedLookup(invocationCacheKey("session_duration", transcriptPath, sessionID), false, func() (time.Duration, error) {
+		return sessionDurationFromTranscript(transcriptPath, sessionID)
+	})
+}
+
+// cachedTranscriptStats is readTranscriptStats memoized for the event.
+func cachedTranscriptStats(transcriptPath, sessionID string) (transcriptStats, error) {
+	return cachedLookup(invocationCacheKey("transcript_stats", transcriptPath, sessionID), false, func() (transcriptStats, error) {
+		return readTranscriptStats(transcriptPath, sessionID)
+	})
+}
+
+// cachedSessionEditedFiles is sessionEditedFiles memoized for the event.
+func cachedSessionEditedFiles(transcriptPath, sessionID string) ([]string, error) {
+	return cachedLookup(invocationCacheKey("edited_files", transcriptPath, sessionID), false, func() ([]string, error) {
+		return sessionEditedFiles(transcriptPath, sessionID)
+	})
+}
+
+// gitWorktreeStatus is the result of gitWorktreeDirty.
+type gitWorktreeStatus struct {
+	dirty, inRepo bool
+}
+
+// cachedGitWorktreeDirty is gitWorktreeDirty memoized until an action runs.
+func cachedGitWorktreeDirty(dir string) (dirty, inRepo bool, err error) {
+	status, err := cachedLookup(invocationCacheKey("git_status", dir), true, func() (gitWorktreeStatus, error) {
+		dirty, inRepo, err := gitWorktreeDirty(dir)
+		return gitWorktreeStatus{dirty: dirty, inRepo: inRepo}, err
+	})
+	return status.dirty, status.inRepo, err
+}
+
+// cachedGitSigningEnabled is gitSigningEnabled memoized until an action runs.
+func cachedGitSigningEnabled(dir, format string) bool {
+	enabled, _ := cachedLookup(invocationCacheKey("git_signing", dir, format), true, func() (bool, error) {
+		return gitSigningEnabled(dir, format), nil
+	})
+	return enabled
+}
+
+// cachedGitTracked is isGitTracked memoized until an action runs.
+func cachedGitTracked(filePath string) (bool, error) {
+	key := filePath
+	if absPath, err := filepath.Abs(filePath); err == nil {
+		key = absPath
+	}
+	return cachedLookup(invocationCacheKey("git_tracked", key), true, func() (bool, error) {
+		return isGitTracked(filePath)
+	})
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCachedLookup(t *testing.T) {
+	t.Cleanup(resetInvocationCache)
+
+	calls := 0
+	lookup := func(key string, workspace bool) int {
+		value, _ := cachedLookup(invocationCacheKey("test", key), workspace, func() (int, error) {
+			calls++
+			return calls, nil
+		})
+		return value
+	}
+
+	if lookup("transcript", false) != 1 || lookup("transcript", false) != 1 || lookup("status", true) != 2 || lookup("status", true) != 2 {
+		t.Fatalf("Expected each lookup to be computed once, got %d calls", calls)
+	}
+
+	// アクションの後はワークスペースの結果だけを計算し直す
+	if _, _, _, err := NewActionExecutor(nil).runAction(Action{Type: "command", Command: "true"}, map[string]any{}); err != nil {
+		t.Fatal(err)
+	}
+	if lookup("transcript", false) != 1 || lookup("status", true) != 3 {
+		t.Errorf("Expected only the workspace lookup to be recomputed after an action, got %d calls", calls)
+	}
+
+	resetInvocationCache()
+	if lookup("transcript", false) != 4 {
+		t.Errorf("Expected every lookup to be recomputed after a reset, got %d calls", calls)
+	}
+}
+
+func TestCheckCondition_TranscriptScannedOnce(t *testing.T) {
+	t.Cleanup(resetInvocationCache)
+	transcript := createTestTranscript(t, "s1", 2)
+	input := &UserPromptSubmitInput{BaseInput: BaseInput{SessionID: "s1", TranscriptPath: transcript}}
+
+	// 3回目のプロンプトで発火する条件を複数のフックが使う
+	condition := Condition{Type: ConditionEveryNPrompts, Value: "3"}
+	if matched, err := checkUserPromptSubmitCondition(condition, input); err != nil || !matched {
+		t.Fatalf("checkUserPromptSubmitCondition() = %v, %v, want true", matched, err)
+	}
+	// 同じイベントの中ではtranscriptを読み直さない
+	if err := os.WriteFile(transcript, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if matched, err := checkUserPromptSubmitCondition(condition, input); err != nil || !matched {
+		t.Errorf("checkUserPromptSubmitCondition() = %v, %v, want the memoized count", matched, err)
+	}
+
+	resetInvocationCache()
+	if matched, err := checkUserPromptSubmitCondition(condition, input); err != nil || matched {
+		t.Errorf("checkUserPromptSubmitCondition() = %v, %v, want false for the next event", matched, err)
+	}
+}
+
+func TestCachedGitTracked_RelativePath(t *testing.T) {
+	t.Cleanup(resetInvocationCache)
+	repo := t.TempDir()
+	if err := runCommand("cd "+repo+" && git init -q && touch a.txt && git add a.txt", false, nil); err != nil {
+		t.Skipf("git is not available: %v", err)
+	}
+	other := t.TempDir()
+	if err := os.WriteFile(filepath.Join(other, "a.txt"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// 相対パスは絶対パスで区別する
+	t.Chdir(repo)
+	if tracked, err := cachedGitTracked("a.txt"); err != nil || !tracked {
+		t.Errorf("cachedGitTracked() in the repository = %v, %v, want true", tracked, err)
+	}
+	t.Chdir(other)
+	if tracked, err := cachedGitTracked("a.txt"); err != nil || tracked {
+		t.Errorf("cachedGitTracked() outside the repository = %v, %v, want false", tracked, err)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// binarySniffSize is the number of leading bytes checked for NUL, the heuristic git uses to tell binary files apart.
+const binarySniffSize = 8000
+
+// byteSizeUnits are the units of a file_size_gt value (1024-based).
+var byteSizeUnits = map[string]int64{
+	"": 1, "b": 1,
+	"k": 1 << 10, "kb": 1 << 10,
+	"m": 1 << 20, "mb": 1 << 20,
+	"g": 1 << 30, "gb": 1 << 30,
+}
+
+// parseByteSize parses a size like "500KB", "5MB" or "1048576".
+func parseByteSize(value string) (int64, error) {
+	s := strings.TrimSpace(value)
+	i := len(s)
+	for i > 0 && (s[i-1] < '0' || s[i-1] > '9') {
+		i--
+	}
+	multiplier, ok := byteSizeUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if !ok || i == 0 {
+		return 0, fmt.Errorf("invalid size %q (e.g. 500KB, 5MB)", value)
+	}
+	n, err := strconv.ParseInt(s[:i], 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q (e.g. 500KB, 5MB)", value)
+	}
+	return n * multiplier, nil
+}
+
+// looksBinary reports whether data looks like the content of a binary file (a NUL in the first binarySniffSize bytes).
+func looksBinary(data []byte) bool {
+	return bytes.IndexByte(data[:min(len(data), binarySniffSize)], 0) >= 0
+}
+
+// matchLargeFiles evaluates file_size_gt / file_is_binary: true when the content a Write creates,
+// or any file a `git add` in the command stages, is larger than the value or looks binary.
+func matchLargeFiles(condition Condition, toolInput *ToolInput, cwd string) (bool, error) {
+	var limit int64
+	if condition.Type == ConditionFileSizeGt {
+		var err error
+		if limit, err = parseByteSize(condition.Value); err != nil {
+			return false, fmt.Errorf("file_size_gt: %w", err)
+		}
+	}
+
+	if toolInput.Content != "" {
+		if condition.Type == ConditionFileSizeGt {
+			return int64(len(toolInput.Content)) > limit, nil
+		}
+		return looksBinary([]byte(toolInput.Content)), nil
+	}
+
+	for _, path := range gitAddedFiles(toolInput.Command, cwd) {
+		if condition.Type == ConditionFileSizeGt {
+			if info, err := os.Stat(path); err == nil && info.Size() > limit {
+				return true, nil
+			}
+			continue
+		}
+		if fileLooksBinary(path) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// fileLooksBinary reports whether the file at path looks binary. Unreadable files don't.
+func fileLooksBinary(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = f.Close() }()
+	head, err := io.ReadAll(io.LimitReader(f, binarySniffSize))
+	return err == nil && looksBinary(head)
+}
+
+// gitAddedFiles returns the absolute paths of the existing files the `git add` commands in the shell command
+// would stage: modified tracked files and untracked, not ignored files matching the pathspecs
+// (the whole working tree for -A/-u without pathspecs; only tracked files for -u).
+func gitAddedFiles(command, cwd string) []string {
+	if !strings.Contains(command, "add") {
+		return nil
+	}
+	var files []string
+	for _, args := range shellCallArgs(command) {
+		subcommand, addArgs, dir, ok := gitSubcommand(args, cwd)
+		if !ok || subcommand != "add" || dir == "" {
+			continue
+		}
+		files = append(files, gitAddCandidates(addArgs, dir)...)
+	}
+	return files
+}
+
+// gitAddCandidates lists the files `git add args` run in dir would stage, using `git ls-files`.
+func gitAddCandidates(args []string, dir string) []string {
+	var pathspecs []string
+	all, update := false, false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			pathspecs = append(pathspecs, args[i+1:]...)
+			i = len(args)
+		case arg == "--all" || arg == "--no-ignore-removal":
+			all = true
+		case arg == "--update":
+			update = true
+		case arg == "--chmod" || arg == "--pathspec-from-file":
+			i++
+		case strings.HasPrefix(arg, "--"):
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			all = all || strings.Contains(arg, "A")
+			update = update || strings.Contains(arg, "u")
+		default:
+			pathspecs = append(pathspecs, arg)
+		}
+	}
+	if len(pathspecs) == 0 {
+		if !all && !update {
+			return nil
+		}
+		pathspecs = []string{":/"}
+	}
+
+	lsArgs := []string{"-C", dir, "ls-files", "-z", "--full-name", "--modified"}
+	if !update {
+		lsArgs = append(lsArgs, "--others", "--exclude-standard")
+	}
+	out, err := exec.Command("git", append(append(lsArgs, "--"), pathspecs...)...).Output()
+	if err != nil {
+		return nil
+	}
+	root, err := exec.Command("git", "-C", dir, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return nil
+	}
+	top := strings.TrimSpace(string(root))
+
+	var files []string
+	seen := map[string]bool{}
+	for _, name := range strings.Split(string(out), "\x00") {
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		files = append(files, filepath.Join(top, filepath.FromSlash(name)))
+	}
+	return files
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{value: "1048576", want: 1 << 20},
+		{value: "500KB", want: 500 << 10},
+		{value: "5MB", want: 5 << 20},
+		{value: "5 mb", want: 5 << 20},
+		{value: "1G", want: 1 << 30},
+		{value: "10B", want: 10},
+		{value: "", wantErr: true},
+		{value: "MB", wantErr: true},
+		{value: "5TB", wantErr: true},
+		{value: "1.5MB", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseByteSize(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseByteSize(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseByteSize(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckCondition_LargeFile(t *testing.T) {
+	repo := t.TempDir()
+	if err := runCommand("cd "+repo+" && git init -q && git config user.email a@b && git config user.name a", false, nil); err != nil {
+		t.Skipf("git is not available: %v", err)
+	}
+	write := func(name string, data []byte) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(repo, name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(repo, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("small.txt", []byte("hello\n"))
+	write("dist/app.bin", append([]byte("ELF"), make([]byte, 4096)...))
+	write(".gitignore", []byte("ignored.bin\n"))
+	write("ignored.bin", make([]byte, 4096))
+	write("tracked.txt", []byte("v1\n"))
+	if err := runCommand("cd "+repo+" && git add tracked.txt && git commit -q -m init", false, nil); err != nil {
+		t.Fatal(err)
+	}
+	write("tracked.txt", []byte(strings.Repeat("x", 4096)))
+
+	sizeGt := Condition{Type: ConditionFileSizeGt, Value: "1KB"}
+	binary := Condition{Type: ConditionFileIsBinary}
+	tests := []struct {
+		name      string
+		condition Condition
+		input     ToolInput
+		want      bool
+	}{
+		{name: "write large content", condition: sizeGt, input: ToolInput{FilePath: "a.txt", Content: strings.Repeat("x", 2048)}, want: true},
+		{name: "write small content", condition: sizeGt, input: ToolInput{FilePath: "a.txt", Content: "x"}, want: false},
+		{name: "write binary content", condition: binary, input: ToolInput{FilePath: "a.bin", Content: "a\x00b"}, want: true},
+		{name: "write text content", condition: binary, input: ToolInput{FilePath: "a.txt", Content: "text"}, want: false},
+		{name: "add large file", condition: sizeGt, input: ToolInput{Command: "git add dist/app.bin"}, want: true},
+		{name: "add small file", condition: sizeGt, input: ToolInput{Command: "git add small.txt"}, want: false},
+		{name: "add directory", condition: binary, input: ToolInput{Command: "git add dist"}, want: true},
+		{name: "add dot", condition: sizeGt, input: ToolInput{Command: "git add ."}, want: true},
+		{name: "add -A", condition: binary, input: ToolInput{Command: "git add -A && git commit -m x"}, want: true},
+		{name: "add -u stages tracked files only", condition: binary, input: ToolInput{Command: "git add -u"}, want: false},
+		{name: "add -u large tracked file", condition: sizeGt, input: ToolInput{Command: "git add -u"}, want: true},
+		{name: "ignored files are not staged", condition: binary, input: ToolInput{Command: "git add ignored.bin small.txt"}, want: false},
+		{name: "add without pathspec", condition: sizeGt, input: ToolInput{Command: "git add"}, want: false},
+		{name: "not git add", condition: sizeGt, input: ToolInput{Command: "cat dist/app.bin"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := checkToolCondition(tt.condition, &tt.input, repo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("checkToolCondition() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := checkToolCondition(Condition{Type: ConditionFileSizeGt, Value: "big"}, &ToolInput{Content: "x"}, repo); err == nil {
+		t.Error("invalid size: want error")
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Default truncation of the linter output reported to Claude by lint_feedback actions.
+const (
+	lintFeedbackMaxLines = 30
+	lintFeedbackMaxBytes = 4000
+)
+
+// defaultLinters are the linter commands of lint_feedback actions by file extension, in order of
+// preference: the first one installed is used. The file path is appended to the command, and a linter
+// reports issues by exiting with a non-zero status.
+var defaultLinters = map[string][]string{
+	".py":   {"ruff check --quiet --output-format concise"},
+	".pyi":  {"ruff check --quiet --output-format concise"},
+	".sh":   {"shellcheck --format gcc"},
+	".bash": {"shellcheck --format gcc"},
+	".yaml": {"yamllint --format parsable"},
+	".yml":  {"yamllint --format parsable"},
+}
+
+// eslintExtensions are the extensions linted with eslint (the project's node_modules/.bin/eslint if installed).
+var eslintExtensions = []string{".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx", ".mts", ".cts", ".vue"}
+
+func init() {
+	for _, ext := range eslintExtensions {
+		defaultLinters[ext] = []string{"eslint"}
+	}
+}
+
+// lintFeedbackTargetFor returns the linter for the file at path: the linters entry of its extension
+// (an empty command disables linting), otherwise the first installed default linter.
+func lintFeedbackTargetFor(path string, linters map[string]string) (target *fileToolTarget, missing []string) {
+	return fileToolTargetFor(path, defaultLinters, linters)
+}
+
+// truncateLintOutput keeps the first maxLines lines and maxBytes bytes of a linter's output
+// (the defaults when zero, unlimited when negative) and tells how many lines were left out.
+func truncateLintOutput(output string, maxLines, maxBytes int) string {
+	if maxLines == 0 {
+		maxLines = lintFeedbackMaxLines
+	}
+	if maxBytes == 0 {
+		maxBytes = lintFeedbackMaxBytes
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	kept, size := 0, 0
+	for kept < len(lines) {
+		if maxLines > 0 && kept >= maxLines {
+			break
+		}
+		if maxBytes > 0 && size+len(lines[kept])+1 > maxBytes && kept > 0 {
+			break
+		}
+		size += len(lines[kept]) + 1
+		kept++
+	}
+	result := strings.Join(lines[:kept], "\n")
+	if maxBytes > 0 && len(result) > maxBytes {
+		result = strings.ToValidUTF8(result[:maxBytes], "")
+	}
+	if n := len(lines) - kept; n > 0 {
+		result += fmt.Sprintf("\n... and %d more line(s)", n)
+	}
+	return result
+}
+
+// lintFeedback runs the linter of the file at filePath (shown to Claude as displayPath) for a lint_feedback action.
+// When the linter reports issues, the hook blocks with its (truncated) output as the reason so that Claude fixes them.
+// Files without a linter are ignored, and a linter that is not installed or can't run only prints a warning.
+func (e *ActionExecutor) lintFeedback(action Action, filePath, displayPath string, rawJSON any) *ActionOutput {
+	target, missing := lintFeedbackTargetFor(filePath, action.Linters)
+	if target == nil {
+		if len(missing) > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: lint_feedback could not lint %s: %s not installed\n", displayPath, formatterList(missing))
+		}
+		return nil
+	}
+
+	lint := Action{Type: "lint_feedback", Command: target.Command, EnvFrom: action.EnvFrom}
+	stdout, stderr, exitCode, err := e.runAction(lint, rawJSON)
+	if exitCode == 0 {
+		return nil
+	}
+	if exitCode == 127 || (err != nil && stdout == "" && stderr == "") {
+		// リンタが動かないだけなのでブロックしない
+		message := strings.TrimSpace(stderr)
+		if message == "" && err != nil {
+			message = err.Error()
+		}
+		fmt.Fprintf(os.Stderr, "Warning: lint_feedback could not run %s: %s\n", target.Display, message)
+		return nil
+	}
+
+	output := truncateLintOutput(stdout+"\n"+stderr, action.MaxLines, action.MaxBytes)
+	return &ActionOutput{
+		Continue:      true,
+		Decision:      "block",
+		Reason:        fmt.Sprintf("%s found issues in %s (exit code %d). Fix them:\n%s", target.Name, displayPath, exitCode, output),
+		SystemMessage: fmt.Sprintf("lint_feedback: %s found issues in %s", target.Name, displayPath),
+		HookEventName: "PostToolUse",
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTruncateLintOutput(t *testing.T) {
+	output := "a.py:1:1: E1\na.py:2:1: E2\na.py:3:1: E3\n"
+	tests := []struct {
+		name               string
+		maxLines, maxBytes int
+		want               string
+	}{
+		{"defaults", 0, 0, "a.py:1:1: E1\na.py:2:1: E2\na.py:3:1: E3"},
+		{"max_lines", 2, 0, "a.py:1:1: E1\na.py:2:1: E2\n... and 1 more line(s)"},
+		{"max_bytes", -1, 20, "a.py:1:1: E1\n... and 2 more line(s)"},
+		{"long first line", 0, 5, "a.py:\n... and 2 more line(s)"},
+		{"unlimited", -1, -1, "a.py:1:1: E1\na.py:2:1: E2\na.py:3:1: E3"},
+	}
+	for _, tt := range tests {
+		if got := truncateLintOutput(output, tt.maxLines, tt.maxBytes); got != tt.want {
+			t.Errorf("%s: truncateLintOutput() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExecutePostToolUseAction_LintFeedback(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.py")
+	linter := filepath.Join(dir, "lint.sh")
+	// TODOを含む行を問題として報告する
+	script := "#!/bin/sh\nif grep -n TODO \"$1\"; then exit 1; fi\n"
+	if err := os.WriteFile(linter, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	executor := NewActionExecutor(nil)
+	input := &PostToolUseInput{BaseInput: BaseInput{Cwd: dir}, ToolName: "Edit", ToolInput: ToolInput{FilePath: "app.py"}}
+	action := Action{Type: "lint_feedback", Linters: map[string]string{"py": linter}, MaxLines: 1}
+
+	run := func(content string, action Action) *ActionOutput {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		output, err := executor.ExecutePostToolUseAction(action, input, map[string]any{"cwd": dir})
+		if err != nil {
+			t.Fatalf("ExecutePostToolUseAction() error = %v", err)
+		}
+		return output
+	}
+
+	if output := run("print('ok')\n", action); output != nil {
+		t.Errorf("Expected no output for a clean file, got %+v", output)
+	}
+	want := "lint.sh found issues in app.py (exit code 1). Fix them:\n1:# TODO one\n... and 1 more line(s)"
+	if output := run("# TODO one\n# TODO two\n", action); output == nil || output.Decision != "block" || output.Reason != want ||
+		output.SystemMessage != "lint_feedback: lint.sh found issues in app.py" {
+		t.Errorf("Unexpected output for lint issues: %+v", output)
+	}
+
+	missing := Action{Type: "lint_feedback", Linters: map[string]string{".py": "no-such-linter --check"}}
+	if output := run("# TODO\n", missing); output != nil {
+		t.Errorf("Expected a missing linter not to block, got %+v", output)
+	}
+	disabled := Action{Type: "lint_feedback", Linters: map[string]string{".py": ""}}
+	if output := run("# TODO\n", disabled); output != nil {
+		t.Errorf("Expected an empty linter to disable linting, got %+v", output)
+	}
+}
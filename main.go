@@ -1,26 +1,81 @@
 package main
 
 import (
-	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"strings"
 )
 
 func main() {
 	configPath := flag.String("config", "", "Path to config file")
-	command := flag.String("command", "run", "Command to execute (run, dry-run)")
-	eventType := flag.String("event", "", "Event type for run/dry-run command")
+	command := flag.String("command", "run", "Command to execute (run, dry-run, explain, bench, compile, simulate, ui, validate, budget, report, hooks, explain-decision, schema, init, serve)")
+	eventType := flag.String("event", "", "Event type for run/dry-run/explain/bench command")
+	stdinFile := flag.String("stdin-file", "", "Read event JSON from file instead of stdin (run/dry-run/explain/bench)")
+	outputFile := flag.String("output-file", "", "Write output to file instead of stdout (run/dry-run/explain/bench)")
+	listenAddr := flag.String("listen", defaultUIListenAddr, "Listen address of the policy UI (ui, serve -ui)")
+	serveUI := flag.Bool("ui", false, "Also serve the policy UI on -listen (serve)")
+	approvalListen := flag.String("approval-listen", "", "Listen address of the endpoint receiving ask_external decisions (serve)")
+	maxInput := flag.Int64("max-input-size", defaultMaxInputSize, "Maximum size of the event JSON in bytes (0 for unlimited)")
+	inputOverflow := flag.String("input-overflow", inputOverflowTruncate, "How to handle input larger than -max-input-size (truncate, reject)")
+	chaos := flag.Bool("chaos", false, "Inject command failures, timeouts and malformed outputs (dry-run only)")
+	maxSideEffects := flag.String("max-side-effects", "", "Only detail the hooks whose declared side effects go beyond this comma-separated list of writes_files, network and notifications, or none (dry-run only)")
+	output := flag.String("output", outputModeJSON, "Output mode of the run command: json, or exitcode for the legacy exit code protocol (0 allow, 2 block)")
+	batchQueueDir := flag.String("batch-dir", "", "Queue directory for the batch-flush command (started by cchook for batch hooks)")
+	projectConfig := flag.Bool("project-config", true, "Merge the "+projectConfigFileName+" found from the event cwd on top of the config (run/dry-run/explain/bench)")
+	initTemplate := flag.String("template", defaultInitTemplate, "Starter template of the init command ("+strings.Join(initTemplateNames(), ", ")+")")
+	force := flag.Bool("force", false, "Overwrite an existing config file (init)")
+	configCache := flag.Bool("config-cache", true, "Cache the parsed config under the user cache directory until a config file's modification time changes")
+	client := flag.Bool("client", false, "Send the event to the daemon started by the serve command, or run the hooks in this process when none is running (run)")
+	socketPath := flag.String("socket", "", "Unix socket of the daemon (serve, -client; default: $XDG_RUNTIME_DIR/cchook.sock)")
+	benchInput := flag.String("input", "", "Sample event JSON file of the bench command (same as -stdin-file)")
+	iterations := flag.Int("iterations", defaultBenchIterations, "Number of times the bench command evaluates the event")
+	since := flag.String("since", defaultReportSince, "Period of the decision log summarized by the report command (e.g. 7d, 24h)")
+	idleTimeout := flag.Duration("idle-timeout", defaultIdleTimeout, "Exit the daemon after no events for this long (serve; 0 for never)")
 	flag.Parse()
 
-	if (*command == "run" || *command == "dry-run") && *eventType == "" {
+	// run/dry-run/explain/benchはイベントのJSONを読み込む
+	readsEvent := *command == "run" || *command == "dry-run" || *command == "explain" || *command == "bench"
+
+	if readsEvent && *eventType == "" {
 		fmt.Fprintf(os.Stderr, "Error: event type is required for %s command\n", *command)
 		os.Exit(1)
 	}
 
+	if *inputOverflow != inputOverflowTruncate && *inputOverflow != inputOverflowReject {
+		fmt.Fprintf(os.Stderr, "Error: invalid -input-overflow '%s'. Valid values: truncate, reject\n", *inputOverflow)
+		os.Exit(1)
+	}
+	maxInputSize = *maxInput
+	inputOverflowStrategy = *inputOverflow
+
+	if *output != outputModeJSON && *output != outputModeExitCode {
+		fmt.Fprintf(os.Stderr, "Error: invalid -output '%s'. Valid values: json, exitcode\n", *output)
+		os.Exit(1)
+	}
+	hookOutputMode = *output
+	configCacheEnabled = *configCache
+
+	if *chaos && *command != "dry-run" {
+		fmt.Fprintf(os.Stderr, "Error: -chaos can only be used with the dry-run command\n")
+		os.Exit(1)
+	}
+	if *maxSideEffects != "" && *command != "dry-run" {
+		fmt.Fprintf(os.Stderr, "Error: -max-side-effects can only be used with the dry-run command\n")
+		os.Exit(1)
+	}
+	effects, err := parseMaxSideEffects(*maxSideEffects)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	dryRunMaxSideEffects = effects
+
 	// イベントタイプの妥当性検証
-	if *command == "run" || *command == "dry-run" {
+	if readsEvent {
 		eventType := HookEventType(*eventType)
 		if !eventType.IsValid() {
 			fmt.Fprintf(os.Stderr, "Error: invalid event type '%s'. Valid types: PreToolUse, PostToolUse, PermissionRequest, Notification, Stop, SubagentStop, SubagentStart, PreCompact, SessionStart, SessionEnd, UserPromptSubmit\n", string(eventType))
@@ -28,541 +83,791 @@ func main() {
 		}
 	}
 
-	config, err := loadConfig(*configPath)
+	if *benchInput != "" {
+		*stdinFile = *benchInput
+	}
+	if readsEvent {
+		if err := redirectStdio(*stdinFile, *outputFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// initはスターター設定を書き出す（既存の設定は-forceが無ければ上書きしない）
+	if *command == "init" {
+		if err := runInit(os.Stdout, *configPath, *initTemplate, *force); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// serveはイベントをunixソケットで受け付けるデーモンになる（設定はイベント毎に読み込み、メモリにキャッシュする）
+	if *command == "serve" {
+		uiAddr := ""
+		if *serveUI {
+			uiAddr = *listenAddr
+		}
+		if err := runServe(*socketPath, *idleTimeout, uiAddr, *configPath, *approvalListen); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// compileはYAMLを直接パースしてアーティファクトを書き出す（既存のアーティファクトは使わない）
+	if *command == "compile" {
+		outPath, err := compileConfig(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error compiling config: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Compiled config written to %s\n", outPath)
+		os.Exit(0)
+	}
+
+	// validateはYAMLを直接パースして位置情報付きで問題を報告する
+	if *command == "validate" {
+		if err := runValidate(os.Stdout, *configPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// schemaは設定ファイルのJSON Schemaを出力する（設定は読まない）
+	if *command == "schema" {
+		if err := runSchema(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// simulateは設定のライブリロードのため自身で設定を読み込む
+	if *command == "simulate" {
+		if *eventType != "" && !HookEventType(*eventType).IsValid() {
+			fmt.Fprintf(os.Stderr, "Error: invalid event type '%s'\n", *eventType)
+			os.Exit(1)
+		}
+		if err := runSimulate(*configPath, HookEventType(*eventType), os.Stdin); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// batch-flushはbatchフックのキューをまとめて実行する（キューにフック定義があるので設定は読まない）
+	if *command == "batch-flush" {
+		if err := runBatchFlush(*batchQueueDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// uiは読み取り専用のWeb UIを提供する（設定はリクエスト毎に読み込む）
+	if *command == "ui" {
+		fmt.Fprintf(os.Stderr, "Serving policy UI on http://%s\n", *listenAddr)
+		server := &http.Server{Addr: *listenAddr, Handler: newPolicyUIHandler(*configPath), ReadHeaderTimeout: daemonRequestTimeout}
+		if err := server.ListenAndServe(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// runはイベント毎の処理をrunHookEventで行う (serveのデーモンも同じ処理でイベントを実行する)
+	if *command == "run" {
+		if *client {
+			os.Exit(runClient(*socketPath, *configPath, HookEventType(*eventType), *projectConfig))
+		}
+		os.Exit(runHookEvent(*configPath, HookEventType(*eventType), *projectConfig))
+	}
+
+	config, err := loadEventConfig(*configPath, readsEvent && *projectConfig)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
 		os.Exit(1)
 	}
 
+	// budget_exceeded/budget_remaining_belowはメインの設定のbudget:を使う
+	activeBudget = config.Budget
+
+	// git条件はgitが使えないときメインの設定のon_git_unavailableに従う
+	activeGitUnavailable = config.OnGitUnavailable
+	gitUnavailableWarnings = nil
+
 	switch *command {
-	case "run":
-		if HookEventType(*eventType) == SessionStart {
-			// SessionStart special handling with JSON output
-			output, err := RunSessionStartHooks(config)
-			if err != nil {
-				// Log error to stderr
-				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
-				// Ensure output has continue field and hookSpecificOutput even on error (requirement 1.4)
-				if output == nil {
-					output = &SessionStartOutput{
-						Continue:      false,
-						SystemMessage: fmt.Sprintf("Failed to process SessionStart: %v", err),
-						HookSpecificOutput: &SessionStartHookSpecificOutput{
-							HookEventName: "SessionStart",
-						},
-					}
-				}
+	case "dry-run":
+		if *chaos {
+			var r io.Reader
+			if r, err = stdinReader(); err == nil {
+				err = runChaos(os.Stdout, r, config, HookEventType(*eventType))
+			}
+			break
+		}
+		err = dryRunHooks(config, HookEventType(*eventType))
+	case "explain":
+		err = explainHooks(config, HookEventType(*eventType))
+	case "bench":
+		var r io.Reader
+		if r, err = stdinReader(); err == nil {
+			err = runBench(os.Stdout, r, config, HookEventType(*eventType), *iterations)
+		}
+	case "budget":
+		err = runBudget(os.Stdout, config.Budget)
+	case "report":
+		err = runReport(os.Stdout, config, flag.Args(), *since)
+	case "hooks":
+		err = runHooksCommand(os.Stdout, config, flag.Args())
+	case "explain-decision":
+		err = runExplainDecision(os.Stdout, config, flag.Args())
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", *command)
+		os.Exit(1)
+	}
+
+	if err != nil {
+		exitWithError(err)
+	}
+}
+
+// exitWithError prints err and exits: with the code of an ExitError (to its output stream), or 1 otherwise.
+func exitWithError(err error) {
+	os.Exit(reportError(err))
+}
+
+// reportError prints err like exitWithError and returns the exit code instead of exiting.
+func reportError(err error) int {
+	var exitErr *ExitError
+	// errors.Joinでラップされた場合でもExitErrorを取り出せるようにerrors.Asを使用
+	if errors.As(err, &exitErr) {
+		// ExitError の場合は適切な出力先に出力して指定のコードで終了
+		// err.Error()を使ってラップされた全メッセージを出力
+		if exitErr.Stderr {
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		} else {
+			fmt.Println(err.Error())
+		}
+		return exitErr.Code
+	}
+	// 通常のエラーの場合
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	return 1
+}
+
+// loadEventConfig loads the config at configPath and, with projectConfig, merges the project config
+// found from the cwd of the event JSON (read ahead from stdin).
+func loadEventConfig(configPath string, projectConfig bool) (*Config, error) {
+	config, err := loadConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	// プロジェクトの.cchook.yamlはイベント入力のcwdから探すため、入力を先読みする
+	// (読み込みエラーはparseInputが返し、各イベントのfail-safeで扱う)
+	if projectConfig {
+		if rawInput, err := prefetchInput(); err == nil {
+			if config, err = withProjectConfig(config, configPath, inputCwd(rawInput)); err != nil {
+				return nil, fmt.Errorf("project config: %w", err)
 			}
+		}
+	}
+	return config, nil
+}
+
+// runHookEvent runs the hooks of eventType for the event JSON on stdin and writes the output to stdout,
+// as `cchook -event <eventType>` does. Returns the exit code of the process.
+func runHookEvent(configPath string, eventType HookEventType, projectConfig bool) int {
+	config, err := loadEventConfig(configPath, projectConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		return 1
+	}
+
+	// budget_exceeded/budget_remaining_belowはメインの設定のbudget:を使う
+	activeBudget = config.Budget
+
+	// git条件はgitが使えないときメインの設定のon_git_unavailableに従う
+	activeGitUnavailable = config.OnGitUnavailable
+	gitUnavailableWarnings = nil
+	resetMatchedHookIDs()
+	eventTraceID = newTraceID()
+
+	// ログの設定に失敗してもフックの実行は止めない
+	if config.Log != nil {
+		if err := setupHookLog(config.Log, eventType); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to set up log: %v\n", err)
+		}
+	}
+
+	// -command hooks disableで無効にしたフックは実行しない
+	if config, err = withoutDisabledHooks(config, eventType); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	// decision_logは最終出力と一緒にイベント入力のsession_idやtool_inputを記録するため、入力を先読みする
+	activeDecisionLog = config.DecisionLog
+	if activeDecisionLog != nil {
+		_, _ = prefetchInput()
+	}
+
+	// 他のユーザーが書き換えられる設定ファイルやスクリプトは任意コマンドの実行につながる
+	if err := checkFilePermissions(config, eventType); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
 
-			// Marshal JSON with 2-space indent
-			jsonBytes, err := json.MarshalIndent(output, "", "  ")
-			if err != nil {
-				// Marshal failure should not be fatal - output minimal valid JSON and exit 0
-				fmt.Fprintf(os.Stderr, "Warning: Error marshaling JSON: %v\n", err)
-				// Fallback to minimal valid output
-				fallbackOutput := SessionStartOutput{
-					Continue: false,
+	if eventType == SessionStart {
+		// SessionStart special handling with JSON output
+		output, err := RunSessionStartHooks(config)
+		if err != nil {
+			// Log error to stderr
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			// Ensure output has continue field and hookSpecificOutput even on error (requirement 1.4)
+			if output == nil {
+				output = &SessionStartOutput{
+					Continue:      false,
+					SystemMessage: fmt.Sprintf("Failed to process SessionStart: %v", err),
 					HookSpecificOutput: &SessionStartHookSpecificOutput{
 						HookEventName: "SessionStart",
 					},
-					SystemMessage: fmt.Sprintf("Failed to marshal output: %v", err),
 				}
-				jsonBytes, _ = json.MarshalIndent(fallbackOutput, "", "  ")
 			}
+		}
 
-			// Validate final JSON output against schema (non-functional requirement)
-			if err := validateSessionStartOutput(jsonBytes); err != nil {
-				// Validation failure should not be fatal - log warning and continue
-				fmt.Fprintf(os.Stderr, "Warning: Final JSON output validation failed: %v\n", err)
-			}
+		// Marshal JSON (2-space indent unless output_format: compact)
+		jsonBytes, err := marshalOutput(config.OutputFormat, output)
+		if err != nil {
+			// Marshal failure should not be fatal - output minimal valid JSON and exit 0
+			fmt.Fprintf(os.Stderr, "Warning: Error marshaling JSON: %v\n", err)
+			// Fallback to minimal valid output
+			fallbackOutput := SessionStartOutput{
+				Continue: false,
+				HookSpecificOutput: &SessionStartHookSpecificOutput{
+					HookEventName: "SessionStart",
+				},
+				SystemMessage: fmt.Sprintf("Failed to marshal output: %v", err),
+			}
+			jsonBytes, _ = marshalOutput(config.OutputFormat, fallbackOutput)
+		}
 
-			// Output JSON to stdout
-			fmt.Println(string(jsonBytes))
-			// Always exit 0 for SessionStart (continue field controls behavior)
-			os.Exit(0)
-		}
-
-		if HookEventType(*eventType) == UserPromptSubmit {
-			// UserPromptSubmit special handling with JSON output
-			output, err := RunUserPromptSubmitHooks(config)
-			if err != nil {
-				// Log error to stderr
-				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
-				// Ensure output has decision field and hookSpecificOutput even on error
-				if output == nil {
-					output = &UserPromptSubmitOutput{
-						Continue:      true,
-						Decision:      "block",
-						SystemMessage: fmt.Sprintf("Failed to process UserPromptSubmit: %v", err),
-						HookSpecificOutput: &UserPromptSubmitHookSpecificOutput{
-							HookEventName: "UserPromptSubmit",
-						},
-					}
-				}
-			}
+		// Validate final JSON output against schema (non-functional requirement)
+		if err := validateSessionStartOutput(jsonBytes); err != nil {
+			// Validation failure should not be fatal - log warning and continue
+			fmt.Fprintf(os.Stderr, "Warning: Final JSON output validation failed: %v\n", err)
+		}
 
-			// Marshal JSON with 2-space indent
-			jsonBytes, err := json.MarshalIndent(output, "", "  ")
-			if err != nil {
-				// Marshal failure should not be fatal - output minimal valid JSON and exit 0
-				fmt.Fprintf(os.Stderr, "Warning: Error marshaling JSON: %v\n", err)
-				// Fallback to minimal valid output
-				fallbackOutput := UserPromptSubmitOutput{
-					Continue: true,
-					Decision: "block",
+		// Output JSON to stdout (or translate it to an exit code with -output exitcode)
+		if err := writeHookOutput(os.Stdout, SessionStart, jsonBytes); err != nil {
+			return reportError(err)
+		}
+		// Always exit 0 for SessionStart with JSON output (continue field controls behavior)
+		return 0
+	}
+
+	if eventType == UserPromptSubmit {
+		// UserPromptSubmit special handling with JSON output
+		output, err := RunUserPromptSubmitHooks(config)
+		if err != nil {
+			// Log error to stderr
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			// Ensure output has decision field and hookSpecificOutput even on error
+			if output == nil {
+				output = &UserPromptSubmitOutput{
+					Continue:      true,
+					Decision:      "block",
+					SystemMessage: fmt.Sprintf("Failed to process UserPromptSubmit: %v", err),
 					HookSpecificOutput: &UserPromptSubmitHookSpecificOutput{
 						HookEventName: "UserPromptSubmit",
 					},
-					SystemMessage: fmt.Sprintf("Failed to marshal output: %v", err),
 				}
-				jsonBytes, _ = json.MarshalIndent(fallbackOutput, "", "  ")
 			}
+		}
 
-			// Validate final JSON output against schema (non-functional requirement)
-			if err := validateUserPromptSubmitOutput(jsonBytes); err != nil {
-				// Validation failure should not be fatal - log warning and continue
-				fmt.Fprintf(os.Stderr, "Warning: Final JSON output validation failed: %v\n", err)
-			}
+		// Marshal JSON (2-space indent unless output_format: compact)
+		jsonBytes, err := marshalOutput(config.OutputFormat, output)
+		if err != nil {
+			// Marshal failure should not be fatal - output minimal valid JSON and exit 0
+			fmt.Fprintf(os.Stderr, "Warning: Error marshaling JSON: %v\n", err)
+			// Fallback to minimal valid output
+			fallbackOutput := UserPromptSubmitOutput{
+				Continue: true,
+				Decision: "block",
+				HookSpecificOutput: &UserPromptSubmitHookSpecificOutput{
+					HookEventName: "UserPromptSubmit",
+				},
+				SystemMessage: fmt.Sprintf("Failed to marshal output: %v", err),
+			}
+			jsonBytes, _ = marshalOutput(config.OutputFormat, fallbackOutput)
+		}
 
-			// Output JSON to stdout
-			fmt.Println(string(jsonBytes))
-			// Always exit 0 for UserPromptSubmit (decision field controls behavior)
-			os.Exit(0)
-		}
-
-		if HookEventType(*eventType) == PreToolUse {
-			// PreToolUse special handling with JSON output
-			output, err := RunPreToolUseHooks(config)
-			if err != nil {
-				// Log error to stderr
-				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
-				// Ensure output has hookSpecificOutput even on error
-				if output == nil {
-					output = &PreToolUseOutput{
-						Continue:      true,
-						SystemMessage: fmt.Sprintf("Failed to process PreToolUse: %v", err),
-						HookSpecificOutput: &PreToolUseHookSpecificOutput{
-							HookEventName:      "PreToolUse",
-							PermissionDecision: "deny",
-						},
-					}
-				}
-			}
+		// Validate final JSON output against schema (non-functional requirement)
+		if err := validateUserPromptSubmitOutput(jsonBytes); err != nil {
+			// Validation failure should not be fatal - log warning and continue
+			fmt.Fprintf(os.Stderr, "Warning: Final JSON output validation failed: %v\n", err)
+		}
 
-			// Marshal JSON with 2-space indent
-			jsonBytes, err := json.MarshalIndent(output, "", "  ")
-			if err != nil {
-				// Marshal failure should not be fatal - output minimal valid JSON and exit 0
-				fmt.Fprintf(os.Stderr, "Warning: Error marshaling JSON: %v\n", err)
-				// Fallback to minimal valid output
-				fallbackOutput := PreToolUseOutput{
-					Continue: true,
+		// Output JSON to stdout (or translate it to an exit code with -output exitcode)
+		if err := writeHookOutput(os.Stdout, UserPromptSubmit, jsonBytes); err != nil {
+			return reportError(err)
+		}
+		// Always exit 0 for UserPromptSubmit with JSON output (decision field controls behavior)
+		return 0
+	}
+
+	if eventType == PreToolUse {
+		// PreToolUse special handling with JSON output
+		output, err := RunPreToolUseHooks(config)
+		if err != nil {
+			// Log error to stderr
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			// Ensure output has hookSpecificOutput even on error
+			if output == nil {
+				output = &PreToolUseOutput{
+					Continue:      true,
+					SystemMessage: fmt.Sprintf("Failed to process PreToolUse: %v", err),
 					HookSpecificOutput: &PreToolUseHookSpecificOutput{
 						HookEventName:      "PreToolUse",
 						PermissionDecision: "deny",
 					},
-					SystemMessage: fmt.Sprintf("Failed to marshal output: %v", err),
 				}
-				jsonBytes, _ = json.MarshalIndent(fallbackOutput, "", "  ")
 			}
+		}
 
-			// Validate final JSON output against schema (non-functional requirement)
-			if err := validatePreToolUseOutput(jsonBytes); err != nil {
-				// Validation failure should not be fatal - log warning and continue
-				fmt.Fprintf(os.Stderr, "Warning: Final JSON output validation failed: %v\n", err)
-			}
+		// Marshal JSON (2-space indent unless output_format: compact)
+		jsonBytes, err := marshalOutput(config.OutputFormat, output)
+		if err != nil {
+			// Marshal failure should not be fatal - output minimal valid JSON and exit 0
+			fmt.Fprintf(os.Stderr, "Warning: Error marshaling JSON: %v\n", err)
+			// Fallback to minimal valid output
+			fallbackOutput := PreToolUseOutput{
+				Continue: true,
+				HookSpecificOutput: &PreToolUseHookSpecificOutput{
+					HookEventName:      "PreToolUse",
+					PermissionDecision: "deny",
+				},
+				SystemMessage: fmt.Sprintf("Failed to marshal output: %v", err),
+			}
+			jsonBytes, _ = marshalOutput(config.OutputFormat, fallbackOutput)
+		}
 
-			// Output JSON to stdout
-			fmt.Println(string(jsonBytes))
-			// Always exit 0 for PreToolUse (permissionDecision field controls behavior)
-			os.Exit(0)
-		}
-
-		if HookEventType(*eventType) == Stop {
-			// Stop special handling with JSON output
-			output, err := RunStopHooks(config)
-			if err != nil {
-				// Log error to stderr
-				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
-				if output == nil {
-					output = &StopOutput{
-						Continue:      true,
-						Decision:      "block",
-						Reason:        fmt.Sprintf("Failed to process Stop: %v", err),
-						SystemMessage: fmt.Sprintf("Failed to process Stop: %v", err),
-					}
-				} else {
-					// fail-safe: エラー時はdecisionを"block"に強制
-					output.Decision = "block"
-					if output.Reason == "" {
-						output.Reason = fmt.Sprintf("Failed to process Stop: %v", err)
-					}
-					errMsg := fmt.Sprintf("Failed to process Stop: %v", err)
-					if output.SystemMessage != "" {
-						output.SystemMessage += "\n" + errMsg
-					} else {
-						output.SystemMessage = errMsg
-					}
-				}
-			}
+		// Validate final JSON output against schema (non-functional requirement)
+		if err := validatePreToolUseOutput(jsonBytes); err != nil {
+			// Validation failure should not be fatal - log warning and continue
+			fmt.Fprintf(os.Stderr, "Warning: Final JSON output validation failed: %v\n", err)
+		}
+
+		// Output JSON to stdout (or translate it to an exit code with -output exitcode)
+		if err := writeHookOutput(os.Stdout, PreToolUse, jsonBytes); err != nil {
+			return reportError(err)
+		}
+		// Always exit 0 for PreToolUse with JSON output (permissionDecision field controls behavior)
+		return 0
+	}
 
-			// Marshal JSON with 2-space indent
-			jsonBytes, err := json.MarshalIndent(output, "", "  ")
-			if err != nil {
-				// Marshal failure should not be fatal - output minimal valid JSON and exit 0
-				fmt.Fprintf(os.Stderr, "Warning: Error marshaling JSON: %v\n", err)
-				fallbackOutput := StopOutput{
+	if eventType == Stop {
+		// Stop special handling with JSON output
+		output, err := RunStopHooks(config)
+		if err != nil {
+			// Log error to stderr
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			if output == nil {
+				output = &StopOutput{
 					Continue:      true,
 					Decision:      "block",
-					Reason:        fmt.Sprintf("Failed to marshal output: %v", err),
-					SystemMessage: fmt.Sprintf("Failed to marshal output: %v", err),
+					Reason:        fmt.Sprintf("Failed to process Stop: %v", err),
+					SystemMessage: fmt.Sprintf("Failed to process Stop: %v", err),
 				}
-				jsonBytes, _ = json.MarshalIndent(fallbackOutput, "", "  ")
-			}
-
-			// Validate final JSON output against schema (non-functional requirement)
-			if err := validateStopOutput(jsonBytes); err != nil {
-				// Validation failure should not be fatal - log warning and continue
-				fmt.Fprintf(os.Stderr, "Warning: Final JSON output validation failed: %v\n", err)
-			}
-
-			// Output JSON to stdout
-			fmt.Println(string(jsonBytes))
-			// Always exit 0 for Stop (decision field controls behavior)
-			os.Exit(0)
-		}
-
-		if HookEventType(*eventType) == SubagentStop {
-			// SubagentStop special handling with JSON output
-			output, err := RunSubagentStopHooks(config)
-			if err != nil {
-				// Log error to stderr
-				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
-				if output == nil {
-					output = &SubagentStopOutput{
-						Continue:      true,
-						Decision:      "block",
-						Reason:        fmt.Sprintf("Failed to process SubagentStop: %v", err),
-						SystemMessage: fmt.Sprintf("Failed to process SubagentStop: %v", err),
-					}
+			} else {
+				// fail-safe: エラー時はdecisionを"block"に強制
+				output.Decision = "block"
+				if output.Reason == "" {
+					output.Reason = fmt.Sprintf("Failed to process Stop: %v", err)
+				}
+				errMsg := fmt.Sprintf("Failed to process Stop: %v", err)
+				if output.SystemMessage != "" {
+					output.SystemMessage += "\n" + errMsg
 				} else {
-					// fail-safe: エラー時はdecisionを"block"に強制
-					output.Decision = "block"
-					if output.Reason == "" {
-						output.Reason = fmt.Sprintf("Failed to process SubagentStop: %v", err)
-					}
-					errMsg := fmt.Sprintf("Failed to process SubagentStop: %v", err)
-					if output.SystemMessage != "" {
-						output.SystemMessage += "\n" + errMsg
-					} else {
-						output.SystemMessage = errMsg
-					}
+					output.SystemMessage = errMsg
 				}
 			}
+		}
+
+		// Marshal JSON (2-space indent unless output_format: compact)
+		jsonBytes, err := marshalOutput(config.OutputFormat, output)
+		if err != nil {
+			// Marshal failure should not be fatal - output minimal valid JSON and exit 0
+			fmt.Fprintf(os.Stderr, "Warning: Error marshaling JSON: %v\n", err)
+			fallbackOutput := StopOutput{
+				Continue:      true,
+				Decision:      "block",
+				Reason:        fmt.Sprintf("Failed to marshal output: %v", err),
+				SystemMessage: fmt.Sprintf("Failed to marshal output: %v", err),
+			}
+			jsonBytes, _ = marshalOutput(config.OutputFormat, fallbackOutput)
+		}
 
-			// Marshal JSON with 2-space indent
-			jsonBytes, err := json.MarshalIndent(output, "", "  ")
-			if err != nil {
-				// Marshal failure should not be fatal - output minimal valid JSON and exit 0
-				fmt.Fprintf(os.Stderr, "Warning: Error marshaling JSON: %v\n", err)
-				fallbackOutput := SubagentStopOutput{
+		// Validate final JSON output against schema (non-functional requirement)
+		if err := validateStopOutput(jsonBytes); err != nil {
+			// Validation failure should not be fatal - log warning and continue
+			fmt.Fprintf(os.Stderr, "Warning: Final JSON output validation failed: %v\n", err)
+		}
+
+		// Output JSON to stdout (or translate it to an exit code with -output exitcode)
+		if err := writeHookOutput(os.Stdout, Stop, jsonBytes); err != nil {
+			return reportError(err)
+		}
+		// Always exit 0 for Stop with JSON output (decision field controls behavior)
+		return 0
+	}
+
+	if eventType == SubagentStop {
+		// SubagentStop special handling with JSON output
+		output, err := RunSubagentStopHooks(config)
+		if err != nil {
+			// Log error to stderr
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			if output == nil {
+				output = &SubagentStopOutput{
 					Continue:      true,
 					Decision:      "block",
-					Reason:        fmt.Sprintf("Failed to marshal output: %v", err),
-					SystemMessage: fmt.Sprintf("Failed to marshal output: %v", err),
+					Reason:        fmt.Sprintf("Failed to process SubagentStop: %v", err),
+					SystemMessage: fmt.Sprintf("Failed to process SubagentStop: %v", err),
 				}
-				jsonBytes, _ = json.MarshalIndent(fallbackOutput, "", "  ")
-			}
-
-			// Validate final JSON output against schema (non-functional requirement)
-			if err := validateSubagentStopOutput(jsonBytes); err != nil {
-				// Validation failure should not be fatal - log warning and continue
-				fmt.Fprintf(os.Stderr, "Warning: Final JSON output validation failed: %v\n", err)
-			}
-
-			// Output JSON to stdout
-			fmt.Println(string(jsonBytes))
-			// Always exit 0 for SubagentStop (decision field controls behavior)
-			os.Exit(0)
-		}
-
-		if HookEventType(*eventType) == PreCompact {
-			// PreCompact special handling with JSON output
-			output, err := RunPreCompactHooks(config)
-			if err != nil {
-				// Log error to stderr
-				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
-				if output == nil {
-					output = &PreCompactOutput{
-						Continue:      true,
-						SystemMessage: fmt.Sprintf("Failed to process PreCompact: %v", err),
-					}
+			} else {
+				// fail-safe: エラー時はdecisionを"block"に強制
+				output.Decision = "block"
+				if output.Reason == "" {
+					output.Reason = fmt.Sprintf("Failed to process SubagentStop: %v", err)
+				}
+				errMsg := fmt.Sprintf("Failed to process SubagentStop: %v", err)
+				if output.SystemMessage != "" {
+					output.SystemMessage += "\n" + errMsg
 				} else {
-					// fail-safe: PreCompact always continue=true, add error to systemMessage
-					errMsg := fmt.Sprintf("Failed to process PreCompact: %v", err)
-					if output.SystemMessage != "" {
-						output.SystemMessage += "\n" + errMsg
-					} else {
-						output.SystemMessage = errMsg
-					}
+					output.SystemMessage = errMsg
 				}
 			}
+		}
+
+		// Marshal JSON (2-space indent unless output_format: compact)
+		jsonBytes, err := marshalOutput(config.OutputFormat, output)
+		if err != nil {
+			// Marshal failure should not be fatal - output minimal valid JSON and exit 0
+			fmt.Fprintf(os.Stderr, "Warning: Error marshaling JSON: %v\n", err)
+			fallbackOutput := SubagentStopOutput{
+				Continue:      true,
+				Decision:      "block",
+				Reason:        fmt.Sprintf("Failed to marshal output: %v", err),
+				SystemMessage: fmt.Sprintf("Failed to marshal output: %v", err),
+			}
+			jsonBytes, _ = marshalOutput(config.OutputFormat, fallbackOutput)
+		}
+
+		// Validate final JSON output against schema (non-functional requirement)
+		if err := validateSubagentStopOutput(jsonBytes); err != nil {
+			// Validation failure should not be fatal - log warning and continue
+			fmt.Fprintf(os.Stderr, "Warning: Final JSON output validation failed: %v\n", err)
+		}
+
+		// Output JSON to stdout (or translate it to an exit code with -output exitcode)
+		if err := writeHookOutput(os.Stdout, SubagentStop, jsonBytes); err != nil {
+			return reportError(err)
+		}
+		// Always exit 0 for SubagentStop with JSON output (decision field controls behavior)
+		return 0
+	}
 
-			// Marshal JSON with 2-space indent
-			jsonBytes, err := json.MarshalIndent(output, "", "  ")
-			if err != nil {
-				// Marshal failure should not be fatal - output minimal valid JSON and exit 0
-				fmt.Fprintf(os.Stderr, "Warning: Error marshaling JSON: %v\n", err)
-				fallbackOutput := PreCompactOutput{
+	if eventType == PreCompact {
+		// PreCompact special handling with JSON output
+		output, err := RunPreCompactHooks(config)
+		if err != nil {
+			// Log error to stderr
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			if output == nil {
+				output = &PreCompactOutput{
 					Continue:      true,
-					SystemMessage: fmt.Sprintf("Failed to marshal output: %v", err),
+					SystemMessage: fmt.Sprintf("Failed to process PreCompact: %v", err),
+				}
+			} else {
+				// fail-safe: PreCompact always continue=true, add error to systemMessage
+				errMsg := fmt.Sprintf("Failed to process PreCompact: %v", err)
+				if output.SystemMessage != "" {
+					output.SystemMessage += "\n" + errMsg
+				} else {
+					output.SystemMessage = errMsg
 				}
-				jsonBytes, _ = json.MarshalIndent(fallbackOutput, "", "  ")
 			}
+		}
 
-			// Validate final JSON output against schema (non-functional requirement)
-			if err := validatePreCompactOutput(jsonBytes); err != nil {
-				// Validation failure should not be fatal - log warning and continue
-				fmt.Fprintf(os.Stderr, "Warning: Final JSON output validation failed: %v\n", err)
+		// Marshal JSON (2-space indent unless output_format: compact)
+		jsonBytes, err := marshalOutput(config.OutputFormat, output)
+		if err != nil {
+			// Marshal failure should not be fatal - output minimal valid JSON and exit 0
+			fmt.Fprintf(os.Stderr, "Warning: Error marshaling JSON: %v\n", err)
+			fallbackOutput := PreCompactOutput{
+				Continue:      true,
+				SystemMessage: fmt.Sprintf("Failed to marshal output: %v", err),
 			}
+			jsonBytes, _ = marshalOutput(config.OutputFormat, fallbackOutput)
+		}
 
-			// Output JSON to stdout
-			fmt.Println(string(jsonBytes))
-			// Always exit 0 for PreCompact (compaction cannot be blocked)
-			os.Exit(0)
-		}
-
-		if HookEventType(*eventType) == SessionEnd {
-			// SessionEnd special handling with JSON output
-			output, err := RunSessionEndHooks(config)
-			if err != nil {
-				// Log error to stderr
-				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
-				if output == nil {
-					output = &SessionEndOutput{
-						Continue:      true,
-						SystemMessage: fmt.Sprintf("Failed to process SessionEnd: %v", err),
-					}
-				} else {
-					// fail-safe: SessionEnd always continue=true, add error to systemMessage
-					errMsg := fmt.Sprintf("Failed to process SessionEnd: %v", err)
-					if output.SystemMessage != "" {
-						output.SystemMessage += "\n" + errMsg
-					} else {
-						output.SystemMessage = errMsg
-					}
-				}
-			}
+		// Validate final JSON output against schema (non-functional requirement)
+		if err := validatePreCompactOutput(jsonBytes); err != nil {
+			// Validation failure should not be fatal - log warning and continue
+			fmt.Fprintf(os.Stderr, "Warning: Final JSON output validation failed: %v\n", err)
+		}
+
+		// Output JSON to stdout (or translate it to an exit code with -output exitcode)
+		if err := writeHookOutput(os.Stdout, PreCompact, jsonBytes); err != nil {
+			return reportError(err)
+		}
+		// Always exit 0 for PreCompact with JSON output (compaction cannot be blocked)
+		return 0
+	}
 
-			// Marshal JSON with 2-space indent
-			jsonBytes, err := json.MarshalIndent(output, "", "  ")
-			if err != nil {
-				// Marshal failure should not be fatal - output minimal valid JSON and exit 0
-				fmt.Fprintf(os.Stderr, "Warning: Error marshaling JSON: %v\n", err)
-				fallbackOutput := SessionEndOutput{
+	if eventType == SessionEnd {
+		// SessionEnd special handling with JSON output
+		output, err := RunSessionEndHooks(config)
+		if err != nil {
+			// Log error to stderr
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			if output == nil {
+				output = &SessionEndOutput{
 					Continue:      true,
-					SystemMessage: fmt.Sprintf("Failed to marshal output: %v", err),
+					SystemMessage: fmt.Sprintf("Failed to process SessionEnd: %v", err),
+				}
+			} else {
+				// fail-safe: SessionEnd always continue=true, add error to systemMessage
+				errMsg := fmt.Sprintf("Failed to process SessionEnd: %v", err)
+				if output.SystemMessage != "" {
+					output.SystemMessage += "\n" + errMsg
+				} else {
+					output.SystemMessage = errMsg
 				}
-				jsonBytes, _ = json.MarshalIndent(fallbackOutput, "", "  ")
 			}
+		}
 
-			// Validate final JSON output against schema (non-functional requirement)
-			if err := validateSessionEndOutput(jsonBytes); err != nil {
-				// Validation failure should not be fatal - log warning and continue
-				fmt.Fprintf(os.Stderr, "Warning: Final JSON output validation failed: %v\n", err)
+		// Marshal JSON (2-space indent unless output_format: compact)
+		jsonBytes, err := marshalOutput(config.OutputFormat, output)
+		if err != nil {
+			// Marshal failure should not be fatal - output minimal valid JSON and exit 0
+			fmt.Fprintf(os.Stderr, "Warning: Error marshaling JSON: %v\n", err)
+			fallbackOutput := SessionEndOutput{
+				Continue:      true,
+				SystemMessage: fmt.Sprintf("Failed to marshal output: %v", err),
 			}
+			jsonBytes, _ = marshalOutput(config.OutputFormat, fallbackOutput)
+		}
 
-			// Output JSON to stdout
-			fmt.Println(string(jsonBytes))
-			// Always exit 0 for SessionEnd (session end cannot be blocked)
-			os.Exit(0)
-		}
-		if HookEventType(*eventType) == PostToolUse {
-			// PostToolUse special handling with JSON output
-			output, err := RunPostToolUseHooks(config)
-			if err != nil {
-				// Log error to stderr
-				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
-				if output == nil {
-					output = &PostToolUseOutput{
-						Continue:      true,
-						Decision:      "block",
-						Reason:        fmt.Sprintf("Failed to process PostToolUse: %v", err),
-						SystemMessage: fmt.Sprintf("Failed to process PostToolUse: %v", err),
-						HookSpecificOutput: &PostToolUseHookSpecificOutput{
-							HookEventName: "PostToolUse",
-						},
-					}
-				} else {
-					// fail-safe: エラー時はdecisionを"block"に強制
-					output.Decision = "block"
-					if output.Reason == "" {
-						output.Reason = fmt.Sprintf("Failed to process PostToolUse: %v", err)
-					}
-					errMsg := fmt.Sprintf("Failed to process PostToolUse: %v", err)
-					if output.SystemMessage != "" {
-						output.SystemMessage += "\n" + errMsg
-					} else {
-						output.SystemMessage = errMsg
-					}
-				}
-			}
+		// Validate final JSON output against schema (non-functional requirement)
+		if err := validateSessionEndOutput(jsonBytes); err != nil {
+			// Validation failure should not be fatal - log warning and continue
+			fmt.Fprintf(os.Stderr, "Warning: Final JSON output validation failed: %v\n", err)
+		}
 
-			// Marshal JSON with 2-space indent
-			jsonBytes, err := json.MarshalIndent(output, "", "  ")
-			if err != nil {
-				// Marshal failure should not be fatal - output minimal valid JSON and exit 0
-				fmt.Fprintf(os.Stderr, "Warning: Error marshaling JSON: %v\n", err)
-				fallbackOutput := PostToolUseOutput{
+		// Output JSON to stdout (or translate it to an exit code with -output exitcode)
+		if err := writeHookOutput(os.Stdout, SessionEnd, jsonBytes); err != nil {
+			return reportError(err)
+		}
+		// Always exit 0 for SessionEnd with JSON output (session end cannot be blocked)
+		return 0
+	}
+	if eventType == PostToolUse {
+		// PostToolUse special handling with JSON output
+		output, err := RunPostToolUseHooks(config)
+		if err != nil {
+			// Log error to stderr
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			if output == nil {
+				output = &PostToolUseOutput{
 					Continue:      true,
 					Decision:      "block",
-					Reason:        fmt.Sprintf("Failed to marshal output: %v", err),
-					SystemMessage: fmt.Sprintf("Failed to marshal output: %v", err),
+					Reason:        fmt.Sprintf("Failed to process PostToolUse: %v", err),
+					SystemMessage: fmt.Sprintf("Failed to process PostToolUse: %v", err),
 					HookSpecificOutput: &PostToolUseHookSpecificOutput{
 						HookEventName: "PostToolUse",
 					},
 				}
-				jsonBytes, _ = json.MarshalIndent(fallbackOutput, "", "  ")
+			} else {
+				// fail-safe: エラー時はdecisionを"block"に強制
+				output.Decision = "block"
+				if output.Reason == "" {
+					output.Reason = fmt.Sprintf("Failed to process PostToolUse: %v", err)
+				}
+				errMsg := fmt.Sprintf("Failed to process PostToolUse: %v", err)
+				if output.SystemMessage != "" {
+					output.SystemMessage += "\n" + errMsg
+				} else {
+					output.SystemMessage = errMsg
+				}
 			}
+		}
 
-			// Validate final JSON output against schema (non-functional requirement)
-			if err := validatePostToolUseOutput(jsonBytes); err != nil {
-				// Validation failure should not be fatal - log warning and continue
-				fmt.Fprintf(os.Stderr, "Warning: Final JSON output validation failed: %v\n", err)
-			}
+		// Marshal JSON (2-space indent unless output_format: compact)
+		jsonBytes, err := marshalOutput(config.OutputFormat, output)
+		if err != nil {
+			// Marshal failure should not be fatal - output minimal valid JSON and exit 0
+			fmt.Fprintf(os.Stderr, "Warning: Error marshaling JSON: %v\n", err)
+			fallbackOutput := PostToolUseOutput{
+				Continue:      true,
+				Decision:      "block",
+				Reason:        fmt.Sprintf("Failed to marshal output: %v", err),
+				SystemMessage: fmt.Sprintf("Failed to marshal output: %v", err),
+				HookSpecificOutput: &PostToolUseHookSpecificOutput{
+					HookEventName: "PostToolUse",
+				},
+			}
+			jsonBytes, _ = marshalOutput(config.OutputFormat, fallbackOutput)
+		}
 
-			// Output JSON to stdout
-			fmt.Println(string(jsonBytes))
-			// Always exit 0 for PostToolUse (decision field controls behavior)
-			os.Exit(0)
-		}
-
-		if HookEventType(*eventType) == Notification {
-			// Notification special handling with JSON output
-			output, err := RunNotificationHooks(config)
-			if err != nil {
-				// Log error to stderr
-				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
-				// Ensure output has hookSpecificOutput even on error
-				if output == nil {
-					output = &NotificationOutput{
-						Continue:      true,
-						SystemMessage: fmt.Sprintf("Failed to process Notification: %v", err),
-						HookSpecificOutput: &NotificationHookSpecificOutput{
-							HookEventName: "Notification",
-						},
-					}
-				}
-			}
+		// Validate final JSON output against schema (non-functional requirement)
+		if err := validatePostToolUseOutput(jsonBytes); err != nil {
+			// Validation failure should not be fatal - log warning and continue
+			fmt.Fprintf(os.Stderr, "Warning: Final JSON output validation failed: %v\n", err)
+		}
 
-			// Marshal JSON with 2-space indent
-			jsonBytes, err := json.MarshalIndent(output, "", "  ")
-			if err != nil {
-				// Marshal failure should not be fatal - output minimal valid JSON and exit 0
-				fmt.Fprintf(os.Stderr, "Warning: Error marshaling JSON: %v\n", err)
-				// Fallback to minimal valid output
-				fallbackOutput := NotificationOutput{
-					Continue: true,
+		// Output JSON to stdout (or translate it to an exit code with -output exitcode)
+		if err := writeHookOutput(os.Stdout, PostToolUse, jsonBytes); err != nil {
+			return reportError(err)
+		}
+		// Always exit 0 for PostToolUse with JSON output (decision field controls behavior)
+		return 0
+	}
+
+	if eventType == Notification {
+		// Notification special handling with JSON output
+		output, err := RunNotificationHooks(config)
+		if err != nil {
+			// Log error to stderr
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			// Ensure output has hookSpecificOutput even on error
+			if output == nil {
+				output = &NotificationOutput{
+					Continue:      true,
+					SystemMessage: fmt.Sprintf("Failed to process Notification: %v", err),
 					HookSpecificOutput: &NotificationHookSpecificOutput{
 						HookEventName: "Notification",
 					},
-					SystemMessage: fmt.Sprintf("Failed to marshal output: %v", err),
 				}
-				jsonBytes, _ = json.MarshalIndent(fallbackOutput, "", "  ")
 			}
+		}
 
-			// Validate final JSON output against schema (non-functional requirement)
-			if err := validateNotificationOutput(jsonBytes); err != nil {
-				// Validation failure should not be fatal - log warning and continue
-				fmt.Fprintf(os.Stderr, "Warning: Final JSON output validation failed: %v\n", err)
-			}
+		// Marshal JSON (2-space indent unless output_format: compact)
+		jsonBytes, err := marshalOutput(config.OutputFormat, output)
+		if err != nil {
+			// Marshal failure should not be fatal - output minimal valid JSON and exit 0
+			fmt.Fprintf(os.Stderr, "Warning: Error marshaling JSON: %v\n", err)
+			// Fallback to minimal valid output
+			fallbackOutput := NotificationOutput{
+				Continue: true,
+				HookSpecificOutput: &NotificationHookSpecificOutput{
+					HookEventName: "Notification",
+				},
+				SystemMessage: fmt.Sprintf("Failed to marshal output: %v", err),
+			}
+			jsonBytes, _ = marshalOutput(config.OutputFormat, fallbackOutput)
+		}
 
-			// Output JSON to stdout
-			fmt.Println(string(jsonBytes))
-			// Always exit 0 for Notification (continue field controls behavior)
-			os.Exit(0)
-		}
-
-		if HookEventType(*eventType) == SubagentStart {
-			// SubagentStart special handling with JSON output
-			output, err := RunSubagentStartHooks(config)
-			if err != nil {
-				// Log error to stderr
-				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
-				// Ensure output has hookSpecificOutput even on error
-				if output == nil {
-					output = &SubagentStartOutput{
-						Continue:      true,
-						SystemMessage: fmt.Sprintf("Failed to process SubagentStart: %v", err),
-						HookSpecificOutput: &SubagentStartHookSpecificOutput{
-							HookEventName: "SubagentStart",
-						},
-					}
-				}
-			}
+		// Validate final JSON output against schema (non-functional requirement)
+		if err := validateNotificationOutput(jsonBytes); err != nil {
+			// Validation failure should not be fatal - log warning and continue
+			fmt.Fprintf(os.Stderr, "Warning: Final JSON output validation failed: %v\n", err)
+		}
+
+		// Output JSON to stdout (or translate it to an exit code with -output exitcode)
+		if err := writeHookOutput(os.Stdout, Notification, jsonBytes); err != nil {
+			return reportError(err)
+		}
+		// Always exit 0 for Notification with JSON output (continue field controls behavior)
+		return 0
+	}
 
-			// Marshal JSON with 2-space indent
-			jsonBytes, err := json.MarshalIndent(output, "", "  ")
-			if err != nil {
-				// Marshal failure should not be fatal - output minimal valid JSON and exit 0
-				fmt.Fprintf(os.Stderr, "Warning: Error marshaling JSON: %v\n", err)
-				// Fallback to minimal valid output
-				fallbackOutput := SubagentStartOutput{
-					Continue: true,
+	if eventType == SubagentStart {
+		// SubagentStart special handling with JSON output
+		output, err := RunSubagentStartHooks(config)
+		if err != nil {
+			// Log error to stderr
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			// Ensure output has hookSpecificOutput even on error
+			if output == nil {
+				output = &SubagentStartOutput{
+					Continue:      true,
+					SystemMessage: fmt.Sprintf("Failed to process SubagentStart: %v", err),
 					HookSpecificOutput: &SubagentStartHookSpecificOutput{
 						HookEventName: "SubagentStart",
 					},
-					SystemMessage: fmt.Sprintf("Failed to marshal output: %v", err),
 				}
-				jsonBytes, _ = json.MarshalIndent(fallbackOutput, "", "  ")
 			}
+		}
 
-			// Validate final JSON output against schema (non-functional requirement)
-			if err := validateSubagentStartOutput(jsonBytes); err != nil {
-				// Validation failure should not be fatal - log warning and continue
-				fmt.Fprintf(os.Stderr, "Warning: Final JSON output validation failed: %v\n", err)
-			}
+		// Marshal JSON (2-space indent unless output_format: compact)
+		jsonBytes, err := marshalOutput(config.OutputFormat, output)
+		if err != nil {
+			// Marshal failure should not be fatal - output minimal valid JSON and exit 0
+			fmt.Fprintf(os.Stderr, "Warning: Error marshaling JSON: %v\n", err)
+			// Fallback to minimal valid output
+			fallbackOutput := SubagentStartOutput{
+				Continue: true,
+				HookSpecificOutput: &SubagentStartHookSpecificOutput{
+					HookEventName: "SubagentStart",
+				},
+				SystemMessage: fmt.Sprintf("Failed to marshal output: %v", err),
+			}
+			jsonBytes, _ = marshalOutput(config.OutputFormat, fallbackOutput)
+		}
 
-			// Output JSON to stdout
-			fmt.Println(string(jsonBytes))
-			// Always exit 0 for SubagentStart (continue field controls behavior)
-			os.Exit(0)
+		// Validate final JSON output against schema (non-functional requirement)
+		if err := validateSubagentStartOutput(jsonBytes); err != nil {
+			// Validation failure should not be fatal - log warning and continue
+			fmt.Fprintf(os.Stderr, "Warning: Final JSON output validation failed: %v\n", err)
 		}
 
-		if HookEventType(*eventType) == PermissionRequest {
-			// PermissionRequest special handling with JSON output
-			err := RunPermissionRequestHooks(config)
-			// Always exit 0 (error handling is done inside RunPermissionRequestHooks)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
-			}
-			os.Exit(0)
+		// Output JSON to stdout (or translate it to an exit code with -output exitcode)
+		if err := writeHookOutput(os.Stdout, SubagentStart, jsonBytes); err != nil {
+			return reportError(err)
 		}
-		err = runHooks(config, HookEventType(*eventType))
-	case "dry-run":
-		err = dryRunHooks(config, HookEventType(*eventType))
-	default:
-		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", *command)
-		os.Exit(1)
+		// Always exit 0 for SubagentStart with JSON output (continue field controls behavior)
+		return 0
 	}
 
-	// ExitError の場合は特別な処理
-	if err != nil {
+	if eventType == PermissionRequest {
+		// PermissionRequest special handling with JSON output
+		err := RunPermissionRequestHooks(config)
+		// Always exit 0 (error handling is done inside RunPermissionRequestHooks) unless -output exitcode blocks
 		var exitErr *ExitError
-		// errors.Joinでラップされた場合でもExitErrorを取り出せるようにerrors.Asを使用
 		if errors.As(err, &exitErr) {
-			// ExitError の場合は適切な出力先に出力して指定のコードで終了
-			// err.Error()を使ってラップされた全メッセージを出力
-			if exitErr.Stderr {
-				fmt.Fprintf(os.Stderr, "%s\n", err.Error())
-			} else {
-				fmt.Println(err.Error())
-			}
-			os.Exit(exitErr.Code)
-		} else {
-			// 通常のエラーの場合
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			return reportError(err)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		return 0
+	}
+	if err := runHooks(config, eventType); err != nil {
+		return reportError(err)
+	}
+	return 0
+}
+
+// redirectStdio replaces os.Stdin / os.Stdout with the given files so that scripts and agents
+// that can't pipe stdio can still drive run/dry-run. Empty paths leave the stream untouched.
+// The files are intentionally left open until the process exits (main always exits via os.Exit).
+func redirectStdio(stdinFile, outputFile string) error {
+	if stdinFile != "" {
+		f, err := os.Open(stdinFile)
+		if err != nil {
+			return fmt.Errorf("failed to open stdin file: %w", err)
+		}
+		os.Stdin = f
+	}
+
+	if outputFile != "" {
+		f, err := os.Create(outputFile)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
 		}
+		os.Stdout = f
 	}
+	return nil
 }
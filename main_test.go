@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bytes"
 	"os"
 	"strings"
 	"testing"
@@ -34,19 +33,7 @@ func TestDryRunHooks_UnknownEventType(t *testing.T) {
 }
 
 func TestDryRunHooks_Success(t *testing.T) {
-	// 標準入力をバックアップして復元
-	oldStdin := os.Stdin
-	defer func() { os.Stdin = oldStdin }()
-
-	// 標準出力をキャプチャ
-	oldStdout := os.Stdout
-	r2, w2, _ := os.Pipe()
-	os.Stdout = w2
-
 	// 正常なJSONを標準入力に設定
-	r, w, _ := os.Pipe()
-	os.Stdin = r
-
 	jsonInput := `{
 		"session_id": "test",
 		"transcript_path": "/tmp/test",
@@ -55,10 +42,7 @@ func TestDryRunHooks_Success(t *testing.T) {
 		"tool_input": {"file_path": "test.go"}
 	}`
 
-	go func() {
-		defer func() { _ = w.Close() }()
-		_, _ = w.Write([]byte(jsonInput))
-	}()
+	withStdin(t, jsonInput)
 
 	config := &Config{
 		PreToolUse: []PreToolUseHook{
@@ -71,16 +55,10 @@ func TestDryRunHooks_Success(t *testing.T) {
 		},
 	}
 
-	err := dryRunHooks(config, PreToolUse)
-
-	// 標準出力を復元
-	_ = w2.Close()
-	os.Stdout = oldStdout
-
-	// 出力を読み取り
-	var buf bytes.Buffer
-	_, _ = buf.ReadFrom(r2)
-	output := buf.String()
+	var err error
+	output := captureStdout(t, func() {
+		err = dryRunHooks(config, PreToolUse)
+	})
 
 	if err != nil {
 		t.Errorf("dryRunHooks() error = %v", err)
@@ -90,7 +68,7 @@ func TestDryRunHooks_Success(t *testing.T) {
 		t.Errorf("Expected dry run header in output, got: %q", output)
 	}
 
-	if !strings.Contains(output, "Command: echo test.go") {
+	if !strings.Contains(output, "Command: echo 'test.go'") {
 		t.Errorf("Expected command with replaced variables, got: %q", output)
 	}
 	t.Logf("Full output: %q", output)
@@ -98,14 +76,8 @@ func TestDryRunHooks_Success(t *testing.T) {
 
 // エラーケース: 空の標準入力
 func TestRunHooks_EmptyInput(t *testing.T) {
-	// 標準入力をバックアップして復元
-	oldStdin := os.Stdin
-	defer func() { os.Stdin = oldStdin }()
-
-	// 空の標準入力を設定
-	r, w, _ := os.Pipe()
-	os.Stdin = r
-	_ = w.Close() // すぐに閉じて EOF を発生させる
+	// 空の標準入力を設定（すぐに閉じて EOF を発生させる）
+	withStdin(t, "")
 
 	config := &Config{}
 	err := runHooks(config, PreToolUse)
@@ -117,20 +89,9 @@ func TestRunHooks_EmptyInput(t *testing.T) {
 
 // エラーケース: 部分的なJSON
 func TestRunHooks_PartialJSON(t *testing.T) {
-	// 標準入力をバックアップして復元
-	oldStdin := os.Stdin
-	defer func() { os.Stdin = oldStdin }()
-
-	// 部分的なJSONを標準入力に設定
-	r, w, _ := os.Pipe()
-	os.Stdin = r
-
 	partialJSON := `{"session_id": "test"` // 不完全なJSON
 
-	go func() {
-		defer func() { _ = w.Close() }()
-		_, _ = w.Write([]byte(partialJSON))
-	}()
+	withStdin(t, partialJSON)
 
 	config := &Config{}
 	err := runHooks(config, PreToolUse)
@@ -142,14 +103,6 @@ func TestRunHooks_PartialJSON(t *testing.T) {
 
 // エラーケース: 型が一致しないJSON
 func TestRunHooks_WrongJSONType(t *testing.T) {
-	// 標準入力をバックアップして復元
-	oldStdin := os.Stdin
-	defer func() { os.Stdin = oldStdin }()
-
-	// PreToolUse以外の構造のJSONを標準入力に設定
-	r, w, _ := os.Pipe()
-	os.Stdin = r
-
 	wrongJSON := `{
 		"session_id": "test",
 		"transcript_path": "/tmp/test",
@@ -157,10 +110,7 @@ func TestRunHooks_WrongJSONType(t *testing.T) {
 		"message": "This is notification format"
 	}` // NotificationInput の形式
 
-	go func() {
-		defer func() { _ = w.Close() }()
-		_, _ = w.Write([]byte(wrongJSON))
-	}()
+	withStdin(t, wrongJSON)
 
 	config := &Config{}
 	// PreToolUseとして解釈しようとするが、tool_nameがない
@@ -823,3 +773,60 @@ EOF
 		t.Errorf("SystemMessage = %q, want %q", output.SystemMessage, expectedMsg)
 	}
 }
+
+func TestRedirectStdio(t *testing.T) {
+	oldStdin, oldStdout := os.Stdin, os.Stdout
+	t.Cleanup(func() {
+		os.Stdin, os.Stdout = oldStdin, oldStdout
+	})
+
+	tmpDir := t.TempDir()
+	inputPath := tmpDir + "/input.json"
+	outputPath := tmpDir + "/output.json"
+
+	input := `{"session_id":"s1","transcript_path":"/tmp/t","hook_event_name":"PermissionRequest","tool_name":"Bash","tool_input":{"command":"rm -rf /"}}`
+	if err := os.WriteFile(inputPath, []byte(input), 0644); err != nil {
+		t.Fatalf("Failed to write input file: %v", err)
+	}
+
+	if err := redirectStdio(inputPath, outputPath); err != nil {
+		t.Fatalf("redirectStdio() error = %v", err)
+	}
+
+	config := &Config{
+		PermissionRequest: []PermissionRequestHook{
+			{
+				Matcher: "Bash",
+				Actions: []Action{
+					{Type: "output", Message: "denied: {.tool_input.command}", Behavior: stringPtr("deny")},
+				},
+			},
+		},
+	}
+	if err := RunPermissionRequestHooks(config); err != nil {
+		t.Fatalf("RunPermissionRequestHooks() error = %v", err)
+	}
+
+	got, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	if !strings.Contains(string(got), `"message": "denied: rm -rf /"`) {
+		t.Errorf("Output file does not contain hook output, got: %s", got)
+	}
+}
+
+func TestRedirectStdio_MissingStdinFile(t *testing.T) {
+	oldStdin, oldStdout := os.Stdin, os.Stdout
+	t.Cleanup(func() {
+		os.Stdin, os.Stdout = oldStdin, oldStdout
+	})
+
+	err := redirectStdio(t.TempDir()+"/missing.json", "")
+	if err == nil {
+		t.Fatal("Expected error for missing stdin file, got nil")
+	}
+	if os.Stdin != oldStdin || os.Stdout != oldStdout {
+		t.Error("Expected stdio to be left untouched on error")
+	}
+}
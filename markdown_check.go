@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/xeipuuv/gojsonschema"
+	"gopkg.in/yaml.v3"
+)
+
+// markdownExtensions are the files markdown_check actions check.
+var markdownExtensions = []string{".md", ".markdown"}
+
+var (
+	// [text](target "title") と ![alt](target)
+	markdownInlineLinkPattern = regexp.MustCompile(`!?\[[^\]]*\]\(\s*(<[^>]*>|[^)\s]+)(?:\s+(?:"[^"]*"|'[^']*'))?\s*\)`)
+	// [id]: target
+	markdownReferencePattern = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:\s*(<[^>]*>|\S+)`)
+	markdownHeadingPattern   = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	markdownFencePattern     = regexp.MustCompile("^ {0,3}(```+|~~~+)")
+	markdownCodeSpanPattern  = regexp.MustCompile("`+[^`]*`+")
+	// <a name="x"> / <div id="x"> も参照先になる
+	markdownHTMLAnchorPattern = regexp.MustCompile(`\s(?:name|id)="([^"]+)"`)
+	urlSchemePattern          = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]*:`)
+)
+
+// markdownFinding is a problem found by markdown_check, at a 1-based line (0 for the whole file).
+type markdownFinding struct {
+	Line    int
+	Message string
+}
+
+// markdownHeading is an ATX heading of a Markdown document.
+type markdownHeading struct {
+	Line int
+	Text string
+}
+
+// markdownDocument is the part of a Markdown file markdown_check looks at.
+type markdownDocument struct {
+	frontmatter    string // ---で囲まれた部分 (区切りを除く)
+	hasFrontmatter bool
+	headings       []markdownHeading
+	links          []markdownLink
+	anchors        map[string]bool
+}
+
+// markdownLink is the target of a link or link reference definition.
+type markdownLink struct {
+	Line   int
+	Target string
+}
+
+// isMarkdownFile reports whether path has a Markdown extension.
+func isMarkdownFile(path string) bool {
+	return slices.Contains(markdownExtensions, strings.ToLower(filepath.Ext(path)))
+}
+
+// parseMarkdown extracts the frontmatter, headings, links and anchors of src.
+// Code blocks and code spans are skipped.
+func parseMarkdown(src string) *markdownDocument {
+	doc := &markdownDocument{anchors: map[string]bool{}}
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+
+	start := 0
+	if len(lines) > 0 && strings.TrimRight(lines[0], " \t") == "---" {
+		for i := 1; i < len(lines); i++ {
+			if line := strings.TrimRight(lines[i], " \t"); line == "---" || line == "..." {
+				doc.frontmatter = strings.Join(lines[1:i], "\n")
+				doc.hasFrontmatter = true
+				start = i + 1
+				break
+			}
+		}
+	}
+
+	slugs := map[string]int{}
+	fence := ""
+	for i := start; i < len(lines); i++ {
+		line := lines[i]
+		if m := markdownFencePattern.FindStringSubmatch(line); m != nil {
+			switch {
+			case fence == "":
+				fence = m[1]
+			case m[1][0] == fence[0] && len(m[1]) >= len(fence):
+				fence = ""
+			}
+			continue
+		}
+		if fence != "" {
+			continue
+		}
+
+		if m := markdownHeadingPattern.FindStringSubmatch(line); m != nil && m[2] != "" {
+			text := strings.TrimSpace(m[2])
+			doc.headings = append(doc.headings, markdownHeading{Line: i + 1, Text: text})
+			// GitHubと同じく、同じアンカーには-1, -2...が付く
+			slug := markdownSlug(text)
+			if n := slugs[slug]; n > 0 {
+				doc.anchors[fmt.Sprintf("%s-%d", slug, n)] = true
+			} else {
+				doc.anchors[slug] = true
+			}
+			slugs[slug]++
+		}
+		for _, m := range markdownHTMLAnchorPattern.FindAllStringSubmatch(line, -1) {
+			doc.anchors[m[1]] = true
+		}
+
+		text := markdownCodeSpanPattern.ReplaceAllString(line, "")
+		for _, m := range markdownInlineLinkPattern.FindAllStringSubmatch(text, -1) {
+			doc.links = append(doc.links, markdownLink{Line: i + 1, Target: strings.Trim(m[1], "<>")})
+		}
+		if m := markdownReferencePattern.FindStringSubmatch(text); m != nil {
+			doc.links = append(doc.links, markdownLink{Line: i + 1, Target: strings.Trim(m[1], "<>")})
+		}
+	}
+	return doc
+}
+
+// markdownSlug returns the anchor GitHub generates for a heading: lowercase, punctuation removed, spaces as hyphens.
+func markdownSlug(heading string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(heading) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteByte('-')
+		}
+	}
+	return b.String()
+}
+
+// checkMarkdownFile checks the Markdown file at path for dead relative links (including anchors), duplicate headings,
+// invalid YAML frontmatter and, if schemaPath is set, frontmatter that doesn't match the JSON Schema.
+func checkMarkdownFile(path, schemaPath string) ([]markdownFinding, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc := parseMarkdown(string(content))
+	var findings []markdownFinding
+
+	if doc.hasFrontmatter || schemaPath != "" {
+		findings = append(findings, checkFrontmatter(doc, schemaPath)...)
+	}
+
+	first := map[string]int{}
+	for _, h := range doc.headings {
+		if line, ok := first[h.Text]; ok {
+			findings = append(findings, markdownFinding{Line: h.Line, Message: fmt.Sprintf("duplicate heading %q (first at line %d)", h.Text, line)})
+			continue
+		}
+		first[h.Text] = h.Line
+	}
+
+	anchorsOf := map[string]map[string]bool{path: doc.anchors}
+	for _, link := range doc.links {
+		if message := checkMarkdownLink(path, link.Target, anchorsOf); message != "" {
+			findings = append(findings, markdownFinding{Line: link.Line, Message: message})
+		}
+	}
+
+	slices.SortStableFunc(findings, func(a, b markdownFinding) int { return a.Line - b.Line })
+	return findings, nil
+}
+
+// checkMarkdownLink returns why the link target of the Markdown file at path is dead, or "" if it is fine.
+// URLs with a scheme are not checked. anchorsOf caches the anchors of the Markdown files linked to.
+func checkMarkdownLink(path, target string, anchorsOf map[string]map[string]bool) string {
+	if target == "" || urlSchemePattern.MatchString(target) || strings.HasPrefix(target, "//") {
+		return ""
+	}
+	file, anchor, _ := strings.Cut(target, "#")
+	file, _, _ = strings.Cut(file, "?")
+	if decoded, err := url.PathUnescape(file); err == nil {
+		file = decoded
+	}
+
+	resolved := path
+	if file != "" {
+		if strings.HasPrefix(file, "/") {
+			// GitHubと同じく、/始まりはリポジトリのルートから
+			root, ok := findUpward(filepath.Dir(path), ".git")
+			if !ok {
+				return ""
+			}
+			resolved = filepath.Join(root, filepath.FromSlash(file))
+		} else {
+			resolved = filepath.Join(filepath.Dir(path), filepath.FromSlash(file))
+		}
+		if _, err := os.Stat(resolved); err != nil {
+			return fmt.Sprintf("dead link %s (%s does not exist)", target, file)
+		}
+	}
+
+	if anchor == "" || !isMarkdownFile(resolved) {
+		return ""
+	}
+	anchors, ok := anchorsOf[resolved]
+	if !ok {
+		content, err := os.ReadFile(resolved)
+		if err != nil {
+			return ""
+		}
+		anchors = parseMarkdown(string(content)).anchors
+		anchorsOf[resolved] = anchors
+	}
+	if decoded, err := url.PathUnescape(anchor); err == nil {
+		anchor = decoded
+	}
+	if !anchors[anchor] && !anchors[strings.ToLower(anchor)] {
+		return fmt.Sprintf("dead link %s (no heading with anchor #%s)", target, anchor)
+	}
+	return ""
+}
+
+// checkFrontmatter checks that the frontmatter is valid YAML and matches the JSON Schema at schemaPath (if set).
+func checkFrontmatter(doc *markdownDocument, schemaPath string) []markdownFinding {
+	var value any
+	if err := yaml.Unmarshal([]byte(doc.frontmatter), &value); err != nil {
+		return []markdownFinding{{Line: 1, Message: fmt.Sprintf("invalid frontmatter YAML: %s", strings.TrimPrefix(err.Error(), "yaml: "))}}
+	}
+	if schemaPath == "" {
+		return nil
+	}
+	if value == nil {
+		value = map[string]any{}
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return []markdownFinding{{Line: 1, Message: fmt.Sprintf("frontmatter can't be checked against the schema: %v", err)}}
+	}
+	schema, err := os.ReadFile(schemaPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: markdown_check: failed to read frontmatter schema: %v\n", err)
+		return nil
+	}
+	result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(schema), gojsonschema.NewBytesLoader(data))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: markdown_check: invalid frontmatter schema %s: %v\n", schemaPath, err)
+		return nil
+	}
+	line := 1
+	if !doc.hasFrontmatter {
+		line = 0
+	}
+	var findings []markdownFinding
+	for _, e := range result.Errors() {
+		findings = append(findings, markdownFinding{Line: line, Message: "frontmatter: " + e.String()})
+	}
+	return findings
+}
+
+// markdownCheckReport formats the findings of the file for Claude.
+func markdownCheckReport(displayPath string, findings []markdownFinding) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Markdown issues in %s:", displayPath)
+	for _, f := range findings {
+		if f.Line > 0 {
+			fmt.Fprintf(&b, "\n- line %d: %s", f.Line, f.Message)
+		} else {
+			fmt.Fprintf(&b, "\n- %s", f.Message)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckMarkdownFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"docs/guide.md": "---\n" +
+			"title: Guide\n" +
+			"tags: [a, b]\n" +
+			"---\n" +
+			"# Guide\n" +
+			"\n" +
+			"See [setup](setup.md#install-the-cli), [missing](missing.md) and [api](../api/README.md).\n" +
+			"![diagram](img/diagram.png) and [site](https://example.com/x.md) and [mail](mailto:a@example.com).\n" +
+			"Jump to [usage](#usage), [typo](#usgae) or [second usage](#usage-1).\n" +
+			"\n" +
+			"## Usage\n" +
+			"\n" +
+			"`[not a link](nowhere.md)`\n" +
+			"\n" +
+			"```markdown\n" +
+			"[in a code block](nowhere.md)\n" +
+			"## Usage\n" +
+			"```\n" +
+			"\n" +
+			"## Usage\n" +
+			"\n" +
+			"[ref]: ./gone.md\n" +
+			"[setup anchor](setup.md#no-such-section)\n",
+		"docs/setup.md":        "# Setup\n\n## Install the CLI\n",
+		"docs/img/diagram.png": "",
+		"api/README.md":        "# API\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	findings, err := checkMarkdownFile(filepath.Join(dir, "docs/guide.md"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := markdownCheckReport("docs/guide.md", findings)
+	want := "Markdown issues in docs/guide.md:\n" +
+		"- line 7: dead link missing.md (missing.md does not exist)\n" +
+		"- line 9: dead link #usgae (no heading with anchor #usgae)\n" +
+		"- line 20: duplicate heading \"Usage\" (first at line 11)\n" +
+		"- line 22: dead link ./gone.md (./gone.md does not exist)\n" +
+		"- line 23: dead link setup.md#no-such-section (no heading with anchor #no-such-section)"
+	if got != want {
+		t.Errorf("report =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCheckMarkdownFile_Frontmatter(t *testing.T) {
+	dir := t.TempDir()
+	schema := filepath.Join(dir, "schema.json")
+	if err := os.WriteFile(schema, []byte(`{"type":"object","required":["title"],"properties":{"title":{"type":"string"},"draft":{"type":"boolean"}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		schema  string
+		want    []string
+	}{
+		{name: "valid", content: "---\ntitle: Hello\ndraft: false\n---\n# Hello\n", schema: schema},
+		{name: "no schema", content: "---\ndraft: yes please\n---\n", schema: ""},
+		{name: "invalid YAML", content: "---\ntitle: [unclosed\n---\n", schema: "", want: []string{"line 1: invalid frontmatter YAML"}},
+		{
+			name:    "schema violation",
+			content: "---\ndraft: \"no\"\n---\n# Draft\n",
+			schema:  schema,
+			want:    []string{"line 1: frontmatter: (root): title is required", "line 1: frontmatter: draft: Invalid type. Expected: boolean, given: string"},
+		},
+		{name: "missing frontmatter", content: "# No frontmatter\n", schema: schema, want: []string{"- frontmatter: (root): title is required"}},
+		{name: "unreadable schema is skipped", content: "---\ndraft: 1\n---\n", schema: filepath.Join(dir, "missing.json")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "page.md")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			findings, err := checkMarkdownFile(path, tt.schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(findings) != len(tt.want) {
+				t.Fatalf("findings = %+v, want %d", findings, len(tt.want))
+			}
+			report := markdownCheckReport("page.md", findings)
+			for _, want := range tt.want {
+				if !strings.Contains(report, want) {
+					t.Errorf("report = %q, want it to contain %q", report, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMarkdownSlug(t *testing.T) {
+	tests := map[string]string{
+		"Install the CLI":          "install-the-cli",
+		"`cooldown` option":        "cooldown-option",
+		"What's new? (v2.0)":       "whats-new-v20",
+		"snake_case and-hyphens":   "snake_case-and-hyphens",
+		"日本語の見出し":                  "日本語の見出し",
+		"Trailing closing hashes ": "trailing-closing-hashes-",
+	}
+	for heading, want := range tests {
+		if got := markdownSlug(heading); got != want {
+			t.Errorf("markdownSlug(%q) = %q, want %q", heading, got, want)
+		}
+	}
+}
+
+func TestExecutePostToolUseAction_MarkdownCheck(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("# Title\n\n[broken](nope.md)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "ok.md"), []byte("# Title\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("[broken](nope.md)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	report := "Markdown issues in README.md:\n- line 3: dead link nope.md (nope.md does not exist)"
+
+	tests := []struct {
+		name         string
+		file         string
+		action       Action
+		wantDecision string
+		wantReason   string
+		wantContext  string
+		wantNil      bool
+	}{
+		{name: "no findings", file: "ok.md", action: Action{Type: "markdown_check"}, wantNil: true},
+		{name: "not Markdown", file: "notes.txt", action: Action{Type: "markdown_check"}, wantNil: true},
+		{name: "missing file", file: "missing.md", action: Action{Type: "markdown_check"}, wantNil: true},
+		{name: "findings as additionalContext", file: "README.md", action: Action{Type: "markdown_check"}, wantContext: report},
+		{name: "block", file: "README.md", action: Action{Type: "markdown_check", Decision: stringPtr("block")}, wantDecision: "block", wantReason: report},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := &PostToolUseInput{BaseInput: BaseInput{Cwd: dir}, ToolName: "Write", ToolInput: ToolInput{FilePath: tt.file}}
+			output, err := NewActionExecutor(nil).ExecutePostToolUseAction(tt.action, input, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantNil {
+				if output != nil {
+					t.Errorf("output = %+v, want nil", output)
+				}
+				return
+			}
+			if output == nil {
+				t.Fatal("output = nil")
+			}
+			if output.Decision != tt.wantDecision || output.Reason != tt.wantReason || output.AdditionalContext != tt.wantContext {
+				t.Errorf("output = decision %q, reason %q, context %q; want %q, %q, %q",
+					output.Decision, output.Reason, output.AdditionalContext, tt.wantDecision, tt.wantReason, tt.wantContext)
+			}
+		})
+	}
+}
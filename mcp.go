@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+)
+
+// mcpToolPrefix is the prefix of the tool names Claude Code gives MCP tools: mcp__<server>__<tool>.
+const mcpToolPrefix = "mcp__"
+
+// parseMCPToolName splits an MCP tool name "mcp__<server>__<tool>" into the server and tool names.
+// ok is false for built-in tools and malformed names.
+func parseMCPToolName(toolName string) (server, tool string, ok bool) {
+	rest, found := strings.CutPrefix(toolName, mcpToolPrefix)
+	if !found {
+		return "", "", false
+	}
+	server, tool, found = strings.Cut(rest, "__")
+	if !found || server == "" || tool == "" {
+		return "", "", false
+	}
+	return server, tool, true
+}
+
+// checkMCPServerMatcher checks the mcp_server field of a tool event hook: pipe-separated server names (exact match).
+// An empty field matches every tool; otherwise built-in tools never match.
+func checkMCPServerMatcher(mcpServer, toolName string) bool {
+	if mcpServer == "" {
+		return true
+	}
+	server, _, ok := parseMCPToolName(toolName)
+	if !ok {
+		return false
+	}
+	for _, name := range strings.Split(mcpServer, "|") {
+		if strings.TrimSpace(name) == server {
+			return true
+		}
+	}
+	return false
+}
+
+// checkMCPCondition checks the mcp_server_is / mcp_tool_is conditions against the server and tool parsed from toolName.
+// Both are false for built-in tools. Returns ErrConditionNotHandled for other condition types.
+func checkMCPCondition(condition Condition, toolName string) (bool, error) {
+	var target string
+	server, tool, ok := parseMCPToolName(toolName)
+	switch condition.Type {
+	case ConditionMCPServerIs:
+		target = server
+	case ConditionMCPToolIs:
+		target = tool
+	default:
+		return false, ErrConditionNotHandled
+	}
+	if !ok {
+		return false, nil
+	}
+	value, target, err := prepareStringMatch(condition, target)
+	return err == nil && target == value, err
+}
+
+// withMCPTool adds the server and tool of an MCP tool call to the event JSON as mcp,
+// so that templates can use {.mcp.server} and {.mcp.tool}.
+func withMCPTool(rawJSON any) {
+	m, ok := rawJSON.(map[string]any)
+	if !ok {
+		return
+	}
+	toolName, _ := m["tool_name"].(string)
+	server, tool, ok := parseMCPToolName(toolName)
+	if !ok {
+		return
+	}
+	m["mcp"] = map[string]any{"server": server, "tool": tool}
+}
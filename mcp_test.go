@@ -0,0 +1,135 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMCPToolName(t *testing.T) {
+	tests := []struct {
+		toolName   string
+		wantServer string
+		wantTool   string
+		wantOK     bool
+	}{
+		{toolName: "mcp__github__create_issue", wantServer: "github", wantTool: "create_issue", wantOK: true},
+		{toolName: "mcp__my_server__list__items", wantServer: "my_server", wantTool: "list__items", wantOK: true},
+		{toolName: "mcp__claude-in-chrome__navigate", wantServer: "claude-in-chrome", wantTool: "navigate", wantOK: true},
+		{toolName: "Bash"},
+		{toolName: "mcp__github"},
+		{toolName: "mcp____tool"},
+		{toolName: "mcp__github__"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.toolName, func(t *testing.T) {
+			server, tool, ok := parseMCPToolName(tt.toolName)
+			if server != tt.wantServer || tool != tt.wantTool || ok != tt.wantOK {
+				t.Errorf("parseMCPToolName(%q) = %q, %q, %v, want %q, %q, %v", tt.toolName, server, tool, ok, tt.wantServer, tt.wantTool, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestCheckToolMatcher_MCPServer(t *testing.T) {
+	tests := []struct {
+		name      string
+		matcher   string
+		mcpServer string
+		toolName  string
+		want      bool
+	}{
+		{"No mcp_server matches built-in tools", "", "", "Bash", true},
+		{"Server matches", "", "github", "mcp__github__create_issue", true},
+		{"Server is exact", "", "git", "mcp__github__create_issue", false},
+		{"Pipe-separated servers", "", "slack | github", "mcp__github__create_issue", true},
+		{"Built-in tools never match", "*", "github", "Bash", false},
+		{"Matcher still applies", "create_.*", "github", "mcp__github__list_issues", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkToolMatcher(tt.matcher, nil, tt.mcpServer, tt.toolName); got != tt.want {
+				t.Errorf("checkToolMatcher(%q, nil, %q, %q) = %v, want %v", tt.matcher, tt.mcpServer, tt.toolName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckPreToolUseCondition_MCP(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition Condition
+		toolName  string
+		want      bool
+	}{
+		{name: "server is", condition: Condition{Type: ConditionMCPServerIs, Value: "github"}, toolName: "mcp__github__create_issue", want: true},
+		{name: "server is not", condition: Condition{Type: ConditionMCPServerIs, Value: "slack"}, toolName: "mcp__github__create_issue", want: false},
+		{name: "tool is", condition: Condition{Type: ConditionMCPToolIs, Value: "create_issue"}, toolName: "mcp__github__create_issue", want: true},
+		{name: "tool is exact", condition: Condition{Type: ConditionMCPToolIs, Value: "create"}, toolName: "mcp__github__create_issue", want: false},
+		{name: "tool values", condition: Condition{Type: ConditionMCPToolIs, Values: []string{"delete_repo", "merge_pull_request"}}, toolName: "mcp__github__merge_pull_request", want: true},
+		{name: "ignore_case", condition: Condition{Type: ConditionMCPServerIs, Value: "GitHub", IgnoreCase: true}, toolName: "mcp__github__create_issue", want: true},
+		{name: "built-in tool", condition: Condition{Type: ConditionMCPToolIs, Value: "Bash"}, toolName: "Bash", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := checkPreToolUseCondition(tt.condition, &PreToolUseInput{ToolName: tt.toolName})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("checkPreToolUseCondition(%v, %q) = %v, want %v", tt.condition.Type, tt.toolName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithMCPTool(t *testing.T) {
+	rawJSON := map[string]any{"tool_name": "mcp__github__create_issue"}
+	withMCPTool(rawJSON)
+	if want := map[string]any{"server": "github", "tool": "create_issue"}; !reflect.DeepEqual(rawJSON["mcp"], want) {
+		t.Errorf("mcp = %v, want %v", rawJSON["mcp"], want)
+	}
+	if got := unifiedTemplateReplace("{.mcp.server}/{.mcp.tool}", rawJSON); got != "github/create_issue" {
+		t.Errorf("template = %q", got)
+	}
+
+	rawJSON = map[string]any{"tool_name": "Bash"}
+	withMCPTool(rawJSON)
+	if _, ok := rawJSON["mcp"]; ok {
+		t.Errorf("built-in tool: mcp = %v, want none", rawJSON["mcp"])
+	}
+}
+
+func TestDryRunPreToolUseHooks_MCPServer(t *testing.T) {
+	config := &Config{
+		PreToolUse: []PreToolUseHook{
+			{
+				MCPServer: "github",
+				Actions:   []Action{{Type: "output", Message: "GitHub tool"}},
+			},
+			{
+				MCPServer: "slack",
+				Actions:   []Action{{Type: "output", Message: "Slack tool"}},
+			},
+		},
+	}
+	input := &PreToolUseInput{ToolName: "mcp__github__create_issue"}
+	rawJSON := map[string]any{"tool_name": input.ToolName}
+
+	var err error
+	output := captureStdout(t, func() {
+		err = dryRunPreToolUseHooks(os.Stdout, config, input, rawJSON)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"[Hook 1] Would execute:", "MCP server: github, tool: create_issue", "Message: GitHub tool"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got: %q", want, output)
+		}
+	}
+	if strings.Contains(output, "Slack tool") {
+		t.Errorf("hook for another server should not run, got: %q", output)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// hookMutexNamePattern restricts mutex names to characters that are safe as a file name.
+var hookMutexNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// hookMutexDir returns the directory holding the lock files of hook mutexes.
+// It is a variable so that tests can use a temporary directory.
+var hookMutexDir = func() string {
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "cchook", "locks")
+	}
+	return filepath.Join(os.TempDir(), "cchook-locks")
+}
+
+// validateHookMutexName checks that name can be used as a mutex name.
+func validateHookMutexName(name string) error {
+	if !hookMutexNamePattern.MatchString(name) || name == "." || name == ".." {
+		return fmt.Errorf("invalid mutex name %q (use letters, digits, '.', '_' and '-')", name)
+	}
+	return nil
+}
+
+// lockHookMutex blocks until the named mutex is acquired and returns the function releasing it.
+// The mutex is a lock on a file shared by all cchook processes of the user, so that actions of
+// hooks with the same `mutex` never run at the same time, even across parallel sessions or subagents.
+func lockHookMutex(name string) (func(), error) {
+	if err := validateHookMutexName(name); err != nil {
+		return nil, err
+	}
+	dir := hookMutexDir()
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create mutex directory: %w", err)
+	}
+	unlock, err := lockFile(filepath.Join(dir, name+".lock"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire mutex %q: %w", name, err)
+	}
+	return unlock, nil
+}
@@ -0,0 +1,49 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// staleLockAge is the age after which a lock file left by a crashed process is removed.
+const staleLockAge = 10 * time.Minute
+
+// lockFile creates path exclusively, polling while another process holds it.
+// Platforms without flock fall back to the existence of the lock file.
+func lockFile(path string) (func(), error) {
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			_ = f.Close()
+			return func() { _ = os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			_ = os.Remove(path)
+			continue
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// tryLockFile creates path exclusively without waiting. It returns false if another process holds it.
+func tryLockFile(path string) (func(), bool, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err == nil {
+		_ = f.Close()
+		return func() { _ = os.Remove(path) }, true, nil
+	}
+	if !errors.Is(err, os.ErrExist) {
+		return nil, false, err
+	}
+	if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLockAge {
+		_ = os.Remove(path)
+		return tryLockFile(path)
+	}
+	return nil, false, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func withHookMutexDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	original := hookMutexDir
+	hookMutexDir = func() string { return dir }
+	t.Cleanup(func() { hookMutexDir = original })
+	return dir
+}
+
+func TestLockHookMutex(t *testing.T) {
+	withHookMutexDir(t)
+
+	unlock, err := lockHookMutex("go-mod")
+	if err != nil {
+		t.Fatalf("lockHookMutex() error = %v", err)
+	}
+
+	acquired := make(chan func())
+	go func() {
+		second, err := lockHookMutex("go-mod")
+		if err != nil {
+			t.Errorf("lockHookMutex() error = %v", err)
+			close(acquired)
+			return
+		}
+		acquired <- second
+	}()
+
+	// 別の名前のmutexは独立している
+	other, err := lockHookMutex("npm")
+	if err != nil {
+		t.Fatalf("lockHookMutex() error = %v", err)
+	}
+	other()
+
+	select {
+	case <-acquired:
+		t.Fatal("Expected the second lock to wait for the first one")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	unlock()
+	select {
+	case second := <-acquired:
+		if second != nil {
+			second()
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the second lock to be acquired after unlock")
+	}
+
+	if _, err := lockHookMutex("../escape"); err == nil {
+		t.Error("Expected an error for a mutex name with a path separator")
+	}
+}
+
+func TestExecuteStopHooks_Mutex(t *testing.T) {
+	withHookMutexDir(t)
+	logPath := filepath.Join(t.TempDir(), "log")
+
+	config := &Config{
+		Stop: []StopHook{{
+			Mutex: "tidy",
+			Actions: []Action{{
+				Type:    "command",
+				Command: "echo start >> " + logPath + " && sleep 0.2 && echo end >> " + logPath,
+			}},
+		}},
+	}
+
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := executeStopHooks(config, &StopInput{}, map[string]any{}); err != nil {
+				t.Errorf("executeStopHooks() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	if got, want := strings.Fields(string(data)), "start end start end"; strings.Join(got, " ") != want {
+		t.Errorf("Command runs = %v, want %s (serialized)", got, want)
+	}
+}
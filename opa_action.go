@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// defaultOPAQuery is the query of an opa action without `query`.
+const defaultOPAQuery = "data.cchook.decision"
+
+// opaEvalTimeout bounds the evaluation of a policy (e.g. one calling http.send).
+const opaEvalTimeout = 10 * time.Second
+
+// opaProgram is the OPA CLI opa actions run. It is a variable so that tests can use a fake.
+var opaProgram = "opa"
+
+// opaActionQuery returns the query of an opa action.
+func opaActionQuery(action Action) string {
+	if action.Query == "" {
+		return defaultOPAQuery
+	}
+	return action.Query
+}
+
+// opaEvalOutput is the output of `opa eval --format json`: the results of the query, or the errors.
+type opaEvalOutput struct {
+	Result []struct {
+		Expressions []struct {
+			Value any `json:"value"`
+		} `json:"expressions"`
+	} `json:"result"`
+	Errors []struct {
+		Message  string `json:"message"`
+		Code     string `json:"code"`
+		Location *struct {
+			File string `json:"file"`
+			Row  int    `json:"row"`
+		} `json:"location"`
+	} `json:"errors"`
+}
+
+// err returns the first of the errors OPA reports at once, so that they fit on a line
+// (the rest are usually caused by the first), or nil.
+func (out *opaEvalOutput) err(query string) error {
+	if len(out.Errors) == 0 {
+		return nil
+	}
+	first := out.Errors[0]
+	msg := fmt.Sprintf("%s: %s", first.Code, first.Message)
+	switch {
+	case first.Location != nil && first.Location.File != "":
+		// コンパイルエラーにはファイル名と行番号が含まれる
+		msg = fmt.Sprintf("%s:%d: %s", first.Location.File, first.Location.Row, msg)
+	case first.Location != nil:
+		msg = fmt.Sprintf("invalid query %q: %s", query, msg)
+	}
+	if len(out.Errors) > 1 {
+		msg += fmt.Sprintf(" (and %d more errors)", len(out.Errors)-1)
+	}
+	return errors.New(msg)
+}
+
+// evalOPA evaluates query against the policy file at path with `opa eval`, with input (JSON) as input
+// unless it is nil. print() calls of the policy are returned as printed.
+func evalOPA(ctx context.Context, path, query string, input []byte) (*opaEvalOutput, string, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, "", fmt.Errorf("failed to read policy: %w", err)
+	}
+	args := []string{"eval", "--format", "json", "--data", path}
+	if input != nil {
+		args = append(args, "--stdin-input")
+	}
+	cmd := exec.CommandContext(ctx, opaProgram, append(args, "--", query)...)
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	runErr := cmd.Run()
+	if errors.Is(runErr, exec.ErrNotFound) {
+		return nil, "", fmt.Errorf("%s is not installed (opa actions run `opa eval`, see https://www.openpolicyagent.org/docs/#running-opa)", opaProgram)
+	}
+	if ctx.Err() != nil {
+		return nil, stderr.String(), fmt.Errorf("policy evaluation timed out after %s", opaEvalTimeout)
+	}
+
+	var out opaEvalOutput
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		if runErr != nil {
+			return nil, stderr.String(), fmt.Errorf("opa eval failed: %v: %s", runErr, strings.TrimSpace(stderr.String()))
+		}
+		return nil, stderr.String(), fmt.Errorf("failed to parse the output of opa eval: %w", err)
+	}
+	return &out, stderr.String(), out.err(query)
+}
+
+// checkOPAQuery compiles the policy file at path and the query evaluated against it, without input.
+func checkOPAQuery(path, query string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), opaEvalTimeout)
+	defer cancel()
+	_, _, err := evalOPA(ctx, path, query, nil)
+	return err
+}
+
+// runOPAAction evaluates the policy of an opa action with `opa eval` and the event JSON as input, and reports
+// the result like a command: an object decision is returned as stdout (hook output, the same fields as a command's
+// JSON output), an undefined decision is no output, and policy errors (or a missing opa) are failures, so that
+// the event's fail-safe decision applies. print() output of the policy is returned as stderr.
+func runOPAAction(action Action, rawJSON any) (stdout, stderr string, exitCode int, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), opaEvalTimeout)
+	defer cancel()
+
+	input, err := json.Marshal(rawJSON)
+	if err != nil {
+		return "", "", 1, fmt.Errorf("failed to encode input: %w", err)
+	}
+	path := resolveToolFilePath(action.Policy, rawJSONCwd(rawJSON))
+	out, printed, err := evalOPA(ctx, path, opaActionQuery(action), input)
+	if err != nil {
+		return "", printed, 1, err
+	}
+	if len(out.Result) == 0 || len(out.Result[0].Expressions) == 0 {
+		return "", printed, 0, nil
+	}
+
+	value := out.Result[0].Expressions[0].Value
+	decision, ok := value.(map[string]any)
+	if !ok {
+		return "", printed, 1, fmt.Errorf("%s: %s must be an object, got %v", path, opaActionQuery(action), value)
+	}
+	data, err := json.Marshal(decision)
+	if err != nil {
+		return "", printed, 1, fmt.Errorf("failed to encode decision: %w", err)
+	}
+	return string(data), printed, 0, nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testOPAPolicy = `package cchook
+
+deny_commands := {"rm", "shutdown"}
+
+decision := {
+	"hookSpecificOutput": {
+		"hookEventName": "PreToolUse",
+		"permissionDecision": "deny",
+		"permissionDecisionReason": sprintf("%s is not allowed by policy", [input.tool_input.command]),
+	},
+} if {
+	print("checking", input.tool_input.command)
+	deny_commands[input.tool_input.command]
+}
+
+allowed := input.tool_name == "Read"
+
+conflict := 1 if input.tool_name == "Bash"
+conflict := 2 if input.tool_name == "Bash"
+`
+
+// writeOPAPolicy writes testOPAPolicy to policy.rego in a temporary directory and returns the directory.
+// The test is skipped when the opa CLI is not installed.
+func writeOPAPolicy(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath(opaProgram); err != nil {
+		t.Skip("opa is not installed")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "policy.rego"), []byte(testOPAPolicy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestRunOPAAction(t *testing.T) {
+	dir := writeOPAPolicy(t)
+	tests := []struct {
+		name        string
+		action      Action
+		command     string
+		wantStdout  string
+		wantStderr  string
+		wantFailure string // substring of the error, "" for success
+	}{
+		{
+			name:       "object decision is hook output",
+			action:     Action{Type: "opa", Policy: "policy.rego"},
+			command:    "rm",
+			wantStdout: `{"hookSpecificOutput":{"hookEventName":"PreToolUse","permissionDecision":"deny","permissionDecisionReason":"rm is not allowed by policy"}}`,
+			wantStderr: "checking rm",
+		},
+		{
+			name:       "undefined decision is no output",
+			action:     Action{Type: "opa", Policy: filepath.Join(dir, "policy.rego")},
+			command:    "ls",
+			wantStderr: "checking ls",
+		},
+		{
+			name:        "non-object decision",
+			action:      Action{Type: "opa", Policy: "policy.rego", Query: "data.cchook.allowed"},
+			command:     "ls",
+			wantFailure: "data.cchook.allowed must be an object, got false",
+		},
+		{
+			name:        "evaluation error",
+			action:      Action{Type: "opa", Policy: "policy.rego", Query: "data.cchook.conflict"},
+			command:     "ls",
+			wantFailure: "complete rules must not produce multiple outputs",
+		},
+		{
+			name:        "missing policy",
+			action:      Action{Type: "opa", Policy: "missing.rego"},
+			command:     "ls",
+			wantFailure: "failed to read policy",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rawJSON := map[string]any{"cwd": dir, "tool_name": "Bash", "tool_input": map[string]any{"command": tt.command}}
+			stdout, stderr, exitCode, err := runOPAAction(tt.action, rawJSON)
+			if tt.wantFailure != "" {
+				if exitCode == 0 || err == nil || !strings.Contains(err.Error(), tt.wantFailure) {
+					t.Errorf("runOPAAction() = %d, %v; want failure %q", exitCode, err, tt.wantFailure)
+				}
+				return
+			}
+			if err != nil || exitCode != 0 || stdout != tt.wantStdout || !strings.Contains(stderr, tt.wantStderr) {
+				t.Errorf("runOPAAction() = %q, %q, %d, %v; want %q, %q", stdout, stderr, exitCode, err, tt.wantStdout, tt.wantStderr)
+			}
+		})
+	}
+}
+
+func TestExecuteAction_OPA(t *testing.T) {
+	dir := writeOPAPolicy(t)
+	executor := NewActionExecutor(nil)
+	rawJSON := func(command string) map[string]any {
+		return map[string]any{"cwd": dir, "tool_name": "Bash", "tool_input": map[string]any{"command": command}}
+	}
+
+	output, err := executor.ExecutePreToolUseAction(Action{Type: "opa", Policy: "policy.rego"}, &PreToolUseInput{ToolName: "Bash"}, rawJSON("shutdown"))
+	if err != nil {
+		t.Fatalf("ExecutePreToolUseAction() error = %v", err)
+	}
+	if output == nil || output.PermissionDecision != "deny" || output.PermissionDecisionReason != "shutdown is not allowed by policy" {
+		t.Errorf("Expected the policy to deny, got %+v", output)
+	}
+
+	// ポリシーの評価に失敗したらfail-safeでdenyする
+	output, err = executor.ExecutePreToolUseAction(Action{Type: "opa", Policy: "policy.rego", Query: "data.cchook.conflict"}, &PreToolUseInput{ToolName: "Bash"}, rawJSON("ls"))
+	if err != nil {
+		t.Fatalf("ExecutePreToolUseAction() error = %v", err)
+	}
+	if output == nil || output.PermissionDecision != "deny" || !strings.Contains(output.SystemMessage, "Policy evaluation failed") {
+		t.Errorf("Expected a failing policy to deny, got %+v", output)
+	}
+	if msg := actionFailureMessage(Action{Type: "opa"}, 1, "checking ls\n", errors.New("boom")); msg != "Policy evaluation failed: boom\nchecking ls" {
+		t.Errorf("actionFailureMessage() = %q, want the error and the print() output", msg)
+	}
+}
+
+func TestRunOPAAction_NotInstalled(t *testing.T) {
+	original := opaProgram
+	opaProgram = "cchook-missing-opa"
+	t.Cleanup(func() { opaProgram = original })
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "policy.rego"), []byte(testOPAPolicy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// opaが無ければ失敗として扱い、イベントのfail-safeな判定になる
+	_, _, exitCode, err := runOPAAction(Action{Type: "opa", Policy: "policy.rego"}, map[string]any{"cwd": dir})
+	if exitCode == 0 || err == nil || !strings.Contains(err.Error(), "cchook-missing-opa is not installed") {
+		t.Errorf("runOPAAction() = %d, %v, want a failure", exitCode, err)
+	}
+}
+
+func TestValidateConfigData_OPAPolicy(t *testing.T) {
+	t.Chdir(writeOPAPolicy(t))
+	if err := os.WriteFile("broken.rego", []byte("package cchook\n\ndecision := {\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config := "PreToolUse:\n  - actions:\n      - type: opa\n        policy: policy.rego\n      - type: opa\n        policy: broken.rego\n      - type: opa\n        policy: policy.rego\n        query: \"data.cchook[\"\n"
+	var got []string
+	for _, issue := range validateConfigData("config.yaml", []byte(config)) {
+		got = append(got, issue.String())
+	}
+	if len(got) != 2 || !strings.Contains(got[0], "6:17: error: PreToolUse hook 1 action 2: broken.rego:") ||
+		!strings.Contains(got[1], "8:17: error: PreToolUse hook 1 action 3:") {
+		t.Errorf("validateConfigData() = %q, want compile errors of the broken policy and query", got)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Values of the -output flag.
+const (
+	outputModeJSON     = "json"     // JSON output on stdout, always exit 0 (default)
+	outputModeExitCode = "exitcode" // legacy exit code protocol: 0 allow, 2 block with the reason on stderr
+)
+
+// hookOutputMode is the -output flag of the run command.
+var hookOutputMode = outputModeJSON
+
+// defaultBlockReason is the stderr message of a block whose output has no reason.
+const defaultBlockReason = "Blocked by cchook"
+
+// legacyOutput holds the fields of the JSON output that the exit code protocol can express.
+type legacyOutput struct {
+	Decision           string `json:"decision"`
+	Reason             string `json:"reason"`
+	SystemMessage      string `json:"systemMessage"`
+	HookSpecificOutput struct {
+		PermissionDecision       string `json:"permissionDecision"`
+		PermissionDecisionReason string `json:"permissionDecisionReason"`
+		AdditionalContext        string `json:"additionalContext"`
+		Decision                 *struct {
+			Behavior string `json:"behavior"`
+			Message  string `json:"message"`
+		} `json:"decision"`
+	} `json:"hookSpecificOutput"`
+}
+
+// writeHookOutput writes the final JSON output of eventType according to -output,
+// with the warnings of on_git_unavailable: warn added to its systemMessage.
+// With -output exitcode the decision is translated into Claude Code's exit code protocol instead:
+// a block is returned as an ExitError with code 2 and the reason for stderr, and additional context
+// of SessionStart / UserPromptSubmit is written to w as plain text (stdout is added to the context on exit 0).
+func writeHookOutput(w io.Writer, eventType HookEventType, jsonBytes []byte) error {
+	jsonBytes = withGitUnavailableWarnings(jsonBytes)
+	logOutput(jsonBytes)
+	exportDecision(eventType, jsonBytes)
+	if hookOutputMode != outputModeExitCode {
+		fmt.Fprintln(w, string(jsonBytes))
+		return nil
+	}
+
+	context, reason, blocked, err := exitCodeDecision(eventType, jsonBytes)
+	if err != nil {
+		// 出力を解釈できない場合はブロックする（fail-safe）
+		return NewExitError(2, fmt.Sprintf("Failed to translate output to exit code: %v", err), true)
+	}
+	if blocked {
+		return NewExitError(2, reason, true)
+	}
+	if context != "" {
+		fmt.Fprintln(w, context)
+	}
+	return nil
+}
+
+// exitCodeDecision extracts what the exit code protocol can express from the JSON output of eventType:
+// whether the event is blocked (and why), and the additional context to print on success.
+// PreToolUse "ask" has no exit code equivalent and blocks, like "deny".
+// Events that can't be blocked (Notification, SessionStart, SessionEnd, PreCompact, SubagentStart) always succeed.
+func exitCodeDecision(eventType HookEventType, jsonBytes []byte) (context, reason string, blocked bool, err error) {
+	var output legacyOutput
+	if err := json.Unmarshal(jsonBytes, &output); err != nil {
+		return "", "", false, err
+	}
+	specific := output.HookSpecificOutput
+
+	switch eventType {
+	case PreToolUse:
+		if specific.PermissionDecision == "deny" || specific.PermissionDecision == "ask" {
+			return "", blockReason(specific.PermissionDecisionReason), true, nil
+		}
+	case PermissionRequest:
+		if specific.Decision != nil && specific.Decision.Behavior == "deny" {
+			return "", blockReason(specific.Decision.Message), true, nil
+		}
+	case PostToolUse, Stop, SubagentStop, UserPromptSubmit:
+		if output.Decision == "block" {
+			// UserPromptSubmitの出力にはreasonが無く、メッセージはsystemMessageに入る
+			reason := output.Reason
+			if reason == "" {
+				reason = output.SystemMessage
+			}
+			return "", blockReason(reason), true, nil
+		}
+	}
+
+	if eventType == SessionStart || eventType == UserPromptSubmit {
+		context = specific.AdditionalContext
+	}
+	return context, "", false, nil
+}
+
+// blockReason returns reason, or a generic message when it is empty (exit code 2 needs a message on stderr).
+func blockReason(reason string) string {
+	if reason == "" {
+		return defaultBlockReason
+	}
+	return reason
+}
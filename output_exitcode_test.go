@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// withHookOutputMode sets -output for the duration of the test.
+func withHookOutputMode(t *testing.T, mode string) {
+	t.Helper()
+	original := hookOutputMode
+	hookOutputMode = mode
+	t.Cleanup(func() { hookOutputMode = original })
+}
+
+func TestExitCodeDecision(t *testing.T) {
+	tests := []struct {
+		name        string
+		eventType   HookEventType
+		output      string
+		wantContext string
+		wantReason  string
+		wantBlocked bool
+	}{
+		{"PreToolUse allow", PreToolUse, `{"continue":true,"hookSpecificOutput":{"hookEventName":"PreToolUse","permissionDecision":"allow"}}`, "", "", false},
+		{"PreToolUse deny", PreToolUse, `{"continue":true,"hookSpecificOutput":{"hookEventName":"PreToolUse","permissionDecision":"deny","permissionDecisionReason":"use uv"}}`, "", "use uv", true},
+		{"PreToolUse ask blocks", PreToolUse, `{"continue":true,"hookSpecificOutput":{"hookEventName":"PreToolUse","permissionDecision":"ask"}}`, "", defaultBlockReason, true},
+		{"PermissionRequest deny", PermissionRequest, `{"continue":true,"hookSpecificOutput":{"hookEventName":"PermissionRequest","decision":{"behavior":"deny","message":"no"}}}`, "", "no", true},
+		{"PermissionRequest allow", PermissionRequest, `{"continue":true,"hookSpecificOutput":{"hookEventName":"PermissionRequest","decision":{"behavior":"allow"}}}`, "", "", false},
+		{"Stop block", Stop, `{"continue":true,"decision":"block","reason":"tests failed"}`, "", "tests failed", true},
+		{"PostToolUse no decision", PostToolUse, `{"continue":true}`, "", "", false},
+		{"UserPromptSubmit block uses systemMessage", UserPromptSubmit, `{"continue":true,"decision":"block","systemMessage":"no secrets"}`, "", "no secrets", true},
+		{"UserPromptSubmit context", UserPromptSubmit, `{"continue":true,"hookSpecificOutput":{"hookEventName":"UserPromptSubmit","additionalContext":"be brief"}}`, "be brief", "", false},
+		{"SessionStart context", SessionStart, `{"continue":true,"hookSpecificOutput":{"hookEventName":"SessionStart","additionalContext":"on main"}}`, "on main", "", false},
+		{"Notification can't block", Notification, `{"continue":false,"decision":"block"}`, "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			context, reason, blocked, err := exitCodeDecision(tt.eventType, []byte(tt.output))
+			if err != nil {
+				t.Fatalf("exitCodeDecision() error = %v", err)
+			}
+			if context != tt.wantContext || reason != tt.wantReason || blocked != tt.wantBlocked {
+				t.Errorf("exitCodeDecision() = %q, %q, %v, want %q, %q, %v", context, reason, blocked, tt.wantContext, tt.wantReason, tt.wantBlocked)
+			}
+		})
+	}
+}
+
+func TestWriteHookOutput(t *testing.T) {
+	deny := []byte(`{"continue":true,"hookSpecificOutput":{"hookEventName":"PreToolUse","permissionDecision":"deny","permissionDecisionReason":"use uv"}}`)
+
+	var buf bytes.Buffer
+	if err := writeHookOutput(&buf, PreToolUse, deny); err != nil || buf.String() != string(deny)+"\n" {
+		t.Errorf("writeHookOutput() = %q, %v, want the JSON output by default", buf.String(), err)
+	}
+
+	withHookOutputMode(t, outputModeExitCode)
+	buf.Reset()
+	err := writeHookOutput(&buf, PreToolUse, deny)
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 2 || !exitErr.Stderr || exitErr.Message != "use uv" || buf.Len() != 0 {
+		t.Errorf("writeHookOutput() = %q, %v, want exit code 2 with the reason on stderr", buf.String(), err)
+	}
+
+	buf.Reset()
+	if err := writeHookOutput(&buf, SessionStart, []byte(`{"continue":true,"hookSpecificOutput":{"hookEventName":"SessionStart","additionalContext":"on main"}}`)); err != nil || buf.String() != "on main\n" {
+		t.Errorf("writeHookOutput() = %q, %v, want the additional context as plain text", buf.String(), err)
+	}
+
+	if err := writeHookOutput(&buf, Stop, []byte("not json")); !errors.As(err, &exitErr) || exitErr.Code != 2 {
+		t.Errorf("writeHookOutput() error = %v, want a fail-safe block for unparsable output", err)
+	}
+}
+
+func TestRunPermissionRequestHooks_ExitCodeOutput(t *testing.T) {
+	withHookOutputMode(t, outputModeExitCode)
+	withStdin(t, `{"session_id":"s1","hook_event_name":"PermissionRequest","tool_name":"Bash","tool_input":{"command":"rm -rf /"}}`)
+
+	config := &Config{PermissionRequest: []PermissionRequestHook{{
+		Matcher: "Bash",
+		Actions: []Action{{Type: "output", Message: "denied: {.tool_input.command}", Behavior: stringPtr("deny")}},
+	}}}
+	var err error
+	stdout := captureStdout(t, func() {
+		err = RunPermissionRequestHooks(config)
+	})
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 2 || exitErr.Message != "denied: rm -rf /" {
+		t.Errorf("RunPermissionRequestHooks() error = %v, want exit code 2", err)
+	}
+	if stdout != "" {
+		t.Errorf("Expected no stdout, got %q", stdout)
+	}
+}
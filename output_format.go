@@ -0,0 +1,28 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Values of the `output_format` setting, applied to the JSON output of every event.
+const (
+	outputFormatIndent2 = "indent2" // 2-space indented (default)
+	outputFormatCompact = "compact" // single line, e.g. for logging pipelines
+)
+
+// validateOutputFormat checks the `output_format` setting of a config.
+func validateOutputFormat(format string) error {
+	if format != "" && format != outputFormatIndent2 && format != outputFormatCompact {
+		return fmt.Errorf("output_format: invalid value %q (must be indent2 or compact)", format)
+	}
+	return nil
+}
+
+// marshalOutput encodes the JSON output of an event following the `output_format` setting.
+func marshalOutput(format string, output any) ([]byte, error) {
+	if format == outputFormatCompact {
+		return json.Marshal(output)
+	}
+	return json.MarshalIndent(output, "", "  ")
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarshalOutput(t *testing.T) {
+	output := &StopOutput{Continue: true, Decision: "block", Reason: "tests failed"}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"", "{\n  \"continue\": true,\n  \"decision\": \"block\",\n  \"reason\": \"tests failed\"\n}"},
+		{outputFormatIndent2, "{\n  \"continue\": true,\n  \"decision\": \"block\",\n  \"reason\": \"tests failed\"\n}"},
+		{outputFormatCompact, `{"continue":true,"decision":"block","reason":"tests failed"}`},
+	}
+	for _, tt := range tests {
+		got, err := marshalOutput(tt.format, output)
+		if err != nil {
+			t.Fatalf("marshalOutput(%q) error = %v", tt.format, err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("marshalOutput(%q) = %s, want %s", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestRunPermissionRequestHooks_CompactOutput(t *testing.T) {
+	withStdin(t, `{"session_id":"s1","hook_event_name":"PermissionRequest","tool_name":"Bash","tool_input":{"command":"ls"}}`)
+
+	config := &Config{
+		OutputFormat: outputFormatCompact,
+		PermissionRequest: []PermissionRequestHook{{
+			Matcher: "Bash",
+			Actions: []Action{{Type: "output", Message: "ok", Behavior: stringPtr("allow")}},
+		}},
+	}
+	var err error
+	stdout := captureStdout(t, func() {
+		err = RunPermissionRequestHooks(config)
+	})
+	if err != nil {
+		t.Fatalf("RunPermissionRequestHooks() error = %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(stdout), "\n"); len(lines) != 1 || !strings.Contains(lines[0], `"behavior":"allow"`) {
+		t.Errorf("Expected single-line JSON output, got %q", stdout)
+	}
+}
+
+func TestLoadConfig_InvalidOutputFormat(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFiles(t, dir, map[string]string{"config.yaml": "output_format: pretty\n"})
+
+	if _, err := loadConfig(dir + "/config.yaml"); err == nil || !strings.Contains(err.Error(), `invalid value "pretty"`) {
+		t.Errorf("loadConfig() error = %v, want invalid output_format", err)
+	}
+}
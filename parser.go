@@ -1,20 +1,64 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 )
 
+// prefetchedStdin is the event JSON read from stdin before the hooks run (see prefetchInput).
+type prefetchedStdin struct {
+	raw json.RawMessage
+	err error
+}
+
+// prefetchedInput is set once main has read stdin ahead of parsing; parseInput then uses it instead of os.Stdin.
+var prefetchedInput *prefetchedStdin
+
+// prefetchInput reads the event JSON from stdin ahead of parsing (e.g. to find the project config from its cwd).
+// The result, including a read error, is what parseInput and later calls return.
+func prefetchInput() (json.RawMessage, error) {
+	if prefetchedInput != nil {
+		return prefetchedInput.raw, prefetchedInput.err
+	}
+	raw, err := readInput(os.Stdin)
+	prefetchedInput = &prefetchedStdin{raw: raw, err: err}
+	return raw, err
+}
+
+// stdinReader returns the reader of the event JSON: the prefetched input if any, os.Stdin otherwise.
+func stdinReader() (io.Reader, error) {
+	if prefetchedInput == nil {
+		return os.Stdin, nil
+	}
+	if prefetchedInput.err != nil {
+		return nil, prefetchedInput.err
+	}
+	return bytes.NewReader(prefetchedInput.raw), nil
+}
+
 // parseInput parses JSON input from stdin and returns both structured data and raw JSON.
 // It handles special processing for PreToolUse and PostToolUse events that have complex tool_input fields.
 func parseInput[T HookInput](eventType HookEventType) (T, any, error) {
-	var rawInput json.RawMessage
+	r, err := stdinReader()
+	if err != nil {
+		var input T
+		return input, nil, err
+	}
+	return parseInputFrom[T](r, eventType)
+}
+
+// parseInputFrom is parseInput reading from an arbitrary reader (used by simulate).
+func parseInputFrom[T HookInput](r io.Reader, eventType HookEventType) (T, any, error) {
 	var input T
 
-	// まずJSONを取得
-	if err := json.NewDecoder(os.Stdin).Decode(&rawInput); err != nil {
-		return input, nil, fmt.Errorf("failed to decode JSON input: %w", err)
+	// まずJSONを取得（巨大な入力はmaxInputSizeに従って切り詰める）
+	rawInput, err := readInput(r)
+	if err != nil {
+		return input, nil, err
 	}
 
 	// 生のJSONをinterface{}に変換（JQ用）
@@ -31,27 +75,75 @@ func parseInput[T HookInput](eventType HookEventType) (T, any, error) {
 		if err != nil {
 			return input, nil, err
 		}
-		if result, ok := any(preInput).(T); ok {
-			return result, rawJSON, nil
+		withNormalizedFilePath(rawJSON, &preInput.ToolInput, preInput.Cwd)
+		result, ok := any(preInput).(T)
+		if !ok {
+			return input, nil, fmt.Errorf("type assertion failed for %s", eventType)
 		}
-		return input, nil, fmt.Errorf("type assertion failed for %s", eventType)
+		input = result
 
 	case PostToolUse:
 		postInput, err := parsePostToolUseInput(rawInput)
 		if err != nil {
 			return input, nil, err
 		}
-		if result, ok := any(postInput).(T); ok {
-			return result, rawJSON, nil
+		withNormalizedFilePath(rawJSON, &postInput.ToolInput, postInput.Cwd)
+		result, ok := any(postInput).(T)
+		if !ok {
+			return input, nil, fmt.Errorf("type assertion failed for PostToolUse")
 		}
-		return input, nil, fmt.Errorf("type assertion failed for PostToolUse")
+		input = result
 
 	default:
 		// その他のイベントタイプは従来通り
 		if err := json.Unmarshal(rawInput, &input); err != nil {
 			return input, nil, fmt.Errorf("failed to decode %s input: %w", eventType, err)
 		}
-		return input, rawJSON, nil
+	}
+
+	// Bashのgit pushとMCPツールのサーバー・ツール名をテンプレートに渡す
+	if eventType == PreToolUse || eventType == PostToolUse || eventType == PermissionRequest {
+		withGitPush(rawJSON)
+		withMCPTool(rawJSON)
+	}
+
+	// tool_input_jq条件は生のJSONに対して評価する
+	if setter, ok := any(input).(interface{ setRawJSON(any) }); ok && rawJSON != nil {
+		setter.setRawJSON(rawJSON)
+	}
+	return input, rawJSON, nil
+}
+
+// normalizeFilePath returns tool_input.file_path in the form conditions and templates see it: a leading ~ expanded
+// to the home directory, resolved against cwd and cleaned (. and .. segments, repeated separators), so that
+// conditions agree on the file whatever form Claude wrote the path in. Without a cwd relative paths stay relative.
+func normalizeFilePath(filePath, cwd string) string {
+	if filePath == "" {
+		return ""
+	}
+	if filePath == "~" {
+		filePath = "~/"
+	}
+	filePath = expandHomeDir(filePath)
+	if !filepath.IsAbs(filePath) && cwd != "" {
+		filePath = filepath.Join(cwd, filePath)
+	}
+	return filepath.Clean(filePath)
+}
+
+// withNormalizedFilePath normalizes tool_input.file_path of the parsed input and the event JSON (see normalizeFilePath)
+// before any condition or template sees it, keeping the path Claude sent as tool_input.file_path_raw.
+func withNormalizedFilePath(rawJSON any, toolInput *ToolInput, cwd string) {
+	if toolInput.FilePath == "" {
+		return
+	}
+	raw := toolInput.FilePath
+	toolInput.FilePath = normalizeFilePath(raw, cwd)
+	if m, ok := rawJSON.(map[string]any); ok {
+		if fields, ok := m["tool_input"].(map[string]any); ok {
+			fields["file_path"] = toolInput.FilePath
+			fields["file_path_raw"] = raw
+		}
 	}
 }
 
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// Values of the `strict_permissions` setting.
+const (
+	strictPermissionsWarn   = "warn"   // print a warning and run the hooks (default)
+	strictPermissionsRefuse = "refuse" // don't run the hooks
+	strictPermissionsOff    = "off"    // don't check
+)
+
+// scriptInterpreters maps commands whose first non-option argument is the script they run
+// to their option for inline code (e.g. "bash -c '...'" runs no script file).
+var scriptInterpreters = map[string]string{
+	"sh": "-c", "bash": "-c", "zsh": "-c", "fish": "-c",
+	"python": "-c", "python3": "-c",
+	"node": "-e", "ruby": "-e", "perl": "-e", "php": "-r",
+}
+
+// validateStrictPermissions checks the `strict_permissions` setting of a config.
+func validateStrictPermissions(mode string) error {
+	switch mode {
+	case "", strictPermissionsWarn, strictPermissionsRefuse, strictPermissionsOff:
+		return nil
+	default:
+		return fmt.Errorf("strict_permissions: invalid value %q (must be warn, refuse or off)", mode)
+	}
+}
+
+// checkFilePermissions reports config files (with the compiled artifacts and parsed config caches they may be
+// loaded from) and scripts run by the command actions of eventType that can be modified by other users (group or world writable): anyone who can write them can run
+// arbitrary commands with the user's privileges on every hook event.
+// With `strict_permissions: refuse` an error is returned, otherwise a warning is printed to stderr.
+func checkFilePermissions(config *Config, eventType HookEventType) error {
+	if config.StrictPermissions == strictPermissionsOff || runtime.GOOS == "windows" {
+		return nil
+	}
+
+	var paths []string
+	for _, file := range config.Files {
+		paths = append(paths, file, compiledConfigPath(file), configCachePath(file))
+	}
+	for _, action := range eventActions(config, eventType) {
+		// devcontainerランナーはdevcontainerが無いプロジェクトではローカルで実行する
+		if action.Type == "command" && (action.Runner == "" || action.Runner == runnerDevcontainer) {
+			paths = append(paths, commandScripts(configuredCommand(action))...)
+		}
+	}
+	var insecure []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if info.Mode().Perm()&0o022 != 0 {
+			insecure = append(insecure, fmt.Sprintf("%s (%s)", path, info.Mode().Perm()))
+		}
+	}
+	if len(insecure) == 0 {
+		return nil
+	}
+
+	msg := fmt.Sprintf("writable by group or others: %s", strings.Join(insecure, ", "))
+	if config.StrictPermissions == strictPermissionsRefuse {
+		return fmt.Errorf("refusing to run hooks: %s (fix with chmod go-w)", msg)
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %s (fix with chmod go-w, or set strict_permissions: refuse to refuse running)\n", msg)
+	return nil
+}
+
+// eventActions returns the actions of every hook of eventType.
+func eventActions(config *Config, eventType HookEventType) []Action {
+	return slices.Concat(hookActions(config, eventType)...)
+}
+
+// commandScripts returns the paths of the scripts a command runs: commands given by path
+// (e.g. "./scripts/lint.sh", "~/bin/check") and scripts passed to an interpreter (e.g. "bash hooks/fmt.sh").
+// Commands looked up in PATH are not included.
+func commandScripts(command string) []string {
+	file, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+	if err != nil {
+		return nil
+	}
+	cfg := &expand.Config{Env: expand.FuncEnviron(os.Getenv)}
+
+	var scripts []string
+	syntax.Walk(file, func(node syntax.Node) bool {
+		call, ok := node.(*syntax.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+		var args []string
+		for _, word := range call.Args {
+			fields, err := expand.Fields(cfg, word)
+			if err != nil || len(fields) != 1 {
+				break
+			}
+			args = append(args, fields[0])
+		}
+		if len(args) == 0 {
+			return true
+		}
+
+		script := args[0]
+		if inline, ok := scriptInterpreters[filepath.Base(args[0])]; ok {
+			script = ""
+			for _, arg := range args[1:] {
+				if arg == inline {
+					break
+				}
+				if !strings.HasPrefix(arg, "-") {
+					script = arg
+					break
+				}
+			}
+		}
+		if strings.Contains(script, "/") || (script != args[0] && script != "") {
+			scripts = append(scripts, script)
+		}
+		return true
+	})
+	return scripts
+}
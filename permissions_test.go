@@ -0,0 +1,121 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCommandScripts(t *testing.T) {
+	t.Setenv("HOOKS_DIR", "/opt/hooks")
+
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"gofmt -w {.tool_input.file_path}", nil},
+		{"./scripts/lint.sh --fix", []string{"./scripts/lint.sh"}},
+		{"bash -e hooks/fmt.sh arg", []string{"hooks/fmt.sh"}},
+		{"python3 check.py", []string{"check.py"}},
+		{"cd sub && $HOOKS_DIR/run.sh | tee out.log", []string{"/opt/hooks/run.sh"}},
+		{"/usr/bin/env true; sh -c './inline.sh'", []string{"/usr/bin/env"}},
+		{"echo 'unterminated", nil},
+	}
+	for _, tt := range tests {
+		got := commandScripts(tt.command)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("commandScripts(%q) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestCheckFilePermissions(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	scriptPath := filepath.Join(dir, "check.sh")
+	for _, path := range []string{configPath, scriptPath} {
+		if err := os.WriteFile(path, []byte("# test\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := &Config{
+		Files: []string{configPath},
+		Stop:  []StopHook{{Actions: []Action{{Type: "command", Command: scriptPath + " --strict"}}}},
+	}
+
+	config.StrictPermissions = strictPermissionsRefuse
+	if err := checkFilePermissions(config, Stop); err != nil {
+		t.Errorf("checkFilePermissions() error = %v, want nil for 0600 files", err)
+	}
+
+	if err := os.Chmod(scriptPath, 0o777); err != nil {
+		t.Fatal(err)
+	}
+	err := checkFilePermissions(config, Stop)
+	if err == nil || !strings.Contains(err.Error(), scriptPath+" (-rwxrwxrwx)") || strings.Contains(err.Error(), configPath) {
+		t.Errorf("checkFilePermissions() error = %v, want the world-writable script", err)
+	}
+	// 他のイベントのスクリプトは見ない
+	if err := checkFilePermissions(config, PreToolUse); err != nil {
+		t.Errorf("checkFilePermissions(PreToolUse) error = %v, want nil", err)
+	}
+
+	if err := os.Chmod(configPath, 0o660); err != nil {
+		t.Fatal(err)
+	}
+	config.StrictPermissions = ""
+	if err := checkFilePermissions(config, Stop); err != nil {
+		t.Errorf("checkFilePermissions() error = %v, want only a warning by default", err)
+	}
+
+	// コンパイル済みの設定やキャッシュはYAMLより先に読まれる
+	if err := os.Chmod(configPath, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(compiledConfigPath(configPath), nil, 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(compiledConfigPath(configPath), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	config.StrictPermissions = strictPermissionsRefuse
+	if err := checkFilePermissions(config, Stop); err == nil || !strings.Contains(err.Error(), compiledConfigPath(configPath)) {
+		t.Errorf("checkFilePermissions() error = %v, want the world-writable compiled config", err)
+	}
+
+	config.StrictPermissions = strictPermissionsOff
+	config.Files = append(config.Files, filepath.Join(dir, "missing.yaml"))
+	if err := checkFilePermissions(config, Stop); err != nil {
+		t.Errorf("checkFilePermissions() error = %v, want nil when off", err)
+	}
+}
+
+func TestLoadConfig_StrictPermissions(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFiles(t, dir, map[string]string{
+		"config.yaml":  "include: [shared.yaml]\nstrict_permissions: refuse\n",
+		"shared.yaml":  "strict_permissions: off\n" + stopHook("shared"),
+		"invalid.yaml": "strict_permissions: strict\n",
+	})
+
+	config, err := loadConfig(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	if config.StrictPermissions != strictPermissionsRefuse {
+		t.Errorf("StrictPermissions = %q, want the value of the main config", config.StrictPermissions)
+	}
+	want := []string{filepath.Join(dir, "shared.yaml"), filepath.Join(dir, "config.yaml")}
+	slices.Sort(want)
+	got := slices.Sorted(slices.Values(config.Files))
+	if !slices.Equal(got, want) {
+		t.Errorf("Files = %q, want %q", got, want)
+	}
+
+	if _, err := loadConfig(filepath.Join(dir, "invalid.yaml")); err == nil || !strings.Contains(err.Error(), `invalid value "strict"`) {
+		t.Errorf("loadConfig() error = %v, want invalid strict_permissions", err)
+	}
+}
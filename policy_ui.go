@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultUIListenAddr is the default address of `cchook -command ui` (loopback only).
+const defaultUIListenAddr = "127.0.0.1:8765"
+
+// policyUIRecentDecisions is the number of decisions of the decision log the policy UI shows.
+const policyUIRecentDecisions = 50
+
+// policyUIRedacted replaces header values and URL credentials in the hooks shown by the policy UI.
+const policyUIRedacted = "REDACTED"
+
+// policyUIEventOrder is the display order of events in the policy UI.
+var policyUIEventOrder = []HookEventType{
+	PreToolUse, PostToolUse, PermissionRequest, UserPromptSubmit, Notification,
+	Stop, SubagentStop, SubagentStart, PreCompact, SessionStart, SessionEnd,
+}
+
+// policyUIEventHooks is the hooks of a single event rendered in the policy UI.
+type policyUIEventHooks struct {
+	Event string
+	Count int
+	YAML  string
+}
+
+// policyUIDecision is a decision of the decision log rendered in the policy UI.
+type policyUIDecision struct {
+	Time string
+	decision
+}
+
+// policyUIPage is the data passed to policyUITemplate.
+type policyUIPage struct {
+	ConfigPath     string
+	ConfigError    string
+	Events         []policyUIEventHooks
+	DecisionLog    string // decision_logのファイル ("" なら設定されていない)
+	Decisions      []policyUIDecision
+	DecisionsError string
+	EventTypes     []string
+	TestEvent      string
+	TestInput      string
+	TestResult     string
+	TestError      string
+}
+
+var policyUITemplate = template.Must(template.New("ui").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>cchook policies</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+pre { background: #f4f4f4; padding: 1em; overflow-x: auto; }
+textarea { width: 100%; font-family: monospace; }
+.error { color: #b00; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.2em 0.5em; text-align: left; vertical-align: top; }
+</style>
+</head>
+<body>
+<h1>cchook policies</h1>
+<p>Config: <code>{{.ConfigPath}}</code> (read-only)</p>
+{{if .ConfigError}}<p class="error">{{.ConfigError}}</p>{{end}}
+
+<h2>Hooks</h2>
+{{range .Events}}
+<h3>{{.Event}} ({{.Count}})</h3>
+<pre>{{.YAML}}</pre>
+{{else}}
+<p>No hooks configured.</p>
+{{end}}
+
+<h2>Recent decisions</h2>
+{{if .DecisionsError}}<p class="error">{{.DecisionsError}}</p>
+{{else if not .DecisionLog}}<p>No decision log (set <code>decision_log.path</code> in the config).</p>
+{{else}}
+<p>Latest first, from <code>{{.DecisionLog}}</code>. Run <code>cchook -command explain-decision &lt;trace&gt;</code> for the details of a decision.</p>
+<table>
+<tr><th>Time</th><th>Event</th><th>Tool</th><th>Decision</th><th>Reason</th><th>Hooks</th><th>Trace</th></tr>
+{{range .Decisions}}<tr><td>{{.Time}}</td><td>{{.Event}}</td><td>{{.ToolName}}</td><td>{{.Decision}}</td><td>{{.Reason}}</td><td>{{range .Hooks}}{{.}} {{end}}</td><td><code>{{.TraceID}}</code></td></tr>
+{{else}}<tr><td colspan="7">No decisions yet.</td></tr>
+{{end}}</table>
+{{end}}
+
+<h2>Test an event</h2>
+<p>Shows which hooks would match (dry-run). No commands are executed.</p>
+<form method="post" action="/test">
+<select name="event">
+{{range .EventTypes}}<option value="{{.}}"{{if eq . $.TestEvent}} selected{{end}}>{{.}}</option>{{end}}
+</select>
+<textarea name="input" rows="10">{{.TestInput}}</textarea>
+<button type="submit">Test</button>
+</form>
+{{if .TestError}}<p class="error">{{.TestError}}</p>{{end}}
+{{if .TestResult}}<pre>{{.TestResult}}</pre>{{end}}
+</body>
+</html>
+`))
+
+// newPolicyUIHandler returns a read-only web UI listing configured hooks, the recent decisions of the decision log
+// and a form to dry-run an event. The config is re-read on every request so that edits are reflected without
+// restarting. Requests are serialized with the events of the daemon (see eventMutex).
+// Requests whose Host isn't a loopback address are rejected, so that a DNS rebinding page can't read the config.
+func newPolicyUIHandler(configPath string) http.Handler {
+	if configPath == "" {
+		configPath = getDefaultConfigPath()
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		page, _ := newPolicyUIPage(configPath)
+		page.TestEvent = string(PreToolUse)
+		page.TestInput = eventTemplates[PreToolUse]
+		renderPolicyUI(w, page)
+	})
+	mux.HandleFunc("/test", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if crossOriginRequest(r) {
+			http.Error(w, "cross-origin requests are not allowed", http.StatusForbidden)
+			return
+		}
+		page, config := newPolicyUIPage(configPath)
+		page.TestEvent = r.FormValue("event")
+		page.TestInput = r.FormValue("input")
+
+		eventType := HookEventType(page.TestEvent)
+		switch {
+		case !eventType.IsValid():
+			page.TestError = "invalid event type: " + page.TestEvent
+		case config == nil:
+			page.TestError = "config could not be loaded"
+		default:
+			resetEventCaches()
+			var buf bytes.Buffer
+			if err := dryRunHooksFrom(&buf, strings.NewReader(page.TestInput), config, eventType); err != nil {
+				page.TestError = err.Error()
+			}
+			page.TestResult = buf.String()
+		}
+		renderPolicyUI(w, page)
+	})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !loopbackHost(r.Host) {
+			http.Error(w, "the policy UI only accepts requests to a loopback host", http.StatusForbidden)
+			return
+		}
+		eventMutex.Lock()
+		defer eventMutex.Unlock()
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// loopbackHost reports whether host (the Host header, with or without a port) names the local machine.
+func loopbackHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// crossOriginRequest reports whether r was sent by a page of another origin (CSRF): browsers set Origin and
+// Sec-Fetch-Site on cross-origin POSTs, while clients such as curl set neither.
+func crossOriginRequest(r *http.Request) bool {
+	if site := r.Header.Get("Sec-Fetch-Site"); site != "" && site != "same-origin" && site != "none" {
+		return true
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	u, err := url.Parse(origin)
+	return err != nil || u.Host != r.Host
+}
+
+// recentDecisions returns the last n decisions of the decision log at path, latest first.
+func recentDecisions(path string, n int) ([]policyUIDecision, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open decision log: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var decisions []policyUIDecision
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		d, at, ok := parseDecisionRecord(scanner.Bytes())
+		if !ok {
+			continue
+		}
+		decisions = append(decisions, policyUIDecision{Time: at.Local().Format(time.DateTime), decision: d})
+		if len(decisions) > n {
+			decisions = decisions[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read decision log: %w", err)
+	}
+	slices.Reverse(decisions)
+	return decisions, nil
+}
+
+// newPolicyUIPage loads the config and builds the hook listing.
+// The returned config is nil when it could not be loaded.
+func newPolicyUIPage(configPath string) (policyUIPage, *Config) {
+	page := policyUIPage{ConfigPath: configPath}
+	for _, eventType := range policyUIEventOrder {
+		page.EventTypes = append(page.EventTypes, string(eventType))
+	}
+
+	config, err := loadConfig(configPath)
+	if err != nil {
+		page.ConfigError = err.Error()
+		return page, nil
+	}
+
+	if config.DecisionLog != nil && config.DecisionLog.Path != "" {
+		page.DecisionLog = expandHomeDir(config.DecisionLog.Path)
+		decisions, err := recentDecisions(page.DecisionLog, policyUIRecentDecisions)
+		if err != nil {
+			page.DecisionsError = err.Error()
+		}
+		page.Decisions = decisions
+	}
+
+	for _, eventType := range policyUIEventOrder {
+		hooks, count := configHooksForEvent(config, eventType)
+		if count == 0 {
+			continue
+		}
+		var node yaml.Node
+		if err := node.Encode(hooks); err != nil {
+			page.ConfigError = err.Error()
+			continue
+		}
+		redactPolicyUINode(&node)
+		data, err := yaml.Marshal(&node)
+		if err != nil {
+			page.ConfigError = err.Error()
+			continue
+		}
+		page.Events = append(page.Events, policyUIEventHooks{
+			Event: string(eventType),
+			Count: count,
+			YAML:  string(data),
+		})
+	}
+	return page, config
+}
+
+// redactPolicyUINode redacts the hooks encoded in node before they are shown: the values of headers
+// (e.g. bearer tokens of http actions), the credentials of urls, and secrets found in any other value.
+func redactPolicyUINode(node *yaml.Node) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			switch {
+			case key.Value == "headers" && value.Kind == yaml.MappingNode:
+				for j := 1; j < len(value.Content); j += 2 {
+					value.Content[j].SetString(policyUIRedacted)
+				}
+			case key.Value == "url" && value.Kind == yaml.ScalarNode:
+				value.SetString(redactURLCredentials(value.Value))
+			default:
+				redactPolicyUINode(value)
+			}
+		}
+	case yaml.SequenceNode, yaml.DocumentNode:
+		for _, item := range node.Content {
+			redactPolicyUINode(item)
+		}
+	case yaml.ScalarNode:
+		for _, finding := range scanSecrets("", node.Value) {
+			node.Value = strings.ReplaceAll(node.Value, finding.Secret, redactSecret(finding.Secret))
+		}
+	}
+}
+
+// redactURLCredentials replaces the user info of rawURL (user:password@ or token@) and secrets found in it.
+func redactURLCredentials(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.User != nil {
+		u.User = url.User(policyUIRedacted)
+		rawURL = u.String()
+	}
+	for _, finding := range scanSecrets("", rawURL) {
+		rawURL = strings.ReplaceAll(rawURL, finding.Secret, redactSecret(finding.Secret))
+	}
+	return rawURL
+}
+
+// configHooksForEvent returns the hook slice of eventType and its length.
+func configHooksForEvent(config *Config, eventType HookEventType) (any, int) {
+	switch eventType {
+	case PreToolUse:
+		return config.PreToolUse, len(config.PreToolUse)
+	case PostToolUse:
+		return config.PostToolUse, len(config.PostToolUse)
+	case PermissionRequest:
+		return config.PermissionRequest, len(config.PermissionRequest)
+	case Notification:
+		return config.Notification, len(config.Notification)
+	case Stop:
+		return config.Stop, len(config.Stop)
+	case SubagentStop:
+		return config.SubagentStop, len(config.SubagentStop)
+	case SubagentStart:
+		return config.SubagentStart, len(config.SubagentStart)
+	case PreCompact:
+		return config.PreCompact, len(config.PreCompact)
+	case SessionStart:
+		return config.SessionStart, len(config.SessionStart)
+	case SessionEnd:
+		return config.SessionEnd, len(config.SessionEnd)
+	case UserPromptSubmit:
+		return config.UserPromptSubmit, len(config.UserPromptSubmit)
+	default:
+		return nil, 0
+	}
+}
+
+// renderPolicyUI writes the policy UI page.
+func renderPolicyUI(w http.ResponseWriter, page policyUIPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := policyUITemplate.Execute(w, page); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
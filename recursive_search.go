@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// recursiveSearchTimeout is the time budget of the *_recursive conditions, so that a search of a huge tree
+// fails the hook instead of hanging Claude Code. It is a variable so that tests can shorten it.
+var recursiveSearchTimeout = 5 * time.Second
+
+// recursiveSearch is a search of the current directory tree by a *_recursive condition.
+type recursiveSearch struct {
+	ctx      context.Context
+	name     string
+	isDir    bool
+	maxDepth int      // 0 for no limit
+	exclude  []string // globs of entries not to search
+	repoRoot string   // "" outside a git repository
+}
+
+// existsRecursive searches the current directory tree for a file or directory (isDir) named like the condition value,
+// within the condition's max_depth and not looking into excluded entries, .git and directories ignored by git
+// (an ignored directory itself is still found by name). The search fails after recursiveSearchTimeout.
+func existsRecursive(condition Condition, isDir bool) (bool, error) {
+	if condition.Value == "" {
+		return false, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), recursiveSearchTimeout)
+	defer cancel()
+
+	search := &recursiveSearch{ctx: ctx, name: condition.Value, isDir: isDir, maxDepth: condition.MaxDepth, exclude: condition.Exclude}
+	var patterns []gitignore.Pattern
+	if cwd, err := filepath.Abs("."); err == nil {
+		if root, ok := findUpward(cwd, ".git"); ok {
+			search.repoRoot = root
+			// リポジトリのルートからcwdまでの.gitignoreも適用する
+			patterns = gitignorePatterns(root, append(search.repoSegments(cwd), ""))
+		}
+	}
+	found, err := search.walk(".", 1, patterns)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return false, fmt.Errorf("search for %q timed out after %s (narrow it with max_depth or exclude)", condition.Value, recursiveSearchTimeout)
+	}
+	return found, err
+}
+
+// repoSegments returns the path segments of the absolute path dir relative to the repository root.
+func (s *recursiveSearch) repoSegments(dir string) []string {
+	rel, err := filepath.Rel(s.repoRoot, dir)
+	if err != nil || rel == "." {
+		return nil
+	}
+	return strings.Split(filepath.ToSlash(rel), "/")
+}
+
+// walk searches the entries of dir, at the given depth (1 for the entries of the search root).
+// patterns are the gitignore patterns that apply to dir.
+func (s *recursiveSearch) walk(dir string, depth int, patterns []gitignore.Pattern) (bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false, nil // 読めないディレクトリは飛ばす
+	}
+	var segments []string
+	if s.repoRoot != "" {
+		if abs, err := filepath.Abs(dir); err == nil {
+			segments = s.repoSegments(abs)
+		}
+		if dir != "." {
+			patterns = append(slices.Clip(patterns), readGitignoreFile(filepath.Join(dir, ".gitignore"), segments)...)
+		}
+	}
+	matcher := gitignore.NewMatcher(patterns)
+
+	var subdirs []string
+	for _, entry := range entries {
+		if err := s.ctx.Err(); err != nil {
+			return false, err
+		}
+		entryPath := filepath.Join(dir, entry.Name())
+		if s.excluded(entryPath) {
+			continue
+		}
+		if entry.IsDir() == s.isDir && entry.Name() == s.name {
+			return true, nil
+		}
+		if !entry.IsDir() || entry.Name() == ".git" || (s.maxDepth > 0 && depth >= s.maxDepth) {
+			continue
+		}
+		if s.repoRoot != "" && matcher.Match(append(slices.Clip(segments), entry.Name()), true) {
+			continue
+		}
+		subdirs = append(subdirs, entryPath)
+	}
+	// 浅い場所にあるものを先に見つける
+	for _, subdir := range subdirs {
+		if found, err := s.walk(subdir, depth+1, patterns); found || err != nil {
+			return found, err
+		}
+	}
+	return false, nil
+}
+
+// excluded reports whether an entry matches an exclude glob: patterns with a slash are matched against
+// the path relative to the search root, others against the entry name.
+func (s *recursiveSearch) excluded(entryPath string) bool {
+	rel := filepath.ToSlash(entryPath)
+	for _, pattern := range s.exclude {
+		target := path.Base(rel)
+		if strings.Contains(pattern, "/") {
+			target = rel
+		}
+		if matched, _ := matchGlob(strings.TrimSuffix(pattern, "/"), target); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExistsRecursive(t *testing.T) {
+	dir := t.TempDir()
+	for path, content := range map[string]string{
+		".git/HEAD":             "ref: refs/heads/main\n",
+		".gitignore":            "build/\n",
+		"build/target.txt":      "",
+		"a/b/c/deep.txt":        "",
+		"vendor/lib.txt":        "",
+		"sub/.gitignore":        "private/\n",
+		"sub/private/secret.go": "",
+	} {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(dir)
+
+	tests := []struct {
+		name      string
+		condition Condition
+		isDir     bool
+		want      bool
+	}{
+		{"found", Condition{Value: "deep.txt"}, false, true},
+		{"within max_depth", Condition{Value: "deep.txt", MaxDepth: 4}, false, true},
+		{"beyond max_depth", Condition{Value: "deep.txt", MaxDepth: 3}, false, false},
+		{"excluded by name", Condition{Value: "lib.txt", Exclude: []string{"vendor"}}, false, false},
+		{"excluded by path", Condition{Value: "deep.txt", Exclude: []string{"a/b/"}}, false, false},
+		{"name exclude at any depth", Condition{Value: "deep.txt", Exclude: []string{"b"}}, false, false},
+		{"glob exclude", Condition{Value: "lib.txt", Exclude: []string{"vend*"}}, false, false},
+		{"not searched in ignored directories", Condition{Value: "target.txt"}, false, false},
+		{"nested .gitignore", Condition{Value: "secret.go"}, false, false},
+		{"ignored directory is found by name", Condition{Value: "build"}, true, true},
+		{".git is not searched", Condition{Value: "HEAD"}, false, false},
+		{"directory", Condition{Value: "c"}, true, true},
+		{"file is not a directory", Condition{Value: "deep.txt"}, true, false},
+		{"empty value", Condition{}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := existsRecursive(tt.condition, tt.isDir)
+			if err != nil || got != tt.want {
+				t.Errorf("existsRecursive() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+
+	original := recursiveSearchTimeout
+	recursiveSearchTimeout = 0
+	t.Cleanup(func() { recursiveSearchTimeout = original })
+	if _, err := checkCommonCondition(Condition{Type: ConditionFileExistsRecursive, Value: "missing.txt"}, &BaseInput{}); err == nil ||
+		!strings.Contains(err.Error(), `file_exists_recursive: search for "missing.txt" timed out`) {
+		t.Errorf("Expected the search to time out, got %v", err)
+	}
+}
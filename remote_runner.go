@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Schemes of the `runner` field of command actions.
+const (
+	runnerSchemeSSH    = "ssh"
+	runnerSchemeDocker = "docker"
+)
+
+// sshControlPersist is how long an idle SSH master connection is kept open for later actions and events.
+const sshControlPersist = "10m"
+
+// sshControlDir returns the directory of the SSH control sockets shared by cchook processes.
+// It is a variable so that tests can replace it.
+var sshControlDir = func() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "cchook", "ssh")
+}
+
+// commandRunnerTarget is a parsed `runner` value.
+type commandRunnerTarget struct {
+	scheme string
+	host   string // ssh: [user@]host, docker: container name
+	port   string // ssh only
+	dir    string // working directory on the target ("" for the default)
+}
+
+// parseCommandRunner parses a `runner` value: ssh://[user@]host[:port][/dir], docker://container[/dir] or devcontainer.
+func parseCommandRunner(runner string) (*commandRunnerTarget, error) {
+	if runner == runnerDevcontainer {
+		return &commandRunnerTarget{scheme: runnerDevcontainer}, nil
+	}
+	u, err := url.Parse(runner)
+	if err != nil {
+		return nil, fmt.Errorf("invalid runner %q: %w", runner, err)
+	}
+	if u.RawQuery != "" || u.Fragment != "" || u.Opaque != "" {
+		return nil, fmt.Errorf("invalid runner %q: must be ssh://[user@]host[:port][/dir] or docker://container[/dir]", runner)
+	}
+	target := &commandRunnerTarget{scheme: u.Scheme, host: u.Hostname(), port: u.Port(), dir: u.Path}
+	if target.dir == "/" {
+		target.dir = ""
+	}
+
+	switch u.Scheme {
+	case runnerSchemeSSH:
+		if u.User != nil {
+			if _, hasPassword := u.User.Password(); hasPassword {
+				return nil, fmt.Errorf("invalid runner %q: passwords are not supported (use SSH keys)", runner)
+			}
+			target.host = u.User.Username() + "@" + target.host
+		}
+	case runnerSchemeDocker:
+		if u.User != nil || u.Port() != "" {
+			return nil, fmt.Errorf("invalid runner %q: docker runners take a container name only", runner)
+		}
+	default:
+		return nil, fmt.Errorf("invalid runner %q: scheme must be ssh or docker (or devcontainer)", runner)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid runner %q: missing host or container", runner)
+	}
+	return target, nil
+}
+
+// wrapCommand returns the local command line running cmd on the target.
+// stdin is forwarded, so use_stdin works the same as for local commands.
+func (t *commandRunnerTarget) wrapCommand(cmd string) string {
+	if t.scheme == runnerSchemeSSH && t.dir != "" {
+		cmd = "cd " + shellQuote(t.dir) + " && " + cmd
+	}
+
+	var args []string
+	switch t.scheme {
+	case runnerSchemeSSH:
+		// ControlMasterで接続を使い回し、アクション毎のSSHハンドシェイクを避ける
+		args = []string{"ssh", "-o", "BatchMode=yes", "-o", "ControlMaster=auto",
+			"-o", "ControlPath=" + filepath.Join(sshControlDir(), "%C"), "-o", "ControlPersist=" + sshControlPersist}
+		if t.port != "" {
+			args = append(args, "-p", t.port)
+		}
+		args = append(args, "--", t.host, cmd)
+	case runnerSchemeDocker:
+		args = []string{"docker", "exec", "-i"}
+		if t.dir != "" {
+			args = append(args, "-w", t.dir)
+		}
+		args = append(args, t.host, "sh", "-c", cmd)
+	}
+
+	for i, arg := range args {
+		args[i] = shellQuote(arg)
+	}
+	return strings.Join(args, " ")
+}
+
+// shellQuote quotes s as a single POSIX shell word.
+// Single quotes keep everything literal, including newlines of multi-line commands.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// remoteCommand returns the command line that runs cmd with the `runner` of an action (cmd itself without a runner).
+// cwd is the working directory of the event, used to find the project of the devcontainer runner.
+func remoteCommand(runner, cwd, cmd string) (string, error) {
+	if runner == "" {
+		return cmd, nil
+	}
+	target, err := parseCommandRunner(runner)
+	if err != nil {
+		return "", err
+	}
+	if target.scheme == runnerDevcontainer {
+		root, ok := findDevcontainerProject(cwd)
+		if !ok {
+			// プロジェクトがdevcontainerを定義していなければローカルで実行する
+			return cmd, nil
+		}
+		return devcontainerCommand(root, cwd, cmd), nil
+	}
+	if target.scheme == runnerSchemeSSH {
+		if err := os.MkdirAll(sshControlDir(), 0o700); err != nil {
+			return "", fmt.Errorf("failed to create SSH control directory: %w", err)
+		}
+	}
+	return target.wrapCommand(cmd), nil
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"maps"
+	"net/url"
+	"os"
+	"os/user"
+	"slices"
+	"time"
+)
+
+// defaultReportSince is the period of the decision log `cchook -command report` summarizes without -since.
+const defaultReportSince = "7d"
+
+// defaultReportTimeout is the timeout of `report push` without `timeout`.
+const defaultReportTimeout = 10 * time.Second
+
+// reportStatsVersion is the version of the stats payload, bumped when its fields change incompatibly.
+const reportStatsVersion = 1
+
+// reportDecisionOrder is the display order of decisions in the HTML report.
+var reportDecisionOrder = []string{decisionAllow, decisionDeny, decisionAsk, decisionBlock, decisionStop, decisionNone}
+
+// validateReportConfig checks the `report:` block of a config.
+func validateReportConfig(c *ReportConfig) error {
+	if c == nil {
+		return nil
+	}
+	if c.URL == "" {
+		return errors.New("report: url is required")
+	}
+	if err := validateSinkURL(c.URL); err != nil {
+		return fmt.Errorf("report: %w", err)
+	}
+	if _, err := parseSinkTimeout(c.Timeout, defaultReportTimeout); err != nil {
+		return fmt.Errorf("report: %w", err)
+	}
+	return nil
+}
+
+// decisionStats are the anonymized counts of decisions pushed to the team endpoint: no session IDs, paths,
+// tool inputs or reasons, and the reporter is a hash of the host and user names.
+type decisionStats struct {
+	Version   int                       `json:"version"`
+	Reporter  string                    `json:"reporter"`
+	Team      string                    `json:"team,omitempty"`
+	From      time.Time                 `json:"from"`
+	To        time.Time                 `json:"to"`
+	Total     int                       `json:"total"`
+	Decisions map[string]int            `json:"decisions"`
+	Events    map[string]map[string]int `json:"events"` // event → decision → count
+	Tools     map[string]map[string]int `json:"tools"`  // tool → decision → count
+	Hooks     map[string]map[string]int `json:"hooks"`  // event/hook ID → decision → count
+}
+
+// newDecisionStats returns empty stats of the period [from, to].
+func newDecisionStats(from, to time.Time) *decisionStats {
+	return &decisionStats{
+		Version:   reportStatsVersion,
+		From:      from,
+		To:        to,
+		Decisions: map[string]int{},
+		Events:    map[string]map[string]int{},
+		Tools:     map[string]map[string]int{},
+		Hooks:     map[string]map[string]int{},
+	}
+}
+
+// add counts a decision.
+func (s *decisionStats) add(d decision) {
+	s.Total++
+	s.Decisions[d.Decision]++
+	countDecision(s.Events, d.Event, d.Decision, 1)
+	if d.ToolName != "" {
+		countDecision(s.Tools, d.ToolName, d.Decision, 1)
+	}
+	for _, id := range d.Hooks {
+		countDecision(s.Hooks, d.Event+"/"+id, d.Decision, 1)
+	}
+}
+
+// merge adds the counts of other, widening the period to cover both.
+func (s *decisionStats) merge(other *decisionStats) {
+	if other.From.Before(s.From) {
+		s.From = other.From
+	}
+	if other.To.After(s.To) {
+		s.To = other.To
+	}
+	s.Total += other.Total
+	for decision, n := range other.Decisions {
+		s.Decisions[decision] += n
+	}
+	for _, pair := range []struct{ dst, src map[string]map[string]int }{{s.Events, other.Events}, {s.Tools, other.Tools}, {s.Hooks, other.Hooks}} {
+		for key, counts := range pair.src {
+			for decision, n := range counts {
+				countDecision(pair.dst, key, decision, n)
+			}
+		}
+	}
+}
+
+// countDecision adds n to the count of decision under key.
+func countDecision(counts map[string]map[string]int, key, decision string, n int) {
+	if counts[key] == nil {
+		counts[key] = map[string]int{}
+	}
+	counts[key][decision] += n
+}
+
+// reporterID returns the anonymous ID of this host and user in pushed stats.
+func reporterID() string {
+	hostname, _ := os.Hostname()
+	username := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		username = u.Username
+	}
+	sum := sha256.Sum256([]byte("cchook-report\x00" + hostname + "\x00" + username))
+	return hex.EncodeToString(sum[:8])
+}
+
+// readDecisionLog counts the decisions of the decision log at path (CloudEvents or OCSF records, one per line)
+// made within [from, to]. Lines that are not decision records are skipped.
+func readDecisionLog(path string, from, to time.Time) (*decisionStats, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open decision log: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	stats := newDecisionStats(from, to)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		d, at, ok := parseDecisionRecord(scanner.Bytes())
+		if ok && !at.Before(from) && !at.After(to) {
+			stats.add(d)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read decision log: %w", err)
+	}
+	return stats, nil
+}
+
+// parseDecisionRecord returns the decision of a CloudEvents or OCSF record of the decision log and its time.
+func parseDecisionRecord(line []byte) (decision, time.Time, bool) {
+	var record struct {
+		Time     any       `json:"time"` // CloudEvents: RFC 3339, OCSF: milliseconds since the epoch
+		Data     *decision `json:"data"`
+		Unmapped *decision `json:"unmapped"`
+	}
+	if err := json.Unmarshal(line, &record); err != nil {
+		return decision{}, time.Time{}, false
+	}
+	var at time.Time
+	switch t := record.Time.(type) {
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, t)
+		if err != nil {
+			return decision{}, time.Time{}, false
+		}
+		at = parsed
+	case float64:
+		at = time.UnixMilli(int64(t))
+	default:
+		return decision{}, time.Time{}, false
+	}
+	d := record.Data
+	if d == nil {
+		d = record.Unmapped
+	}
+	if d == nil || d.Event == "" || d.Decision == "" {
+		return decision{}, time.Time{}, false
+	}
+	return *d, at, true
+}
+
+// loadReportStats reads stats files written by `report push` receivers: each file holds one payload,
+// or one payload per line.
+func loadReportStats(paths []string) (*decisionStats, int, error) {
+	var merged *decisionStats
+	reporters := map[string]bool{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read stats: %w", err)
+		}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		for {
+			stats := newDecisionStats(time.Time{}, time.Time{})
+			if err := decoder.Decode(stats); errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return nil, 0, fmt.Errorf("%s: invalid stats: %w", path, err)
+			}
+			if stats.Version != reportStatsVersion {
+				return nil, 0, fmt.Errorf("%s: unsupported stats version %d (expected %d)", path, stats.Version, reportStatsVersion)
+			}
+			reporters[stats.Reporter] = true
+			if merged == nil {
+				merged = newDecisionStats(stats.From, stats.To)
+			}
+			merged.merge(stats)
+		}
+	}
+	if merged == nil {
+		return nil, 0, errors.New("no stats found")
+	}
+	return merged, len(reporters), nil
+}
+
+// runReport runs `cchook -command report push|render`: push uploads the stats of the local decision log
+// to the team endpoint of the `report:` block, and render writes an HTML summary of the local decision log,
+// or of the stats files given after render (e.g. those pushed by the team), to w.
+func runReport(w io.Writer, config *Config, args []string, since string) error {
+	if len(args) == 0 || (args[0] != "push" && args[0] != "render") {
+		return errors.New("usage: cchook -command report [-since 7d] push | render [stats.json ...]")
+	}
+	subcommand, files := args[0], args[1:]
+	if subcommand == "push" && len(files) > 0 {
+		return errors.New("report push takes no arguments")
+	}
+
+	if subcommand == "render" && len(files) > 0 {
+		stats, reporters, err := loadReportStats(files)
+		if err != nil {
+			return err
+		}
+		return renderReport(w, stats, reporters)
+	}
+
+	period, err := parseDurationWithDays(since)
+	if err != nil || period <= 0 {
+		return fmt.Errorf("invalid -since %q (use a duration such as 7d or 24h)", since)
+	}
+	if config.DecisionLog == nil || config.DecisionLog.Path == "" {
+		return errors.New("report: no decision log to summarize (set decision_log.path)")
+	}
+	now := currentTime()
+	stats, err := readDecisionLog(expandHomeDir(config.DecisionLog.Path), now.Add(-period), now)
+	if err != nil {
+		return err
+	}
+
+	if subcommand == "render" {
+		return renderReport(w, stats, 1)
+	}
+	// 送信先を設定した場合だけ送る (オプトイン)
+	if config.Report == nil {
+		return errors.New("report: pushing is opt-in; add a report: block with the url of the team endpoint")
+	}
+	stats.Reporter, stats.Team = reporterID(), config.Report.Team
+	return pushReport(w, DefaultHTTPClient, config.Report, stats)
+}
+
+// pushReport POSTs stats to the team endpoint of the `report:` block.
+func pushReport(w io.Writer, client HTTPClient, c *ReportConfig, stats *decisionStats) error {
+	timeout, err := parseSinkTimeout(c.Timeout, defaultReportTimeout)
+	if err != nil {
+		return fmt.Errorf("report: %w", err)
+	}
+	body, err := json.Marshal(stats)
+	if err != nil {
+		return err
+	}
+	if err := postToSink(client, c.URL, c.Headers, timeout, "application/json", body); err != nil {
+		return fmt.Errorf("failed to push report: %w", err)
+	}
+	host := ""
+	if u, err := url.Parse(os.ExpandEnv(c.URL)); err == nil {
+		host = u.Host
+	}
+	fmt.Fprintf(w, "Pushed %d decisions (%s to %s) to %s\n", stats.Total, stats.From.Format(time.DateOnly), stats.To.Format(time.DateOnly), host)
+	return nil
+}
+
+// reportRow is a row of a table of the HTML report: counts by decision in reportDecisionOrder.
+type reportRow struct {
+	Name   string
+	Total  int
+	Counts []int
+}
+
+// reportPage is the data passed to reportTemplate.
+type reportPage struct {
+	From, To  string
+	Reporters int
+	Total     int
+	Decisions []string
+	Overall   []int
+	Events    []reportRow
+	Tools     []reportRow
+	Hooks     []reportRow
+}
+
+// reportRows turns counts by name and decision into rows sorted by total (most first).
+func reportRows(counts map[string]map[string]int) []reportRow {
+	var rows []reportRow
+	for _, name := range slices.Sorted(maps.Keys(counts)) {
+		row := reportRow{Name: name}
+		for _, decision := range reportDecisionOrder {
+			row.Counts = append(row.Counts, counts[name][decision])
+			row.Total += counts[name][decision]
+		}
+		rows = append(rows, row)
+	}
+	slices.SortStableFunc(rows, func(a, b reportRow) int { return b.Total - a.Total })
+	return rows
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>cchook decision report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.8em; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+</style>
+</head>
+<body>
+<h1>cchook decision report</h1>
+<p>{{.From}} to {{.To}}: {{.Total}} decisions from {{.Reporters}} reporter(s)</p>
+
+<h2>Decisions</h2>
+<table>
+<tr>{{range .Decisions}}<th>{{.}}</th>{{end}}</tr>
+<tr>{{range .Overall}}<td>{{.}}</td>{{end}}</tr>
+</table>
+
+<h2>By event</h2>
+<table>
+<tr><th>event</th><th>total</th>{{range .Decisions}}<th>{{.}}</th>{{end}}</tr>
+{{range .Events}}<tr><td>{{.Name}}</td><td>{{.Total}}</td>{{range .Counts}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+
+<h2>By tool</h2>
+{{if .Tools}}<table>
+<tr><th>tool</th><th>total</th>{{range .Decisions}}<th>{{.}}</th>{{end}}</tr>
+{{range .Tools}}<tr><td>{{.Name}}</td><td>{{.Total}}</td>{{range .Counts}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>{{else}}<p>No tool decisions.</p>{{end}}
+
+<h2>By hook</h2>
+{{if .Hooks}}<table>
+<tr><th>hook</th><th>total</th>{{range .Decisions}}<th>{{.}}</th>{{end}}</tr>
+{{range .Hooks}}<tr><td>{{.Name}}</td><td>{{.Total}}</td>{{range .Counts}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>{{else}}<p>No matched hooks.</p>{{end}}
+</body>
+</html>
+`))
+
+// renderReport writes the HTML summary of stats to w.
+func renderReport(w io.Writer, stats *decisionStats, reporters int) error {
+	page := reportPage{
+		From:      stats.From.Format(time.DateOnly),
+		To:        stats.To.Format(time.DateOnly),
+		Reporters: reporters,
+		Total:     stats.Total,
+		Decisions: reportDecisionOrder,
+		Events:    reportRows(stats.Events),
+		Tools:     reportRows(stats.Tools),
+		Hooks:     reportRows(stats.Hooks),
+	}
+	for _, decision := range reportDecisionOrder {
+		page.Overall = append(page.Overall, stats.Decisions[decision])
+	}
+	return reportTemplate.Execute(w, page)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeDecisionLog writes decisions made at the given times to a decision log in both formats (alternating).
+func writeDecisionLog(t *testing.T, decisions []decision, times []time.Time) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "decisions.jsonl")
+	for i, d := range decisions {
+		format := decisionLogCloudEvents
+		if i%2 == 1 {
+			format = decisionLogOCSF
+		}
+		if err := writeDecisionRecord(&DecisionLogConfig{Format: format, Path: path}, d, times[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := f.WriteString("not a record\n{}\n"); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunReport(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	original := currentTime
+	currentTime = func() time.Time { return now }
+	t.Cleanup(func() { currentTime = original })
+
+	logPath := writeDecisionLog(t, []decision{
+		{Event: "PreToolUse", SessionID: "s1", Cwd: "/secret/project", ToolName: "Bash", ToolInput: json.RawMessage(`{"command":"rm -rf /"}`), Decision: decisionDeny, Reason: "no rm", Hooks: []string{"ab12f3c"}},
+		{Event: "PreToolUse", SessionID: "s1", ToolName: "Bash", Decision: decisionAllow},
+		{Event: "Stop", SessionID: "s2", Decision: decisionBlock, Reason: "tests fail"},
+		{Event: "PreToolUse", SessionID: "s3", ToolName: "Write", Decision: decisionDeny},
+	}, []time.Time{now.Add(-time.Hour), now.Add(-2 * time.Hour), now.Add(-48 * time.Hour), now.Add(-30 * 24 * time.Hour)})
+
+	var received []byte
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+		auth = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+	t.Setenv("TEAM_TOKEN", "secret")
+
+	config := &Config{
+		DecisionLog: &DecisionLogConfig{Path: logPath},
+		Report:      &ReportConfig{URL: server.URL, Headers: map[string]string{"Authorization": "Bearer $TEAM_TOKEN"}, Team: "platform"},
+	}
+
+	t.Run("push", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := runReport(&out, config, []string{"push"}, "7d"); err != nil {
+			t.Fatalf("runReport() error = %v", err)
+		}
+		if !strings.HasPrefix(out.String(), "Pushed 3 decisions (2026-03-03 to 2026-03-10) to 127.0.0.1:") || auth != "Bearer secret" {
+			t.Errorf("Output %q, Authorization %q", out.String(), auth)
+		}
+
+		var stats decisionStats
+		if err := json.Unmarshal(received, &stats); err != nil {
+			t.Fatal(err)
+		}
+		if stats.Total != 3 || stats.Decisions[decisionDeny] != 1 || stats.Events["PreToolUse"][decisionAllow] != 1 ||
+			stats.Tools["Bash"][decisionDeny] != 1 || stats.Hooks["PreToolUse/ab12f3c"][decisionDeny] != 1 || stats.Team != "platform" || stats.Reporter == "" {
+			t.Errorf("Unexpected stats: %s", received)
+		}
+		for _, secret := range []string{"s1", "/secret/project", "rm -rf", "no rm", "tests fail"} {
+			if strings.Contains(string(received), secret) {
+				t.Errorf("Pushed stats should not contain %q: %s", secret, received)
+			}
+		}
+	})
+
+	t.Run("render local", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := runReport(&out, config, []string{"render"}, "30d"); err != nil {
+			t.Fatalf("runReport() error = %v", err)
+		}
+		html := out.String()
+		for _, want := range []string{"2026-02-08 to 2026-03-10: 4 decisions from 1 reporter(s)", "<td>Bash</td><td>2</td><td>1</td><td>1</td>", "<td>Stop</td><td>1</td>"} {
+			if !strings.Contains(html, want) {
+				t.Errorf("HTML should contain %q:\n%s", want, html)
+			}
+		}
+	})
+
+	t.Run("render pushed stats", func(t *testing.T) {
+		dir := t.TempDir()
+		other := `{"version":1,"reporter":"other","from":"2026-03-01T00:00:00Z","to":"2026-03-08T00:00:00Z","total":2,` +
+			`"decisions":{"deny":2},"events":{"PreToolUse":{"deny":2}},"tools":{"Bash":{"deny":2}}}`
+		files := []string{filepath.Join(dir, "a.json"), filepath.Join(dir, "b.jsonl")}
+		if err := os.WriteFile(files[0], received, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(files[1], []byte(other+"\n"+other+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		var out bytes.Buffer
+		if err := runReport(&out, &Config{}, append([]string{"render"}, files...), "7d"); err != nil {
+			t.Fatalf("runReport() error = %v", err)
+		}
+		html := out.String()
+		for _, want := range []string{"2026-03-01 to 2026-03-10: 7 decisions from 2 reporter(s)", "<td>Bash</td><td>6</td><td>1</td><td>5</td>"} {
+			if !strings.Contains(html, want) {
+				t.Errorf("HTML should contain %q:\n%s", want, html)
+			}
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			config  *Config
+			args    []string
+			since   string
+			wantErr string
+		}{
+			{config, nil, "7d", "usage:"},
+			{config, []string{"upload"}, "7d", "usage:"},
+			{config, []string{"push", "extra"}, "7d", "takes no arguments"},
+			{config, []string{"push"}, "soon", "invalid -since"},
+			{&Config{DecisionLog: config.DecisionLog}, []string{"push"}, "7d", "opt-in"},
+			{&Config{}, []string{"render"}, "7d", "set decision_log.path"},
+		}
+		for _, tt := range tests {
+			err := runReport(io.Discard, tt.config, tt.args, tt.since)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("runReport(%v, %q) error = %v, want %q", tt.args, tt.since, err, tt.wantErr)
+			}
+		}
+	})
+}
+
+func TestValidateReportConfig(t *testing.T) {
+	if err := validateReportConfig(&ReportConfig{URL: "${TEAM_ENDPOINT}/stats", Timeout: "30s"}); err != nil {
+		t.Errorf("validateReportConfig() error = %v", err)
+	}
+	for config, want := range map[*ReportConfig]string{
+		{}:                                 "url is required",
+		{URL: "ftp://example.com"}:         "invalid url",
+		{URL: "https://x.y", Timeout: "x"}: "invalid timeout",
+	} {
+		if err := validateReportConfig(config); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("validateReportConfig(%+v) error = %v, want %q", config, err, want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// RewriteRule is a regex substitution a rewrite_command action applies to tool_input.command.
+type RewriteRule struct {
+	Pattern string `yaml:"pattern" jsonschema:"required"` // Go正規表現
+	Replace string `yaml:"replace,omitempty"`             // 置換文字列 ($1や${name}でキャプチャグループを参照)
+}
+
+// validateRewriteRules checks the rules of a rewrite_command action.
+func validateRewriteRules(rules []RewriteRule) error {
+	if len(rules) == 0 {
+		return errors.New("rewrite_command action requires rules")
+	}
+	var errs []error
+	for i, rule := range rules {
+		if rule.Pattern == "" {
+			errs = append(errs, fmt.Errorf("rules[%d]: pattern is required", i))
+			continue
+		}
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			errs = append(errs, fmt.Errorf("rules[%d]: invalid regex pattern: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// rewriteCommand applies rules in order, each to the result of the previous one, replacing every match.
+// matched is true when any rule's pattern matched the command.
+func rewriteCommand(command string, rules []RewriteRule) (rewritten string, matched bool, err error) {
+	rewritten = command
+	for i, rule := range rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return command, false, fmt.Errorf("rules[%d]: invalid regex pattern: %w", i, err)
+		}
+		if !re.MatchString(rewritten) {
+			continue
+		}
+		matched = true
+		rewritten = re.ReplaceAllString(rewritten, rule.Replace)
+	}
+	return rewritten, matched, nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+var testRewriteRules = []RewriteRule{
+	{Pattern: `^rm\s+(-[rf]+\s+)*`, Replace: "trash "},
+	{Pattern: `\bterraform apply\b`, Replace: "terraform plan"},
+	{Pattern: `\bkubectl (?P<verb>apply|delete)\b`, Replace: "kubectl ${verb} --dry-run=client"},
+}
+
+func TestRewriteCommand(t *testing.T) {
+	tests := []struct {
+		command     string
+		want        string
+		wantMatched bool
+	}{
+		{command: "rm -rf build", want: "trash build", wantMatched: true},
+		{command: "rm -r -f build dist", want: "trash build dist", wantMatched: true},
+		{command: "cd infra && terraform apply -auto-approve", want: "cd infra && terraform plan -auto-approve", wantMatched: true},
+		{command: "kubectl apply -f a.yaml && kubectl delete -f b.yaml", want: "kubectl apply --dry-run=client -f a.yaml && kubectl delete --dry-run=client -f b.yaml", wantMatched: true},
+		{command: "ls -la", want: "ls -la", wantMatched: false},
+		{command: "echo firm", want: "echo firm", wantMatched: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			got, matched, err := rewriteCommand(tt.command, testRewriteRules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want || matched != tt.wantMatched {
+				t.Errorf("rewriteCommand(%q) = %q, %v, want %q, %v", tt.command, got, matched, tt.want, tt.wantMatched)
+			}
+		})
+	}
+
+	// 後のルールは前のルールの結果に適用される
+	got, _, err := rewriteCommand("a", []RewriteRule{{Pattern: "a", Replace: "b"}, {Pattern: "b", Replace: "c"}})
+	if err != nil || got != "c" {
+		t.Errorf("chained rules = %q, %v, want \"c\"", got, err)
+	}
+	if _, _, err := rewriteCommand("a", []RewriteRule{{Pattern: "("}}); err == nil {
+		t.Error("invalid pattern: want error")
+	}
+}
+
+func TestValidateRewriteRules(t *testing.T) {
+	if err := validateRewriteRules(testRewriteRules); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := validateRewriteRules(nil); err == nil || err.Error() != "rewrite_command action requires rules" {
+		t.Errorf("error = %v, want missing rules", err)
+	}
+	if err := validateRewriteRules([]RewriteRule{{Pattern: "ok"}, {Replace: "x"}}); err == nil || err.Error() != "rules[1]: pattern is required" {
+		t.Errorf("error = %v, want missing pattern", err)
+	}
+}
+
+func TestExecutePreToolUseAction_RewriteCommand(t *testing.T) {
+	rawJSON := func(command string) map[string]any {
+		return map[string]any{
+			"tool_name":  "Bash",
+			"tool_input": map[string]any{"command": command, "description": "Clean up"},
+		}
+	}
+	input := func(command string) *PreToolUseInput {
+		return &PreToolUseInput{ToolName: "Bash", ToolInput: ToolInput{Command: command}}
+	}
+	action := Action{Type: "rewrite_command", Rules: testRewriteRules}
+
+	output, err := NewActionExecutor(nil).ExecutePreToolUseAction(action, input("rm -rf build"), rawJSON("rm -rf build"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &ActionOutput{
+		Continue:                 true,
+		PermissionDecision:       "ask",
+		HookEventName:            "PreToolUse",
+		PermissionDecisionReason: "Rewrote the command to: trash build",
+		AdditionalContext:        "The command was rewritten by a hook and runs as: trash build",
+		UpdatedInput:             map[string]any{"command": "trash build", "description": "Clean up"},
+	}
+	if !reflect.DeepEqual(output, want) {
+		t.Errorf("output = %+v, want %+v", output, want)
+	}
+
+	allow := action
+	allow.PermissionDecision = stringPtr("allow")
+	allow.Message = "rm is replaced by trash ({.tool_input.description})"
+	output, err = NewActionExecutor(nil).ExecutePreToolUseAction(allow, input("rm -rf build"), rawJSON("rm -rf build"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output == nil || output.PermissionDecision != "allow" || output.PermissionDecisionReason != "rm is replaced by trash (Clean up)" {
+		t.Errorf("output = %+v, want allow with the templated message", output)
+	}
+
+	// どのルールにもマッチしなければClaude Codeに任せる (deny_if_no_match時はdeny)
+	output, err = NewActionExecutor(nil).ExecutePreToolUseAction(action, input("ls"), rawJSON("ls"))
+	if err != nil || output != nil {
+		t.Errorf("no match: output = %+v, err = %v, want nil", output, err)
+	}
+	strict := action
+	strict.DenyIfNoMatch = true
+	output, err = NewActionExecutor(nil).ExecutePreToolUseAction(strict, input("ls"), rawJSON("ls"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output == nil || output.PermissionDecision != "deny" || output.PermissionDecisionReason != "The command does not match any rewrite rule: ls" {
+		t.Errorf("deny_if_no_match: output = %+v, want deny", output)
+	}
+
+	output, err = NewActionExecutor(nil).ExecutePreToolUseAction(strict, &PreToolUseInput{ToolName: "Write", ToolInput: ToolInput{FilePath: "a.txt"}}, nil)
+	if err != nil || output != nil {
+		t.Errorf("tool without command: output = %+v, err = %v, want nil", output, err)
+	}
+
+	output, err = NewActionExecutor(nil).ExecutePreToolUseAction(Action{Type: "rewrite_command", Rules: []RewriteRule{{Pattern: "("}}}, input("ls"), rawJSON("ls"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output == nil || output.PermissionDecision != "deny" {
+		t.Errorf("invalid pattern: output = %+v, want deny", output)
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultRunTestsTimeout is the timeout of a run_tests action without `timeout`.
+const defaultRunTestsTimeout = 10 * time.Minute
+
+// runTestsMaxNames is the number of failing tests a run_tests action names in its summary.
+const runTestsMaxNames = 10
+
+var (
+	// testFailureStartPattern matches the first line of the failures in the output of common test runners
+	// (go test, pytest, jest/vitest, TAP), where the summary given to Claude starts.
+	testFailureStartPattern = regexp.MustCompile(`^(\s*--- FAIL:|FAIL\b|=+ (FAILURES|ERRORS) =+|FAILED\b|\s*● |\s*[✕×] |not ok\b)`)
+	// failedTestPatterns extract the names of failing tests from the same runners.
+	failedTestPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^\s*--- FAIL: (\S+)`),
+		regexp.MustCompile(`^FAILED (\S+)`),
+		regexp.MustCompile(`^\s*[✕×] (.+?)(?: \(\d+(?:\.\d+)? ?m?s\))?$`),
+		regexp.MustCompile(`^not ok \d+ (?:- )?(.+)`),
+	}
+)
+
+// runTestsResult is the last result of a run_tests command in a session, recorded with the fingerprint
+// of the working tree it ran on so that the tests don't run again until the tree changes.
+type runTestsResult struct {
+	Cwd         string    `json:"cwd"`
+	Command     string    `json:"command"`
+	Fingerprint string    `json:"fingerprint"`
+	Passed      bool      `json:"passed"`
+	Reason      string    `json:"reason,omitempty"`
+	Time        time.Time `json:"time"`
+}
+
+// runTestsTimeout returns the timeout of a run_tests action.
+func runTestsTimeout(action Action) (time.Duration, error) {
+	if action.Timeout == "" {
+		return defaultRunTestsTimeout, nil
+	}
+	timeout, err := time.ParseDuration(action.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %q: %w", action.Timeout, err)
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("invalid timeout %q: must be positive", action.Timeout)
+	}
+	return timeout, nil
+}
+
+// gitTreeFingerprint returns a hash of the state of the repository containing dir: HEAD, the staged and
+// unstaged changes and the untracked (not ignored) files. It returns "" outside a git repository.
+func gitTreeFingerprint(dir string) (string, error) {
+	if dir == "" {
+		return "", nil
+	}
+	if _, err := openGitRepository(dir); err != nil {
+		return "", nil
+	}
+
+	hash := sha256.New()
+	// コミットの無いリポジトリではHEADが無い
+	head, _ := exec.Command("git", "-C", dir, "rev-parse", "--verify", "-q", "HEAD").Output()
+	hash.Write(head)
+	for _, args := range [][]string{{"diff", "--binary", "--cached"}, {"diff", "--binary"}} {
+		var stderr bytes.Buffer
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Stdout = hash
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("git diff failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+	}
+
+	root, err := exec.Command("git", "-C", dir, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse failed: %w", err)
+	}
+	untracked, err := exec.Command("git", "-C", dir, "ls-files", "--others", "--exclude-standard", "--full-name", "-z").Output()
+	if err != nil {
+		return "", fmt.Errorf("git ls-files failed: %w", err)
+	}
+	for name := range strings.SplitSeq(strings.TrimSuffix(string(untracked), "\x00"), "\x00") {
+		if name == "" {
+			continue
+		}
+		fmt.Fprintf(hash, "\x00%s\x00", name)
+		if f, err := os.Open(filepath.Join(strings.TrimSpace(string(root)), name)); err == nil {
+			_, _ = io.Copy(hash, f)
+			f.Close()
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// loadRunTestsResults returns the results of the run_tests commands of the session.
+func loadRunTestsResults(sessionID string) []runTestsResult {
+	if sessionID == "" {
+		return nil
+	}
+	data, err := os.ReadFile(sessionStatePath(sessionID, ".tests.json"))
+	if err != nil {
+		return nil
+	}
+	var results []runTestsResult
+	if json.Unmarshal(data, &results) != nil {
+		return nil
+	}
+	return results
+}
+
+// lastRunTestsResult returns the last result of command run in cwd in the session.
+func lastRunTestsResult(sessionID, cwd, command string) (runTestsResult, bool) {
+	for _, result := range loadRunTestsResults(sessionID) {
+		if result.Cwd == cwd && result.Command == command {
+			return result, true
+		}
+	}
+	return runTestsResult{}, false
+}
+
+// saveRunTestsResult records the result of a run_tests command, replacing the previous one of the same command and cwd.
+func saveRunTestsResult(sessionID string, result runTestsResult) error {
+	if sessionID == "" {
+		return nil
+	}
+	results := slices.DeleteFunc(loadRunTestsResults(sessionID), func(r runTestsResult) bool {
+		return r.Cwd == result.Cwd && r.Command == result.Command
+	})
+	data, err := json.Marshal(append(results, result))
+	if err != nil {
+		return err
+	}
+	path := sessionStatePath(sessionID, ".tests.json")
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create session state directory: %w", err)
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// failedTestNames returns the names of the failing tests reported in output, in order and without duplicates.
+func failedTestNames(output string) []string {
+	var names []string
+	for line := range strings.SplitSeq(output, "\n") {
+		for _, pattern := range failedTestPatterns {
+			if m := pattern.FindStringSubmatch(line); m != nil && !slices.Contains(names, m[1]) {
+				names = append(names, m[1])
+				break
+			}
+		}
+	}
+	return names
+}
+
+// runTestsReason summarizes failed tests for Claude: the failing tests, then the output from the first
+// failure (or the end of the output if the runner is not recognized), truncated to maxLines lines and
+// maxBytes bytes (the lint_feedback defaults when zero, unlimited when negative).
+func runTestsReason(command string, exitCode int, output string, maxLines, maxBytes int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Tests failed (%s, exit code %d).", command, exitCode)
+	if names := failedTestNames(output); len(names) > 0 {
+		fmt.Fprintf(&b, " Failing: %s", strings.Join(names[:min(len(names), runTestsMaxNames)], ", "))
+		if n := len(names) - runTestsMaxNames; n > 0 {
+			fmt.Fprintf(&b, " and %d more", n)
+		}
+		b.WriteString(".")
+	}
+	b.WriteString(" Fix them before stopping:\n")
+
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	start := slices.IndexFunc(lines, testFailureStartPattern.MatchString)
+	switch {
+	case start >= 0:
+		lines = lines[start:]
+	default:
+		// 失敗の始まりが分からなければ、結果の出る末尾を残す
+		limit := maxLines
+		if limit == 0 {
+			limit = lintFeedbackMaxLines
+		}
+		if limit > 0 && len(lines) > limit {
+			fmt.Fprintf(&b, "... %d earlier line(s)\n", len(lines)-limit)
+			lines = lines[len(lines)-limit:]
+		}
+	}
+	b.WriteString(truncateLintOutput(strings.Join(lines, "\n"), maxLines, maxBytes))
+	return b.String()
+}
+
+// runTests runs the test command of a run_tests action unless it already ran on the same working tree
+// in this session, and blocks the stop with a summary of the failures until the tests pass.
+// When Claude continued because of the failures but changed nothing, the stop is allowed so that it doesn't loop.
+// Tests that time out or can't run only print a warning.
+func (e *ActionExecutor) runTests(action Action, input *StopInput, rawJSON any) *ActionOutput {
+	command := configuredCommand(action)
+	fingerprint, err := gitTreeFingerprint(input.Cwd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: run_tests: %v\n", err)
+	}
+	if last, ok := lastRunTestsResult(input.SessionID, input.Cwd, command); ok && fingerprint != "" && last.Fingerprint == fingerprint {
+		switch {
+		case last.Passed:
+			return nil
+		case input.StopHookActive:
+			return &ActionOutput{
+				Continue:      true,
+				SystemMessage: fmt.Sprintf("run_tests: tests are still failing with no changes since the last run (%s)", command),
+			}
+		default:
+			return runTestsBlock(last.Reason)
+		}
+	}
+
+	stdout, stderr, exitCode, err := e.runAction(action, rawJSON)
+	if errors.Is(err, ErrCommandTimedOut) {
+		message := fmt.Sprintf("run_tests: %s %v", command, err)
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", message)
+		return &ActionOutput{Continue: true, SystemMessage: message}
+	}
+	if exitCode == 127 || (err != nil && stdout == "" && stderr == "") {
+		// テストを実行できないだけなのでブロックしない
+		message := strings.TrimSpace(stderr)
+		if message == "" && err != nil {
+			message = err.Error()
+		}
+		fmt.Fprintf(os.Stderr, "Warning: run_tests could not run %s: %s\n", command, message)
+		return nil
+	}
+
+	result := runTestsResult{Cwd: input.Cwd, Command: command, Passed: exitCode == 0, Time: currentTime()}
+	if !result.Passed {
+		result.Reason = runTestsReason(command, exitCode, stdout+"\n"+stderr, action.MaxLines, action.MaxBytes)
+	}
+	// テストが作ったファイルも含めて、実行後の状態を記録する
+	if fingerprint != "" {
+		if result.Fingerprint, err = gitTreeFingerprint(input.Cwd); err == nil {
+			if err := saveRunTestsResult(input.SessionID, result); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: run_tests: failed to record the result: %v\n", err)
+			}
+		}
+	}
+	if result.Passed {
+		return nil
+	}
+	return runTestsBlock(result.Reason)
+}
+
+// runTestsBlock blocks the stop with the summary of the failed tests.
+func runTestsBlock(reason string) *ActionOutput {
+	summary, _, _ := strings.Cut(reason, "\n")
+	return &ActionOutput{
+		Continue:      true,
+		Decision:      "block",
+		Reason:        reason,
+		SystemMessage: "run_tests: " + strings.TrimSuffix(summary, " Fix them before stopping:"),
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+import "os/exec"
+
+// killProcessGroupOnCancel is a no-op on platforms without process groups; only the shell is killed on a timeout.
+func killProcessGroupOnCancel(cmd *exec.Cmd) {}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunTestsReason(t *testing.T) {
+	goOutput := `=== RUN   TestAdd
+--- PASS: TestAdd (0.00s)
+=== RUN   TestSub
+    calc_test.go:12: Sub(3, 1) = 4, want 2
+--- FAIL: TestSub (0.00s)
+--- FAIL: TestDiv (0.00s)
+    calc_test.go:20: Div(1, 0) did not panic
+FAIL
+FAIL	example.com/calc	0.002s`
+
+	want := "Tests failed (go test ./..., exit code 1). Failing: TestSub, TestDiv. Fix them before stopping:\n" +
+		"--- FAIL: TestSub (0.00s)\n--- FAIL: TestDiv (0.00s)\n    calc_test.go:20: Div(1, 0) did not panic\nFAIL\nFAIL\texample.com/calc\t0.002s"
+	if got := runTestsReason("go test ./...", 1, goOutput, 0, 0); got != want {
+		t.Errorf("runTestsReason(go test) =\n%s\nwant\n%s", got, want)
+	}
+
+	pytestOutput := "collected 3 items\n\ntest_calc.py .F.\n\n=================================== FAILURES ===================================\n" +
+		"___ test_sub ___\n    assert 4 == 2\n=========================== short test summary info ============================\n" +
+		"FAILED test_calc.py::test_sub - assert 4 == 2\n========================= 1 failed, 2 passed in 0.01s ========================="
+	got := runTestsReason("pytest", 1, pytestOutput, 3, 0)
+	if !strings.HasPrefix(got, "Tests failed (pytest, exit code 1). Failing: test_calc.py::test_sub. Fix them before stopping:\n====") ||
+		!strings.HasSuffix(got, "    assert 4 == 2\n... and 3 more line(s)") {
+		t.Errorf("runTestsReason(pytest) =\n%s", got)
+	}
+
+	// 失敗の始まりが分からない出力は末尾を残す
+	got = runTestsReason("make test", 2, "line 1\nline 2\nline 3\nmake: *** [test] Error 1", 2, 0)
+	want = "Tests failed (make test, exit code 2). Fix them before stopping:\n... 2 earlier line(s)\nline 3\nmake: *** [test] Error 1"
+	if got != want {
+		t.Errorf("runTestsReason(unknown runner) =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestExecuteStopAction_RunTests(t *testing.T) {
+	withSessionStateDir(t)
+	repo := t.TempDir()
+	if err := runCommand("cd "+repo+" && git init -q", false, nil); err != nil {
+		t.Skipf("git is not available: %v", err)
+	}
+	// 実行回数を数え、.failが無ければ成功するテストコマンド
+	counter := filepath.Join(t.TempDir(), "runs")
+	action := Action{Type: "run_tests", Command: "cd " + shellQuote(repo) + " && echo run >> " + shellQuote(counter) + " && if [ -f .fail ]; then echo '--- FAIL: TestX (0.00s)'; exit 1; fi"}
+	runs := func() int {
+		data, _ := os.ReadFile(counter)
+		return strings.Count(string(data), "run")
+	}
+	run := func(stopHookActive bool) *ActionOutput {
+		t.Helper()
+		input := &StopInput{BaseInput: BaseInput{SessionID: "s1", Cwd: repo}, StopHookActive: stopHookActive}
+		output, err := NewActionExecutor(nil).ExecuteStopAction(action, input, map[string]any{"cwd": repo})
+		if err != nil {
+			t.Fatalf("ExecuteStopAction() error = %v", err)
+		}
+		return output
+	}
+
+	if output := run(false); output != nil || runs() != 1 {
+		t.Fatalf("passing tests: output = %+v, runs = %d, want allow after 1 run", output, runs())
+	}
+	// 作業ツリーが変わらなければ再実行しない
+	if output := run(false); output != nil || runs() != 1 {
+		t.Fatalf("unchanged tree: output = %+v, runs = %d, want the cached result", output, runs())
+	}
+
+	if err := os.WriteFile(filepath.Join(repo, ".fail"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	output := run(false)
+	if output == nil || output.Decision != "block" || !strings.Contains(output.Reason, "Failing: TestX.") || runs() != 2 {
+		t.Fatalf("failing tests: output = %+v, runs = %d, want block after 2 runs", output, runs())
+	}
+	if output.SystemMessage != "run_tests: Tests failed ("+action.Command+", exit code 1). Failing: TestX." {
+		t.Errorf("SystemMessage = %q", output.SystemMessage)
+	}
+	// 失敗でブロックした後に何も変わっていなければ、ループしないよう停止を許可する
+	output = run(true)
+	if output == nil || output.Decision != "" || !strings.Contains(output.SystemMessage, "still failing") || runs() != 2 {
+		t.Errorf("unchanged after block: output = %+v, runs = %d, want allow with a warning", output, runs())
+	}
+
+	if err := os.Remove(filepath.Join(repo, ".fail")); err != nil {
+		t.Fatal(err)
+	}
+	if output := run(true); output != nil || runs() != 3 {
+		t.Errorf("fixed tests: output = %+v, runs = %d, want allow after 3 runs", output, runs())
+	}
+}
+
+func TestExecuteStopAction_RunTestsTimeout(t *testing.T) {
+	withSessionStateDir(t)
+	action := Action{Type: "run_tests", Command: "sleep 10", Timeout: "100ms"}
+	start := time.Now()
+	output, err := NewActionExecutor(nil).ExecuteStopAction(action, &StopInput{BaseInput: BaseInput{SessionID: "s1"}}, map[string]any{})
+	if err != nil {
+		t.Fatalf("ExecuteStopAction() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("ExecuteStopAction() took %s, want the command killed after the timeout", elapsed)
+	}
+	if output == nil || output.Decision != "" || output.SystemMessage != "run_tests: sleep 10 timed out after 100ms" {
+		t.Errorf("ExecuteStopAction() = %+v, want allow with a timeout warning", output)
+	}
+
+	_, _, _, err = runCommandWithTimeout("sleep 10", 50*time.Millisecond, false, nil)
+	if !errors.Is(err, ErrCommandTimedOut) {
+		t.Errorf("runCommandWithTimeout() error = %v, want ErrCommandTimedOut", err)
+	}
+}
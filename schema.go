@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+
+	"github.com/invopop/jsonschema"
+)
+
+// actionTypes lists every action type.
+var actionTypes = []string{
+	"command", "output", "http", "hook_changes_report", "secret_scan", "syntax_check",
+	"markdown_check", "typecheck", "format_file", "lint_feedback", "terminology", "breaking_change", "rewrite_command", "time_tracking",
+	"digest", "run_tests", "git_checkpoint", "opa", "ask_external",
+}
+
+// eventScopedActionTypes lists the events an action type can be used with.
+// Action types not listed here (command, output, http and opa) are supported by every event.
+var eventScopedActionTypes = map[string][]HookEventType{
+	"hook_changes_report": {Stop},
+	"secret_scan":         {PreToolUse, PostToolUse},
+	"syntax_check":        {PostToolUse},
+	"markdown_check":      {PostToolUse},
+	"typecheck":           {PostToolUse},
+	"format_file":         {PostToolUse},
+	"lint_feedback":       {PostToolUse},
+	"terminology":         {PreToolUse, PostToolUse},
+	"breaking_change":     {PreToolUse},
+	"rewrite_command":     {PreToolUse},
+	"ask_external":        {PreToolUse},
+	"time_tracking":       {SessionStart, SessionEnd},
+	"digest":              {Notification, Stop},
+	"run_tests":           {Stop},
+	"git_checkpoint":      {PostToolUse, Stop},
+}
+
+// conditionTypeNames returns the condition types supported by eventType.
+func conditionTypeNames(eventType HookEventType) []any {
+	var names []any
+	for _, c := range conditionTypes {
+		if events, ok := eventScopedConditions[c]; !ok || slices.Contains(events, eventType) {
+			names = append(names, c.String())
+		}
+	}
+	return names
+}
+
+// actionTypeNames returns the action types supported by eventType.
+func actionTypeNames(eventType HookEventType) []any {
+	var names []any
+	for _, t := range actionTypes {
+		if events, ok := eventScopedActionTypes[t]; !ok || slices.Contains(events, eventType) {
+			names = append(names, t)
+		}
+	}
+	return names
+}
+
+// configSchema generates the JSON Schema of the config file from the Go types (yaml field names).
+// Each event gets its own condition and action definitions ("PreToolUseCondition", "PreToolUseAction", ...)
+// whose type is limited to what the event supports, so editors only complete valid types.
+func configSchema() *jsonschema.Schema {
+	r := jsonschema.Reflector{
+		FieldNameTag:               "yaml",
+		RequiredFromJSONSchemaTags: true,
+		ExpandedStruct:             true,
+		Anonymous:                  true,
+		Mapper: func(t reflect.Type) *jsonschema.Schema {
+			if t == reflect.TypeOf(ConditionType{}) {
+				return &jsonschema.Schema{Type: "string"}
+			}
+			return nil
+		},
+	}
+	schema := r.Reflect(&Config{})
+	schema.Title = "cchook config"
+	schema.Description = "Configuration of cchook (~/.config/cchook/config.yaml or .cchook.yaml)"
+
+	defs := schema.Definitions
+	condition, action := defs["Condition"], defs["Action"]
+	for pair := schema.Properties.Oldest(); pair != nil; pair = pair.Next() {
+		eventType := HookEventType(pair.Key)
+		hookType, ok := validateHookTypes[eventType]
+		if !ok {
+			continue
+		}
+		conditionName, actionName := pair.Key+"Condition", pair.Key+"Action"
+		defs[conditionName] = withProperties(condition, map[string]*jsonschema.Schema{
+			"type":       {Type: "string", Enum: conditionTypeNames(eventType)},
+			"conditions": arrayOf(conditionName),
+		})
+		defs[actionName] = withProperties(action, map[string]*jsonschema.Schema{
+			"type": {Type: "string", Enum: actionTypeNames(eventType)},
+		})
+		defs[hookType.Name()] = withProperties(defs[hookType.Name()], map[string]*jsonschema.Schema{
+			"conditions": arrayOf(conditionName),
+			"actions":    arrayOf(actionName),
+		})
+	}
+	delete(defs, "Condition")
+	delete(defs, "Action")
+	return schema
+}
+
+// withProperties returns a copy of s with the given properties replaced (other properties are shared).
+func withProperties(s *jsonschema.Schema, overrides map[string]*jsonschema.Schema) *jsonschema.Schema {
+	c := *s
+	c.Properties = jsonschema.NewProperties()
+	for pair := s.Properties.Oldest(); pair != nil; pair = pair.Next() {
+		value := pair.Value
+		if override, ok := overrides[pair.Key]; ok {
+			value = override
+		}
+		c.Properties.Set(pair.Key, value)
+	}
+	return &c
+}
+
+// arrayOf returns the schema of an array of the definition def.
+func arrayOf(def string) *jsonschema.Schema {
+	return &jsonschema.Schema{Type: "array", Items: &jsonschema.Schema{Ref: "#/$defs/" + def}}
+}
+
+// runSchema writes the JSON Schema of the config file to w.
+func runSchema(w io.Writer) error {
+	data, err := json.MarshalIndent(configSchema(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal schema: %w", err)
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
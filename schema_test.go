@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/xeipuuv/gojsonschema"
+	"gopkg.in/yaml.v3"
+)
+
+func TestConditionTypes(t *testing.T) {
+	src, err := os.ReadFile("types.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var defined []string
+	for _, m := range regexp.MustCompile(`= ConditionType\{"(\w+)"\}`).FindAllStringSubmatch(string(src), -1) {
+		defined = append(defined, m[1])
+	}
+	var listed []string
+	for _, c := range conditionTypes {
+		listed = append(listed, c.String())
+		if parsed, err := parseConditionType(c.String()); err != nil || parsed != c {
+			t.Errorf("parseConditionType(%q) = %v, %v", c, parsed, err)
+		}
+	}
+	slices.Sort(defined)
+	slices.Sort(listed)
+	if !slices.Equal(defined, listed) {
+		t.Errorf("conditionTypes = %q, want the defined condition types %q", listed, defined)
+	}
+}
+
+// TestEventScopedActionTypes checks that the action types of the schema agree with validate.
+func TestEventScopedActionTypes(t *testing.T) {
+	for eventType := range validateHookTypes {
+		supported := actionTypeNames(eventType)
+		for _, actionType := range actionTypes {
+			data := string(eventType) + ":\n  - actions:\n      - type: " + actionType + "\n"
+			rejected := false
+			for _, issue := range validateConfigData("config.yaml", []byte(data)) {
+				rejected = rejected || strings.Contains(issue.Message, "action is only supported for")
+			}
+			if want := slices.Contains(supported, any(actionType)); rejected == want {
+				t.Errorf("%s %s: supported by schema = %v, rejected by validate = %v", eventType, actionType, want, rejected)
+			}
+		}
+	}
+}
+
+func TestConfigSchema(t *testing.T) {
+	data, err := json.Marshal(configSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	validate := func(t *testing.T, config string) []string {
+		t.Helper()
+		var doc map[string]any
+		if err := yaml.Unmarshal([]byte(config), &doc); err != nil {
+			t.Fatal(err)
+		}
+		result, err := schema.Validate(gojsonschema.NewGoLoader(doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var errs []string
+		for _, e := range result.Errors() {
+			errs = append(errs, e.String())
+		}
+		return errs
+	}
+
+	config, err := os.ReadFile("testdata/integration_test_config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := validate(t, string(config)); len(errs) > 0 {
+		t.Errorf("integration_test_config.yaml: %q", errs)
+	}
+
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{
+			name: "valid",
+			config: `output_format: compact
+PreToolUse:
+  - matcher: Bash
+    conditions:
+      - type: not
+        conditions:
+          - type: command_regex
+            value: "^git "
+    actions:
+      - type: rewrite_command
+        rules:
+          - pattern: "^npm "
+            replace: "pnpm "
+`,
+		},
+		{name: "unknown event", config: "PreToolUSe: []\n", want: "Additional property PreToolUSe is not allowed"},
+		{name: "unknown hook field", config: "Stop:\n  - matchr: x\n", want: "Additional property matchr is not allowed"},
+		{name: "condition type required", config: "Stop:\n  - conditions:\n      - value: x\n", want: "type is required"},
+		{name: "condition of another event", config: "Stop:\n  - conditions:\n      - type: command_regex\n", want: "Stop.0.conditions.0.type"},
+		{name: "nested condition of another event", config: "Stop:\n  - conditions:\n      - type: any_of\n        conditions:\n          - type: prompt_regex\n", want: "Stop.0.conditions.0.conditions.0.type"},
+		{name: "action of another event", config: "Stop:\n  - actions:\n      - type: rewrite_command\n", want: "Stop.0.actions.0.type"},
+		{name: "invalid enum", config: "PreToolUse:\n  - actions:\n      - type: output\n        permission_decision: allwo\n", want: "permission_decision"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validate(t, tt.config)
+			if tt.want == "" {
+				if len(errs) > 0 {
+					t.Errorf("want valid, got %q", errs)
+				}
+				return
+			}
+			if !slices.ContainsFunc(errs, func(e string) bool { return strings.Contains(e, tt.want) }) {
+				t.Errorf("want an error containing %q, got %q", tt.want, errs)
+			}
+		})
+	}
+}
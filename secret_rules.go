@@ -0,0 +1,64 @@
+package main
+
+import "regexp"
+
+// secretRule is a kind of credential detected by secret_scan actions.
+type secretRule struct {
+	ID          string
+	Description string
+	Pattern     *regexp.Regexp
+	Group       int // 秘密の値を含むサブマッチ (0はマッチ全体)
+}
+
+// builtinSecretRules is the ruleset of secret_scan actions.
+// Patterns are anchored on fixed prefixes or key names to keep false positives low.
+var builtinSecretRules = []secretRule{
+	{
+		ID:          "aws-access-key-id",
+		Description: "AWS access key ID",
+		Pattern:     regexp.MustCompile(`\b((?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16})\b`),
+		Group:       1,
+	},
+	{
+		ID:          "aws-secret-access-key",
+		Description: "AWS secret access key",
+		Pattern:     regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})(?:[^A-Za-z0-9/+=]|$)`),
+		Group:       1,
+	},
+	{
+		ID:          "gcp-api-key",
+		Description: "Google Cloud API key",
+		Pattern:     regexp.MustCompile(`\b(AIza[0-9A-Za-z_-]{35})(?:[^0-9A-Za-z_-]|$)`),
+		Group:       1,
+	},
+	{
+		ID:          "gcp-oauth-client-secret",
+		Description: "Google OAuth client secret",
+		Pattern:     regexp.MustCompile(`\b(GOCSPX-[0-9A-Za-z_-]{28})(?:[^0-9A-Za-z_-]|$)`),
+		Group:       1,
+	},
+	{
+		ID:          "gcp-service-account-key",
+		Description: "Google Cloud service account key",
+		Pattern:     regexp.MustCompile(`"private_key_id"\s*:\s*"([0-9a-f]{40})"`),
+		Group:       1,
+	},
+	{
+		ID:          "slack-token",
+		Description: "Slack token",
+		Pattern:     regexp.MustCompile(`\b(xox[abposr]-[0-9A-Za-z-]{10,})\b`),
+		Group:       1,
+	},
+	{
+		ID:          "slack-webhook-url",
+		Description: "Slack webhook URL",
+		Pattern:     regexp.MustCompile(`https://hooks\.slack\.com/(?:services|workflows)/(T[0-9A-Z]+/[0-9A-Za-z/]+)`),
+		Group:       1,
+	},
+	{
+		ID:          "private-key",
+		Description: "private key (PEM block)",
+		Pattern:     regexp.MustCompile(`-----BEGIN ((?:[A-Z0-9]+ )*PRIVATE KEY(?: BLOCK)?)-----`),
+		Group:       1,
+	},
+}
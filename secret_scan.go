@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// maxSecretScanFileSize is the number of bytes of the target file a secret_scan action with scan_file reads.
+const maxSecretScanFileSize = 1 << 20
+
+// secretFinding is a secret found by a secret_scan action.
+type secretFinding struct {
+	Rule   secretRule
+	Where  string // 見つかった場所 ("content", "command", ファイルパスなど)
+	Line   int    // 1始まりの行番号
+	Secret string
+}
+
+// String describes the finding with the secret redacted.
+func (f secretFinding) String() string {
+	return fmt.Sprintf("%s (%s) in %s line %d", f.Rule.Description, redactSecret(f.Secret), f.Where, f.Line)
+}
+
+// scanSecrets returns the secrets the built-in rules find in text, in the order they appear.
+// Placeholder values such as AWS's documentation keys (containing "EXAMPLE") are ignored.
+func scanSecrets(where, text string) []secretFinding {
+	type located struct {
+		finding secretFinding
+		offset  int
+	}
+	var found []located
+	for _, rule := range builtinSecretRules {
+		for _, m := range rule.Pattern.FindAllStringSubmatchIndex(text, -1) {
+			start, end := m[2*rule.Group], m[2*rule.Group+1]
+			if start < 0 {
+				continue
+			}
+			secret := text[start:end]
+			if strings.Contains(strings.ToUpper(secret), "EXAMPLE") {
+				continue
+			}
+			found = append(found, located{
+				finding: secretFinding{Rule: rule, Where: where, Line: strings.Count(text[:start], "\n") + 1, Secret: secret},
+				offset:  start,
+			})
+		}
+	}
+
+	slices.SortStableFunc(found, func(a, b located) int { return a.offset - b.offset })
+	findings := make([]secretFinding, len(found))
+	for i := range found {
+		findings[i] = found[i].finding
+	}
+	return findings
+}
+
+// redactSecret keeps the first 4 characters of a secret (enough to tell which key it is) and masks the rest.
+func redactSecret(secret string) string {
+	runes := []rune(secret)
+	if len(runes) <= 8 {
+		return strings.Repeat("*", len(runes))
+	}
+	return string(runes[:4]) + strings.Repeat("*", min(len(runes)-4, 16))
+}
+
+// redactSecretsJSON returns data with the secrets found in its string values redacted.
+func redactSecretsJSON(data json.RawMessage) json.RawMessage {
+	var value any
+	if len(data) == 0 || json.Unmarshal(data, &value) != nil {
+		return data
+	}
+	var redact func(v any) any
+	redact = func(v any) any {
+		switch v := v.(type) {
+		case string:
+			for _, finding := range scanSecrets("", v) {
+				v = strings.ReplaceAll(v, finding.Secret, redactSecret(finding.Secret))
+			}
+			return v
+		case map[string]any:
+			for key, item := range v {
+				v[key] = redact(item)
+			}
+		case []any:
+			for i, item := range v {
+				v[i] = redact(item)
+			}
+		}
+		return v
+	}
+	redacted, err := json.Marshal(redact(value))
+	if err != nil {
+		return data
+	}
+	return redacted
+}
+
+// secretScanToolInput scans what a tool call writes or runs: the content of Write, the new strings of Edit/MultiEdit
+// and the command of Bash.
+func secretScanToolInput(toolInput ToolInput) []secretFinding {
+	var findings []secretFinding
+	findings = append(findings, scanSecrets("content", toolInput.Content)...)
+	findings = append(findings, scanSecrets("new_string", toolInput.NewString)...)
+	for i, edit := range toolInput.Edits {
+		findings = append(findings, scanSecrets(fmt.Sprintf("edits[%d].new_string", i), edit.NewString)...)
+	}
+	findings = append(findings, scanSecrets("command", toolInput.Command)...)
+	return findings
+}
+
+// secretScanFile scans the first maxSecretScanFileSize bytes of the file at path.
+// Missing and binary files have no findings.
+func secretScanFile(path string) []secretFinding {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	content, err := io.ReadAll(io.LimitReader(f, maxSecretScanFileSize))
+	if err != nil || bytes.IndexByte(content, 0) >= 0 {
+		return nil
+	}
+	return scanSecrets(path, string(content))
+}
+
+// secretScanReason returns the reason of a secret_scan action for findings, without the secrets themselves.
+func secretScanReason(findings []secretFinding) string {
+	var b strings.Builder
+	b.WriteString("Possible secret detected. Remove it and read it from an environment variable or a secret manager instead:")
+	for _, finding := range findings {
+		b.WriteString("\n- ")
+		b.WriteString(finding.String())
+	}
+	return b.String()
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// shellBuiltins are the commands run by the shell itself, which are not looked up in PATH.
+var shellBuiltins = map[string]bool{
+	":": true, ".": true, "[": true, "alias": true, "break": true, "builtin": true, "cd": true, "command": true,
+	"continue": true, "echo": true, "eval": true, "exec": true, "exit": true, "export": true, "false": true,
+	"getopts": true, "hash": true, "local": true, "printf": true, "pwd": true, "read": true, "readonly": true,
+	"return": true, "set": true, "shift": true, "source": true, "test": true, "trap": true, "true": true,
+	"type": true, "ulimit": true, "umask": true, "unalias": true, "unset": true, "wait": true,
+}
+
+// selfTestResult is the result of the self-test of a session, recorded in its session state.
+type selfTestResult struct {
+	Time    time.Time           `json:"time"`
+	Checked []string            `json:"checked"`
+	Missing map[string][]string `json:"missing,omitempty"` // 見つからないコマンド → それを使うフック ("Stop hook 2")
+}
+
+// commandPrograms returns the programs a command runs: the first word of each simple command,
+// except shell builtins and words that are only known at run time (templates, command substitutions).
+func commandPrograms(command string) []string {
+	file, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+	if err != nil {
+		return nil
+	}
+	cfg := &expand.Config{Env: expand.FuncEnviron(os.Getenv)}
+
+	var programs []string
+	syntax.Walk(file, func(node syntax.Node) bool {
+		call, ok := node.(*syntax.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+		fields, err := expand.Fields(cfg, call.Args[0])
+		if err != nil || len(fields) != 1 {
+			return true
+		}
+		program := fields[0]
+		if program == "" || shellBuiltins[program] || strings.ContainsAny(program, "{}$") {
+			return true
+		}
+		if !slices.Contains(programs, program) {
+			programs = append(programs, program)
+		}
+		return true
+	})
+	return programs
+}
+
+// programExists reports whether program can be run: an executable file for a path, otherwise a command in PATH.
+func programExists(program string) bool {
+	if strings.Contains(program, "/") {
+		info, err := os.Stat(program)
+		return err == nil && !info.IsDir() && info.Mode().Perm()&0o111 != 0
+	}
+	_, err := exec.LookPath(program)
+	return err == nil
+}
+
+// runSelfTest checks that the programs run by the local command actions of every event exist.
+// It runs once per session (the result is recorded in the session state, except during a diagnostic run) and returns the warning
+// for the systemMessage of SessionStart, empty when every program was found or the session was already checked.
+func runSelfTest(config *Config, sessionID string) string {
+	path := ""
+	if sessionID != "" {
+		path = sessionStatePath(sessionID, ".selftest.json")
+		if _, err := os.Stat(path); err == nil {
+			return ""
+		}
+	}
+
+	result := selfTestResult{Time: currentTime(), Missing: map[string][]string{}}
+	var missing []string
+	for _, eventType := range policyUIEventOrder {
+		for i, actions := range hookActions(config, eventType) {
+			hook := fmt.Sprintf("%s hook %d", eventType, i+1)
+			for _, action := range actions {
+				// ランナーで実行するコマンドはローカルに無くてもよい
+				if action.Type != "command" || action.Runner != "" {
+					continue
+				}
+				for _, program := range commandPrograms(configuredCommand(action)) {
+					if !slices.Contains(result.Checked, program) {
+						result.Checked = append(result.Checked, program)
+						if !programExists(program) {
+							missing = append(missing, program)
+						}
+					}
+					if slices.Contains(missing, program) && !slices.Contains(result.Missing[program], hook) {
+						result.Missing[program] = append(result.Missing[program], hook)
+					}
+				}
+			}
+		}
+	}
+
+	if path != "" && !diagnosticRun {
+		if data, err := json.Marshal(result); err == nil {
+			if err := os.MkdirAll(filepath.Dir(path), 0o700); err == nil {
+				_ = os.WriteFile(path, data, 0o600)
+			}
+		}
+	}
+	if len(missing) == 0 {
+		return ""
+	}
+	entries := make([]string, len(missing))
+	for i, program := range missing {
+		entries[i] = fmt.Sprintf("%s (%s)", program, strings.Join(result.Missing[program], ", "))
+	}
+	noun := "commands"
+	if len(missing) == 1 {
+		noun = "command"
+	}
+	return fmt.Sprintf("cchook self-test: %d %s not found: %s", len(missing), noun, strings.Join(entries, ", "))
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCommandPrograms(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"gofmt -l . && go vet ./...", []string{"gofmt", "go"}},
+		{"cd {.cwd} && echo done | tee -a log.txt", []string{"tee"}},
+		{`if [ -f go.mod ]; then ./scripts/lint.sh "$(git diff --name-only)"; fi`, []string{"./scripts/lint.sh", "git"}},
+		{"{.tool_input.command} --version", nil},
+		{"FOO=1 make test", []string{"make"}},
+		{"unterminated '", nil},
+	}
+	for _, tt := range tests {
+		if got := commandPrograms(tt.command); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("commandPrograms(%q) = %v, want %v", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestRunSelfTest(t *testing.T) {
+	withSessionStateDir(t)
+	script := filepath.Join(t.TempDir(), "check.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\n"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &Config{
+		SelfTest: true,
+		PostToolUse: []PostToolUseHook{
+			{Actions: []Action{{Type: "command", Command: "cchook-missing-formatter -w {.tool_input.file_path}"}}},
+			{Actions: []Action{{Type: "command", Command: script + " && sh -c true"}}},
+		},
+		Stop: []StopHook{
+			{Actions: []Action{{Type: "command", Args: []string{"cchook-missing-formatter", "--check"}}}},
+			{Actions: []Action{{Type: "command", Command: "cchook-missing-linter", Runner: "remote"}}},
+		},
+	}
+
+	want := "cchook self-test: 1 command not found: cchook-missing-formatter (PostToolUse hook 1, Stop hook 1)"
+	if got := runSelfTest(config, "s1"); got != want {
+		t.Errorf("runSelfTest() = %q, want %q", got, want)
+	}
+	// 同じセッションでは2回目以降警告しない (resume・compactでもSessionStartは発火する)
+	if got := runSelfTest(config, "s1"); got != "" {
+		t.Errorf("runSelfTest() for the same session = %q, want no warning", got)
+	}
+	data, err := os.ReadFile(sessionStatePath("s1", ".selftest.json"))
+	if err != nil || !strings.Contains(string(data), `"checked":["cchook-missing-formatter","`+script+`","sh"]`) {
+		t.Errorf("Recorded result = %s, %v", data, err)
+	}
+
+	output, err := executeSessionStartHooks(config, &SessionStartInput{BaseInput: BaseInput{SessionID: "s2"}}, map[string]any{})
+	if err != nil || output.SystemMessage != want || !output.Continue {
+		t.Errorf("executeSessionStartHooks() = %+v, %v, want the self-test warning", output, err)
+	}
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultIdleTimeout is how long `cchook -command serve` waits for an event before exiting.
+const defaultIdleTimeout = 30 * time.Minute
+
+// daemonDialTimeout bounds connecting to the daemon, so that a client falls back to running the hooks itself quickly.
+const daemonDialTimeout = time.Second
+
+// daemonReadyTimeout bounds waiting for the daemon to pick up an event. The daemon runs events one at a time,
+// so while a long hook runs (e.g. a run_tests Stop hook) the events of other sessions are run by their clients.
+const daemonReadyTimeout = 500 * time.Millisecond
+
+// daemonReady is sent by the daemon when it picks up a connection; only then does the client send its event,
+// so that an event given up on by the client is never run by the daemon too.
+const daemonReady byte = 1
+
+// daemonRequestTimeout bounds reading a request, so that a stuck client can't block the daemon.
+const daemonRequestTimeout = 10 * time.Second
+
+// daemonProtocolVersion is bumped whenever daemonRequest or daemonResponse changes.
+const daemonProtocolVersion = 2
+
+// defaultSocketPath returns the unix socket of the daemon: $XDG_RUNTIME_DIR/cchook.sock,
+// or a per-user socket in the temporary directory.
+func defaultSocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "cchook.sock")
+	}
+	return filepath.Join(os.TempDir(), "cchook-"+strconv.Itoa(os.Getuid())+".sock")
+}
+
+// daemonVersion identifies the cchook binary, so that a client and a daemon built from different sources
+// (e.g. after upgrading cchook while the daemon runs) notice it. It is a variable so that tests can change it.
+var daemonVersion = sync.OnceValue(func() string {
+	id := strconv.Itoa(daemonProtocolVersion)
+	if exe, err := os.Executable(); err == nil {
+		if stamp, err := statFileStamp(exe); err == nil {
+			id += fmt.Sprintf(":%s:%d:%d", exe, stamp.Size, stamp.ModTime)
+		}
+	}
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:8])
+})
+
+// daemonRequest is an event sent by `cchook -client` to the daemon: the flags of the run command,
+// and the process state the hooks depend on.
+type daemonRequest struct {
+	Version       string
+	ConfigPath    string
+	EventType     HookEventType
+	ProjectConfig bool
+	MaxInputSize  int64
+	InputOverflow string
+	Output        string
+	Cwd           string
+	Env           []string
+	Stdin         []byte
+}
+
+// daemonResponse is the result of a daemonRequest. VersionMismatch is set instead of running the hooks
+// when the client is a different cchook binary.
+type daemonResponse struct {
+	VersionMismatch bool
+	Stdout          []byte
+	Stderr          []byte
+	ExitCode        int
+}
+
+// eventMutex serializes the events run by the daemon with the requests of its policy UI,
+// since both use process-wide state (working directory, environment, caches).
+var eventMutex sync.Mutex
+
+// runServe serves events on the unix socket at socketPath until no event arrives for idleTimeout
+// (0 for no limit), a client of another version connects, or the process is interrupted.
+// Events are run one at a time, since the working directory, environment and stdio are process-wide.
+// With uiAddr, the policy UI of the config at configPath is served on it too, and with approvalAddr,
+// the endpoint receiving the decisions of ask_external actions.
+func runServe(socketPath string, idleTimeout time.Duration, uiAddr, configPath, approvalAddr string) error {
+	if socketPath == "" {
+		socketPath = defaultSocketPath()
+	}
+	if conn, err := net.DialTimeout("unix", socketPath, daemonDialTimeout); err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("a daemon is already serving on %s", socketPath)
+	}
+	// 前回のデーモンが残したソケットファイルを消す
+	_ = os.Remove(socketPath)
+	if err := os.MkdirAll(filepath.Dir(socketPath), 0o700); err != nil {
+		return fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", socketPath, err)
+	}
+	unixListener := listener.(*net.UnixListener)
+	unixListener.SetUnlinkOnClose(true)
+	defer func() { _ = listener.Close() }()
+	// 他のユーザーがイベントを送れないようにする
+	if err := os.Chmod(socketPath, 0o600); err != nil {
+		return fmt.Errorf("failed to restrict socket permissions: %w", err)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		if _, ok := <-signals; ok {
+			_ = listener.Close()
+		}
+	}()
+
+	configMemoryCache = map[string]*configCacheEntry{}
+	defer func() { configMemoryCache = nil }()
+
+	if uiAddr != "" {
+		uiListener, err := net.Listen("tcp", uiAddr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", uiAddr, err)
+		}
+		server := &http.Server{Handler: newPolicyUIHandler(configPath), ReadHeaderTimeout: daemonRequestTimeout}
+		defer func() { _ = server.Close() }()
+		fmt.Fprintf(os.Stderr, "cchook: serving policy UI on http://%s\n", uiListener.Addr())
+		go func() { _ = server.Serve(uiListener) }()
+	}
+	if approvalAddr != "" {
+		stop, err := startApprovalEndpoint(approvalAddr)
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
+
+	for {
+		if idleTimeout > 0 {
+			_ = unixListener.SetDeadline(time.Now().Add(idleTimeout))
+		}
+		conn, err := listener.Accept()
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			fmt.Fprintf(os.Stderr, "cchook: no events for %s, shutting down\n", idleTimeout)
+			return nil
+		}
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to accept a connection: %w", err)
+		}
+		if mismatch := serveConn(conn); mismatch {
+			fmt.Fprintf(os.Stderr, "cchook: a client of another cchook version connected, shutting down\n")
+			return nil
+		}
+	}
+}
+
+// serveConn runs the event of a single connection. Returns true when the client is of another version.
+func serveConn(conn net.Conn) bool {
+	defer func() { _ = conn.Close() }()
+	// クライアントが待ちきれずに閉じていれば何もしない
+	if _, err := conn.Write([]byte{daemonReady}); err != nil {
+		return false
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(daemonRequestTimeout))
+	var req daemonRequest
+	if err := gob.NewDecoder(conn).Decode(&req); err != nil {
+		// 接続だけして閉じるのは、デーモンが動いているかの確認
+		if !errors.Is(err, io.EOF) {
+			fmt.Fprintf(os.Stderr, "Warning: failed to read a request: %v\n", err)
+		}
+		return false
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+
+	var resp daemonResponse
+	if req.Version != daemonVersion() {
+		resp.VersionMismatch = true
+	} else {
+		eventMutex.Lock()
+		resp = serveHookEvent(req)
+		eventMutex.Unlock()
+	}
+	if err := gob.NewEncoder(conn).Encode(&resp); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to send a response: %v\n", err)
+	}
+	return resp.VersionMismatch
+}
+
+// serveHookEvent runs the hooks of a request like `cchook -event` run by the client would:
+// in the client's working directory and environment, with the request's stdin, capturing stdout and stderr.
+func serveHookEvent(req daemonRequest) (resp daemonResponse) {
+	stdin, stdout, stderr := os.Stdin, os.Stdout, os.Stderr
+	wd, _ := os.Getwd()
+	env := os.Environ()
+	files, err := newDaemonStdio(req.Stdin)
+	if err != nil {
+		return daemonResponse{Stderr: fmt.Appendf(nil, "Error: %v\n", err), ExitCode: 1}
+	}
+	defer func() {
+		os.Stdin, os.Stdout, os.Stderr = stdin, stdout, stderr
+		_ = os.Chdir(wd)
+		setEnviron(env)
+		resp.Stdout, resp.Stderr = files.close()
+	}()
+
+	os.Stdin, os.Stdout, os.Stderr = files.stdin, files.stdout, files.stderr
+	setEnviron(req.Env)
+	if err := os.Chdir(req.Cwd); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		resp.ExitCode = 1
+		return resp
+	}
+	maxInputSize = req.MaxInputSize
+	inputOverflowStrategy = req.InputOverflow
+	hookOutputMode = req.Output
+	// 1回の起動毎の状態を新しいプロセスと同じにする
+	prefetchedInput = nil
+	resetHookLog()
+	resetEventCaches()
+
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(os.Stderr, "Error: panic: %v\n", r)
+			resp.ExitCode = 1
+		}
+	}()
+	resp.ExitCode = runHookEvent(req.ConfigPath, req.EventType, req.ProjectConfig)
+	resetHookLog()
+	return resp
+}
+
+// daemonStdio are the temporary files standing in for the stdio of a request
+// (files rather than pipes, so that the hooks' commands can inherit them).
+type daemonStdio struct {
+	stdin, stdout, stderr *os.File
+}
+
+// newDaemonStdio creates the stdio files of a request, with data as stdin.
+func newDaemonStdio(data []byte) (*daemonStdio, error) {
+	var files daemonStdio
+	for _, f := range []**os.File{&files.stdin, &files.stdout, &files.stderr} {
+		tmp, err := os.CreateTemp("", "cchook-stdio-*")
+		if err != nil {
+			files.close()
+			return nil, fmt.Errorf("failed to create a stdio file: %w", err)
+		}
+		// 開いたまま消しておき、デーモンが落ちてもファイルが残らないようにする
+		_ = os.Remove(tmp.Name())
+		*f = tmp
+	}
+	if _, err := files.stdin.Write(data); err != nil {
+		files.close()
+		return nil, fmt.Errorf("failed to write stdin: %w", err)
+	}
+	if _, err := files.stdin.Seek(0, io.SeekStart); err != nil {
+		files.close()
+		return nil, fmt.Errorf("failed to write stdin: %w", err)
+	}
+	return &files, nil
+}
+
+// close closes the files and returns what was written to stdout and stderr.
+func (files *daemonStdio) close() (stdout, stderr []byte) {
+	read := func(f *os.File) []byte {
+		if f == nil {
+			return nil
+		}
+		defer func() { _ = f.Close() }()
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil
+		}
+		data, _ := io.ReadAll(f)
+		return data
+	}
+	stdout, stderr = read(files.stdout), read(files.stderr)
+	read(files.stdin)
+	return stdout, stderr
+}
+
+// setEnviron replaces the environment of the process with env ("KEY=value" entries).
+func setEnviron(env []string) {
+	os.Clearenv()
+	for _, kv := range env {
+		if key, value, ok := strings.Cut(kv, "="); ok && key != "" {
+			_ = os.Setenv(key, value)
+		}
+	}
+}
+
+// runClient sends the event on stdin to the daemon listening on socketPath and writes its output,
+// returning the exit code of the hooks. When no daemon is running, the daemon is busy with another event for
+// daemonReadyTimeout, or the daemon is another version of cchook, the hooks are run in this process instead.
+func runClient(socketPath, configPath string, eventType HookEventType, projectConfig bool) int {
+	if socketPath == "" {
+		socketPath = defaultSocketPath()
+	}
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to read stdin: %v\n", err)
+		return 1
+	}
+	cwd, _ := os.Getwd()
+	resp, err := requestDaemon(socketPath, daemonRequest{
+		Version:       daemonVersion(),
+		ConfigPath:    configPath,
+		EventType:     eventType,
+		ProjectConfig: projectConfig,
+		MaxInputSize:  maxInputSize,
+		InputOverflow: inputOverflowStrategy,
+		Output:        hookOutputMode,
+		Cwd:           cwd,
+		Env:           os.Environ(),
+		Stdin:         data,
+	})
+	if err != nil && !errors.Is(err, errDaemonUnavailable) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err != nil || resp.VersionMismatch {
+		// デーモンが使えなくてもフックは実行する（読み込んだstdinは先読みした入力として渡す）
+		raw, err := readInput(bytes.NewReader(data))
+		prefetchedInput = &prefetchedStdin{raw: raw, err: err}
+		return runHookEvent(configPath, eventType, projectConfig)
+	}
+	_, _ = os.Stdout.Write(resp.Stdout)
+	_, _ = os.Stderr.Write(resp.Stderr)
+	return resp.ExitCode
+}
+
+// errDaemonUnavailable is returned by requestDaemon when no daemon accepted the event (so it was not run).
+var errDaemonUnavailable = errors.New("daemon is not running")
+
+// requestDaemon sends req to the daemon listening on socketPath and returns its response.
+func requestDaemon(socketPath string, req daemonRequest) (*daemonResponse, error) {
+	conn, err := net.DialTimeout("unix", socketPath, daemonDialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errDaemonUnavailable, err)
+	}
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetReadDeadline(time.Now().Add(daemonReadyTimeout))
+	ready := make([]byte, 1)
+	if _, err := io.ReadFull(conn, ready); err != nil || ready[0] != daemonReady {
+		return nil, fmt.Errorf("%w: the daemon is busy: %v", errDaemonUnavailable, err)
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+	if err := gob.NewEncoder(conn).Encode(&req); err != nil {
+		return nil, fmt.Errorf("%w: failed to send the event: %v", errDaemonUnavailable, err)
+	}
+	var resp daemonResponse
+	if err := gob.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("daemon: failed to read the response: %w", err)
+	}
+	return &resp, nil
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startTestDaemon runs runServe on a new socket until the test ends and returns the socket and runServe's result.
+func startTestDaemon(t *testing.T, idleTimeout time.Duration) (string, chan error) {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "cchook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	socketPath := filepath.Join(dir, "cchook.sock")
+	done := make(chan error, 1)
+	go func() { done <- runServe(socketPath, idleTimeout, "", "", "") }()
+	for i := 0; i < 100; i++ {
+		if _, err := os.Stat(socketPath); err == nil {
+			return socketPath, done
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("daemon did not start")
+	return "", nil
+}
+
+func TestRunServe(t *testing.T) {
+	origCacheDir := configCacheDir
+	cacheDir := t.TempDir()
+	configCacheDir = func() string { return cacheDir }
+	t.Cleanup(func() { configCacheDir = origCacheDir })
+
+	project := t.TempDir()
+	configPath := filepath.Join(project, "config.yaml")
+	writeConfig := func(message string, mtime time.Time) {
+		t.Helper()
+		config := "PreToolUse:\n  - matcher: Bash\n    actions:\n      - type: output\n        permission_decision: deny\n        message: \"" + message + "\"\n"
+		if err := os.WriteFile(configPath, []byte(config), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(configPath, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfig("{env.CCHOOK_TEST_PROFILE}: {.tool_input.command}", time.Now().Add(-time.Hour))
+
+	socketPath, done := startTestDaemon(t, 0)
+	if err := runServe(socketPath, 0, "", "", ""); err == nil || !strings.Contains(err.Error(), "already serving") {
+		t.Errorf("second daemon: err = %v", err)
+	}
+
+	request := daemonRequest{
+		Version:       daemonVersion(),
+		ConfigPath:    "config.yaml",
+		EventType:     PreToolUse,
+		MaxInputSize:  defaultMaxInputSize,
+		InputOverflow: inputOverflowTruncate,
+		Output:        outputModeJSON,
+		Cwd:           project,
+		Env:           []string{"CCHOOK_TEST_PROFILE=strict", "HOME=" + project},
+		Stdin:         []byte(`{"session_id":"s1","cwd":"` + project + `","tool_name":"Bash","tool_input":{"command":"ls"}}`),
+	}
+	wd, _ := os.Getwd()
+	resp, err := requestDaemon(socketPath, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.ExitCode != 0 || !strings.Contains(string(resp.Stdout), `"permissionDecisionReason": "strict: ls"`) {
+		t.Errorf("response = %d, stdout %s, stderr %s", resp.ExitCode, resp.Stdout, resp.Stderr)
+	}
+	if got, _ := os.Getwd(); got != wd || os.Getenv("CCHOOK_TEST_PROFILE") != "" {
+		t.Errorf("daemon state leaked: cwd %s, env %q", got, os.Getenv("CCHOOK_TEST_PROFILE"))
+	}
+
+	// 設定を書き換えたら次のイベントから使う
+	writeConfig("updated", time.Now().Add(-time.Minute))
+	if resp, err = requestDaemon(socketPath, request); err != nil || !strings.Contains(string(resp.Stdout), `"permissionDecisionReason": "updated"`) {
+		t.Errorf("after editing the config: %v, stdout %s", err, resp.Stdout)
+	}
+
+	request.Version = "other"
+	if resp, err = requestDaemon(socketPath, request); err != nil || !resp.VersionMismatch || len(resp.Stdout) > 0 {
+		t.Errorf("version mismatch: %+v, %v", resp, err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("runServe = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("daemon did not shut down after a version mismatch")
+	}
+	if _, err := requestDaemon(socketPath, request); err == nil {
+		t.Error("daemon still accepts events")
+	}
+}
+
+func TestRequestDaemon_Busy(t *testing.T) {
+	socketPath, _ := startTestDaemon(t, 0)
+	// イベントを送らない接続でデーモンを塞ぐ
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	if _, err := io.ReadFull(conn, make([]byte, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	_, err = requestDaemon(socketPath, daemonRequest{Version: daemonVersion()})
+	if !errors.Is(err, errDaemonUnavailable) {
+		t.Errorf("requestDaemon() error = %v, want errDaemonUnavailable so that the client runs the hooks", err)
+	}
+	if elapsed := time.Since(start); elapsed > daemonRequestTimeout/2 {
+		t.Errorf("requestDaemon() waited %s for a busy daemon", elapsed)
+	}
+}
+
+func TestRunServe_IdleTimeout(t *testing.T) {
+	socketPath, done := startTestDaemon(t, 50*time.Millisecond)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("runServe = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("daemon did not shut down when idle")
+	}
+	if _, err := os.Stat(socketPath); !os.IsNotExist(err) {
+		t.Errorf("socket was not removed: %v", err)
+	}
+}
+
+func TestRunServe_UI(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("Stop:\n  - actions:\n      - type: output\n        message: daemon-ui\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	_ = listener.Close()
+
+	dir, err := os.MkdirTemp("", "cchook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	socketPath := filepath.Join(dir, "cchook.sock")
+	done := make(chan error, 1)
+	go func() { done <- runServe(socketPath, 200*time.Millisecond, addr, configPath, "") }()
+
+	var body string
+	for i := 0; i < 100 && body == ""; i++ {
+		time.Sleep(10 * time.Millisecond)
+		resp, err := http.Get("http://" + addr + "/")
+		if err != nil {
+			continue
+		}
+		data, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		body = string(data)
+	}
+	if !strings.Contains(body, "daemon-ui") {
+		t.Errorf("Expected the daemon to serve the policy UI, got:\n%s", body)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("runServe = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("daemon did not exit after the idle timeout")
+	}
+}
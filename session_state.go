@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sessionStateDir returns the directory holding per-session state (the files changed by hooks).
+// It is a variable so that tests can use a temporary directory.
+var sessionStateDir = func() string {
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "cchook", "sessions")
+	}
+	return filepath.Join(os.TempDir(), "cchook-sessions")
+}
+
+// sessionIDPattern matches session IDs that can be used as file names as they are (Claude Code uses UUIDs).
+var sessionIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,128}$`)
+
+// hookChange is a file rewritten by the actions of a hook, recorded one JSON line per change.
+type hookChange struct {
+	Time     time.Time `json:"time"`
+	File     string    `json:"file"`
+	Event    string    `json:"event"`
+	Hook     int       `json:"hook"` // 1始まりのフック番号
+	Commands []string  `json:"commands,omitempty"`
+}
+
+// sessionStatePath returns the path of the state file of the session with the given extension.
+func sessionStatePath(sessionID, ext string) string {
+	name := sessionID
+	if !sessionIDPattern.MatchString(name) {
+		sum := sha256.Sum256([]byte(sessionID))
+		name = hex.EncodeToString(sum[:])
+	}
+	return filepath.Join(sessionStateDir(), name+ext)
+}
+
+// recordHookChange appends change to the changes of the session. Changes without a session,
+// or made during a diagnostic run, are not recorded.
+func recordHookChange(sessionID string, change hookChange) error {
+	if sessionID == "" || diagnosticRun {
+		return nil
+	}
+	data, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	path := sessionStatePath(sessionID, ".changes.jsonl")
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create session state directory: %w", err)
+	}
+	// 1行ずつO_APPENDで書くので、並列に動くフックの記録が混ざらない
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to record hook change: %w", err)
+	}
+	_, err = f.Write(append(data, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record hook change: %w", err)
+	}
+	return nil
+}
+
+// loadHookChanges returns the changes recorded for the session, oldest first.
+func loadHookChanges(sessionID string) ([]hookChange, error) {
+	if sessionID == "" {
+		return nil, nil
+	}
+	f, err := os.Open(sessionStatePath(sessionID, ".changes.jsonl"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var changes []hookChange
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var change hookChange
+		// 書き込み途中の行は読み飛ばす
+		if json.Unmarshal(scanner.Bytes(), &change) == nil {
+			changes = append(changes, change)
+		}
+	}
+	return changes, scanner.Err()
+}
+
+// reportedHookChanges returns how many changes of the session a hook_changes_report action already reported.
+func reportedHookChanges(sessionID string) int {
+	data, err := os.ReadFile(sessionStatePath(sessionID, ".reported"))
+	if err != nil {
+		return 0
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// hookChangesReport returns the message of a hook_changes_report action: the files changed by hooks
+// since the last report of the session, each listed once with the hooks that changed it.
+// It returns "" when no file changed. With markReported the changes are left out of the next report.
+func hookChangesReport(sessionID string, markReported bool) (string, error) {
+	changes, err := loadHookChanges(sessionID)
+	if err != nil {
+		return "", fmt.Errorf("failed to read hook changes: %w", err)
+	}
+	reported := min(reportedHookChanges(sessionID), len(changes))
+	if reported == len(changes) {
+		return "", nil
+	}
+
+	var files []string
+	byFile := map[string][]string{}
+	for _, change := range changes[reported:] {
+		if _, ok := byFile[change.File]; !ok {
+			files = append(files, change.File)
+		}
+		by := fmt.Sprintf("%s hook %d", change.Event, change.Hook)
+		if len(change.Commands) > 0 {
+			by += ": " + strings.Join(change.Commands, "; ")
+		}
+		if !slices.Contains(byFile[change.File], by) {
+			byFile[change.File] = append(byFile[change.File], by)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("Files changed by cchook hooks (not by Claude):")
+	for _, file := range files {
+		fmt.Fprintf(&b, "\n- %s (%s)", file, strings.Join(byFile[file], ", "))
+	}
+
+	// 次回のレポートには新しい変更だけを載せる
+	if !markReported {
+		return b.String(), nil
+	}
+	if err := os.WriteFile(sessionStatePath(sessionID, ".reported"), []byte(strconv.Itoa(len(changes))), 0o600); err != nil {
+		return "", fmt.Errorf("failed to record hook changes report: %w", err)
+	}
+	return b.String(), nil
+}
+
+// hookCommands returns the commands of the command actions of a hook, as written in the config.
+func hookCommands(actions []Action) []string {
+	var commands []string
+	for _, action := range actions {
+		if action.Type == "command" {
+			commands = append(commands, configuredCommand(action))
+		}
+	}
+	return commands
+}
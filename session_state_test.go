@@ -0,0 +1,124 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withSessionStateDir makes the session state use a temporary directory.
+func withSessionStateDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	original := sessionStateDir
+	sessionStateDir = func() string { return dir }
+	t.Cleanup(func() { sessionStateDir = original })
+	return dir
+}
+
+func TestHookChangesReport(t *testing.T) {
+	withSessionStateDir(t)
+	const sessionID = "0b6c7f1e-session"
+
+	if report, err := hookChangesReport(sessionID, true); err != nil || report != "" {
+		t.Fatalf("hookChangesReport() without changes = %q, %v, want empty", report, err)
+	}
+
+	changes := []hookChange{
+		{File: "/repo/main.go", Event: "PostToolUse", Hook: 1, Commands: []string{"gofmt -w {.tool_input.file_path}"}},
+		{File: "/repo/util.go", Event: "PostToolUse", Hook: 1, Commands: []string{"gofmt -w {.tool_input.file_path}"}},
+		{File: "/repo/main.go", Event: "PostToolUse", Hook: 1, Commands: []string{"gofmt -w {.tool_input.file_path}"}},
+		{File: "/repo/main.go", Event: "PostToolUse", Hook: 3, Commands: []string{"goimports -w {.tool_input.file_path}"}},
+	}
+	for _, change := range changes {
+		if err := recordHookChange(sessionID, change); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := hookChangesReport(sessionID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Files changed by cchook hooks (not by Claude):\n" +
+		"- /repo/main.go (PostToolUse hook 1: gofmt -w {.tool_input.file_path}, PostToolUse hook 3: goimports -w {.tool_input.file_path})\n" +
+		"- /repo/util.go (PostToolUse hook 1: gofmt -w {.tool_input.file_path})"
+	if report != want {
+		t.Errorf("hookChangesReport() = %q, want %q", report, want)
+	}
+
+	// 報告済みの変更は次のレポートに載らない
+	if report, err := hookChangesReport(sessionID, true); err != nil || report != "" {
+		t.Errorf("second hookChangesReport() = %q, %v, want empty", report, err)
+	}
+	if err := recordHookChange(sessionID, hookChange{File: "/repo/util.go", Event: "PostToolUse", Hook: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if report, _ := hookChangesReport(sessionID, true); report != "Files changed by cchook hooks (not by Claude):\n- /repo/util.go (PostToolUse hook 2)" {
+		t.Errorf("hookChangesReport() after a new change = %q", report)
+	}
+
+	// 他のセッションの変更は載らない
+	if report, _ := hookChangesReport("another-session", true); report != "" {
+		t.Errorf("hookChangesReport() of another session = %q, want empty", report)
+	}
+}
+
+func TestSessionStatePath(t *testing.T) {
+	dir := withSessionStateDir(t)
+
+	if got := sessionStatePath("abc-123", ".changes.jsonl"); got != filepath.Join(dir, "abc-123.changes.jsonl") {
+		t.Errorf("sessionStatePath() = %q", got)
+	}
+	// ファイル名に使えないIDはハッシュにする
+	got := sessionStatePath("../../etc/passwd", ".changes.jsonl")
+	if filepath.Dir(got) != dir || strings.Contains(filepath.Base(got), "..") {
+		t.Errorf("sessionStatePath() = %q, want a file in %q", got, dir)
+	}
+}
+
+func TestExecutePostToolUseHooks_RecordsHookChanges(t *testing.T) {
+	withSessionStateDir(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(path, []byte("original\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &Config{PostToolUse: []PostToolUseHook{
+		{Matcher: "Write", Actions: []Action{{Type: "command", Command: "cat {.tool_input.file_path} > /dev/null"}}},
+		{Matcher: "Write", Actions: []Action{{Type: "command", Command: "printf 'formatted\\n' > {.tool_input.file_path}"}}},
+	}}
+	input := &PostToolUseInput{
+		BaseInput: BaseInput{SessionID: "session-1", Cwd: dir},
+		ToolName:  "Write",
+		ToolInput: ToolInput{FilePath: "main.go"},
+	}
+	rawJSON := map[string]any{"session_id": "session-1", "cwd": dir, "tool_name": "Write", "tool_input": map[string]any{"file_path": path}}
+	if _, err := executePostToolUseHooksJSON(config, input, rawJSON); err != nil {
+		t.Fatalf("executePostToolUseHooksJSON() error = %v", err)
+	}
+
+	changes, err := loadHookChanges("session-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].File != path || changes[0].Hook != 2 || changes[0].Event != "PostToolUse" {
+		t.Fatalf("changes = %+v, want one change of %s by hook 2", changes, path)
+	}
+
+	// Stopのhook_changes_reportアクションが変更をsystemMessageで伝える
+	executor := NewActionExecutor(nil)
+	stopInput := &StopInput{BaseInput: BaseInput{SessionID: "session-1"}}
+	output, err := executor.ExecuteStopAction(Action{Type: "hook_changes_report"}, stopInput, map[string]any{"session_id": "session-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output == nil || !strings.Contains(output.SystemMessage, path) || output.Decision != "" {
+		t.Errorf("ExecuteStopAction() = %+v, want a systemMessage listing %s", output, path)
+	}
+	if output, _ := executor.ExecuteStopAction(Action{Type: "hook_changes_report"}, stopInput, nil); output != nil {
+		t.Errorf("ExecuteStopAction() without new changes = %+v, want nil", output)
+	}
+}
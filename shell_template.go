@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// templatePattern matches the {query} placeholders of a template.
+var templatePattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+// shellQuoteState is where a template placeholder appears in a shell command line.
+type shellQuoteState int
+
+const (
+	shellUnquoted shellQuoteState = iota
+	shellSingleQuoted
+	shellDoubleQuoted
+)
+
+// shellTemplateReplace expands the templates of a command action like unifiedTemplateReplace, but escapes
+// each value for where it appears in the command line, so that prompts or file paths can't inject commands:
+// unquoted values become a single-quoted word, and values inside '...' or "..." are escaped for those quotes.
+// Values whose query ends with `| raw` are inserted as they are, and so are unquoted values ending with
+// `| shellquote` (already quoted).
+func shellTemplateReplace(template string, rawJSON any) string {
+	var b strings.Builder
+	state := shellUnquoted
+	last := 0
+	for _, m := range templatePattern.FindAllStringSubmatchIndex(template, -1) {
+		literal := template[last:m[0]]
+		b.WriteString(literal)
+		state = scanShellQuotes(literal, state)
+		last = m[1]
+
+		query := strings.TrimSpace(template[m[2]:m[3]])
+		segments := splitJQPipeline(query)
+		filter := strings.TrimSpace(segments[len(segments)-1])
+		if filter == "raw" && len(segments) > 1 {
+			query = strings.Join(segments[:len(segments)-1], "|")
+		}
+		value, err := executeJQQuery(query, rawJSON)
+		if err != nil {
+			value = fmt.Sprintf("[JQ_ERROR: %s]", err.Error())
+		}
+
+		switch {
+		case filter == "raw", filter == "shellquote" && state == shellUnquoted:
+			b.WriteString(value)
+		default:
+			b.WriteString(shellEscape(value, state))
+		}
+	}
+	b.WriteString(template[last:])
+	return b.String()
+}
+
+// scanShellQuotes returns the quote state after the literal text s of a command line, starting in state.
+func scanShellQuotes(s string, state shellQuoteState) shellQuoteState {
+	for i := 0; i < len(s); i++ {
+		switch state {
+		case shellUnquoted:
+			switch s[i] {
+			case '\\':
+				i++
+			case '\'':
+				state = shellSingleQuoted
+			case '"':
+				state = shellDoubleQuoted
+			}
+		case shellSingleQuoted:
+			if s[i] == '\'' {
+				state = shellUnquoted
+			}
+		case shellDoubleQuoted:
+			switch s[i] {
+			case '\\':
+				i++
+			case '"':
+				state = shellUnquoted
+			}
+		}
+	}
+	return state
+}
+
+// shellEscape escapes value so that the shell reads it literally in state.
+func shellEscape(value string, state shellQuoteState) string {
+	switch state {
+	case shellSingleQuoted:
+		// 'を閉じて\'を挟み、また開く
+		return strings.ReplaceAll(value, "'", `'\''`)
+	case shellDoubleQuoted:
+		var b strings.Builder
+		for _, r := range value {
+			if r == '\\' || r == '$' || r == '`' || r == '"' {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		}
+		return b.String()
+	default:
+		return shellQuote(value)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestShellTemplateReplace(t *testing.T) {
+	data := map[string]any{
+		"tool_input": map[string]any{"file_path": "/repo/my file.go"},
+		"prompt":     `it's "$(rm -rf ~)" \ ok`,
+		"files":      []any{"a.go", "b c.go"},
+		"count":      3,
+	}
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{name: "unquoted", template: "gofmt -w {.tool_input.file_path}", want: `gofmt -w '/repo/my file.go'`},
+		{name: "unquoted injection", template: "echo {.prompt}", want: `echo 'it'\''s "$(rm -rf ~)" \ ok'`},
+		{name: "single quoted", template: "echo 'prompt: {.prompt}'", want: `echo 'prompt: it'\''s "$(rm -rf ~)" \ ok'`},
+		{name: "double quoted", template: `echo "prompt: {.prompt}"`, want: `echo "prompt: it's \"\$(rm -rf ~)\" \\ ok"`},
+		{name: "escaped quote is literal", template: `echo \' {.count}`, want: `echo \' '3'`},
+		{name: "quotes closed before value", template: `echo "a" {.count} 'b' {.count}`, want: `echo "a" '3' 'b' '3'`},
+		{name: "single quote inside double quotes", template: `echo "it's {.count}"`, want: `echo "it's 3"`},
+		{name: "raw", template: "goimports -w {.files | @sh | raw}", want: `goimports -w 'a.go' 'b c.go'`},
+		{name: "raw inside quotes", template: `echo "{.count | raw}"`, want: `echo "3"`},
+		{name: "shellquote is not quoted twice", template: "echo {.prompt | truncate 4 | shellquote}", want: `echo 'it'\''…'`},
+		{name: "missing value", template: "echo {.missing}", want: "echo ''"},
+		{name: "no template", template: "go vet ./...", want: "go vet ./..."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shellTemplateReplace(tt.template, data); got != tt.want {
+				t.Errorf("shellTemplateReplace(%q) = %q, want %q", tt.template, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShellTemplateReplace_ShellReadsValuesLiterally(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	values := []string{
+		`plain`,
+		`with space`,
+		`it's`,
+		`"double"`,
+		`$(echo injected)`,
+		"`echo injected`",
+		`a; echo injected`,
+		`back\slash`,
+		"multi\nline",
+	}
+	templates := []string{
+		`printf '%s' {.value}`,
+		`printf '%s' '{.value}'`,
+		`printf '%s' "{.value}"`,
+		`printf '%s' "<{.value}>"`,
+	}
+	for _, value := range values {
+		for _, template := range templates {
+			command := shellTemplateReplace(template, map[string]any{"value": value})
+			out, err := exec.Command("sh", "-c", command).Output()
+			if err != nil {
+				t.Errorf("%q failed: %v", command, err)
+				continue
+			}
+			got := strings.TrimSuffix(strings.TrimPrefix(string(out), "<"), ">")
+			if got != value {
+				t.Errorf("%q printed %q, want %q", command, out, value)
+			}
+		}
+	}
+}
+
+func TestActionCommand_ShellEscaping(t *testing.T) {
+	rawJSON := map[string]any{"tool_input": map[string]any{"file_path": "a;b.go"}}
+	if got := actionCommand(Action{Type: "command", Command: "gofmt -w {.tool_input.file_path}"}, rawJSON); got != `gofmt -w 'a;b.go'` {
+		t.Errorf("actionCommand() = %q", got)
+	}
+	// typecheckのコマンドはcchookが組み立てるので展開しない
+	if got := actionCommand(Action{Type: "typecheck", Command: "tsc {x}"}, rawJSON); got != "tsc {x}" {
+		t.Errorf("actionCommand(typecheck) = %q", got)
+	}
+}
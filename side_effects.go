@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Side effects an action can declare with `side_effects`.
+const (
+	sideEffectWritesFiles   = "writes_files"  // writes or formats files
+	sideEffectNetwork       = "network"       // sends requests to other hosts
+	sideEffectNotifications = "notifications" // notifies people (desktop, chat, mail)
+)
+
+// sideEffectNames are the valid side effects, in the order dry-run shows them.
+var sideEffectNames = []string{sideEffectWritesFiles, sideEffectNetwork, sideEffectNotifications}
+
+// dryRunMaxSideEffects is the -max-side-effects limit of dry-run: hooks declaring only these side effects
+// are shown in one line. nil when there is no limit.
+var dryRunMaxSideEffects []string
+
+// validateSideEffect checks a side effect declared by an action.
+func validateSideEffect(name string) error {
+	if !slices.Contains(sideEffectNames, name) {
+		return fmt.Errorf("invalid side effect %q (must be %s)", name, strings.Join(sideEffectNames, ", "))
+	}
+	return nil
+}
+
+// parseMaxSideEffects parses the -max-side-effects flag: a comma-separated list of side effects,
+// or "none". An empty value means no limit (nil).
+func parseMaxSideEffects(value string) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if value == "none" {
+		return []string{}, nil
+	}
+	var effects []string
+	for name := range strings.SplitSeq(value, ",") {
+		name = strings.TrimSpace(name)
+		if err := validateSideEffect(name); err != nil {
+			return nil, fmt.Errorf("-max-side-effects: %w", err)
+		}
+		effects = append(effects, name)
+	}
+	return effects, nil
+}
+
+// hookSideEffects returns the side effects declared by the actions of a hook, in the order of sideEffectNames.
+func hookSideEffects(actions []Action) []string {
+	var effects []string
+	for _, name := range sideEffectNames {
+		if slices.ContainsFunc(actions, func(action Action) bool { return slices.Contains(action.SideEffects, name) }) {
+			effects = append(effects, name)
+		}
+	}
+	return effects
+}
+
+// dryRunWithinMaxSideEffects writes a one-line summary of a hook that would run and reports true
+// when its side effects stay within -max-side-effects, so that the dry-run only details the hooks beyond it.
+func dryRunWithinMaxSideEffects(w io.Writer, index int, actions []Action) bool {
+	if dryRunMaxSideEffects == nil {
+		return false
+	}
+	effects := hookSideEffects(actions)
+	if slices.ContainsFunc(effects, func(name string) bool { return !slices.Contains(dryRunMaxSideEffects, name) }) {
+		return false
+	}
+	summary := "none"
+	if len(effects) > 0 {
+		summary = strings.Join(effects, ", ")
+	}
+	fmt.Fprintf(w, "[Hook %d] Would execute (side effects within -max-side-effects: %s)\n", index+1, summary)
+	return true
+}
+
+// dryRunSideEffects writes the side effects declared by the actions of a hook that would run.
+func dryRunSideEffects(w io.Writer, actions []Action) {
+	effects := hookSideEffects(actions)
+	if len(effects) == 0 {
+		return
+	}
+	line := "  Side effects: " + strings.Join(effects, ", ")
+	if dryRunMaxSideEffects != nil {
+		line += " (beyond -max-side-effects)"
+	}
+	fmt.Fprintln(w, line)
+}
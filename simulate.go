@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// eventTemplates are sample event inputs per event type used by `cchook -command simulate`.
+var eventTemplates = map[HookEventType]string{
+	PreToolUse:        `{"session_id":"simulate","transcript_path":"/tmp/transcript.jsonl","cwd":".","hook_event_name":"PreToolUse","tool_name":"Bash","tool_input":{"command":"rm -rf build"}}`,
+	PostToolUse:       `{"session_id":"simulate","transcript_path":"/tmp/transcript.jsonl","cwd":".","hook_event_name":"PostToolUse","tool_name":"Write","tool_input":{"file_path":"main.go","content":"package main"},"tool_response":{"success":true}}`,
+	PermissionRequest: `{"session_id":"simulate","transcript_path":"/tmp/transcript.jsonl","cwd":".","hook_event_name":"PermissionRequest","tool_name":"Bash","tool_input":{"command":"git push"}}`,
+	Notification:      `{"session_id":"simulate","transcript_path":"/tmp/transcript.jsonl","cwd":".","hook_event_name":"Notification","message":"Claude is waiting for your input","notification_type":"idle_prompt"}`,
+	Stop:              `{"session_id":"simulate","transcript_path":"/tmp/transcript.jsonl","cwd":".","hook_event_name":"Stop","stop_hook_active":false}`,
+	SubagentStop:      `{"session_id":"simulate","transcript_path":"/tmp/transcript.jsonl","cwd":".","hook_event_name":"SubagentStop","stop_hook_active":false,"agent_id":"agent-1","agent_type":"Explore"}`,
+	SubagentStart:     `{"session_id":"simulate","transcript_path":"/tmp/transcript.jsonl","cwd":".","hook_event_name":"SubagentStart","agent_id":"agent-1","agent_type":"Explore"}`,
+	PreCompact:        `{"session_id":"simulate","transcript_path":"/tmp/transcript.jsonl","cwd":".","hook_event_name":"PreCompact","trigger":"manual","custom_instructions":""}`,
+	SessionStart:      `{"session_id":"simulate","transcript_path":"/tmp/transcript.jsonl","cwd":".","hook_event_name":"SessionStart","source":"startup"}`,
+	SessionEnd:        `{"session_id":"simulate","transcript_path":"/tmp/transcript.jsonl","cwd":".","hook_event_name":"SessionEnd","reason":"clear"}`,
+	UserPromptSubmit:  `{"session_id":"simulate","transcript_path":"/tmp/transcript.jsonl","cwd":".","hook_event_name":"UserPromptSubmit","prompt":"Please refactor main.go"}`,
+}
+
+const simulateHelp = `Commands:
+  :event <EventType>     Set the event type used for inputs without hook_event_name
+  :template [EventType]  Show and evaluate the sample event for the (current) event type
+  :templates             List event types that have sample events
+  :reload                Reload the config file
+  :help                  Show this help
+  :quit                  Exit
+Anything else is read as event JSON (multi-line paste is supported).
+The config file is reloaded automatically when it changes.`
+
+// simulator holds the REPL state of `cchook -command simulate`.
+type simulator struct {
+	configPath string
+	config     *Config
+	modTime    time.Time
+	eventType  HookEventType
+}
+
+// runSimulate starts an interactive REPL reading events from in and printing
+// matched hooks (dry-run) and the resulting JSON output for each event.
+// Note: command actions are actually executed to produce the resulting JSON.
+func runSimulate(configPath string, eventType HookEventType, in io.Reader) error {
+	if configPath == "" {
+		configPath = getDefaultConfigPath()
+	}
+	s := &simulator{configPath: configPath, eventType: eventType}
+	if err := s.reload(); err != nil {
+		return err
+	}
+
+	fmt.Println("cchook simulate - type :help for commands")
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	var pending strings.Builder
+
+	s.prompt(pending.Len() > 0)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if pending.Len() == 0 && strings.HasPrefix(trimmed, ":") {
+			if quit := s.handleCommand(trimmed); quit {
+				return nil
+			}
+			s.prompt(false)
+			continue
+		}
+
+		if pending.Len() == 0 && trimmed == "" {
+			s.prompt(false)
+			continue
+		}
+
+		pending.WriteString(line)
+		pending.WriteString("\n")
+		data := []byte(pending.String())
+		if json.Valid(data) {
+			s.evaluate(data)
+			pending.Reset()
+		} else if trimmed == "" {
+			// 空行で入力を確定し、不正なJSONとして破棄する
+			fmt.Fprintf(os.Stderr, "Error: invalid event JSON\n")
+			pending.Reset()
+		}
+		s.prompt(pending.Len() > 0)
+	}
+	return scanner.Err()
+}
+
+// prompt prints the REPL prompt. continuation is true while a multi-line JSON is being pasted.
+func (s *simulator) prompt(continuation bool) {
+	if continuation {
+		fmt.Print("... ")
+		return
+	}
+	event := string(s.eventType)
+	if event == "" {
+		event = "-"
+	}
+	fmt.Printf("cchook(%s)> ", event)
+}
+
+// handleCommand executes a `:command` line. Returns true when the REPL should exit.
+func (s *simulator) handleCommand(line string) bool {
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case ":quit", ":q", ":exit":
+		return true
+	case ":help", ":h":
+		fmt.Println(simulateHelp)
+	case ":event":
+		if len(fields) < 2 {
+			fmt.Fprintf(os.Stderr, "Error: usage: :event <EventType>\n")
+			break
+		}
+		eventType := HookEventType(fields[1])
+		if !eventType.IsValid() {
+			fmt.Fprintf(os.Stderr, "Error: invalid event type '%s'\n", fields[1])
+			break
+		}
+		s.eventType = eventType
+	case ":templates":
+		for _, name := range templateEventNames() {
+			fmt.Println(name)
+		}
+	case ":template":
+		eventType := s.eventType
+		if len(fields) >= 2 {
+			eventType = HookEventType(fields[1])
+		}
+		template, ok := eventTemplates[eventType]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: no template for event type '%s' (see :templates)\n", eventType)
+			break
+		}
+		s.eventType = eventType
+		fmt.Println(template)
+		s.evaluate([]byte(template))
+	case ":reload":
+		if err := s.reload(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			break
+		}
+		fmt.Printf("Config reloaded: %s\n", s.configPath)
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown command %s (type :help)\n", fields[0])
+	}
+	return false
+}
+
+// reload loads the config file and remembers its modification time.
+func (s *simulator) reload() error {
+	info, err := os.Stat(s.configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	config, err := loadConfig(s.configPath)
+	if err != nil {
+		return err
+	}
+	s.config = config
+	s.modTime = info.ModTime()
+	activeBudget = config.Budget
+	return nil
+}
+
+// reloadIfChanged reloads the config when the file has been modified since the last load.
+func (s *simulator) reloadIfChanged() {
+	info, err := os.Stat(s.configPath)
+	if err != nil || info.ModTime().Equal(s.modTime) {
+		return
+	}
+	if err := s.reload(); err != nil {
+		// 編集途中の不正なYAMLでは直前の設定を使い続ける
+		fmt.Fprintf(os.Stderr, "Warning: keeping previous config: %v\n", err)
+		return
+	}
+	fmt.Printf("Config reloaded: %s\n", s.configPath)
+}
+
+// evaluate shows matched hooks and the resulting JSON output for a single event.
+func (s *simulator) evaluate(data []byte) {
+	s.reloadIfChanged()
+
+	eventType := s.eventType
+	var base struct {
+		HookEventName HookEventType `json:"hook_event_name"`
+	}
+	if err := json.Unmarshal(data, &base); err == nil && base.HookEventName != "" {
+		eventType = base.HookEventName
+	}
+	if !eventType.IsValid() {
+		fmt.Fprintf(os.Stderr, "Error: unknown event type '%s' (set hook_event_name or use :event)\n", eventType)
+		return
+	}
+
+	if err := simulateEvent(s.config, eventType, data); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+}
+
+// simulateEvent prints the dry-run result and the resulting JSON output of eventType for data.
+func simulateEvent(config *Config, eventType HookEventType, data []byte) error {
+	resetEventCaches()
+	if err := dryRunHooksFrom(os.Stdout, bytes.NewReader(data), config, eventType); err != nil {
+		return err
+	}
+
+	output, err := executeHooksFrom(bytes.NewReader(data), config, eventType, true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	jsonBytes, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal output: %w", err)
+	}
+	fmt.Println("=== Output ===")
+	fmt.Println(string(jsonBytes))
+	return nil
+}
+
+// templateEventNames returns event types that have sample events, sorted by name.
+func templateEventNames() []string {
+	names := make([]string, 0, len(eventTemplates))
+	for eventType := range eventTemplates {
+		names = append(names, string(eventType))
+	}
+	sort.Strings(names)
+	return names
+}
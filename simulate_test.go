@@ -0,0 +1,156 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const simulateTestConfig = `PreToolUse:
+  - matcher: "Bash"
+    conditions:
+      - type: command_starts_with
+        value: "rm"
+    actions:
+      - type: output
+        message: "no rm: {.tool_input.command}"
+        permission_decision: "deny"
+`
+
+func writeSimulateConfig(t *testing.T, content string) string {
+	t.Helper()
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return configPath
+}
+
+func TestRunSimulate_MultiLineEvent(t *testing.T) {
+	configPath := writeSimulateConfig(t, simulateTestConfig)
+
+	input := strings.Join([]string{
+		`{"hook_event_name": "PreToolUse",`,
+		`  "tool_name": "Bash",`,
+		`  "tool_input": {"command": "rm -rf build"}}`,
+		":quit",
+		"",
+	}, "\n")
+
+	var err error
+	output := captureStdout(t, func() {
+		err = runSimulate(configPath, "", strings.NewReader(input))
+	})
+	if err != nil {
+		t.Fatalf("runSimulate() error = %v", err)
+	}
+
+	for _, want := range []string{
+		"[Hook 1] Would execute:",
+		`"permissionDecision": "deny"`,
+		`"permissionDecisionReason": "no rm: rm -rf build"`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestRunSimulate_EventCommandAndTemplate(t *testing.T) {
+	configPath := writeSimulateConfig(t, simulateTestConfig)
+
+	// hook_event_nameの無い入力は:eventで指定したイベントとして扱う
+	input := strings.Join([]string{
+		":event PreToolUse",
+		`{"tool_name": "Bash", "tool_input": {"command": "rm a"}}`,
+		":template Stop",
+		":quit",
+		"",
+	}, "\n")
+
+	var err error
+	output := captureStdout(t, func() {
+		err = runSimulate(configPath, "", strings.NewReader(input))
+	})
+	if err != nil {
+		t.Fatalf("runSimulate() error = %v", err)
+	}
+
+	if !strings.Contains(output, `"permissionDecisionReason": "no rm: rm a"`) {
+		t.Errorf("Expected PreToolUse output, got:\n%s", output)
+	}
+	if !strings.Contains(output, "=== Stop Hooks (Dry Run) ===") {
+		t.Errorf("Expected Stop template to be evaluated, got:\n%s", output)
+	}
+	if !strings.Contains(output, "cchook(Stop)> ") {
+		t.Errorf("Expected prompt to switch to Stop, got:\n%s", output)
+	}
+}
+
+func TestSimulator_ReloadIfChanged(t *testing.T) {
+	configPath := writeSimulateConfig(t, simulateTestConfig)
+
+	s := &simulator{configPath: configPath, eventType: PreToolUse}
+	if err := s.reload(); err != nil {
+		t.Fatalf("reload() error = %v", err)
+	}
+
+	updated := strings.Replace(simulateTestConfig, `permission_decision: "deny"`, `permission_decision: "ask"`, 1)
+	if err := os.WriteFile(configPath, []byte(updated), 0644); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+	// mtimeの分解能に依存しないよう明示的に進める
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(configPath, future, future); err != nil {
+		t.Fatalf("Failed to update mtime: %v", err)
+	}
+
+	output := captureStdout(t, func() {
+		s.evaluate([]byte(`{"tool_name": "Bash", "tool_input": {"command": "rm a"}}`))
+	})
+
+	if !strings.Contains(output, "Config reloaded") {
+		t.Errorf("Expected config to be reloaded, got:\n%s", output)
+	}
+	if !strings.Contains(output, `"permissionDecision": "ask"`) {
+		t.Errorf("Expected updated config to be used, got:\n%s", output)
+	}
+}
+
+func TestEventTemplates_CoverAllEvents(t *testing.T) {
+	for _, eventType := range []HookEventType{
+		PreToolUse, PostToolUse, PermissionRequest, Notification, Stop, SubagentStop,
+		SubagentStart, PreCompact, SessionStart, SessionEnd, UserPromptSubmit,
+	} {
+		template, ok := eventTemplates[eventType]
+		if !ok {
+			t.Errorf("Missing template for %s", eventType)
+			continue
+		}
+		var err error
+		captureStdout(t, func() {
+			err = simulateEvent(&Config{}, eventType, []byte(template))
+		})
+		if err != nil {
+			t.Errorf("Template for %s failed to simulate: %v", eventType, err)
+		}
+	}
+}
+
+func TestSimulateEvent_ResetsEventCaches(t *testing.T) {
+	t.Cleanup(resetInvocationCache)
+	// 前のイベントのgit_dirtyなどの結果を使い回さない
+	if _, err := cachedLookup("stale", true, func() (bool, error) { return true, nil }); err != nil {
+		t.Fatal(err)
+	}
+	captureStdout(t, func() {
+		if err := simulateEvent(&Config{}, Stop, []byte(`{"hook_event_name":"Stop"}`)); err != nil {
+			t.Errorf("simulateEvent() error = %v", err)
+		}
+	})
+	if _, ok := invocationCache["stale"]; ok {
+		t.Error("Expected the lookups of the previous event to be dropped")
+	}
+}
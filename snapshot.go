@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+)
+
+// fileSnapshot is a copy of tool_input.file_path that the command actions of a `snapshot: true` hook work on.
+// The copy replaces the original only if the original is still the snapshotted content,
+// so a formatter never clobbers an edit made while it was running.
+type fileSnapshot struct {
+	path string   // the original file
+	copy string   // the copy the command works on (same directory, so that config files are found)
+	hash [32]byte // SHA-256 of the original when the snapshot was taken
+}
+
+// takeFileSnapshot copies the file at path next to it. It returns nil if the file can't be read.
+func takeFileSnapshot(path string) (*fileSnapshot, error) {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return nil, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil
+	}
+
+	// 拡張子を保つ（フォーマッタは拡張子で言語を判断する）
+	f, err := os.CreateTemp(filepath.Dir(path), ".cchook-snapshot-*-"+filepath.Base(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot of %s: %w", path, err)
+	}
+	snapshot := &fileSnapshot{path: path, copy: f.Name(), hash: sha256.Sum256(content)}
+	_, err = f.Write(content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(snapshot.copy, info.Mode().Perm())
+	}
+	if err != nil {
+		snapshot.discard()
+		return nil, fmt.Errorf("failed to create snapshot of %s: %w", path, err)
+	}
+	return snapshot, nil
+}
+
+// withCopy returns rawJSON with tool_input.file_path replaced by the copy, for templates and use_stdin.
+func (s *fileSnapshot) withCopy(rawJSON map[string]any) map[string]any {
+	toolInput, _ := rawJSON["tool_input"].(map[string]any)
+	toolInput = maps.Clone(toolInput)
+	toolInput["file_path"] = s.copy
+	rawJSON = maps.Clone(rawJSON)
+	rawJSON["tool_input"] = toolInput
+	return rawJSON
+}
+
+// apply replaces the original with the copy if the command changed the copy.
+// It returns false, discarding the copy, if the original changed since the snapshot was taken.
+func (s *fileSnapshot) apply() (bool, error) {
+	current, err := os.ReadFile(s.path)
+	if err != nil || sha256.Sum256(current) != s.hash {
+		s.discard()
+		return false, nil
+	}
+	result, err := os.ReadFile(s.copy)
+	if err != nil {
+		s.discard()
+		return true, fmt.Errorf("failed to read snapshot of %s: %w", s.path, err)
+	}
+	if bytes.Equal(result, current) {
+		s.discard()
+		return true, nil
+	}
+	if err := os.Rename(s.copy, s.path); err != nil {
+		s.discard()
+		return true, fmt.Errorf("failed to apply snapshot of %s: %w", s.path, err)
+	}
+	return true, nil
+}
+
+// discard removes the copy.
+func (s *fileSnapshot) discard() {
+	_ = os.Remove(s.copy)
+}
+
+// snapshotFilePath returns the absolute tool_input.file_path of the event JSON ("" if it has none).
+func snapshotFilePath(rawJSON map[string]any) string {
+	toolInput, _ := rawJSON["tool_input"].(map[string]any)
+	filePath, _ := toolInput["file_path"].(string)
+	cwd, _ := rawJSON["cwd"].(string)
+	return resolveToolFilePath(filePath, cwd)
+}
+
+// resolveToolFilePath resolves a relative tool_input.file_path against cwd ("" stays "").
+func resolveToolFilePath(filePath, cwd string) string {
+	if filePath == "" || filepath.IsAbs(filePath) {
+		return filePath
+	}
+	return filepath.Join(cwd, filePath)
+}
+
+// fileContentHash returns the SHA-256 of the file's content, or false if it can't be read.
+func fileContentHash(path string) ([32]byte, bool) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return [32]byte{}, false
+	}
+	return sha256.Sum256(content), true
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestActionExecutor_Snapshot(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string // ORIGは元のファイルのパス
+		wantExit int
+		want     string
+	}{
+		{"result is applied", "printf 'formatted\\n' > {.tool_input.file_path}", 0, "formatted\n"},
+		{"unchanged result", "cat {.tool_input.file_path} > /dev/null", 0, "original\n"},
+		{"file changed meanwhile", "printf 'formatted\\n' > {.tool_input.file_path} && printf 'newer edit\\n' > ORIG", 0, "newer edit\n"},
+		{"command failed", "printf 'broken\\n' > {.tool_input.file_path} && exit 3", 3, "original\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "main.go")
+			if err := os.WriteFile(path, []byte("original\n"), 0640); err != nil {
+				t.Fatal(err)
+			}
+			// 相対パスはcwdから解決する
+			rawJSON := map[string]any{"cwd": dir, "tool_input": map[string]any{"file_path": "main.go"}}
+			command := strings.ReplaceAll(tt.command, "ORIG", path)
+
+			executor := NewActionExecutor(nil).withSnapshot(true)
+			_, _, exitCode, _ := executor.runAction(Action{Type: "command", Command: command}, rawJSON)
+			if exitCode != tt.wantExit {
+				t.Errorf("exit code = %d, want %d", exitCode, tt.wantExit)
+			}
+
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("file content = %q, want %q", got, tt.want)
+			}
+			if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0640 {
+				t.Errorf("file mode = %v, %v, want 0640", info.Mode().Perm(), err)
+			}
+			if leftovers, _ := filepath.Glob(filepath.Join(dir, ".cchook-snapshot-*")); len(leftovers) > 0 {
+				t.Errorf("snapshot copies left behind: %v", leftovers)
+			}
+			if rawJSON["tool_input"].(map[string]any)["file_path"] != "main.go" {
+				t.Error("the event JSON was modified")
+			}
+		})
+	}
+
+	t.Run("missing file runs on the path as-is", func(t *testing.T) {
+		runner := &recordingRunner{}
+		executor := NewActionExecutor(runner).withSnapshot(true)
+		rawJSON := map[string]any{"tool_input": map[string]any{"file_path": "/nonexistent/main.go"}}
+		if _, _, _, err := executor.runAction(Action{Type: "command", Command: "gofmt -w {.tool_input.file_path}"}, rawJSON); err != nil {
+			t.Fatalf("runAction() error = %v", err)
+		}
+		if runner.last != "gofmt -w '/nonexistent/main.go'" {
+			t.Errorf("Ran %q", runner.last)
+		}
+	})
+}
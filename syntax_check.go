@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// syntaxCheckers maps file extensions to the parsers of syntax_check actions.
+var syntaxCheckers = map[string]func(src string) error{
+	".json": checkJSONSyntax,
+	".yaml": checkYAMLSyntax,
+	".yml":  checkYAMLSyntax,
+	".toml": checkTOMLSyntax,
+	".xml":  checkXMLSyntax,
+}
+
+// syntaxError is a parse error with its position (1-based; 0 when unknown).
+type syntaxError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *syntaxError) Error() string {
+	switch {
+	case e.Line == 0:
+		return e.Message
+	case e.Column == 0:
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// checkFileSyntax parses the file at path with the parser for its extension.
+// It returns false if the extension is not supported or the file can't be read.
+func checkFileSyntax(path string) (bool, error) {
+	check, ok := syntaxCheckers[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return false, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false, nil
+	}
+	return true, check(string(content))
+}
+
+// lineColumn converts a byte offset of src into a 1-based line and column (in characters).
+func lineColumn(src string, offset int) (int, int) {
+	offset = min(max(offset, 0), len(src))
+	before := src[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := utf8.RuneCountInString(before[strings.LastIndexByte(before, '\n')+1:]) + 1
+	return line, column
+}
+
+func checkJSONSyntax(src string) error {
+	dec := json.NewDecoder(strings.NewReader(src))
+	var value any
+	err := dec.Decode(&value)
+	if err == nil {
+		// 値の後ろに余計なものが続いていないか
+		if _, err = dec.Token(); err == io.EOF {
+			return nil
+		}
+		if err == nil {
+			offset := int(dec.InputOffset())
+			line, column := lineColumn(src, offset)
+			return &syntaxError{Line: line, Column: column, Message: "unexpected data after the top-level value"}
+		}
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		// Offsetはエラーになった文字の直後を指す
+		line, column := lineColumn(src, int(syntaxErr.Offset)-1)
+		return &syntaxError{Line: line, Column: column, Message: syntaxErr.Error()}
+	}
+	if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+		line, column := lineColumn(src, len(src))
+		return &syntaxError{Line: line, Column: column, Message: "unexpected end of JSON input"}
+	}
+	return &syntaxError{Message: err.Error()}
+}
+
+// yamlErrorLinePattern extracts the line of yaml.v3 errors ("yaml: line 3: ...").
+var yamlErrorLinePattern = regexp.MustCompile(`^yaml: (?:line (\d+): )?(.*)$`)
+
+func checkYAMLSyntax(src string) error {
+	// 複数ドキュメント (---区切り) も全て検査する
+	dec := yaml.NewDecoder(strings.NewReader(src))
+	for {
+		var node yaml.Node
+		err := dec.Decode(&node)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			message := err.Error()
+			if m := yamlErrorLinePattern.FindStringSubmatch(message); m != nil {
+				line, _ := strconv.Atoi(m[1])
+				return &syntaxError{Line: line, Message: m[2]}
+			}
+			return &syntaxError{Message: message}
+		}
+	}
+}
+
+func checkXMLSyntax(src string) error {
+	dec := xml.NewDecoder(strings.NewReader(src))
+	dec.Strict = true
+	dec.Entity = xml.HTMLEntity
+	// エンコーディング宣言は検査の対象外 (UTF-8以外もそのまま読む)
+	dec.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) { return input, nil }
+	root := false
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			if !root {
+				return &syntaxError{Message: "no root element"}
+			}
+			return nil
+		}
+		if err != nil {
+			var syntaxErr *xml.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				_, column := dec.InputPos()
+				if syntaxErr.Line != lineOfOffset(src, dec.InputOffset()) {
+					column = 0
+				}
+				return &syntaxError{Line: syntaxErr.Line, Column: column, Message: syntaxErr.Msg}
+			}
+			line, column := dec.InputPos()
+			return &syntaxError{Line: line, Column: column, Message: err.Error()}
+		}
+		if _, ok := token.(xml.StartElement); ok {
+			root = true
+		}
+	}
+}
+
+// lineOfOffset returns the 1-based line of a byte offset of src.
+func lineOfOffset(src string, offset int64) int {
+	return strings.Count(src[:min(int(offset), len(src))], "\n") + 1
+}
+
+// syntaxCheckReason returns the reason of a syntax_check action for a file that failed to parse.
+func syntaxCheckReason(path string, err error) string {
+	var syntaxErr *syntaxError
+	if errors.As(err, &syntaxErr) && syntaxErr.Line > 0 {
+		location := fmt.Sprintf("%s:%d", path, syntaxErr.Line)
+		if syntaxErr.Column > 0 {
+			location += fmt.Sprintf(":%d", syntaxErr.Column)
+		}
+		return fmt.Sprintf("Syntax error in %s: %s", location, syntaxErr.Message)
+	}
+	return fmt.Sprintf("Syntax error in %s: %v", path, err)
+}
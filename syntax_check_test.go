@@ -0,0 +1,163 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckSyntax(t *testing.T) {
+	tests := []struct {
+		name    string
+		check   func(string) error
+		src     string
+		wantErr string // 空ならエラー無し (YAMLの行はyaml.v3の報告どおり)
+	}{
+		{name: "json valid", check: checkJSONSyntax, src: `{"a": [1, 2, {"b": null}]}`},
+		{name: "json trailing comma", check: checkJSONSyntax, src: "{\n  \"a\": 1,\n}\n", wantErr: "line 3, column 1: invalid character '}' looking for beginning of object key string"},
+		{name: "json truncated", check: checkJSONSyntax, src: "{\n  \"a\": [1,", wantErr: "line 2, column 11: unexpected end of JSON input"},
+		{name: "json trailing data", check: checkJSONSyntax, src: "{}\n{}", wantErr: "line 2, column 2: unexpected data after the top-level value"},
+		{name: "json empty", check: checkJSONSyntax, src: "", wantErr: "line 1, column 1: unexpected end of JSON input"},
+
+		{name: "yaml valid", check: checkYAMLSyntax, src: "a: 1\nb:\n  - x\n  - y\n---\nc: d\n"},
+		{name: "yaml bad indentation", check: checkYAMLSyntax, src: "a:\n  b: 1\n c: 2\n", wantErr: "line 2: did not find expected key"},
+		{name: "yaml error in second document", check: checkYAMLSyntax, src: "a: 1\n---\nb: [1, 2\n", wantErr: "line 2: did not find expected ',' or ']'"},
+
+		{name: "xml valid", check: checkXMLSyntax, src: "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<a x=\"1\"><b/>&amp;</a>\n"},
+		{name: "xml mismatched tag", check: checkXMLSyntax, src: "<a>\n  <b>\n</a>\n", wantErr: "line 3, column 5: element <b> closed by </a>"},
+		{name: "xml no root", check: checkXMLSyntax, src: "<!-- only a comment -->", wantErr: "no root element"},
+
+		{name: "toml valid", check: checkTOMLSyntax, src: `# config
+title = "TOML \"example\" \u00e9"
+literal = 'C:\path'
+multi = """
+Roses are red \
+  Violets are blue"""
+multi_literal = '''
+raw \n text'''
+int = +1_000
+hex = 0xDEAD_beef
+float = -3.14e-2
+special = -inf
+bool = true
+date = 1979-05-27
+datetime = 1979-05-27 07:32:00.999-08:00
+time = 07:32:00
+arr = [ 1, 2, # comment
+  3, ]
+nested = [[1, 2], ["a", 'b']]
+inline = { x = 1, y.z = "2" }
+"quoted key" = 1
+a.b.c = 1
+
+[server]
+host = "localhost"
+
+[server.tls]
+enabled = false
+
+[[products]]
+name = "Hammer"
+
+[[products]]
+name = "Nail"
+`},
+		{name: "toml missing value", check: checkTOMLSyntax, src: "a = 1\nb =\n", wantErr: "line 2, column 4: expected a value, found '\\n'"},
+		{name: "toml missing equals", check: checkTOMLSyntax, src: "name \"x\"\n", wantErr: "line 1, column 6: expected \"=\" after key, found '\"'"},
+		{name: "toml unterminated string", check: checkTOMLSyntax, src: "a = \"abc\nb = 1\n", wantErr: "line 1, column 5: unterminated string"},
+		{name: "toml invalid escape", check: checkTOMLSyntax, src: `a = "\q"`, wantErr: `line 1, column 6: invalid escape sequence \q`},
+		{name: "toml invalid number", check: checkTOMLSyntax, src: "a = 01\n", wantErr: `line 1, column 5: invalid value "01"`},
+		{name: "toml invalid date", check: checkTOMLSyntax, src: "a = 2024-02-30\n", wantErr: `line 1, column 5: invalid date "2024-02-30"`},
+		{name: "toml two values on a line", check: checkTOMLSyntax, src: "a = 1 b = 2\n", wantErr: "line 1, column 7: expected a newline, found 'b'"},
+		{name: "toml duplicate key", check: checkTOMLSyntax, src: "a = 1\n[t]\na = 1\nb = 2\nb = 3\n", wantErr: `line 5, column 1: duplicate key "b"`},
+		{name: "toml duplicate table", check: checkTOMLSyntax, src: "[a]\nx = 1\n[b]\n[a]\n", wantErr: "line 4, column 1: table [a] is defined twice"},
+		{name: "toml table redefines value", check: checkTOMLSyntax, src: "a = 1\n[a]\n", wantErr: `line 2, column 1: key "a" is already defined as a value`},
+		{name: "toml array table after table", check: checkTOMLSyntax, src: "[a]\n[[a]]\n", wantErr: "line 2, column 1: table [a] is already defined as a table"},
+		{name: "toml unterminated array", check: checkTOMLSyntax, src: "a = [1, 2\n", wantErr: "line 1, column 5: unterminated array"},
+		{name: "toml array missing comma", check: checkTOMLSyntax, src: "a = [1 2]\n", wantErr: `line 1, column 8: expected "," or "]" in array, found '2'`},
+		{name: "toml multi-line inline table", check: checkTOMLSyntax, src: "a = { x = 1,\n y = 2 }\n", wantErr: "line 1, column 5: unterminated inline table"},
+		{name: "toml inline table duplicate key", check: checkTOMLSyntax, src: "a = { x = 1, x = 2 }\n", wantErr: `line 1, column 14: duplicate key "x"`},
+		{name: "toml unclosed table header", check: checkTOMLSyntax, src: "[a\n", wantErr: `line 1, column 3: expected "]", found '\n'`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.check(tt.src)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("error = nil, want %q", tt.wantErr)
+			}
+			if !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want %q", err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecutePostToolUseAction_SyntaxCheck(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("valid.json", `{"a": 1}`)
+	write("broken.json", "{\n  \"a\": 1,\n}\n")
+	write("broken.txt", "{")
+	executor := NewActionExecutor(nil)
+
+	tests := []struct {
+		name         string
+		file         string
+		action       Action
+		wantDecision string
+		wantReason   string
+		wantContext  string
+		wantNil      bool
+	}{
+		{name: "valid file", file: "valid.json", action: Action{Type: "syntax_check"}, wantNil: true},
+		{name: "unsupported extension", file: "broken.txt", action: Action{Type: "syntax_check"}, wantNil: true},
+		{name: "missing file", file: "missing.json", action: Action{Type: "syntax_check"}, wantNil: true},
+		{
+			name:         "syntax error blocks",
+			file:         "broken.json",
+			action:       Action{Type: "syntax_check"},
+			wantDecision: "block",
+			wantReason:   "Syntax error in broken.json:3:1: invalid character '}' looking for beginning of object key string",
+		},
+		{
+			name:        "report only",
+			file:        "broken.json",
+			action:      Action{Type: "syntax_check", Decision: stringPtr("")},
+			wantContext: "Syntax error in broken.json:3:1: invalid character '}' looking for beginning of object key string",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := &PostToolUseInput{BaseInput: BaseInput{Cwd: dir}, ToolName: "Write", ToolInput: ToolInput{FilePath: tt.file}}
+			output, err := executor.ExecutePostToolUseAction(tt.action, input, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantNil {
+				if output != nil {
+					t.Errorf("output = %+v, want nil", output)
+				}
+				return
+			}
+			if output == nil {
+				t.Fatal("output = nil")
+			}
+			if output.Decision != tt.wantDecision || output.Reason != tt.wantReason || output.AdditionalContext != tt.wantContext {
+				t.Errorf("output = decision %q, reason %q, context %q; want %q, %q, %q",
+					output.Decision, output.Reason, output.AdditionalContext, tt.wantDecision, tt.wantReason, tt.wantContext)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/itchyny/gojq"
+)
+
+// templateFilter is a function templates can pipe values into, in addition to jq's builtins.
+// Filters with arguments can be written jq-style (`truncate(80)`) or shell-style (`truncate 80`).
+type templateFilter struct {
+	minArity, maxArity int
+	fn                 func(v any, args []any) any
+}
+
+// templateFilters is the filter registry, e.g. `{.tool_input.file_path | basename}` or `{.prompt | truncate 80}`.
+// Filters pass null through, so `{.source | upper // "unknown"}` falls back to the default.
+var templateFilters = map[string]templateFilter{
+	"basename": {fn: stringFilter(filepath.Base)},
+	"dirname":  {fn: stringFilter(filepath.Dir)},
+	"upper":    {fn: stringFilter(strings.ToUpper)},
+	"lower":    {fn: stringFilter(strings.ToLower)},
+	"truncate": {minArity: 1, maxArity: 1, fn: truncateFilter},
+	"json":     {fn: jsonFilter},
+	// コマンドアクションに値を1つの引数として安全に埋め込む
+	"shellquote": {fn: func(v any, _ []any) any { return shellQuote(jqValueToString(v)) }},
+	// コマンドアクションで自動のシェルエスケープを外す (値はそのまま)
+	"raw": {fn: func(v any, _ []any) any { return v }},
+}
+
+// templateFilterOptions returns the compiler options registering templateFilters as jq functions.
+func templateFilterOptions() []gojq.CompilerOption {
+	var options []gojq.CompilerOption
+	for name, filter := range templateFilters {
+		options = append(options, gojq.WithFunction(name, filter.minArity, filter.maxArity, filter.fn))
+	}
+	return options
+}
+
+// stringFilter makes a filter of a string function. Non-string values are converted like template output.
+func stringFilter(f func(string) string) func(any, []any) any {
+	return func(v any, _ []any) any {
+		if v == nil {
+			return nil
+		}
+		return f(jqValueToString(v))
+	}
+}
+
+// truncateFilter shortens a string to at most n characters, ending with "…" when cut.
+func truncateFilter(v any, args []any) any {
+	if v == nil {
+		return nil
+	}
+	n, ok := jqInt(args[0])
+	if !ok || n < 1 {
+		return fmt.Errorf("truncate: length must be a positive integer, got %v", args[0])
+	}
+	s := jqValueToString(v)
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
+
+// jsonFilter encodes a value as compact JSON (strings are quoted).
+func jsonFilter(v any, _ []any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("json: %w", err)
+	}
+	return string(data)
+}
+
+// jqInt returns a jq number argument as an int.
+func jqInt(v any) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case float64:
+		if n == float64(int(n)) {
+			return int(n), true
+		}
+	}
+	return 0, false
+}
+
+// templateFilterShorthand matches a pipeline segment calling a filter shell-style: `truncate 80`.
+var templateFilterShorthand = regexp.MustCompile(`^(\s*)([a-z_]+)\s+(-?[0-9]+(?:\.[0-9]+)?|"(?:[^"\\]|\\.)*")(\s*)$`)
+
+// rewriteTemplateFilters turns the shell-style filter calls of a template query into jq calls
+// (`.prompt | truncate 80` → `.prompt | truncate(80)`). Other segments are left alone.
+func rewriteTemplateFilters(query string) string {
+	if !strings.Contains(query, "|") {
+		return query
+	}
+	segments := splitJQPipeline(query)
+	for i, segment := range segments {
+		m := templateFilterShorthand.FindStringSubmatch(segment)
+		if m == nil {
+			continue
+		}
+		if filter, ok := templateFilters[m[2]]; ok && filter.maxArity > 0 {
+			segments[i] = m[1] + m[2] + "(" + m[3] + ")" + m[4]
+		}
+	}
+	return strings.Join(segments, "|")
+}
+
+// splitJQPipeline splits a jq query at its top-level pipes, leaving pipes inside strings,
+// parentheses, brackets and braces (and the `//` and `|=` operators) alone.
+func splitJQPipeline(query string) []string {
+	var segments []string
+	depth, start := 0, 0
+	inString, escaped := false, false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case inString:
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		case c == '|' && depth == 0 && (i+1 >= len(query) || query[i+1] != '='):
+			segments = append(segments, query[start:i])
+			start = i + 1
+		}
+	}
+	return append(segments, query[start:])
+}
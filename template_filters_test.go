@@ -0,0 +1,91 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedTemplateReplace_Filters(t *testing.T) {
+	data := map[string]any{
+		"tool_input": map[string]any{"file_path": "/repo/src/main.go"},
+		"prompt":     "Please refactor the payment module",
+		"tool_name":  "Edit",
+		"title":      "it's done",
+		"tags":       []any{"a", "b"},
+		"count":      3,
+	}
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{name: "basename", template: "gofmt -w {.tool_input.file_path | basename}", want: "gofmt -w main.go"},
+		{name: "dirname", template: "cd {.tool_input.file_path | dirname}", want: "cd /repo/src"},
+		{name: "upper and lower", template: "{.tool_name | upper}/{.tool_name | lower}", want: "EDIT/edit"},
+		{name: "chained", template: "{.tool_input.file_path | basename | upper}", want: "MAIN.GO"},
+		{name: "truncate shorthand", template: "{.prompt | truncate 10}", want: "Please re…"},
+		{name: "truncate call", template: "{.prompt | truncate(10)}", want: "Please re…"},
+		{name: "truncate short string", template: "{.tool_name | truncate 80}", want: "Edit"},
+		{name: "truncate multibyte", template: "{.title | truncate 4}", want: "it'…"},
+		{name: "json", template: "{.tags | json} {.tool_name | json}", want: `["a","b"] "Edit"`},
+		{name: "shellquote", template: "echo {.title | shellquote}", want: `echo 'it'\''s done'`},
+		{name: "shellquote number", template: "seq {.count | shellquote}", want: "seq '3'"},
+		{name: "default", template: `{.source // "unknown"}`, want: "unknown"},
+		{name: "default after filter", template: `{.source | upper // "unknown"}`, want: "unknown"},
+		{name: "filter of missing field", template: "[{.source | basename}]", want: "[]"},
+		{name: "pipe in string", template: `{.tool_name + " | truncate 2"}`, want: "Edit | truncate 2"},
+		{name: "jq builtins still work", template: "{.tags | length}", want: "2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unifiedTemplateReplace(tt.template, data); got != tt.want {
+				t.Errorf("unifiedTemplateReplace(%q) = %q, want %q", tt.template, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnifiedTemplateReplace_FilterErrors(t *testing.T) {
+	got := unifiedTemplateReplace("{.prompt | truncate 0}", map[string]any{"prompt": "hello"})
+	if !strings.HasPrefix(got, "[JQ_ERROR:") || !strings.Contains(got, "truncate: length must be a positive integer") {
+		t.Errorf("got %q, want a truncate error", got)
+	}
+}
+
+func TestSplitJQPipeline(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: ".a", want: []string{".a"}},
+		{query: ".a | basename | upper", want: []string{".a ", " basename ", " upper"}},
+		{query: `.a // "x|y" | lower`, want: []string{`.a // "x|y" `, " lower"}},
+		{query: `.a | map(. | tostring) | join("\"|")`, want: []string{".a ", " map(. | tostring) ", ` join("\"|")`}},
+		{query: ".a |= 1 | .a", want: []string{".a |= 1 ", " .a"}},
+	}
+	for _, tt := range tests {
+		if got := splitJQPipeline(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitJQPipeline(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestRewriteTemplateFilters(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: ".prompt | truncate 80", want: ".prompt | truncate(80)"},
+		{query: ".prompt | truncate 80 | upper", want: ".prompt | truncate(80) | upper"},
+		{query: ".prompt | truncate(80)", want: ".prompt | truncate(80)"},
+		{query: ".prompt | basename", want: ".prompt | basename"},
+		{query: ".prompt | unknown 80", want: ".prompt | unknown 80"},
+		{query: ".prompt", want: ".prompt"},
+	}
+	for _, tt := range tests {
+		if got := rewriteTemplateFilters(tt.query); got != tt.want {
+			t.Errorf("rewriteTemplateFilters(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
@@ -3,7 +3,7 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"regexp"
+	"os"
 	"strings"
 	"sync"
 
@@ -12,7 +12,7 @@ import (
 
 // JQクエリのキャッシュ（パフォーマンス向上のため）
 var (
-	jqQueryCache = make(map[string]*gojq.Query)
+	jqQueryCache = make(map[string]*gojq.Code)
 	jqCacheMutex sync.RWMutex
 )
 
@@ -21,33 +21,13 @@ var (
 // executeJQQuery executes a gojq query against the input and returns the result as a string.
 // It caches compiled queries for performance. Returns an error if the query is invalid or execution fails.
 func executeJQQuery(queryStr string, input any) (string, error) {
-	// クエリをキャッシュから取得または作成
-	jqCacheMutex.RLock()
-	query, exists := jqQueryCache[queryStr]
-	jqCacheMutex.RUnlock()
-
-	if !exists {
-		// クエリをパースしてキャッシュに保存
-		var err error
-		query, err = gojq.Parse(queryStr)
-		if err != nil {
-			return "", fmt.Errorf("invalid jq query '%s': %w", queryStr, err)
-		}
-
-		jqCacheMutex.Lock()
-		jqQueryCache[queryStr] = query
-		jqCacheMutex.Unlock()
-	}
-
-	// 入力データをJSONとしてマーシャル/アンマーシャルして、gojq互換の型に変換
-	inputJSON, err := json.Marshal(input)
+	query, err := compileJQQuery(queryStr)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal input to JSON: %w", err)
+		return "", err
 	}
-
-	var gojqInput any
-	if err := json.Unmarshal(inputJSON, &gojqInput); err != nil {
-		return "", fmt.Errorf("failed to unmarshal JSON for gojq: %w", err)
+	gojqInput, err := toJQInput(input)
+	if err != nil {
+		return "", err
 	}
 
 	// クエリを実行
@@ -81,6 +61,74 @@ func executeJQQuery(queryStr string, input any) (string, error) {
 	}
 }
 
+// compileJQQuery parses and compiles a jq query with the template filters, caching the result.
+// `$ENV` and `env` give the environment variables of cchook (unset variables are null),
+// read when the query is compiled: the environment doesn't change during an invocation.
+func compileJQQuery(queryStr string) (*gojq.Code, error) {
+	// クエリをキャッシュから取得または作成
+	jqCacheMutex.RLock()
+	code, exists := jqQueryCache[queryStr]
+	jqCacheMutex.RUnlock()
+	if exists {
+		return code, nil
+	}
+
+	// クエリをパースしてキャッシュに保存
+	query, err := gojq.Parse(rewriteTemplateFilters(queryStr))
+	if err != nil {
+		return nil, fmt.Errorf("invalid jq query '%s': %w", queryStr, err)
+	}
+	code, err = gojq.Compile(query, append(templateFilterOptions(), gojq.WithEnvironLoader(os.Environ))...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jq query '%s': %w", queryStr, err)
+	}
+	jqCacheMutex.Lock()
+	jqQueryCache[queryStr] = code
+	jqCacheMutex.Unlock()
+	return code, nil
+}
+
+// toJQInput converts input to the types gojq works with (maps, slices, float64, ...).
+func toJQInput(input any) (any, error) {
+	// 入力データをJSONとしてマーシャル/アンマーシャルして、gojq互換の型に変換
+	inputJSON, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal input to JSON: %w", err)
+	}
+	var gojqInput any
+	if err := json.Unmarshal(inputJSON, &gojqInput); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON for gojq: %w", err)
+	}
+	return gojqInput, nil
+}
+
+// evaluateJQCondition runs a jq query against the input and reports whether it produced a truthy value
+// (anything but false and null, as in jq's `select`). A query without output is false.
+func evaluateJQCondition(queryStr string, input any) (bool, error) {
+	query, err := compileJQQuery(queryStr)
+	if err != nil {
+		return false, err
+	}
+	gojqInput, err := toJQInput(input)
+	if err != nil {
+		return false, err
+	}
+
+	iter := query.Run(gojqInput)
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			return false, nil
+		}
+		if err, ok := v.(error); ok {
+			return false, fmt.Errorf("jq query execution error: %w", err)
+		}
+		if v != nil && v != false {
+			return true, nil
+		}
+	}
+}
+
 // jqValueToString converts a gojq result value to a string representation.
 // Handles strings, booleans, null, numbers, and objects/arrays (as JSON).
 func jqValueToString(value any) string {
@@ -109,9 +157,7 @@ func jqValueToString(value any) string {
 // Patterns are detected using {}, and the content is treated as a JQ query executed against rawJSON.
 func unifiedTemplateReplace(template string, rawJSON any) string {
 	// パターン: { で始まり } で終わる任意の内容
-	pattern := regexp.MustCompile(`\{([^}]+)\}`)
-
-	return pattern.ReplaceAllStringFunc(template, func(match string) string {
+	return templatePattern.ReplaceAllStringFunc(template, func(match string) string {
 		jqQuery := strings.TrimSpace(match[1 : len(match)-1]) // {} を除去
 
 		// 常にJQクエリとして処理
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 	"testing"
 )
 
@@ -357,6 +359,26 @@ func TestUnifiedTemplateReplace_EdgeCases(t *testing.T) {
 	}
 }
 
+func TestUnifiedTemplateReplace_Env(t *testing.T) {
+	t.Setenv("CCHOOK_TEST_PROFILE", "strict")
+	_ = os.Unsetenv("CCHOOK_TEST_UNSET")
+	data := map[string]any{"cwd": "/repo"}
+
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"profile={env.CCHOOK_TEST_PROFILE}, cwd={.cwd}", "profile=strict, cwd=/repo"},
+		{"unset={env.CCHOOK_TEST_UNSET}", "unset="},
+		{`{$ENV.CCHOOK_TEST_UNSET // "lax"}`, "lax"},
+	}
+	for _, tt := range tests {
+		if got := unifiedTemplateReplace(tt.template, data); got != tt.want {
+			t.Errorf("unifiedTemplateReplace(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
+
 func TestJQQueryCache(t *testing.T) {
 	// キャッシュの動作確認
 	query := ".test"
@@ -495,3 +517,60 @@ func TestUnifiedTemplateReplace_RealWorldExamples(t *testing.T) {
 		})
 	}
 }
+
+// FuzzUnifiedTemplateReplace はテンプレートと入力JSON（モデルが生成する未信頼の文字列を含む）でpanicしないことを確認する
+func FuzzUnifiedTemplateReplace(f *testing.F) {
+	seeds := []struct {
+		template string
+		input    string
+	}{
+		{"gofmt -w {.tool_input.file_path}", `{"tool_input":{"file_path":"main.go"}}`},
+		{"{.prompt} / {.prompt | length}", `{"prompt":"日本語のプロンプト🎉"}`},
+		{"{{.nested}} {} { } {.a.b.c[0]}", `{"nested":{"x":1}}`},
+		{"{if .ok then \"OK\" else \"NG\" end}", `{"ok":false}`},
+		{"{.tool_input.command | split(\" \") | .[0]}", `{"tool_input":{"command":"rm -rf /"}}`},
+		{"{.x", `"plain string"`},
+		{"{.[] | .}", `[1,"two",null,true,{"k":"v"}]`},
+		{"\xff{.\xfe}", `{"\u0000":"\ud800"}`},
+	}
+	for _, s := range seeds {
+		f.Add(s.template, s.input)
+	}
+
+	f.Fuzz(func(t *testing.T, template string, input string) {
+		var data any
+		if err := json.Unmarshal([]byte(input), &data); err != nil {
+			// JSONとして不正な場合は文字列そのものを入力として扱う
+			data = input
+		}
+		_ = unifiedTemplateReplace(template, data)
+	})
+}
+
+// FuzzExecuteJQQuery はjqのパス式のパースと実行がpanicしないことを確認する
+func FuzzExecuteJQQuery(f *testing.F) {
+	seeds := []string{
+		".tool_input.file_path",
+		".tool_input[\"file_path\"]",
+		".a.b[0].c",
+		".[]?",
+		".[-1:]",
+		".\"日本語\"",
+		"..",
+		"(",
+		".[",
+		"$__loc__",
+	}
+	for _, s := range seeds {
+		f.Add(s)
+	}
+
+	input := map[string]any{
+		"tool_input": map[string]any{"file_path": "main.go", "command": "ls"},
+		"a":          []any{1, "x", nil},
+	}
+
+	f.Fuzz(func(t *testing.T, query string) {
+		_, _ = executeJQQuery(query, input)
+	})
+}
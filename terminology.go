@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// terminologyFinding is a use of a banned term found by a terminology action.
+type terminologyFinding struct {
+	Where string // 見つかった場所 ("content", "new_string", "edits[0].new_string")
+	Line  int    // 1始まりの行番号
+	Found string // 書かれていた表記
+	Rule  TermRule
+}
+
+// String describes the finding with the preferred term, if any.
+func (f terminologyFinding) String() string {
+	s := fmt.Sprintf("%s line %d: %q", f.Where, f.Line, f.Found)
+	if f.Rule.Preferred != "" {
+		s += fmt.Sprintf(" (use %q)", preferredTermFor(f.Found, f.Rule.Preferred))
+	}
+	if f.Rule.Note != "" {
+		s += ": " + f.Rule.Note
+	}
+	return s
+}
+
+// validateTermRules checks the terms of a terminology action.
+func validateTermRules(rules []TermRule) error {
+	if len(rules) == 0 {
+		return errors.New("terminology action requires terms")
+	}
+	var errs []error
+	for i, rule := range rules {
+		if strings.TrimSpace(rule.Term) == "" {
+			errs = append(errs, fmt.Errorf("terms[%d]: term is required", i))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// findTerms returns the uses of the terms of rules in text, by line.
+// Terms match case-insensitively and, where they start or end with a letter or digit, only as whole words.
+// An occurrence written exactly as the preferred term is fine, so {term: github, preferred: GitHub}
+// reports "github" and "Github" but not "GitHub".
+func findTerms(where, text string, rules []TermRule) []terminologyFinding {
+	var findings []terminologyFinding
+	for _, rule := range rules {
+		term := strings.TrimSpace(rule.Term)
+		if term == "" {
+			continue
+		}
+		pattern := regexp.MustCompile(`(?i)` + regexp.QuoteMeta(term))
+		for _, m := range pattern.FindAllStringIndex(text, -1) {
+			start, end := m[0], m[1]
+			if !isWordBoundary(text, start, true) || !isWordBoundary(text, end, false) {
+				continue
+			}
+			found := text[start:end]
+			if found == rule.Preferred {
+				continue
+			}
+			findings = append(findings, terminologyFinding{
+				Where: where,
+				Line:  strings.Count(text[:start], "\n") + 1,
+				Found: found,
+				Rule:  rule,
+			})
+		}
+	}
+	slices.SortStableFunc(findings, func(a, b terminologyFinding) int { return a.Line - b.Line })
+	return findings
+}
+
+// isWordBoundary reports whether a term starting (or ending) at offset i of text is not part of a longer word.
+// Only letters and digits of the term's own edge need a boundary, so "C++" and Japanese terms match anywhere.
+func isWordBoundary(text string, i int, start bool) bool {
+	var inside, outside rune
+	if start {
+		inside, _ = utf8.DecodeRuneInString(text[i:])
+		if i == 0 {
+			return true
+		}
+		outside, _ = utf8.DecodeLastRuneInString(text[:i])
+	} else {
+		inside, _ = utf8.DecodeLastRuneInString(text[:i])
+		if i == len(text) {
+			return true
+		}
+		outside, _ = utf8.DecodeRuneInString(text[i:])
+	}
+	if !isASCIIWordRune(inside) {
+		return true
+	}
+	return !isASCIIWordRune(outside)
+}
+
+// isASCIIWordRune reports whether r is an ASCII letter, digit or underscore.
+func isASCIIWordRune(r rune) bool {
+	return r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_')
+}
+
+// preferredTermFor returns preferred capitalized like found when preferred is all lowercase
+// ("Whitelist" suggests "Allowlist"); otherwise preferred as it is, since its casing is the point.
+func preferredTermFor(found, preferred string) string {
+	if preferred != strings.ToLower(preferred) {
+		return preferred
+	}
+	first, _ := utf8.DecodeRuneInString(found)
+	if !unicode.IsUpper(first) {
+		return preferred
+	}
+	if found == strings.ToUpper(found) && utf8.RuneCountInString(found) > 1 {
+		return strings.ToUpper(preferred)
+	}
+	r, size := utf8.DecodeRuneInString(preferred)
+	return string(unicode.ToUpper(r)) + preferred[size:]
+}
+
+// terminologyToolInput checks what a tool call writes: the content of Write and the new strings of Edit/MultiEdit.
+func terminologyToolInput(toolInput ToolInput, rules []TermRule) []terminologyFinding {
+	var findings []terminologyFinding
+	findings = append(findings, findTerms("content", toolInput.Content, rules)...)
+	findings = append(findings, findTerms("new_string", toolInput.NewString, rules)...)
+	for i, edit := range toolInput.Edits {
+		findings = append(findings, findTerms(fmt.Sprintf("edits[%d].new_string", i), edit.NewString, rules)...)
+	}
+	return findings
+}
+
+// terminologyReport formats the findings of a tool call writing to filePath for Claude.
+func terminologyReport(filePath string, findings []terminologyFinding) string {
+	var b strings.Builder
+	if filePath != "" {
+		fmt.Fprintf(&b, "Terminology issues in %s:", filePath)
+	} else {
+		b.WriteString("Terminology issues:")
+	}
+	for _, finding := range findings {
+		b.WriteString("\n- ")
+		b.WriteString(finding.String())
+	}
+	return b.String()
+}
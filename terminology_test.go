@@ -0,0 +1,125 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testTermRules = []TermRule{
+	{Term: "whitelist", Preferred: "allowlist"},
+	{Term: "github", Preferred: "GitHub"},
+	{Term: "master branch", Preferred: "main branch", Note: "the default branch was renamed"},
+	{Term: "C++"},
+	{Term: "出来る", Preferred: "できる"},
+}
+
+func TestFindTerms(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "no terms", text: "Add the host to the allowlist on GitHub", want: nil},
+		{name: "banned term", text: "Add the host to the whitelist", want: []string{`content line 1: "whitelist" (use "allowlist")`}},
+		{name: "capitalized", text: "Whitelist:\nWHITELIST", want: []string{
+			`content line 1: "Whitelist" (use "Allowlist")`,
+			`content line 2: "WHITELIST" (use "ALLOWLIST")`,
+		}},
+		{name: "casing", text: "Push to Github or github.com, not GitHub", want: []string{
+			`content line 1: "Github" (use "GitHub")`,
+			`content line 1: "github" (use "GitHub")`,
+		}},
+		{name: "whole words only", text: "whitelisted githubusercontent", want: nil},
+		{name: "phrase with note", text: "Merge into the\nMaster branch", want: []string{`content line 2: "Master branch" (use "Main branch"): the default branch was renamed`}},
+		{name: "symbols at the edge", text: "Written in C++17? No, C++.", want: []string{`content line 1: "C++"`, `content line 1: "C++"`}},
+		{name: "Japanese", text: "変更出来る", want: []string{`content line 1: "出来る" (use "できる")`}},
+		{name: "sorted by line", text: "github\nwhitelist\ngithub", want: []string{
+			`content line 1: "github" (use "GitHub")`,
+			`content line 2: "whitelist" (use "allowlist")`,
+			`content line 3: "github" (use "GitHub")`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, finding := range findTerms("content", tt.text, testTermRules) {
+				got = append(got, finding.String())
+			}
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findTerms() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateTermRules(t *testing.T) {
+	if err := validateTermRules(testTermRules); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := validateTermRules(nil); err == nil || err.Error() != "terminology action requires terms" {
+		t.Errorf("error = %v, want missing terms", err)
+	}
+	if err := validateTermRules([]TermRule{{Term: "ok"}, {Preferred: "x"}}); err == nil || err.Error() != "terms[1]: term is required" {
+		t.Errorf("error = %v, want missing term", err)
+	}
+}
+
+func TestExecutePreToolUseAction_Terminology(t *testing.T) {
+	input := &PreToolUseInput{
+		ToolName: "MultiEdit",
+		ToolInput: ToolInput{
+			FilePath: "docs/setup.md",
+			Edits:    []ToolInputEdit{{NewString: "fine"}, {NewString: "Add it to the\nwhitelist"}},
+		},
+	}
+	report := "Terminology issues in docs/setup.md:\n- edits[1].new_string line 2: \"whitelist\" (use \"allowlist\")"
+
+	output, err := NewActionExecutor(nil).ExecutePreToolUseAction(Action{Type: "terminology", Terms: testTermRules}, input, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output == nil || output.PermissionDecision != "" || output.AdditionalContext != report {
+		t.Errorf("output = %+v, want additionalContext %q without a decision", output, report)
+	}
+
+	output, err = NewActionExecutor(nil).ExecutePreToolUseAction(Action{Type: "terminology", Terms: testTermRules, PermissionDecision: stringPtr("deny")}, input, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output == nil || output.PermissionDecision != "deny" || output.PermissionDecisionReason != report {
+		t.Errorf("output = %+v, want deny with %q", output, report)
+	}
+
+	input.ToolInput.Edits = []ToolInputEdit{{NewString: "allowlist"}}
+	output, err = NewActionExecutor(nil).ExecutePreToolUseAction(Action{Type: "terminology", Terms: testTermRules, PermissionDecision: stringPtr("deny")}, input, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != nil {
+		t.Errorf("output = %+v, want nil", output)
+	}
+}
+
+func TestExecutePostToolUseAction_Terminology(t *testing.T) {
+	input := &PostToolUseInput{ToolName: "Write", ToolInput: ToolInput{FilePath: "README.md", Content: "# Setup\n\nClone it from github"}}
+
+	output, err := NewActionExecutor(nil).ExecutePostToolUseAction(Action{Type: "terminology", Terms: testTermRules}, input, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output == nil || output.Decision != "" || !strings.Contains(output.AdditionalContext, `content line 3: "github" (use "GitHub")`) {
+		t.Errorf("output = %+v, want the finding as additionalContext", output)
+	}
+
+	output, err = NewActionExecutor(nil).ExecutePostToolUseAction(Action{Type: "terminology", Terms: testTermRules, Decision: stringPtr("block")}, input, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output == nil || output.Decision != "block" || !strings.HasPrefix(output.Reason, "Terminology issues in README.md:") {
+		t.Errorf("output = %+v, want block", output)
+	}
+}
@@ -1,6 +1,11 @@
 package main
 
-import "errors"
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+)
 
 // stubRunnerWithOutput is a test stub that implements CommandRunner for testing executor actions.
 type stubRunnerWithOutput struct {
@@ -51,3 +56,50 @@ func stringPtr(s string) *string {
 func intPtr(i int) *int {
 	return &i
 }
+
+// withStdin replaces os.Stdin with a pipe that yields input and then EOF.
+// The original os.Stdin is restored when the test finishes.
+func withStdin(t *testing.T, input string) {
+	t.Helper()
+
+	oldStdin := os.Stdin
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	os.Stdin = r
+	t.Cleanup(func() {
+		os.Stdin = oldStdin
+		_ = r.Close()
+	})
+
+	go func() {
+		defer func() { _ = w.Close() }()
+		_, _ = w.Write([]byte(input))
+	}()
+}
+
+// captureStdout returns everything written to os.Stdout while fn runs.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	oldStdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	os.Stdout = w
+	defer func() { os.Stdout = oldStdout }()
+
+	// パイプのバッファが溢れてブロックしないよう並行して読み取る
+	done := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(r)
+		done <- buf.String()
+	}()
+
+	fn()
+	_ = w.Close()
+	return <-done
+}
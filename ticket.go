@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+)
+
+// defaultTicketPattern matches Jira/Linear style ticket IDs (PROJ-123). It is used when ticket_referenced has no value.
+const defaultTicketPattern = `\b[A-Z][A-Z0-9]+-[0-9]+\b`
+
+// ticketReferenced evaluates ticket_referenced: whether the current git branch, the prompt of the event or
+// a user prompt of the session in the transcript contains a ticket ID matching the pattern of condition.
+// The first ID found is stored in the event JSON as ticket, so that the hook's actions (and later hooks)
+// can use {.ticket}.
+func ticketReferenced(condition Condition, baseInput *BaseInput) (bool, error) {
+	if condition.Value == "" {
+		condition.Value = defaultTicketPattern
+	}
+	pattern := condition.Value
+	if condition.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, fmt.Errorf("invalid regex pattern: %w", err)
+	}
+
+	ticket, err := findTicketReference(re, baseInput)
+	if err != nil || ticket == "" {
+		return false, err
+	}
+	if m, ok := baseInput.RawJSON.(map[string]any); ok {
+		m["ticket"] = ticket
+	}
+	return true, nil
+}
+
+// findTicketReference returns the first match of re in the branch name, the prompt of the event
+// or the user prompts of the session (oldest first). Empty when there is none.
+func findTicketReference(re *regexp.Regexp, baseInput *BaseInput) (string, error) {
+	if ticket := re.FindString(currentGitBranch(baseInput.Cwd)); ticket != "" {
+		return ticket, nil
+	}
+	if m, ok := baseInput.RawJSON.(map[string]any); ok {
+		if prompt, _ := m["prompt"].(string); prompt != "" {
+			if ticket := re.FindString(prompt); ticket != "" {
+				return ticket, nil
+			}
+		}
+	}
+
+	file, err := os.Open(baseInput.TranscriptPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to open transcript: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var ticket string
+	err = forEachTranscriptEntry(file, func(entry transcriptEntry) {
+		if ticket != "" || entry.Type != "user" || entry.SessionID != baseInput.SessionID || entry.Message == nil {
+			return
+		}
+		for _, text := range entry.Message.texts() {
+			if ticket = re.FindString(text); ticket != "" {
+				return
+			}
+		}
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to read transcript: %w", err)
+	}
+	return ticket, nil
+}
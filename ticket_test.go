@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTicketReferenced(t *testing.T) {
+	repo := t.TempDir()
+	if err := runCommand("cd "+repo+" && git init -q -b feature/PROJ-42-login", false, nil); err != nil {
+		t.Skipf("git is not available: %v", err)
+	}
+	transcript := filepath.Join(t.TempDir(), "transcript.jsonl")
+	lines := []string{
+		`{"type":"user","sessionId":"s1","message":{"role":"user","content":"Fix the login bug"}}`,
+		`{"type":"assistant","sessionId":"s1","message":{"content":[{"type":"text","text":"See OPS-7"}]}}`,
+		`{"type":"user","sessionId":"other","message":{"role":"user","content":"OPS-1"}}`,
+		`{"type":"user","sessionId":"s1","message":{"role":"user","content":[{"type":"text","text":"This is for ABC-123 and #456"}]}}`,
+	}
+	if err := os.WriteFile(transcript, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		condition  Condition
+		cwd        string
+		prompt     string
+		transcript string
+		want       bool
+		wantTicket string
+	}{
+		{name: "branch name", condition: Condition{Type: ConditionTicketReferenced}, cwd: repo, transcript: transcript, want: true, wantTicket: "PROJ-42"},
+		{name: "session prompt", condition: Condition{Type: ConditionTicketReferenced}, transcript: transcript, want: true, wantTicket: "ABC-123"},
+		{name: "current prompt", condition: Condition{Type: ConditionTicketReferenced}, prompt: "Implement XY-9 next", transcript: transcript, want: true, wantTicket: "XY-9"},
+		{name: "custom pattern", condition: Condition{Type: ConditionTicketReferenced, Value: `#[0-9]+`}, cwd: repo, transcript: transcript, want: true, wantTicket: "#456"},
+		{name: "assistant messages don't count", condition: Condition{Type: ConditionTicketReferenced, Value: `OPS-[0-9]+`}, transcript: transcript, want: false},
+		{name: "ignore_case", condition: Condition{Type: ConditionTicketReferenced, Value: `login`, IgnoreCase: true}, prompt: "LOGIN page", want: true, wantTicket: "LOGIN"},
+		{name: "no transcript", condition: Condition{Type: ConditionTicketReferenced}, transcript: filepath.Join(t.TempDir(), "missing.jsonl"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rawJSON := map[string]any{}
+			if tt.prompt != "" {
+				rawJSON["prompt"] = tt.prompt
+			}
+			baseInput := &BaseInput{SessionID: "s1", Cwd: tt.cwd, TranscriptPath: tt.transcript, RawJSON: rawJSON}
+			got, err := checkCommonCondition(tt.condition, baseInput)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("ticket_referenced = %v, want %v", got, tt.want)
+			}
+			if ticket, _ := rawJSON["ticket"].(string); ticket != tt.wantTicket {
+				t.Errorf("ticket = %q, want %q", ticket, tt.wantTicket)
+			}
+		})
+	}
+
+	if _, err := checkCommonCondition(Condition{Type: ConditionTicketReferenced, Value: "("}, &BaseInput{}); err == nil {
+		t.Error("invalid pattern: want error")
+	}
+}
+
+func TestExecuteStopHooks_TicketReminder(t *testing.T) {
+	config := &Config{Stop: []StopHook{
+		{
+			Conditions: []Condition{{Type: ConditionNot, Conditions: []Condition{{Type: ConditionTicketReferenced}}}},
+			Actions:    []Action{{Type: "output", Decision: stringPtr("block"), Reason: stringPtr("Link this work to a ticket (e.g. PROJ-123) before stopping")}},
+		},
+	}}
+
+	input := &StopInput{BaseInput: BaseInput{SessionID: "s1", TranscriptPath: filepath.Join(t.TempDir(), "missing.jsonl")}}
+	input.RawJSON = map[string]any{}
+	output, err := executeStopHooks(config, input, input.RawJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Decision != "block" || output.Reason != "Link this work to a ticket (e.g. PROJ-123) before stopping" {
+		t.Errorf("output = %+v, want block", output)
+	}
+
+	input.RawJSON = map[string]any{"prompt": "PROJ-1"}
+	output, err = executeStopHooks(config, input, input.RawJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Decision != "" {
+		t.Errorf("output = %+v, want no decision", output)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// currentTime returns the time time_between and day_of_week are evaluated at.
+// It is a variable so that tests can fix the clock.
+var currentTime = time.Now
+
+// weekdayNames maps the accepted day names (lower case) to weekdays.
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// conditionLocation returns the time zone of a condition's `tz` (the local time zone if empty).
+func conditionLocation(tz string) (*time.Location, error) {
+	if tz == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tz %q: %w", tz, err)
+	}
+	return loc, nil
+}
+
+// parseTimeRange parses a time_between value "HH:MM-HH:MM" into minutes since midnight.
+// The end is exclusive, and a range whose end is before its start spans midnight (e.g. "22:00-06:00").
+func parseTimeRange(value string) (start, end int, err error) {
+	from, to, ok := strings.Cut(value, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid value %q: expected \"HH:MM-HH:MM\"", value)
+	}
+	if start, err = parseClock(from); err == nil {
+		end, err = parseClock(to)
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid value %q: %w", value, err)
+	}
+	if start == end {
+		return 0, 0, fmt.Errorf("invalid value %q: the range is empty", value)
+	}
+	return start, end, nil
+}
+
+// parseClock parses "HH:MM" (24-hour clock, "24:00" for the end of the day) into minutes since midnight.
+func parseClock(s string) (int, error) {
+	if strings.TrimSpace(s) == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (use HH:MM)", strings.TrimSpace(s))
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// timeBetween reports whether the current time in tz is within the time_between range value.
+func timeBetween(value, tz string) (bool, error) {
+	start, end, err := parseTimeRange(value)
+	if err != nil {
+		return false, err
+	}
+	loc, err := conditionLocation(tz)
+	if err != nil {
+		return false, err
+	}
+	now := currentTime().In(loc)
+	minute := now.Hour()*60 + now.Minute()
+	if start < end {
+		return start <= minute && minute < end, nil
+	}
+	return minute >= start || minute < end, nil
+}
+
+// parseDaysOfWeek parses a day_of_week value: comma separated day names ("Mon", "monday")
+// or ranges ("Mon-Fri", "Fri-Mon" wraps around the weekend).
+func parseDaysOfWeek(value string) ([7]bool, error) {
+	var days [7]bool
+	for _, item := range strings.Split(value, ",") {
+		from, to, isRange := strings.Cut(strings.TrimSpace(item), "-")
+		first, ok := weekdayNames[strings.ToLower(strings.TrimSpace(from))]
+		last := first
+		if ok && isRange {
+			last, ok = weekdayNames[strings.ToLower(strings.TrimSpace(to))]
+		}
+		if !ok {
+			return days, fmt.Errorf("invalid day of week %q in %q (use Mon, Tue, ... or ranges like Mon-Fri)", strings.TrimSpace(item), value)
+		}
+		for d := first; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// isDayOfWeek reports whether today in tz is one of the days of the day_of_week value.
+func isDayOfWeek(value, tz string) (bool, error) {
+	days, err := parseDaysOfWeek(value)
+	if err != nil {
+		return false, err
+	}
+	loc, err := conditionLocation(tz)
+	if err != nil {
+		return false, err
+	}
+	return days[currentTime().In(loc).Weekday()], nil
+}
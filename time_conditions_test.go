@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// withCurrentTime fixes the clock of time_between and day_of_week.
+func withCurrentTime(t *testing.T, now time.Time) {
+	t.Helper()
+	original := currentTime
+	currentTime = func() time.Time { return now }
+	t.Cleanup(func() { currentTime = original })
+}
+
+func TestTimeBetween(t *testing.T) {
+	// 2025-01-10 (金) 08:30 UTC = 17:30 JST
+	withCurrentTime(t, time.Date(2025, 1, 10, 8, 30, 0, 0, time.UTC))
+
+	tests := []struct {
+		value   string
+		tz      string
+		want    bool
+		wantErr bool
+	}{
+		{"08:00-18:00", "UTC", true, false},
+		{"08:30-09:00", "UTC", true, false},
+		{"07:00-08:30", "UTC", false, false}, // 終了時刻は含まない
+		{"09:00-18:00", "Asia/Tokyo", true, false},
+		{"09:00-17:30", "Asia/Tokyo", false, false},
+		{"22:00-09:00", "UTC", true, false}, // 日付をまたぐ範囲
+		{"22:00-06:00", "UTC", false, false},
+		{"8:00 - 18:00", "UTC", true, false},
+		{"17:00-24:00", "Asia/Tokyo", true, false},
+		{"09:00", "UTC", false, true},
+		{"25:00-26:00", "UTC", false, true},
+		{"09:00-09:00", "UTC", false, true},
+		{"08:00-18:00", "Mars/Olympus", false, true},
+	}
+	for _, tt := range tests {
+		got, err := timeBetween(tt.value, tt.tz)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("timeBetween(%q, %q) error = %v, wantErr %v", tt.value, tt.tz, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("timeBetween(%q, %q) = %v, want %v", tt.value, tt.tz, got, tt.want)
+		}
+	}
+}
+
+func TestIsDayOfWeek(t *testing.T) {
+	// 2025-01-10 (金) 20:00 UTC = 2025-01-11 (土) 05:00 JST
+	withCurrentTime(t, time.Date(2025, 1, 10, 20, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		value   string
+		tz      string
+		want    bool
+		wantErr bool
+	}{
+		{"Fri", "UTC", true, false},
+		{"friday", "UTC", true, false},
+		{"Mon-Fri", "UTC", true, false},
+		{"Mon-Fri", "Asia/Tokyo", false, false},
+		{"Sat,Sun", "Asia/Tokyo", true, false},
+		{"Fri-Mon", "Asia/Tokyo", true, false}, // 週末をまたぐ範囲
+		{"Tue-Thu", "UTC", false, false},
+		{"Mon, Wed", "UTC", false, false},
+		{"Fry", "UTC", false, true},
+		{"Mon-", "UTC", false, true},
+	}
+	for _, tt := range tests {
+		got, err := isDayOfWeek(tt.value, tt.tz)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("isDayOfWeek(%q, %q) error = %v, wantErr %v", tt.value, tt.tz, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("isDayOfWeek(%q, %q) = %v, want %v", tt.value, tt.tz, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// timeTrackingNow returns the current time for time_tracking actions. It is a variable so that tests can fix the clock.
+var timeTrackingNow = time.Now
+
+// timeEntry is a record of the time ledger written by time_tracking actions, one JSON line per start and stop.
+type timeEntry struct {
+	Event           string     `json:"event"` // "start" or "stop"
+	SessionID       string     `json:"session_id"`
+	Project         string     `json:"project"`
+	Branch          string     `json:"branch,omitempty"`
+	Tags            []string   `json:"tags,omitempty"`
+	Start           time.Time  `json:"start"`
+	End             *time.Time `json:"end,omitempty"`              // stopのみ
+	DurationSeconds int64      `json:"duration_seconds,omitempty"` // stopのみ
+}
+
+// timeLedgerPath returns the ledger file of a time_tracking action ("~/" is expanded to the home directory).
+// The default is $XDG_DATA_HOME/cchook/time.jsonl (~/.local/share/cchook/time.jsonl).
+func timeLedgerPath(action Action) string {
+	home, _ := os.UserHomeDir()
+	if action.Ledger != "" {
+		if rest, ok := strings.CutPrefix(action.Ledger, "~/"); ok && home != "" {
+			return filepath.Join(home, rest)
+		}
+		return action.Ledger
+	}
+	dataDir := os.Getenv("XDG_DATA_HOME")
+	if dataDir == "" {
+		dataDir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataDir, "cchook", "time.jsonl")
+}
+
+// timeTrackingProject returns the project of a new timer: the templated project of the action,
+// or the name of the git repository (or directory) containing cwd.
+func timeTrackingProject(action Action, cwd string, rawJSON any) string {
+	if action.Project != "" {
+		return unifiedTemplateReplace(action.Project, rawJSON)
+	}
+	if repo, err := openGitRepository(cwd); err == nil {
+		if wt, err := repo.Worktree(); err == nil {
+			return filepath.Base(wt.Filesystem.Root())
+		}
+	}
+	return filepath.Base(cwd)
+}
+
+// openTimeEntry returns the start of the session's running timer in the ledger (nil when none is running).
+func openTimeEntry(path, sessionID string) (*timeEntry, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read time ledger: %w", err)
+	}
+	var open *timeEntry
+	for _, line := range strings.Split(string(data), "\n") {
+		var entry timeEntry
+		if json.Unmarshal([]byte(line), &entry) != nil || entry.SessionID != sessionID {
+			continue
+		}
+		switch entry.Event {
+		case "start":
+			open = &entry
+		case "stop":
+			open = nil
+		}
+	}
+	return open, nil
+}
+
+// appendTimeEntry appends entry to the ledger.
+func appendTimeEntry(path string, entry timeEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create time ledger directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open time ledger: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write time ledger: %w", err)
+	}
+	return nil
+}
+
+// trackTime runs a time_tracking action: SessionStart starts a timer for the session tagged with the project and branch
+// (a running timer keeps running, e.g. on compact), and SessionEnd stops it and records the duration.
+// The entry is stored in the event JSON as time_entry so that later actions (e.g. an http action posting it
+// to Toggl or Clockify) can use {.time_entry.project}, {.time_entry.start}, {.time_entry.duration_seconds}, etc.
+// Without record the entry is only stored in the event JSON, not in the ledger.
+// Returns nil when SessionEnd finds no running timer.
+func trackTime(action Action, eventType HookEventType, baseInput *BaseInput, rawJSON any, record bool) (*timeEntry, error) {
+	if baseInput.SessionID == "" {
+		return nil, errors.New("session_id is empty")
+	}
+	path := timeLedgerPath(action)
+	open, err := openTimeEntry(path, baseInput.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := open
+	switch {
+	case eventType == SessionStart && open == nil:
+		entry = &timeEntry{
+			Event:     "start",
+			SessionID: baseInput.SessionID,
+			Project:   timeTrackingProject(action, baseInput.Cwd, rawJSON),
+			Branch:    currentGitBranch(baseInput.Cwd),
+			Start:     timeTrackingNow().UTC().Truncate(time.Second),
+		}
+		for _, tag := range action.Tags {
+			if tag = unifiedTemplateReplace(tag, rawJSON); tag != "" {
+				entry.Tags = append(entry.Tags, tag)
+			}
+		}
+		if record {
+			if err := appendTimeEntry(path, *entry); err != nil {
+				return nil, err
+			}
+		}
+	case eventType == SessionEnd && open != nil:
+		end := timeTrackingNow().UTC().Truncate(time.Second)
+		entry.Event = "stop"
+		entry.End = &end
+		entry.DurationSeconds = int64(end.Sub(entry.Start).Seconds())
+		if record {
+			if err := appendTimeEntry(path, *entry); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	if m, ok := rawJSON.(map[string]any); ok {
+		// テンプレート (gojq) が扱える型 ([]any, int) で格納する
+		tags := make([]any, len(entry.Tags))
+		for i, tag := range entry.Tags {
+			tags[i] = tag
+		}
+		value := map[string]any{
+			"project": entry.Project,
+			"branch":  entry.Branch,
+			"tags":    tags,
+			"start":   entry.Start.Format(time.RFC3339),
+		}
+		if entry.End != nil {
+			value["end"] = entry.End.Format(time.RFC3339)
+			value["duration_seconds"] = int(entry.DurationSeconds)
+			value["duration"] = (time.Duration(entry.DurationSeconds) * time.Second).String()
+		}
+		m["time_entry"] = value
+	}
+	return entry, nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrackTime(t *testing.T) {
+	repo := filepath.Join(t.TempDir(), "webapp")
+	if err := os.Mkdir(repo, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := runCommand("cd "+repo+" && git init -q -b feature/login", false, nil); err != nil {
+		t.Skipf("git is not available: %v", err)
+	}
+	ledger := filepath.Join(t.TempDir(), "time", "ledger.jsonl")
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	timeTrackingNow = func() time.Time { return now }
+	t.Cleanup(func() { timeTrackingNow = time.Now })
+
+	action := Action{Type: "time_tracking", Ledger: ledger, Tags: []string{"claude", "{.source}", ""}}
+	baseInput := &BaseInput{SessionID: "s1", Cwd: repo}
+	rawJSON := map[string]any{"source": "startup"}
+	entry, err := trackTime(action, SessionStart, baseInput, rawJSON, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &timeEntry{Event: "start", SessionID: "s1", Project: "webapp", Branch: "feature/login", Tags: []string{"claude", "startup"}, Start: now}
+	if !reflect.DeepEqual(entry, want) {
+		t.Errorf("start = %+v, want %+v", entry, want)
+	}
+
+	// compactなどで再度SessionStartが来ても計測は続く
+	now = now.Add(30 * time.Minute)
+	if _, err := trackTime(action, SessionStart, baseInput, map[string]any{"source": "compact"}, true); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(60*time.Minute + 15*time.Second)
+	rawJSON = map[string]any{}
+	entry, err = trackTime(action, SessionEnd, baseInput, rawJSON, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil || entry.Event != "stop" || entry.DurationSeconds != 5415 || !entry.End.Equal(now) || entry.Project != "webapp" {
+		t.Errorf("stop = %+v", entry)
+	}
+	wantJSON := map[string]any{
+		"project": "webapp", "branch": "feature/login", "tags": []any{"claude", "startup"},
+		"start": "2025-01-06T09:00:00Z", "end": "2025-01-06T10:30:15Z", "duration_seconds": 5415, "duration": "1h30m15s",
+	}
+	if !reflect.DeepEqual(rawJSON["time_entry"], wantJSON) {
+		t.Errorf("time_entry = %v, want %v", rawJSON["time_entry"], wantJSON)
+	}
+	if got := unifiedTemplateReplace("{.time_entry.project}: {.time_entry.duration_seconds}s", rawJSON); got != "webapp: 5415s" {
+		t.Errorf("template = %q", got)
+	}
+
+	// 計測中のタイマーが無ければ何もしない
+	entry, err = trackTime(action, SessionEnd, baseInput, map[string]any{}, true)
+	if err != nil || entry != nil {
+		t.Errorf("second stop = %+v, %v, want nil", entry, err)
+	}
+	data, err := os.ReadFile(ledger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 2 {
+		t.Errorf("ledger has %d lines, want start and stop: %q", len(lines), lines)
+	}
+
+	if _, err := trackTime(action, SessionEnd, &BaseInput{}, map[string]any{}, true); err == nil {
+		t.Error("empty session_id: want error")
+	}
+}
+
+func TestTrackTime_WithoutRecord(t *testing.T) {
+	ledger := filepath.Join(t.TempDir(), "ledger.jsonl")
+	action := Action{Type: "time_tracking", Ledger: ledger, Project: "webapp"}
+	rawJSON := map[string]any{}
+	entry, err := trackTime(action, SessionStart, &BaseInput{SessionID: "s1", Cwd: t.TempDir()}, rawJSON, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil || entry.Event != "start" || rawJSON["time_entry"] == nil {
+		t.Errorf("start = %+v, time_entry = %v, want the entry in the event JSON", entry, rawJSON["time_entry"])
+	}
+	if _, err := os.Stat(ledger); !os.IsNotExist(err) {
+		t.Errorf("ledger was written without record: %v", err)
+	}
+}
+
+func TestTimeLedgerPath(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", "/data")
+	if got := timeLedgerPath(Action{}); got != "/data/cchook/time.jsonl" {
+		t.Errorf("default = %q", got)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip(err)
+	}
+	if got := timeLedgerPath(Action{Ledger: "~/time.jsonl"}); got != filepath.Join(home, "time.jsonl") {
+		t.Errorf("~/ = %q", got)
+	}
+}
+
+func TestExecuteSessionEndHooksJSON_TimeTracking(t *testing.T) {
+	ledger := filepath.Join(t.TempDir(), "time.jsonl")
+	now := time.Date(2025, 1, 6, 18, 0, 0, 0, time.UTC)
+	timeTrackingNow = func() time.Time { return now }
+	t.Cleanup(func() { timeTrackingNow = time.Now })
+	start := timeEntry{Event: "start", SessionID: "s1", Project: "api", Start: now.Add(-2 * time.Hour)}
+	if err := appendTimeEntry(ledger, start); err != nil {
+		t.Fatal(err)
+	}
+	config := &Config{SessionEnd: []SessionEndHook{
+		{Actions: []Action{
+			{Type: "time_tracking", Ledger: ledger},
+			{Type: "output", Message: "Tracked {.time_entry.duration} on {.time_entry.project}"},
+		}},
+	}}
+	input := &SessionEndInput{BaseInput: BaseInput{SessionID: "s1"}}
+	output, err := executeSessionEndHooksJSON(config, input, map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.SystemMessage != "Tracked 2h0m0s on api" {
+		t.Errorf("systemMessage = %q", output.SystemMessage)
+	}
+}
@@ -0,0 +1,534 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// TOMLの値のうち、文字列・配列・インラインテーブル以外（数値、日時）の書式
+var (
+	tomlIntegerPattern   = regexp.MustCompile(`^(?:[+-]?(?:0|[1-9](?:_?[0-9])*)|0x[0-9A-Fa-f](?:_?[0-9A-Fa-f])*|0o[0-7](?:_?[0-7])*|0b[01](?:_?[01])*)$`)
+	tomlFloatPattern     = regexp.MustCompile(`^(?:[+-]?(?:0|[1-9](?:_?[0-9])*)(?:\.[0-9](?:_?[0-9])*(?:[eE][+-]?[0-9](?:_?[0-9])*)?|[eE][+-]?[0-9](?:_?[0-9])*)|[+-]?(?:inf|nan))$`)
+	tomlDateTimePattern  = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})(?:[Tt ](\d{2}:\d{2}:\d{2})(?:\.\d+)?(?:[Zz]|[+-]\d{2}:\d{2})?)?$`)
+	tomlLocalTimePattern = regexp.MustCompile(`^(\d{2}:\d{2}:\d{2})(?:\.\d+)?$`)
+)
+
+// checkTOMLSyntax reports the first syntax error of a TOML 1.0 document, including duplicate keys and tables.
+// It only validates; values are not decoded.
+func checkTOMLSyntax(src string) error {
+	p := &tomlParser{src: src, keys: map[string]bool{}, tables: map[string]bool{}, arrayTables: map[string]bool{}}
+	return p.parse()
+}
+
+// tomlParser is a recursive descent validator of TOML documents.
+type tomlParser struct {
+	src         string
+	pos         int
+	table       string          // 現在のテーブルのキー (要素を\x00で連結)
+	keys        map[string]bool // 値を定義したキー
+	tables      map[string]bool // [table]で定義したテーブル
+	arrayTables map[string]bool // [[table]]で定義したテーブル配列
+}
+
+func (p *tomlParser) parse() error {
+	for {
+		p.skipWhitespace()
+		if p.eof() {
+			return nil
+		}
+		switch c := p.src[p.pos]; {
+		case c == '#' || c == '\n' || c == '\r':
+		case c == '[':
+			if err := p.parseTableHeader(); err != nil {
+				return err
+			}
+		default:
+			if err := p.parseKeyValue(); err != nil {
+				return err
+			}
+		}
+		p.skipWhitespace()
+		if err := p.skipComment(); err != nil {
+			return err
+		}
+		if p.eof() {
+			return nil
+		}
+		if !p.consumeNewline() {
+			return p.errorf("expected a newline, found %s", p.describe())
+		}
+	}
+}
+
+// parseTableHeader parses [table] and [[array.of.tables]].
+func (p *tomlParser) parseTableHeader() error {
+	start := p.pos
+	array := strings.HasPrefix(p.src[p.pos:], "[[")
+	if array {
+		p.pos += 2
+	} else {
+		p.pos++
+	}
+	p.skipWhitespace()
+	key, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	p.skipWhitespace()
+	closing := "]"
+	if array {
+		closing = "]]"
+	}
+	if !strings.HasPrefix(p.src[p.pos:], closing) {
+		return p.errorf("expected %q, found %s", closing, p.describe())
+	}
+	p.pos += len(closing)
+
+	name := strings.Join(key, "\x00")
+	display := strings.Join(key, ".")
+	switch {
+	case p.keys[name]:
+		return p.errorAt(start, "key %q is already defined as a value", display)
+	case array && p.tables[name]:
+		return p.errorAt(start, "table [%s] is already defined as a table", display)
+	case !array && p.arrayTables[name]:
+		return p.errorAt(start, "table [%s] is already defined as an array of tables", display)
+	case !array && p.tables[name]:
+		return p.errorAt(start, "table [%s] is defined twice", display)
+	}
+	if array {
+		// 新しい要素なので、前の要素のキーとテーブルは定義し直せる
+		p.arrayTables[name] = true
+		p.forget(name + "\x00")
+	} else {
+		p.tables[name] = true
+	}
+	p.table = name
+	return nil
+}
+
+// forget removes the keys and tables under prefix.
+func (p *tomlParser) forget(prefix string) {
+	for _, m := range []map[string]bool{p.keys, p.tables, p.arrayTables} {
+		for name := range m {
+			if strings.HasPrefix(name, prefix) {
+				delete(m, name)
+			}
+		}
+	}
+}
+
+// parseKeyValue parses `key = value` in the current table.
+func (p *tomlParser) parseKeyValue() error {
+	start := p.pos
+	key, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	p.skipWhitespace()
+	if p.eof() || p.src[p.pos] != '=' {
+		return p.errorf("expected \"=\" after key, found %s", p.describe())
+	}
+	p.pos++
+	p.skipWhitespace()
+	if err := p.parseValue(); err != nil {
+		return err
+	}
+
+	name := strings.Join(key, "\x00")
+	if p.table != "" {
+		name = p.table + "\x00" + name
+	}
+	if p.keys[name] || p.tables[name] || p.arrayTables[name] {
+		return p.errorAt(start, "duplicate key %q", strings.Join(key, "."))
+	}
+	p.keys[name] = true
+	return nil
+}
+
+// parseKey parses a dotted key and returns its parts.
+func (p *tomlParser) parseKey() ([]string, error) {
+	var parts []string
+	for {
+		part, err := p.parseSimpleKey()
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, part)
+		save := p.pos
+		p.skipWhitespace()
+		if p.eof() || p.src[p.pos] != '.' {
+			p.pos = save
+			return parts, nil
+		}
+		p.pos++
+		p.skipWhitespace()
+	}
+}
+
+func (p *tomlParser) parseSimpleKey() (string, error) {
+	if p.eof() {
+		return "", p.errorf("expected a key, found end of file")
+	}
+	start := p.pos
+	switch p.src[p.pos] {
+	case '"':
+		if err := p.parseBasicString(); err != nil {
+			return "", err
+		}
+		return p.src[start:p.pos], nil
+	case '\'':
+		if err := p.parseLiteralString(); err != nil {
+			return "", err
+		}
+		return p.src[start:p.pos], nil
+	}
+	for !p.eof() && isTOMLBareKeyChar(p.src[p.pos]) {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.errorf("expected a key, found %s", p.describe())
+	}
+	return p.src[start:p.pos], nil
+}
+
+func isTOMLBareKeyChar(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *tomlParser) parseValue() error {
+	if p.eof() {
+		return p.errorf("expected a value, found end of file")
+	}
+	rest := p.src[p.pos:]
+	switch {
+	case strings.HasPrefix(rest, `"""`):
+		return p.parseMultilineString('"')
+	case strings.HasPrefix(rest, `'''`):
+		return p.parseMultilineString('\'')
+	case rest[0] == '"':
+		return p.parseBasicString()
+	case rest[0] == '\'':
+		return p.parseLiteralString()
+	case rest[0] == '[':
+		return p.parseArray()
+	case rest[0] == '{':
+		return p.parseInlineTable()
+	}
+	return p.parseScalar()
+}
+
+// parseScalar parses booleans, numbers and dates.
+func (p *tomlParser) parseScalar() error {
+	start := p.pos
+	for !p.eof() && strings.IndexByte("+-_.:0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+	// 日付と時刻の間は空白でもよい (1979-05-27 07:32:00)
+	if p.pos-start == 10 && tomlDateTimePattern.MatchString(p.src[start:p.pos]) &&
+		len(p.src) > p.pos+3 && p.src[p.pos] == ' ' && isDigit(p.src[p.pos+1]) && isDigit(p.src[p.pos+2]) && p.src[p.pos+3] == ':' {
+		p.pos++
+		for !p.eof() && strings.IndexByte("+-.:0123456789Zz", p.src[p.pos]) >= 0 {
+			p.pos++
+		}
+	}
+	token := p.src[start:p.pos]
+	switch {
+	case token == "":
+		return p.errorf("expected a value, found %s", p.describe())
+	case token == "true" || token == "false":
+		return nil
+	case tomlIntegerPattern.MatchString(token) || tomlFloatPattern.MatchString(token):
+		return nil
+	}
+	if m := tomlDateTimePattern.FindStringSubmatch(token); m != nil {
+		if _, err := time.Parse("2006-01-02", m[1]); err != nil {
+			return p.errorAt(start, "invalid date %q", m[1])
+		}
+		if m[2] != "" {
+			if _, err := time.Parse("15:04:05", m[2]); err != nil {
+				return p.errorAt(start, "invalid time %q", m[2])
+			}
+		}
+		return nil
+	}
+	if m := tomlLocalTimePattern.FindStringSubmatch(token); m != nil {
+		if _, err := time.Parse("15:04:05", m[1]); err != nil {
+			return p.errorAt(start, "invalid time %q", m[1])
+		}
+		return nil
+	}
+	return p.errorAt(start, "invalid value %q", token)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func (p *tomlParser) parseBasicString() error {
+	start := p.pos
+	p.pos++
+	for {
+		if p.eof() || p.src[p.pos] == '\n' || p.src[p.pos] == '\r' {
+			return p.errorAt(start, "unterminated string")
+		}
+		switch c := p.src[p.pos]; {
+		case c == '"':
+			p.pos++
+			return nil
+		case c == '\\':
+			if err := p.parseEscape(); err != nil {
+				return err
+			}
+		case isTOMLControlChar(c):
+			return p.errorf("control character %U in string", rune(c))
+		default:
+			p.pos++
+		}
+	}
+}
+
+func (p *tomlParser) parseLiteralString() error {
+	start := p.pos
+	p.pos++
+	for {
+		if p.eof() || p.src[p.pos] == '\n' || p.src[p.pos] == '\r' {
+			return p.errorAt(start, "unterminated string")
+		}
+		switch c := p.src[p.pos]; {
+		case c == '\'':
+			p.pos++
+			return nil
+		case isTOMLControlChar(c):
+			return p.errorf("control character %U in string", rune(c))
+		default:
+			p.pos++
+		}
+	}
+}
+
+// parseMultilineString parses a multi-line basic or literal string, delimited by three quote characters.
+func (p *tomlParser) parseMultilineString(quote byte) error {
+	start := p.pos
+	delimiter := strings.Repeat(string(quote), 3)
+	p.pos += 3
+	for {
+		if p.eof() {
+			return p.errorAt(start, "unterminated multi-line string")
+		}
+		if strings.HasPrefix(p.src[p.pos:], delimiter) {
+			p.pos += 3
+			// 閉じる直前に1〜2個の引用符を含められる
+			for i := 0; i < 2 && !p.eof() && p.src[p.pos] == quote; i++ {
+				p.pos++
+			}
+			return nil
+		}
+		switch c := p.src[p.pos]; {
+		case c == '\\' && quote == '"':
+			if err := p.parseEscape(); err != nil {
+				return err
+			}
+		case c == '\n' || c == '\r':
+			p.pos++
+		case isTOMLControlChar(c):
+			return p.errorf("control character %U in string", rune(c))
+		default:
+			p.pos++
+		}
+	}
+}
+
+// parseEscape parses an escape sequence of a basic string, including a line ending backslash in a multi-line one.
+func (p *tomlParser) parseEscape() error {
+	start := p.pos
+	p.pos++
+	if p.eof() {
+		return p.errorAt(start, "unterminated escape sequence")
+	}
+	switch c := p.src[p.pos]; c {
+	case 'b', 't', 'n', 'f', 'r', '"', '\\':
+		p.pos++
+		return nil
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		p.pos++
+		for i := 0; i < n; i++ {
+			if p.eof() || strings.IndexByte("0123456789abcdefABCDEF", p.src[p.pos]) < 0 {
+				return p.errorAt(start, "invalid unicode escape (\\%c needs %d hex digits)", c, n)
+			}
+			p.pos++
+		}
+		return nil
+	case ' ', '\t', '\n', '\r':
+		// 行末のバックスラッシュ (複数行文字列のみ、改行と続く空白を取り除く)
+		save := p.pos
+		p.skipWhitespace()
+		if p.consumeNewline() {
+			return nil
+		}
+		p.pos = save
+	}
+	return p.errorAt(start, "invalid escape sequence \\%c", p.src[p.pos])
+}
+
+func isTOMLControlChar(c byte) bool {
+	return c < 0x20 && c != '\t' || c == 0x7f
+}
+
+func (p *tomlParser) parseArray() error {
+	start := p.pos
+	p.pos++
+	for {
+		if err := p.skipBlank(); err != nil {
+			return err
+		}
+		if p.eof() {
+			return p.errorAt(start, "unterminated array")
+		}
+		if p.src[p.pos] == ']' {
+			p.pos++
+			return nil
+		}
+		if err := p.parseValue(); err != nil {
+			return err
+		}
+		if err := p.skipBlank(); err != nil {
+			return err
+		}
+		switch {
+		case p.eof():
+			return p.errorAt(start, "unterminated array")
+		case p.src[p.pos] == ',':
+			p.pos++
+		case p.src[p.pos] == ']':
+			p.pos++
+			return nil
+		default:
+			return p.errorf("expected \",\" or \"]\" in array, found %s", p.describe())
+		}
+	}
+}
+
+func (p *tomlParser) parseInlineTable() error {
+	start := p.pos
+	p.pos++
+	keys := map[string]bool{}
+	p.skipWhitespace()
+	if !p.eof() && p.src[p.pos] == '}' {
+		p.pos++
+		return nil
+	}
+	for {
+		p.skipWhitespace()
+		if p.eof() || p.src[p.pos] == '\n' || p.src[p.pos] == '\r' {
+			return p.errorAt(start, "unterminated inline table (inline tables must be on a single line)")
+		}
+		keyStart := p.pos
+		key, err := p.parseKey()
+		if err != nil {
+			return err
+		}
+		p.skipWhitespace()
+		if p.eof() || p.src[p.pos] != '=' {
+			return p.errorf("expected \"=\" after key, found %s", p.describe())
+		}
+		p.pos++
+		p.skipWhitespace()
+		if err := p.parseValue(); err != nil {
+			return err
+		}
+		name := strings.Join(key, "\x00")
+		if keys[name] {
+			return p.errorAt(keyStart, "duplicate key %q", strings.Join(key, "."))
+		}
+		keys[name] = true
+
+		p.skipWhitespace()
+		switch {
+		case p.eof() || p.src[p.pos] == '\n' || p.src[p.pos] == '\r':
+			return p.errorAt(start, "unterminated inline table (inline tables must be on a single line)")
+		case p.src[p.pos] == ',':
+			p.pos++
+		case p.src[p.pos] == '}':
+			p.pos++
+			return nil
+		default:
+			return p.errorf("expected \",\" or \"}\" in inline table, found %s", p.describe())
+		}
+	}
+}
+
+// skipWhitespace skips spaces and tabs.
+func (p *tomlParser) skipWhitespace() {
+	for !p.eof() && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// skipComment skips a comment up to (not including) the end of the line.
+func (p *tomlParser) skipComment() error {
+	if p.eof() || p.src[p.pos] != '#' {
+		return nil
+	}
+	for !p.eof() && p.src[p.pos] != '\n' && !strings.HasPrefix(p.src[p.pos:], "\r\n") {
+		if c := p.src[p.pos]; isTOMLControlChar(c) {
+			return p.errorf("control character %U in comment", rune(c))
+		}
+		p.pos++
+	}
+	return nil
+}
+
+// skipBlank skips whitespace, comments and newlines (inside arrays).
+func (p *tomlParser) skipBlank() error {
+	for {
+		p.skipWhitespace()
+		if err := p.skipComment(); err != nil {
+			return err
+		}
+		if !p.consumeNewline() {
+			return nil
+		}
+	}
+}
+
+func (p *tomlParser) consumeNewline() bool {
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], "\n"):
+		p.pos++
+	case strings.HasPrefix(p.src[p.pos:], "\r\n"):
+		p.pos += 2
+	default:
+		return false
+	}
+	return true
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+// describe returns the character at the current position for error messages.
+func (p *tomlParser) describe() string {
+	if p.eof() {
+		return "end of file"
+	}
+	r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+	return fmt.Sprintf("%q", r)
+}
+
+func (p *tomlParser) errorf(format string, args ...any) error {
+	return p.errorAt(p.pos, format, args...)
+}
+
+func (p *tomlParser) errorAt(pos int, format string, args ...any) error {
+	line, column := lineColumn(p.src, pos)
+	return &syntaxError{Line: line, Column: column, Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// exitCodePattern matches the "Exit code N" that Claude Code puts at the start of the result of a failed Bash command.
+var exitCodePattern = regexp.MustCompile(`^Exit code (-?\d+)`)
+
+// exitCodeFields are the tool_response fields holding the exit code of a command, by preference.
+var exitCodeFields = []string{"exit_code", "exitCode", "returnCode", "code"}
+
+// decode returns the tool_response as a JSON value (nil when empty or invalid).
+func (r ToolResponse) decode() any {
+	var value any
+	if len(r) == 0 || json.Unmarshal(r, &value) != nil {
+		return nil
+	}
+	return value
+}
+
+// texts returns the strings of the tool_response: the response itself when it is a string,
+// otherwise every string value of the object (stdout, stderr, content, ...), in key order.
+func (r ToolResponse) texts() []string {
+	var texts []string
+	var walk func(value any)
+	walk = func(value any) {
+		switch v := value.(type) {
+		case string:
+			texts = append(texts, v)
+		case []any:
+			for _, item := range v {
+				walk(item)
+			}
+		case map[string]any:
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			slices.Sort(keys)
+			for _, key := range keys {
+				walk(v[key])
+			}
+		}
+	}
+	walk(r.decode())
+	return texts
+}
+
+// exitCode returns the exit code of the command of a Bash tool_response: an exit code field, or the
+// "Exit code N" of an error result. A Bash result without either succeeded (Claude Code reports failures as errors).
+// ok is false for other tools.
+func (r ToolResponse) exitCode(toolName string) (code int, ok bool) {
+	value := r.decode()
+	if m, isObject := value.(map[string]any); isObject {
+		for _, field := range exitCodeFields {
+			if n, isNumber := m[field].(float64); isNumber {
+				return int(n), true
+			}
+		}
+		for _, field := range []string{"error", "stderr"} {
+			if s, isString := m[field].(string); isString {
+				if code, ok := parseExitCode(s); ok {
+					return code, true
+				}
+			}
+		}
+	}
+	if s, isString := value.(string); isString {
+		if code, ok := parseExitCode(s); ok {
+			return code, true
+		}
+	}
+	if toolName == "Bash" && value != nil {
+		return 0, true
+	}
+	return 0, false
+}
+
+// parseExitCode parses the "Exit code N" at the start of s.
+func parseExitCode(s string) (int, bool) {
+	match := exitCodePattern.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return 0, false
+	}
+	code, err := strconv.Atoi(match[1])
+	return code, err == nil
+}
+
+// hasError reports whether the tool_response reports a failure: an error field, is_error / success: false /
+// interrupted, a non-zero exit code, or an error result ("Error: ...", "Exit code N", <tool_use_error>).
+func (r ToolResponse) hasError(toolName string) bool {
+	if code, ok := r.exitCode(toolName); ok && code != 0 {
+		return true
+	}
+	switch v := r.decode().(type) {
+	case string:
+		return strings.HasPrefix(strings.TrimSpace(v), "Error") || strings.Contains(v, "<tool_use_error>")
+	case map[string]any:
+		if errValue, ok := v["error"]; ok && errValue != nil && errValue != "" && errValue != false {
+			return true
+		}
+		for _, field := range []string{"is_error", "isError", "interrupted"} {
+			if v[field] == true {
+				return true
+			}
+		}
+		return v["success"] == false
+	}
+	return false
+}
+
+// checkToolResponseCondition checks the tool_response conditions of PostToolUse.
+// Returns ErrConditionNotHandled for other condition types.
+func checkToolResponseCondition(condition Condition, toolName string, response ToolResponse) (bool, error) {
+	switch condition.Type {
+	case ConditionToolResponseContains:
+		// tool_responseの文字列 (stdout, stderr, content, ...) のいずれかが指定文字列を含む
+		for _, text := range response.texts() {
+			value, text, err := prepareStringMatch(condition, text)
+			if err != nil {
+				return false, err
+			}
+			if strings.Contains(text, value) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case ConditionToolErrorIsPresent:
+		// ツールが失敗を報告した（Bashは0以外の終了コード）
+		return response.hasError(toolName), nil
+	case ConditionExitCodeIs:
+		// Bashの終了コードが一致する（Bash以外ではfalse）
+		want, err := strconv.Atoi(strings.TrimSpace(condition.Value))
+		if err != nil {
+			return false, fmt.Errorf("exit_code_is: invalid exit code %q", condition.Value)
+		}
+		code, ok := response.exitCode(toolName)
+		return ok && code == want, nil
+	default:
+		return false, ErrConditionNotHandled
+	}
+}
+
+// validateExitCode checks the value of an exit_code_is condition.
+func validateExitCode(value string) error {
+	if _, err := strconv.Atoi(strings.TrimSpace(value)); err != nil {
+		return errors.New("requires an exit code (e.g. \"0\" or \"1\")")
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCheckToolResponseCondition(t *testing.T) {
+	const (
+		bashSuccess = `{"stdout":"ok\n","stderr":"","interrupted":false,"isImage":false}`
+		bashFailure = `"Exit code 2\ngo: build failed: undefined: foo"`
+		bashCode    = `{"stdout":"","stderr":"FAIL","exit_code":1}`
+		writeResult = `{"type":"create","filePath":"/tmp/a.go","success":true}`
+		readError   = `"<tool_use_error>File does not exist.</tool_use_error>"`
+		mcpError    = `{"content":[{"type":"text","text":"rate limited"}],"isError":true}`
+	)
+	tests := []struct {
+		name      string
+		condition Condition
+		toolName  string
+		response  string
+		want      bool
+	}{
+		{"contains stdout", Condition{Type: ConditionToolResponseContains, Value: "ok"}, "Bash", bashSuccess, true},
+		{"contains error result", Condition{Type: ConditionToolResponseContains, Value: "UNDEFINED", IgnoreCase: true}, "Bash", bashFailure, true},
+		{"contains nested text", Condition{Type: ConditionToolResponseContains, Value: "rate limited"}, "mcp__api__call", mcpError, true},
+		{"contains no match", Condition{Type: ConditionToolResponseContains, Value: "panic"}, "Bash", bashSuccess, false},
+		{"error in successful bash", Condition{Type: ConditionToolErrorIsPresent}, "Bash", bashSuccess, false},
+		{"error in failed bash", Condition{Type: ConditionToolErrorIsPresent}, "Bash", bashFailure, true},
+		{"error with exit code field", Condition{Type: ConditionToolErrorIsPresent}, "Bash", bashCode, true},
+		{"error in successful write", Condition{Type: ConditionToolErrorIsPresent}, "Write", writeResult, false},
+		{"tool_use_error", Condition{Type: ConditionToolErrorIsPresent}, "Read", readError, true},
+		{"MCP isError", Condition{Type: ConditionToolErrorIsPresent}, "mcp__api__call", mcpError, true},
+		{"success false", Condition{Type: ConditionToolErrorIsPresent}, "Write", `{"success":false}`, true},
+		{"exit code of successful bash", Condition{Type: ConditionExitCodeIs, Value: "0"}, "Bash", bashSuccess, true},
+		{"exit code of failed bash", Condition{Type: ConditionExitCodeIs, Value: "2"}, "Bash", bashFailure, true},
+		{"exit code field", Condition{Type: ConditionExitCodeIs, Value: "1"}, "Bash", bashCode, true},
+		{"exit code mismatch", Condition{Type: ConditionExitCodeIs, Value: "1"}, "Bash", bashFailure, false},
+		{"no exit code for other tools", Condition{Type: ConditionExitCodeIs, Value: "0"}, "Write", writeResult, false},
+		{"empty response", Condition{Type: ConditionToolErrorIsPresent}, "Bash", ``, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := &PostToolUseInput{ToolName: tt.toolName, ToolResponse: ToolResponse(tt.response)}
+			got, err := checkPostToolUseCondition(tt.condition, input)
+			if err != nil {
+				t.Fatalf("checkPostToolUseCondition() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("checkPostToolUseCondition() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := checkPostToolUseCondition(Condition{Type: ConditionExitCodeIs, Value: "failure"}, &PostToolUseInput{ToolName: "Bash"}); err == nil {
+		t.Error("Expected an error for an invalid exit code")
+	}
+	if _, err := checkPreToolUseCondition(Condition{Type: ConditionToolErrorIsPresent}, &PreToolUseInput{ToolName: "Bash"}); err == nil {
+		t.Error("Expected tool_error_is_present to be unsupported for PreToolUse")
+	}
+}
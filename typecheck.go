@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// typecheckMaxDiagnostics is the number of diagnostics a typecheck action reports to Claude.
+const typecheckMaxDiagnostics = 20
+
+// typecheckTarget is the check a typecheck action runs for an edited file.
+type typecheckTarget struct {
+	Tool    string // "go" or "tsc"
+	Dir     string // チェックを実行するディレクトリ (モジュール/プロジェクトのルート)
+	Display string // Claudeとdry-runに見せるコマンド
+	Command string // 実際に実行するシェルコマンド
+}
+
+// typecheckTargetFor returns the check for the file at path: `go vet` of its package for .go files
+// (run in the nearest go.mod directory) and `tsc --noEmit` of the nearest tsconfig.json for TypeScript files.
+// It returns false for other files and for files outside a Go module or TypeScript project.
+func typecheckTargetFor(path string) (typecheckTarget, bool) {
+	if path == "" {
+		return typecheckTarget{}, false
+	}
+	dir := filepath.Dir(path)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".go":
+		root, ok := findUpward(dir, "go.mod")
+		if !ok {
+			return typecheckTarget{}, false
+		}
+		pkg := "."
+		if rel, err := filepath.Rel(root, dir); err == nil && rel != "." {
+			pkg = "./" + filepath.ToSlash(rel)
+		}
+		return typecheckTarget{
+			Tool:    "go",
+			Dir:     root,
+			Display: "go vet " + pkg,
+			Command: "cd " + shellQuote(root) + " && go vet " + shellQuote(pkg),
+		}, true
+	case ".ts", ".tsx", ".mts", ".cts":
+		root, ok := findUpward(dir, "tsconfig.json")
+		if !ok {
+			return typecheckTarget{}, false
+		}
+		// プロジェクトにインストールされたtscを優先する
+		tsc := "tsc"
+		if bin, ok := findUpward(root, filepath.Join("node_modules", ".bin", "tsc")); ok {
+			tsc = shellQuote(filepath.Join(bin, "node_modules", ".bin", "tsc"))
+		}
+		tsconfig := filepath.Join(root, "tsconfig.json")
+		return typecheckTarget{
+			Tool:    "tsc",
+			Dir:     root,
+			Display: "tsc --noEmit -p " + tsconfig,
+			Command: "cd " + shellQuote(root) + " && " + tsc + " --noEmit --pretty false -p tsconfig.json",
+		}, true
+	}
+	return typecheckTarget{}, false
+}
+
+// findUpward returns the nearest directory from dir up to the root that contains name.
+func findUpward(dir, name string) (string, bool) {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+var (
+	// ./pkg/a.go:12:3: message (go vetのtype errorは "vet: " が前に付く)
+	goDiagnosticPattern = regexp.MustCompile(`^(?:vet: )?(\S+?\.go):(\d+)(?::(\d+))?: (.+)$`)
+	// src/a.ts(12,5): error TS2322: message
+	tscDiagnosticPattern = regexp.MustCompile(`^(.+?)\((\d+),(\d+)\): ((?:error|warning) TS\d+: .+)$`)
+)
+
+// typecheckDiagnostic is a problem reported by go vet or tsc.
+type typecheckDiagnostic struct {
+	File     string // Dirからの相対パスは絶対パスに直す
+	Position string // "12:3"
+	Message  string
+}
+
+// parseTypecheckOutput extracts the diagnostics of the check's output.
+func parseTypecheckOutput(target typecheckTarget, output string) []typecheckDiagnostic {
+	pattern := goDiagnosticPattern
+	if target.Tool == "tsc" {
+		pattern = tscDiagnosticPattern
+	}
+	var diagnostics []typecheckDiagnostic
+	for _, line := range strings.Split(output, "\n") {
+		m := pattern.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if m == nil {
+			continue
+		}
+		file := m[1]
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(target.Dir, file)
+		}
+		position := m[2]
+		if m[3] != "" {
+			position += ":" + m[3]
+		}
+		diagnostics = append(diagnostics, typecheckDiagnostic{File: file, Position: position, Message: m[4]})
+	}
+	return diagnostics
+}
+
+// typecheckReason summarizes a failed check for Claude: the diagnostics of the edited file first,
+// then the others (at most typecheckMaxDiagnostics), or the end of the output if none could be parsed.
+// Paths under cwd are shown relative to it.
+func typecheckReason(target typecheckTarget, filePath, cwd, output string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Type check failed after editing %s (%s)", displayPath(filePath, cwd), target.Display)
+
+	diagnostics := parseTypecheckOutput(target, output)
+	if len(diagnostics) == 0 {
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		if len(lines) > typecheckMaxDiagnostics {
+			lines = lines[len(lines)-typecheckMaxDiagnostics:]
+		}
+		b.WriteString(":\n" + strings.Join(lines, "\n"))
+		return b.String()
+	}
+
+	slices.SortStableFunc(diagnostics, func(a, b typecheckDiagnostic) int {
+		switch {
+		case a.File == filePath && b.File != filePath:
+			return -1
+		case a.File != filePath && b.File == filePath:
+			return 1
+		}
+		return 0
+	})
+	fmt.Fprintf(&b, ": %d problem(s)", len(diagnostics))
+	for _, d := range diagnostics[:min(len(diagnostics), typecheckMaxDiagnostics)] {
+		fmt.Fprintf(&b, "\n- %s:%s: %s", displayPath(d.File, cwd), d.Position, d.Message)
+	}
+	if n := len(diagnostics) - typecheckMaxDiagnostics; n > 0 {
+		fmt.Fprintf(&b, "\n- ... and %d more", n)
+	}
+	return b.String()
+}
+
+// displayPath returns path relative to cwd if it is under cwd, path itself otherwise.
+func displayPath(path, cwd string) string {
+	if cwd == "" {
+		return path
+	}
+	if rel, err := filepath.Rel(cwd, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return rel
+	}
+	return path
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTypecheckTargetFor(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"go.mod":                         "module example.com/m\n",
+		"main.go":                        "package main\n",
+		"internal/pkg/a.go":              "package pkg\n",
+		"web/tsconfig.json":              "{}",
+		"web/src/app.tsx":                "",
+		"web/node_modules/.bin/tsc":      "",
+		"scripts/tsconfig.json":          "{}",
+		"scripts/build.ts":               "",
+		"notes/readme.txt":               "",
+		"internal/pkg/testdata/input.go": "package testdata\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	web := filepath.Join(dir, "web")
+	scripts := filepath.Join(dir, "scripts")
+
+	tests := []struct {
+		file        string
+		wantOK      bool
+		wantDisplay string
+		wantCommand string
+	}{
+		{"main.go", true, "go vet .", "cd " + shellQuote(dir) + " && go vet '.'"},
+		{"internal/pkg/a.go", true, "go vet ./internal/pkg", "cd " + shellQuote(dir) + " && go vet './internal/pkg'"},
+		{"internal/pkg/testdata/input.go", true, "go vet ./internal/pkg/testdata", "cd " + shellQuote(dir) + " && go vet './internal/pkg/testdata'"},
+		{"web/src/app.tsx", true, "tsc --noEmit -p " + filepath.Join(web, "tsconfig.json"),
+			"cd " + shellQuote(web) + " && " + shellQuote(filepath.Join(web, "node_modules", ".bin", "tsc")) + " --noEmit --pretty false -p tsconfig.json"},
+		{"scripts/build.ts", true, "tsc --noEmit -p " + filepath.Join(scripts, "tsconfig.json"),
+			"cd " + shellQuote(scripts) + " && tsc --noEmit --pretty false -p tsconfig.json"},
+		{"notes/readme.txt", false, "", ""},
+		{"", false, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			path := ""
+			if tt.file != "" {
+				path = filepath.Join(dir, tt.file)
+			}
+			target, ok := typecheckTargetFor(path)
+			if ok != tt.wantOK {
+				t.Fatalf("typecheckTargetFor() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if target.Display != tt.wantDisplay || target.Command != tt.wantCommand {
+				t.Errorf("typecheckTargetFor() = %q, %q; want %q, %q", target.Display, target.Command, tt.wantDisplay, tt.wantCommand)
+			}
+		})
+	}
+
+	// go.modもtsconfig.jsonも無いディレクトリ
+	other := t.TempDir()
+	if _, ok := typecheckTargetFor(filepath.Join(other, "main.go")); ok {
+		t.Error("typecheckTargetFor() outside a module ok = true, want false")
+	}
+}
+
+func TestTypecheckReason(t *testing.T) {
+	goTarget := typecheckTarget{Tool: "go", Dir: "/repo", Display: "go vet ./pkg"}
+	tscTarget := typecheckTarget{Tool: "tsc", Dir: "/repo/web", Display: "tsc --noEmit -p /repo/web/tsconfig.json"}
+
+	tests := []struct {
+		name     string
+		target   typecheckTarget
+		filePath string
+		output   string
+		want     string
+	}{
+		{
+			name:     "go vet diagnostics of the edited file first",
+			target:   goTarget,
+			filePath: "/repo/pkg/b.go",
+			output: "# example.com/m/pkg\n" +
+				"pkg/a.go:3:2: fmt.Printf format %d has arg s of wrong type string\n" +
+				"vet: pkg/b.go:10:5: undefined: foo\n" +
+				"\tcontinued explanation\n",
+			want: "Type check failed after editing pkg/b.go (go vet ./pkg): 2 problem(s)\n" +
+				"- pkg/b.go:10:5: undefined: foo\n" +
+				"- pkg/a.go:3:2: fmt.Printf format %d has arg s of wrong type string",
+		},
+		{
+			name:     "tsc diagnostics",
+			target:   tscTarget,
+			filePath: "/repo/web/src/app.ts",
+			output: "src/util.ts(1,7): error TS2322: Type 'string' is not assignable to type 'number'.\n" +
+				"src/app.ts(4,1): error TS2304: Cannot find name 'foo'.\n",
+			want: "Type check failed after editing web/src/app.ts (tsc --noEmit -p /repo/web/tsconfig.json): 2 problem(s)\n" +
+				"- web/src/app.ts:4:1: error TS2304: Cannot find name 'foo'.\n" +
+				"- web/src/util.ts:1:7: error TS2322: Type 'string' is not assignable to type 'number'.",
+		},
+		{
+			name:     "unparsable output",
+			target:   goTarget,
+			filePath: "/repo/pkg/a.go",
+			output:   "go: updates to go.mod needed; to update it:\n\tgo mod tidy\n",
+			want:     "Type check failed after editing pkg/a.go (go vet ./pkg):\ngo: updates to go.mod needed; to update it:\n\tgo mod tidy",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := typecheckReason(tt.target, tt.filePath, "/repo", tt.output); got != tt.want {
+				t.Errorf("typecheckReason() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("many diagnostics", func(t *testing.T) {
+		var output strings.Builder
+		for i := 1; i <= typecheckMaxDiagnostics+5; i++ {
+			fmt.Fprintf(&output, "pkg/a.go:%d:1: problem %d\n", i, i)
+		}
+		got := typecheckReason(goTarget, "/repo/pkg/a.go", "/repo", output.String())
+		if !strings.HasSuffix(got, "\n- pkg/a.go:20:1: problem 20\n- ... and 5 more") {
+			t.Errorf("typecheckReason() = %s", got)
+		}
+	})
+}
+
+func TestExecutePostToolUseAction_Typecheck(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/m\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	vetOutput := "# example.com/m\nvet: ./main.go:4:2: undefined: foo\n"
+	tests := []struct {
+		name         string
+		file         string
+		action       Action
+		runner       *stubRunnerWithOutput
+		wantDecision string
+		wantReason   string
+		wantContext  string
+		wantNil      bool
+	}{
+		{name: "check passes", file: "main.go", action: Action{Type: "typecheck"}, runner: &stubRunnerWithOutput{}, wantNil: true},
+		{name: "not a Go or TypeScript file", file: "README.md", action: Action{Type: "typecheck"}, runner: &stubRunnerWithOutput{exitCode: 1, stderr: vetOutput}, wantNil: true},
+		{name: "go not installed", file: "main.go", action: Action{Type: "typecheck"}, runner: &stubRunnerWithOutput{exitCode: 127, stderr: "sh: 1: go: not found"}, wantNil: true},
+		{
+			name:         "errors block",
+			file:         "main.go",
+			action:       Action{Type: "typecheck"},
+			runner:       &stubRunnerWithOutput{exitCode: 1, stderr: vetOutput},
+			wantDecision: "block",
+			wantReason:   "Type check failed after editing main.go (go vet .): 1 problem(s)\n- main.go:4:2: undefined: foo",
+		},
+		{
+			name:        "report only",
+			file:        "main.go",
+			action:      Action{Type: "typecheck", Decision: stringPtr("")},
+			runner:      &stubRunnerWithOutput{exitCode: 1, stderr: vetOutput},
+			wantContext: "Type check failed after editing main.go (go vet .): 1 problem(s)\n- main.go:4:2: undefined: foo",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := &PostToolUseInput{BaseInput: BaseInput{Cwd: dir}, ToolName: "Edit", ToolInput: ToolInput{FilePath: tt.file}}
+			output, err := NewActionExecutor(tt.runner).ExecutePostToolUseAction(tt.action, input, map[string]any{"cwd": dir})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantNil {
+				if output != nil {
+					t.Errorf("output = %+v, want nil", output)
+				}
+				return
+			}
+			if output == nil {
+				t.Fatal("output = nil")
+			}
+			if output.Decision != tt.wantDecision || output.Reason != tt.wantReason || output.AdditionalContext != tt.wantContext {
+				t.Errorf("output = decision %q, reason %q, context %q; want %q, %q, %q",
+					output.Decision, output.Reason, output.AdditionalContext, tt.wantDecision, tt.wantReason, tt.wantContext)
+			}
+		})
+	}
+}
+
+func TestExecutePostToolUseAction_TypecheckGoVet(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not installed")
+	}
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"go.mod":        "module example.com/m\n\ngo 1.21\n",
+		"main.go":       "package main\n\nfunc main() {}\n",
+		"pkg/broken.go": "package pkg\n\nfunc F() int {\n\treturn undefinedName\n}\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	executor := NewActionExecutor(nil)
+	check := func(file string) *ActionOutput {
+		t.Helper()
+		input := &PostToolUseInput{BaseInput: BaseInput{Cwd: dir}, ToolName: "Edit", ToolInput: ToolInput{FilePath: file}}
+		output, err := executor.ExecutePostToolUseAction(Action{Type: "typecheck"}, input, map[string]any{"cwd": dir})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return output
+	}
+
+	// 壊れたパッケージとは別のパッケージだけをチェックする
+	if output := check("main.go"); output != nil {
+		t.Errorf("main.go: output = %+v, want nil", output)
+	}
+	output := check("pkg/broken.go")
+	if output == nil || output.Decision != "block" {
+		t.Fatalf("pkg/broken.go: output = %+v, want block", output)
+	}
+	if !strings.Contains(output.Reason, "(go vet ./pkg)") || !strings.Contains(output.Reason, "- pkg/broken.go:4:9: undefined: undefinedName") {
+		t.Errorf("pkg/broken.go: reason = %q", output.Reason)
+	}
+}
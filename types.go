@@ -3,17 +3,50 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"time"
 )
 
 // CommandRunner is an interface for executing shell commands.
-// This interface allows for dependency injection in tests.
+// This interface allows for dependency injection in tests, and for routing command actions
+// through other backends within cchook (chaos, explain). It is not an API for other programs:
+// cchook is a main package and can't be imported, so SSH and container backends are the `runner`
+// of command actions (see parseCommandRunner) instead.
+//
+// cmd is the command line after template expansion, to be interpreted by a POSIX shell.
+// When useStdin is true, data (the event JSON) must be written to the command's stdin as JSON.
 type CommandRunner interface {
 	// RunCommand executes a shell command with optional stdin data.
 	RunCommand(cmd string, useStdin bool, data any) error
 	// RunCommandWithOutput executes a shell command and returns stdout, stderr, exit code, and error.
+	// A command that ran and exited non-zero should report its exit code with a nil error;
+	// err is for failures to run the command at all (exitCode is then treated as a failure too).
 	RunCommandWithOutput(cmd string, useStdin bool, data any) (stdout, stderr string, exitCode int, err error)
 }
 
+// ArgvCommandRunner is implemented by CommandRunners that can run a program without a shell.
+// Command actions written with args use it when they run locally; with other CommandRunners,
+// they run the equivalent shell command line (see argvCommandLine) instead.
+//
+// env holds NAME=value entries added to the environment of the program.
+type ArgvCommandRunner interface {
+	RunArgsWithOutput(args, env []string, useStdin bool, data any) (stdout, stderr string, exitCode int, err error)
+}
+
+// TimeoutCommandRunner is implemented by CommandRunners that can kill a shell command after a timeout.
+// run_tests actions use it; with other CommandRunners, their commands run without a timeout.
+//
+// A command killed after the timeout must be reported with an error wrapping ErrCommandTimedOut.
+type TimeoutCommandRunner interface {
+	RunCommandWithTimeout(cmd string, timeout time.Duration, useStdin bool, data any) (stdout, stderr string, exitCode int, err error)
+}
+
+// HTTPClient is an interface for sending the requests of http actions.
+// This interface allows for dependency injection in tests.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
 // イベントタイプのenum定義
 type HookEventType string
 
@@ -54,6 +87,9 @@ type BaseInput struct {
 	Cwd            string        `json:"cwd,omitempty"`
 	PermissionMode string        `json:"permission_mode,omitempty"`
 	HookEventName  HookEventType `json:"hook_event_name"`
+
+	// RawJSON is the whole event JSON as parsed by parseInputFrom (nil for inputs built in code).
+	RawJSON any `json:"-"`
 }
 
 // GetEventType returns the hook event type from the base input.
@@ -61,13 +97,45 @@ func (b BaseInput) GetEventType() HookEventType {
 	return b.HookEventName
 }
 
+// setRawJSON keeps the whole event JSON for conditions evaluated against it (tool_input_jq).
+func (b *BaseInput) setRawJSON(rawJSON any) {
+	b.RawJSON = rawJSON
+}
+
 // Tool input structures - 全ツール共通構造と仮定
 type ToolInput struct {
-	FilePath string `json:"file_path"`
-	Content  string `json:"content"`
-	Command  string `json:"command"`
-	URL      string `json:"url"`    // WebFetch用
-	Prompt   string `json:"prompt"` // WebFetch用
+	FilePath   string          `json:"file_path"`
+	Content    string          `json:"content"`
+	OldString  string          `json:"old_string,omitempty"`  // Edit用
+	NewString  string          `json:"new_string,omitempty"`  // Edit用
+	ReplaceAll bool            `json:"replace_all,omitempty"` // Edit用
+	Edits      []ToolInputEdit `json:"edits,omitempty"`       // MultiEdit用
+	Command    string          `json:"command"`
+	URL        string          `json:"url"`    // WebFetch用
+	Prompt     string          `json:"prompt"` // WebFetch用
+}
+
+// ToolInputEdit is one of the edits of a MultiEdit tool call.
+type ToolInputEdit struct {
+	OldString  string `json:"old_string"`
+	NewString  string `json:"new_string"`
+	ReplaceAll bool   `json:"replace_all,omitempty"`
+}
+
+// writtenContents returns the text the tool writes: content (Write), new_string (Edit) and each new_string of edits (MultiEdit).
+func (t *ToolInput) writtenContents() []string {
+	var contents []string
+	for _, s := range []string{t.Content, t.NewString} {
+		if s != "" {
+			contents = append(contents, s)
+		}
+	}
+	for _, edit := range t.Edits {
+		if edit.NewString != "" {
+			contents = append(contents, edit.NewString)
+		}
+	}
+	return contents
 }
 
 // PreToolUse用
@@ -145,7 +213,10 @@ func (s *StopInput) GetToolName() string {
 // SubagentStop用
 type SubagentStopInput struct {
 	BaseInput
-	StopHookActive bool `json:"stop_hook_active"`
+	StopHookActive      bool   `json:"stop_hook_active"`
+	AgentID             string `json:"agent_id"`
+	AgentType           string `json:"agent_type"`            // SubagentStartと同じエージェント種別
+	AgentTranscriptPath string `json:"agent_transcript_path"` // サブエージェント自身のtranscript
 }
 
 // GetToolName returns an empty string as SubagentStop events have no associated tool.
@@ -233,7 +304,7 @@ type ActionOutput struct {
 	PermissionDecision       string         // "allow", "deny", or "ask" (PreToolUse only, empty for SessionStart/UserPromptSubmit)
 	PermissionDecisionReason string         // Reason for permission decision (PreToolUse only)
 	UpdatedInput             map[string]any // Updated tool input parameters (PreToolUse only)
-	Behavior                 string         // "allow" or "deny" (PermissionRequest only)
+	Behavior                 string         // "allow", "deny" or "ask" (PermissionRequest only)
 	Message                  string         // Deny message (PermissionRequest only)
 	Interrupt                bool           // Interrupt flag for deny (PermissionRequest only)
 	Reason                   string         // Reason for decision (Stop/SubagentStop/PostToolUse only, required when decision is "block")
@@ -271,7 +342,7 @@ type PermissionRequestOutput struct {
 	StopReason         string                               `json:"stopReason,omitempty"`
 	SuppressOutput     bool                                 `json:"suppressOutput,omitempty"`
 	SystemMessage      string                               `json:"systemMessage,omitempty"`
-	HookSpecificOutput *PermissionRequestHookSpecificOutput `json:"hookSpecificOutput"` // Required
+	HookSpecificOutput *PermissionRequestHookSpecificOutput `json:"hookSpecificOutput,omitempty"` // Required in command output; omitted for ask (Claude Code shows its permission dialog)
 }
 
 // PermissionRequestHookSpecificOutput represents the hookSpecificOutput field for PermissionRequest hooks
@@ -282,7 +353,7 @@ type PermissionRequestHookSpecificOutput struct {
 
 // PermissionRequestDecision represents the decision object within PermissionRequest hookSpecificOutput
 type PermissionRequestDecision struct {
-	Behavior     string         `json:"behavior"`               // Required: "allow" or "deny"
+	Behavior     string         `json:"behavior"`               // Required: "allow", "deny" or "ask" (ask is cchook only and never sent to Claude Code)
 	UpdatedInput map[string]any `json:"updatedInput,omitempty"` // Optional: allow時のみ
 	Message      string         `json:"message,omitempty"`      // Optional: deny時のみ
 	Interrupt    bool           `json:"interrupt,omitempty"`    // Optional: deny時のみ、デフォルトfalse
@@ -396,66 +467,133 @@ type Hook interface {
 
 // イベントタイプ毎の設定構造体
 type PreToolUseHook struct {
-	Matcher    string      `yaml:"matcher"`
-	Conditions []Condition `yaml:"conditions,omitempty"`
-	Actions    []Action    `yaml:"actions"`
+	Name          string      `yaml:"name,omitempty"` // フックの名前 (安定したフックIDの元になる。省略時は内容から作る)
+	Matcher       string      `yaml:"matcher"`
+	ExcludeTools  []string    `yaml:"exclude_tools,omitempty"` // matcherに一致しても除外するツール名 (完全一致)
+	MCPServer     string      `yaml:"mcp_server,omitempty"`    // MCPサーバー名 (パイプ区切り、完全一致)。指定時はそのサーバーのMCPツールのみ
+	Conditions    []Condition `yaml:"conditions,omitempty"`
+	Actions       []Action    `yaml:"actions"`
+	Mutex         string      `yaml:"mutex,omitempty"`                                                // 同じ名前のフック同士でアクションをプロセス間排他する
+	EnvFrom       string      `yaml:"env_from,omitempty"`                                             // コマンドアクションの実行前にcwdのdirenv/nix developの環境を読み込む
+	Cooldown      string      `yaml:"cooldown,omitempty"`                                             // マッチしてからこの時間はフックを再度実行しない
+	CooldownScope string      `yaml:"cooldown_scope,omitempty" jsonschema:"enum=session,enum=global"` // cooldownをセッション毎 (session, 省略時) か全セッション共通 (global) で数える
 }
 
 type PostToolUseHook struct {
-	Matcher    string      `yaml:"matcher"`
-	Conditions []Condition `yaml:"conditions,omitempty"`
-	Actions    []Action    `yaml:"actions"`
+	Name          string      `yaml:"name,omitempty"` // フックの名前 (安定したフックIDの元になる。省略時は内容から作る)
+	Matcher       string      `yaml:"matcher"`
+	ExcludeTools  []string    `yaml:"exclude_tools,omitempty"` // matcherに一致しても除外するツール名 (完全一致)
+	MCPServer     string      `yaml:"mcp_server,omitempty"`    // MCPサーバー名 (パイプ区切り、完全一致)。指定時はそのサーバーのMCPツールのみ
+	Conditions    []Condition `yaml:"conditions,omitempty"`
+	Actions       []Action    `yaml:"actions"`
+	Mutex         string      `yaml:"mutex,omitempty"`                                                // 同じ名前のフック同士でアクションをプロセス間排他する
+	EnvFrom       string      `yaml:"env_from,omitempty"`                                             // コマンドアクションの実行前にcwdのdirenv/nix developの環境を読み込む
+	Cooldown      string      `yaml:"cooldown,omitempty"`                                             // マッチしてからこの時間はフックを再度実行しない
+	CooldownScope string      `yaml:"cooldown_scope,omitempty" jsonschema:"enum=session,enum=global"` // cooldownをセッション毎 (session, 省略時) か全セッション共通 (global) で数える
+	Snapshot      bool        `yaml:"snapshot,omitempty"`                                             // コマンドアクションはfile_pathのコピーを書き換え、元のファイルが変わっていなければ反映する
+
+	NotifyModelOnFileChange bool   `yaml:"notify_model_on_file_change,omitempty"` // アクションがfile_pathを書き換えたら読み直すようClaudeに伝える
+	Batch                   bool   `yaml:"batch,omitempty"`                       // イベントを溜めて、静かになったらまとめて1回実行する
+	BatchQuietPeriod        string `yaml:"batch_quiet_period,omitempty"`          // batchの実行までに待つ、イベントが来ない時間 (省略時2s)
 }
 
 type PermissionRequestHook struct {
-	Matcher    string      `yaml:"matcher"`
-	Conditions []Condition `yaml:"conditions,omitempty"`
-	Actions    []Action    `yaml:"actions"`
+	Name          string      `yaml:"name,omitempty"` // フックの名前 (安定したフックIDの元になる。省略時は内容から作る)
+	Matcher       string      `yaml:"matcher"`
+	ExcludeTools  []string    `yaml:"exclude_tools,omitempty"` // matcherに一致しても除外するツール名 (完全一致)
+	MCPServer     string      `yaml:"mcp_server,omitempty"`    // MCPサーバー名 (パイプ区切り、完全一致)。指定時はそのサーバーのMCPツールのみ
+	Conditions    []Condition `yaml:"conditions,omitempty"`
+	Actions       []Action    `yaml:"actions"`
+	Mutex         string      `yaml:"mutex,omitempty"`                                                // 同じ名前のフック同士でアクションをプロセス間排他する
+	EnvFrom       string      `yaml:"env_from,omitempty"`                                             // コマンドアクションの実行前にcwdのdirenv/nix developの環境を読み込む
+	Cooldown      string      `yaml:"cooldown,omitempty"`                                             // マッチしてからこの時間はフックを再度実行しない
+	CooldownScope string      `yaml:"cooldown_scope,omitempty" jsonschema:"enum=session,enum=global"` // cooldownをセッション毎 (session, 省略時) か全セッション共通 (global) で数える
 }
 
 type NotificationHook struct {
-	Matcher    string      `yaml:"matcher,omitempty"` // Matches against notification_type (e.g., "permission_prompt|idle_prompt")
-	Conditions []Condition `yaml:"conditions,omitempty"`
-	Actions    []Action    `yaml:"actions"`
+	Name          string      `yaml:"name,omitempty"`    // フックの名前 (安定したフックIDの元になる。省略時は内容から作る)
+	Matcher       string      `yaml:"matcher,omitempty"` // Matches against notification_type (e.g., "permission_prompt|idle_prompt")
+	Conditions    []Condition `yaml:"conditions,omitempty"`
+	Actions       []Action    `yaml:"actions"`
+	Mutex         string      `yaml:"mutex,omitempty"`                                                // 同じ名前のフック同士でアクションをプロセス間排他する
+	EnvFrom       string      `yaml:"env_from,omitempty"`                                             // コマンドアクションの実行前にcwdのdirenv/nix developの環境を読み込む
+	Cooldown      string      `yaml:"cooldown,omitempty"`                                             // マッチしてからこの時間はフックを再度実行しない
+	CooldownScope string      `yaml:"cooldown_scope,omitempty" jsonschema:"enum=session,enum=global"` // cooldownをセッション毎 (session, 省略時) か全セッション共通 (global) で数える
 }
 
 type StopHook struct {
-	Conditions []Condition `yaml:"conditions,omitempty"`
-	Actions    []Action    `yaml:"actions"`
+	Name          string      `yaml:"name,omitempty"` // フックの名前 (安定したフックIDの元になる。省略時は内容から作る)
+	Conditions    []Condition `yaml:"conditions,omitempty"`
+	Actions       []Action    `yaml:"actions"`
+	Mutex         string      `yaml:"mutex,omitempty"`                                                // 同じ名前のフック同士でアクションをプロセス間排他する
+	EnvFrom       string      `yaml:"env_from,omitempty"`                                             // コマンドアクションの実行前にcwdのdirenv/nix developの環境を読み込む
+	Cooldown      string      `yaml:"cooldown,omitempty"`                                             // マッチしてからこの時間はフックを再度実行しない
+	CooldownScope string      `yaml:"cooldown_scope,omitempty" jsonschema:"enum=session,enum=global"` // cooldownをセッション毎 (session, 省略時) か全セッション共通 (global) で数える
 }
 
 type SubagentStopHook struct {
-	Conditions []Condition `yaml:"conditions,omitempty"`
-	Actions    []Action    `yaml:"actions"`
+	Name          string      `yaml:"name,omitempty"` // フックの名前 (安定したフックIDの元になる。省略時は内容から作る)
+	Matcher       string      `yaml:"matcher"`        // agent type (Bash, Explore, Plan, or custom agent names)
+	Conditions    []Condition `yaml:"conditions,omitempty"`
+	Actions       []Action    `yaml:"actions"`
+	Mutex         string      `yaml:"mutex,omitempty"`                                                // 同じ名前のフック同士でアクションをプロセス間排他する
+	EnvFrom       string      `yaml:"env_from,omitempty"`                                             // コマンドアクションの実行前にcwdのdirenv/nix developの環境を読み込む
+	Cooldown      string      `yaml:"cooldown,omitempty"`                                             // マッチしてからこの時間はフックを再度実行しない
+	CooldownScope string      `yaml:"cooldown_scope,omitempty" jsonschema:"enum=session,enum=global"` // cooldownをセッション毎 (session, 省略時) か全セッション共通 (global) で数える
 }
 
 type PreCompactHook struct {
-	Matcher    string      `yaml:"matcher"` // "manual" or "auto"
-	Conditions []Condition `yaml:"conditions,omitempty"`
-	Actions    []Action    `yaml:"actions"`
+	Name          string      `yaml:"name,omitempty"` // フックの名前 (安定したフックIDの元になる。省略時は内容から作る)
+	Matcher       string      `yaml:"matcher"`        // "manual" or "auto"
+	Conditions    []Condition `yaml:"conditions,omitempty"`
+	Actions       []Action    `yaml:"actions"`
+	Mutex         string      `yaml:"mutex,omitempty"`                                                // 同じ名前のフック同士でアクションをプロセス間排他する
+	EnvFrom       string      `yaml:"env_from,omitempty"`                                             // コマンドアクションの実行前にcwdのdirenv/nix developの環境を読み込む
+	Cooldown      string      `yaml:"cooldown,omitempty"`                                             // マッチしてからこの時間はフックを再度実行しない
+	CooldownScope string      `yaml:"cooldown_scope,omitempty" jsonschema:"enum=session,enum=global"` // cooldownをセッション毎 (session, 省略時) か全セッション共通 (global) で数える
 }
 
 type SessionStartHook struct {
-	Matcher    string      `yaml:"matcher"` // "startup", "resume", or "clear"
-	Conditions []Condition `yaml:"conditions,omitempty"`
-	Actions    []Action    `yaml:"actions"`
+	Name          string      `yaml:"name,omitempty"` // フックの名前 (安定したフックIDの元になる。省略時は内容から作る)
+	Matcher       string      `yaml:"matcher"`        // "startup", "resume", or "clear"
+	Conditions    []Condition `yaml:"conditions,omitempty"`
+	Actions       []Action    `yaml:"actions"`
+	Mutex         string      `yaml:"mutex,omitempty"`                                                // 同じ名前のフック同士でアクションをプロセス間排他する
+	EnvFrom       string      `yaml:"env_from,omitempty"`                                             // コマンドアクションの実行前にcwdのdirenv/nix developの環境を読み込む
+	Cooldown      string      `yaml:"cooldown,omitempty"`                                             // マッチしてからこの時間はフックを再度実行しない
+	CooldownScope string      `yaml:"cooldown_scope,omitempty" jsonschema:"enum=session,enum=global"` // cooldownをセッション毎 (session, 省略時) か全セッション共通 (global) で数える
 }
 
 // SubagentStartHook はSubagentStartフックの設定
 type SubagentStartHook struct {
-	Matcher    string      `yaml:"matcher"` // agent type (Bash, Explore, Plan, or custom agent names)
-	Conditions []Condition `yaml:"conditions,omitempty"`
-	Actions    []Action    `yaml:"actions"`
+	Name          string      `yaml:"name,omitempty"` // フックの名前 (安定したフックIDの元になる。省略時は内容から作る)
+	Matcher       string      `yaml:"matcher"`        // agent type (Bash, Explore, Plan, or custom agent names)
+	Conditions    []Condition `yaml:"conditions,omitempty"`
+	Actions       []Action    `yaml:"actions"`
+	Mutex         string      `yaml:"mutex,omitempty"`                                                // 同じ名前のフック同士でアクションをプロセス間排他する
+	EnvFrom       string      `yaml:"env_from,omitempty"`                                             // コマンドアクションの実行前にcwdのdirenv/nix developの環境を読み込む
+	Cooldown      string      `yaml:"cooldown,omitempty"`                                             // マッチしてからこの時間はフックを再度実行しない
+	CooldownScope string      `yaml:"cooldown_scope,omitempty" jsonschema:"enum=session,enum=global"` // cooldownをセッション毎 (session, 省略時) か全セッション共通 (global) で数える
 }
 
 type UserPromptSubmitHook struct {
-	Conditions []Condition `yaml:"conditions,omitempty"`
-	Actions    []Action    `yaml:"actions"`
+	Name          string      `yaml:"name,omitempty"` // フックの名前 (安定したフックIDの元になる。省略時は内容から作る)
+	Conditions    []Condition `yaml:"conditions,omitempty"`
+	Actions       []Action    `yaml:"actions"`
+	Mutex         string      `yaml:"mutex,omitempty"`                                                // 同じ名前のフック同士でアクションをプロセス間排他する
+	EnvFrom       string      `yaml:"env_from,omitempty"`                                             // コマンドアクションの実行前にcwdのdirenv/nix developの環境を読み込む
+	Cooldown      string      `yaml:"cooldown,omitempty"`                                             // マッチしてからこの時間はフックを再度実行しない
+	CooldownScope string      `yaml:"cooldown_scope,omitempty" jsonschema:"enum=session,enum=global"` // cooldownをセッション毎 (session, 省略時) か全セッション共通 (global) で数える
 }
 
 type SessionEndHook struct {
-	Conditions []Condition `yaml:"conditions,omitempty"`
-	Actions    []Action    `yaml:"actions"`
+	Name          string      `yaml:"name,omitempty"` // フックの名前 (安定したフックIDの元になる。省略時は内容から作る)
+	Conditions    []Condition `yaml:"conditions,omitempty"`
+	Actions       []Action    `yaml:"actions"`
+	Mutex         string      `yaml:"mutex,omitempty"`                                                // 同じ名前のフック同士でアクションをプロセス間排他する
+	EnvFrom       string      `yaml:"env_from,omitempty"`                                             // コマンドアクションの実行前にcwdのdirenv/nix developの環境を読み込む
+	Cooldown      string      `yaml:"cooldown,omitempty"`                                             // マッチしてからこの時間はフックを再度実行しない
+	CooldownScope string      `yaml:"cooldown_scope,omitempty" jsonschema:"enum=session,enum=global"` // cooldownをセッション毎 (session, 省略時) か全セッション共通 (global) で数える
 }
 
 // 共通の条件構造体
@@ -479,26 +617,96 @@ var (
 	ConditionDirNotExists           = ConditionType{"dir_not_exists"}
 	ConditionDirNotExistsRecursive  = ConditionType{"dir_not_exists_recursive"}
 	ConditionPermissionModeIs       = ConditionType{"permission_mode_is"}
+	ConditionFileOlderThan          = ConditionType{"file_older_than"}
+	ConditionFileNewerThan          = ConditionType{"file_newer_than"}
+	ConditionFileSHA256Is           = ConditionType{"file_sha256_is"}
+	ConditionFileContentEqualsFile  = ConditionType{"file_content_equals_file"}
+	ConditionSessionDurationLt      = ConditionType{"session_duration_lt"}
+	ConditionSessionDurationGt      = ConditionType{"session_duration_gt"}
+	ConditionTokensUsedLt           = ConditionType{"tokens_used_lt"}
+	ConditionTokensUsedGt           = ConditionType{"tokens_used_gt"}
+	ConditionLastAssistantMatches   = ConditionType{"last_assistant_message_matches"}
+	ConditionChangelogNotUpdated    = ConditionType{"changelog_not_updated"}
+	ConditionTicketReferenced       = ConditionType{"ticket_referenced"}
+	ConditionInMeeting              = ConditionType{"in_meeting"}
+	ConditionFreezeActive           = ConditionType{"freeze_active"}
+	ConditionDigestPending          = ConditionType{"digest_pending"}
+	ConditionBudgetExceeded         = ConditionType{"budget_exceeded"}
+	ConditionBudgetRemainingBelow   = ConditionType{"budget_remaining_below"}
+	ConditionToolInputJQ            = ConditionType{"tool_input_jq"}
+	ConditionCEL                    = ConditionType{"cel"}
+	ConditionGitBranchIs            = ConditionType{"git_branch_is"}
+	ConditionGitBranchMatches       = ConditionType{"git_branch_matches"}
+	ConditionInDevcontainer         = ConditionType{"in_devcontainer"}
+	ConditionGitDirty               = ConditionType{"git_dirty"}
+	ConditionGitClean               = ConditionType{"git_clean"}
+	ConditionGitSigningEnabled      = ConditionType{"git_signing_enabled"}
+	ConditionEnvIs                  = ConditionType{"env_is"}
+	ConditionEnvSet                 = ConditionType{"env_set"}
+	ConditionEnvMatches             = ConditionType{"env_matches"}
+	ConditionTimeBetween            = ConditionType{"time_between"}
+	ConditionDayOfWeek              = ConditionType{"day_of_week"}
 
 	// Tool-related conditions (PreToolUse/PostToolUse)
 	ConditionFileExtension     = ConditionType{"file_extension"}
+	ConditionFilePathMatches   = ConditionType{"file_path_matches"}
+	ConditionFileIsGitignored  = ConditionType{"file_is_gitignored"}
+	ConditionFileNotGitignored = ConditionType{"file_not_gitignored"}
+	ConditionContentContains   = ConditionType{"content_contains"}
+	ConditionContentMatches    = ConditionType{"content_matches"}
 	ConditionCommandContains   = ConditionType{"command_contains"}
 	ConditionCommandStartsWith = ConditionType{"command_starts_with"}
+	ConditionCommandRegex      = ConditionType{"command_regex"}
+	ConditionCommandNotRegex   = ConditionType{"command_not_regex"}
 	ConditionURLStartsWith     = ConditionType{"url_starts_with"}
 
+	// MCP tool conditions (PreToolUse/PostToolUse/PermissionRequest on mcp__<server>__<tool>)
+	ConditionMCPServerIs = ConditionType{"mcp_server_is"}
+	ConditionMCPToolIs   = ConditionType{"mcp_tool_is"}
+
+	// Large/binary file conditions (PreToolUse/PostToolUse on Write and `git add`)
+	ConditionFileSizeGt   = ConditionType{"file_size_gt"}
+	ConditionFileIsBinary = ConditionType{"file_is_binary"}
+
+	// git commit message conditions (PreToolUse/PostToolUse on Bash)
+	ConditionGitCommitMessageMatches    = ConditionType{"git_commit_message_matches"}
+	ConditionGitCommitMessageNotMatches = ConditionType{"git_commit_message_not_matches"}
+
+	// git push conditions (PreToolUse/PostToolUse on Bash)
+	ConditionPushToRemoteIs          = ConditionType{"push_to_remote_is"}
+	ConditionPushIsForce             = ConditionType{"push_is_force"}
+	ConditionPushTargetBranchMatches = ConditionType{"push_target_branch_matches"}
+
 	// Prompt-related conditions (UserPromptSubmit)
 	ConditionPromptRegex   = ConditionType{"prompt_regex"}
 	ConditionEveryNPrompts = ConditionType{"every_n_prompts"}
 
+	// Notification-related conditions (Notification)
+	ConditionNotificationMessageContains = ConditionType{"notification_message_contains"}
+	ConditionNotificationMessageRegex    = ConditionType{"notification_message_regex"}
+
 	// Reason-related conditions (SessionEnd)
 	ConditionReasonIs = ConditionType{"reason_is"}
 
+	// Tool response conditions (PostToolUse)
+	ConditionToolResponseContains = ConditionType{"tool_response_contains"}
+	ConditionToolErrorIsPresent   = ConditionType{"tool_error_is_present"}
+	ConditionExitCodeIs           = ConditionType{"exit_code_is"}
+
+	// Agent-related conditions (SubagentStart, SubagentStop)
+	ConditionAgentTypeIs = ConditionType{"agent_type_is"}
+
 	// Git-related conditions (PreToolUse for Bash commands)
 	ConditionGitTrackedFileOperation = ConditionType{"git_tracked_file_operation"}
 	ConditionCwdIs                   = ConditionType{"cwd_is"}
 	ConditionCwdIsNot                = ConditionType{"cwd_is_not"}
 	ConditionCwdContains             = ConditionType{"cwd_contains"}
 	ConditionCwdNotContains          = ConditionType{"cwd_not_contains"}
+
+	// Composite conditions (all events): group child conditions
+	ConditionAnyOf = ConditionType{"any_of"}
+	ConditionAllOf = ConditionType{"all_of"}
+	ConditionNot   = ConditionType{"not"}
 )
 
 // UnmarshalYAML implements yaml.Unmarshaler for ConditionType
@@ -508,53 +716,251 @@ func (c *ConditionType) UnmarshalYAML(unmarshal func(any) error) error {
 		return err
 	}
 
+	ct, err := parseConditionType(s)
+	if err != nil {
+		return err
+	}
+	*c = ct
+	return nil
+}
+
+// parseConditionType returns the predefined ConditionType singleton for the given name.
+func parseConditionType(s string) (ConditionType, error) {
+	var c ConditionType
 	switch s {
 	case "file_exists":
-		*c = ConditionFileExists
+		c = ConditionFileExists
 	case "file_exists_recursive":
-		*c = ConditionFileExistsRecursive
+		c = ConditionFileExistsRecursive
 	case "file_not_exists":
-		*c = ConditionFileNotExists
+		c = ConditionFileNotExists
 	case "file_not_exists_recursive":
-		*c = ConditionFileNotExistsRecursive
+		c = ConditionFileNotExistsRecursive
 	case "dir_exists":
-		*c = ConditionDirExists
+		c = ConditionDirExists
 	case "dir_exists_recursive":
-		*c = ConditionDirExistsRecursive
+		c = ConditionDirExistsRecursive
 	case "dir_not_exists":
-		*c = ConditionDirNotExists
+		c = ConditionDirNotExists
 	case "dir_not_exists_recursive":
-		*c = ConditionDirNotExistsRecursive
+		c = ConditionDirNotExistsRecursive
 	case "file_extension":
-		*c = ConditionFileExtension
+		c = ConditionFileExtension
+	case "file_path_matches":
+		c = ConditionFilePathMatches
+	case "file_is_gitignored":
+		c = ConditionFileIsGitignored
+	case "file_not_gitignored":
+		c = ConditionFileNotGitignored
+	case "content_contains":
+		c = ConditionContentContains
+	case "content_matches":
+		c = ConditionContentMatches
 	case "command_contains":
-		*c = ConditionCommandContains
+		c = ConditionCommandContains
 	case "command_starts_with":
-		*c = ConditionCommandStartsWith
+		c = ConditionCommandStartsWith
+	case "command_regex":
+		c = ConditionCommandRegex
+	case "command_not_regex":
+		c = ConditionCommandNotRegex
+	case "git_commit_message_matches":
+		c = ConditionGitCommitMessageMatches
+	case "git_commit_message_not_matches":
+		c = ConditionGitCommitMessageNotMatches
+	case "push_to_remote_is":
+		c = ConditionPushToRemoteIs
+	case "push_is_force":
+		c = ConditionPushIsForce
+	case "push_target_branch_matches":
+		c = ConditionPushTargetBranchMatches
 	case "url_starts_with":
-		*c = ConditionURLStartsWith
+		c = ConditionURLStartsWith
+	case "mcp_server_is":
+		c = ConditionMCPServerIs
+	case "mcp_tool_is":
+		c = ConditionMCPToolIs
+	case "file_size_gt":
+		c = ConditionFileSizeGt
+	case "file_is_binary":
+		c = ConditionFileIsBinary
 	case "prompt_regex":
-		*c = ConditionPromptRegex
+		c = ConditionPromptRegex
 	case "every_n_prompts":
-		*c = ConditionEveryNPrompts
+		c = ConditionEveryNPrompts
+	case "notification_message_contains":
+		c = ConditionNotificationMessageContains
+	case "notification_message_regex":
+		c = ConditionNotificationMessageRegex
 	case "reason_is":
-		*c = ConditionReasonIs
+		c = ConditionReasonIs
+	case "tool_response_contains":
+		c = ConditionToolResponseContains
+	case "tool_error_is_present":
+		c = ConditionToolErrorIsPresent
+	case "exit_code_is":
+		c = ConditionExitCodeIs
+	case "agent_type_is":
+		c = ConditionAgentTypeIs
 	case "git_tracked_file_operation":
-		*c = ConditionGitTrackedFileOperation
+		c = ConditionGitTrackedFileOperation
 	case "cwd_is":
-		*c = ConditionCwdIs
+		c = ConditionCwdIs
 	case "cwd_is_not":
-		*c = ConditionCwdIsNot
+		c = ConditionCwdIsNot
 	case "cwd_contains":
-		*c = ConditionCwdContains
+		c = ConditionCwdContains
 	case "cwd_not_contains":
-		*c = ConditionCwdNotContains
+		c = ConditionCwdNotContains
 	case "permission_mode_is":
-		*c = ConditionPermissionModeIs
+		c = ConditionPermissionModeIs
+	case "file_older_than":
+		c = ConditionFileOlderThan
+	case "file_newer_than":
+		c = ConditionFileNewerThan
+	case "file_sha256_is":
+		c = ConditionFileSHA256Is
+	case "file_content_equals_file":
+		c = ConditionFileContentEqualsFile
+	case "session_duration_lt":
+		c = ConditionSessionDurationLt
+	case "session_duration_gt":
+		c = ConditionSessionDurationGt
+	case "tokens_used_lt":
+		c = ConditionTokensUsedLt
+	case "tokens_used_gt":
+		c = ConditionTokensUsedGt
+	case "last_assistant_message_matches":
+		c = ConditionLastAssistantMatches
+	case "changelog_not_updated":
+		c = ConditionChangelogNotUpdated
+	case "ticket_referenced":
+		c = ConditionTicketReferenced
+	case "in_meeting":
+		c = ConditionInMeeting
+	case "freeze_active":
+		c = ConditionFreezeActive
+	case "digest_pending":
+		c = ConditionDigestPending
+	case "budget_exceeded":
+		c = ConditionBudgetExceeded
+	case "budget_remaining_below":
+		c = ConditionBudgetRemainingBelow
+	case "tool_input_jq":
+		c = ConditionToolInputJQ
+	case "cel":
+		c = ConditionCEL
+	case "git_branch_is":
+		c = ConditionGitBranchIs
+	case "git_branch_matches":
+		c = ConditionGitBranchMatches
+	case "in_devcontainer":
+		c = ConditionInDevcontainer
+	case "git_dirty":
+		c = ConditionGitDirty
+	case "git_clean":
+		c = ConditionGitClean
+	case "git_signing_enabled":
+		c = ConditionGitSigningEnabled
+	case "env_is":
+		c = ConditionEnvIs
+	case "env_set":
+		c = ConditionEnvSet
+	case "env_matches":
+		c = ConditionEnvMatches
+	case "time_between":
+		c = ConditionTimeBetween
+	case "day_of_week":
+		c = ConditionDayOfWeek
+	case "any_of":
+		c = ConditionAnyOf
+	case "all_of":
+		c = ConditionAllOf
+	case "not":
+		c = ConditionNot
 	default:
-		return fmt.Errorf("invalid condition type: %s", s)
+		return ConditionType{}, fmt.Errorf("invalid condition type: %s", s)
 	}
-	return nil
+	return c, nil
+}
+
+// conditionTypes lists every condition type in the order of parseConditionType (used by the config JSON Schema).
+var conditionTypes = []ConditionType{
+	ConditionFileExists,
+	ConditionFileExistsRecursive,
+	ConditionFileNotExists,
+	ConditionFileNotExistsRecursive,
+	ConditionDirExists,
+	ConditionDirExistsRecursive,
+	ConditionDirNotExists,
+	ConditionDirNotExistsRecursive,
+	ConditionFileExtension,
+	ConditionFilePathMatches,
+	ConditionFileIsGitignored,
+	ConditionFileNotGitignored,
+	ConditionContentContains,
+	ConditionContentMatches,
+	ConditionCommandContains,
+	ConditionCommandStartsWith,
+	ConditionCommandRegex,
+	ConditionCommandNotRegex,
+	ConditionGitCommitMessageMatches,
+	ConditionGitCommitMessageNotMatches,
+	ConditionPushToRemoteIs,
+	ConditionPushIsForce,
+	ConditionPushTargetBranchMatches,
+	ConditionURLStartsWith,
+	ConditionMCPServerIs,
+	ConditionMCPToolIs,
+	ConditionFileSizeGt,
+	ConditionFileIsBinary,
+	ConditionPromptRegex,
+	ConditionEveryNPrompts,
+	ConditionNotificationMessageContains,
+	ConditionNotificationMessageRegex,
+	ConditionReasonIs,
+	ConditionToolResponseContains,
+	ConditionToolErrorIsPresent,
+	ConditionExitCodeIs,
+	ConditionAgentTypeIs,
+	ConditionGitTrackedFileOperation,
+	ConditionCwdIs,
+	ConditionCwdIsNot,
+	ConditionCwdContains,
+	ConditionCwdNotContains,
+	ConditionPermissionModeIs,
+	ConditionFileOlderThan,
+	ConditionFileNewerThan,
+	ConditionFileSHA256Is,
+	ConditionFileContentEqualsFile,
+	ConditionSessionDurationLt,
+	ConditionSessionDurationGt,
+	ConditionTokensUsedLt,
+	ConditionTokensUsedGt,
+	ConditionLastAssistantMatches,
+	ConditionChangelogNotUpdated,
+	ConditionTicketReferenced,
+	ConditionInMeeting,
+	ConditionFreezeActive,
+	ConditionDigestPending,
+	ConditionBudgetExceeded,
+	ConditionBudgetRemainingBelow,
+	ConditionToolInputJQ,
+	ConditionCEL,
+	ConditionGitBranchIs,
+	ConditionGitBranchMatches,
+	ConditionInDevcontainer,
+	ConditionGitDirty,
+	ConditionGitClean,
+	ConditionGitSigningEnabled,
+	ConditionEnvIs,
+	ConditionEnvSet,
+	ConditionEnvMatches,
+	ConditionTimeBetween,
+	ConditionDayOfWeek,
+	ConditionAnyOf,
+	ConditionAllOf,
+	ConditionNot,
 }
 
 // MarshalYAML implements yaml.Marshaler for ConditionType
@@ -563,37 +969,147 @@ func (c ConditionType) MarshalYAML() (any, error) {
 }
 
 type Condition struct {
-	Type  ConditionType `yaml:"type"`
-	Value string        `yaml:"value"`
+	Type       ConditionType `yaml:"type" jsonschema:"required"`
+	Value      string        `yaml:"value"`
+	Values     []string      `yaml:"values,omitempty"`                                    // valueの代わりに複数値を指定 (matchで結合)
+	Match      string        `yaml:"match,omitempty" jsonschema:"enum=any,enum=all"`      // "any" (default) or "all": valuesの結合方法
+	Conditions []Condition   `yaml:"conditions,omitempty"`                                // 子条件 (any_of/all_of/notのみ)
+	IgnoreCase bool          `yaml:"ignore_case,omitempty"`                               // 大文字小文字を区別しない (文字列条件のみ)
+	Normalize  string        `yaml:"normalize,omitempty" jsonschema:"enum=nfc,enum=nfkc"` // "nfc" or "nfkc": Unicode正規化してから比較 (文字列条件のみ)
+	TZ         string        `yaml:"tz,omitempty"`                                        // IANAタイムゾーン名 (time_between/day_of_week/freeze_activeのみ, 省略時はローカル)
+	MaxDepth   int           `yaml:"max_depth,omitempty"`                                 // 探索する深さの上限 (*_recursiveのみ, 省略時は無制限)
+	Exclude    []string      `yaml:"exclude,omitempty"`                                   // 探索しないファイル・ディレクトリのglob (*_recursiveのみ)
 }
 
 // Action - 全てのイベントタイプで共通のアクション構造体
 type Action struct {
-	Type               string  `yaml:"type"`
-	Command            string  `yaml:"command,omitempty"`
-	Message            string  `yaml:"message,omitempty"`
-	UseStdin           bool    `yaml:"use_stdin,omitempty"`
-	ExitStatus         *int    `yaml:"exit_status,omitempty"`
-	Continue           *bool   `yaml:"continue,omitempty"`
-	Decision           *string `yaml:"decision,omitempty"`            // "block" only, or omit field entirely (internal: empty string will be omitted from JSON; UserPromptSubmit/PostToolUse)
-	PermissionDecision *string `yaml:"permission_decision,omitempty"` // "allow", "deny", or "ask" (PreToolUse only)
-	Behavior           *string `yaml:"behavior,omitempty"`            // "allow" or "deny" (PermissionRequest only)
-	Interrupt          *bool   `yaml:"interrupt,omitempty"`           // deny時のみ (PermissionRequest only)
-	Reason             *string `yaml:"reason,omitempty"`              // Reason for decision (Stop/SubagentStop/PostToolUse)
-	AdditionalContext  *string `yaml:"additional_context,omitempty"`  // Additional context for Claude (PreToolUse)
+	Type               string            `yaml:"type" jsonschema:"required"`
+	Command            string            `yaml:"command,omitempty"`
+	Args               []string          `yaml:"args,omitempty"` // Program and arguments run without a shell (command only, templated; alternative to command)
+	Env                map[string]string `yaml:"env,omitempty"`  // Environment variables for args (command only, templated)
+	Message            string            `yaml:"message,omitempty"`
+	UseStdin           *bool             `yaml:"use_stdin,omitempty"`                                           // Deprecated: true is the default (stdin: raw), false is stdin: none
+	Stdin              string            `yaml:"stdin,omitempty" jsonschema:"enum=none,enum=raw,enum=enriched"` // "none", "raw" (default) or "enriched": JSON passed to the command on stdin (command only)
+	ExitStatus         *int              `yaml:"exit_status,omitempty"`
+	Continue           *bool             `yaml:"continue,omitempty"`
+	Decision           *string           `yaml:"decision,omitempty" jsonschema:"enum=block,enum="`                                      // "block" only, or omit field entirely (internal: empty string will be omitted from JSON; UserPromptSubmit/PostToolUse)
+	PermissionDecision *string           `yaml:"permission_decision,omitempty" jsonschema:"enum=allow,enum=deny,enum=ask"`              // "allow", "deny", or "ask" (PreToolUse only)
+	Behavior           *string           `yaml:"behavior,omitempty" jsonschema:"enum=allow,enum=deny,enum=ask"`                         // "allow", "deny" or "ask" (PermissionRequest only)
+	Interrupt          *bool             `yaml:"interrupt,omitempty"`                                                                   // deny時のみ (PermissionRequest only)
+	Reason             *string           `yaml:"reason,omitempty"`                                                                      // Reason for decision (Stop/SubagentStop/PostToolUse)
+	AdditionalContext  *string           `yaml:"additional_context,omitempty"`                                                          // Additional context for Claude (PreToolUse)
+	ModelHint          *string           `yaml:"model_hint,omitempty"`                                                                  // Corrective instruction for Claude appended to additionalContext on deny/block (PreToolUse/PostToolUse/UserPromptSubmit)
+	SuggestCommand     *string           `yaml:"suggest_command,omitempty"`                                                             // Alternative command shown on deny/ask; offered via updatedInput on ask (PreToolUse only)
+	UpdatedInput       map[string]any    `yaml:"updated_input,omitempty"`                                                               // Fields overriding tool_input (templated); ignored on deny (PreToolUse/PermissionRequest output only)
+	SystemMessage      *string           `yaml:"system_message,omitempty"`                                                              // Message shown to the user (PreToolUse/PostToolUse/PermissionRequest output only)
+	SuppressOutput     *bool             `yaml:"suppress_output,omitempty"`                                                             // Hide stdout from the transcript (PreToolUse/PostToolUse/PermissionRequest output only)
+	URL                string            `yaml:"url,omitempty"`                                                                         // Request URL (http and ask_external, templated)
+	Method             string            `yaml:"method,omitempty" jsonschema:"enum=GET,enum=POST,enum=PUT,enum=PATCH,enum=DELETE"`      // GET, POST (default), PUT, PATCH or DELETE (http and ask_external)
+	Headers            map[string]string `yaml:"headers,omitempty"`                                                                     // Request headers (http and ask_external, templated)
+	Body               any               `yaml:"body,omitempty"`                                                                        // Request body: a string, or a mapping/list sent as JSON (http and ask_external, templated)
+	Timeout            string            `yaml:"timeout,omitempty"`                                                                     // Request timeout such as "5s" (http, default 10s), test timeout (run_tests, default 10m), or how long to wait for a decision (ask_external, default 50s)
+	Runner             string            `yaml:"runner,omitempty"`                                                                      // Where the command runs: ssh://[user@]host[:port][/dir], docker://container[/dir] or devcontainer (command only, default local)
+	EnvFrom            string            `yaml:"env_from,omitempty"`                                                                    // Load the environment of "direnv" or "nix develop" in cwd before running the command (command only, overrides the hook's)
+	ScanFile           bool              `yaml:"scan_file,omitempty"`                                                                   // Also scan tool_input.file_path after the tool ran (secret_scan only, PostToolUse)
+	FrontmatterSchema  string            `yaml:"frontmatter_schema,omitempty"`                                                          // JSON Schema file the YAML frontmatter must match, relative to cwd (markdown_check only)
+	Terms              []TermRule        `yaml:"terms,omitempty"`                                                                       // Banned terms and their preferred replacements (terminology only)
+	Base               string            `yaml:"base,omitempty"`                                                                        // Git revision to compare against (breaking_change only, default HEAD)
+	Rules              []RewriteRule     `yaml:"rules,omitempty"`                                                                       // Regex substitutions applied to tool_input.command in order (rewrite_command only)
+	DenyIfNoMatch      bool              `yaml:"deny_if_no_match,omitempty"`                                                            // Deny commands no rule matches (rewrite_command only)
+	Ledger             string            `yaml:"ledger,omitempty"`                                                                      // Time ledger file (time_tracking only, default $XDG_DATA_HOME/cchook/time.jsonl)
+	Project            string            `yaml:"project,omitempty"`                                                                     // Project of the timer (time_tracking only, templated, default the repository name)
+	Tags               []string          `yaml:"tags,omitempty"`                                                                        // Tags of the timer (time_tracking only, templated)
+	Flush              bool              `yaml:"flush,omitempty"`                                                                       // Flush the queued notifications into {.digest} instead of queueing (digest only)
+	Policy             string            `yaml:"policy,omitempty"`                                                                      // Rego policy file evaluated with the event JSON as input, relative to cwd (opa only)
+	Query              string            `yaml:"query,omitempty"`                                                                       // Rego query whose object result is the hook output (opa only, default data.cchook.decision)
+	Formatters         map[string]string `yaml:"formatters,omitempty"`                                                                  // Formatter command by file extension, run with the file path appended ("" disables; format_file only, overrides the built-in ones)
+	Linters            map[string]string `yaml:"linters,omitempty"`                                                                     // Linter command by file extension, run with the file path appended ("" disables; lint_feedback only, overrides the built-in ones)
+	MaxLines           int               `yaml:"max_lines,omitempty"`                                                                   // Lines of the linter or test output given to Claude (lint_feedback and run_tests, default 30, -1 unlimited)
+	MaxBytes           int               `yaml:"max_bytes,omitempty"`                                                                   // Bytes of the linter or test output given to Claude (lint_feedback and run_tests, default 4000, -1 unlimited)
+	Mode               string            `yaml:"mode,omitempty" jsonschema:"enum=commit,enum=stash"`                                    // "commit" (default) or "stash": how the checkpoint is recorded (git_checkpoint only)
+	Repos              []string          `yaml:"repos,omitempty"`                                                                       // Repository roots allowed to be checkpointed, as absolute path globs with ~ (git_checkpoint only, required)
+	SideEffects        []string          `yaml:"side_effects,omitempty" jsonschema:"enum=writes_files,enum=network,enum=notifications"` // Side effects of the action shown by dry-run (writes_files, network, notifications)
+}
+
+// LogConfig is the `log:` block that records every hook evaluation to a file.
+type LogConfig struct {
+	Path   string `yaml:"path,omitempty"`                                                         // ログファイル (省略時はユーザーキャッシュディレクトリのcchook/cchook.log)
+	Level  string `yaml:"level,omitempty" jsonschema:"enum=debug,enum=info,enum=warn,enum=error"` // debug, info (default), warn, error
+	Format string `yaml:"format,omitempty" jsonschema:"enum=json,enum=text"`                      // json (default) or text
+}
+
+// DecisionLogConfig is the `decision_log:` block that exports the decision of every event as a CloudEvents or OCSF record.
+type DecisionLogConfig struct {
+	Format           string            `yaml:"format,omitempty" jsonschema:"enum=cloudevents,enum=ocsf"` // cloudevents (default) or ocsf
+	Path             string            `yaml:"path,omitempty"`                                           // 1行1レコードで追記するファイル
+	URL              string            `yaml:"url,omitempty"`                                            // レコードをPOSTするURL ($VARを展開)
+	Headers          map[string]string `yaml:"headers,omitempty"`                                        // POSTのヘッダー ($VARを展開)
+	Timeout          string            `yaml:"timeout,omitempty"`                                        // POSTのタイムアウト (default 5s)
+	IncludeToolInput bool              `yaml:"include_tool_input,omitempty"`                             // イベントのtool_inputを出力する (秘密情報はマスク)
+}
+
+// ProjectTrustConfig is the `project_config:` block: the repositories whose .cchook.yaml is trusted.
+type ProjectTrustConfig struct {
+	Trusted      []string `yaml:"trusted,omitempty"`       // .cchook.yamlを読み込むディレクトリ (絶対パスか~/で始まるglob)
+	AllowReplace bool     `yaml:"allow_replace,omitempty"` // merge: replaceでグローバルのフックを置き換えることを許可する
+}
+
+// ReportConfig is the `report:` block: the team endpoint `cchook -command report push` uploads decision stats to.
+type ReportConfig struct {
+	URL     string            `yaml:"url" jsonschema:"required"` // 統計をPOSTするチームのエンドポイント ($VARを展開)
+	Headers map[string]string `yaml:"headers,omitempty"`         // POSTのヘッダー ($VARを展開)
+	Timeout string            `yaml:"timeout,omitempty"`         // POSTのタイムアウト (default 10s)
+	Team    string            `yaml:"team,omitempty"`            // 統計に付けるチーム名
+}
+
+// BudgetConfig is the `budget:` block: token and cost limits checked by the budget conditions.
+type BudgetConfig struct {
+	SessionTokens string                `yaml:"session_tokens,omitempty"` // 1セッションのトークン数の上限 (200000, 200k, 1.5M)
+	DailyTokens   string                `yaml:"daily_tokens,omitempty"`   // 1日 (ローカル時刻) の全セッションのトークン数の上限
+	SessionCost   float64               `yaml:"session_cost,omitempty"`   // 1セッションのコストの上限 (USD)
+	DailyCost     float64               `yaml:"daily_cost,omitempty"`     // 1日の全セッションのコストの上限 (USD)
+	Prices        map[string]ModelPrice `yaml:"prices,omitempty"`         // モデル名の前方一致 → 価格 (組み込みの価格表より優先)
+}
+
+// ModelPrice is the price of a model in USD per million tokens.
+// Zero cache prices default to 1.25x (write) and 0.1x (read) the input price.
+type ModelPrice struct {
+	Input      float64 `yaml:"input" jsonschema:"required"`
+	Output     float64 `yaml:"output" jsonschema:"required"`
+	CacheWrite float64 `yaml:"cache_write,omitempty"`
+	CacheRead  float64 `yaml:"cache_read,omitempty"`
+}
+
+// TermRule is a term a terminology action reports, with the term to use instead.
+type TermRule struct {
+	Term      string `yaml:"term" jsonschema:"required"` // 使わない表記 (大文字小文字を区別しない)
+	Preferred string `yaml:"preferred,omitempty"`        // 代わりに使う表記 (この表記そのものは報告しない)
+	Note      string `yaml:"note,omitempty"`             // 理由など、報告に添える説明
 }
 
 // 設定ファイル構造
 type Config struct {
-	PreToolUse        []PreToolUseHook        `yaml:"PreToolUse,omitempty"`
-	PostToolUse       []PostToolUseHook       `yaml:"PostToolUse,omitempty"`
-	PermissionRequest []PermissionRequestHook `yaml:"PermissionRequest,omitempty"`
-	Notification      []NotificationHook      `yaml:"Notification,omitempty"`
-	Stop              []StopHook              `yaml:"Stop,omitempty"`
-	SubagentStop      []SubagentStopHook      `yaml:"SubagentStop,omitempty"`
-	SubagentStart     []SubagentStartHook     `yaml:"SubagentStart,omitempty"`
-	PreCompact        []PreCompactHook        `yaml:"PreCompact,omitempty"`
-	SessionStart      []SessionStartHook      `yaml:"SessionStart,omitempty"`
-	SessionEnd        []SessionEndHook        `yaml:"SessionEnd,omitempty"`
-	UserPromptSubmit  []UserPromptSubmitHook  `yaml:"UserPromptSubmit,omitempty"`
+	Include           []string                 `yaml:"include,omitempty"`                                                        // 他の設定ファイル (相対パスは設定ファイルのディレクトリ基準、glob可)
+	Merge             map[HookEventType]string `yaml:"merge,omitempty"`                                                          // イベント毎のマージ方法 (append/replace, .cchook.yamlのみ)
+	Log               *LogConfig               `yaml:"log,omitempty"`                                                            // フック評価のログ出力 (メインの設定ファイルのみ)
+	OutputFormat      string                   `yaml:"output_format,omitempty" jsonschema:"enum=indent2,enum=compact"`           // 出力JSONの形式 (indent2/compact, メインの設定ファイルのみ)
+	StrictPermissions string                   `yaml:"strict_permissions,omitempty" jsonschema:"enum=warn,enum=refuse,enum=off"` // グループ/他人が書き込める設定・スクリプトの扱い (warn/refuse/off, メインの設定ファイルのみ)
+	OnGitUnavailable  string                   `yaml:"on_git_unavailable,omitempty" jsonschema:"enum=skip,enum=warn,enum=deny"`  // gitが無い・リポジトリ外でのgit条件の扱い (skip/warn/deny, メインの設定ファイルのみ)
+	SelfTest          bool                     `yaml:"self_test,omitempty"`                                                      // SessionStartでcommandアクションのコマンドの有無をセッション毎に1回確認 (メインの設定ファイルのみ)
+	Budget            *BudgetConfig            `yaml:"budget,omitempty"`                                                         // トークン・コストの上限 (budget_exceeded/budget_remaining_below, メインの設定ファイルのみ)
+	DecisionLog       *DecisionLogConfig       `yaml:"decision_log,omitempty"`                                                   // 判定のCloudEvents/OCSF形式での出力 (メインの設定ファイルのみ)
+	Report            *ReportConfig            `yaml:"report,omitempty"`                                                         // report pushの送信先 (メインの設定ファイルのみ)
+	ProjectConfig     *ProjectTrustConfig      `yaml:"project_config,omitempty"`                                                 // 読み込む.cchook.yamlの許可リスト (メインの設定ファイルのみ)
+	Files             []string                 `yaml:"-"`                                                                        // 読み込んだ設定ファイル (include・.cchook.yamlを含む絶対パス)
+	PreToolUse        []PreToolUseHook         `yaml:"PreToolUse,omitempty"`
+	PostToolUse       []PostToolUseHook        `yaml:"PostToolUse,omitempty"`
+	PermissionRequest []PermissionRequestHook  `yaml:"PermissionRequest,omitempty"`
+	Notification      []NotificationHook       `yaml:"Notification,omitempty"`
+	Stop              []StopHook               `yaml:"Stop,omitempty"`
+	SubagentStop      []SubagentStopHook       `yaml:"SubagentStop,omitempty"`
+	SubagentStart     []SubagentStartHook      `yaml:"SubagentStart,omitempty"`
+	PreCompact        []PreCompactHook         `yaml:"PreCompact,omitempty"`
+	SessionStart      []SessionStartHook       `yaml:"SessionStart,omitempty"`
+	SessionEnd        []SessionEndHook         `yaml:"SessionEnd,omitempty"`
+	UserPromptSubmit  []UserPromptSubmitHook   `yaml:"UserPromptSubmit,omitempty"`
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -684,7 +685,7 @@ func TestAction_UnmarshalYAML(t *testing.T) {
 	tests := []struct {
 		name         string
 		yamlContent  string
-		wantUseStdin bool
+		wantUseStdin *bool
 		wantType     string
 		wantCommand  string
 	}{
@@ -695,7 +696,7 @@ type: command
 command: "python process.py"
 use_stdin: true
 `,
-			wantUseStdin: true,
+			wantUseStdin: boolPtr(true),
 			wantType:     "command",
 			wantCommand:  "python process.py",
 		},
@@ -706,17 +707,17 @@ type: command
 command: "echo 'test'"
 use_stdin: false
 `,
-			wantUseStdin: false,
+			wantUseStdin: boolPtr(false),
 			wantType:     "command",
 			wantCommand:  "echo 'test'",
 		},
 		{
-			name: "use_stdin omitted (default false)",
+			name: "use_stdin omitted",
 			yamlContent: `
 type: command
 command: "ls -la"
 `,
-			wantUseStdin: false,
+			wantUseStdin: nil,
 			wantType:     "command",
 			wantCommand:  "ls -la",
 		},
@@ -727,7 +728,7 @@ type: output
 message: "Warning message"
 use_stdin: true
 `,
-			wantUseStdin: true,
+			wantUseStdin: boolPtr(true),
 			wantType:     "output",
 			wantCommand:  "",
 		},
@@ -741,7 +742,7 @@ use_stdin: true
 				t.Fatalf("Failed to unmarshal YAML: %v", err)
 			}
 
-			if action.UseStdin != tt.wantUseStdin {
+			if !reflect.DeepEqual(action.UseStdin, tt.wantUseStdin) {
 				t.Errorf("UseStdin: expected %v, got %v", tt.wantUseStdin, action.UseStdin)
 			}
 			if action.Type != tt.wantType {
@@ -806,7 +807,7 @@ func TestPreToolUseAction_UnmarshalYAML_WithAction(t *testing.T) {
 	tests := []struct {
 		name         string
 		yamlContent  string
-		wantUseStdin bool
+		wantUseStdin *bool
 		wantType     string
 		wantCommand  string
 	}{
@@ -817,7 +818,7 @@ type: command
 command: "python validate.py"
 use_stdin: true
 `,
-			wantUseStdin: true,
+			wantUseStdin: boolPtr(true),
 			wantType:     "command",
 			wantCommand:  "python validate.py",
 		},
@@ -827,7 +828,7 @@ use_stdin: true
 type: output
 message: "Test message"
 `,
-			wantUseStdin: false,
+			wantUseStdin: nil,
 			wantType:     "output",
 			wantCommand:  "",
 		},
@@ -841,7 +842,7 @@ message: "Test message"
 				t.Fatalf("Failed to unmarshal YAML: %v", err)
 			}
 
-			if action.UseStdin != tt.wantUseStdin {
+			if !reflect.DeepEqual(action.UseStdin, tt.wantUseStdin) {
 				t.Errorf("UseStdin: expected %v, got %v", tt.wantUseStdin, action.UseStdin)
 			}
 			if action.Type != tt.wantType {
@@ -2,35 +2,121 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-git/go-git/v5"
-	"mvdan.cc/sh/v3/shell"
+	"mvdan.cc/sh/v3/expand"
 	"mvdan.cc/sh/v3/syntax"
 )
 
 // parseInput関数は parser.go に移動
 
+// 正規表現マッチャーのキャッシュ（同じmatcherを毎回コンパイルしないため）
+var (
+	matcherRegexCache = make(map[string]*regexp.Regexp)
+	matcherRegexMutex sync.RWMutex
+)
+
+// matcherAlternatives splits matcher at the "|" outside of groups and character classes, so that a regex
+// alternative such as "mcp__(github|gitlab)__.*" stays whole. The alternatives are trimmed.
+func matcherAlternatives(matcher string) []string {
+	var alternatives []string
+	depth, inClass, start := 0, false, 0
+	for i := 0; i < len(matcher); i++ {
+		switch c := matcher[i]; {
+		case c == '\\':
+			i++ // エスケープされた文字は区切りにならない
+		case inClass:
+			inClass = c != ']'
+		case c == '[':
+			inClass = true
+		case c == '(':
+			depth++
+		case c == ')':
+			depth = max(depth-1, 0)
+		case c == '|' && depth == 0:
+			alternatives = append(alternatives, strings.TrimSpace(matcher[start:i]))
+			start = i + 1
+		}
+	}
+	return append(alternatives, strings.TrimSpace(matcher[start:]))
+}
+
+// isRegexPattern reports whether an alternative of a matcher is a regular expression rather than a name,
+// i.e. whether it contains a regex metacharacter ("*" alone matches any name).
+func isRegexPattern(pattern string) bool {
+	return pattern != "*" && strings.ContainsAny(pattern, `.*+?()[]{}^$\`)
+}
+
+// compileMatcherRegex compiles a regex alternative of a matcher (cached). The regex must match the whole name.
+func compileMatcherRegex(matcher string) (*regexp.Regexp, error) {
+	matcherRegexMutex.RLock()
+	re, exists := matcherRegexCache[matcher]
+	matcherRegexMutex.RUnlock()
+	if exists {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(`^(?:` + matcher + `)$`)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex matcher %q: %w", matcher, err)
+	}
+	matcherRegexMutex.Lock()
+	matcherRegexCache[matcher] = re
+	matcherRegexMutex.Unlock()
+	return re, nil
+}
+
 // checkMatcher checks if the tool name matches the matcher pattern.
-// Supports pipe-separated patterns with partial matching.
+// Supports pipe-separated patterns with partial matching. "*" matches any tool.
+// Each alternative is matched on its own: one containing regex metacharacters (e.g. "mcp__.*__write.*")
+// is a regular expression that must match the whole tool name (an invalid regex matches nothing),
+// while plain names keep partial matching, so "Edit|mcp__.*" still matches "MultiEdit".
 func checkMatcher(matcher string, toolName string) bool {
 	if matcher == "" {
 		return true
 	}
 
-	for _, pattern := range strings.Split(matcher, "|") {
-		if strings.Contains(toolName, strings.TrimSpace(pattern)) {
+	for _, pattern := range matcherAlternatives(matcher) {
+		switch {
+		case pattern == "*":
+			return true
+		case isRegexPattern(pattern):
+			if re, err := compileMatcherRegex(pattern); err == nil && re.MatchString(toolName) {
+				return true
+			}
+		case strings.Contains(toolName, pattern):
 			return true
 		}
 	}
 	return false
 }
 
+// checkToolMatcher checks the matcher of a tool event hook, excluding the tools listed in excludeTools (exact names)
+// and, when mcpServer is set, tools that aren't MCP tools of those servers.
+func checkToolMatcher(matcher string, excludeTools []string, mcpServer string, toolName string) bool {
+	if slices.Contains(excludeTools, toolName) || !checkMCPServerMatcher(mcpServer, toolName) {
+		return false
+	}
+	return checkMatcher(matcher, toolName)
+}
+
 // checkNotificationMatcher checks if the notification_type matches the matcher pattern.
 // Unlike checkMatcher, this uses exact matching instead of partial matching
 // to prevent "idle" from matching "idle_prompt".
@@ -48,42 +134,147 @@ func checkNotificationMatcher(matcher string, notificationType string) bool {
 	return false
 }
 
-// existsRecursive recursively searches for a file or directory by name.
-// If isDir is true, it searches for directories; otherwise, it searches for files.
-func existsRecursive(name string, isDir bool) bool {
-	if name == "" {
+// fileExistsRecursive recursively searches for a file named like the condition value in the directory tree.
+func fileExistsRecursive(condition Condition) (bool, error) {
+	return existsRecursive(condition, false)
+}
+
+// fileExists checks if a file exists at the specified path.
+func fileExists(path string) bool {
+	if path == "" {
 		return false
 	}
+	_, err := os.Stat(path)
+	return err == nil
+}
 
-	found := false
-	err := filepath.Walk(".", func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil // エラーがあっても続ける
-		}
-		if info.IsDir() == isDir && filepath.Base(path) == name {
-			found = true
-			return filepath.SkipAll // 見つかったら探索を終了
+// fileOlderThan parses value as "<path> <duration>" (e.g. "go.sum 24h", "docs/api.md 7d")
+// and reports whether the file was last modified more than duration ago.
+// exists is false when the file does not exist.
+func fileOlderThan(value string) (older bool, exists bool, err error) {
+	path, duration, err := parseFileAgeValue(value)
+	if err != nil {
+		return false, false, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, false, nil
+	}
+	return time.Since(info.ModTime()) > duration, true, nil
+}
+
+// parseFileAgeValue splits a file_older_than / file_newer_than value into its path and duration.
+func parseFileAgeValue(value string) (string, time.Duration, error) {
+	idx := strings.LastIndexAny(strings.TrimSpace(value), " \t")
+	if idx < 0 {
+		return "", 0, fmt.Errorf("invalid value %q: expected \"<path> <duration>\"", value)
+	}
+	path := strings.TrimSpace(value[:idx])
+	duration, err := parseDurationWithDays(strings.TrimSpace(value)[idx+1:])
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid duration in %q: %w", value, err)
+	}
+	return path, duration, nil
+}
+
+// splitPathArguments splits a condition value into exactly n shell words
+// (quotes and environment variables such as $HOME are supported).
+func splitPathArguments(value string, n int, usage string) ([]string, error) {
+	fields, err := splitCommandFields(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q: %w", value, err)
+	}
+	if len(fields) != n {
+		return nil, fmt.Errorf("invalid value %q: expected %s", value, usage)
+	}
+	return fields, nil
+}
+
+// parseEnvConditionValue splits an env_is / env_matches value "NAME=value" into the variable name and the value
+// or pattern. An env_set value is the name alone.
+func parseEnvConditionValue(conditionType ConditionType, value string) (name, operand string, err error) {
+	name = value
+	if conditionType != ConditionEnvSet {
+		var ok bool
+		name, operand, ok = strings.Cut(value, "=")
+		if !ok {
+			return "", "", fmt.Errorf("invalid value %q: expected \"NAME=value\"", value)
 		}
-		return nil
-	})
+	}
+	if !envVarNamePattern.MatchString(name) {
+		return "", "", fmt.Errorf("invalid environment variable name %q", name)
+	}
+	return name, operand, nil
+}
+
+// fileSHA256Is parses value as "<path> <sha256 hex>" and reports whether the file's SHA-256 matches.
+// A missing file does not match.
+func fileSHA256Is(value string) (bool, error) {
+	args, err := splitPathArguments(value, 2, `"<path> <sha256>"`)
 	if err != nil {
-		return false
+		return false, err
 	}
-	return found
+	want := strings.ToLower(args[1])
+	if len(want) != sha256.Size*2 {
+		return false, fmt.Errorf("invalid sha256 %q: expected %d hex characters", args[1], sha256.Size*2)
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return false, nil
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", args[0], err)
+	}
+	return hex.EncodeToString(h.Sum(nil)) == want, nil
 }
 
-// fileExistsRecursive recursively searches for a file by name in the directory tree.
-func fileExistsRecursive(filename string) bool {
-	return existsRecursive(filename, false)
+// fileContentEqualsFile parses value as "<path> <blessed path>" and reports whether both files have the same content.
+// A missing file does not match.
+func fileContentEqualsFile(value string) (bool, error) {
+	args, err := splitPathArguments(value, 2, `"<path> <blessed path>"`)
+	if err != nil {
+		return false, err
+	}
+	got, err := os.ReadFile(args[0])
+	if err != nil {
+		return false, nil
+	}
+	want, err := os.ReadFile(args[1])
+	if err != nil {
+		return false, nil
+	}
+	return bytes.Equal(got, want), nil
 }
 
-// fileExists checks if a file exists at the specified path.
-func fileExists(path string) bool {
-	if path == "" {
-		return false
+// parseDurationWithDays parses a duration like time.ParseDuration, additionally accepting
+// days ("7d") and weeks ("2w"). Negative durations are rejected.
+func parseDurationWithDays(s string) (time.Duration, error) {
+	var d time.Duration
+	var err error
+	switch {
+	case strings.HasSuffix(s, "d") || strings.HasSuffix(s, "w"):
+		unit := 24 * time.Hour
+		if strings.HasSuffix(s, "w") {
+			unit = 7 * 24 * time.Hour
+		}
+		var n float64
+		n, err = strconv.ParseFloat(s[:len(s)-1], 64)
+		d = time.Duration(n * float64(unit))
+	default:
+		d, err = time.ParseDuration(s)
 	}
-	_, err := os.Stat(path)
-	return err == nil
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("negative duration %q", s)
+	}
+	return d, nil
 }
 
 // dirExists checks if a directory exists at the specified path.
@@ -98,16 +289,16 @@ func dirExists(path string) bool {
 	return info.IsDir()
 }
 
-// dirExistsRecursive recursively searches for a directory by name in the directory tree.
-func dirExistsRecursive(dirname string) bool {
-	return existsRecursive(dirname, true)
+// dirExistsRecursive recursively searches for a directory named like the condition value in the directory tree.
+func dirExistsRecursive(condition Condition) (bool, error) {
+	return existsRecursive(condition, true)
 }
 
 // findGitRepository finds the Git repository containing the given path.
 func findGitRepository(path string) (*git.Repository, error) {
 	dir := filepath.Dir(path)
 	for {
-		repo, err := git.PlainOpen(dir)
+		repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
 		if err == nil {
 			return repo, nil
 		}
@@ -175,14 +366,18 @@ func checkGitTrackedFileOperation(command string, blockedOps string) (bool, erro
 	}
 
 	// プロセス置換 <() や >() を事前チェック
-	// shell.Fieldsはプロセス置換を展開できずpanicするため
+	// expand.Fieldsはプロセス置換を展開できずpanicするため
 	if containsProcessSubstitution(command) {
 		return false, ErrProcessSubstitutionDetected
 	}
 
 	// コマンドラインをパース（環境変数展開あり）
-	args, err := shell.Fields(command, nil)
+	args, err := splitCommandFields(command)
 	if err != nil {
+		// 巨大なブレース展開は安全側に倒してエラーとする
+		if errors.Is(err, ErrBraceExpansionTooLarge) {
+			return false, err
+		}
 		// パースエラーの場合は条件にマッチしないとする
 		return false, nil
 	}
@@ -240,7 +435,7 @@ func checkGitTrackedFileOperation(command string, blockedOps string) (bool, erro
 
 	// 各ファイルがGit管理下にあるかチェック
 	for _, filePath := range filePaths {
-		tracked, err := isGitTracked(filePath)
+		tracked, err := cachedGitTracked(filePath)
 		if err != nil {
 			// エラーは無視して続行
 			continue
@@ -267,9 +462,32 @@ func (r *realCommandRunner) RunCommandWithOutput(cmd string, useStdin bool, data
 	return runCommandWithOutput(cmd, useStdin, data)
 }
 
+// RunArgsWithOutput implements ArgvCommandRunner.RunArgsWithOutput
+func (r *realCommandRunner) RunArgsWithOutput(args, env []string, useStdin bool, data any) (stdout, stderr string, exitCode int, err error) {
+	return runArgsWithOutput(args, env, useStdin, data)
+}
+
+// RunCommandWithTimeout implements TimeoutCommandRunner.RunCommandWithTimeout
+func (r *realCommandRunner) RunCommandWithTimeout(cmd string, timeout time.Duration, useStdin bool, data any) (stdout, stderr string, exitCode int, err error) {
+	return runCommandWithTimeout(cmd, timeout, useStdin, data)
+}
+
 // DefaultCommandRunner is the default implementation used in production.
+// Hooks run command actions with it, so replacing it routes every command action through another CommandRunner
+// (as the dry-run chaos mode and explain do).
 var DefaultCommandRunner CommandRunner = &realCommandRunner{}
 
+// CommandRunner/HTTPClientの実装であることをコンパイル時に保証する
+var (
+	_ CommandRunner        = (*realCommandRunner)(nil)
+	_ ArgvCommandRunner    = (*realCommandRunner)(nil)
+	_ TimeoutCommandRunner = (*realCommandRunner)(nil)
+	_ CommandRunner        = (*chaosCommandRunner)(nil)
+	_ CommandRunner        = explainCommandRunner{}
+	_ HTTPClient           = (*chaosHTTPClient)(nil)
+	_ HTTPClient           = explainHTTPClient{}
+)
+
 // runCommand executes a shell command with optional JSON data passed via stdin.
 func runCommand(command string, useStdin bool, data any) error {
 	if strings.TrimSpace(command) == "" {
@@ -300,8 +518,31 @@ func runCommandWithOutput(command string, useStdin bool, data any) (stdout strin
 	}
 
 	// シェル経由でコマンドを実行
-	cmd := exec.Command("sh", "-c", command)
+	return captureCommandOutput(exec.Command("sh", "-c", command), useStdin, data)
+}
+
+// runCommandWithTimeout executes a command like runCommandWithOutput and kills it (with the processes it started)
+// when it runs longer than timeout.
+func runCommandWithTimeout(command string, timeout time.Duration, useStdin bool, data any) (stdout string, stderr string, exitCode int, err error) {
+	if strings.TrimSpace(command) == "" {
+		return "", "", 1, fmt.Errorf("empty command")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	killProcessGroupOnCancel(cmd)
+	// 殺したプロセスの子孫が出力を握ったままでも待ち続けない
+	cmd.WaitDelay = time.Second
+	stdout, stderr, exitCode, err = captureCommandOutput(cmd, useStdin, data)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return stdout, stderr, 1, fmt.Errorf("%w after %s", ErrCommandTimedOut, timeout)
+	}
+	return stdout, stderr, exitCode, err
+}
 
+// captureCommandOutput runs cmd and captures stdout, stderr, and exit code.
+func captureCommandOutput(cmd *exec.Cmd, useStdin bool, data any) (stdout string, stderr string, exitCode int, err error) {
 	// stdout/stderrをキャプチャするためのバッファ
 	var outBuf, errBuf bytes.Buffer
 	cmd.Stdout = &outBuf
@@ -370,3 +611,85 @@ func containsProcessSubstitution(command string) bool {
 
 	return found
 }
+
+// maxCommandFields is the upper bound of fields produced by brace expansion while tokenizing a command.
+// {1..100000000} や {a,b}{a,b}... のような入力でハングやメモリ枯渇を起こさないための上限
+const maxCommandFields = 10000
+
+// splitCommandFields tokenizes a command line like shell.Fields (with environment variable expansion),
+// but returns ErrBraceExpansionTooLarge instead of expanding braces into more than maxCommandFields fields.
+func splitCommandFields(command string) ([]string, error) {
+	p := syntax.NewParser()
+	var words []*syntax.Word
+	total := 0
+	for w, err := range p.WordsSeq(strings.NewReader(command)) {
+		if err != nil {
+			return nil, err
+		}
+		// SplitBracesはPartsを置き換えるのでコピーに対して実行する
+		word := *w
+		count := 1
+		if syntax.SplitBraces(&word) {
+			count = braceExpansionCount(word.Parts)
+		}
+		total += count
+		if total > maxCommandFields {
+			return nil, ErrBraceExpansionTooLarge
+		}
+		words = append(words, w)
+	}
+	cfg := &expand.Config{Env: expand.FuncEnviron(os.Getenv)}
+	return expand.Fields(cfg, words...)
+}
+
+// braceExpansionCount returns the number of fields the word parts expand to, saturating above maxCommandFields.
+func braceExpansionCount(parts []syntax.WordPart) int {
+	count := 1
+	for _, part := range parts {
+		br, ok := part.(*syntax.BraceExp)
+		if !ok {
+			continue
+		}
+		count *= braceElemsCount(br)
+		if count > maxCommandFields {
+			return maxCommandFields + 1
+		}
+	}
+	return count
+}
+
+// braceElemsCount returns the number of alternatives of a single brace expression.
+func braceElemsCount(br *syntax.BraceExp) int {
+	if !br.Sequence {
+		count := 0
+		for _, elem := range br.Elems {
+			count += braceExpansionCount(elem.Parts)
+			if count > maxCommandFields {
+				return maxCommandFields + 1
+			}
+		}
+		return count
+	}
+
+	// {x..y[..incr]} の要素数を展開せずに計算する
+	if len(br.Elems) < 2 {
+		return 1
+	}
+	from, err1 := strconv.Atoi(br.Elems[0].Lit())
+	to, err2 := strconv.Atoi(br.Elems[1].Lit())
+	if err1 != nil || err2 != nil {
+		// 文字のシーケンス（{a..z}）は高々256要素
+		return 256
+	}
+	incr := 1.0
+	if len(br.Elems) > 2 {
+		if n, err := strconv.Atoi(br.Elems[2].Lit()); err == nil && n != 0 {
+			incr = math.Abs(float64(n))
+		}
+	}
+	span := math.Abs(float64(to)-float64(from))/incr + 1
+	if span > maxCommandFields {
+		return maxCommandFields + 1
+	}
+	return int(span)
+}
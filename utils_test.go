@@ -2,8 +2,11 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -25,6 +28,18 @@ func TestCheckMatcher(t *testing.T) {
 		{"Whitespace handling", " Write | Edit ", "Write", true},
 		{"Case sensitive", "write", "Write", false},
 		{"Complex tool name", "Multi", "MultiEdit", true},
+		{"Wildcard matches any tool", "*", "mcp__github__create_issue", true},
+		{"Wildcard among patterns", "Write|*", "Read", true},
+		{"Regex matches MCP namespace", "mcp__.*__write.*", "mcp__fs__write_file", true},
+		{"Regex must match the whole name", "mcp__.*__write.*", "x_mcp__fs__write_file", false},
+		{"Regex does not match other tools", "mcp__.*__write.*", "mcp__fs__read_file", false},
+		{"Regex with alternatives", "Notebook.*|Write", "NotebookEdit", true},
+		{"Plain alternative next to a regex keeps partial matching", "Edit|mcp__.*", "MultiEdit", true},
+		{"Regex alternative next to a name must match the whole name", "Edit|mcp__.*", "x_mcp__fs__read", false},
+		{"Regex group alternatives stay in the regex", "mcp__(github|gitlab)__.*", "mcp__gitlab__create_issue", true},
+		{"Regex group alternatives are not partial names", "mcp__(github|gitlab)__.*", "github", false},
+		{"Escaped pipe is part of the regex", `a\|b`, "a|b", true},
+		{"Invalid regex matches nothing", "mcp__(.*", "mcp__(x", false},
 	}
 
 	for _, tt := range tests {
@@ -36,6 +51,52 @@ func TestCheckMatcher(t *testing.T) {
 	}
 }
 
+func TestMatcherAlternatives(t *testing.T) {
+	tests := []struct {
+		matcher string
+		want    []string
+	}{
+		{"Write", []string{"Write"}},
+		{" Write | Edit ", []string{"Write", "Edit"}},
+		{"Explore|", []string{"Explore", ""}},
+		{"Edit|mcp__(github|gitlab)__.*", []string{"Edit", "mcp__(github|gitlab)__.*"}},
+		{"[|]x|Write", []string{"[|]x", "Write"}},
+		{`a\|b|c`, []string{`a\|b`, "c"}},
+	}
+	for _, tt := range tests {
+		if got := matcherAlternatives(tt.matcher); !slices.Equal(got, tt.want) {
+			t.Errorf("matcherAlternatives(%q) = %q, want %q", tt.matcher, got, tt.want)
+		}
+	}
+}
+
+func TestCheckToolMatcher(t *testing.T) {
+	readOnly := []string{"Read", "Glob", "Grep"}
+	tests := []struct {
+		name         string
+		matcher      string
+		excludeTools []string
+		toolName     string
+		want         bool
+	}{
+		{"Wildcard without exclusions", "*", nil, "Read", true},
+		{"Wildcard excludes read-only tool", "*", readOnly, "Grep", false},
+		{"Wildcard keeps other tools", "*", readOnly, "Bash", true},
+		{"Exclusion is exact match", "*", readOnly, "ReadNotebook", true},
+		{"Exclusion with empty matcher", "", readOnly, "Read", false},
+		{"Exclusion with pattern matcher", "Write|Edit", []string{"Edit"}, "Edit", false},
+		{"Matcher still applies", "Write", readOnly, "Bash", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkToolMatcher(tt.matcher, tt.excludeTools, "", tt.toolName); got != tt.want {
+				t.Errorf("checkToolMatcher(%q, %v, %q) = %v, want %v", tt.matcher, tt.excludeTools, tt.toolName, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestCheckNotificationMatcher(t *testing.T) {
 	tests := []struct {
 		name             string
@@ -75,19 +136,8 @@ func TestParseInput_Success(t *testing.T) {
 		"tool_input": {"file_path": "test.go"}
 	}`
 
-	// 標準入力をバックアップして復元
-	oldStdin := os.Stdin
-	defer func() { os.Stdin = oldStdin }()
-
-	// パイプを作成して標準入力として設定
-	r, w, _ := os.Pipe()
-	os.Stdin = r
-
 	// JSONデータを書き込み
-	go func() {
-		defer func() { _ = w.Close() }()
-		_, _ = w.Write([]byte(jsonInput))
-	}()
+	withStdin(t, jsonInput)
 
 	// parseInputをテスト
 	result, _, err := parseInput[*PreToolUseInput](PreToolUse)
@@ -108,19 +158,8 @@ func TestParseInput_InvalidJSON(t *testing.T) {
 	// 不正なJSONを標準入力にセット
 	invalidJSON := `{"invalid": json}`
 
-	// 標準入力をバックアップして復元
-	oldStdin := os.Stdin
-	defer func() { os.Stdin = oldStdin }()
-
-	// パイプを作成
-	r, w, _ := os.Pipe()
-	os.Stdin = r
-
 	// 不正なJSONを書き込み
-	go func() {
-		defer func() { _ = w.Close() }()
-		_, _ = w.Write([]byte(invalidJSON))
-	}()
+	withStdin(t, invalidJSON)
 
 	// parseInputをテスト(エラーが期待される)
 	_, _, err := parseInput[*PreToolUseInput](PreToolUse)
@@ -133,6 +172,43 @@ func TestParseInput_InvalidJSON(t *testing.T) {
 	}
 }
 
+func TestParseInput_NormalizesFilePath(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip(err)
+	}
+	tests := []struct {
+		filePath string
+		cwd      string
+		want     string
+	}{
+		{"src/./main.go", "/repo", "/repo/src/main.go"},
+		{"./src//app/../main.go", "/repo/", "/repo/src/main.go"},
+		{"/repo/src/../../etc/passwd", "/repo", "/etc/passwd"},
+		{"~/notes.md", "/repo", filepath.Join(home, "notes.md")},
+		{"src/main.go", "", "src/main.go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filePath, func(t *testing.T) {
+			data := fmt.Sprintf(`{"cwd":%q,"hook_event_name":"PostToolUse","tool_name":"Write","tool_input":{"file_path":%q}}`, tt.cwd, tt.filePath)
+			input, rawJSON, err := parseInputFrom[*PostToolUseInput](strings.NewReader(data), PostToolUse)
+			if err != nil {
+				t.Fatalf("parseInputFrom() error = %v", err)
+			}
+			if input.ToolInput.FilePath != tt.want {
+				t.Errorf("ToolInput.FilePath = %q, want %q", input.ToolInput.FilePath, tt.want)
+			}
+			if got := unifiedTemplateReplace("{.tool_input.file_path} {.tool_input.file_path_raw}", rawJSON); got != tt.want+" "+tt.filePath {
+				t.Errorf("templates = %q, want the normalized and the raw path", got)
+			}
+			// updatedInputのベースにはClaudeが送ったパスを使う
+			if updated := copyToolInput(rawJSON); updated["file_path"] != tt.filePath || updated["file_path_raw"] != nil {
+				t.Errorf("copyToolInput() = %v, want the raw file_path only", updated)
+			}
+		})
+	}
+}
+
 func TestRunCommand_Success(t *testing.T) {
 	// 成功するコマンドをテスト
 	err := runCommand("echo test", false, nil)
@@ -519,3 +595,99 @@ func TestContainsProcessSubstitution(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitCommandFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    []string
+		wantErr error
+	}{
+		{
+			name:    "simple command",
+			command: "rm -rf foo.txt",
+			want:    []string{"rm", "-rf", "foo.txt"},
+		},
+		{
+			name:    "quoted multibyte argument",
+			command: `rm "日本語 ファイル.txt"`,
+			want:    []string{"rm", "日本語 ファイル.txt"},
+		},
+		{
+			name:    "small brace expansion",
+			command: "rm file{1..3}.txt",
+			want:    []string{"rm", "file1.txt", "file2.txt", "file3.txt"},
+		},
+		{
+			name:    "huge sequence expansion is rejected",
+			command: "rm {1..100000000}",
+			wantErr: ErrBraceExpansionTooLarge,
+		},
+		{
+			name:    "huge sequence with negative bounds is rejected",
+			command: "rm {-9223372036854775808..9223372036854775807}",
+			wantErr: ErrBraceExpansionTooLarge,
+		},
+		{
+			name:    "exponential brace combination is rejected",
+			command: "rm {a,b}{a,b}{a,b}{a,b}{a,b}{a,b}{a,b}{a,b}{a,b}{a,b}{a,b}{a,b}{a,b}{a,b}",
+			wantErr: ErrBraceExpansionTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitCommandFields(tt.command)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("splitCommandFields(%q) error = %v, want %v", tt.command, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("splitCommandFields(%q) unexpected error: %v", tt.command, err)
+			}
+			if strings.Join(got, "\x00") != strings.Join(tt.want, "\x00") {
+				t.Errorf("splitCommandFields(%q) = %q, want %q", tt.command, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckGitTrackedFileOperation_LargeBraceExpansion(t *testing.T) {
+	_, err := checkGitTrackedFileOperation("rm {1..100000000} main.go", "rm|mv")
+	if !errors.Is(err, ErrBraceExpansionTooLarge) {
+		t.Errorf("Expected ErrBraceExpansionTooLarge, got %v", err)
+	}
+}
+
+// FuzzSplitCommandFields はモデルが生成した任意のコマンド文字列でトークナイズがpanic・ハングしないことを確認する
+func FuzzSplitCommandFields(f *testing.F) {
+	seeds := []string{
+		"rm -rf foo.txt",
+		"mv -t dest a b",
+		`rm "日本語 ファイル.txt" 'single quoted'`,
+		"rm file{1..3}.txt {a,b{c,d}}",
+		"rm {1..100000000}",
+		"rm {a..z..-2} {09..1}",
+		"echo $(ls) `pwd` ${HOME:-/tmp} $((1+2))",
+		"diff <(a) >(b)",
+		"cmd1 && cmd2 || cmd3; cmd4 | cmd5",
+		"echo '<unclosed",
+		"\x00\xff\xfe",
+	}
+	for _, s := range seeds {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, command string) {
+		// checkGitTrackedFileOperationと同じくプロセス置換は事前に除外する
+		if containsProcessSubstitution(command) {
+			return
+		}
+		fields, err := splitCommandFields(command)
+		if err == nil && len(fields) > maxCommandFields {
+			t.Errorf("splitCommandFields(%q) returned %d fields, want at most %d", command, len(fields), maxCommandFields)
+		}
+	})
+}
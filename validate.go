@@ -0,0 +1,1257 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severities of configIssue.
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+)
+
+// configIssue is a problem found by `cchook -command validate`.
+// Line and Column are 1-based positions in File (0 when unknown).
+type configIssue struct {
+	File     string
+	Line     int
+	Column   int
+	Severity string
+	Message  string
+}
+
+// String formats the issue as "file:line:col: severity: message".
+func (i configIssue) String() string {
+	if i.Line == 0 {
+		return fmt.Sprintf("%s: %s: %s", i.File, i.Severity, i.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s", i.File, i.Line, i.Column, i.Severity, i.Message)
+}
+
+// validateHookTypes maps each event to its hook struct, whose yaml tags are the allowed hook fields.
+var validateHookTypes = map[HookEventType]reflect.Type{
+	PreToolUse:        reflect.TypeOf(PreToolUseHook{}),
+	PostToolUse:       reflect.TypeOf(PostToolUseHook{}),
+	PermissionRequest: reflect.TypeOf(PermissionRequestHook{}),
+	Notification:      reflect.TypeOf(NotificationHook{}),
+	Stop:              reflect.TypeOf(StopHook{}),
+	SubagentStop:      reflect.TypeOf(SubagentStopHook{}),
+	SubagentStart:     reflect.TypeOf(SubagentStartHook{}),
+	PreCompact:        reflect.TypeOf(PreCompactHook{}),
+	SessionStart:      reflect.TypeOf(SessionStartHook{}),
+	SessionEnd:        reflect.TypeOf(SessionEndHook{}),
+	UserPromptSubmit:  reflect.TypeOf(UserPromptSubmitHook{}),
+}
+
+// toolEvents are the events whose input has tool_name and tool_input.
+var toolEvents = []HookEventType{PreToolUse, PostToolUse, PermissionRequest}
+
+// eventScopedConditions lists the events a condition type can be used with.
+// Condition types not listed here (file, cwd, permission_mode and condition groups) are supported by every event.
+var eventScopedConditions = map[ConditionType][]HookEventType{
+	ConditionFileExtension:               toolEvents,
+	ConditionFilePathMatches:             toolEvents,
+	ConditionFileIsGitignored:            toolEvents,
+	ConditionFileNotGitignored:           toolEvents,
+	ConditionContentContains:             toolEvents,
+	ConditionContentMatches:              toolEvents,
+	ConditionCommandContains:             toolEvents,
+	ConditionCommandStartsWith:           toolEvents,
+	ConditionCommandRegex:                toolEvents,
+	ConditionCommandNotRegex:             toolEvents,
+	ConditionGitCommitMessageMatches:     toolEvents,
+	ConditionGitCommitMessageNotMatches:  toolEvents,
+	ConditionPushToRemoteIs:              toolEvents,
+	ConditionPushIsForce:                 toolEvents,
+	ConditionPushTargetBranchMatches:     toolEvents,
+	ConditionURLStartsWith:               toolEvents,
+	ConditionFileSizeGt:                  toolEvents,
+	ConditionMCPServerIs:                 toolEvents,
+	ConditionMCPToolIs:                   toolEvents,
+	ConditionFileIsBinary:                toolEvents,
+	ConditionGitTrackedFileOperation:     toolEvents,
+	ConditionPromptRegex:                 {UserPromptSubmit},
+	ConditionEveryNPrompts:               {UserPromptSubmit},
+	ConditionReasonIs:                    {SessionEnd},
+	ConditionAgentTypeIs:                 {SubagentStart, SubagentStop},
+	ConditionToolResponseContains:        {PostToolUse},
+	ConditionToolErrorIsPresent:          {PostToolUse},
+	ConditionExitCodeIs:                  {PostToolUse},
+	ConditionNotificationMessageContains: {Notification},
+	ConditionNotificationMessageRegex:    {Notification},
+}
+
+// eventScopedActionFields lists the events an action field has an effect on.
+// Fields not listed here are used by every event.
+var eventScopedActionFields = map[string][]HookEventType{
+	"permission_decision": {PreToolUse},
+	"decision":            {PostToolUse, Stop, SubagentStop, UserPromptSubmit},
+	"reason":              reasonEvents,
+	"suggest_command":     {PreToolUse},
+	"additional_context":  {PreToolUse},
+	"model_hint":          {PreToolUse, PostToolUse, UserPromptSubmit},
+	"behavior":            {PermissionRequest},
+	"interrupt":           {PermissionRequest},
+	"updated_input":       {PreToolUse, PermissionRequest},
+	"system_message":      toolEvents,
+	"suppress_output":     toolEvents,
+	"scan_file":           {PostToolUse},
+	"frontmatter_schema":  {PostToolUse},
+	"terms":               {PreToolUse, PostToolUse},
+	"base":                {PreToolUse},
+	"rules":               {PreToolUse},
+	"deny_if_no_match":    {PreToolUse},
+	"ledger":              {SessionStart, SessionEnd},
+	"project":             {SessionStart, SessionEnd},
+	"tags":                {SessionStart, SessionEnd},
+	"flush":               {Notification, Stop},
+}
+
+// reasonEvents are the events whose output actions have a model-facing reason separate from message.
+var reasonEvents = []HookEventType{PostToolUse, Stop, SubagentStop}
+
+// outputOnlyActionFields are action fields that set the hook output directly and are ignored by command actions
+// (a command sets them in its own JSON output).
+var outputOnlyActionFields = []string{"updated_input", "system_message", "suppress_output"}
+
+// httpActionFields are action fields only used by http actions.
+var httpActionFields = []string{"url", "method", "headers", "body", "timeout"}
+
+// knownNotificationTypes are the notification_type values sent by Claude Code.
+var knownNotificationTypes = []string{"permission_prompt", "idle_prompt", "auth_success", "elicitation_dialog"}
+
+// validateConfigFile reads the config at configPath (default path when empty) and the files it includes,
+// and reports all issues found. The returned error is only for failures to read configPath.
+func validateConfigFile(configPath string) (string, []configIssue, error) {
+	if configPath == "" {
+		configPath = getDefaultConfigPath()
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return configPath, nil, fmt.Errorf("failed to read config file %s: %w", configPath, err)
+	}
+	return configPath, validateConfigData(configPath, data), nil
+}
+
+// runValidate validates the config and writes the issues to w.
+// It returns an error when at least one issue is an error (warnings alone pass).
+func runValidate(w io.Writer, configPath string) error {
+	path, issues, err := validateConfigFile(configPath)
+	if err != nil {
+		return err
+	}
+
+	errorCount := 0
+	for _, issue := range issues {
+		fmt.Fprintln(w, issue.String())
+		if issue.Severity == severityError {
+			errorCount++
+		}
+	}
+	if errorCount > 0 {
+		return fmt.Errorf("%s: %d error(s), %d warning(s)", path, errorCount, len(issues)-errorCount)
+	}
+	fmt.Fprintf(w, "%s: OK (%d warning(s))\n", path, len(issues))
+	return nil
+}
+
+// configValidator collects issues while walking the YAML node tree of a config and its includes.
+type configValidator struct {
+	issues  []configIssue
+	file    string          // file currently being validated
+	loading []string        // include chain currently being validated (cycle detection)
+	visited map[string]bool // files already validated
+}
+
+// validateConfigData validates a YAML config read from path (and the files it includes)
+// and returns every issue found, in file order.
+func validateConfigData(path string, data []byte) []configIssue {
+	v := &configValidator{visited: map[string]bool{}}
+	v.validateFile(path, data)
+	return v.issues
+}
+
+// validateFile validates a single config file, then the files it includes.
+func (v *configValidator) validateFile(path string, data []byte) {
+	key := absConfigPath(path)
+	v.visited[key] = true
+	v.file = path
+	start := len(v.issues)
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		v.add(severityError, nil, "%v", err)
+		return
+	}
+	if len(doc.Content) == 0 {
+		return
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		v.errorf(root, "config must be a mapping of event names to hooks")
+		return
+	}
+
+	var includes *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if key.Value == "include" {
+			includes = value
+			continue
+		}
+		if key.Value == "merge" {
+			v.validateMerge(path, key, value)
+			continue
+		}
+		if key.Value == "log" {
+			v.validateLog(path, key, value)
+			continue
+		}
+		if key.Value == "output_format" {
+			v.checkMainConfigOnly(path, key)
+			if err := validateOutputFormat(value.Value); err != nil || value.Kind != yaml.ScalarNode {
+				v.errorf(value, "output_format: invalid value %q (must be indent2 or compact)", value.Value)
+			}
+			continue
+		}
+		if key.Value == "budget" {
+			v.validateBudget(path, key, value)
+			continue
+		}
+		if key.Value == "strict_permissions" {
+			v.checkMainConfigOnly(path, key)
+			if err := validateStrictPermissions(value.Value); err != nil || value.Kind != yaml.ScalarNode {
+				v.errorf(value, "strict_permissions: invalid value %q (must be warn, refuse or off)", value.Value)
+			}
+			continue
+		}
+		if key.Value == "on_git_unavailable" {
+			v.checkMainConfigOnly(path, key)
+			if err := validateGitUnavailable(value.Value); err != nil || value.Kind != yaml.ScalarNode {
+				v.errorf(value, "on_git_unavailable: invalid value %q (must be skip, warn or deny)", value.Value)
+			}
+			continue
+		}
+		if key.Value == "self_test" {
+			v.checkMainConfigOnly(path, key)
+			continue
+		}
+		if key.Value == "project_config" {
+			v.validateProjectTrust(path, key, value)
+			continue
+		}
+		if key.Value == "decision_log" {
+			v.validateDecisionLog(path, key, value)
+			continue
+		}
+		if key.Value == "report" {
+			v.validateReport(path, key, value)
+			continue
+		}
+		eventType := HookEventType(key.Value)
+		if !eventType.IsValid() {
+			v.errorf(key, "unknown event type %q", key.Value)
+			continue
+		}
+		if value.Kind == yaml.ScalarNode && value.Tag == "!!null" {
+			continue
+		}
+		if value.Kind != yaml.SequenceNode {
+			v.errorf(value, "%s must be a list of hooks", eventType)
+			continue
+		}
+		names := map[string]bool{}
+		for j, hook := range value.Content {
+			where := fmt.Sprintf("%s hook %d", eventType, j+1)
+			v.validateHook(eventType, where, hook)
+			// 名前はフックIDの元になるので、同じイベントの中で重複できない
+			if name := mappingValue(hook, "name"); name != nil && name.Kind == yaml.ScalarNode && name.Value != "" {
+				if names[name.Value] {
+					v.errorf(name, "%s: duplicate hook name %q", where, name.Value)
+				}
+				names[name.Value] = true
+			}
+		}
+	}
+	includedFiles := v.resolveIncludes(key, includes)
+
+	// 構造上の問題がなければ、実際の読み込みでしか分からない型エラーも拾う
+	if !slices.ContainsFunc(v.issues[start:], func(i configIssue) bool { return i.Severity == severityError }) {
+		var config Config
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			var typeErr *yaml.TypeError
+			if errors.As(err, &typeErr) {
+				for _, msg := range typeErr.Errors {
+					v.add(severityError, nil, "%s", msg)
+				}
+			} else {
+				v.add(severityError, nil, "%v", err)
+			}
+		}
+	}
+
+	slices.SortStableFunc(v.issues[start:], func(a, b configIssue) int {
+		if a.Line != b.Line {
+			return a.Line - b.Line
+		}
+		return a.Column - b.Column
+	})
+
+	v.loading = append(v.loading, key)
+	for _, file := range includedFiles {
+		if v.visited[file] {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			v.file = path
+			v.add(severityError, nil, "failed to read included config file: %v", err)
+			continue
+		}
+		v.validateFile(file, data)
+	}
+	v.loading = v.loading[:len(v.loading)-1]
+	v.file = path
+}
+
+// resolveIncludes checks the include list of the file whose absolute path is key
+// and returns the files it includes.
+func (v *configValidator) resolveIncludes(key string, node *yaml.Node) []string {
+	if node == nil {
+		return nil
+	}
+	if node.Kind != yaml.SequenceNode {
+		v.errorf(node, "include must be a list of file paths")
+		return nil
+	}
+
+	var files []string
+	for _, item := range node.Content {
+		if item.Kind != yaml.ScalarNode {
+			v.errorf(item, "include must be a list of file paths")
+			continue
+		}
+		pattern := includePattern(filepath.Dir(key), item.Value)
+		matched, err := resolveInclude(pattern)
+		if err != nil {
+			v.errorf(item, "invalid include %q: %v", item.Value, err)
+			continue
+		}
+		if len(matched) == 0 {
+			v.warnf(item, "include %q matches no files", item.Value)
+		}
+		for _, file := range matched {
+			if slices.Contains(v.loading, file) || file == key {
+				v.errorf(item, "include cycle: %s", strings.Join(append(slices.Clone(v.loading), key, file), " -> "))
+				continue
+			}
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// validateMerge checks the `merge:` section (event type → append/replace) of the file at path.
+func (v *configValidator) validateMerge(path string, key, node *yaml.Node) {
+	if len(v.loading) > 0 || filepath.Base(path) != projectConfigFileName {
+		v.warnf(key, "merge is only used by a project config (%s) and is ignored here", projectConfigFileName)
+	}
+	if node.Kind != yaml.MappingNode {
+		v.errorf(node, "merge must be a mapping of event names to append or replace")
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		eventType, strategy := node.Content[i], node.Content[i+1]
+		if !HookEventType(eventType.Value).IsValid() {
+			v.errorf(eventType, "merge: unknown event type %q", eventType.Value)
+		}
+		if strategy.Value != mergeAppend && strategy.Value != mergeReplace {
+			v.errorf(strategy, "merge: invalid strategy %q (must be append or replace)", strategy.Value)
+		}
+	}
+}
+
+// checkMainConfigOnly warns about a setting (key) that is only read from the main config.
+func (v *configValidator) checkMainConfigOnly(path string, key *yaml.Node) {
+	if len(v.loading) > 0 || filepath.Base(path) == projectConfigFileName {
+		v.warnf(key, "%s is only used in the main config and is ignored here", key.Value)
+	}
+}
+
+// validateLog checks the `log:` block of the file at path.
+func (v *configValidator) validateLog(path string, key, node *yaml.Node) {
+	v.checkMainConfigOnly(path, key)
+	if node.Kind != yaml.MappingNode {
+		v.errorf(node, "log must be a mapping with path, level and format")
+		return
+	}
+	v.checkFields(node, yamlFieldNames(reflect.TypeOf(LogConfig{})), "log")
+	if level := mappingValue(node, "level"); level != nil {
+		if _, ok := hookLogLevels[level.Value]; !ok {
+			v.errorf(level, "log: invalid level %q (must be debug, info, warn or error)", level.Value)
+		}
+	}
+	if format := mappingValue(node, "format"); format != nil && format.Value != logFormatJSON && format.Value != logFormatText {
+		v.errorf(format, "log: invalid format %q (must be json or text)", format.Value)
+	}
+}
+
+// validateProjectTrust checks the `project_config:` block of the file at path.
+func (v *configValidator) validateProjectTrust(path string, key, node *yaml.Node) {
+	v.checkMainConfigOnly(path, key)
+	if node.Kind != yaml.MappingNode {
+		v.errorf(node, "project_config must be a mapping with trusted and allow_replace")
+		return
+	}
+	v.checkFields(node, yamlFieldNames(reflect.TypeOf(ProjectTrustConfig{})), "project_config")
+	var trust ProjectTrustConfig
+	if err := node.Decode(&trust); err != nil {
+		v.errorf(node, "project_config: %v", err)
+		return
+	}
+	if err := validateProjectTrustConfig(&trust); err != nil {
+		v.errorf(node, "%v", err)
+	}
+}
+
+// validateDecisionLog checks the `decision_log:` block of the file at path.
+func (v *configValidator) validateDecisionLog(path string, key, node *yaml.Node) {
+	v.checkMainConfigOnly(path, key)
+	if node.Kind != yaml.MappingNode {
+		v.errorf(node, "decision_log must be a mapping with path or url")
+		return
+	}
+	v.checkFields(node, yamlFieldNames(reflect.TypeOf(DecisionLogConfig{})), "decision_log")
+	var c DecisionLogConfig
+	if err := node.Decode(&c); err != nil {
+		v.errorf(node, "decision_log: %v", err)
+		return
+	}
+	if err := validateDecisionLogConfig(&c); err != nil {
+		v.errorf(node, "%v", err)
+	}
+}
+
+// validateReport checks the `report:` block of the file at path.
+func (v *configValidator) validateReport(path string, key, node *yaml.Node) {
+	v.checkMainConfigOnly(path, key)
+	if node.Kind != yaml.MappingNode {
+		v.errorf(node, "report must be a mapping with url")
+		return
+	}
+	v.checkFields(node, yamlFieldNames(reflect.TypeOf(ReportConfig{})), "report")
+	var c ReportConfig
+	if err := node.Decode(&c); err != nil {
+		v.errorf(node, "report: %v", err)
+		return
+	}
+	if err := validateReportConfig(&c); err != nil {
+		v.errorf(node, "%v", err)
+	}
+}
+
+// validateBudget checks the `budget:` block of the file at path.
+func (v *configValidator) validateBudget(path string, key, node *yaml.Node) {
+	v.checkMainConfigOnly(path, key)
+	if node.Kind != yaml.MappingNode {
+		v.errorf(node, "budget must be a mapping of limits")
+		return
+	}
+	v.checkFields(node, yamlFieldNames(reflect.TypeOf(BudgetConfig{})), "budget")
+	var budget BudgetConfig
+	if err := node.Decode(&budget); err != nil {
+		v.errorf(node, "budget: %v", err)
+		return
+	}
+	if err := validateBudgetConfig(&budget); err != nil {
+		v.errorf(node, "%v", err)
+	}
+	if len(budget.limits()) == 0 {
+		v.warnf(key, "budget has no limits (set session_tokens, daily_tokens, session_cost or daily_cost)")
+	}
+	if prices := mappingValue(node, "prices"); prices != nil && prices.Kind == yaml.MappingNode {
+		for i := 1; i < len(prices.Content); i += 2 {
+			if prices.Content[i].Kind == yaml.MappingNode {
+				v.checkFields(prices.Content[i], yamlFieldNames(reflect.TypeOf(ModelPrice{})), "budget: prices of "+prices.Content[i-1].Value)
+			}
+		}
+	}
+}
+
+// validateHook checks the fields, matcher, conditions and actions of a single hook.
+func (v *configValidator) validateHook(eventType HookEventType, where string, hook *yaml.Node) {
+	if hook.Kind != yaml.MappingNode {
+		v.errorf(hook, "%s: hook must be a mapping", where)
+		return
+	}
+	v.checkFields(hook, yamlFieldNames(validateHookTypes[eventType]), where)
+
+	if matcher := mappingValue(hook, "matcher"); matcher != nil {
+		v.validateMatcher(eventType, where, matcher)
+	}
+	if conditions := mappingValue(hook, "conditions"); conditions != nil {
+		v.validateConditionList(eventType, where, conditions, 1)
+	}
+	if mutex := mappingValue(hook, "mutex"); mutex != nil {
+		if err := validateHookMutexName(mutex.Value); err != nil {
+			v.errorf(mutex, "%s: %v", where, err)
+		}
+	}
+	if envFrom := mappingValue(hook, "env_from"); envFrom != nil {
+		if err := validateEnvFrom(envFrom.Value); err != nil {
+			v.errorf(envFrom, "%s: %v", where, err)
+		}
+	}
+	if cooldown := mappingValue(hook, "cooldown"); cooldown != nil {
+		if err := validateCooldown(cooldown.Value, ""); err != nil {
+			v.errorf(cooldown, "%s: %v", where, err)
+		}
+	}
+	if scope := mappingValue(hook, "cooldown_scope"); scope != nil {
+		if err := validateCooldown("", scope.Value); err != nil {
+			v.errorf(scope, "%s: %v", where, err)
+		} else if mappingValue(hook, "cooldown") == nil {
+			v.warnf(scope, "%s: cooldown_scope has no effect without cooldown", where)
+		}
+	}
+	if eventType == PostToolUse {
+		v.checkBatch(where, hook)
+	}
+
+	actions := mappingValue(hook, "actions")
+	if actions == nil || len(actions.Content) == 0 {
+		v.warnf(hook, "%s: hook has no actions", where)
+		return
+	}
+	if actions.Kind != yaml.SequenceNode {
+		v.errorf(actions, "%s: actions must be a list", where)
+		return
+	}
+	for i, action := range actions.Content {
+		v.validateAction(eventType, fmt.Sprintf("%s action %d", where, i+1), action)
+	}
+}
+
+// checkBatch reports invalid quiet periods of batch hooks and options that have no effect with or without batch.
+func (v *configValidator) checkBatch(where string, node *yaml.Node) {
+	var hook PostToolUseHook
+	if err := node.Decode(&hook); err != nil {
+		return
+	}
+	if quiet := mappingValue(node, "batch_quiet_period"); quiet != nil {
+		if !hook.Batch {
+			v.warnf(quiet, "%s: batch_quiet_period is only used with batch: true", where)
+		} else if _, err := batchQuietPeriod(hook); err != nil {
+			v.errorf(quiet, "%s: %v", where, err)
+		}
+	}
+	if !hook.Batch {
+		return
+	}
+	// バッチ実行は元のイベントと切り離されて動く
+	for _, field := range []string{"snapshot", "notify_model_on_file_change"} {
+		if value := mappingValue(node, field); value != nil {
+			v.warnf(value, "%s: %s is ignored for batch hooks", where, field)
+		}
+	}
+}
+
+// validateMatcher reports matchers that can never match (or unintentionally match everything).
+func (v *configValidator) validateMatcher(eventType HookEventType, where string, node *yaml.Node) {
+	matcher := node.Value
+	if node.Kind != yaml.ScalarNode || matcher == "" {
+		return
+	}
+
+	switch eventType {
+	case PreToolUse, PostToolUse, PermissionRequest, SubagentStart, SubagentStop:
+		for _, pattern := range matcherAlternatives(matcher) {
+			if isRegexPattern(pattern) {
+				if _, err := compileMatcherRegex(pattern); err != nil {
+					v.errorf(node, "%s: %v", where, err)
+					return
+				}
+				continue
+			}
+			// 部分一致のため、空の選択肢はすべてにマッチしてしまう
+			if pattern == "" {
+				v.warnf(node, "%s: matcher %q has an empty alternative, which matches everything", where, matcher)
+				return
+			}
+		}
+	case Notification:
+		unknown := 0
+		patterns := strings.Split(matcher, "|")
+		for _, pattern := range patterns {
+			if !slices.Contains(knownNotificationTypes, strings.TrimSpace(pattern)) {
+				v.warnf(node, "%s: matcher contains unknown notification_type %q (known types: %s)", where, strings.TrimSpace(pattern), strings.Join(knownNotificationTypes, ", "))
+				unknown++
+			}
+		}
+		if unknown == len(patterns) {
+			v.warnf(node, "%s: hook is unreachable: matcher %q never matches", where, matcher)
+		}
+	case PreCompact:
+		// PreCompactとSessionStartは完全一致（パイプ区切り非対応）
+		if matcher != "manual" && matcher != "auto" {
+			v.warnf(node, "%s: hook is unreachable: matcher %q never matches (expected: manual, auto, or empty)", where, matcher)
+		}
+	case SessionStart:
+		if !slices.Contains([]string{"startup", "resume", "clear", "compact"}, matcher) {
+			v.warnf(node, "%s: hook is unreachable: matcher %q never matches (expected: startup, resume, clear, compact, or empty)", where, matcher)
+		}
+	}
+}
+
+// validateConditionList validates a list of conditions at the given nesting depth.
+func (v *configValidator) validateConditionList(eventType HookEventType, where string, node *yaml.Node, depth int) {
+	if node.Kind != yaml.SequenceNode {
+		v.errorf(node, "%s: conditions must be a list", where)
+		return
+	}
+	for _, condition := range node.Content {
+		v.validateCondition(eventType, where, condition, depth)
+	}
+}
+
+// validateCondition checks a single condition: its type, applicability to the event and value.
+func (v *configValidator) validateCondition(eventType HookEventType, where string, node *yaml.Node, depth int) {
+	if node.Kind != yaml.MappingNode {
+		v.errorf(node, "%s: condition must be a mapping", where)
+		return
+	}
+	v.checkFields(node, yamlFieldNames(reflect.TypeOf(Condition{})), where)
+
+	var raw struct {
+		Type       string      `yaml:"type"`
+		Value      string      `yaml:"value"`
+		Values     []string    `yaml:"values"`
+		Match      string      `yaml:"match"`
+		Conditions []yaml.Node `yaml:"conditions"`
+		IgnoreCase bool        `yaml:"ignore_case"`
+		Normalize  string      `yaml:"normalize"`
+		TZ         string      `yaml:"tz"`
+		MaxDepth   int         `yaml:"max_depth"`
+		Exclude    []string    `yaml:"exclude"`
+	}
+	if err := node.Decode(&raw); err != nil {
+		v.errorf(node, "%s: %v", where, err)
+		return
+	}
+
+	typeNode := mappingValue(node, "type")
+	if typeNode == nil {
+		v.errorf(node, "%s: condition type is required", where)
+		return
+	}
+	conditionType, err := parseConditionType(raw.Type)
+	if err != nil {
+		v.errorf(typeNode, "%s: %v", where, err)
+		return
+	}
+
+	if isCompositeCondition(conditionType) {
+		if depth > maxConditionNestingDepth {
+			v.errorf(node, "%s: condition groups are nested deeper than %d levels", where, maxConditionNestingDepth)
+			return
+		}
+		if raw.Value != "" || len(raw.Values) > 0 {
+			v.errorf(node, "%s: %s does not take value or values", where, conditionType)
+		}
+		children := mappingValue(node, "conditions")
+		if children == nil || len(children.Content) == 0 {
+			v.errorf(node, "%s: %s requires at least one condition in conditions", where, conditionType)
+			return
+		}
+		v.validateConditionList(eventType, where, children, depth+1)
+		return
+	}
+	if children := mappingValue(node, "conditions"); children != nil {
+		v.errorf(children, "%s: conditions can only be used with any_of, all_of or not: %s", where, conditionType)
+	}
+
+	if events, ok := eventScopedConditions[conditionType]; ok && !slices.Contains(events, eventType) {
+		v.errorf(typeNode, "%s: condition type %s is not supported for %s events", where, conditionType, eventType)
+	}
+
+	if _, err := normalizeUnicode(raw.Normalize, ""); err != nil {
+		v.errorf(mappingValue(node, "normalize"), "%s: %v", where, err)
+	}
+	if tz := mappingValue(node, "tz"); tz != nil {
+		if conditionType != ConditionTimeBetween && conditionType != ConditionDayOfWeek && conditionType != ConditionFreezeActive {
+			v.warnf(tz, "%s: tz is only used by time_between, day_of_week and freeze_active", where)
+		} else if _, err := conditionLocation(raw.TZ); err != nil {
+			v.errorf(tz, "%s: %s: %v", where, conditionType, err)
+		}
+	}
+	v.checkRecursiveSearchFields(where, conditionType, node, raw.MaxDepth, raw.Exclude)
+	if len(raw.Values) == 0 {
+		if raw.Match != "" {
+			v.errorf(mappingValue(node, "match"), "%s: match requires values for condition type: %s", where, conditionType)
+		}
+		v.checkConditionValue(where, conditionType, raw.Value, raw.IgnoreCase, mappingValue(node, "value"), node)
+		return
+	}
+	if raw.Value != "" {
+		v.errorf(mappingValue(node, "value"), "%s: value and values cannot be used together for condition type: %s", where, conditionType)
+	}
+	if raw.Match != "" && raw.Match != "any" && raw.Match != "all" {
+		v.errorf(mappingValue(node, "match"), "%s: invalid match value: %s (must be 'any' or 'all')", where, raw.Match)
+	}
+	values := mappingValue(node, "values")
+	for i, value := range raw.Values {
+		v.checkConditionValue(where, conditionType, value, raw.IgnoreCase, values.Content[i], node)
+	}
+}
+
+// recursiveConditions are the conditions searching the directory tree, which take max_depth and exclude.
+var recursiveConditions = []ConditionType{
+	ConditionFileExistsRecursive, ConditionFileNotExistsRecursive, ConditionDirExistsRecursive, ConditionDirNotExistsRecursive,
+}
+
+// checkRecursiveSearchFields reports max_depth and exclude of a condition that doesn't search the tree, and invalid values.
+func (v *configValidator) checkRecursiveSearchFields(where string, conditionType ConditionType, node *yaml.Node, maxDepth int, exclude []string) {
+	for _, key := range []string{"max_depth", "exclude"} {
+		if field := mappingValue(node, key); field != nil && !slices.Contains(recursiveConditions, conditionType) {
+			v.warnf(field, "%s: %s is only used by file_exists_recursive, file_not_exists_recursive, dir_exists_recursive and dir_not_exists_recursive", where, key)
+		}
+	}
+	if maxDepth < 0 {
+		v.errorf(mappingValue(node, "max_depth"), "%s: %s: max_depth must not be negative", where, conditionType)
+	}
+	if patterns := mappingValue(node, "exclude"); patterns != nil && patterns.Kind == yaml.SequenceNode {
+		for i, pattern := range exclude {
+			if err := validateGlob(pattern); err != nil {
+				v.errorf(patterns.Content[i], "%s: %s: invalid exclude pattern: %v", where, conditionType, err)
+			}
+		}
+	}
+}
+
+// checkConditionValue reports values that would make the condition fail at runtime (bad regexes, malformed arguments).
+// at is the node of the value, or nil when it is omitted (the condition node is used instead).
+func (v *configValidator) checkConditionValue(where string, conditionType ConditionType, value string, ignoreCase bool, at, condition *yaml.Node) {
+	if at == nil {
+		at = condition
+	}
+
+	var err error
+	switch conditionType {
+	case ConditionPromptRegex, ConditionNotificationMessageRegex, ConditionCommandRegex, ConditionCommandNotRegex, ConditionGitBranchMatches, ConditionPushTargetBranchMatches, ConditionContentMatches, ConditionLastAssistantMatches:
+		err = checkRegexValue(value, ignoreCase)
+	case ConditionEveryNPrompts:
+		if n, convErr := strconv.Atoi(value); convErr != nil {
+			err = fmt.Errorf("invalid value for every_n_prompts: %w", convErr)
+		} else if n <= 0 {
+			err = fmt.Errorf("every_n_prompts value must be positive: %d", n)
+		}
+	case ConditionFileOlderThan, ConditionFileNewerThan:
+		_, _, err = parseFileAgeValue(value)
+	case ConditionSessionDurationLt, ConditionSessionDurationGt:
+		_, err = parseDurationWithDays(value)
+	case ConditionTokensUsedLt, ConditionTokensUsedGt:
+		_, err = parseTokenCount(value)
+	case ConditionBudgetExceeded:
+		if value != "" && !slices.Contains(budgetLimitNames, value) {
+			err = fmt.Errorf("unknown budget limit %q (must be one of %s, or empty for any)", value, strings.Join(budgetLimitNames, ", "))
+		}
+	case ConditionBudgetRemainingBelow:
+		_, _, err = parseBudgetRemaining(value)
+	case ConditionFileSHA256Is:
+		_, err = splitPathArguments(value, 2, `"<path> <sha256>"`)
+	case ConditionFileContentEqualsFile:
+		_, err = splitPathArguments(value, 2, `"<path> <blessed path>"`)
+	case ConditionGitCommitMessageMatches, ConditionGitCommitMessageNotMatches:
+		err = validateGitCommitMessagePattern(value, ignoreCase)
+	case ConditionGitSigningEnabled:
+		err = validateGitSigningFormat(value)
+	case ConditionFileSizeGt:
+		_, err = parseByteSize(value)
+	case ConditionPushToRemoteIs:
+		if value == "" {
+			err = fmt.Errorf("requires a remote name or URL")
+		}
+	case ConditionToolInputJQ:
+		_, err = compileJQQuery(value)
+	case ConditionCEL:
+		_, err = compileCELCondition(value)
+	case ConditionFilePathMatches:
+		err = validateGlob(value)
+	case ConditionChangelogNotUpdated:
+		_, _, err = parseChangelogNotUpdatedValue(value)
+	case ConditionTicketReferenced:
+		if value != "" {
+			err = checkRegexValue(value, ignoreCase)
+		}
+	case ConditionAgentTypeIs:
+		if value == "" {
+			err = fmt.Errorf("requires an agent type")
+		}
+	case ConditionToolResponseContains:
+		if value == "" {
+			err = fmt.Errorf("requires a string to search for")
+		}
+	case ConditionExitCodeIs:
+		err = validateExitCode(value)
+	case ConditionInMeeting:
+		if strings.TrimSpace(value) == "" {
+			err = fmt.Errorf("requires an iCalendar URL or file")
+		}
+	case ConditionFreezeActive:
+		tz := ""
+		if node := mappingValue(condition, "tz"); node != nil {
+			tz = node.Value
+		}
+		err = validateFreezeSource(value, tz)
+	case ConditionTimeBetween:
+		_, _, err = parseTimeRange(value)
+	case ConditionDayOfWeek:
+		_, err = parseDaysOfWeek(value)
+	case ConditionEnvIs, ConditionEnvSet, ConditionEnvMatches:
+		var operand string
+		_, operand, err = parseEnvConditionValue(conditionType, value)
+		if err == nil && conditionType == ConditionEnvMatches {
+			err = checkRegexValue(operand, ignoreCase)
+		}
+	case ConditionFileIsGitignored, ConditionFileNotGitignored, ConditionInDevcontainer, ConditionGitDirty, ConditionGitClean, ConditionPushIsForce, ConditionFileIsBinary, ConditionDigestPending, ConditionToolErrorIsPresent:
+		if value != "" {
+			err = fmt.Errorf("does not take a value")
+		}
+	}
+	if err != nil {
+		v.errorf(at, "%s: %s: %v", where, conditionType, err)
+	}
+}
+
+// checkRegexValue checks the pattern of a regex condition, compiled like matchRegex does.
+func checkRegexValue(pattern string, ignoreCase bool) error {
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		return fmt.Errorf("invalid regex pattern: %w", err)
+	}
+	return nil
+}
+
+// validateAction checks the fields and decision values of a single action.
+func (v *configValidator) validateAction(eventType HookEventType, where string, node *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		v.errorf(node, "%s: action must be a mapping", where)
+		return
+	}
+	v.checkFields(node, yamlFieldNames(reflect.TypeOf(Action{})), where)
+
+	var action Action
+	if err := node.Decode(&action); err != nil {
+		v.errorf(node, "%s: %v", where, err)
+		return
+	}
+
+	switch action.Type {
+	case "command":
+		v.checkCommandArgs(where, node, action)
+		if err := validateStdinMode(action.Stdin); err != nil {
+			v.errorf(mappingValue(node, "stdin"), "%s: %v", where, err)
+		}
+		if action.UseStdin != nil && action.Stdin != "" {
+			v.warnf(mappingValue(node, "use_stdin"), "%s: use_stdin is ignored when stdin is set", where)
+		}
+		if action.Runner != "" {
+			if _, err := parseCommandRunner(action.Runner); err != nil {
+				v.errorf(mappingValue(node, "runner"), "%s: %v", where, err)
+			}
+		}
+		if action.EnvFrom != "" {
+			if err := validateEnvFrom(action.EnvFrom); err != nil {
+				v.errorf(mappingValue(node, "env_from"), "%s: %v", where, err)
+			}
+		}
+	case "output":
+		v.checkOutputMessage(eventType, where, node, action)
+	case "http":
+		v.checkHTTPAction(eventType, where, node, action)
+	case "opa":
+		v.checkOPAAction(eventType, where, node, action)
+	case "hook_changes_report":
+		if eventType != Stop {
+			v.errorf(mappingValue(node, "type"), "%s: hook_changes_report action is only supported for Stop events", where)
+		}
+	case "secret_scan":
+		if eventType != PreToolUse && eventType != PostToolUse {
+			v.errorf(mappingValue(node, "type"), "%s: secret_scan action is only supported for PreToolUse and PostToolUse events", where)
+		}
+	case "syntax_check":
+		if eventType != PostToolUse {
+			v.errorf(mappingValue(node, "type"), "%s: syntax_check action is only supported for PostToolUse events", where)
+		}
+	case "markdown_check":
+		if eventType != PostToolUse {
+			v.errorf(mappingValue(node, "type"), "%s: markdown_check action is only supported for PostToolUse events", where)
+		}
+	case "breaking_change":
+		if eventType != PreToolUse {
+			v.errorf(mappingValue(node, "type"), "%s: breaking_change action is only supported for PreToolUse events", where)
+		}
+		if action.PermissionDecision != nil && *action.PermissionDecision == "allow" {
+			v.errorf(mappingValue(node, "permission_decision"), "%s: breaking_change permission_decision must be ask or deny", where)
+		}
+		if strings.HasPrefix(action.Base, "-") {
+			v.errorf(mappingValue(node, "base"), "%s: invalid base %q (must be a Git revision)", where, action.Base)
+		}
+	case "terminology":
+		if eventType != PreToolUse && eventType != PostToolUse {
+			v.errorf(mappingValue(node, "type"), "%s: terminology action is only supported for PreToolUse and PostToolUse events", where)
+		}
+		if err := validateTermRules(action.Terms); err != nil {
+			target := mappingValue(node, "terms")
+			if target == nil {
+				target = node
+			}
+			v.errorf(target, "%s: %v", where, err)
+		}
+		if terms := mappingValue(node, "terms"); terms != nil && terms.Kind == yaml.SequenceNode {
+			for i, term := range terms.Content {
+				if term.Kind == yaml.MappingNode {
+					v.checkFields(term, yamlFieldNames(reflect.TypeOf(TermRule{})), fmt.Sprintf("%s: terms[%d]", where, i))
+				}
+			}
+		}
+	case "rewrite_command":
+		if eventType != PreToolUse {
+			v.errorf(mappingValue(node, "type"), "%s: rewrite_command action is only supported for PreToolUse events", where)
+		}
+		if action.PermissionDecision != nil && *action.PermissionDecision == "deny" {
+			v.errorf(mappingValue(node, "permission_decision"), "%s: rewrite_command permission_decision must be allow or ask", where)
+		}
+		if err := validateRewriteRules(action.Rules); err != nil {
+			target := mappingValue(node, "rules")
+			if target == nil {
+				target = node
+			}
+			v.errorf(target, "%s: %v", where, err)
+		}
+		if rules := mappingValue(node, "rules"); rules != nil && rules.Kind == yaml.SequenceNode {
+			for i, rule := range rules.Content {
+				if rule.Kind == yaml.MappingNode {
+					v.checkFields(rule, yamlFieldNames(reflect.TypeOf(RewriteRule{})), fmt.Sprintf("%s: rules[%d]", where, i))
+				}
+			}
+		}
+	case "ask_external":
+		if eventType != PreToolUse {
+			v.errorf(mappingValue(node, "type"), "%s: ask_external action is only supported for PreToolUse events", where)
+		}
+		if strings.TrimSpace(action.URL) == "" {
+			v.errorf(node, "%s: ask_external action requires url (where the pending tool call is posted)", where)
+		}
+		if action.Method != "" && !slices.Contains(httpActionMethods, httpActionMethod(action)) {
+			v.errorf(mappingValue(node, "method"), "%s: invalid method %q (must be one of %s)", where, action.Method, strings.Join(httpActionMethods, ", "))
+		}
+		if _, err := askExternalTimeout(action); err != nil {
+			v.errorf(mappingValue(node, "timeout"), "%s: %v", where, err)
+		}
+		if action.PermissionDecision != nil && *action.PermissionDecision == "allow" {
+			v.errorf(mappingValue(node, "permission_decision"), "%s: ask_external permission_decision must be ask or deny", where)
+		}
+	case "typecheck":
+		if eventType != PostToolUse {
+			v.errorf(mappingValue(node, "type"), "%s: typecheck action is only supported for PostToolUse events", where)
+		}
+		if action.EnvFrom != "" {
+			if err := validateEnvFrom(action.EnvFrom); err != nil {
+				v.errorf(mappingValue(node, "env_from"), "%s: %v", where, err)
+			}
+		}
+	case "format_file":
+		if eventType != PostToolUse {
+			v.errorf(mappingValue(node, "type"), "%s: format_file action is only supported for PostToolUse events", where)
+		}
+		v.checkToolExtensions(where, "formatters", mappingValue(node, "formatters"))
+	case "lint_feedback":
+		if eventType != PostToolUse {
+			v.errorf(mappingValue(node, "type"), "%s: lint_feedback action is only supported for PostToolUse events", where)
+		}
+		v.checkToolExtensions(where, "linters", mappingValue(node, "linters"))
+		if action.EnvFrom != "" {
+			if err := validateEnvFrom(action.EnvFrom); err != nil {
+				v.errorf(mappingValue(node, "env_from"), "%s: %v", where, err)
+			}
+		}
+	case "time_tracking":
+		if eventType != SessionStart && eventType != SessionEnd {
+			v.errorf(mappingValue(node, "type"), "%s: time_tracking action is only supported for SessionStart and SessionEnd events", where)
+		}
+	case "digest":
+		switch {
+		case eventType != Notification && eventType != Stop:
+			v.errorf(mappingValue(node, "type"), "%s: digest action is only supported for Notification and Stop events", where)
+		case eventType == Stop && !action.Flush:
+			v.errorf(mappingValue(node, "type"), "%s: digest action can only queue Notification events (use flush: true)", where)
+		}
+	case "run_tests":
+		if eventType != Stop {
+			v.errorf(mappingValue(node, "type"), "%s: run_tests action is only supported for Stop events", where)
+		}
+		if strings.TrimSpace(action.Command) == "" {
+			v.errorf(node, "%s: run_tests action requires command", where)
+		}
+		if _, err := runTestsTimeout(action); err != nil {
+			v.errorf(mappingValue(node, "timeout"), "%s: %v", where, err)
+		}
+		if action.EnvFrom != "" {
+			if err := validateEnvFrom(action.EnvFrom); err != nil {
+				v.errorf(mappingValue(node, "env_from"), "%s: %v", where, err)
+			}
+		}
+	case "git_checkpoint":
+		if eventType != PostToolUse && eventType != Stop {
+			v.errorf(mappingValue(node, "type"), "%s: git_checkpoint action is only supported for PostToolUse and Stop events", where)
+		}
+		if action.Mode != "" && action.Mode != gitCheckpointCommit && action.Mode != gitCheckpointStash {
+			v.errorf(mappingValue(node, "mode"), "%s: invalid mode %q (must be commit or stash)", where, action.Mode)
+		}
+		if len(action.Repos) == 0 {
+			v.errorf(node, "%s: git_checkpoint action requires repos (the repositories allowed to be checkpointed)", where)
+		}
+		for i, repo := range action.Repos {
+			if err := validateGitCheckpointRepo(repo); err != nil {
+				v.errorf(mappingValue(node, "repos").Content[i], "%s: repos: %v", where, err)
+			}
+		}
+	case "":
+		v.errorf(node, "%s: action type is required", where)
+	default:
+		v.errorf(mappingValue(node, "type"), "%s: unknown action type %q (must be command, output, http, hook_changes_report, secret_scan, syntax_check, markdown_check, typecheck, format_file, lint_feedback, terminology, breaking_change, rewrite_command, time_tracking, digest, run_tests, git_checkpoint, opa or ask_external)", where, action.Type)
+	}
+
+	for i, effect := range action.SideEffects {
+		if err := validateSideEffect(effect); err != nil {
+			v.errorf(mappingValue(node, "side_effects").Content[i], "%s: side_effects: %v", where, err)
+		}
+	}
+	if action.PermissionDecision != nil && !slices.Contains([]string{"allow", "deny", "ask"}, *action.PermissionDecision) {
+		v.errorf(mappingValue(node, "permission_decision"), "%s: invalid permission_decision %q (must be allow, deny, or ask)", where, *action.PermissionDecision)
+	}
+	if action.Behavior != nil && !isPermissionRequestBehavior(*action.Behavior) {
+		v.errorf(mappingValue(node, "behavior"), "%s: invalid behavior %q (must be allow, deny, or ask)", where, *action.Behavior)
+	}
+	if action.Decision != nil && *action.Decision != "" && *action.Decision != "block" {
+		v.errorf(mappingValue(node, "decision"), "%s: invalid decision %q (must be block or empty)", where, *action.Decision)
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if events, ok := eventScopedActionFields[key.Value]; ok && !slices.Contains(events, eventType) {
+			v.warnf(key, "%s: %s is ignored for %s events", where, key.Value, eventType)
+		} else if action.Type == "command" && slices.Contains(outputOnlyActionFields, key.Value) {
+			v.warnf(key, "%s: %s is ignored by command actions (set it in the command's JSON output)", where, key.Value)
+		} else if action.Type == "http" && slices.Contains(outputOnlyActionFields, key.Value) {
+			v.warnf(key, "%s: %s is ignored by http actions (set it in the JSON response)", where, key.Value)
+		} else if action.Type == "opa" && slices.Contains(outputOnlyActionFields, key.Value) {
+			v.warnf(key, "%s: %s is ignored by opa actions (set it in the policy decision)", where, key.Value)
+		} else if action.Type == "run_tests" && key.Value == "timeout" {
+			continue
+		} else if action.Type == "ask_external" && slices.Contains(httpActionFields, key.Value) {
+			continue
+		} else if action.Type != "http" && slices.Contains(httpActionFields, key.Value) {
+			v.warnf(key, "%s: %s is only used by http actions", where, key.Value)
+		} else if action.Type != "command" && (key.Value == "runner" || key.Value == "args" || key.Value == "env" || key.Value == "stdin") {
+			v.warnf(key, "%s: %s is only used by command actions", where, key.Value)
+		} else if action.Type != "command" && action.Type != "typecheck" && action.Type != "lint_feedback" && action.Type != "run_tests" && key.Value == "env_from" {
+			v.warnf(key, "%s: %s is only used by command, typecheck, lint_feedback and run_tests actions", where, key.Value)
+		} else if action.Type != "secret_scan" && key.Value == "scan_file" {
+			v.warnf(key, "%s: %s is only used by secret_scan actions", where, key.Value)
+		} else if action.Type != "markdown_check" && key.Value == "frontmatter_schema" {
+			v.warnf(key, "%s: %s is only used by markdown_check actions", where, key.Value)
+		} else if action.Type != "terminology" && key.Value == "terms" {
+			v.warnf(key, "%s: %s is only used by terminology actions", where, key.Value)
+		} else if action.Type != "breaking_change" && key.Value == "base" {
+			v.warnf(key, "%s: %s is only used by breaking_change actions", where, key.Value)
+		} else if action.Type != "rewrite_command" && (key.Value == "rules" || key.Value == "deny_if_no_match") {
+			v.warnf(key, "%s: %s is only used by rewrite_command actions", where, key.Value)
+		} else if action.Type != "time_tracking" && (key.Value == "ledger" || key.Value == "project" || key.Value == "tags") {
+			v.warnf(key, "%s: %s is only used by time_tracking actions", where, key.Value)
+		} else if action.Type != "format_file" && key.Value == "formatters" {
+			v.warnf(key, "%s: %s is only used by format_file actions", where, key.Value)
+		} else if action.Type != "lint_feedback" && key.Value == "linters" {
+			v.warnf(key, "%s: %s is only used by lint_feedback actions", where, key.Value)
+		} else if action.Type != "lint_feedback" && action.Type != "run_tests" && (key.Value == "max_lines" || key.Value == "max_bytes") {
+			v.warnf(key, "%s: %s is only used by lint_feedback and run_tests actions", where, key.Value)
+		} else if action.Type != "git_checkpoint" && (key.Value == "mode" || key.Value == "repos") {
+			v.warnf(key, "%s: %s is only used by git_checkpoint actions", where, key.Value)
+		} else if action.Type != "digest" && key.Value == "flush" {
+			v.warnf(key, "%s: %s is only used by digest actions", where, key.Value)
+		} else if action.Type != "opa" && (key.Value == "policy" || key.Value == "query") {
+			v.warnf(key, "%s: %s is only used by opa actions", where, key.Value)
+		}
+	}
+
+	// updatedInputはdeny時に使えない（PreToolUse/PermissionRequestの出力はどちらもdenyがデフォルト）
+	if action.Type == "output" && action.UpdatedInput != nil {
+		switch {
+		case eventType == PreToolUse && (action.PermissionDecision == nil || *action.PermissionDecision == "deny"):
+			v.warnf(mappingValue(node, "updated_input"), "%s: updated_input is ignored when permission_decision is deny (the default)", where)
+		case eventType == PermissionRequest && (action.Behavior == nil || *action.Behavior == "deny"):
+			v.warnf(mappingValue(node, "updated_input"), "%s: updated_input is ignored when behavior is deny (the default)", where)
+		case eventType == PermissionRequest && *action.Behavior == "ask":
+			v.warnf(mappingValue(node, "updated_input"), "%s: updated_input is ignored when behavior is ask", where)
+		}
+	}
+}
+
+// checkToolExtensions reports invalid extensions of the formatters or linters mapping of an action.
+func (v *configValidator) checkToolExtensions(where, field string, node *yaml.Node) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if ext := node.Content[i].Value; strings.ContainsAny(strings.TrimPrefix(ext, "."), "./*") || ext == "" || ext == "." {
+			v.errorf(node.Content[i], "%s: %s: invalid extension %q (use e.g. .go or go)", where, field, ext)
+		}
+	}
+}
+
+// checkHTTPAction reports http actions that fail at runtime (missing url, bad method or timeout).
+func (v *configValidator) checkHTTPAction(eventType HookEventType, where string, node *yaml.Node, action Action) {
+	if strings.TrimSpace(action.URL) == "" {
+		v.errorf(node, "%s: http action requires url", where)
+	}
+	if action.Method != "" && !slices.Contains(httpActionMethods, httpActionMethod(action)) {
+		v.errorf(mappingValue(node, "method"), "%s: invalid method %q (must be one of %s)", where, action.Method, strings.Join(httpActionMethods, ", "))
+	}
+	if _, err := httpActionTimeout(action); err != nil {
+		v.errorf(mappingValue(node, "timeout"), "%s: %v", where, err)
+	}
+	// PermissionRequestは出力が無いとdenyになる
+	if eventType == PermissionRequest {
+		v.warnf(node, "%s: http action denies the request unless the endpoint responds with a JSON decision", where)
+	}
+}
+
+// checkOPAAction reports opa actions that fail at runtime: a missing policy, or a policy or query that doesn't compile.
+// Relative policy paths are resolved against the current directory, like cwd when run from the project.
+func (v *configValidator) checkOPAAction(eventType HookEventType, where string, node *yaml.Node, action Action) {
+	if strings.TrimSpace(action.Policy) == "" {
+		v.errorf(node, "%s: opa action requires policy", where)
+		return
+	}
+	if _, err := os.Stat(action.Policy); errors.Is(err, fs.ErrNotExist) {
+		v.warnf(mappingValue(node, "policy"), "%s: policy %s does not exist (relative paths are resolved against cwd)", where, action.Policy)
+	} else if _, err := exec.LookPath(opaProgram); err != nil {
+		v.warnf(node, "%s: opa is not installed, so the policy is not checked and the action fails at runtime", where)
+	} else if err := checkOPAQuery(action.Policy, opaActionQuery(action)); err != nil {
+		v.errorf(mappingValue(node, "policy"), "%s: %v", where, err)
+	}
+	// PermissionRequestは出力が無いとdenyになる
+	if eventType == PermissionRequest {
+		v.warnf(node, "%s: opa action denies the request unless the policy returns a decision", where)
+	}
+}
+
+// checkCommandArgs checks the command or args (and env) of a command action.
+func (v *configValidator) checkCommandArgs(where string, node *yaml.Node, action Action) {
+	switch {
+	case len(action.Args) > 0 && action.Command != "":
+		v.errorf(mappingValue(node, "args"), "%s: command and args are mutually exclusive", where)
+	case len(action.Args) > 0:
+		if strings.TrimSpace(action.Args[0]) == "" {
+			v.errorf(mappingValue(node, "args"), "%s: args[0] must be the program to run", where)
+		}
+	case strings.TrimSpace(action.Command) == "":
+		v.errorf(node, "%s: command action requires command or args", where)
+	}
+	if len(action.Env) == 0 {
+		return
+	}
+	if len(action.Args) == 0 {
+		v.warnf(mappingValue(node, "env"), "%s: env is only used with args (set variables in the command line instead)", where)
+	}
+	for _, name := range slices.Sorted(maps.Keys(action.Env)) {
+		if !envVarNamePattern.MatchString(name) {
+			v.errorf(mappingValue(node, "env"), "%s: invalid env name %q", where, name)
+		}
+	}
+}
+
+// checkOutputMessage reports output actions that fail at runtime for lack of a message,
+// and reasons that are never used.
+func (v *configValidator) checkOutputMessage(eventType HookEventType, where string, node *yaml.Node, action Action) {
+	hasReason := action.Reason != nil && strings.TrimSpace(*action.Reason) != ""
+	if strings.TrimSpace(action.Message) == "" {
+		switch {
+		case eventType == PermissionRequest && action.Behavior != nil && (*action.Behavior == "allow" || *action.Behavior == "ask"):
+		case slices.Contains(reasonEvents, eventType):
+			if !hasReason {
+				v.errorf(node, "%s: output action requires message (shown to the user) or reason (sent to Claude)", where)
+			}
+		default:
+			v.errorf(node, "%s: output action requires message", where)
+		}
+	}
+	if hasReason && slices.Contains(reasonEvents, eventType) && (action.Decision == nil || *action.Decision != "block") {
+		v.warnf(mappingValue(node, "reason"), "%s: reason is only sent to Claude when decision is block", where)
+	}
+}
+
+// checkFields warns about keys of a mapping node that are not in allowed.
+func (v *configValidator) checkFields(node *yaml.Node, allowed map[string]bool, where string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if !allowed[key.Value] {
+			v.warnf(key, "%s: unknown field %q", where, key.Value)
+		}
+	}
+}
+
+func (v *configValidator) errorf(node *yaml.Node, format string, args ...any) {
+	v.add(severityError, node, format, args...)
+}
+
+func (v *configValidator) warnf(node *yaml.Node, format string, args ...any) {
+	v.add(severityWarning, node, format, args...)
+}
+
+func (v *configValidator) add(severity string, node *yaml.Node, format string, args ...any) {
+	issue := configIssue{File: v.file, Severity: severity, Message: fmt.Sprintf(format, args...)}
+	if node != nil {
+		issue.Line, issue.Column = node.Line, node.Column
+	}
+	v.issues = append(v.issues, issue)
+}
+
+// mappingValue returns the value node of key in a mapping node, or nil if the key is absent.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// yamlFieldNames returns the yaml field names of a struct type.
+func yamlFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
@@ -0,0 +1,1570 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateConfigData(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     string
+		want     []string // "line:col: severity: substring"
+		wantNone bool
+	}{
+		{
+			name: "valid config",
+			yaml: `PreToolUse:
+  - matcher: "Write|Edit"
+    conditions:
+      - type: file_extension
+        values: [".go", ".ts"]
+    actions:
+      - type: command
+        command: "gofmt -w {.tool_input.file_path}"
+UserPromptSubmit:
+  - conditions:
+      - type: prompt_regex
+        value: "^deploy"
+        ignore_case: true
+    actions:
+      - type: output
+        message: "blocked"
+        decision: block
+`,
+			wantNone: true,
+		},
+		{
+			name: "unknown event type",
+			yaml: `PreToolUse: []
+PreToolUsee:
+  - actions: []
+`,
+			want: []string{`2:1: error: unknown event type "PreToolUsee"`},
+		},
+		{
+			name: "invalid condition type and condition not supported by event",
+			yaml: `Stop:
+  - conditions:
+      - type: file_exist
+        value: "go.mod"
+      - type: prompt_regex
+        value: "foo"
+    actions:
+      - type: output
+        message: "x"
+`,
+			want: []string{
+				"3:15: error: Stop hook 1: invalid condition type: file_exist",
+				"5:15: error: Stop hook 1: condition type prompt_regex is not supported for Stop events",
+			},
+		},
+		{
+			name: "bad regex and condition values",
+			yaml: `UserPromptSubmit:
+  - conditions:
+      - type: prompt_regex
+        values: ["ok", "(unclosed"]
+      - type: every_n_prompts
+        value: "0"
+      - type: file_older_than
+        value: "go.sum"
+      - type: prompt_regex
+        value: "a"
+        match: all
+    actions:
+      - type: output
+        message: "x"
+`,
+			want: []string{
+				"4:24: error: UserPromptSubmit hook 1: prompt_regex: invalid regex pattern",
+				"6:16: error: UserPromptSubmit hook 1: every_n_prompts: every_n_prompts value must be positive",
+				`8:16: error: UserPromptSubmit hook 1: file_older_than: invalid value "go.sum"`,
+				"11:16: error: UserPromptSubmit hook 1: match requires values",
+			},
+		},
+		{
+			name: "file_path_matches",
+			yaml: `PostToolUse:
+  - matcher: "Write|Edit"
+    conditions:
+      - type: file_path_matches
+        values: ["src/**/*.test.ts", "lib/[a-"]
+    actions:
+      - type: command
+        command: "npx vitest run {.tool_input.file_path}"
+`,
+			want: []string{
+				`5:38: error: PostToolUse hook 1: file_path_matches: invalid glob pattern segment "[a-"`,
+			},
+		},
+		{
+			name: "gitignore conditions",
+			yaml: `PostToolUse:
+  - matcher: "Write|Edit"
+    conditions:
+      - type: file_not_gitignored
+        value: "vendor/"
+    actions:
+      - type: command
+        command: "gofmt -w {.tool_input.file_path}"
+Stop:
+  - conditions:
+      - type: file_is_gitignored
+    actions:
+      - type: output
+        message: "done"
+`,
+			want: []string{
+				`5:16: error: PostToolUse hook 1: file_not_gitignored: does not take a value`,
+				`11:15: error: Stop hook 1: condition type file_is_gitignored is not supported for Stop events`,
+			},
+		},
+		{
+			name: "command regex conditions",
+			yaml: `PreToolUse:
+  - matcher: "Bash"
+    conditions:
+      - type: command_regex
+        value: "git\\s+push\\s+(-f|--force"
+      - type: command_not_regex
+        value: "^(ls|cat)\\b"
+    actions:
+      - type: output
+        message: "x"
+Stop:
+  - conditions:
+      - type: command_regex
+        value: "rm"
+    actions:
+      - type: output
+        message: "x"
+`,
+			want: []string{
+				"5:16: error: PreToolUse hook 1: command_regex: invalid regex pattern",
+				"13:15: error: Stop hook 1: condition type command_regex is not supported for Stop events",
+			},
+		},
+		{
+			name: "git commit message conditions",
+			yaml: `PreToolUse:
+  - matcher: "Bash"
+    conditions:
+      - type: git_commit_message_not_matches
+        value: conventional
+      - type: git_commit_message_matches
+        value: "^WIP("
+      - type: git_commit_message_not_matches
+        value: ""
+    actions:
+      - type: output
+        message: "x"
+UserPromptSubmit:
+  - conditions:
+      - type: git_commit_message_matches
+        value: "WIP"
+    actions:
+      - type: output
+        message: "x"
+`,
+			want: []string{
+				"7:16: error: PreToolUse hook 1: git_commit_message_matches: invalid regex pattern",
+				"9:16: error: PreToolUse hook 1: git_commit_message_not_matches: requires a regex or",
+				"15:15: error: UserPromptSubmit hook 1: condition type git_commit_message_matches is not supported for UserPromptSubmit events",
+			},
+		},
+		{
+			name: "changelog condition",
+			yaml: `Stop:
+  - conditions:
+      - type: changelog_not_updated
+        value: "src/** -- docs/adr/**"
+      - type: changelog_not_updated
+        value: "src/** --"
+    actions:
+      - type: output
+        message: "x"
+`,
+			want: []string{
+				`6:16: error: Stop hook 1: changelog_not_updated: invalid value "src/** --": expected changelog globs after --`,
+			},
+		},
+		{
+			name: "ticket condition",
+			yaml: `Stop:
+  - conditions:
+      - type: ticket_referenced
+      - type: ticket_referenced
+        value: "(PROJ"
+    actions:
+      - type: output
+        message: "x"
+`,
+			want: []string{
+				"5:16: error: Stop hook 1: ticket_referenced: invalid regex pattern",
+			},
+		},
+		{
+			name: "git signing condition",
+			yaml: `PreToolUse:
+  - matcher: "Bash"
+    conditions:
+      - type: not
+        conditions:
+          - type: git_signing_enabled
+      - type: git_signing_enabled
+        value: pgp
+    actions:
+      - type: output
+        message: "x"
+`,
+			want: []string{
+				`8:16: error: PreToolUse hook 1: git_signing_enabled: unknown signing format "pgp"`,
+			},
+		},
+		{
+			name: "large file conditions",
+			yaml: `PreToolUse:
+  - matcher: "Write|Bash"
+    conditions:
+      - type: file_size_gt
+        value: 5MB
+      - type: file_size_gt
+        value: huge
+      - type: file_is_binary
+        value: "true"
+    actions:
+      - type: output
+        message: "x"
+`,
+			want: []string{
+				`7:16: error: PreToolUse hook 1: file_size_gt: invalid size "huge" (e.g. 500KB, 5MB)`,
+				"9:16: error: PreToolUse hook 1: file_is_binary: does not take a value",
+			},
+		},
+		{
+			name: "git push conditions",
+			yaml: `PreToolUse:
+  - matcher: "Bash"
+    conditions:
+      - type: push_is_force
+      - type: push_to_remote_is
+        value: origin
+      - type: push_target_branch_matches
+        value: "^(main|master$"
+      - type: push_is_force
+        value: "true"
+      - type: push_to_remote_is
+    actions:
+      - type: output
+        message: "x"
+`,
+			want: []string{
+				"8:16: error: PreToolUse hook 1: push_target_branch_matches: invalid regex pattern",
+				"10:16: error: PreToolUse hook 1: push_is_force: does not take a value",
+				"11:9: error: PreToolUse hook 1: push_to_remote_is: requires a remote name or URL",
+			},
+		},
+		{
+			name: "git branch conditions",
+			yaml: `Stop:
+  - conditions:
+      - type: git_branch_is
+        value: "main"
+      - type: git_dirty
+      - type: git_branch_matches
+        value: "^(main|release/"
+    actions:
+      - type: output
+        message: "x"
+`,
+			want: []string{
+				"7:16: error: Stop hook 1: git_branch_matches: invalid regex pattern",
+			},
+		},
+		{
+			name: "env conditions",
+			yaml: `UserPromptSubmit:
+  - conditions:
+      - type: env_is
+        value: "CCHOOK_PROFILE=strict"
+      - type: env_set
+        value: "CI"
+      - type: env_matches
+        value: "CCHOOK_PROFILE=^(strict|paranoid"
+      - type: env_is
+        value: "CI"
+      - type: env_set
+        value: "CI=true"
+    actions:
+      - type: output
+        message: "x"
+`,
+			want: []string{
+				"8:16: error: UserPromptSubmit hook 1: env_matches: invalid regex pattern",
+				`10:16: error: UserPromptSubmit hook 1: env_is: invalid value "CI": expected "NAME=value"`,
+				`12:16: error: UserPromptSubmit hook 1: env_set: invalid environment variable name "CI=true"`,
+			},
+		},
+		{
+			name: "time conditions",
+			yaml: `PreToolUse:
+  - matcher: "Bash"
+    conditions:
+      - type: time_between
+        value: "09:00-18:00"
+        tz: "Asia/Tokyo"
+      - type: day_of_week
+        value: "Mon-Fry"
+      - type: time_between
+        value: "9am-6pm"
+        tz: "Asia/Tokio"
+      - type: command_contains
+        value: "deploy"
+        tz: "UTC"
+    actions:
+      - type: output
+        message: "x"
+`,
+			want: []string{
+				`8:16: error: PreToolUse hook 1: day_of_week: invalid day of week "Mon-Fry"`,
+				`10:16: error: PreToolUse hook 1: time_between: invalid value "9am-6pm"`,
+				`11:13: error: PreToolUse hook 1: time_between: invalid tz "Asia/Tokio"`,
+				`14:13: warning: PreToolUse hook 1: tz is only used by time_between, day_of_week and freeze_active`,
+			},
+		},
+		{
+			name: "content conditions",
+			yaml: `PreToolUse:
+  - matcher: "Write|Edit"
+    conditions:
+      - type: content_matches
+        value: "(AKIA[0-9A-Z]{16}"
+    actions:
+      - type: output
+        message: "x"
+UserPromptSubmit:
+  - conditions:
+      - type: content_contains
+        value: "secret"
+    actions:
+      - type: output
+        message: "x"
+`,
+			want: []string{
+				"5:16: error: PreToolUse hook 1: content_matches: invalid regex pattern",
+				"11:15: error: UserPromptSubmit hook 1: condition type content_contains is not supported for UserPromptSubmit events",
+			},
+		},
+		{
+			name: "devcontainer",
+			yaml: `PostToolUse:
+  - matcher: "Write|Edit"
+    conditions:
+      - type: in_devcontainer
+        value: "true"
+    actions:
+      - type: command
+        command: "gofmt -w {.tool_input.file_path}"
+        runner: devcontainer
+      - type: command
+        command: "go vet ./..."
+        runner: devcontainers
+`,
+			want: []string{
+				"5:16: error: PostToolUse hook 1: in_devcontainer: does not take a value",
+				`12:17: error: PostToolUse hook 1 action 2: invalid runner "devcontainers": scheme must be ssh or docker (or devcontainer)`,
+			},
+		},
+		{
+			name: "notification message conditions",
+			yaml: `Notification:
+  - matcher: "permission_prompt"
+    conditions:
+      - type: notification_message_regex
+        value: "use (Bash"
+      - type: notification_message_contains
+        value: "Bash"
+    actions:
+      - type: output
+        message: "x"
+Stop:
+  - conditions:
+      - type: notification_message_contains
+        value: "Bash"
+    actions:
+      - type: output
+        message: "x"
+`,
+			want: []string{
+				"5:16: error: Notification hook 1: notification_message_regex: invalid regex pattern",
+				"13:15: error: Stop hook 1: condition type notification_message_contains is not supported for Stop events",
+			},
+		},
+		{
+			name: "format file",
+			yaml: `PostToolUse:
+  - matcher: "Write|Edit"
+    actions:
+      - type: format_file
+        formatters:
+          .sql: "sqlfluff fix -f"
+          "*.go": "gofumpt -w"
+PreToolUse:
+  - actions:
+      - type: format_file
+      - type: output
+        message: "x"
+        formatters:
+          go: "gofumpt -w"
+`,
+			want: []string{
+				`7:11: error: PostToolUse hook 1 action 1: formatters: invalid extension "*.go" (use e.g. .go or go)`,
+				"10:15: error: PreToolUse hook 1 action 1: format_file action is only supported for PostToolUse events",
+				"13:9: warning: PreToolUse hook 1 action 2: formatters is only used by format_file actions",
+			},
+		},
+		{
+			name: "lint feedback",
+			yaml: `PostToolUse:
+  - matcher: "Write|Edit"
+    actions:
+      - type: lint_feedback
+        linters:
+          py: "ruff check"
+          "src/*.sh": "shellcheck"
+        max_lines: 50
+Stop:
+  - actions:
+      - type: lint_feedback
+`,
+			want: []string{
+				`7:11: error: PostToolUse hook 1 action 1: linters: invalid extension "src/*.sh" (use e.g. .go or go)`,
+				"11:15: error: Stop hook 1 action 1: lint_feedback action is only supported for PostToolUse events",
+			},
+		},
+		{
+			name: "run tests",
+			yaml: `Stop:
+  - actions:
+      - type: run_tests
+        command: "go test ./..."
+        timeout: 5m
+        max_lines: 50
+      - type: run_tests
+        timeout: forever
+SubagentStop:
+  - actions:
+      - type: run_tests
+        command: "make test"
+`,
+			want: []string{
+				"7:9: error: Stop hook 1 action 2: run_tests action requires command",
+				"8:18: error: Stop hook 1 action 2: invalid timeout \"forever\": time: invalid duration \"forever\"",
+				"11:15: error: SubagentStop hook 1 action 1: run_tests action is only supported for Stop events",
+			},
+		},
+		{
+			name: "git checkpoint",
+			yaml: `Stop:
+  - actions:
+      - type: git_checkpoint
+        mode: stash
+        repos: ["~/src/*", "/work/app"]
+      - type: git_checkpoint
+        mode: branch
+        repos: ["src/app"]
+      - type: git_checkpoint
+PreToolUse:
+  - actions:
+      - type: git_checkpoint
+        repos: ["/work/app"]
+`,
+			want: []string{
+				`7:15: error: Stop hook 1 action 2: invalid mode "branch" (must be commit or stash)`,
+				`8:17: error: Stop hook 1 action 2: repos: repo "src/app" must be an absolute path or start with ~/`,
+				"9:9: error: Stop hook 1 action 3: git_checkpoint action requires repos (the repositories allowed to be checkpointed)",
+				"12:15: error: PreToolUse hook 1 action 1: git_checkpoint action is only supported for PostToolUse and Stop events",
+			},
+		},
+		{
+			name: "duplicate hook names",
+			yaml: `Stop:
+  - name: tests
+    actions:
+      - type: command
+        command: "make test"
+  - name: tests
+    actions:
+      - type: command
+        command: "make e2e"
+`,
+			want: []string{
+				`6:11: error: Stop hook 2: duplicate hook name "tests"`,
+			},
+		},
+		{
+			name: "side effects",
+			yaml: `Stop:
+  - actions:
+      - type: command
+        command: "notify-send done"
+        side_effects: [notifications, filesystem]
+`,
+			want: []string{
+				`5:39: error: Stop hook 1 action 1: side_effects: invalid side effect "filesystem" (must be writes_files, network, notifications)`,
+			},
+		},
+		{
+			name: "tool response",
+			yaml: `PostToolUse:
+  - conditions:
+      - type: exit_code_is
+        value: "failed"
+      - type: tool_error_is_present
+        value: "yes"
+    actions:
+      - type: output
+        message: "x"
+PreToolUse:
+  - conditions:
+      - type: tool_response_contains
+        value: "error"
+    actions:
+      - type: output
+        message: "x"
+`,
+			want: []string{
+				`4:16: error: PostToolUse hook 1: exit_code_is: requires an exit code (e.g. "0" or "1")`,
+				"6:16: error: PostToolUse hook 1: tool_error_is_present: does not take a value",
+				"12:15: error: PreToolUse hook 1: condition type tool_response_contains is not supported for PreToolUse events",
+			},
+		},
+		{
+			name: "agent type",
+			yaml: `SubagentStop:
+  - matcher: "Explore|"
+    conditions:
+      - type: agent_type_is
+        value: ""
+    actions:
+      - type: output
+        message: "x"
+Stop:
+  - conditions:
+      - type: agent_type_is
+        value: "Explore"
+    actions:
+      - type: output
+        message: "x"
+`,
+			want: []string{
+				"2:14: warning: SubagentStop hook 1: matcher \"Explore|\" has an empty alternative, which matches everything",
+				"5:16: error: SubagentStop hook 1: agent_type_is: requires an agent type",
+				"11:15: error: Stop hook 1: condition type agent_type_is is not supported for Stop events",
+			},
+		},
+		{
+			name: "session duration",
+			yaml: `Stop:
+  - conditions:
+      - type: session_duration_lt
+        value: "10m"
+      - type: session_duration_gt
+        value: "ten minutes"
+    actions:
+      - type: output
+        decision: block
+        reason: "x"
+`,
+			want: []string{
+				`6:16: error: Stop hook 1: session_duration_gt: invalid duration`,
+			},
+		},
+		{
+			name: "transcript stats",
+			yaml: `Stop:
+  - conditions:
+      - type: tokens_used_gt
+        value: "1.5M"
+      - type: tokens_used_lt
+        value: "lots"
+      - type: last_assistant_message_matches
+        value: "(TODO"
+    actions:
+      - type: output
+        decision: block
+        reason: "x"
+`,
+			want: []string{
+				`6:16: error: Stop hook 1: tokens_used_lt: invalid token count "lots" (use a number such as 200000, 200k or 1.5M)`,
+				"8:16: error: Stop hook 1: last_assistant_message_matches: invalid regex pattern",
+			},
+		},
+		{
+			name: "budget",
+			yaml: `budget:
+  session_tokens: "lots"
+  daily_cost: 20
+  weekly_cost: 100
+  prices:
+    local-model:
+      input: 0.1
+      outptu: 0.2
+UserPromptSubmit:
+  - conditions:
+      - type: budget_exceeded
+        value: daily_cost
+      - type: budget_exceeded
+        value: monthly_cost
+      - type: budget_remaining_below
+        value: "session_tokens:20"
+    actions:
+      - type: output
+        message: "x"
+`,
+			want: []string{
+				`2:3: error: budget: invalid session_tokens "lots" (use a positive number such as 200000, 200k or 1.5M)`,
+				`4:3: warning: budget: unknown field "weekly_cost"`,
+				`8:7: warning: budget: prices of local-model: unknown field "outptu"`,
+				`14:16: error: UserPromptSubmit hook 1: budget_exceeded: unknown budget limit "monthly_cost" (must be one of session_tokens, session_cost, daily_tokens, daily_cost, or empty for any)`,
+				`16:16: error: UserPromptSubmit hook 1: budget_remaining_below: invalid remaining budget "session_tokens:20" (use a percentage such as "20%" or "daily_cost:20%")`,
+			},
+		},
+		{
+			name: "tool_input_jq",
+			yaml: `PreToolUse:
+  - matcher: "Bash"
+    conditions:
+      - type: tool_input_jq
+        value: '.tool_input.command | test("rm -rf /")'
+      - type: tool_input_jq
+        value: '.tool_input.command | test('
+    actions:
+      - type: output
+        message: "x"
+`,
+			want: []string{
+				`7:16: error: PreToolUse hook 1: tool_input_jq: invalid jq query`,
+			},
+		},
+		{
+			name: "recursive search options",
+			yaml: `Stop:
+  - conditions:
+      - type: file_exists_recursive
+        value: go.mod
+        max_depth: 2
+        exclude: [node_modules, "vendor/**"]
+      - type: dir_not_exists_recursive
+        value: dist
+        max_depth: -1
+        exclude: ["[a-"]
+      - type: file_exists
+        value: go.mod
+        max_depth: 1
+    actions:
+      - type: output
+        message: "x"
+`,
+			want: []string{
+				`9:20: error: Stop hook 1: dir_not_exists_recursive: max_depth must not be negative`,
+				`10:19: error: Stop hook 1: dir_not_exists_recursive: invalid exclude pattern`,
+				`13:20: warning: Stop hook 1: max_depth is only used by file_exists_recursive, file_not_exists_recursive, dir_exists_recursive and dir_not_exists_recursive`,
+			},
+		},
+		{
+			name: "cel",
+			yaml: `PreToolUse:
+  - matcher: "Bash"
+    conditions:
+      - type: cel
+        value: 'tool_input.command.startsWith("rm ")'
+      - type: cel
+        value: 'tool_name'
+      - type: cel
+        value: 'tool_nme == "Bash"'
+    actions:
+      - type: output
+        message: "x"
+`,
+			want: []string{
+				`7:16: error: PreToolUse hook 1: cel: expression must evaluate to bool, got string`,
+				`9:16: error: PreToolUse hook 1: cel: invalid expression: ERROR: <input>:1:1: undeclared reference to 'tool_nme'`,
+			},
+		},
+		{
+			name: "freeze_active",
+			yaml: `PreToolUse:
+  - matcher: "Bash"
+    conditions:
+      - type: freeze_active
+        value: "$FREEZE_WINDOWS_URL"
+        tz: Europe/Berlin
+      - type: freeze_active
+        value: " "
+    actions:
+      - type: output
+        message: "x"
+`,
+			want: []string{
+				`8:16: error: PreToolUse hook 1: freeze_active: requires a freeze windows file or URL`,
+			},
+		},
+		{
+			name: "condition groups",
+			yaml: `PreToolUse:
+  - matcher: "Bash"
+    conditions:
+      - type: any_of
+        value: "x"
+        conditions:
+          - type: command_contains
+            value: "rm"
+      - type: not
+      - type: cwd_is
+        value: "/tmp"
+        conditions:
+          - type: cwd_is
+            value: "/"
+    actions:
+      - type: output
+        message: "x"
+`,
+			want: []string{
+				"4:9: error: PreToolUse hook 1: any_of does not take value or values",
+				"9:9: error: PreToolUse hook 1: not requires at least one condition",
+				"13:11: error: PreToolUse hook 1: conditions can only be used with any_of, all_of or not",
+			},
+		},
+		{
+			name: "actions",
+			yaml: `PreToolUse:
+  - matcher: "Bash"
+    actions:
+      - type: output
+        mesage: "typo"
+        permission_decision: "block"
+      - type: shell
+      - type: command
+        behavior: "allow"
+`,
+			want: []string{
+				"4:9: error: PreToolUse hook 1 action 1: output action requires message",
+				`5:9: warning: PreToolUse hook 1 action 1: unknown field "mesage"`,
+				`6:30: error: PreToolUse hook 1 action 1: invalid permission_decision "block"`,
+				`7:15: error: PreToolUse hook 1 action 2: unknown action type "shell"`,
+				"8:9: error: PreToolUse hook 1 action 3: command action requires command",
+				"9:9: warning: PreToolUse hook 1 action 3: behavior is ignored for PreToolUse events",
+			},
+		},
+		{
+			name: "output fields applicability",
+			yaml: `PreToolUse:
+  - matcher: "Bash"
+    actions:
+      - type: output
+        message: "x"
+        updated_input:
+          command: "ls"
+      - type: command
+        command: "check"
+        system_message: "x"
+Stop:
+  - actions:
+      - type: output
+        message: "x"
+        updated_input:
+          command: "ls"
+`,
+			want: []string{
+				"7:11: warning: PreToolUse hook 1 action 1: updated_input is ignored when permission_decision is deny",
+				"10:9: warning: PreToolUse hook 1 action 2: system_message is ignored by command actions",
+				"15:9: warning: Stop hook 1 action 1: updated_input is ignored for Stop events",
+			},
+		},
+		{
+			name: "PermissionRequest ask",
+			yaml: `PermissionRequest:
+  - matcher: "Bash"
+    actions:
+      - type: output
+        behavior: "ask"
+      - type: output
+        behavior: "ask"
+        message: "Confirm"
+        updated_input:
+          command: "ls"
+      - type: output
+        behavior: "prompt"
+        message: "x"
+`,
+			want: []string{
+				"10:11: warning: PermissionRequest hook 1 action 2: updated_input is ignored when behavior is ask",
+				`12:19: error: PermissionRequest hook 1 action 3: invalid behavior "prompt" (must be allow, deny, or ask)`,
+			},
+		},
+		{
+			name: "http actions",
+			yaml: `Stop:
+  - actions:
+      - type: http
+        method: "FETCH"
+        timeout: "10"
+      - type: http
+        url: "https://hooks.slack.com/services/${SLACK_WEBHOOK}"
+        body:
+          text: "{.session_id} stopped"
+      - type: command
+        command: "notify"
+        url: "https://example.com"
+PermissionRequest:
+  - matcher: "Bash"
+    actions:
+      - type: http
+        url: "https://approvals.example.com"
+        suppress_output: true
+`,
+			want: []string{
+				"3:9: error: Stop hook 1 action 1: http action requires url",
+				`4:17: error: Stop hook 1 action 1: invalid method "FETCH"`,
+				`5:18: error: Stop hook 1 action 1: invalid timeout "10"`,
+				"12:9: warning: Stop hook 1 action 3: url is only used by http actions",
+				"16:9: warning: PermissionRequest hook 1 action 1: http action denies the request unless the endpoint responds with a JSON decision",
+				"18:9: warning: PermissionRequest hook 1 action 1: suppress_output is ignored by http actions",
+			},
+		},
+		{
+			name: "runner",
+			yaml: `PostToolUse:
+  - matcher: "Write|Edit"
+    actions:
+      - type: command
+        command: "make lint"
+        runner: "ssh://ci@build-01:2222/srv/app"
+      - type: command
+        command: "make test"
+        runner: "kubectl://pod"
+      - type: output
+        message: "x"
+        runner: "docker://devcontainer"
+`,
+			want: []string{
+				`9:17: error: PostToolUse hook 1 action 2: invalid runner "kubectl://pod": scheme must be ssh or docker`,
+				"12:9: warning: PostToolUse hook 1 action 3: runner is only used by command actions",
+			},
+		},
+		{
+			name: "mutex",
+			yaml: `PostToolUse:
+  - matcher: "Write|Edit"
+    mutex: go-mod
+    actions:
+      - type: command
+        command: "go mod tidy"
+Stop:
+  - mutex: "../tidy"
+    actions:
+      - type: command
+        command: "go mod tidy"
+`,
+			want: []string{
+				`8:12: error: Stop hook 1: invalid mutex name "../tidy" (use letters, digits, '.', '_' and '-')`,
+			},
+		},
+		{
+			name: "cooldown",
+			yaml: `Stop:
+  - cooldown: 10m
+    cooldown_scope: global
+    actions:
+      - type: output
+        message: "Take a break"
+  - cooldown: soon
+    actions:
+      - type: output
+        message: "Take a break"
+  - cooldown: 1h
+    cooldown_scope: project
+    actions:
+      - type: output
+        message: "Take a break"
+  - cooldown_scope: session
+    actions:
+      - type: output
+        message: "Take a break"
+`,
+			want: []string{
+				`7:15: error: Stop hook 2: invalid cooldown "soon" (use a positive duration such as 30s, 10m, 1h or 1d)`,
+				`12:21: error: Stop hook 3: invalid cooldown_scope "project" (must be session or global)`,
+				`16:21: warning: Stop hook 4: cooldown_scope has no effect without cooldown`,
+			},
+		},
+		{
+			name: "snapshot",
+			yaml: `PostToolUse:
+  - matcher: "Write|Edit"
+    snapshot: true
+    actions:
+      - type: command
+        command: "gofmt -w {.tool_input.file_path}"
+Stop:
+  - snapshot: true
+    actions:
+      - type: command
+        command: "make fmt"
+`,
+			want: []string{
+				`8:5: warning: Stop hook 1: unknown field "snapshot"`,
+			},
+		},
+		{
+			name: "env_from",
+			yaml: `PostToolUse:
+  - matcher: "Write|Edit"
+    env_from: direnv
+    actions:
+      - type: command
+        command: "gofmt -l ."
+        env_from: "nix develop"
+      - type: output
+        message: "x"
+        env_from: direnv
+Stop:
+  - env_from: nix
+    actions:
+      - type: command
+        command: "make test"
+        env_from: asdf
+`,
+			want: []string{
+				`10:9: warning: PostToolUse hook 1 action 2: env_from is only used by command, typecheck, lint_feedback and run_tests actions`,
+				`12:15: error: Stop hook 1: invalid env_from "nix" (must be "direnv" or "nix develop")`,
+				`16:19: error: Stop hook 1 action 1: invalid env_from "asdf" (must be "direnv" or "nix develop")`,
+			},
+		},
+		{
+			name: "hook_changes_report",
+			yaml: `Stop:
+  - actions:
+      - type: hook_changes_report
+PostToolUse:
+  - matcher: "Write"
+    actions:
+      - type: hook_changes_report
+`,
+			want: []string{
+				`7:15: error: PostToolUse hook 1 action 1: hook_changes_report action is only supported for Stop events`,
+			},
+		},
+		{
+			name: "secret_scan",
+			yaml: `PreToolUse:
+  - matcher: "Write|Edit|Bash"
+    actions:
+      - type: secret_scan
+        scan_file: true
+PostToolUse:
+  - matcher: "Write|Edit"
+    actions:
+      - type: secret_scan
+        scan_file: true
+      - type: command
+        command: "gofmt -w {.tool_input.file_path}"
+        scan_file: true
+Stop:
+  - actions:
+      - type: secret_scan
+`,
+			want: []string{
+				`5:9: warning: PreToolUse hook 1 action 1: scan_file is ignored for PreToolUse events`,
+				`13:9: warning: PostToolUse hook 1 action 2: scan_file is only used by secret_scan actions`,
+				`16:15: error: Stop hook 1 action 1: secret_scan action is only supported for PreToolUse and PostToolUse events`,
+			},
+		},
+		{
+			name: "batch",
+			yaml: `PostToolUse:
+  - matcher: "Write|Edit"
+    batch: true
+    batch_quiet_period: "soon"
+    snapshot: true
+    actions:
+      - type: command
+        command: "make lint"
+  - matcher: "Write|Edit"
+    batch_quiet_period: "5s"
+    actions:
+      - type: command
+        command: "make lint"
+`,
+			want: []string{
+				`4:25: error: PostToolUse hook 1: invalid batch_quiet_period "soon"`,
+				`5:15: warning: PostToolUse hook 1: snapshot is ignored for batch hooks`,
+				`10:25: warning: PostToolUse hook 2: batch_quiet_period is only used with batch: true`,
+			},
+		},
+		{
+			name: "syntax_check",
+			yaml: `PostToolUse:
+  - matcher: "Write|Edit"
+    actions:
+      - type: syntax_check
+PreToolUse:
+  - matcher: "Write"
+    actions:
+      - type: syntax_check
+`,
+			want: []string{
+				`8:15: error: PreToolUse hook 1 action 1: syntax_check action is only supported for PostToolUse events`,
+			},
+		},
+		{
+			name: "markdown_check",
+			yaml: `PostToolUse:
+  - matcher: "Write|Edit"
+    actions:
+      - type: markdown_check
+        frontmatter_schema: docs/frontmatter.schema.json
+        decision: block
+      - type: syntax_check
+        frontmatter_schema: docs/frontmatter.schema.json
+Stop:
+  - actions:
+      - type: markdown_check
+`,
+			want: []string{
+				`8:9: warning: PostToolUse hook 1 action 2: frontmatter_schema is only used by markdown_check actions`,
+				`11:15: error: Stop hook 1 action 1: markdown_check action is only supported for PostToolUse events`,
+			},
+		},
+		{
+			name: "typecheck",
+			yaml: `PostToolUse:
+  - matcher: "Write|Edit|MultiEdit"
+    actions:
+      - type: typecheck
+        env_from: direnv
+        decision: ""
+Stop:
+  - actions:
+      - type: typecheck
+        env_from: asdf
+`,
+			want: []string{
+				`9:15: error: Stop hook 1 action 1: typecheck action is only supported for PostToolUse events`,
+				`10:19: error: Stop hook 1 action 1: invalid env_from "asdf" (must be "direnv" or "nix develop")`,
+			},
+		},
+		{
+			name: "terminology",
+			yaml: `PreToolUse:
+  - matcher: "Write|Edit"
+    actions:
+      - type: terminology
+        permission_decision: deny
+        terms:
+          - term: whitelist
+            preferred: allowlist
+            replacement: allowlist
+          - preferred: GitHub
+      - type: output
+        message: "Check the terms"
+        terms:
+          - term: blacklist
+Stop:
+  - actions:
+      - type: terminology
+`,
+			want: []string{
+				`7:11: error: PreToolUse hook 1 action 1: terms[1]: term is required`,
+				`9:13: warning: PreToolUse hook 1 action 1: terms[0]: unknown field "replacement"`,
+				`13:9: warning: PreToolUse hook 1 action 2: terms is only used by terminology actions`,
+				`17:9: error: Stop hook 1 action 1: terminology action requires terms`,
+				`17:15: error: Stop hook 1 action 1: terminology action is only supported for PreToolUse and PostToolUse events`,
+			},
+		},
+		{
+			name: "command args and env",
+			yaml: `PostToolUse:
+  - matcher: "Write"
+    actions:
+      - type: command
+        args: ["gofmt", "-w", "{.tool_input.file_path}"]
+        env:
+          GOFLAGS: "-mod=mod"
+      - type: command
+        command: "gofmt -w {.tool_input.file_path}"
+        args: ["gofmt"]
+      - type: command
+        args: [""]
+        env:
+          "BAD-NAME": "x"
+      - type: command
+        command: "make fmt"
+        env:
+          FOO: "1"
+      - type: command
+      - type: output
+        message: "x"
+        args: ["x"]
+`,
+			want: []string{
+				"10:15: error: PostToolUse hook 1 action 2: command and args are mutually exclusive",
+				"12:15: error: PostToolUse hook 1 action 3: args[0] must be the program to run",
+				"14:11: error: PostToolUse hook 1 action 3: invalid env name \"BAD-NAME\"",
+				"18:11: warning: PostToolUse hook 1 action 4: env is only used with args",
+				"19:9: error: PostToolUse hook 1 action 5: command action requires command or args",
+				"22:9: warning: PostToolUse hook 1 action 6: args is only used by command actions",
+			},
+		},
+		{
+			name: "command stdin",
+			yaml: `PostToolUse:
+  - matcher: "Write"
+    actions:
+      - type: command
+        command: "cat"
+        stdin: enriched
+      - type: command
+        command: "cat"
+        stdin: json
+      - type: command
+        command: "cat"
+        use_stdin: true
+        stdin: none
+      - type: output
+        message: "x"
+        stdin: raw
+`,
+			want: []string{
+				"9:16: error: PostToolUse hook 1 action 2: stdin must be none, raw or enriched",
+				"12:20: warning: PostToolUse hook 1 action 3: use_stdin is ignored when stdin is set",
+				"16:9: warning: PostToolUse hook 1 action 4: stdin is only used by command actions",
+			},
+		},
+		{
+			name: "breaking_change",
+			yaml: `PreToolUse:
+  - matcher: "Write|Edit|MultiEdit"
+    actions:
+      - type: breaking_change
+        base: origin/main
+      - type: breaking_change
+        permission_decision: allow
+        base: --output=/tmp/x
+      - type: output
+        message: "API changed"
+        base: origin/main
+PostToolUse:
+  - matcher: "Write|Edit"
+    actions:
+      - type: breaking_change
+`,
+			want: []string{
+				`7:30: error: PreToolUse hook 1 action 2: breaking_change permission_decision must be ask or deny`,
+				`8:15: error: PreToolUse hook 1 action 2: invalid base "--output=/tmp/x" (must be a Git revision)`,
+				`11:9: warning: PreToolUse hook 1 action 3: base is only used by breaking_change actions`,
+				`15:15: error: PostToolUse hook 1 action 1: breaking_change action is only supported for PreToolUse events`,
+			},
+		},
+		{
+			name: "rewrite_command",
+			yaml: `PreToolUse:
+  - matcher: "Bash"
+    actions:
+      - type: rewrite_command
+        permission_decision: deny
+        rules:
+          - pattern: '^rm\s+'
+            replace: "trash "
+            flags: g
+          - pattern: "(terraform"
+      - type: output
+        message: "x"
+        deny_if_no_match: true
+Stop:
+  - actions:
+      - type: rewrite_command
+`,
+			want: []string{
+				`5:30: error: PreToolUse hook 1 action 1: rewrite_command permission_decision must be allow or ask`,
+				`7:11: error: PreToolUse hook 1 action 1: rules[1]: invalid regex pattern`,
+				`9:13: warning: PreToolUse hook 1 action 1: rules[0]: unknown field "flags"`,
+				`13:9: warning: PreToolUse hook 1 action 2: deny_if_no_match is only used by rewrite_command actions`,
+				`16:9: error: Stop hook 1 action 1: rewrite_command action requires rules`,
+				`16:15: error: Stop hook 1 action 1: rewrite_command action is only supported for PreToolUse events`,
+			},
+		},
+		{
+			name: "ask_external",
+			yaml: `PreToolUse:
+  - matcher: "Bash"
+    actions:
+      - type: ask_external
+        url: "https://approvals.example.com"
+        timeout: 45s
+      - type: ask_external
+        permission_decision: allow
+        timeout: soon
+Stop:
+  - actions:
+      - type: ask_external
+        url: "https://approvals.example.com"
+`,
+			want: []string{
+				`7:9: error: PreToolUse hook 1 action 2: ask_external action requires url (where the pending tool call is posted)`,
+				`8:30: error: PreToolUse hook 1 action 2: ask_external permission_decision must be ask or deny`,
+				`9:18: error: PreToolUse hook 1 action 2: invalid timeout "soon": time: invalid duration "soon"`,
+				`12:15: error: Stop hook 1 action 1: ask_external action is only supported for PreToolUse events`,
+			},
+		},
+		{
+			name: "time_tracking",
+			yaml: `SessionStart:
+  - actions:
+      - type: time_tracking
+        project: "{.cwd}"
+        tags: [claude]
+      - type: output
+        message: "x"
+        ledger: /tmp/time.jsonl
+Stop:
+  - actions:
+      - type: time_tracking
+`,
+			want: []string{
+				`8:9: warning: SessionStart hook 1 action 2: ledger is only used by time_tracking actions`,
+				`11:15: error: Stop hook 1 action 1: time_tracking action is only supported for SessionStart and SessionEnd events`,
+			},
+		},
+		{
+			name: "digest",
+			yaml: `Notification:
+  - conditions:
+      - type: in_meeting
+    actions:
+      - type: digest
+  - conditions:
+      - type: digest_pending
+        value: "true"
+    actions:
+      - type: digest
+        flush: true
+      - type: output
+        message: "{.digest.text}"
+        flush: true
+Stop:
+  - actions:
+      - type: digest
+PreToolUse:
+  - actions:
+      - type: digest
+        flush: true
+`,
+			want: []string{
+				`3:9: error: Notification hook 1: in_meeting: requires an iCalendar URL or file`,
+				`8:16: error: Notification hook 2: digest_pending: does not take a value`,
+				`14:9: warning: Notification hook 2 action 2: flush is only used by digest actions`,
+				`17:15: error: Stop hook 1 action 1: digest action can only queue Notification events (use flush: true)`,
+				`20:15: error: PreToolUse hook 1 action 1: digest action is only supported for Notification and Stop events`,
+				`21:9: warning: PreToolUse hook 1 action 1: flush is ignored for PreToolUse events`,
+			},
+		},
+		{
+			name: "opa",
+			yaml: `PreToolUse:
+  - actions:
+      - type: opa
+      - type: opa
+        policy: policies/missing.rego
+        system_message: "x"
+      - type: command
+        command: "true"
+        query: data.cchook.decision
+`,
+			want: []string{
+				`3:9: error: PreToolUse hook 1 action 1: opa action requires policy`,
+				`5:17: warning: PreToolUse hook 1 action 2: policy policies/missing.rego does not exist`,
+				`6:9: warning: PreToolUse hook 1 action 2: system_message is ignored by opa actions (set it in the policy decision)`,
+				`9:9: warning: PreToolUse hook 1 action 3: query is only used by opa actions`,
+			},
+		},
+		{
+			name: "message and reason",
+			yaml: `Stop:
+  - actions:
+      - type: output
+        decision: block
+        reason: "Run tests before stopping"
+      - type: output
+        message: "x"
+        reason: "unused"
+      - type: output
+        decision: block
+PostToolUse:
+  - matcher: "Write"
+    actions:
+      - type: output
+        message: "x"
+        decision: block
+        reason: "{.tool_name} output needs review"
+`,
+			want: []string{
+				"8:17: warning: Stop hook 1 action 2: reason is only sent to Claude when decision is block",
+				"9:9: error: Stop hook 1 action 3: output action requires message (shown to the user) or reason (sent to Claude)",
+			},
+		},
+		{
+			name: "unreachable hooks",
+			yaml: `SessionStart:
+  - matcher: "startup|resume"
+    actions:
+      - type: output
+        message: "x"
+PreCompact:
+  - matcher: "Manual"
+    actions:
+      - type: output
+        message: "x"
+Notification:
+  - matcher: "idle"
+    actions:
+      - type: output
+        message: "x"
+PostToolUse:
+  - matcher: "Write|"
+    actions:
+      - type: output
+        message: "x"
+Stop:
+  - matcher: "Bash"
+`,
+			want: []string{
+				`2:14: warning: SessionStart hook 1: hook is unreachable: matcher "startup|resume" never matches`,
+				`7:14: warning: PreCompact hook 1: hook is unreachable: matcher "Manual" never matches`,
+				`12:14: warning: Notification hook 1: matcher contains unknown notification_type "idle"`,
+				`12:14: warning: Notification hook 1: hook is unreachable: matcher "idle" never matches`,
+				`17:14: warning: PostToolUse hook 1: matcher "Write|" has an empty alternative`,
+				`22:5: warning: Stop hook 1: unknown field "matcher"`,
+				"22:5: warning: Stop hook 1: hook has no actions",
+			},
+		},
+		{
+			name: "merge strategies",
+			yaml: `merge:
+  PreToolUse: replace
+  Stopp: append
+  Stop: override
+`,
+			want: []string{
+				"1:1: warning: merge is only used by a project config (.cchook.yaml) and is ignored here",
+				`3:3: error: merge: unknown event type "Stopp"`,
+				`4:9: error: merge: invalid strategy "override"`,
+			},
+		},
+		{
+			name: "project config trust",
+			yaml: `project_config:
+  trusted: [src/*]
+  allow_replac: true
+`,
+			want: []string{
+				`2:3: error: project_config: trusted "src/*" must be an absolute path or start with ~/`,
+				`3:3: warning: project_config: unknown field "allow_replac"`,
+			},
+		},
+		{
+			name: "decision log",
+			yaml: `decision_log:
+  path: ~/.cache/cchook/decisions.jsonl
+  format: ecs
+  include_tool_inputs: true
+`,
+			want: []string{
+				`2:3: error: decision_log: invalid format "ecs" (must be cloudevents or ocsf)`,
+				`4:3: warning: decision_log: unknown field "include_tool_inputs"`,
+			},
+		},
+		{
+			name: "report",
+			yaml: `report:
+  url: ftp://stats.example.com
+  teams: platform
+`,
+			want: []string{
+				`2:3: error: report: invalid url "ftp://stats.example.com"`,
+				`3:3: warning: report: unknown field "teams"`,
+			},
+		},
+		{
+			name: "log",
+			yaml: `log:
+  path: ~/.cache/cchook/hooks.log
+  level: verbose
+  format: logfmt
+  rotate: daily
+`,
+			want: []string{
+				`3:10: error: log: invalid level "verbose" (must be debug, info, warn or error)`,
+				`4:11: error: log: invalid format "logfmt" (must be json or text)`,
+				`5:3: warning: log: unknown field "rotate"`,
+			},
+		},
+		{
+			name: "output format",
+			yaml: "output_format: pretty\n",
+			want: []string{
+				`1:16: error: output_format: invalid value "pretty" (must be indent2 or compact)`,
+			},
+		},
+		{
+			name: "strict permissions",
+			yaml: "strict_permissions: strict\n",
+			want: []string{
+				`1:21: error: strict_permissions: invalid value "strict" (must be warn, refuse or off)`,
+			},
+		},
+		{
+			name: "on git unavailable",
+			yaml: "on_git_unavailable: fail\n",
+			want: []string{
+				`1:21: error: on_git_unavailable: invalid value "fail" (must be skip, warn or deny)`,
+			},
+		},
+		{
+			name: "regex matchers",
+			yaml: `PreToolUse:
+  - matcher: "mcp__.*__write.*"
+    actions:
+      - type: output
+        message: "x"
+  - matcher: "mcp__(github"
+    actions:
+      - type: output
+        message: "x"
+  - matcher: "Edit|mcp__[x"
+    actions:
+      - type: output
+        message: "x"
+`,
+			want: []string{
+				"6:14: error: PreToolUse hook 2: invalid regex matcher \"mcp__(github\": error parsing regexp: missing closing ): `^(?:mcp__(github)$`",
+				"10:14: error: PreToolUse hook 3: invalid regex matcher \"mcp__[x\": error parsing regexp: missing closing ]: `[x)$`",
+			},
+		},
+		{
+			name: "YAML syntax error",
+			yaml: "PreToolUse:\n  - matcher: \"Bash\n",
+			want: []string{"error: yaml: line 2"},
+		},
+		{
+			name: "type errors found by decoding",
+			yaml: `PreToolUse:
+  - matcher: "Bash"
+    actions:
+      - type: output
+        message: "x"
+        exit_status: "two"
+`,
+			want: []string{"error: PreToolUse hook 1 action 1: yaml: unmarshal errors"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, issue := range validateConfigData("config.yaml", []byte(tt.yaml)) {
+				got = append(got, issue.String())
+			}
+
+			if tt.wantNone {
+				if len(got) > 0 {
+					t.Errorf("Expected no issues, got:\n%s", strings.Join(got, "\n"))
+				}
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %d issues, got %d:\n%s", len(tt.want), len(got), strings.Join(got, "\n"))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(got[i], want) {
+					t.Errorf("Issue %d = %q, want it to contain %q", i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestRunValidate(t *testing.T) {
+	dir := t.TempDir()
+
+	validPath := filepath.Join(dir, "valid.yaml")
+	if err := os.WriteFile(validPath, []byte("Stop:\n  - actions:\n      - type: output\n        message: \"x\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := runValidate(&buf, validPath); err != nil {
+		t.Errorf("runValidate() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "OK") {
+		t.Errorf("Expected OK, got:\n%s", buf.String())
+	}
+
+	invalidPath := filepath.Join(dir, "invalid.yaml")
+	if err := os.WriteFile(invalidPath, []byte("Stopp: []\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	err := runValidate(&buf, invalidPath)
+	if err == nil || !strings.Contains(err.Error(), "1 error(s)") {
+		t.Errorf("runValidate() error = %v, want 1 error", err)
+	}
+	if !strings.Contains(buf.String(), invalidPath+":1:1: error:") {
+		t.Errorf("Expected issue with file position, got:\n%s", buf.String())
+	}
+
+	if err := runValidate(&buf, filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("Expected error for missing config file")
+	}
+}
+
+func TestValidateConfigFile_Include(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFiles(t, dir, map[string]string{
+		"config.yaml": `include:
+  - "hooks.d/*.yaml"
+  - missing.yaml
+  - "none.d/*.yaml"
+` + stopHook("root"),
+		"hooks.d/a.yaml": "Stopp: []\n",
+		"hooks.d/b.yaml": "include: [../config.yaml]\n",
+	})
+
+	configPath := filepath.Join(dir, "config.yaml")
+	_, issues, err := validateConfigFile(configPath)
+	if err != nil {
+		t.Fatalf("validateConfigFile() error = %v", err)
+	}
+	var got []string
+	for _, issue := range issues {
+		got = append(got, issue.String())
+	}
+	want := []string{
+		configPath + `:3:5: error: invalid include "missing.yaml"`,
+		configPath + `:4:5: warning: include "none.d/*.yaml" matches no files`,
+		filepath.Join(dir, "hooks.d", "a.yaml") + `:1:1: error: unknown event type "Stopp"`,
+		filepath.Join(dir, "hooks.d", "b.yaml") + ":1:11: error: include cycle",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d issues, got %d:\n%s", len(want), len(got), strings.Join(got, "\n"))
+	}
+	for i := range want {
+		if !strings.HasPrefix(got[i], want[i]) {
+			t.Errorf("Issue %d = %q, want prefix %q", i, got[i], want[i])
+		}
+	}
+}
@@ -237,10 +237,10 @@ func validatePermissionRequestOutput(jsonData []byte) error {
 			// 4. Configure decision
 			if decisionProp, ok := hookSpecific.Properties.Get("decision"); ok {
 				if decision := decisionProp; decision != nil {
-					// behavior must be "allow" or "deny"
+					// behavior must be "allow", "deny" or "ask"
 					if behaviorProp, ok := decision.Properties.Get("behavior"); ok {
 						if behavior := behaviorProp; behavior != nil {
-							behavior.Enum = []any{"allow", "deny"}
+							behavior.Enum = []any{"allow", "deny", "ask"}
 						}
 					}
 					// decision.behavior is required
@@ -307,6 +307,14 @@ func validatePermissionRequestOutput(jsonData []byte) error {
 			if decision.Message == "" {
 				return fmt.Errorf("semantic validation failed: 'message' is required when behavior is 'deny'")
 			}
+		case "ask":
+			// ask時: ユーザーに確認するので updatedInput と interrupt は使えない (message は任意)
+			if decision.UpdatedInput != nil {
+				return fmt.Errorf("semantic validation failed: 'updatedInput' should not exist when behavior is 'ask'")
+			}
+			if decision.Interrupt {
+				return fmt.Errorf("semantic validation failed: 'interrupt' should be false when behavior is 'ask'")
+			}
 		}
 	}
 